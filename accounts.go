@@ -0,0 +1,634 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Имя аккаунта одновременно служит именем игрока в банах и сессиях
+var accountNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 256
+	// Число итераций PBKDF2-SHA256 по рекомендации OWASP
+	passwordIterations = 600000
+)
+
+// Аккаунт игрока. Пароль хранится как pbkdf2-sha256$<итерации>$<соль>$<хэш>.
+// TOTPSecret появляется при подключении 2FA, TOTPEnabled — после
+// подтверждения первым кодом; коды восстановления хранятся как SHA-256.
+// У аккаунта, созданного входом через Discord или Google, нет пароля.
+// Восстановить пароль можно только на подтвержденную почту.
+type Account struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	PasswordHash  string    `json:"password_hash"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	TOTPSecret    string    `json:"totp_secret,omitempty"`
+	TOTPEnabled   bool      `json:"totp_enabled,omitempty"`
+	TOTPLastStep  int64     `json:"totp_last_step,omitempty"`
+	RecoveryCodes []string  `json:"recovery_codes,omitempty"`
+
+	Identities []AccountIdentity `json:"identities,omitempty"`
+
+	// Профиль: отображаемое имя и время загрузки аватара (нет — без аватара)
+	DisplayName     string     `json:"display_name,omitempty"`
+	AvatarUpdatedAt *time.Time `json:"avatar_updated_at,omitempty"`
+
+	// Запрошенное удаление: когда аккаунт будет удален и ID лаунчера,
+	// с которого пришел запрос, — его телеметрия удаляется вместе с аккаунтом
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	DeletionClientID    string     `json:"deletion_client_id,omitempty"`
+}
+
+// Данные входа; TOTPCode — код 2FA или код восстановления, если 2FA
+// включена. Email необязателен и учитывается только при регистрации.
+// CaptchaToken нужен после нескольких неудачных входов.
+type AccountCredentials struct {
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	TOTPCode     string `json:"totp_code"`
+	Email        string `json:"email"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// Токен доступа — короткоживущий JWT, подписанный Ed25519, — и токен
+// обновления сессии для получения следующего
+type AccountTokenResponse struct {
+	Token            string      `json:"token"`
+	ExpiresAt        time.Time   `json:"expires_at"`
+	RefreshToken     string      `json:"refresh_token"`
+	RefreshExpiresAt time.Time   `json:"refresh_expires_at"`
+	SessionID        string      `json:"session_id"`
+	Account          AccountInfo `json:"account"`
+}
+
+// Публичные сведения об аккаунте
+type AccountInfo struct {
+	ID            string    `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	HasPassword   bool      `json:"has_password"`
+
+	Identities          []AccountIdentity `json:"identities,omitempty"`
+	DisplayName         string            `json:"display_name,omitempty"`
+	AvatarUpdatedAt     *time.Time        `json:"avatar_updated_at,omitempty"`
+	DeletionScheduledAt *time.Time        `json:"deletion_scheduled_at,omitempty"`
+}
+
+func (a Account) info() AccountInfo {
+	return AccountInfo{
+		ID:            a.ID,
+		Username:      a.Username,
+		Email:         a.Email,
+		EmailVerified: a.EmailVerified,
+		CreatedAt:     a.CreatedAt,
+		TOTPEnabled:   a.TOTPEnabled,
+		HasPassword:   a.PasswordHash != "",
+		Identities:    a.Identities,
+		DisplayName:   a.DisplayName,
+
+		AvatarUpdatedAt:     a.AvatarUpdatedAt,
+		DeletionScheduledAt: a.DeletionScheduledAt,
+	}
+}
+
+// Аккаунты в DATA_DIR/accounts.json
+type AccountStore struct {
+	mu       sync.Mutex
+	accounts []Account
+}
+
+var accounts = &AccountStore{}
+
+func accountsFile() string {
+	return filepath.Join(currentConfig().DataDir, "accounts.json")
+}
+
+func (s *AccountStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(accountsFile(), &s.accounts)
+}
+
+func (s *AccountStore) ByID(id string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.accounts, func(a Account) bool { return a.ID == id }); i >= 0 {
+		return s.accounts[i], true
+	}
+	return Account{}, false
+}
+
+// Имена аккаунтов уникальны без учета регистра
+func (s *AccountStore) ByUsername(username string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.accounts, func(a Account) bool { return strings.EqualFold(a.Username, username) }); i >= 0 {
+		return s.accounts[i], true
+	}
+	return Account{}, false
+}
+
+// Аккаунт по привязанному внешнему аккаунту провайдера
+func (s *AccountStore) ByIdentity(provider, subject string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.accounts, func(a Account) bool { return a.hasIdentity(provider, subject) }); i >= 0 {
+		return s.accounts[i], true
+	}
+	return Account{}, false
+}
+
+func (a Account) hasIdentity(provider, subject string) bool {
+	return slices.ContainsFunc(a.Identities, func(id AccountIdentity) bool {
+		return id.Provider == provider && id.Subject == subject
+	})
+}
+
+// Аккаунт по адресу почты без учета регистра
+func (s *AccountStore) ByEmail(email string) (Account, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.accounts, func(a Account) bool { return a.Email != "" && strings.EqualFold(a.Email, email) }); i >= 0 {
+		return s.accounts[i], true
+	}
+	return Account{}, false
+}
+
+// Создание аккаунта; false, если имя или почта заняты
+func (s *AccountStore) Create(account Account) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.accounts, func(a Account) bool {
+		return strings.EqualFold(a.Username, account.Username) ||
+			(account.Email != "" && strings.EqualFold(a.Email, account.Email))
+	}) {
+		return false, nil
+	}
+	next := append(slices.Clone(s.accounts), account)
+	if err := saveJSONFile(accountsFile(), next); err != nil {
+		return true, err
+	}
+	s.accounts = next
+	return true, nil
+}
+
+// Изменение аккаунта под блокировкой; fn работает с копией и возвращает
+// ошибку API, если изменение недопустимо
+func (s *AccountStore) Update(id string, fn func(account *Account) *modError) (Account, *modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(id, fn)
+}
+
+// Привязка внешнего аккаунта; прежняя привязка того же провайдера
+// заменяется. Внешний аккаунт не может быть привязан к двум аккаунтам.
+func (s *AccountStore) LinkIdentity(id string, identity AccountIdentity) (Account, *modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.accounts, func(a Account) bool {
+		return a.ID != id && a.hasIdentity(identity.Provider, identity.Subject)
+	}) {
+		return Account{}, &modError{http.StatusConflict, ErrCodeOAuthIdentityLinked, []interface{}{identity.Provider}}, nil
+	}
+	return s.update(id, func(a *Account) *modError {
+		a.Identities = append(slices.DeleteFunc(slices.Clone(a.Identities), func(existing AccountIdentity) bool {
+			return existing.Provider == identity.Provider
+		}), identity)
+		return nil
+	})
+}
+
+// Смена почты; новая почта требует подтверждения. Одна почта не может
+// принадлежать двум аккаунтам.
+func (s *AccountStore) SetEmail(id, email string) (Account, *modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.accounts, func(a Account) bool { return a.ID != id && strings.EqualFold(a.Email, email) }) {
+		return Account{}, &modError{http.StatusConflict, ErrCodeEmailInUse, nil}, nil
+	}
+	return s.update(id, func(a *Account) *modError {
+		if !strings.EqualFold(a.Email, email) {
+			a.EmailVerified = false
+		}
+		a.Email = email
+		return nil
+	})
+}
+
+// ID всех аккаунтов, кроме ожидающих удаления
+func (s *AccountStore) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, account := range s.accounts {
+		if account.DeletionScheduledAt == nil {
+			ids = append(ids, account.ID)
+		}
+	}
+	return ids
+}
+
+// Аккаунты, срок удаления которых наступил
+func (s *AccountStore) DueForDeletion(now time.Time) []Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Account
+	for _, account := range s.accounts {
+		if account.DeletionScheduledAt != nil && !now.Before(*account.DeletionScheduledAt) {
+			result = append(result, account)
+		}
+	}
+	return result
+}
+
+func (s *AccountStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.accounts, func(a Account) bool { return a.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	next := slices.Delete(slices.Clone(s.accounts), i, i+1)
+	if err := saveJSONFile(accountsFile(), next); err != nil {
+		return true, err
+	}
+	s.accounts = next
+	return true, nil
+}
+
+// Изменение под уже взятой блокировкой
+func (s *AccountStore) update(id string, fn func(account *Account) *modError) (Account, *modError, error) {
+	i := slices.IndexFunc(s.accounts, func(a Account) bool { return a.ID == id })
+	if i < 0 {
+		return Account{}, &modError{http.StatusNotFound, ErrCodeAccountNotFound, []interface{}{id}}, nil
+	}
+	next := slices.Clone(s.accounts)
+	if apiErr := fn(&next[i]); apiErr != nil {
+		return Account{}, apiErr, nil
+	}
+	if err := saveJSONFile(accountsFile(), next); err != nil {
+		return Account{}, nil, err
+	}
+	s.accounts = next
+	return next[i], nil, nil
+}
+
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func checkPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err1 := base64.RawStdEncoding.DecodeString(parts[2])
+	expected, err2 := base64.RawStdEncoding.DecodeString(parts[3])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	return err == nil && subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// Хэш-заглушка, чтобы вход с несуществующим именем занимал столько же времени
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := hashPassword("loil-dummy-password")
+	return hash
+})
+
+// Поля JWT токена аккаунта
+type AccountClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+	SessionID string `json:"sid"`
+}
+
+// Заголовок JWT; kid указывает ключ подписи. Токены, выданные до
+// появления ротации, kid не содержат.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+func issueAccountToken(cfg *Config, account Account, sessionID string, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(cfg.AccountTokenTTL)
+	claims, err := json.Marshal(AccountClaims{
+		Issuer:    cfg.PublicURL,
+		Subject:   account.ID,
+		Name:      account.Username,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		ID:        randomID(16),
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	key := accountTokenKeys.Load().active
+	header, err := json.Marshal(jwtHeader{Alg: "EdDSA", Typ: "JWT", Kid: key.ID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature := ed25519.Sign(key.private, []byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), expiresAt, nil
+}
+
+func verifyAccountToken(token string, now time.Time) (AccountClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return AccountClaims{}, false
+	}
+	var header jwtHeader
+	if data, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(data, &header) != nil || header.Alg != "EdDSA" {
+		return AccountClaims{}, false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return AccountClaims{}, false
+	}
+	// Подходит любой еще действующий ключ; без kid перебираем все
+	if !slices.ContainsFunc(usableAccountTokenKeys(currentConfig(), now), func(key AccountTokenKey) bool {
+		return (header.Kid == "" || header.Kid == key.ID) &&
+			ed25519.Verify(key.private.Public().(ed25519.PublicKey), []byte(parts[0]+"."+parts[1]), signature)
+	}) {
+		return AccountClaims{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return AccountClaims{}, false
+	}
+	var claims AccountClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || now.Unix() >= claims.ExpiresAt {
+		return AccountClaims{}, false
+	}
+	return claims, true
+}
+
+// Аккаунт по заголовку Authorization: Bearer <token>. Токен принимается,
+// только пока его сессия не закрыта.
+func authenticateAccount(r *http.Request) (Account, bool) {
+	account, _, ok := accountFromToken(r)
+	return account, ok
+}
+
+func accountFromToken(r *http.Request) (Account, AccountClaims, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return Account{}, AccountClaims{}, false
+	}
+	now := time.Now()
+	claims, ok := verifyAccountToken(token, now)
+	if !ok || !accountSessions.Touch(claims.SessionID, claims.Subject, getClientIP(r), now.UTC()) {
+		return Account{}, AccountClaims{}, false
+	}
+	account, ok := accounts.ByID(claims.Subject)
+	return account, claims, ok
+}
+
+// Проверка входа для обработчиков аккаунта; при отказе ответ уже записан
+func requireAccount(w http.ResponseWriter, r *http.Request) (Account, bool) {
+	account, _, ok := requireAccountClaims(w, r)
+	return account, ok
+}
+
+// То же с полями токена — для обработчиков, которым нужна текущая сессия
+func requireAccountClaims(w http.ResponseWriter, r *http.Request) (Account, AccountClaims, bool) {
+	account, claims, ok := accountFromToken(r)
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, ErrCodeAccountUnauthorized)
+	}
+	return account, claims, ok
+}
+
+// Вход: новая сессия и токены для нее
+func (l *Logger) writeAccountToken(w http.ResponseWriter, r *http.Request, status int, account Account) {
+	response, apiErr := l.startAccountSession(r, account)
+	writeTokenResponse(w, r, status, response, apiErr)
+}
+
+func writeTokenResponse(w http.ResponseWriter, r *http.Request, status int, response AccountTokenResponse, apiErr *modError) {
+	if apiErr != nil {
+		writeModError(w, r, apiErr)
+		return
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Новая сессия; с забаненного железа не пускает ни в какой аккаунт
+func (l *Logger) startAccountSession(r *http.Request, account Account) (AccountTokenResponse, *modError) {
+	hwid, apiErr := l.checkHWID(r, account.Username)
+	if apiErr != nil {
+		return AccountTokenResponse{}, apiErr
+	}
+	now := time.Now().UTC()
+	session, refreshToken, err := accountSessions.Create(account.ID, r, currentConfig().AccountSessionTTL, now)
+	if err != nil {
+		l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+		return AccountTokenResponse{}, &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+	if hwid != nil {
+		if err := hwids.Record(account.ID, hwid, now); err != nil {
+			l.logError("Ошибка сохранения отпечатков железа: %v", err)
+		}
+	}
+	return l.sessionToken(account, session, refreshToken)
+}
+
+func (l *Logger) sessionToken(account Account, session AccountSession, refreshToken string) (AccountTokenResponse, *modError) {
+	token, expiresAt, err := issueAccountToken(currentConfig(), account, session.ID, time.Now().UTC())
+	if err != nil {
+		l.logError("Ошибка выпуска токена: %v", err)
+		return AccountTokenResponse{}, &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+	return AccountTokenResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		SessionID:        session.ID,
+		Account:          account.info(),
+	}, nil
+}
+
+// Регистрация аккаунта
+func (l *Logger) registerHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/auth/register", func() {
+		var req AccountCredentials
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		response, apiErr := l.registerAccount(r, req)
+		writeTokenResponse(w, r, http.StatusCreated, response, apiErr)
+	})
+}
+
+// Регистрация: новый аккаунт и первая сессия в нем
+func (l *Logger) registerAccount(r *http.Request, req AccountCredentials) (AccountTokenResponse, *modError) {
+	if !currentConfig().AccountRegistration || switchOn(SwitchRegistration) {
+		return AccountTokenResponse{}, &modError{http.StatusForbidden, ErrCodeRegistrationDisabled, nil}
+	}
+	if !accountNamePattern.MatchString(req.Username) {
+		return AccountTokenResponse{}, &modError{http.StatusBadRequest, ErrCodeInvalidRequest, nil}
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		return AccountTokenResponse{}, &modError{http.StatusBadRequest, ErrCodeWeakPassword, []interface{}{minPasswordLength}}
+	}
+	email, valid := normalizeEmail(req.Email)
+	if req.Email != "" && !valid {
+		return AccountTokenResponse{}, &modError{http.StatusBadRequest, ErrCodeInvalidEmail, nil}
+	}
+	if _, taken := accounts.ByEmail(email); email != "" && taken {
+		return AccountTokenResponse{}, &modError{http.StatusConflict, ErrCodeEmailInUse, nil}
+	}
+	// Забаненный по железу не заведет новый аккаунт вместо старого
+	if _, apiErr := l.checkHWID(r, req.Username); apiErr != nil {
+		return AccountTokenResponse{}, apiErr
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		l.logError("Ошибка хэширования пароля: %v", err)
+		return AccountTokenResponse{}, &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+	account := Account{
+		ID:           randomID(12),
+		Username:     req.Username,
+		PasswordHash: hash,
+		Email:        email,
+		CreatedAt:    time.Now().UTC(),
+	}
+	created, err := accounts.Create(account)
+	if !created {
+		return AccountTokenResponse{}, &modError{http.StatusConflict, ErrCodeAccountExists, []interface{}{req.Username}}
+	}
+	if err != nil {
+		l.logError("Ошибка сохранения аккаунтов: %v", err)
+		return AccountTokenResponse{}, &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+
+	if email != "" && currentConfig().SMTPHost != "" {
+		l.sendAccountEmail(r, emailTokenVerify, account)
+	}
+	response, apiErr := l.startAccountSession(r, account)
+	if apiErr == nil {
+		l.logSuccess("Зарегистрирован аккаунт %s (%s)", account.Username, account.ID)
+	}
+	return response, apiErr
+}
+
+// Вход по имени и паролю
+func (l *Logger) loginHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/auth/login", func() {
+		var req AccountCredentials
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		response, apiErr := l.loginAccount(r, req)
+		writeTokenResponse(w, r, http.StatusOK, response, apiErr)
+	})
+}
+
+// Вход: проверка блокировок, пароля и второго фактора, затем новая сессия
+func (l *Logger) loginAccount(r *http.Request, req AccountCredentials) (AccountTokenResponse, *modError) {
+	if req.Username == "" || len(req.Password) > maxPasswordLength {
+		return AccountTokenResponse{}, &modError{http.StatusBadRequest, ErrCodeInvalidRequest, nil}
+	}
+	if apiErr := l.checkLoginAttempt(r, req.Username, req.CaptchaToken); apiErr != nil {
+		return AccountTokenResponse{}, apiErr
+	}
+
+	account, found := accounts.ByUsername(req.Username)
+	hash := account.PasswordHash
+	if !found {
+		hash = dummyPasswordHash()
+	}
+	if !checkPassword(hash, req.Password) || !found {
+		l.logError("Неудачный вход в аккаунт %s с %s", req.Username, getClientIP(r))
+		recordLoginFailure(req.Username, r)
+		return AccountTokenResponse{}, &modError{http.StatusUnauthorized, ErrCodeInvalidCredentials, nil}
+	}
+	if account.TOTPEnabled {
+		if req.TOTPCode == "" {
+			return AccountTokenResponse{}, &modError{http.StatusUnauthorized, ErrCodeTOTPRequired, nil}
+		}
+		var apiErr *modError
+		if account, apiErr = l.checkAccountCode(r, account, req.TOTPCode); apiErr != nil {
+			return AccountTokenResponse{}, apiErr
+		}
+	}
+
+	accountSubject, _ := loginSubjects(req.Username, r)
+	loginGuard.Reset(accountSubject)
+	response, apiErr := l.startAccountSession(r, account)
+	if apiErr == nil {
+		l.logSuccess("Вход в аккаунт %s", account.Username)
+	}
+	return response, apiErr
+}
+
+// Текущий аккаунт по токену
+func (l *Logger) accountHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/account", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(account.info())
+	})
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Как часто сохранять время последней активности сессии
+	accountSessionTouchInterval = 5 * time.Minute
+	maxUserAgentLength          = 256
+)
+
+// Сессия входа в аккаунт — устройство, на котором выполнен вход. Токен
+// обновления хранится как SHA-256 и меняется при каждом обновлении;
+// предъявление прежнего токена означает его кражу и закрывает сессию.
+type AccountSession struct {
+	ID           string    `json:"id"`
+	AccountID    string    `json:"account_id"`
+	RefreshHash  string    `json:"refresh_hash"`
+	PreviousHash string    `json:"previous_hash,omitempty"`
+	IP           string    `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastSeen     time.Time `json:"last_seen"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Сессия в списке устройств аккаунта
+type AccountSessionInfo struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// Сессии аккаунтов в DATA_DIR/account_sessions.json
+type AccountSessionStore struct {
+	mu       sync.Mutex
+	sessions []AccountSession
+}
+
+var accountSessions = &AccountSessionStore{}
+
+func accountSessionsFile() string {
+	return filepath.Join(currentConfig().DataDir, "account_sessions.json")
+}
+
+func (s *AccountSessionStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(accountSessionsFile(), &s.sessions)
+}
+
+func hashRefreshToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Новая сессия; токен обновления — "<ID сессии>.<секрет>"
+func (s *AccountSessionStore) Create(accountID string, r *http.Request, ttl time.Duration, now time.Time) (AccountSession, string, error) {
+	secret := randomID(32)
+	session := AccountSession{
+		ID:          randomID(12),
+		AccountID:   accountID,
+		RefreshHash: hashRefreshToken(secret),
+		IP:          getClientIP(r),
+		UserAgent:   requestUserAgent(r),
+		CreatedAt:   now,
+		LastSeen:    now,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.replace(append(slices.Clone(s.sessions), session), now); err != nil {
+		return AccountSession{}, "", err
+	}
+	return session, session.ID + "." + secret, nil
+}
+
+// Обмен токена обновления на новый с продлением сессии. ok=false, если
+// токен неверен; при повторе уже замененного токена сессия закрывается.
+func (s *AccountSessionStore) Refresh(token string, r *http.Request, ttl time.Duration, now time.Time) (AccountSession, string, bool, error) {
+	id, secret, _ := strings.Cut(token, ".")
+	hash := hashRefreshToken(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.sessions, func(session AccountSession) bool { return session.ID == id })
+	if i < 0 || !now.Before(s.sessions[i].ExpiresAt) {
+		return AccountSession{}, "", false, nil
+	}
+	session := s.sessions[i]
+	if subtle.ConstantTimeCompare([]byte(session.PreviousHash), []byte(hash)) == 1 {
+		return AccountSession{}, "", false, s.replace(slices.Delete(slices.Clone(s.sessions), i, i+1), now)
+	}
+	if subtle.ConstantTimeCompare([]byte(session.RefreshHash), []byte(hash)) != 1 {
+		return AccountSession{}, "", false, nil
+	}
+
+	secret = randomID(32)
+	session.PreviousHash = session.RefreshHash
+	session.RefreshHash = hashRefreshToken(secret)
+	session.IP = getClientIP(r)
+	session.UserAgent = requestUserAgent(r)
+	session.LastSeen = now
+	session.ExpiresAt = now.Add(ttl)
+
+	next := slices.Clone(s.sessions)
+	next[i] = session
+	if err := s.replace(next, now); err != nil {
+		return AccountSession{}, "", false, err
+	}
+	return session, session.ID + "." + secret, true, nil
+}
+
+// Проверка, что сессия токена доступа не закрыта. Время активности
+// сохраняется не чаще accountSessionTouchInterval.
+func (s *AccountSessionStore) Touch(id, accountID, ip string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.sessions, func(session AccountSession) bool { return session.ID == id })
+	if i < 0 || s.sessions[i].AccountID != accountID || !now.Before(s.sessions[i].ExpiresAt) {
+		return false
+	}
+	if now.Sub(s.sessions[i].LastSeen) >= accountSessionTouchInterval {
+		next := slices.Clone(s.sessions)
+		next[i].LastSeen = now
+		next[i].IP = ip
+		s.replace(next, now)
+	}
+	return true
+}
+
+func (s *AccountSessionStore) List(accountID string, now time.Time) []AccountSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []AccountSession
+	for _, session := range s.sessions {
+		if session.AccountID == accountID && now.Before(session.ExpiresAt) {
+			result = append(result, session)
+		}
+	}
+	return result
+}
+
+// Закрытие сессий аккаунта, для которых match вернула true; число закрытых
+func (s *AccountSessionStore) Revoke(accountID string, match func(AccountSession) bool, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := 0
+	next := slices.DeleteFunc(slices.Clone(s.sessions), func(session AccountSession) bool {
+		if session.AccountID == accountID && match(session) {
+			revoked++
+			return true
+		}
+		return false
+	})
+	if revoked == 0 {
+		return 0, nil
+	}
+	return revoked, s.replace(next, now)
+}
+
+// Сохранение без истекших сессий (под блокировкой)
+func (s *AccountSessionStore) replace(next []AccountSession, now time.Time) error {
+	next = slices.DeleteFunc(next, func(session AccountSession) bool { return !now.Before(session.ExpiresAt) })
+	if err := saveJSONFile(accountSessionsFile(), next); err != nil {
+		return err
+	}
+	s.sessions = next
+	return nil
+}
+
+func requestUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+// Закрытие всех сессий аккаунта, например после смены пароля
+func (l *Logger) revokeAccountSessions(account Account) {
+	revoked, err := accountSessions.Revoke(account.ID, func(AccountSession) bool { return true }, time.Now())
+	if err != nil {
+		l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+		return
+	}
+	if revoked > 0 {
+		l.logSuccess("Закрыто сессий аккаунта %s: %d", account.Username, revoked)
+	}
+}
+
+// Новый токен доступа по токену обновления
+func (l *Logger) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/auth/refresh", func() {
+		var req RefreshTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		response, apiErr := l.refreshAccountSession(r, req.RefreshToken)
+		writeTokenResponse(w, r, http.StatusOK, response, apiErr)
+	})
+}
+
+func (l *Logger) refreshAccountSession(r *http.Request, refreshToken string) (AccountTokenResponse, *modError) {
+	if refreshToken == "" {
+		return AccountTokenResponse{}, &modError{http.StatusBadRequest, ErrCodeInvalidRequest, nil}
+	}
+	cfg := currentConfig()
+	now := time.Now().UTC()
+	session, refreshToken, ok, err := accountSessions.Refresh(refreshToken, r, cfg.AccountSessionTTL, now)
+	if err != nil {
+		l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+		return AccountTokenResponse{}, &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+	account, found := accounts.ByID(session.AccountID)
+	if !ok || !found {
+		l.logError("Неверный токен обновления с %s", getClientIP(r))
+		return AccountTokenResponse{}, &modError{http.StatusUnauthorized, ErrCodeAccountUnauthorized, nil}
+	}
+	return l.sessionToken(account, session, refreshToken)
+}
+
+// Выход: закрытие сессии текущего токена
+func (l *Logger) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/auth/logout", func() {
+		if apiErr := l.logoutAccount(r); apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (l *Logger) logoutAccount(r *http.Request) *modError {
+	account, claims, ok := accountFromToken(r)
+	if !ok {
+		return &modError{http.StatusUnauthorized, ErrCodeAccountUnauthorized, nil}
+	}
+	if _, err := accountSessions.Revoke(account.ID, func(session AccountSession) bool {
+		return session.ID == claims.SessionID
+	}, time.Now()); err != nil {
+		l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+		return &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+	l.logSuccess("Выход из аккаунта %s", account.Username)
+	return nil
+}
+
+// Устройства, на которых выполнен вход
+func (l *Logger) accountSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/account/sessions", func() {
+		account, claims, ok := requireAccountClaims(w, r)
+		if !ok {
+			return
+		}
+		result := []AccountSessionInfo{}
+		for _, session := range accountSessions.List(account.ID, time.Now()) {
+			result = append(result, AccountSessionInfo{
+				ID:        session.ID,
+				IP:        session.IP,
+				UserAgent: session.UserAgent,
+				CreatedAt: session.CreatedAt,
+				LastSeen:  session.LastSeen,
+				ExpiresAt: session.ExpiresAt,
+				Current:   session.ID == claims.SessionID,
+			})
+		}
+		json.NewEncoder(w).Encode(result)
+	})
+}
+
+// Закрытие одной сессии
+func (l *Logger) revokeAccountSessionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/account/sessions/{id}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		id := r.PathValue("id")
+		revoked, err := accountSessions.Revoke(account.ID, func(session AccountSession) bool { return session.ID == id }, time.Now())
+		if err != nil {
+			l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if revoked == 0 {
+			writeError(w, r, http.StatusNotFound, ErrCodeAccountSessionNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Аккаунт %s закрыл сессию %s", account.Username, id)
+	})
+}
+
+// Закрытие всех сессий, кроме текущей; с ?all=true — и текущей тоже
+func (l *Logger) revokeAccountSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👤", "/api/account/sessions", func() {
+		account, claims, ok := requireAccountClaims(w, r)
+		if !ok {
+			return
+		}
+		all := r.URL.Query().Get("all") == "true"
+		revoked, err := accountSessions.Revoke(account.ID, func(session AccountSession) bool {
+			return all || session.ID != claims.SessionID
+		}, time.Now())
+		if err != nil {
+			l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		json.NewEncoder(w).Encode(RevokeSessionsResponse{Revoked: revoked})
+		l.logSuccess("Аккаунт %s закрыл сессии: %d", account.Username, revoked)
+	})
+}
@@ -0,0 +1,570 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxAchievementName        = 100
+	maxAchievementDescription = 500
+	// Сторона квадратной иконки
+	achievementIconSize = 128
+)
+
+// Достижение, заданное в админке. Игровой сервер сообщает о прогрессе
+// игрока; достижение открывается, когда прогресс доходит до Goal.
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Скрытое не показывается в списке, пока игрок его не откроет
+	Hidden bool `json:"hidden,omitempty"`
+	Points int  `json:"points,omitempty"`
+	// Сколько нужно набрать (убить 100 мобов); 1 — открывается сразу
+	Goal          int        `json:"goal"`
+	IconUpdatedAt *time.Time `json:"icon_updated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type AchievementRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Hidden      bool   `json:"hidden"`
+	Points      int    `json:"points"`
+	Goal        int    `json:"goal"`
+}
+
+// Достижение для лаунчера
+type AchievementInfo struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Hidden      bool   `json:"hidden,omitempty"`
+	Points      int    `json:"points,omitempty"`
+	Goal        int    `json:"goal"`
+	IconURL     string `json:"icon_url,omitempty"`
+	// Доля игроков с прогрессом, открывших достижение
+	UnlockedPercent float64 `json:"unlocked_percent"`
+	// Только в прогрессе игрока
+	Progress   *int       `json:"progress,omitempty"`
+	UnlockedAt *time.Time `json:"unlocked_at,omitempty"`
+}
+
+type AchievementsResponse struct {
+	Achievements []AchievementInfo `json:"achievements"`
+}
+
+// Прогресс игрока в достижении
+type AchievementProgress struct {
+	Progress   int        `json:"progress"`
+	UnlockedAt *time.Time `json:"unlocked_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Запрос игрового сервера. Без progress достижение открывается сразу;
+// progress — набранное значение, а не прибавка, поэтому повтор запроса
+// после сбоя сети ничего не испортит.
+type AchievementUnlockRequest struct {
+	Player      string `json:"player"`
+	Achievement string `json:"achievement"`
+	Progress    *int   `json:"progress"`
+}
+
+type AchievementUnlockResponse struct {
+	Player      string `json:"player"`
+	Achievement string `json:"achievement"`
+	AchievementProgress
+	// Открыто этим запросом
+	Unlocked bool `json:"unlocked"`
+}
+
+// Открытое достижение в вебхуке
+type AchievementUnlockedEvent struct {
+	Player      string    `json:"player"`
+	Achievement string    `json:"achievement"`
+	Name        string    `json:"name"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+}
+
+// Определения достижений в DATA_DIR/achievements.json, иконки в
+// DATA_DIR/achievements/, прогресс игроков в DATA_DIR/achievement_progress.json
+type AchievementStore struct {
+	*jsonListStore[Achievement]
+	// Игрок (имя в нижнем регистре) → достижение → прогресс
+	progress map[string]map[string]AchievementProgress
+}
+
+var achievements = &AchievementStore{jsonListStore: &jsonListStore[Achievement]{file: achievementsFile}}
+
+func achievementsFile() string {
+	return filepath.Join(currentConfig().DataDir, "achievements.json")
+}
+
+func achievementProgressFile() string {
+	return filepath.Join(currentConfig().DataDir, "achievement_progress.json")
+}
+
+func achievementIconPath(id string) string {
+	return filepath.Join(currentConfig().DataDir, "achievements", id+".jpg")
+}
+
+// Имена игроков уникальны без учета регистра, как имена аккаунтов
+func achievementPlayer(name string) string {
+	return strings.ToLower(name)
+}
+
+func (s *AchievementStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := loadJSONFile(s.file(), &s.items); err != nil {
+		return err
+	}
+	s.progress = make(map[string]map[string]AchievementProgress)
+	return loadJSONFile(achievementProgressFile(), &s.progress)
+}
+
+func (s *AchievementStore) Get(id string) (Achievement, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := findAchievement(s.items, id); i >= 0 {
+		return s.items[i], true
+	}
+	return Achievement{}, false
+}
+
+func findAchievement(list []Achievement, id string) int {
+	return slices.IndexFunc(list, func(a Achievement) bool { return a.ID == id })
+}
+
+// Изменение прогресса под блокировкой: fn меняет копию
+func (s *AchievementStore) updateProgress(fn func(progress map[string]map[string]AchievementProgress) bool) error {
+	next := make(map[string]map[string]AchievementProgress, len(s.progress))
+	for player, entries := range s.progress {
+		next[player] = maps.Clone(entries)
+	}
+	if !fn(next) {
+		return nil
+	}
+	if err := saveJSONFile(achievementProgressFile(), next); err != nil {
+		return err
+	}
+	s.progress = next
+	return nil
+}
+
+// Прогресс игрока; достижение открывается, когда прогресс доходит до цели.
+// Прогресс не уменьшается, открытое достижение остается открытым.
+func (s *AchievementStore) Report(player, id string, progress *int, now time.Time) (Achievement, AchievementUnlockResponse, *modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := findAchievement(s.items, id)
+	if i < 0 {
+		return Achievement{}, AchievementUnlockResponse{}, &modError{http.StatusNotFound, ErrCodeAchievementNotFound, []interface{}{id}}, nil
+	}
+	achievement := s.items[i]
+	key := achievementPlayer(player)
+	response := AchievementUnlockResponse{Player: player, Achievement: id}
+	err := s.updateProgress(func(all map[string]map[string]AchievementProgress) bool {
+		current := all[key][id]
+		value := achievement.Goal
+		if progress != nil {
+			value = min(*progress, achievement.Goal)
+		}
+		if value <= current.Progress && (current.UnlockedAt != nil || current.Progress < achievement.Goal) {
+			response.AchievementProgress = current
+			return false
+		}
+
+		current.Progress = max(current.Progress, value)
+		current.UpdatedAt = now
+		if current.UnlockedAt == nil && current.Progress >= achievement.Goal {
+			current.UnlockedAt = &now
+			response.Unlocked = true
+		}
+		if all[key] == nil {
+			all[key] = make(map[string]AchievementProgress)
+		}
+		all[key][id] = current
+		response.AchievementProgress = current
+		return true
+	})
+	return achievement, response, nil, err
+}
+
+// Прогресс игрока по всем достижениям
+func (s *AchievementStore) Progress(player string) map[string]AchievementProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.progress[achievementPlayer(player)])
+}
+
+// Сколько игроков открыли каждое достижение и сколько игроков с прогрессом
+func (s *AchievementStore) unlockCounts() (map[string]int, int) {
+	counts := make(map[string]int)
+	for _, entries := range s.progress {
+		for id, entry := range entries {
+			if entry.UnlockedAt != nil {
+				counts[id]++
+			}
+		}
+	}
+	return counts, len(s.progress)
+}
+
+// Достижения для лаунчера; с player — с прогрессом игрока, а скрытые
+// показываются, только если игрок их открыл
+func (s *AchievementStore) Infos(cfg *Config, player string) []AchievementInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, players := s.unlockCounts()
+	progress := s.progress[achievementPlayer(player)]
+	infos := []AchievementInfo{}
+	for _, achievement := range s.items {
+		entry, ok := progress[achievement.ID]
+		if achievement.Hidden && (player == "" || entry.UnlockedAt == nil) {
+			continue
+		}
+		info := achievement.info(cfg)
+		if players > 0 {
+			info.UnlockedPercent = float64(counts[achievement.ID]) * 100 / float64(players)
+		}
+		if player != "" {
+			value := 0
+			if ok {
+				value = entry.Progress
+			}
+			info.Progress, info.UnlockedAt = &value, entry.UnlockedAt
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Удаление прогресса игрока в достижении (или во всех, если id пуст)
+func (s *AchievementStore) Revoke(player, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := achievementPlayer(player)
+	found := false
+	err := s.updateProgress(func(all map[string]map[string]AchievementProgress) bool {
+		for achievement := range all[key] {
+			if id == "" || achievement == id {
+				delete(all[key], achievement)
+				found = true
+			}
+		}
+		if len(all[key]) == 0 {
+			delete(all, key)
+		}
+		return found
+	})
+	return found, err
+}
+
+// Удаление определения вместе с прогрессом игроков и иконкой
+func (s *AchievementStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := findAchievement(s.items, id)
+	if i < 0 {
+		return false, nil
+	}
+	if err := s.save(slices.Delete(slices.Clone(s.items), i, i+1)); err != nil {
+		return false, err
+	}
+	err := s.updateProgress(func(all map[string]map[string]AchievementProgress) bool {
+		changed := false
+		for player, entries := range all {
+			if _, ok := entries[id]; ok {
+				delete(entries, id)
+				changed = true
+			}
+			if len(entries) == 0 {
+				delete(all, player)
+			}
+		}
+		return changed
+	})
+	if err != nil {
+		return true, err
+	}
+	if err := os.Remove(achievementIconPath(id)); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+func (a Achievement) info(cfg *Config) AchievementInfo {
+	info := AchievementInfo{
+		ID:          a.ID,
+		Name:        a.Name,
+		Description: a.Description,
+		Hidden:      a.Hidden,
+		Points:      a.Points,
+		Goal:        a.Goal,
+	}
+	if a.IconUpdatedAt != nil {
+		info.IconURL = cfg.PublicURL + "/api/achievements/" + a.ID + "/icon?v=" + a.IconUpdatedAt.Format("20060102150405")
+	}
+	return info
+}
+
+func (req AchievementRequest) valid() bool {
+	return req.Name != "" && utf8.RuneCountInString(req.Name) <= maxAchievementName &&
+		utf8.RuneCountInString(req.Description) <= maxAchievementDescription && req.Points >= 0 && req.Goal >= 0
+}
+
+// Список достижений: /api/achievements
+func (l *Logger) achievementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🏆", "/api/achievements", func() {
+		json.NewEncoder(w).Encode(AchievementsResponse{Achievements: achievements.Infos(currentConfig(), "")})
+	})
+}
+
+// Прогресс игрока для экрана достижений в лаунчере
+func (l *Logger) accountAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🏆", "/api/account/achievements", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(AchievementsResponse{Achievements: achievements.Infos(currentConfig(), account.Username)})
+	})
+}
+
+func (l *Logger) achievementIconHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🏆", "/api/achievements/{id}/icon", func() {
+		id := r.PathValue("id")
+		achievement, ok := achievements.Get(id)
+		if !ok || achievement.IconUpdatedAt == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeAchievementNotFound, id)
+			return
+		}
+		file, err := os.Open(achievementIconPath(id))
+		if err != nil {
+			l.logError("Ошибка открытия иконки достижения %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		http.ServeContent(w, r, "", *achievement.IconUpdatedAt, file)
+	})
+}
+
+// Прогресс от игрового сервера: POST /api/achievements/unlock с
+// Authorization: Bearer GAME_SERVER_SECRET
+func (l *Logger) achievementUnlockHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🏆", "/api/achievements/unlock", func() {
+		if !requireGameServerSecret(w, r) {
+			return
+		}
+		var req AchievementUnlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !playerNamePattern.MatchString(req.Player) ||
+			req.Achievement == "" || (req.Progress != nil && *req.Progress < 0) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		achievement, response, apiErr, err := achievements.Report(req.Player, req.Achievement, req.Progress, time.Now().UTC())
+		if err != nil {
+			l.logError("Ошибка сохранения прогресса достижений: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		if response.Unlocked {
+			l.dispatchWebhook(WebhookAchievementUnlocked, AchievementUnlockedEvent{
+				Player:      req.Player,
+				Achievement: achievement.ID,
+				Name:        achievement.Name,
+				UnlockedAt:  *response.UnlockedAt,
+			})
+			l.logSuccess("Игрок %s открыл достижение «%s»", req.Player, achievement.Name)
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Достижение в админке: сколько игроков его открыли
+type AdminAchievement struct {
+	Achievement
+	Unlocked int `json:"unlocked"`
+}
+
+type AdminAchievementsResponse struct {
+	Achievements []AdminAchievement `json:"achievements"`
+	// Игроков с каким-либо прогрессом
+	Players int `json:"players"`
+}
+
+func (s *AchievementStore) Admin() AdminAchievementsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, players := s.unlockCounts()
+	response := AdminAchievementsResponse{Achievements: []AdminAchievement{}, Players: players}
+	for _, achievement := range s.items {
+		response.Achievements = append(response.Achievements, AdminAchievement{Achievement: achievement, Unlocked: counts[achievement.ID]})
+	}
+	sort.Slice(response.Achievements, func(i, j int) bool { return response.Achievements[i].ID < response.Achievements[j].ID })
+	return response
+}
+
+func (l *Logger) adminListAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🏆", "/admin/api/achievements", func() {
+		json.NewEncoder(w).Encode(achievements.Admin())
+	})
+}
+
+// Создание или изменение достижения
+func (l *Logger) adminPutAchievementHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🏆", "/admin/api/achievements/{id}", func() {
+		id := r.PathValue("id")
+		var req AchievementRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !modIDPattern.MatchString(id) || !req.valid() {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if req.Goal == 0 {
+			req.Goal = 1
+		}
+
+		now := time.Now().UTC()
+		var saved Achievement
+		apiErr, err := achievements.update(func(list []Achievement) ([]Achievement, *modError) {
+			saved = Achievement{ID: id, Name: req.Name, Description: req.Description, Hidden: req.Hidden, Points: req.Points, Goal: req.Goal, CreatedAt: now, UpdatedAt: now}
+			if i := findAchievement(list, id); i >= 0 {
+				saved.CreatedAt, saved.IconUpdatedAt = list[i].CreatedAt, list[i].IconUpdatedAt
+				list[i] = saved
+				return list, nil
+			}
+			return append(list, saved), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(saved)
+		l.logSuccess("Достижение %s «%s» сохранено", id, req.Name)
+	})
+}
+
+func (l *Logger) adminDeleteAchievementHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🏆", "/admin/api/achievements/{id}", func() {
+		id := r.PathValue("id")
+		found, err := achievements.Delete(id)
+		if err != nil {
+			l.logError("Ошибка удаления достижения %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeAchievementNotFound, id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Достижение %s удалено вместе с прогрессом игроков", id)
+	})
+}
+
+// Иконка достижения: тело — PNG или JPEG, обрезается до квадрата
+func (l *Logger) adminAchievementIconHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🏆", "/admin/api/achievements/{id}/icon", func() {
+		id := r.PathValue("id")
+		if _, ok := achievements.Get(id); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeAchievementNotFound, id)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(currentConfig().AvatarMaxBytes)))
+		if err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		img, err := decodeScreenshot(data)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidImage)
+			return
+		}
+
+		path := achievementIconPath(id)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			l.logError("Ошибка создания каталога иконок достижений: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		if err := writeJPEG(path, avatarImage(img, achievementIconSize), 90); err != nil {
+			l.logError("Ошибка сохранения иконки достижения %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+
+		var saved Achievement
+		apiErr, err := achievements.update(func(list []Achievement) ([]Achievement, *modError) {
+			i := findAchievement(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeAchievementNotFound, []interface{}{id}}
+			}
+			now := time.Now().UTC()
+			list[i].IconUpdatedAt = &now
+			saved = list[i]
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		json.NewEncoder(w).Encode(saved)
+		l.logSuccess("Иконка достижения %s обновлена", id)
+	})
+}
+
+// Прогресс игрока по всем достижениям, включая скрытые
+func (l *Logger) adminPlayerAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🏆", "/admin/api/achievements/players/{player}", func() {
+		progress := achievements.Progress(r.PathValue("player"))
+		if progress == nil {
+			progress = make(map[string]AchievementProgress)
+		}
+		json.NewEncoder(w).Encode(progress)
+	})
+}
+
+// Сброс прогресса игрока: ?achievement=id — только в одном достижении
+func (l *Logger) adminRevokeAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🏆", "/admin/api/achievements/players/{player}", func() {
+		player, id := r.PathValue("player"), r.URL.Query().Get("achievement")
+		found, err := achievements.Revoke(player, id)
+		if err != nil {
+			l.logError("Ошибка сохранения прогресса достижений: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeAchievementNotFound, id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Прогресс достижений игрока %s сброшен", player)
+	})
+}
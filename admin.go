@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Запрос на изменение статуса публикации новости
+type NewsStatusRequest struct {
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Проверка ключа и прав доступа, аудит и логирование админских запросов
+func (l *Logger) handleAdmin(w http.ResponseWriter, r *http.Request, scope, emoji, endpoint string, handler func()) {
+	l.handleAdminKey(w, r, scope, true, emoji, endpoint, func(AdminKey) { handler() })
+}
+
+// Общая часть админских обработчиков. Пустой scope — доступно любому ключу;
+// secondFactor=false только для подключения 2FA и входа по коду.
+func (l *Logger) handleAdminKey(w http.ResponseWriter, r *http.Request, scope string, secondFactor bool, emoji, endpoint string, handler func(key AdminKey)) {
+	clientIP := getClientIP(r)
+
+	key, ok := authenticateAdmin(r)
+	if !ok {
+		l.logError("Отказано в доступе к %s для %s", endpoint, clientIP)
+		writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized)
+		return
+	}
+	if secondFactor && !checkAdminSecondFactor(w, r, key, scope) {
+		l.logError("Ключ %s «%s» без 2FA пытался вызвать %s", key.ID, key.Name, endpoint)
+		l.logAudit(r, key, endpoint, false)
+		return
+	}
+	if scope != "" && !key.HasScope(scope) {
+		l.logError("Ключ %s «%s» без права %s пытался вызвать %s", key.ID, key.Name, scope, endpoint)
+		l.logAudit(r, key, endpoint, false)
+		writeError(w, r, http.StatusForbidden, ErrCodeForbidden, scope)
+		return
+	}
+
+	l.Printf("%s Админ-запрос %s %s от %s (ключ %s)", emoji, r.Method, endpoint, clientIP, key.Name)
+	l.logAudit(r, key, endpoint, true)
+	w.Header().Set("Content-Type", "application/json")
+
+	handler(key)
+
+	l.logAccess(clientIP, r.Method+" "+endpoint, emoji)
+}
+
+// Список всех новостей, включая черновики
+func (l *Logger) adminListNewsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "🛠️", "/admin/api/news", func() {
+		news, err := loadNews()
+		if err != nil {
+			l.logError("Ошибка загрузки новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(NewsResponse{News: news})
+	})
+}
+
+// Создание новости; по умолчанию создается черновик
+func (l *Logger) adminCreateNewsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "🛠️", "/admin/api/news", func() {
+		var item NewsItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil || item.Title == "" || !item.normalizeTaxonomy() {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if item.Status == "" {
+			item.Status = NewsStatusDraft
+		}
+		if !validNewsStatus(item.Status) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidStatus)
+			return
+		}
+
+		err := updateNews(func(news []NewsItem) ([]NewsItem, bool, error) {
+			item.ID = 1
+			for _, existing := range news {
+				if existing.ID >= item.ID {
+					item.ID = existing.ID + 1
+				}
+			}
+			return append(news, item), true, nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsSave)
+			return
+		}
+
+		l.dispatchWebhook(WebhookNewsCreated, item)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(item)
+		l.logSuccess("Создана новость #%d «%s» (%s)", item.ID, item.Title, item.Status)
+	})
+}
+
+// Полное обновление новости
+func (l *Logger) adminUpdateNewsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "🛠️", "/admin/api/news/{id}", func() {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		var item NewsItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil || item.Title == "" || !item.normalizeTaxonomy() {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if item.Status != "" && !validNewsStatus(item.Status) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidStatus)
+			return
+		}
+		item.ID = id
+
+		l.modifyNewsItem(w, r, id, func(existing *NewsItem) {
+			if item.Status == "" {
+				item.Status = existing.Status
+			}
+			*existing = item
+		})
+	})
+}
+
+// Смена статуса публикации: черновик, запланирована, опубликована, снята
+func (l *Logger) adminNewsStatusHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "🗓️", "/admin/api/news/{id}/status", func() {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		var req NewsStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !validNewsStatus(req.Status) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidStatus)
+			return
+		}
+		if req.Status == NewsStatusScheduled && req.PublishAt == nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodePublishAtRequired)
+			return
+		}
+
+		l.modifyNewsItem(w, r, id, func(item *NewsItem) {
+			item.Status = req.Status
+			item.PublishAt = req.PublishAt
+			item.ExpiresAt = req.ExpiresAt
+			if req.Status == NewsStatusPublished && item.Date == "" {
+				item.Date = time.Now().Format("2006-01-02")
+			}
+		})
+	})
+}
+
+// Удаление новости
+func (l *Logger) adminDeleteNewsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "🗑️", "/admin/api/news/{id}", func() {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		found := false
+		err = updateNews(func(news []NewsItem) ([]NewsItem, bool, error) {
+			for i := range news {
+				if news[i].ID == id {
+					found = true
+					return append(news[:i], news[i+1:]...), true, nil
+				}
+			}
+			return news, false, nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsSave)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeNewsNotFound)
+			return
+		}
+
+		if err := newsEngagement.Delete(id); err != nil {
+			l.logError("Ошибка удаления счетчиков новости #%d: %v", id, err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Удалена новость #%d", id)
+	})
+}
+
+// Изменение одной новости с сохранением и ответом клиенту
+func (l *Logger) modifyNewsItem(w http.ResponseWriter, r *http.Request, id int, modify func(item *NewsItem)) {
+	var updated *NewsItem
+	err := updateNews(func(news []NewsItem) ([]NewsItem, bool, error) {
+		for i := range news {
+			if news[i].ID == id {
+				modify(&news[i])
+				updated = &news[i]
+				return news, true, nil
+			}
+		}
+		return news, false, nil
+	})
+	if err != nil {
+		l.logError("Ошибка сохранения новостей: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeNewsSave)
+		return
+	}
+	if updated == nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeNewsNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(updated)
+	l.logSuccess("Обновлена новость #%d «%s» (%s)", updated.ID, updated.Title, updated.Status)
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Незавершенная загрузка по частям живет сутки после последней части
+const adminUploadTTL = 24 * time.Hour
+
+// Загрузка по частям для админского API: большая сборка не упирается в
+// таймауты одного запроса и докачивается после обрыва. Клиент начинает
+// загрузку с размером и SHA-256 файла, шлет части
+// PUT /admin/api/uploads/{id}?offset=N (часть с X-Chunk-SHA256 сверяется),
+// завершает POST /admin/api/uploads/{id}/complete и затем вызывает обычный
+// маршрут загрузки (сборки, релиза, мода...) с заголовком X-Upload-ID
+// вместо тела.
+type AdminUpload struct {
+	ID string `json:"id"`
+	// Имя файла для справки в списке загрузок
+	Filename  string    `json:"filename,omitempty"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Received  int64     `json:"received"`
+	Complete  bool      `json:"complete"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AdminUploadRequest struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+type AdminUploadResponse struct {
+	AdminUpload
+	ChunkSize int `json:"chunk_size"`
+}
+
+type AdminUploadsResponse struct {
+	Uploads []AdminUpload `json:"uploads"`
+}
+
+type AdminUploadStore struct {
+	*jsonListStore[AdminUpload]
+}
+
+var adminUploads = &AdminUploadStore{&jsonListStore[AdminUpload]{file: adminUploadsFile}}
+
+// Блокировки по загрузкам: части одной загрузки пишутся по очереди.
+// Блокировка удаляется вместе с загрузкой (завершение, отмена, истечение
+// срока), чтобы карта не росла все время работы сервера.
+var (
+	adminUploadLocksMu sync.Mutex
+	adminUploadLocks   = make(map[string]*sync.Mutex)
+)
+
+func lockAdminUpload(id string) func() {
+	adminUploadLocksMu.Lock()
+	lock, ok := adminUploadLocks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		adminUploadLocks[id] = lock
+	}
+	adminUploadLocksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		// Загрузку удалили под этой блокировкой или ее и не было
+		if _, ok := adminUploads.Get(id); !ok {
+			forgetAdminUploadLock(id, lock)
+		}
+		lock.Unlock()
+	}
+}
+
+// Удаление блокировки загрузки, если ее не заменили новой; nil — любой
+func forgetAdminUploadLock(id string, lock *sync.Mutex) {
+	adminUploadLocksMu.Lock()
+	defer adminUploadLocksMu.Unlock()
+	if current, ok := adminUploadLocks[id]; ok && (lock == nil || current == lock) {
+		delete(adminUploadLocks, id)
+	}
+}
+
+func adminUploadsFile() string {
+	return filepath.Join(currentConfig().DataDir, "admin_uploads.json")
+}
+
+func adminUploadsDir() string {
+	return filepath.Join(currentConfig().DataDir, "admin_uploads")
+}
+
+func adminUploadPath(id string) string {
+	return filepath.Join(adminUploadsDir(), id+".part")
+}
+
+func (s *AdminUploadStore) Get(id string) (AdminUpload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := findAdminUpload(s.items, id)
+	if i < 0 {
+		return AdminUpload{}, false
+	}
+	return s.items[i], true
+}
+
+func findAdminUpload(list []AdminUpload, id string) int {
+	return slices.IndexFunc(list, func(u AdminUpload) bool { return u.ID == id })
+}
+
+// Удаление брошенных загрузок вместе с принятыми частями
+func (s *AdminUploadStore) expire(now time.Time) error {
+	var expired []AdminUpload
+	_, err := s.update(func(list []AdminUpload) ([]AdminUpload, *modError) {
+		return slices.DeleteFunc(list, func(u AdminUpload) bool {
+			if now.Sub(u.UpdatedAt) < adminUploadTTL {
+				return false
+			}
+			expired = append(expired, u)
+			return true
+		}), nil
+	})
+	if err == nil {
+		for _, upload := range expired {
+			os.Remove(adminUploadPath(upload.ID))
+			forgetAdminUploadLock(upload.ID, nil)
+		}
+	}
+	return err
+}
+
+// Загрузка с X-Upload-ID для receiveUpload: готовый файл переносится на
+// место временного без копирования, если каталоги на одном разделе.
+// После переноса загрузка израсходована, ее удаляет вызывающий.
+func takeAdminUpload(upload AdminUpload, tmp string) error {
+	if err := os.Rename(adminUploadPath(upload.ID), tmp); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(adminUploadPath(upload.ID))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Завершенная загрузка для X-Upload-ID; при отказе ответ уже записан
+func completedAdminUpload(w http.ResponseWriter, r *http.Request, id string) (AdminUpload, bool) {
+	upload, ok := adminUploads.Get(id)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+		return AdminUpload{}, false
+	}
+	if !upload.Complete {
+		writeError(w, r, http.StatusConflict, ErrCodeUploadIncomplete, upload.Received)
+		return AdminUpload{}, false
+	}
+	return upload, true
+}
+
+func removeAdminUpload(id string) error {
+	_, err := adminUploads.update(func(list []AdminUpload) ([]AdminUpload, *modError) {
+		if i := findAdminUpload(list, id); i >= 0 {
+			list = slices.Delete(list, i, i+1)
+		}
+		return list, nil
+	})
+	os.Remove(adminUploadPath(id))
+	return err
+}
+
+func (l *Logger) adminListUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", true, "📤", "/admin/api/uploads", func(AdminKey) {
+		json.NewEncoder(w).Encode(AdminUploadsResponse{Uploads: adminUploads.List()})
+	})
+}
+
+// Начало загрузки: размер и SHA-256 файла известны заранее. Права на
+// саму загрузку проверяет маршрут, которому файл передается в конце.
+func (l *Logger) adminStartUploadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", true, "📤", "/admin/api/uploads", func(AdminKey) {
+		var req AdminUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		cfg := currentConfig()
+		if _, err := hex.DecodeString(req.SHA256); err != nil || len(req.SHA256) != 64 || req.Size <= 0 ||
+			cfg.MaxUploadBytes > 0 && req.Size > int64(cfg.MaxUploadBytes) ||
+			req.Filename != "" && !uploadFilenamePattern.MatchString(req.Filename) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		now := time.Now().UTC()
+		if err := adminUploads.expire(now); err != nil {
+			l.logError("Ошибка сохранения загрузок по частям: %v", err)
+		}
+		if err := os.MkdirAll(adminUploadsDir(), 0755); err != nil {
+			l.logError("Ошибка создания каталога загрузок: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		// Место проверяется сразу под весь файл, а не под тело запроса
+		r.ContentLength = req.Size
+		if l.rejectLowDisk(w, r, adminUploadsDir()) {
+			return
+		}
+
+		upload := AdminUpload{
+			ID:        randomID(12),
+			Filename:  req.Filename,
+			Size:      req.Size,
+			SHA256:    strings.ToLower(req.SHA256),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		apiErr, err := adminUploads.update(func(list []AdminUpload) ([]AdminUpload, *modError) {
+			return append(list, upload), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(AdminUploadResponse{AdminUpload: upload, ChunkSize: cfg.AdminUploadChunkSize})
+		l.logSuccess("Начата загрузка по частям %s: %s (%d bytes)", upload.ID, upload.Filename, upload.Size)
+	})
+}
+
+// Состояние загрузки: с какого смещения продолжать после обрыва
+func (l *Logger) adminUploadStatusHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", true, "📤", "/admin/api/uploads/{id}", func(AdminKey) {
+		upload, ok := adminUploads.Get(r.PathValue("id"))
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(AdminUploadResponse{AdminUpload: upload, ChunkSize: currentConfig().AdminUploadChunkSize})
+	})
+}
+
+// Часть файла: смещение должно совпадать с уже принятым объемом, повтор
+// последней части безопасен. Часть с X-Chunk-SHA256 при несовпадении
+// хэша не принимается, и ее можно отправить заново.
+func (l *Logger) adminUploadChunkHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", true, "📤", "/admin/api/uploads/{id}", func(AdminKey) {
+		id := r.PathValue("id")
+		offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		expected := strings.ToLower(r.Header.Get("X-Chunk-SHA256"))
+
+		unlock := lockAdminUpload(id)
+		defer unlock()
+		upload, ok := adminUploads.Get(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+			return
+		}
+		if offset > upload.Received || upload.Complete {
+			writeError(w, r, http.StatusConflict, ErrCodeUploadOffsetMismatch, upload.Received)
+			return
+		}
+
+		cfg := currentConfig()
+		file, err := os.OpenFile(adminUploadPath(id), os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			l.logError("Ошибка открытия загрузки %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		// Лишнее после offset отбрасывается: часть могла дойти не целиком
+		file.Truncate(offset)
+		file.Seek(offset, io.SeekStart)
+		hash := sha256.New()
+		limit := min(int64(cfg.AdminUploadChunkSize), upload.Size-offset)
+		written, err := io.Copy(io.MultiWriter(file, hash), http.MaxBytesReader(w, r.Body, limit))
+		mismatch := err == nil && expected != "" && hex.EncodeToString(hash.Sum(nil)) != expected
+		if mismatch || err != nil {
+			// Часть принимается целиком или никак
+			file.Truncate(offset)
+			written = 0
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		apiErr, saveErr := adminUploads.update(func(list []AdminUpload) ([]AdminUpload, *modError) {
+			i := findAdminUpload(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeUploadNotFound, nil}
+			}
+			list[i].Received = offset + written
+			list[i].UpdatedAt = time.Now().UTC()
+			upload = list[i]
+			return list, nil
+		})
+		if err != nil {
+			l.logError("Ошибка приема части загрузки %s: %v", id, err)
+			writeBodyError(w, r, err, ErrCodeUploadFailed)
+			return
+		}
+		if mismatch {
+			writeError(w, r, http.StatusBadRequest, ErrCodeHashMismatch)
+			return
+		}
+		if !l.writeModUpdateResult(w, r, apiErr, saveErr) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(AdminUploadResponse{AdminUpload: upload, ChunkSize: cfg.AdminUploadChunkSize})
+	})
+}
+
+// Завершение: все части приняты, SHA-256 файла совпадает с объявленным
+func (l *Logger) adminCompleteUploadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", true, "📤", "/admin/api/uploads/{id}/complete", func(AdminKey) {
+		id := r.PathValue("id")
+		unlock := lockAdminUpload(id)
+		defer unlock()
+		upload, ok := adminUploads.Get(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+			return
+		}
+		if upload.Received != upload.Size {
+			writeError(w, r, http.StatusConflict, ErrCodeUploadOffsetMismatch, upload.Received)
+			return
+		}
+
+		sum, err := fileSHA256(adminUploadPath(id))
+		if err != nil {
+			l.logError("Ошибка чтения загрузки %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		if sum != upload.SHA256 {
+			// Испорченный файл докачкой не исправить: загрузка начинается заново
+			if err := removeAdminUpload(id); err != nil {
+				l.logError("Ошибка сохранения загрузок по частям: %v", err)
+			}
+			l.logError("Хэш загрузки по частям %s не совпал: ожидали %s, получили %s", id, upload.SHA256, sum)
+			writeError(w, r, http.StatusBadRequest, ErrCodeHashMismatch)
+			return
+		}
+
+		apiErr, err := adminUploads.update(func(list []AdminUpload) ([]AdminUpload, *modError) {
+			i := findAdminUpload(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeUploadNotFound, nil}
+			}
+			list[i].Complete = true
+			list[i].UpdatedAt = time.Now().UTC()
+			upload = list[i]
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(AdminUploadResponse{AdminUpload: upload, ChunkSize: currentConfig().AdminUploadChunkSize})
+		l.logSuccess("Загрузка по частям %s завершена (%d bytes)", id, upload.Size)
+	})
+}
+
+// Отмена загрузки вместе с принятыми частями
+func (l *Logger) adminDeleteUploadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", true, "📤", "/admin/api/uploads/{id}", func(AdminKey) {
+		id := r.PathValue("id")
+		unlock := lockAdminUpload(id)
+		defer unlock()
+		if _, ok := adminUploads.Get(id); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+			return
+		}
+		if err := removeAdminUpload(id); err != nil {
+			l.logError("Ошибка сохранения загрузок по частям: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Загрузка по частям %s отменена", id)
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Статусы жалоб античита
+const (
+	CheatReportPending   = "pending"
+	CheatReportConfirmed = "confirmed"
+	CheatReportDismissed = "dismissed"
+)
+
+const maxCheatReportDetails = 1000
+
+var (
+	cheatDetectionPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+	// SHA-256 файла с доказательствами (запись демо, снимок памяти);
+	// сами файлы остаются на игровом сервере
+	evidenceHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// Срабатывание античита на игровом сервере, ожидающее решения модератора
+type CheatReport struct {
+	ID           string `json:"id"`
+	Player       string `json:"player"`
+	Detection    string `json:"detection"`
+	EvidenceHash string `json:"evidence_hash"`
+	Details      string `json:"details,omitempty"`
+	// Игровой сервер, приславший жалобу
+	Server    string    `json:"server,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	// Сколько раз приходила та же жалоба (игрок и доказательство)
+	Repeats    int        `json:"repeats,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	Reviewer   string     `json:"reviewer,omitempty"`
+}
+
+type CheatReportRequest struct {
+	Player       string `json:"player"`
+	Detection    string `json:"detection"`
+	EvidenceHash string `json:"evidence_hash"`
+	Details      string `json:"details"`
+	Server       string `json:"server"`
+}
+
+type CheatReportResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type CheatReportsResponse struct {
+	Reports []CheatReport `json:"reports"`
+}
+
+// Жалобы в DATA_DIR/cheat_reports.json
+type CheatReportStore struct {
+	*jsonListStore[CheatReport]
+}
+
+var cheatReports = &CheatReportStore{&jsonListStore[CheatReport]{file: cheatReportsFile}}
+
+func cheatReportsFile() string {
+	return filepath.Join(currentConfig().DataDir, "cheat_reports.json")
+}
+
+// Жалобы с фильтрами от новых к старым; пустой фильтр не ограничивает
+func (s *CheatReportStore) List(status, player string) []CheatReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []CheatReport{}
+	for i := len(s.items) - 1; i >= 0; i-- {
+		report := s.items[i]
+		if (status == "" || report.Status == status) && (player == "" || strings.EqualFold(report.Player, player)) {
+			result = append(result, report)
+		}
+	}
+	return result
+}
+
+func (s *CheatReportStore) Get(id string) (CheatReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.items, func(c CheatReport) bool { return c.ID == id }); i >= 0 {
+		return s.items[i], true
+	}
+	return CheatReport{}, false
+}
+
+// Новая жалоба. Повтор с тем же игроком и доказательством, пока жалоба
+// ждет решения, не создает новую, а увеличивает Repeats.
+func (s *CheatReportStore) Add(report CheatReport) (CheatReport, bool, error) {
+	created := true
+	_, err := s.update(func(list []CheatReport) ([]CheatReport, *modError) {
+		i := slices.IndexFunc(list, func(c CheatReport) bool {
+			return c.Status == CheatReportPending && strings.EqualFold(c.Player, report.Player) && c.EvidenceHash == report.EvidenceHash
+		})
+		if i >= 0 {
+			list[i].Repeats++
+			report, created = list[i], false
+			return list, nil
+		}
+		return append(list, report), nil
+	})
+	return report, created, err
+}
+
+func (r CheatReportRequest) valid() bool {
+	return playerNamePattern.MatchString(r.Player) && cheatDetectionPattern.MatchString(r.Detection) &&
+		evidenceHashPattern.MatchString(r.EvidenceHash) && utf8.RuneCountInString(r.Details) <= maxCheatReportDetails &&
+		len(r.Server) <= 128
+}
+
+// Жалоба от игрового сервера: POST /api/anticheat/reports с
+// Authorization: Bearer GAME_SERVER_SECRET
+func (l *Logger) cheatReportHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🕵️", "/api/anticheat/reports", func() {
+		if !requireGameServerSecret(w, r) {
+			return
+		}
+		var req CheatReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.valid() {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+
+		report, created, err := cheatReports.Add(CheatReport{
+			ID:           randomID(8),
+			Player:       req.Player,
+			Detection:    req.Detection,
+			EvidenceHash: strings.ToLower(req.EvidenceHash),
+			Details:      req.Details,
+			Server:       req.Server,
+			Status:       CheatReportPending,
+			CreatedAt:    time.Now().UTC(),
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения жалоб античита: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(CheatReportResponse{ID: report.ID, Status: report.Status})
+		if created {
+			l.dispatchWebhook(WebhookCheatReported, report)
+			l.logWarn("Жалоба античита %s на игрока %s: %s (%s)", report.ID, report.Player, report.Detection, report.Server)
+		}
+	})
+}
+
+// Очередь на разбор: /admin/api/anticheat/reports?status=pending&player=
+func (l *Logger) adminCheatReportsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🕵️", "/admin/api/anticheat/reports", func() {
+		query := r.URL.Query()
+		json.NewEncoder(w).Encode(CheatReportsResponse{Reports: cheatReports.List(query.Get("status"), query.Get("player"))})
+	})
+}
+
+// Решение по жалобе; тело как у PUT /admin/api/bans/{player} (с hwid
+// банится и железо), для отклонения нужен только actor. Подтверждение банит игрока в общем
+// списке банов (игровые серверы узнают через PLAYER_LIST_WEBHOOKS) и
+// закрывает все ожидающие жалобы на него; отклонение — только эту.
+func (l *Logger) adminReviewCheatReportHandler(status string) http.HandlerFunc {
+	action := map[string]string{CheatReportConfirmed: "confirm", CheatReportDismissed: "dismiss"}[status]
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopePlayersWrite, "🕵️", "/admin/api/anticheat/reports/{id}/"+action, func() {
+			id := r.PathValue("id")
+			var req PlayerListRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeBodyError(w, r, err, ErrCodeInvalidRequest)
+				return
+			}
+
+			report, ok := cheatReports.Get(id)
+			if !ok {
+				writeError(w, r, http.StatusNotFound, ErrCodeCheatReportNotFound, id)
+				return
+			}
+			if report.Status != CheatReportPending {
+				writeError(w, r, http.StatusConflict, ErrCodeCheatReportReviewed, id, report.Status)
+				return
+			}
+
+			// Сначала бан: если он не сохранится, жалоба останется в очереди
+			now := time.Now().UTC()
+			reviewer, _ := adminActor(r, req.Actor)
+			if status == CheatReportConfirmed {
+				if req.Reason == "" {
+					req.Reason = fmt.Sprintf("Античит: %s", report.Detection)
+				}
+				ban, ok := req.entry(r, report.Player, now)
+				if !ok {
+					writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+					return
+				}
+				if err := l.putPlayerListEntry(PlayerListBans, ban, req.HWID); err != nil {
+					l.logError("Ошибка сохранения списков игроков: %v", err)
+					writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+					return
+				}
+				l.logSuccess("Игрок %s забанен по жалобе античита %s (%s): %s", report.Player, id, reviewer, ban.Reason)
+			}
+
+			apiErr, err := cheatReports.update(func(list []CheatReport) ([]CheatReport, *modError) {
+				for i := range list {
+					if list[i].ID == id || (status == CheatReportConfirmed && list[i].Status == CheatReportPending && strings.EqualFold(list[i].Player, report.Player)) {
+						list[i].Status, list[i].ReviewedAt, list[i].Reviewer = status, &now, reviewer
+						if list[i].ID == id {
+							report = list[i]
+						}
+					}
+				}
+				return list, nil
+			})
+			if apiErr != nil || err != nil {
+				l.logError("Ошибка сохранения жалоб античита: %v", err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+				return
+			}
+			json.NewEncoder(w).Encode(report)
+			l.logSuccess("Жалоба античита %s: %s (%s)", id, status, reviewer)
+		})
+	}
+}
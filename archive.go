@@ -0,0 +1,272 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Фиксированное время в архивах: одинаковое содержимое каталога всегда
+// дает байт-в-байт одинаковый архив и тот же хэш
+var archiveModTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Файл каталога игры в архиве
+type archiveEntry struct {
+	Name string
+	Path string
+	Size int64
+	Mode fs.FileMode
+}
+
+type archiveFormat struct {
+	Ext         string
+	ContentType string
+	Write       func(w io.Writer, entries []archiveEntry) error
+}
+
+var archiveFormats = map[string]archiveFormat{
+	"zip":    {Ext: "zip", ContentType: "application/zip", Write: writeZipArchive},
+	"tar.gz": {Ext: "tar.gz", ContentType: "application/gzip", Write: writeTarGzArchive},
+}
+
+// Сборка архивов в кэш идет под блокировкой, чтобы одновременные
+// запросы не собирали один и тот же архив параллельно
+var archiveMu sync.Mutex
+
+// Список файлов каталога в стабильном порядке и отпечаток его состояния
+func scanGameDir(dir string) ([]archiveEntry, string, error) {
+	var entries []archiveEntry
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		mode := fs.FileMode(0644)
+		if info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+		entries = append(entries, archiveEntry{Name: filepath.ToSlash(rel), Path: path, Size: info.Size(), Mode: mode})
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	fingerprint := sha256.New()
+	for _, entry := range entries {
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(fingerprint, "%s\x00%d\x00%d\x00%o\n", entry.Name, entry.Size, info.ModTime().UnixNano(), entry.Mode)
+	}
+	return entries, hex.EncodeToString(fingerprint.Sum(nil)), nil
+}
+
+func writeZipArchive(w io.Writer, entries []archiveEntry) error {
+	archive := zip.NewWriter(w)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.Name, Method: zip.Deflate, Modified: archiveModTime}
+		header.SetMode(entry.Mode)
+		dst, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := copyArchiveEntry(dst, entry); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeTarGzArchive(w io.Writer, entries []archiveEntry) error {
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.Name,
+			Size:     entry.Size,
+			Mode:     int64(entry.Mode),
+			ModTime:  archiveModTime,
+			Format:   tar.FormatPAX,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := copyArchiveEntry(archive, entry); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+func copyArchiveEntry(dst io.Writer, entry archiveEntry) error {
+	file, err := os.Open(entry.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Файл мог измениться после сканирования; пишем ровно заявленный размер
+	n, err := io.Copy(dst, io.LimitReader(file, entry.Size))
+	if err == nil && n != entry.Size {
+		err = fmt.Errorf("файл %s изменился во время упаковки", entry.Name)
+	}
+	return err
+}
+
+// Путь к закэшированному архиву; каталог назван по отпечатку, поэтому
+// любое изменение файлов игры дает новый архив
+func cachedArchivePath(cfg *Config, fingerprint string, format archiveFormat) string {
+	return filepath.Join(cfg.DataDir, "archives", fingerprint[:16], "game."+format.Ext)
+}
+
+// Собирает архив в кэш, если его еще нет, и удаляет устаревшие
+func buildCachedArchive(cfg *Config, entries []archiveEntry, fingerprint string, format archiveFormat) (string, error) {
+	archiveMu.Lock()
+	defer archiveMu.Unlock()
+
+	path := cachedArchivePath(cfg, fingerprint, format)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = format.Write(tmp, entries)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	// Архивы прошлых версий каталога больше не нужны
+	root := filepath.Join(cfg.DataDir, "archives")
+	if dirs, err := os.ReadDir(root); err == nil {
+		for _, dir := range dirs {
+			if dir.Name() != fingerprint[:16] {
+				os.RemoveAll(filepath.Join(root, dir.Name()))
+			}
+		}
+	}
+	return path, nil
+}
+
+// Обработчик скачивания каталога игры одним архивом
+func (l *Logger) downloadGameArchiveHandler(ext string) http.HandlerFunc {
+	format := archiveFormats[ext]
+	endpoint := "/api/download/game." + format.Ext
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleWithCORS(w, r, "📦", endpoint, func() {
+			cfg := currentConfig()
+			if l.rejectDuringMaintenance(w, r, "архива игры") || l.rejectWithoutEntitlement(w, r, cfg.GameEntitlement, "архива игры") {
+				return
+			}
+			if cfg.GameDir == "" {
+				writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+				return
+			}
+
+			entries, fingerprint, err := scanGameDir(cfg.GameDir)
+			if os.IsNotExist(err) {
+				l.logError("Каталог игры не найден: %s", cfg.GameDir)
+				writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+				return
+			}
+			if err != nil {
+				l.logError("Ошибка чтения каталога игры %s: %v", cfg.GameDir, err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+				return
+			}
+
+			if cfg.ArchiveCache {
+				path, err := buildCachedArchive(cfg, entries, fingerprint, format)
+				if err != nil {
+					l.logError("Ошибка сборки архива игры: %v", err)
+					writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+					return
+				}
+				l.serveFileDownload(w, r, path, "game."+format.Ext)
+				return
+			}
+
+			l.streamGameArchive(w, r, "game", entries, format)
+		})
+	}
+}
+
+// Архив без кэша собирается на лету; хэш известен только в конце,
+// поэтому X-File-Hash отдается в трейлере. name — имя архива без
+// расширения: game для всего каталога, repair для файлов починки.
+func (l *Logger) streamGameArchive(w http.ResponseWriter, r *http.Request, name string, entries []archiveEntry, format archiveFormat) {
+	artifact := name + "." + format.Ext
+	release, ok := l.acquireDownload(w, r, artifact)
+	if !ok {
+		return
+	}
+	defer release()
+
+	w.Header().Set("Content-Disposition", contentDisposition(downloadDisposition(currentConfig(), artifact, format.ContentType), artifact))
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Trailer", "X-File-Hash")
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(format.Write(writer, entries))
+	}()
+	defer reader.Close()
+
+	hash := md5.New()
+	downloadStats.Begin(artifact)
+	written, err := copyWithIdleTimeout(w, io.TeeReader(reader, hash), currentConfig().DownloadIdleTimeout)
+	downloadStats.Record(artifact, written, err == nil)
+	if err != nil {
+		l.logError("Ошибка отправки архива %s: %v", artifact, err)
+		return
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	w.Header().Set("X-File-Hash", sum)
+	l.logSuccess("Отправлен архив %s (размер: %d bytes, хэш: %s)", artifact, written, sum)
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Права доступа ключей админского API
+const (
+	ScopeAll         = "*"
+	ScopeNewsWrite   = "news:write"
+	ScopeBuildsWrite = "builds:write"
+	ScopeStatsRead   = "stats:read"
+	ScopeServerAdmin = "server:admin"
+	ScopeKeysManage  = "keys:manage"
+	ScopeModsWrite   = "mods:write"
+	// Игровые серверы читают списки игроков, модераторы их меняют
+	ScopePlayersRead  = "players:read"
+	ScopePlayersWrite = "players:write"
+)
+
+// Роли: наборы прав для типичных обязанностей. Ключу можно выдать роли,
+// отдельные права или и то и другое — действуют все сразу.
+const (
+	RoleOwner          = "owner"
+	RoleModerator      = "moderator"
+	RoleNewsEditor     = "news-editor"
+	RoleReleaseManager = "release-manager"
+)
+
+var adminRoles = map[string][]string{
+	RoleOwner:          {ScopeAll},
+	RoleModerator:      {ScopePlayersRead, ScopePlayersWrite, ScopeStatsRead},
+	RoleNewsEditor:     {ScopeNewsWrite},
+	RoleReleaseManager: {ScopeBuildsWrite, ScopeModsWrite},
+}
+
+var knownScopes = map[string]bool{
+	ScopeAll:          true,
+	ScopeNewsWrite:    true,
+	ScopeBuildsWrite:  true,
+	ScopeStatsRead:    true,
+	ScopeServerAdmin:  true,
+	ScopeKeysManage:   true,
+	ScopeModsWrite:    true,
+	ScopePlayersRead:  true,
+	ScopePlayersWrite: true,
+}
+
+// Ключ админского API. На диске хранится только SHA-256 токена,
+// сам токен показывается один раз при создании или ротации.
+type AdminKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	Roles      []string   `json:"roles,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// 2FA: секрет TOTP и последний принятый шаг, наружу не отдаются
+	TOTPSecret   string `json:"totp_secret,omitempty"`
+	TOTPEnabled  bool   `json:"totp_enabled"`
+	TOTPLastStep int64  `json:"totp_last_step,omitempty"`
+}
+
+// Ключ без секретов для ответов API
+func (k AdminKey) public() AdminKey {
+	k.Hash, k.TOTPSecret, k.TOTPLastStep = "", "", 0
+	return k
+}
+
+// Запрос на создание ключа; нужны роли или права
+type AdminKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	Roles  []string `json:"roles"`
+	// Срок жизни токена, например "720h"; пустой — бессрочно
+	TTL string `json:"ttl"`
+}
+
+// Ответ с токеном (единственный раз, когда токен виден)
+type AdminKeyTokenResponse struct {
+	Key   AdminKey `json:"key"`
+	Token string   `json:"token"`
+}
+
+type AdminKeysResponse struct {
+	Keys []AdminKey `json:"keys"`
+}
+
+// Новые роли и права ключа; заменяют прежние целиком
+type AdminKeyPermissionsRequest struct {
+	Scopes []string `json:"scopes"`
+	Roles  []string `json:"roles"`
+}
+
+type AdminRole struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type AdminRolesResponse struct {
+	Roles []AdminRole `json:"roles"`
+}
+
+// Хранилище ключей в DATA_DIR/admin_keys.json
+type AdminKeyStore struct {
+	mu   sync.Mutex
+	path string
+	keys []AdminKey
+}
+
+var adminKeys = &AdminKeyStore{}
+
+// Статический ключ из ADMIN_TOKEN: все права, в файле не хранится
+var staticAdminKey = AdminKey{ID: "static", Name: "ADMIN_TOKEN", Scopes: []string{ScopeAll}, Roles: []string{RoleOwner}}
+
+func (s *AdminKeyStore) Load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []AdminKey
+	if err := loadJSONFile(path, &keys); err != nil {
+		return err
+	}
+	s.path = path
+	s.keys = keys
+	return nil
+}
+
+func (s *AdminKeyStore) save() error {
+	return saveJSONFile(s.path, s.keys)
+}
+
+// Поиск ключа по токену
+func (s *AdminKeyStore) Authenticate(token string, now time.Time) (AdminKey, bool) {
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.keys {
+		key := &s.keys[i]
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			return AdminKey{}, false
+		}
+		key.LastUsedAt = &now
+		return *key, true
+	}
+	return AdminKey{}, false
+}
+
+func (s *AdminKeyStore) List() []AdminKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]AdminKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key.public())
+	}
+	return keys
+}
+
+func (s *AdminKeyStore) Get(id string) (AdminKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.keys, func(k AdminKey) bool { return k.ID == id }); i >= 0 {
+		return s.keys[i], true
+	}
+	return AdminKey{}, false
+}
+
+// Изменение ключа под блокировкой; fn возвращает ошибку API, если
+// изменение недопустимо
+func (s *AdminKeyStore) Update(id string, fn func(key *AdminKey) *modError) (AdminKey, *modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.keys, func(k AdminKey) bool { return k.ID == id })
+	if i < 0 {
+		return AdminKey{}, &modError{http.StatusNotFound, ErrCodeKeyNotFound, nil}, nil
+	}
+	next := slices.Clone(s.keys)
+	if apiErr := fn(&next[i]); apiErr != nil {
+		return AdminKey{}, apiErr, nil
+	}
+	if err := saveJSONFile(s.path, next); err != nil {
+		return AdminKey{}, nil, err
+	}
+	s.keys = next
+	return next[i].public(), nil, nil
+}
+
+func (s *AdminKeyStore) Create(name string, scopes, roles []string, ttl time.Duration) (AdminKey, string, error) {
+	token, hash := newAdminToken()
+	now := time.Now().UTC()
+	key := AdminKey{
+		ID:        randomID(6),
+		Name:      name,
+		Hash:      hash,
+		Scopes:    append([]string{}, scopes...),
+		Roles:     roles,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		key.ExpiresAt = &expires
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = append(s.keys, key)
+	if err := s.save(); err != nil {
+		s.keys = s.keys[:len(s.keys)-1]
+		return AdminKey{}, "", err
+	}
+	key.Hash = ""
+	return key, token, nil
+}
+
+// Ротация: старый токен перестает работать сразу, срок жизни продлевается
+func (s *AdminKeyStore) Rotate(id string) (AdminKey, string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.keys {
+		key := &s.keys[i]
+		if key.ID != id {
+			continue
+		}
+		previous := *key
+		token, hash := newAdminToken()
+		now := time.Now().UTC()
+		if key.ExpiresAt != nil {
+			expires := now.Add(key.ExpiresAt.Sub(key.CreatedAt))
+			if key.RotatedAt != nil {
+				expires = now.Add(key.ExpiresAt.Sub(*key.RotatedAt))
+			}
+			key.ExpiresAt = &expires
+		}
+		key.Hash = hash
+		key.RotatedAt = &now
+		if err := s.save(); err != nil {
+			*key = previous
+			return AdminKey{}, "", true, err
+		}
+		return key.public(), token, true, nil
+	}
+	return AdminKey{}, "", false, nil
+}
+
+// Отзыв ключа; список заменяется только после записи, как в Update, чтобы
+// при ошибке ключ не вернулся после перезапуска
+func (s *AdminKeyStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.keys, func(k AdminKey) bool { return k.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	next := slices.Delete(slices.Clone(s.keys), i, i+1)
+	if err := saveJSONFile(s.path, next); err != nil {
+		return true, err
+	}
+	s.keys = next
+	dropAdminSessions(id)
+	return true, nil
+}
+
+func (k AdminKey) HasScope(scope string) bool {
+	for _, s := range k.permissions() {
+		if s == ScopeAll || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Права ключа: выданные напрямую и полученные через роли
+func (k AdminKey) permissions() []string {
+	scopes := slices.Clone(k.Scopes)
+	for _, role := range k.Roles {
+		scopes = append(scopes, adminRoles[role]...)
+	}
+	return scopes
+}
+
+// Право из other, которого нет у ключа; пустая строка — ключ покрывает все.
+// Ключ не может выдать или отнять больше прав, чем есть у него самого.
+func (k AdminKey) missingScope(other AdminKey) string {
+	for _, scope := range other.permissions() {
+		if !k.HasScope(scope) {
+			return scope
+		}
+	}
+	return ""
+}
+
+// Отказ, если у ключа id есть права, которых нет у actor: иначе ротацией
+// можно получить токен более сильного ключа. При отказе ответ уже записан.
+func rejectPrivilegedKey(w http.ResponseWriter, r *http.Request, actor AdminKey, id string) bool {
+	key, ok := adminKeys.Get(id)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeKeyNotFound)
+		return true
+	}
+	if scope := actor.missingScope(key); scope != "" {
+		writeError(w, r, http.StatusForbidden, ErrCodeForbidden, scope)
+		return true
+	}
+	return false
+}
+
+// Проверка запрошенных ролей и прав; при отказе ответ уже записан
+func validateKeyPermissions(w http.ResponseWriter, r *http.Request, actor AdminKey, scopes, roles []string) bool {
+	for _, scope := range scopes {
+		if !knownScopes[scope] {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidScope, scope)
+			return false
+		}
+	}
+	for _, role := range roles {
+		if _, ok := adminRoles[role]; !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRole, role)
+			return false
+		}
+	}
+	if scope := actor.missingScope(AdminKey{Scopes: scopes, Roles: roles}); scope != "" {
+		writeError(w, r, http.StatusForbidden, ErrCodeForbidden, scope)
+		return false
+	}
+	return true
+}
+
+// Токены вида loil_<случайные байты>; на диск попадает только хэш
+func newAdminToken() (token, hash string) {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	token = "loil_" + hex.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(sum[:])
+}
+
+func randomID(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Определение ключа по заголовку Authorization: Bearer <token> или X-API-Key
+func authenticateAdmin(r *http.Request) (AdminKey, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.Header.Get("X-API-Key")
+	}
+	if token == "" {
+		return AdminKey{}, false
+	}
+
+	if adminToken := currentConfig().AdminToken; adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+		return staticAdminKey, true
+	}
+
+	return adminKeys.Authenticate(token, time.Now())
+}
+
+// Журнал аудита: какой ключ какое действие выполнил
+func (l *Logger) logAudit(r *http.Request, key AdminKey, endpoint string, allowed bool) {
+	date := time.Now().Format("2006-01-02")
+	logDir := "logs"
+	logFile := filepath.Join(logDir, fmt.Sprintf("audit_%s.log", date))
+
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		l.logError("Ошибка создания директории логов: %v", err)
+		return
+	}
+
+	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		l.logError("Ошибка открытия журнала аудита: %v", err)
+		return
+	}
+	defer file.Close()
+
+	result := "allowed"
+	if !allowed {
+		result = "denied"
+	}
+	logEntry := fmt.Sprintf("[%s] key=%s (%s) ip=%s %s %s request_id=%s %s\n",
+		time.Now().Format("2006-01-02 15:04:05"),
+		key.ID, key.Name,
+		getClientIP(r),
+		r.Method, endpoint,
+		requestID(r),
+		result)
+
+	if _, err := file.WriteString(logEntry); err != nil {
+		l.logError("Ошибка записи в журнал аудита: %v", err)
+	}
+}
+
+// Список ключей без хэшей
+func (l *Logger) adminListKeysHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeKeysManage, "🔑", "/admin/api/keys", func() {
+		json.NewEncoder(w).Encode(AdminKeysResponse{Keys: adminKeys.List()})
+	})
+}
+
+// Выпуск нового ключа
+func (l *Logger) adminCreateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, ScopeKeysManage, true, "🔑", "/admin/api/keys", func(actor AdminKey) {
+		var req AdminKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || len(req.Scopes)+len(req.Roles) == 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if !validateKeyPermissions(w, r, actor, req.Scopes, req.Roles) {
+			return
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			parsed, err := time.ParseDuration(req.TTL)
+			if err != nil || parsed <= 0 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			ttl = parsed
+		}
+
+		key, token, err := adminKeys.Create(req.Name, req.Scopes, req.Roles, ttl)
+		if err != nil {
+			l.logError("Ошибка сохранения ключей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(AdminKeyTokenResponse{Key: key, Token: token})
+		l.logSuccess("Выпущен ключ %s «%s» с ролями %v и правами %v", key.ID, key.Name, key.Roles, key.Scopes)
+	})
+}
+
+// Смена ролей и прав ключа
+func (l *Logger) adminKeyPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, ScopeKeysManage, true, "🔑", "/admin/api/keys/{id}/permissions", func(actor AdminKey) {
+		var req AdminKeyPermissionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Scopes)+len(req.Roles) == 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if !validateKeyPermissions(w, r, actor, req.Scopes, req.Roles) {
+			return
+		}
+
+		key, apiErr, err := adminKeys.Update(r.PathValue("id"), func(k *AdminKey) *modError {
+			if scope := actor.missingScope(*k); scope != "" {
+				return &modError{http.StatusForbidden, ErrCodeForbidden, []interface{}{scope}}
+			}
+			k.Scopes, k.Roles = append([]string{}, req.Scopes...), req.Roles
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения ключей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		json.NewEncoder(w).Encode(key)
+		l.logSuccess("Ключу %s «%s» заданы роли %v и права %v (%s)", key.ID, key.Name, key.Roles, key.Scopes, actor.Name)
+	})
+}
+
+// Роли и их права, чтобы админка могла показать, что доступно ключу
+func (l *Logger) adminRolesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, "", "🔑", "/admin/api/roles", func() {
+		response := AdminRolesResponse{Roles: []AdminRole{}}
+		for _, name := range []string{RoleOwner, RoleModerator, RoleNewsEditor, RoleReleaseManager} {
+			response.Roles = append(response.Roles, AdminRole{Name: name, Scopes: adminRoles[name]})
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Ротация токена ключа
+func (l *Logger) adminRotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, ScopeKeysManage, true, "🔑", "/admin/api/keys/{id}/rotate", func(actor AdminKey) {
+		if rejectPrivilegedKey(w, r, actor, r.PathValue("id")) {
+			return
+		}
+		key, token, found, err := adminKeys.Rotate(r.PathValue("id"))
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeKeyNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения ключей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+			return
+		}
+
+		json.NewEncoder(w).Encode(AdminKeyTokenResponse{Key: key, Token: token})
+		l.logSuccess("Токен ключа %s «%s» обновлен", key.ID, key.Name)
+	})
+}
+
+// Отзыв ключа
+func (l *Logger) adminDeleteKeyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, ScopeKeysManage, true, "🔑", "/admin/api/keys/{id}", func(actor AdminKey) {
+		id := r.PathValue("id")
+		if rejectPrivilegedKey(w, r, actor, id) {
+			return
+		}
+		found, err := adminKeys.Delete(id)
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeKeyNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения ключей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Ключ %s отозван", id)
+	})
+}
+
+// Проверка общего секрета игрового сервера из Authorization: Bearer
+func requireGameServerSecret(w http.ResponseWriter, r *http.Request) bool {
+	secret := currentConfig().GameServerSecret
+	if secret == "" {
+		writeError(w, r, http.StatusForbidden, ErrCodeGameServerAPIDisabled)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		writeError(w, r, http.StatusUnauthorized, ErrCodeInvalidServerSecret)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Хранилище блобов: загруженные сборки, моды и ресурспаки лежат в
+// BLOB_DIR по sha256, а файлы в каталогах каналов и версий — жесткие
+// ссылки на них. Одна сборка в тестовом и основном канале или повторная
+// загрузка неизменного лаунчера занимают место на диске один раз, а
+// раздача идет по прежним путям. Файлы артефактов меняются только
+// заменой (rename), поэтому общий inode никогда не переписывается.
+type Blob struct {
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	Refs      []BlobRef `json:"refs"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Файл канала или версии, указывающий на блоб
+type BlobRef struct {
+	Path string `json:"path"`
+	// stable, staging, release/<id> (черновик), project/<id>[/<канал>],
+	// mod или resourcepack
+	Channel  string `json:"channel"`
+	Artifact string `json:"artifact"`
+	Version  string `json:"version,omitempty"`
+}
+
+type BlobsResponse struct {
+	Blobs []Blob `json:"blobs"`
+	// Место под блобами и сколько заняли бы файлы без дедупликации
+	StoredBytes     int64 `json:"stored_bytes"`
+	ReferencedBytes int64 `json:"referenced_bytes"`
+}
+
+type BlobStore struct {
+	mu    sync.Mutex
+	blobs map[string]Blob
+}
+
+var blobs = &BlobStore{}
+
+func blobIndexFile() string {
+	return filepath.Join(currentConfig().DataDir, "blobs.json")
+}
+
+func blobPath(sha string) string {
+	return filepath.Join(currentConfig().BlobDir, sha[:2], sha)
+}
+
+func (s *BlobStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs = make(map[string]Blob)
+	return loadJSONFile(blobIndexFile(), &s.blobs)
+}
+
+func (s *BlobStore) List() BlobsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := BlobsResponse{Blobs: make([]Blob, 0, len(s.blobs))}
+	for _, blob := range s.blobs {
+		response.Blobs = append(response.Blobs, blob)
+		response.StoredBytes += blob.Size
+		response.ReferencedBytes += blob.Size * int64(len(blob.Refs))
+	}
+	sort.Slice(response.Blobs, func(i, j int) bool {
+		return response.Blobs[i].CreatedAt.After(response.Blobs[j].CreatedAt)
+	})
+	return response
+}
+
+// Перевод только что загруженного файла в хранилище: если такой блоб уже
+// есть, файл заменяется ссылкой на него, иначе файл становится новым
+// блобом. Возвращает true, если место на диске сэкономлено.
+func (s *BlobStore) Adopt(ref BlobRef, file uploadedFile) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.withoutPath(ref.Path)
+	blob, exists := next[file.SHA256]
+	storage := blobPath(file.SHA256)
+	if exists {
+		if _, err := os.Stat(storage); err != nil {
+			exists = false
+		}
+	}
+	if exists {
+		if err := linkFile(storage, ref.Path); err != nil {
+			return false, err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(storage), 0755); err != nil {
+			return false, err
+		}
+		os.Remove(storage)
+		if err := os.Link(ref.Path, storage); err != nil {
+			return false, err
+		}
+		blob = Blob{SHA256: file.SHA256, Size: file.Size, CreatedAt: time.Now().UTC()}
+	}
+
+	blob.Refs = append(blob.Refs, ref)
+	next[file.SHA256] = blob
+	return exists, s.save(next)
+}
+
+// Файл переименован (публикация релиза): ссылка переезжает вместе с ним,
+// а прежний файл по новому пути перестает ссылаться на свой блоб
+func (s *BlobStore) Move(oldPath string, ref BlobRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.withoutPath(ref.Path)
+	for sha, blob := range next {
+		if i := slices.IndexFunc(blob.Refs, func(existing BlobRef) bool { return existing.Path == oldPath }); i >= 0 {
+			blob.Refs = slices.Clone(blob.Refs)
+			blob.Refs[i] = ref
+			next[sha] = blob
+		}
+	}
+	return s.save(next)
+}
+
+// Файл удален из канала или версии
+func (s *BlobStore) Release(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.withoutPath(path)
+	for sha, blob := range next {
+		if len(blob.Refs) != len(s.blobs[sha].Refs) {
+			return s.save(next)
+		}
+	}
+	return nil
+}
+
+// Есть ли блоб с таким содержимым
+func (s *BlobStore) Has(sha string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blobs[sha]
+	return ok
+}
+
+// Копия индекса без ссылок с пути; вызывается под s.mu
+func (s *BlobStore) withoutPath(path string) map[string]Blob {
+	next := make(map[string]Blob, len(s.blobs)+1)
+	for sha, blob := range s.blobs {
+		blob.Refs = slices.DeleteFunc(slices.Clone(blob.Refs), func(ref BlobRef) bool { return ref.Path == path })
+		next[sha] = blob
+	}
+	return next
+}
+
+// Сохранение индекса; блобы без ссылок удаляются с диска сразу
+func (s *BlobStore) save(next map[string]Blob) error {
+	var orphans []string
+	for sha, blob := range next {
+		if len(blob.Refs) == 0 {
+			orphans = append(orphans, sha)
+			delete(next, sha)
+		}
+	}
+	if err := saveJSONFile(blobIndexFile(), next); err != nil {
+		return err
+	}
+	s.blobs = next
+	for _, sha := range orphans {
+		os.Remove(blobPath(sha))
+	}
+	return nil
+}
+
+// Сверка ссылок с диском: файл, удаленный или замененный в обход
+// хранилища (очистка версий, ручное копирование в каталог клиентов),
+// больше не держит блоб. Блобы без ссылок удаляются, если не dryRun.
+func (s *BlobStore) collect(dryRun bool) (int, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]Blob, len(s.blobs))
+	removed, reclaimed := 0, int64(0)
+	for sha, blob := range s.blobs {
+		stored, err := os.Stat(blobPath(sha))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		blob.Refs = slices.DeleteFunc(slices.Clone(blob.Refs), func(ref BlobRef) bool {
+			info, err := os.Stat(ref.Path)
+			return err != nil || !os.SameFile(info, stored)
+		})
+		if len(blob.Refs) == 0 {
+			removed++
+			reclaimed += blob.Size
+		}
+		next[sha] = blob
+	}
+	if dryRun {
+		return removed, reclaimed, nil
+	}
+	return removed, reclaimed, s.save(next)
+}
+
+// Жесткая ссылка на source по пути target с заменой прежнего файла
+func linkFile(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(target), ".link-"+randomID(8))
+	if err := os.Link(source, tmp); err != nil {
+		return err
+	}
+	if err := renameFile(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Переименование с заменой. Если оба пути — ссылки на один блоб, rename
+// по POSIX ничего не делает, и прежнее имя приходится удалять отдельно.
+func renameFile(oldPath, newPath string) error {
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(oldPath); err == nil {
+		return os.Remove(oldPath)
+	}
+	return nil
+}
+
+// Загруженный файл в хранилище блобов. Без дедупликации файл остается
+// обычным (например, BLOB_DIR на другом разделе): раздача от этого не
+// страдает, поэтому ошибка только выводится в лог.
+func (l *Logger) adoptBlob(ref BlobRef, file uploadedFile) {
+	deduped, err := blobs.Adopt(ref, file)
+	if err != nil {
+		l.logWarn("Файл %s не переведен в хранилище блобов: %v", ref.Path, err)
+		return
+	}
+	if deduped {
+		l.Printf("♻️ %s совпадает с уже загруженным блобом %s, сэкономлено %d bytes", ref.Path, file.SHA256, file.Size)
+	}
+}
+
+// Блобы со ссылками и экономия места
+func (l *Logger) adminListBlobsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "♻️", "/admin/api/blobs", func() {
+		json.NewEncoder(w).Encode(blobs.List())
+	})
+}
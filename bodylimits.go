@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"sync/atomic"
+)
+
+// Лимит тела запроса маршрута вместо общего MAX_BODY_BYTES; 0 — без лимита
+type bodyLimit func(cfg *Config) int64
+
+// Лимиты маршрутов, которые принимают больше общего MAX_BODY_BYTES
+func uploadBodyLimit(cfg *Config) int64     { return int64(cfg.MaxUploadBytes) }
+func telemetryBodyLimit(cfg *Config) int64  { return int64(cfg.TelemetryMaxBytes) }
+func syncBodyLimit(cfg *Config) int64       { return int64(cfg.SyncMaxValueBytes) }
+func saveChunkBodyLimit(cfg *Config) int64  { return int64(cfg.SavesChunkSize) }
+func screenshotBodyLimit(cfg *Config) int64 { return int64(cfg.ScreenshotMaxBytes) }
+func feedbackBodyLimit(cfg *Config) int64   { return int64(cfg.FeedbackMaxBytes) }
+func avatarBodyLimit(cfg *Config) int64     { return int64(cfg.AvatarMaxBytes) }
+func verifyBodyLimit(cfg *Config) int64     { return int64(cfg.VerifyMaxBytes) }
+func adminChunkBodyLimit(cfg *Config) int64 { return int64(cfg.AdminUploadChunkSize) }
+
+type bodyLimitKey struct{}
+
+// Тело с лимитом помнит, что лимит превышен: обработчик мог принять это
+// за некорректный JSON, а клиент все равно получит 413 (см. writeError)
+type limitedBody struct {
+	io.ReadCloser
+	exceeded atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded.Store(true)
+	}
+	return n, err
+}
+
+// Ограничение тела запроса до вызова обработчика. Если размер известен
+// из Content-Length, отказ сразу; иначе чтение сверх лимита вернет
+// *http.MaxBytesError. При отказе ответ уже записан.
+func limitRequestBody(w http.ResponseWriter, r *http.Request, limit int64) (*http.Request, bool) {
+	if limit <= 0 {
+		return r, true
+	}
+	if r.ContentLength > limit {
+		writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge)
+		return r, false
+	}
+	body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit)}
+	r.Body = body
+	return r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, body)), true
+}
+
+func bodyLimitExceeded(r *http.Request) bool {
+	body, ok := r.Context().Value(bodyLimitKey{}).(*limitedBody)
+	return ok && body.exceeded.Load()
+}
+
+// Ответ на ошибку чтения тела: 413, если превышен лимит, иначе 400 с code
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error, code string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge)
+		return
+	}
+	writeError(w, r, http.StatusBadRequest, code)
+}
+
+// Проверка Content-Type тела; параметры (charset, boundary) не учитываются.
+// При отказе ответ уже записан.
+func requireContentType(w http.ResponseWriter, r *http.Request, allowed ...string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !slices.Contains(allowed, mediaType) {
+		writeError(w, r, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, r.Header.Get("Content-Type"))
+		return false
+	}
+	return true
+}
+
+// Ограничения multipart/form-data для загрузок (отчеты о сбоях, скины и т.п.)
+type multipartLimits struct {
+	MaxParts int
+	// Допустимые типы файловых частей; пустой список — любые
+	FileTypes []string
+}
+
+// Чтение multipart-запроса по частям без буферизации на диске или в
+// памяти; размер всего тела ограничен лимитом маршрута. fn получает
+// каждую часть по очереди. При отказе ответ уже записан.
+func forEachMultipartPart(w http.ResponseWriter, r *http.Request, limits multipartLimits, fn func(part *multipart.Part) error) bool {
+	if !requireContentType(w, r, "multipart/form-data") {
+		return false
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+		return false
+	}
+	for count := 0; ; count++ {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return true
+		}
+		if err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return false
+		}
+		if count >= limits.MaxParts {
+			writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodeTooManyParts, limits.MaxParts)
+			return false
+		}
+		if part.FileName() != "" && len(limits.FileTypes) > 0 {
+			mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if !slices.Contains(limits.FileTypes, mediaType) {
+				writeError(w, r, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, part.Header.Get("Content-Type"))
+				return false
+			}
+		}
+		if err := fn(part); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return false
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Кэш новостей. Файл перечитывается, только если изменились его время
+// модификации или размер (проверка не чаще раза в NEWS_CACHE_TTL), а
+// готовые ответы хранятся уже сериализованными — при старте тысяч
+// лаунчеров сервер не парсит и не кодирует JSON на каждый запрос.
+type NewsCache struct {
+	path      string
+	mu        sync.Mutex
+	news      []NewsItem
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+	responses map[string]cachedResponse
+	// Конфигурация, с которой собраны ответы (ссылки в лентах зависят от PUBLIC_URL)
+	cfg *Config
+}
+
+type cachedResponse struct {
+	body       []byte
+	count      int
+	validUntil time.Time
+}
+
+// Чтобы разнообразие Accept-Language не раздувало кэш
+const maxCachedResponses = 256
+
+var newsCache = &NewsCache{path: newsFile}
+
+// Актуальный список новостей; срез нельзя изменять на месте
+func (c *NewsCache) Get() ([]NewsItem, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+	return c.news, nil
+}
+
+// Готовый ответ по ключу; build вызывается только при промахе кэша
+func (c *NewsCache) Response(key string, build func(news []NewsItem) ([]byte, int, error)) ([]byte, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(); err != nil {
+		return nil, 0, err
+	}
+
+	if cfg := currentConfig(); c.cfg != cfg {
+		c.cfg = cfg
+		c.responses = make(map[string]cachedResponse)
+	}
+
+	now := time.Now()
+	if cached, ok := c.responses[key]; ok && (cached.validUntil.IsZero() || now.Before(cached.validUntil)) {
+		return cached.body, cached.count, nil
+	}
+
+	body, count, err := build(c.news)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(c.responses) >= maxCachedResponses {
+		c.responses = make(map[string]cachedResponse)
+	}
+	c.responses[key] = cachedResponse{
+		body:       body,
+		count:      count,
+		validUntil: nextNewsTransition(c.news, now),
+	}
+	return body, count, nil
+}
+
+// Сброс кэша после записи news.json из админки или планировщика
+func (c *NewsCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checkedAt = time.Time{}
+	c.modTime = time.Time{}
+}
+
+func (c *NewsCache) refresh() error {
+	if c.news != nil && time.Since(c.checkedAt) < currentConfig().NewsCacheTTL {
+		return nil
+	}
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	c.checkedAt = time.Now()
+	if c.news != nil && info.ModTime().Equal(c.modTime) && info.Size() == c.size {
+		return nil
+	}
+
+	news, err := loadNewsFile(c.path)
+	if err != nil {
+		return err
+	}
+	if news == nil {
+		news = []NewsItem{}
+	}
+
+	c.news = news
+	c.modTime = info.ModTime()
+	c.size = info.Size()
+	c.responses = make(map[string]cachedResponse)
+	return nil
+}
+
+// Ближайший момент, когда набор видимых новостей изменится сам по себе
+// (наступит publish_at или expires_at); до него готовые ответы верны
+func nextNewsTransition(news []NewsItem, now time.Time) time.Time {
+	var next time.Time
+	consider := func(t *time.Time) {
+		if t != nil && t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = *t
+		}
+	}
+	for _, item := range news {
+		consider(item.PublishAt)
+		consider(item.ExpiresAt)
+	}
+	return next
+}
+
+// Сериализованный ответ /api/version; пересобирается при смене
+// конфигурации, режима техработ, номера сборки или списка заблокированных
+// версий. Вариантов ответа два: с must_update и без.
+type versionCacheEntry struct {
+	cfg         *Config
+	maintenance bool
+	blocked     *BlockedVersions
+	bodies      [2][]byte
+}
+
+var versionCache atomic.Pointer[versionCacheEntry]
+
+// Ответ /api/version без кэша; gRPC отдает его же
+func currentVersion(mustUpdate bool) VersionResponse {
+	cfg := currentConfig()
+	builds := currentBuilds()
+	return VersionResponse{
+		LauncherVersion: cfg.LauncherVersion,
+		GameVersion:     cfg.GameVersion,
+		Maintenance:     maintenanceMode.Load(),
+		LauncherBuild:   builds.Launcher,
+		GameBuild:       builds.Game,
+		BlockedVersions: *currentBlockedVersions(),
+		MustUpdate:      mustUpdate,
+	}
+}
+
+func versionResponseBody(mustUpdate bool) []byte {
+	cfg := currentConfig()
+	maintenance := maintenanceMode.Load()
+	blocked := currentBlockedVersions()
+
+	variant := 0
+	if mustUpdate {
+		variant = 1
+	}
+
+	if cached := versionCache.Load(); cached != nil && cached.cfg == cfg && cached.maintenance == maintenance && cached.blocked == blocked {
+		return cached.bodies[variant]
+	}
+
+	entry := &versionCacheEntry{cfg: cfg, maintenance: maintenance, blocked: blocked}
+	for i := range entry.bodies {
+		body, _ := json.Marshal(currentVersion(i == 1))
+		entry.bodies[i] = append(body, '\n')
+	}
+	versionCache.Store(entry)
+	return entry.bodies[variant]
+}
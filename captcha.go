@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Адреса, для которых CAPTCHA_ENDPOINTS может требовать CAPTCHA на каждый
+// запрос. Отчеты о сбоях приходят вложениями к отзывам, так что feedback
+// закрывает и их.
+const (
+	CaptchaRegister = "register"
+	CaptchaFeedback = "feedback"
+)
+
+var captchaEndpoints = []string{CaptchaRegister, CaptchaFeedback}
+
+// Что лаунчеру нужно для формы CAPTCHA до первого отказа
+type CaptchaSettings struct {
+	SiteKey   string   `json:"site_key,omitempty"`
+	Endpoints []string `json:"endpoints"`
+}
+
+func captchaSettings(cfg *Config) CaptchaSettings {
+	settings := CaptchaSettings{Endpoints: []string{}}
+	if len(cfg.CaptchaEndpoints) > 0 {
+		settings.SiteKey, settings.Endpoints = cfg.CaptchaSiteKey, cfg.CaptchaEndpoints
+	}
+	return settings
+}
+
+// Токен CAPTCHA для адресов из CAPTCHA_ENDPOINTS: заголовок, а не поле
+// тела, чтобы проверка не зависела от формата запроса (JSON, multipart,
+// gRPC)
+func requestCaptchaToken(r *http.Request) string {
+	return r.Header.Get("X-Captcha-Token")
+}
+
+// Проверка CAPTCHA для адреса, если он есть в CAPTCHA_ENDPOINTS
+func (l *Logger) checkEndpointCaptcha(r *http.Request, endpoint string) *modError {
+	if !slices.Contains(currentConfig().CaptchaEndpoints, endpoint) {
+		return nil
+	}
+	apiErr := l.checkCaptcha(r, requestCaptchaToken(r))
+	if apiErr != nil {
+		l.logWarn("CAPTCHA для %s не пройдена с %s: %s", endpoint, getClientIP(r), apiErr.code)
+	}
+	return apiErr
+}
+
+// Обертка обработчика: без пройденной CAPTCHA запрос до него не доходит
+func (l *Logger) withCaptcha(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if apiErr := l.checkEndpointCaptcha(r, endpoint); apiErr != nil {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			writeModError(w, r, apiErr)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Описание изменений одной версии игры в канале. После обновления
+// лаунчер показывает сводку «что нового» по всем версиям между той, что
+// была у игрока, и новой.
+type ChangelogEntry struct {
+	Version string `json:"version"`
+	// Пусто — основной канал, staging — тестовый
+	Channel      string    `json:"channel,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Content      string    `json:"content"`
+	RenderedHTML string    `json:"rendered_html,omitempty"`
+	ReleasedAt   time.Time `json:"released_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ChangelogRequest struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// По умолчанию — время сохранения
+	ReleasedAt *time.Time `json:"released_at"`
+}
+
+// Изменения от новых версий к старым
+type ChangelogResponse struct {
+	From    string           `json:"from,omitempty"`
+	To      string           `json:"to"`
+	Entries []ChangelogEntry `json:"entries"`
+}
+
+type ChangelogListResponse struct {
+	Entries []ChangelogEntry `json:"entries"`
+}
+
+// Описания изменений в DATA_DIR/changelog.json
+type ChangelogStore struct {
+	*jsonListStore[ChangelogEntry]
+}
+
+var changelogs = &ChangelogStore{&jsonListStore[ChangelogEntry]{file: changelogFile}}
+
+func changelogFile() string {
+	return filepath.Join(currentConfig().DataDir, "changelog.json")
+}
+
+func findChangelogEntry(entries []ChangelogEntry, channel, version string) int {
+	return slices.IndexFunc(entries, func(e ChangelogEntry) bool { return e.Channel == channel && e.Version == version })
+}
+
+// Записи канала в диапазоне (from, to] от новых к старым; пустой from —
+// все версии до to
+func changelogRange(entries []ChangelogEntry, channel, from, to string) []ChangelogEntry {
+	result := []ChangelogEntry{}
+	for _, entry := range entries {
+		if entry.Channel != channel || compareSemver(entry.Version, to) > 0 {
+			continue
+		}
+		if from != "" && compareSemver(entry.Version, from) <= 0 {
+			continue
+		}
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b ChangelogEntry) int { return compareSemver(b.Version, a.Version) })
+	return result
+}
+
+// Описание изменений из опубликованного релиза, если для версии его еще
+// не писали вручную
+func (l *Logger) recordReleaseChangelog(rel Release) {
+	if rel.Project != "" || rel.GameVersion == "" || rel.Changelog == "" {
+		return
+	}
+	_, err := changelogs.update(func(list []ChangelogEntry) ([]ChangelogEntry, *modError) {
+		if findChangelogEntry(list, "", rel.GameVersion) >= 0 {
+			return list, nil
+		}
+		return append(list, ChangelogEntry{Version: rel.GameVersion, Content: rel.Changelog, ReleasedAt: *rel.PublishedAt, UpdatedAt: *rel.PublishedAt}), nil
+	})
+	if err != nil {
+		l.logError("Ошибка сохранения описания изменений %s: %v", rel.GameVersion, err)
+	}
+}
+
+// Что изменилось с версии игрока: /api/changelog?from=1.2.0&to=1.4.0.
+// Без to — до текущей версии канала.
+func (l *Logger) changelogHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📝", "/api/changelog", func() {
+		staging, ok := l.requestStaging(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		from, to := query.Get("from"), query.Get("to")
+		if to == "" {
+			cfg := currentConfig()
+			to = cfg.GameVersion
+			if staging {
+				_, to = currentStaging().artifact(cfg, "game")
+			}
+		}
+		if (from != "" && !isSemver(from)) || !isSemver(to) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		channel := ""
+		if staging {
+			channel = stagingChannel
+		}
+		entries := changelogRange(changelogs.List(), channel, from, to)
+		if query.Get("format") == "html" {
+			for i := range entries {
+				entries[i].RenderedHTML = renderMarkdown(entries[i].Content)
+			}
+		}
+		json.NewEncoder(w).Encode(ChangelogResponse{From: from, To: to, Entries: entries})
+		l.logSuccess("Отправлены изменения %s..%s: %d версий", from, to, len(entries))
+	})
+}
+
+// Канал записи из ?channel=: пусто — основной, staging — тестовый
+func changelogChannel(r *http.Request) (string, bool) {
+	channel := r.URL.Query().Get("channel")
+	return channel, channel == "" || channel == stagingChannel
+}
+
+func (l *Logger) adminListChangelogHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📝", "/admin/api/changelog", func() {
+		entries := changelogs.List()
+		slices.SortFunc(entries, func(a, b ChangelogEntry) int { return compareSemver(b.Version, a.Version) })
+		json.NewEncoder(w).Encode(ChangelogListResponse{Entries: append([]ChangelogEntry{}, entries...)})
+	})
+}
+
+// Создание или замена описания изменений версии
+func (l *Logger) adminPutChangelogHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📝", "/admin/api/changelog/{version}", func() {
+		version := r.PathValue("version")
+		channel, ok := changelogChannel(r)
+		var req ChangelogRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !ok || !isSemver(version) || req.Content == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		entry := ChangelogEntry{Version: version, Channel: channel, Title: req.Title, Content: req.Content, ReleasedAt: now, UpdatedAt: now}
+		apiErr, err := changelogs.update(func(list []ChangelogEntry) ([]ChangelogEntry, *modError) {
+			i := findChangelogEntry(list, channel, version)
+			switch {
+			case req.ReleasedAt != nil:
+				entry.ReleasedAt = req.ReleasedAt.UTC()
+			case i >= 0:
+				entry.ReleasedAt = list[i].ReleasedAt
+			}
+			if i >= 0 {
+				list[i] = entry
+				return list, nil
+			}
+			return append(list, entry), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(entry)
+		l.logSuccess("Описание изменений %s сохранено (канал: %q)", version, channel)
+	})
+}
+
+func (l *Logger) adminDeleteChangelogHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📝", "/admin/api/changelog/{version}", func() {
+		version := r.PathValue("version")
+		channel, ok := changelogChannel(r)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		apiErr, err := changelogs.update(func(list []ChangelogEntry) ([]ChangelogEntry, *modError) {
+			i := findChangelogEntry(list, channel, version)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeChangelogNotFound, []interface{}{version}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Описание изменений %s удалено (канал: %q)", version, channel)
+	})
+}
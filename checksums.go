@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Строка списка контрольных сумм
+type checksumEntry struct {
+	Filename string
+	SHA256   string
+}
+
+// Файлы, которые раздаются для артефакта, и имена, под которыми их
+// сохранит игрок
+func (l *Logger) artifactChecksums(cfg *Config, artifact string) ([]checksumEntry, error) {
+	type distributed struct{ path, name string }
+	var files []distributed
+
+	switch artifact {
+	case "launcher":
+		files = append(files, distributed{filepath.Join(cfg.ClientsDir, cfg.LauncherClient), cfg.LauncherClient})
+	case "game":
+		files = append(files, distributed{filepath.Join(cfg.ClientsDir, cfg.GameClient), cfg.GameClient})
+
+		// Архивы каталога игры попадают в список, только если они кэшируются:
+		// у потоковых архивов хэш заранее неизвестен
+		if cfg.GameDir != "" && cfg.ArchiveCache {
+			entries, fingerprint, err := scanGameDir(cfg.GameDir)
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			if err == nil {
+				for _, ext := range []string{"zip", "tar.gz"} {
+					path, err := buildCachedArchive(cfg, entries, fingerprint, archiveFormats[ext])
+					if err != nil {
+						return nil, err
+					}
+					files = append(files, distributed{path, "game." + ext})
+				}
+			}
+		}
+	}
+
+	checksums := make([]checksumEntry, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file.path)
+		if os.IsNotExist(err) {
+			l.logError("Файл не найден: %s", file.path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry, err := clientIndex.Lookup(file.path, info)
+		if err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, checksumEntry{Filename: file.name, SHA256: entry.SHA256})
+
+		// Сжатые варианты — под именами, которые дали бы им gzip и zstd
+		if variants := loadEncodedVariants(file.path, entry.SHA256); variants != nil {
+			for _, variant := range variants.Variants {
+				encoding, _ := encodingByName(variant.Encoding)
+				checksums = append(checksums, checksumEntry{Filename: file.name + encoding.Ext, SHA256: variant.SHA256})
+			}
+		}
+	}
+	return checksums, nil
+}
+
+// Список SHA256SUMS для проверки скачанного штатной утилитой:
+// sha256sum -c SHA256SUMS
+func (l *Logger) checksumsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔐", "/api/checksums/{artifact}", func() {
+		cfg := currentConfig()
+		artifact := r.PathValue("artifact")
+		if _, ok := artifactFilename(cfg, artifact); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+
+		checksums, err := l.artifactChecksums(cfg, artifact)
+		if err != nil {
+			l.logError("Ошибка подсчета контрольных сумм %s: %v", artifact, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+		if len(checksums) == 0 {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+
+		var body strings.Builder
+		for _, entry := range checksums {
+			// Хэш и имя через два пробела, как в выводе sha256sum
+			fmt.Fprintf(&body, "%s  %s\n", entry.SHA256, entry.Filename)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", contentDisposition(DispositionInline, "SHA256SUMS"))
+		w.Write([]byte(body.String()))
+		l.logSuccess("Отправлены контрольные суммы %s: %d файлов", artifact, len(checksums))
+	})
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Границы чанков определяются содержимым (gear-хэш), поэтому вставка
+// данных в начало файла сдвигает только соседние чанки, а не все
+// последующие. Средний размер чанка — около 1 МБ.
+const (
+	chunkMinSize = 256 << 10
+	chunkMaxSize = 4 << 20
+	// Проверяем старшие биты: в gear-хэше младшие зависят лишь от
+	// последних байтов окна
+	chunkMask = (1<<20 - 1) << 44
+)
+
+// Фиксированная таблица gear-хэша. Она должна быть одинаковой между
+// запусками, иначе чанки новых версий перестанут совпадать со старыми.
+var gearTable = func() (table [256]uint64) {
+	seed := uint64(0x4c4f494c)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		table[i] = z ^ z>>31
+	}
+	return table
+}()
+
+var chunkHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Ссылка на чанк в манифесте
+type ChunkRef struct {
+	Hash   string `json:"hash"`
+	Offset int64  `json:"offset"`
+	Size   int    `json:"size"`
+}
+
+// Манифест файла: из каких чанков он собирается. Лаунчер скачивает
+// только чанки, которых у него еще нет, и проверяет итоговый хэш.
+type ChunkManifest struct {
+	Artifact  string     `json:"artifact"`
+	Filename  string     `json:"filename"`
+	Version   string     `json:"version"`
+	Size      int64      `json:"size"`
+	Hash      string     `json:"hash"`
+	Chunks    []ChunkRef `json:"chunks"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Хранилище чанков: DATA_DIR/chunks/<первые 2 символа>/<sha256>
+func chunkPath(hash string) string {
+	return filepath.Join(currentConfig().DataDir, "chunks", hash[:2], hash)
+}
+
+func chunkManifestPath(artifact string) string {
+	return filepath.Join(currentConfig().DataDir, "manifests", artifact+".json")
+}
+
+// Разбиение потока на чанки по содержимому
+func splitChunks(r io.Reader, emit func(data []byte) error) error {
+	reader := bufio.NewReaderSize(r, 1<<20)
+	buf := make([]byte, 0, chunkMaxSize)
+	var hash uint64
+
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		buf = append(buf, b)
+		hash = hash<<1 + gearTable[b]
+		if (len(buf) >= chunkMinSize && hash&chunkMask == 0) || len(buf) >= chunkMaxSize {
+			if err := emit(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+			hash = 0
+		}
+	}
+
+	if len(buf) > 0 {
+		return emit(buf)
+	}
+	return nil
+}
+
+// Сохраняет чанк, если такого еще нет; возвращает true для нового чанка
+func storeChunk(hash string, data []byte) (bool, error) {
+	path := chunkPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	return true, writeFileAtomic(path, data)
+}
+
+// Нарезка файла на чанки и запись манифеста
+func buildChunkManifest(cfg *Config, artifact, path, fileHash string) (ChunkManifest, int, error) {
+	manifest, created, err := chunkFile(cfg, artifact, path, fileHash)
+	if err != nil {
+		return ChunkManifest{}, 0, err
+	}
+	return manifest, created, saveJSONFile(chunkManifestPath(artifact), manifest)
+}
+
+// Нарезка файла на чанки без записи манифеста: релиз нарезает сборки
+// заранее, а манифесты подменяет вместе с файлами
+func chunkFile(cfg *Config, artifact, path, fileHash string) (ChunkManifest, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ChunkManifest{}, 0, err
+	}
+	defer file.Close()
+
+	manifest := ChunkManifest{
+		Artifact:  artifact,
+		Filename:  filepath.Base(path),
+		Version:   artifactVersion(cfg, artifact),
+		Hash:      fileHash,
+		Chunks:    []ChunkRef{},
+		CreatedAt: time.Now().UTC(),
+	}
+	created := 0
+
+	err = splitChunks(file, func(data []byte) error {
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		isNew, err := storeChunk(hash, data)
+		if err != nil {
+			return err
+		}
+		if isNew {
+			created++
+		}
+		manifest.Chunks = append(manifest.Chunks, ChunkRef{Hash: hash, Offset: manifest.Size, Size: len(data)})
+		manifest.Size += int64(len(data))
+		return nil
+	})
+	if err != nil {
+		return ChunkManifest{}, 0, err
+	}
+	return manifest, created, nil
+}
+
+func loadChunkManifest(artifact string) (*ChunkManifest, error) {
+	var manifest *ChunkManifest
+	if err := loadJSONFile(chunkManifestPath(artifact), &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Версия артефакта из конфигурации
+func artifactVersion(cfg *Config, artifact string) string {
+	if artifact == "launcher" {
+		return cfg.LauncherVersion
+	}
+	return cfg.GameVersion
+}
+
+// Перестраивает манифест, если он устарел относительно файла
+func (l *Logger) syncChunkManifest(cfg *Config, artifact, path, fileHash string) {
+	existing, err := loadChunkManifest(artifact)
+	if err != nil {
+		l.logError("Ошибка чтения манифеста чанков %s: %v", artifact, err)
+	}
+	if existing != nil && existing.Hash == fileHash && existing.Version == artifactVersion(cfg, artifact) {
+		return
+	}
+
+	started := time.Now()
+	manifest, created, err := buildChunkManifest(cfg, artifact, path, fileHash)
+	if err != nil {
+		l.logError("Ошибка нарезки %s на чанки: %v", path, err)
+		return
+	}
+	l.logSuccess("Манифест чанков %s: %d чанков, новых %d (%s)",
+		artifact, len(manifest.Chunks), created, time.Since(started).Round(time.Millisecond))
+}
+
+// Манифест чанков артефакта
+func (l *Logger) chunkManifestHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧩", "/api/manifest/{artifact}", func() {
+		artifact := r.PathValue("artifact")
+		manifest, apiErr := l.artifactManifest(r, artifact)
+		if apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+
+		json.NewEncoder(w).Encode(manifest)
+		l.logSuccess("Отправлен манифест %s: %d чанков", artifact, len(manifest.Chunks))
+	})
+}
+
+// Манифест с проверкой права на игру
+func (l *Logger) artifactManifest(r *http.Request, artifact string) (*ChunkManifest, *modError) {
+	if _, ok := artifactFilename(currentConfig(), artifact); !ok {
+		return nil, &modError{http.StatusNotFound, ErrCodeUnknownArtifact, []interface{}{artifact}}
+	}
+	// Сами чанки не проверяются: их хэши без манифеста не узнать
+	if artifact == "game" {
+		if apiErr := l.checkEntitlement(r, currentConfig().GameEntitlement, "манифеста игры"); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+
+	manifest, err := loadChunkManifest(artifact)
+	if err != nil {
+		l.logError("Ошибка чтения манифеста чанков %s: %v", artifact, err)
+		return nil, &modError{http.StatusInternalServerError, ErrCodeFileOpen, nil}
+	}
+	if manifest == nil {
+		return nil, &modError{http.StatusNotFound, ErrCodeManifestNotFound, nil}
+	}
+	return manifest, nil
+}
+
+// Чанки манифеста, которых нет среди have, по порядку в файле
+func missingChunks(manifest *ChunkManifest, have []string) []ChunkRef {
+	known := make(map[string]bool, len(have))
+	for _, hash := range have {
+		known[hash] = true
+	}
+	var missing []ChunkRef
+	for _, chunk := range manifest.Chunks {
+		if !known[chunk.Hash] {
+			missing = append(missing, chunk)
+		}
+	}
+	return missing
+}
+
+// Отдача чанка по хэшу. Содержимое по адресу никогда не меняется,
+// поэтому чанки можно кэшировать навсегда.
+func (l *Logger) chunkHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧩", "/api/chunks/{hash}", func() {
+		if l.rejectDuringMaintenance(w, r, "чанка") {
+			return
+		}
+		accountID, ok := l.checkDownloadQuota(w, r, "чанка")
+		if !ok {
+			return
+		}
+
+		hash := r.PathValue("hash")
+		if !chunkHashPattern.MatchString(hash) {
+			writeError(w, r, http.StatusNotFound, ErrCodeChunkNotFound)
+			return
+		}
+
+		file, err := os.Open(chunkPath(hash))
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, r, http.StatusNotFound, ErrCodeChunkNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка открытия чанка %s: %v", hash, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			l.logError("Ошибка получения информации о чанке %s: %v", hash, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+
+		// Content-Length, Range для докачки и If-None-Match по ETag
+		// выставит http.ServeContent, как у скачивания целых файлов
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("ETag", `"`+hash+`"`)
+
+		downloadStats.Begin("chunks")
+		out := newIdleTimeoutWriter(w, currentConfig().DownloadIdleTimeout)
+		http.ServeContent(out, r, "", info.ModTime(), file)
+		if r.Method == http.MethodHead || (out.status != http.StatusOK && out.status != http.StatusPartialContent) {
+			// Заголовки без тела (HEAD, 304, 416) скачиванием не считаем
+			downloadStats.Skip("chunks")
+			return
+		}
+		downloadStats.Record("chunks", out.written, out.err == nil)
+		l.recordDownloadUsage(r, accountID, out.written)
+		if out.err != nil {
+			l.logError("Ошибка отправки чанка %s: %v", hash, out.err)
+		}
+	})
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Версия сборки сервера; задается при сборке:
+// go build -ldflags "-X main.buildVersion=1.2.3"
+var buildVersion = "dev"
+
+const cliUsage = `Использование: loil-server <команда> [флаги]
+
+Команды:
+  serve      запуск HTTP-сервера (по умолчанию)
+  hash       вывод манифеста файлов: loil-server hash <файл>...
+  validate   проверка конфигурации, новостей и клиентов
+  version    версия сервера
+  upload     загрузка сборки на сервер через админский API
+  keygen     создание ключа подписи релизов для офлайн-подписи
+  sign       подпись сборки офлайн-ключом: loil-server sign -key release.key <файл>
+  openapi    вывод спецификации OpenAPI публичного API
+
+Флаги конфигурации (serve, validate, openapi): -config, -port, -clients-dir, -public-url, -set KEY=VALUE
+`
+
+// Разбор подкоманды; без подкоманды (или сразу с флагами) запускается сервер
+func runCLI(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runServe(args)
+	}
+
+	command, rest := args[0], args[1:]
+	switch command {
+	case "serve":
+		return runServe(rest)
+	case "hash":
+		return runHash(rest)
+	case "validate":
+		return runValidate(rest)
+	case "version":
+		fmt.Printf("loil-server %s\n", buildVersion)
+		return nil
+	case "upload":
+		return runUpload(rest)
+	case "keygen":
+		return runKeygen(rest)
+	case "sign":
+		return runSign(rest)
+	case "openapi":
+		return runOpenAPI(rest)
+	case "help":
+		fmt.Print(cliUsage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return fmt.Errorf("неизвестная команда %q", command)
+	}
+}
+
+// Команда hash: манифест файлов в том же формате, что отдает сервер
+func runHash(args []string) error {
+	if len(args) == 0 {
+		return errors.New("укажите хотя бы один файл: loil-server hash <файл>...")
+	}
+
+	manifest := make([]FileInfoResponse, 0, len(args))
+	for _, path := range args {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		hash, err := calculateFileHash(path)
+		if err != nil {
+			return fmt.Errorf("ошибка вычисления хэша %s: %v", path, err)
+		}
+		manifest = append(manifest, FileInfoResponse{
+			Filename: filepath.Base(path),
+			Size:     info.Size(),
+			Hash:     hash,
+		})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
+
+// Команда openapi: спецификация для генерации клиентов в сборке
+// лаунчера без запуска сервера. servers берется из PUBLIC_URL.
+func runOpenAPI(args []string) error {
+	if err := loadConfig(args); err != nil {
+		return err
+	}
+
+	logger := &Logger{Logger: log.New(io.Discard, "", 0)}
+	router := NewRouter(logger)
+	logger.registerPublicRoutes(router)
+	_, err := os.Stdout.Write(openAPISpecBody(router))
+	return err
+}
+
+// Команда validate: проверка всего, что может помешать раздаче
+func runValidate(args []string) error {
+	if err := loadConfig(args); err != nil {
+		return err
+	}
+	cfg := currentConfig()
+
+	problems := 0
+	for _, warning := range configWarnings(*cfg) {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+
+	news, err := loadNews()
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", newsFile, err)
+		problems++
+	} else {
+		ids := make(map[int]bool)
+		for _, item := range news {
+			if ids[item.ID] {
+				fmt.Printf("❌ новость #%d: повторяющийся id\n", item.ID)
+				problems++
+			}
+			ids[item.ID] = true
+			if item.Title == "" {
+				fmt.Printf("❌ новость #%d: пустой заголовок\n", item.ID)
+				problems++
+			}
+			if item.Status != "" && !validNewsStatus(item.Status) {
+				fmt.Printf("❌ новость #%d: неизвестный статус %q\n", item.ID, item.Status)
+				problems++
+			}
+			if item.Image != "" {
+				if _, err := os.Stat(filepath.Join(imagesDir, filepath.Base(item.Image))); err != nil {
+					fmt.Printf("❌ новость #%d: нет изображения %s\n", item.ID, item.Image)
+					problems++
+				}
+			}
+		}
+		fmt.Printf("✅ новостей: %d\n", len(news))
+	}
+
+	for _, name := range []string{cfg.LauncherClient, cfg.GameClient} {
+		path := filepath.Join(cfg.ClientsDir, name)
+		if _, err := os.Stat(path); err != nil {
+			fmt.Printf("❌ клиент %s недоступен: %v\n", path, err)
+			problems++
+			continue
+		}
+		if _, err := calculateFileHash(path); err != nil {
+			fmt.Printf("❌ клиент %s не читается: %v\n", path, err)
+			problems++
+			continue
+		}
+		fmt.Printf("✅ клиент %s\n", path)
+	}
+
+	if err := projects.Load(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		problems++
+	}
+	for _, project := range projects.List() {
+		// Клиенты проекта могут еще не быть выложены, это не ошибка
+		dirs := []string{project.ClientsDir}
+		for _, channel := range project.Channels {
+			dirs = append(dirs, channel.ClientsDir)
+		}
+		for _, dir := range dirs {
+			for _, name := range []string{project.LauncherClient, project.GameClient} {
+				if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+					fmt.Printf("⚠️  проект %s: %v\n", project.Name, err)
+				}
+			}
+		}
+		fmt.Printf("✅ проект %s (%s)\n", project.Name, project.Title)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("найдено проблем: %d", problems)
+	}
+	fmt.Println("✅ Конфигурация в порядке")
+	return nil
+}
+
+// Команда upload: отправка сборки на удаленный сервер
+func runUpload(args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	server := fs.String("server", os.Getenv("LOIL_SERVER_URL"), "адрес админского API сервера, например http://127.0.0.1:9090")
+	token := fs.String("token", os.Getenv("ADMIN_TOKEN"), "токен администратора")
+	artifact := fs.String("artifact", "game", "что загружаем: launcher или game")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *server == "" || *token == "" {
+		return errors.New("использование: loil-server upload -server URL -token TOKEN [-artifact game|launcher] <файл>")
+	}
+	path := fs.Arg(0)
+
+	hash, err := calculateFileHash(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimRight(*server, "/") + "/admin/api/upload/" + *artifact
+	req, err := http.NewRequest(http.MethodPut, url, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Authorization", "Bearer "+*token)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-File-Hash", hash)
+
+	fmt.Printf("⬆️  Загрузка %s (%d байт, хэш %s) на %s\n", path, info.Size(), hash, url)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("сервер ответил %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	fmt.Printf("✅ %s\n", strings.TrimSpace(string(body)))
+	return nil
+}
+
+// Команда keygen: пара ключей Ed25519 для офлайн-подписи релизов.
+// Закрытый ключ остается у релиз-менеджера, на сервер кладется только
+// открытый (SIGNING_PUBLIC_KEY).
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	out := fs.String("out", "release.key", "файл закрытого ключа; открытый ключ пишется в <файл>.pub")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := os.Stat(*out); err == nil {
+		return fmt.Errorf("файл %s уже существует", *out)
+	}
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if err := writeEd25519PrivateKey(*out, private); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".pub", []byte(encodePublicKeyPEM(public)), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("🔏 Закрытый ключ: %s\n🔏 Открытый ключ: %s.pub (ключ %s)\n", *out, *out, newReleaseSigner(public, nil).keyID)
+	return nil
+}
+
+// Команда sign: подпись сборки офлайн-ключом. Подпись загружается на
+// сервер через PUT /admin/api/signature/{artifact}.
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	keyPath := fs.String("key", "release.key", "файл закрытого ключа")
+	artifact := fs.String("artifact", "game", "что подписываем: launcher или game")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || (*artifact != "launcher" && *artifact != "game") {
+		return errors.New("использование: loil-server sign -key release.key [-artifact game|launcher] <файл>")
+	}
+
+	private, err := readEd25519PrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	signature := ed25519.Sign(private, []byte(signatureMessage(*artifact, sum)))
+
+	return json.NewEncoder(os.Stdout).Encode(SignatureUploadRequest{Signature: base64.StdEncoding.EncodeToString(signature)})
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Сколько ждем тишины после события в каталоге клиентов, прежде чем
+// сканировать: сборка обычно копируется множеством записей подряд
+const clientsSettleDelay = time.Second
+
+// Сведения о файле в каталоге клиентов
+type ClientFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+	SHA256  string    `json:"sha256"`
+}
+
+// Индекс файлов каталога клиентов: хэши считаются один раз при появлении
+// или замене файла, а не на каждое скачивание. Индекс хранится в
+// DATA_DIR/file_hashes.json, чтобы после перезапуска не считать заново
+// хэши неизменившихся файлов.
+type ClientIndex struct {
+	mu     sync.Mutex
+	files  map[string]ClientFile
+	dirty  bool
+	saveMu sync.Mutex
+}
+
+var clientIndex = &ClientIndex{files: make(map[string]ClientFile)}
+
+// Номера сборок, увеличиваются при замене файла (AUTO_BUMP_BUILD)
+type BuildNumbers struct {
+	Launcher int `json:"launcher"`
+	Game     int `json:"game"`
+}
+
+var (
+	buildsMu sync.Mutex
+	builds   BuildNumbers
+)
+
+func buildsFile() string {
+	return filepath.Join(currentConfig().DataDir, "builds.json")
+}
+
+func loadBuilds() error {
+	buildsMu.Lock()
+	defer buildsMu.Unlock()
+	return loadJSONFile(buildsFile(), &builds)
+}
+
+func currentBuilds() BuildNumbers {
+	buildsMu.Lock()
+	defer buildsMu.Unlock()
+	return builds
+}
+
+func bumpBuild(artifact string) (int, error) {
+	buildsMu.Lock()
+	defer buildsMu.Unlock()
+
+	next := builds
+	switch artifact {
+	case "launcher":
+		next.Launcher++
+	case "game":
+		next.Game++
+	}
+	if err := saveJSONFile(buildsFile(), next); err != nil {
+		return 0, err
+	}
+	builds = next
+	if artifact == "launcher" {
+		return builds.Launcher, nil
+	}
+	return builds.Game, nil
+}
+
+func clientIndexFile() string {
+	return filepath.Join(currentConfig().DataDir, "file_hashes.json")
+}
+
+func (c *ClientIndex) Load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := loadJSONFile(clientIndexFile(), &c.files); err != nil {
+		return err
+	}
+	if c.files == nil {
+		c.files = make(map[string]ClientFile)
+	}
+	return nil
+}
+
+// Сохранение индекса, если в нем что-то изменилось с прошлого раза
+func (c *ClientIndex) Save() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	files := maps.Clone(c.files)
+	c.dirty = false
+	c.mu.Unlock()
+
+	if err := saveJSONFile(clientIndexFile(), files); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Хэши, уже посчитанные при приеме загрузки: файл не читается повторно
+func (c *ClientIndex) Remember(path string, file uploadedFile) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	c.files[path] = ClientFile{Size: info.Size(), ModTime: info.ModTime(), Hash: file.MD5, SHA256: file.SHA256}
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+// Сброс записей, чтобы хэши посчитались заново
+func (c *ClientIndex) Forget(paths []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, path := range paths {
+		if _, ok := c.files[path]; ok {
+			delete(c.files, path)
+			c.dirty = true
+		}
+	}
+}
+
+// Удаление записей файлов, которых больше нет на диске
+func (c *ClientIndex) Prune() int {
+	c.mu.Lock()
+	paths := slices.Collect(maps.Keys(c.files))
+	c.mu.Unlock()
+
+	var gone []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			gone = append(gone, path)
+		}
+	}
+	c.Forget(gone)
+	return len(gone)
+}
+
+// Хэш файла из индекса; если файл изменился с момента последнего
+// подсчета, хэш пересчитывается
+func (c *ClientIndex) Hash(path string, info os.FileInfo) (string, error) {
+	file, err := c.Lookup(path, info)
+	return file.Hash, err
+}
+
+// Запись индекса с MD5 и SHA-256; оба хэша считаются за одно чтение файла
+func (c *ClientIndex) Lookup(path string, info os.FileInfo) (ClientFile, error) {
+	entry, _, err := c.lookup(path, info)
+	return entry, err
+}
+
+// Как Lookup; computed — хэши посчитаны заново, а не взяты из индекса
+func (c *ClientIndex) lookup(path string, info os.FileInfo) (ClientFile, bool, error) {
+	c.mu.Lock()
+	cached, ok := c.files[path]
+	c.mu.Unlock()
+	if ok && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+		return cached, false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ClientFile{}, false, err
+	}
+	defer file.Close()
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), file); err != nil {
+		return ClientFile{}, false, err
+	}
+
+	entry := ClientFile{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Hash:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256:  hex.EncodeToString(sha256Hash.Sum(nil)),
+	}
+	c.mu.Lock()
+	c.files[path] = entry
+	c.dirty = true
+	c.mu.Unlock()
+	return entry, true, nil
+}
+
+// Какой артефакт лежит в файле с таким именем
+func artifactByFilename(cfg *Config, name string) string {
+	switch name {
+	case cfg.LauncherClient:
+		return "launcher"
+	case cfg.GameClient:
+		return "game"
+	}
+	return ""
+}
+
+// Слежение за каталогом клиентов. Каталог сканируется по событиям
+// fsnotify, а раз в CLIENTS_WATCH_INTERVAL — в любом случае: события
+// теряются на сетевых ФС, а без fsnotify остается только опрос. Файл
+// считается готовым, когда его размер и время изменения не меняются между
+// двумя проверками — так не хэшируем недокопированную сборку.
+func (l *Logger) watchClientsDir() {
+	// Сначала хэши всех файлов считаются параллельно, и первый проход
+	// берет их из индекса
+	if run, ok := l.beginFileHashes(false); ok {
+		run()
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		l.logWarn("fsnotify недоступен, каталог клиентов только опрашивается: %v", err)
+	} else {
+		defer watcher.Close()
+	}
+	watchedDir := ""
+
+	seen := make(map[string]ClientFile)
+	pending := make(map[string]ClientFile)
+	first := true
+
+	for {
+		cfg := currentConfig()
+		if watcher != nil && watchedDir != cfg.ClientsDir {
+			if watchedDir != "" {
+				watcher.Remove(watchedDir)
+			}
+			// Каталога может еще не быть — попробуем на следующем проходе
+			watchedDir = ""
+			if err := watcher.Add(cfg.ClientsDir); err == nil {
+				watchedDir = cfg.ClientsDir
+			}
+		}
+		entries, err := os.ReadDir(cfg.ClientsDir)
+		if err != nil && !os.IsNotExist(err) {
+			l.logError("Ошибка чтения каталога клиентов: %v", err)
+		}
+
+		present := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			// Временные файлы загрузки начинаются с точки
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			path := filepath.Join(cfg.ClientsDir, entry.Name())
+			present[path] = true
+			current := ClientFile{Size: info.Size(), ModTime: info.ModTime()}
+
+			if previous, ok := seen[path]; ok && previous.Size == current.Size && previous.ModTime.Equal(current.ModTime) {
+				delete(pending, path)
+				continue
+			}
+			if waiting, ok := pending[path]; !first && (!ok || waiting.Size != current.Size || !waiting.ModTime.Equal(current.ModTime)) {
+				pending[path] = current
+				continue
+			}
+			delete(pending, path)
+
+			indexed, err := clientIndex.Lookup(path, info)
+			if err != nil {
+				l.logError("Ошибка вычисления хэша файла %s: %v", path, err)
+				continue
+			}
+			hash := indexed.Hash
+			current.Hash = hash
+			previous, known := seen[path]
+			seen[path] = current
+
+			// Манифест чанков, .torrent и сжатые варианты строятся и при
+			// первом проходе, если их нет
+			if artifact := artifactByFilename(cfg, entry.Name()); artifact != "" {
+				l.syncChunkManifest(cfg, artifact, path, hash)
+				l.syncEncodedVariants(cfg, path, indexed)
+				if artifact == "game" {
+					l.syncGameTorrent(cfg, path, hash)
+				}
+			}
+
+			if first || (known && previous.Hash == hash) {
+				continue
+			}
+			l.onClientChanged(cfg, entry.Name(), current)
+		}
+
+		for path := range seen {
+			if !present[path] {
+				delete(seen, path)
+				l.Printf("🗑️ Файл %s удален из каталога клиентов", path)
+			}
+		}
+
+		if err := clientIndex.Save(); err != nil {
+			l.logError("Ошибка сохранения индекса хэшей: %v", err)
+		}
+
+		first = false
+		// Недокопированные файлы перепроверяем сразу после паузы
+		wait := cfg.ClientsWatchInterval
+		if len(pending) > 0 {
+			wait = min(wait, clientsSettleDelay)
+		}
+		l.waitClientsDir(watcher, wait)
+	}
+}
+
+// Ожидание следующего прохода: событие в каталоге и clientsSettleDelay
+// тишины после него, но не дольше wait. Без watcher — просто пауза.
+func (l *Logger) waitClientsDir(watcher *fsnotify.Watcher, wait time.Duration) {
+	if watcher == nil {
+		time.Sleep(wait)
+		return
+	}
+	deadline := time.Now().Add(wait)
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-watcher.Events:
+			// Ждем, пока запись в каталог утихнет; долгая загрузка рядом
+			// не откладывает проход дольше wait
+			timeout.Reset(min(clientsSettleDelay, time.Until(deadline)))
+		case err := <-watcher.Errors:
+			l.logError("Ошибка слежения за каталогом клиентов: %v", err)
+		case <-timeout.C:
+			return
+		}
+	}
+}
+
+// Реакция на новую или замененную сборку
+func (l *Logger) onClientChanged(cfg *Config, name string, file ClientFile) {
+	l.Printf("🔄 Обнаружен новый файл %s (%d bytes, хэш: %s)", name, file.Size, file.Hash)
+
+	artifact := artifactByFilename(cfg, name)
+	if artifact != "" && cfg.AutoBumpBuild {
+		build, err := bumpBuild(artifact)
+		if err != nil {
+			l.logError("Ошибка сохранения номера сборки: %v", err)
+		} else {
+			l.logSuccess("Номер сборки %s увеличен до %d", artifact, build)
+		}
+	}
+
+	// Ответ /api/version содержит номера сборок
+	versionCache.Store(nil)
+	eventHub.PublishVersion()
+}
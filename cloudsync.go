@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Ключи синхронизации: settings, keybinds, modpack, options и т.п.
+var syncKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// Значение ключа хранится как есть в DATA_DIR/sync/<id аккаунта>/<ключ>
+type SyncEntry struct {
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	ETag      string    `json:"etag"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SyncListResponse struct {
+	Entries   []SyncEntry `json:"entries"`
+	UsedBytes int64       `json:"used_bytes"`
+	Quota     int64       `json:"quota"`
+}
+
+// Запись и удаление ключей одного аккаунта не должны пересекаться,
+// иначе проверка If-Match и квоты теряет смысл
+var syncMu sync.Mutex
+
+func syncDir(accountID string) string {
+	return filepath.Join(currentConfig().DataDir, "sync", accountID)
+}
+
+func syncETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// Текущее значение ключа; nil, если ключа нет
+func readSyncValue(accountID, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(syncDir(accountID), key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func listSyncEntries(accountID string) ([]SyncEntry, int64, error) {
+	dir := syncDir(accountID)
+	files, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, 0, err
+	}
+
+	entries := []SyncEntry{}
+	var used int64
+	for _, file := range files {
+		if !syncKeyPattern.MatchString(file.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, SyncEntry{
+			Key:       file.Name(),
+			Size:      int64(len(data)),
+			ETag:      syncETag(data),
+			UpdatedAt: info.ModTime().UTC(),
+		})
+		used += int64(len(data))
+	}
+	return entries, used, nil
+}
+
+// Проверка предусловий: If-Match обязателен, если ключ уже существует;
+// If-None-Match: * — запись только нового ключа
+func checkSyncPrecondition(w http.ResponseWriter, r *http.Request, current []byte) bool {
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	switch {
+	case ifNoneMatch == "*" && current != nil:
+		writeError(w, r, http.StatusPreconditionFailed, ErrCodeSyncConflict)
+		return false
+	case ifMatch != "" && ifMatch != "*" && (current == nil || ifMatch != syncETag(current)):
+		writeError(w, r, http.StatusPreconditionFailed, ErrCodeSyncConflict)
+		return false
+	case ifMatch == "" && ifNoneMatch != "*" && current != nil:
+		writeError(w, r, http.StatusPreconditionRequired, ErrCodePreconditionRequired)
+		return false
+	}
+	return true
+}
+
+// Список ключей аккаунта с ETag и занятым местом
+func (l *Logger) syncListHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "☁️", "/api/sync", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+
+		syncMu.Lock()
+		entries, used, err := listSyncEntries(account.ID)
+		syncMu.Unlock()
+		if err != nil {
+			l.logError("Ошибка чтения синхронизации %s: %v", account.Username, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		json.NewEncoder(w).Encode(SyncListResponse{Entries: entries, UsedBytes: used, Quota: int64(currentConfig().SyncQuotaBytes)})
+	})
+}
+
+// Значение ключа: GET /api/sync/settings
+func (l *Logger) syncGetHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "☁️", "/api/sync/{key}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		key := r.PathValue("key")
+		if !syncKeyPattern.MatchString(key) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		syncMu.Lock()
+		data, err := readSyncValue(account.ID, key)
+		syncMu.Unlock()
+		if err != nil {
+			l.logError("Ошибка чтения синхронизации %s/%s: %v", account.Username, key, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if data == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeSyncKeyNotFound, key)
+			return
+		}
+
+		etag := syncETag(data)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	})
+}
+
+// Запись ключа с проверкой If-Match и квот
+func (l *Logger) syncPutHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "☁️", "/api/sync/{key}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		key := r.PathValue("key")
+		if !syncKeyPattern.MatchString(key) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		cfg := currentConfig()
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(cfg.SyncMaxValueBytes)))
+		if err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+
+		syncMu.Lock()
+		defer syncMu.Unlock()
+
+		current, err := readSyncValue(account.ID, key)
+		if err != nil {
+			l.logError("Ошибка чтения синхронизации %s/%s: %v", account.Username, key, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !checkSyncPrecondition(w, r, current) {
+			return
+		}
+
+		_, used, err := listSyncEntries(account.ID)
+		if err != nil {
+			l.logError("Ошибка чтения синхронизации %s: %v", account.Username, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if used-int64(len(current))+int64(len(data)) > int64(cfg.SyncQuotaBytes) {
+			writeError(w, r, http.StatusInsufficientStorage, ErrCodeQuotaExceeded)
+			return
+		}
+
+		if err := writeFileAtomic(filepath.Join(syncDir(account.ID), key), data); err != nil {
+			l.logError("Ошибка записи синхронизации %s/%s: %v", account.Username, key, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		etag := syncETag(data)
+		w.Header().Set("ETag", etag)
+		status := http.StatusOK
+		if current == nil {
+			status = http.StatusCreated
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(SyncEntry{Key: key, Size: int64(len(data)), ETag: etag, UpdatedAt: time.Now().UTC()})
+		l.logSuccess("Синхронизирован ключ %s аккаунта %s (%d bytes)", key, account.Username, len(data))
+	})
+}
+
+// Удаление ключа; If-Match, как и при записи
+func (l *Logger) syncDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "☁️", "/api/sync/{key}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		key := r.PathValue("key")
+		if !syncKeyPattern.MatchString(key) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		syncMu.Lock()
+		defer syncMu.Unlock()
+
+		current, err := readSyncValue(account.ID, key)
+		if err != nil {
+			l.logError("Ошибка чтения синхронизации %s/%s: %v", account.Username, key, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if current == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeSyncKeyNotFound, key)
+			return
+		}
+		if !checkSyncPrecondition(w, r, current) {
+			return
+		}
+		if err := os.Remove(filepath.Join(syncDir(account.ID), key)); err != nil {
+			l.logError("Ошибка удаления синхронизации %s/%s: %v", account.Username, key, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Удален ключ %s аккаунта %s", key, account.Username)
+	})
+}
@@ -0,0 +1,1309 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Структура для конфигурации
+type Config struct {
+	ServerPort      string
+	LauncherClient  string
+	GameClient      string
+	LauncherVersion string
+	GameVersion     string
+	ClientsDir      string
+	DataDir         string
+	PublicURL       string
+	DefaultLanguage string
+	AdminToken      string
+	AdminAddr       string
+	MaintenanceMode bool
+
+	// Адреса публичного API (TCP, unix-сокеты, сокеты от systemd);
+	// пусто — SERVER_PORT на всех интерфейсах
+	Listen []listenAddr
+
+	// TLS публичного слушателя: с сертификатом сервер работает по HTTPS
+	// и HTTP/2, HTTP3 дополнительно слушает тот же порт по UDP (QUIC)
+	TLSCertFile string
+	TLSKeyFile  string
+	HTTP3       bool
+
+	// gRPC-сервисы на публичном порту; без TLS — по HTTP/2 без шифрования
+	// (h2c). GRPCWeb разрешает gRPC-Web из браузера.
+	GRPCEnabled bool
+	GRPCWeb     bool
+
+	// 2FA админского API: обязательна ли она для всех ключей и сколько
+	// живет сессия, открытая по коду
+	AdminRequire2FA    bool
+	Admin2FASessionTTL time.Duration
+
+	// Куда сообщать о панике в обработчиках
+	PanicWebhookURL string
+	SentryDSN       string
+
+	// Уровень лога сервера: debug, info, warn, error
+	LogLevel LogLevel
+
+	// Куда писать журнал доступа: file, syslog, loki, elasticsearch.
+	// Syslog — локальный или udp://, tcp://host:port. Loki и
+	// Elasticsearch получают записи пачками; при недоступности приемника
+	// очередь не растет дальше LOG_SHIP_QUEUE_SIZE, лишнее отбрасывается.
+	LogSinks             []string
+	LogInstance          string
+	SyslogAddr           string
+	SyslogTag            string
+	LogShipURL           string
+	LogShipAuth          string
+	LogShipBatchSize     int
+	LogShipQueueSize     int
+	LogShipFlushInterval time.Duration
+
+	// Место на диске: сколько должно остаться свободным после загрузки
+	// из админки, ниже какого порога слать оповещение, куда и как часто
+	// проверять
+	DiskMinFreeBytes    int
+	DiskAlertFreeBytes  int
+	DiskAlertWebhookURL string
+	DiskCheckInterval   time.Duration
+
+	// Куда рассылать изменения банов и белого списка
+	PlayerListWebhooks      []string
+	PlayerListWebhookSecret string
+
+	// Право, без которого не скачать игру (пусто — игра доступна всем),
+	// и права, которые есть у каждого аккаунта
+	GameEntitlement     string
+	DefaultEntitlements []string
+
+	// Тестовый канал основной игры (?channel=staging): каталог сборок
+	// (пусто — канала нет), право аккаунтов тестеров и адреса, которым
+	// канал виден без входа
+	StagingDir         string
+	StagingEntitlement string
+	StagingAllowedIPs  []*net.IPNet
+
+	NewsSchedulerInterval time.Duration
+	NewsCacheTTL          time.Duration
+	// Сколько кэшировать изображения новостей без хэша в имени
+	ImageCacheMaxAge time.Duration
+	// Команды кодировщиков WebP и AVIF для вариантов изображений новостей
+	// ({input} и {output} — пути файлов); пустая — вариант не строится
+	ImageWebPCommand string
+	ImageAVIFCommand string
+
+	// Поток объявлений /api/events: интервал комментариев-пульса, сколько
+	// последних событий хранить для повтора по Last-Event-ID и сколько
+	// подписчиков держать одновременно (0 — без лимита)
+	EventsHeartbeatInterval time.Duration
+	EventsHistory           int
+	EventsMaxSubscribers    int
+
+	// Слежение за каталогом клиентов: события fsnotify и страховочный
+	// опрос раз в ClientsWatchInterval
+	ClientsWatchInterval time.Duration
+	AutoBumpBuild        bool
+
+	// Каталог игры для раздачи архивом; пустой — архивы отключены
+	GameDir      string
+	ArchiveCache bool
+
+	// Проверка установки игры (/api/verify): размер списка файлов от
+	// лаунчера и пути, которые не считаются лишними (каталоги — с / на конце)
+	VerifyMaxBytes int
+	VerifyIgnore   []string
+
+	// Сколько файлов хэшировать параллельно при запуске и пересчете
+	// (0 — по числу процессоров)
+	HashWorkers int
+
+	// Сжатые gzip-варианты опубликованных файлов для Accept-Encoding;
+	// вариант хранится, только если экономит не меньше PRECOMPRESS_MIN_SAVING
+	PrecompressArtifacts bool
+	PrecompressMinSaving float64
+
+	// Подпись релизов Ed25519: закрытый ключ сервера или только открытый
+	// ключ, если подписи делаются офлайн
+	SigningKey       string
+	SigningPublicKey string
+
+	// Телеметрия лаунчеров
+	TelemetryEnabled    bool
+	TelemetrySampleRate float64
+	TelemetryMaxBytes   int
+	TelemetryMaxEvents  int
+	// Опрос о железе от лаунчеров и сколько хранить ответы установки
+	HardwareSurveyEnabled bool
+	HardwareSurveyMaxAge  time.Duration
+
+	// Аккаунты игроков: ключ подписи токенов, срок жизни токена доступа и
+	// сессии (токена обновления), открыта ли регистрация
+	AccountTokenKey     string
+	AccountTokenTTL     time.Duration
+	AccountSessionTTL   time.Duration
+	AccountRegistration bool
+	// Через сколько после запроса удаляется аккаунт; до этого удаление
+	// можно отменить
+	AccountDeletionGrace time.Duration
+
+	// Защита входа: сколько ошибок до блокировки по аккаунту и по адресу
+	// (0 — без блокировки), начальная и наибольшая длительность блокировки,
+	// после скольких ошибок просить CAPTCHA (без CAPTCHA_SECRET — никогда)
+	LoginAccountMaxFailures int
+	LoginIPMaxFailures      int
+	LoginLockoutBase        time.Duration
+	LoginLockoutMax         time.Duration
+	LoginCaptchaAfter       int
+	CaptchaSecret           string
+	CaptchaSiteKey          string
+	CaptchaVerifyURL        string
+	// Где CAPTCHA нужна всегда, а не только после ошибок входа:
+	// register, feedback
+	CaptchaEndpoints []string
+
+	// Вход через внешних провайдеров (discord, google) и адреса сайтов,
+	// куда можно вернуть игрока после входа, кроме локального лаунчера
+	OAuthClients      map[string]OAuthClient
+	OAuthRedirectURLs []string
+
+	// Почта: SMTP-сервер (пустой хост — письма отключены), адрес
+	// страницы смены пароля и каталог своих шаблонов писем
+	SMTPHost          string
+	SMTPPort          string
+	SMTPUsername      string
+	SMTPPassword      string
+	SMTPFrom          string
+	EmailResetURL     string
+	EmailTemplatesDir string
+
+	// Облачная синхронизация настроек: размер значения и квота на аккаунт
+	SyncMaxValueBytes int
+	SyncQuotaBytes    int
+
+	// Облачные сохранения: квота на аккаунт, размер части при загрузке,
+	// сколько копий одного сохранения хранить и как долго
+	SavesQuotaBytes int
+	SavesChunkSize  int
+	SavesKeep       int
+	SavesMaxAge     time.Duration
+
+	// Очистка старых версий модов и ресурспаков: сколько новейших версий
+	// хранить на каждую версию игры (0 — не чистить), сколько хранить
+	// скачанные и загруженные недавно, как часто запускать очистку
+	ArtifactKeepVersions   int
+	ArtifactKeepDownloaded time.Duration
+	ArtifactGCInterval     time.Duration
+	// Хранилище блобов: одинаковые загруженные файлы хранятся один раз.
+	// Должно быть на том же разделе, что каталоги клиентов и данных.
+	BlobDir string
+
+	// Скриншоты: размер файла, стороны картинки и миниатюры, сколько
+	// скриншотов игрока может ждать модерации
+	ScreenshotMaxBytes       int
+	ScreenshotMaxDimension   int
+	ScreenshotThumbDimension int
+	ScreenshotPendingLimit   int
+
+	// Общий секрет игровых серверов (достижения, рекорды, античит); пусто —
+	// API игровых серверов выключено
+	GameServerSecret string
+	// Ключ сервисного аккаунта Firebase для push-уведомлений; пусто —
+	// уведомления выключены
+	FCMCredentialsFile string
+	// Лимиты хранения входящих: сообщений в ящике и их срок
+	InboxMaxMessages int
+	InboxRetention   time.Duration
+	// Сколько друзей и заявок может быть у аккаунта
+	FriendsMax int
+	// Сколько последних периодов таблиц рекордов хранить
+	LeaderboardKeepPeriods int
+	// Сколько совпавших компонентов HWID (процессор, плата, диск, сеть)
+	// достаточно, чтобы считать железо забаненным
+	HWIDMatchThreshold int
+	// Вход только с отпечатком железа от лаунчера
+	HWIDRequired bool
+
+	// Аватары игроков: размер файла и сторона квадрата после уменьшения
+	AvatarMaxBytes int
+	AvatarSize     int
+
+	// Отзывы и сообщения об ошибках от игроков; размер запроса с вложением
+	FeedbackEnabled  bool
+	FeedbackMaxBytes int
+
+	// Как часто лаунчер подтверждает игровую сессию
+	SessionHeartbeatInterval time.Duration
+
+	// Встроенный BitTorrent-трекер для раздачи клиента игры
+	TorrentTracker          bool
+	TorrentAnnounceInterval time.Duration
+
+	// Таймауты HTTP-сервера и обработчиков
+	ReadHeaderTimeout   time.Duration
+	ReadTimeout         time.Duration
+	IdleTimeout         time.Duration
+	APITimeout          time.Duration
+	DownloadIdleTimeout time.Duration
+	// Сколько прежний процесс после обновления без остановки (SIGUSR2)
+	// дожидается незавершенных скачиваний
+	UpgradeDrainTimeout time.Duration
+
+	// Одновременные скачивания полных файлов: всего и с одного адреса
+	// (0 — без лимита). При занятом общем лимите до DOWNLOAD_QUEUE_SIZE
+	// запросов ждут освобождения потока DOWNLOAD_QUEUE_TIMEOUT.
+	MaxConcurrentDownloads int
+	MaxDownloadsPerIP      int
+	DownloadQueueSize      int
+	DownloadQueueTimeout   time.Duration
+	// Очередь с токенами через /api/download/queue вместо ожидания на
+	// соединении: до DOWNLOAD_QUEUE_MAX_TOKENS мест, токен без опроса
+	// живет DOWNLOAD_QUEUE_TOKEN_TTL, допущенному место держится
+	// DOWNLOAD_QUEUE_ADMIT_TTL
+	DownloadQueueTokens    bool
+	DownloadQueueMaxTokens int
+	DownloadQueueTokenTTL  time.Duration
+	DownloadQueueAdmitTTL  time.Duration
+	// Суточные квоты трафика в байтах (0 — без квоты): для вошедших в
+	// аккаунт и для анонимных скачиваний с одного адреса. Аккаунт, с
+	// которого за сутки качали с DOWNLOAD_ABUSE_IPS адресов, попадает в
+	// отчет как подозрительный; учет хранится DOWNLOAD_USAGE_DAYS дней.
+	DownloadQuotaAccountBytes int
+	DownloadQuotaIPBytes      int
+	DownloadAbuseIPs          int
+	DownloadUsageDays         int
+	// Режим зеркала: адрес основного сервера и токен из его
+	// /admin/api/mirrors. Зеркало забирает сборки, версии и новости при
+	// запуске, по уведомлению и раз в MIRROR_SYNC_INTERVAL.
+	MirrorPrimaryURL   string
+	MirrorToken        string
+	MirrorSyncInterval time.Duration
+	// Реплика только для чтения: синхронизируется с REPLICA_OF как
+	// зеркало (с MIRROR_TOKEN) и отклоняет запросы, меняющие данные, в
+	// публичном API и в админке. Раздача, новости и версии работают.
+	ReplicaOf string
+	// Общее состояние экземпляров за балансировщиком: блокировки входа,
+	// игровые сессии, счетчики скачиваний и режим техработ хранятся в
+	// Redis (redis:// или rediss://) под ключами с REDIS_PREFIX
+	RedisURL     string
+	RedisPrefix  string
+	RedisTimeout time.Duration
+	// Раздача через S3/MinIO: скачивания перенаправляются на подписанные
+	// ссылки, живущие S3_PRESIGN_TTL, на объекты <S3_PREFIX><sha256[:2]>/<sha256>
+	// в S3_BUCKET. Пустой S3_BUCKET — файлы отдает сервер.
+	S3Endpoint   string
+	S3Region     string
+	S3Bucket     string
+	S3Prefix     string
+	S3AccessKey  string
+	S3SecretKey  string
+	S3PresignTTL time.Duration
+	// Content-Disposition отдаваемых файлов: тип артефакта или MIME-тип
+	// (можно image/*) -> inline или attachment. Чего нет в списке,
+	// скачивается как attachment.
+	DownloadDisposition map[string]string
+	// Защита от перегрузки: пороги числа горутин, занятой кучи и задержки
+	// записи на диск (0 — не проверять), интервал проверки и Retry-After
+	// для отклоненных запросов
+	OverloadMaxGoroutines  int
+	OverloadMaxHeapBytes   int
+	OverloadMaxDiskLatency time.Duration
+	OverloadCheckInterval  time.Duration
+	OverloadRetryAfter     time.Duration
+
+	// Лимиты тела запроса: общий и для загрузок в админке (сборки,
+	// рантаймы, моды); 0 — без лимита. У загрузок игроков свои лимиты.
+	MaxBodyBytes   int
+	MaxUploadBytes int
+	// Размер части при загрузке по частям в админке
+	AdminUploadChunkSize int
+
+	// Прокси, которым доверяем заголовки X-Forwarded-For/X-Real-IP
+	TrustedProxies []*net.IPNet
+
+	// Доступ к публичному API: если белый список задан, пускаем только
+	// из него; черный список закрывает доступ всегда
+	IPAllowlist []*net.IPNet
+	IPDenylist  []*net.IPNet
+	// Автоматическая блокировка адреса на IP_BAN_DURATION после стольких
+	// ответов 404, 401 или 429 за IP_BAN_WINDOW (0 — не блокировать)
+	IPBanNotFoundLimit    int
+	IPBanAuthFailureLimit int
+	IPBanRateLimitHits    int
+	IPBanWindow           time.Duration
+	IPBanDuration         time.Duration
+}
+
+// Текущая конфигурация — неизменяемый снимок, который целиком заменяется
+// при перезагрузке. Обработчики берут снимок один раз на запрос.
+var configSnapshot atomic.Pointer[Config]
+
+// Аргументы командной строки запоминаются для перезагрузки конфигурации
+var configArgs []string
+
+func currentConfig() *Config {
+	return configSnapshot.Load()
+}
+
+// Источники значения параметра в порядке возрастания приоритета:
+// значение по умолчанию, файл конфигурации, .env, переменные окружения,
+// флаги командной строки. Версии основной игры из опубликованного релиза
+// (release) важнее всех источников.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceDotenv  = ".env"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+	sourceRelease = "release"
+)
+
+// Одна строка отчета об итоговой конфигурации. Значения секретов
+// (Secret) в отчет не выводятся.
+type configEntry struct {
+	Key    string
+	Value  string
+	Source string
+	Secret bool
+}
+
+type configLoader struct {
+	file    map[string]string
+	dotenv  map[string]string
+	flags   map[string]string
+	entries []configEntry
+}
+
+var (
+	configReportMu sync.Mutex
+	configReport   []configEntry
+)
+
+// Загрузка конфигурации: файл (JSON или YAML), затем .env и окружение,
+// затем флаги командной строки. Новый снимок подменяет текущий только
+// после успешной проверки.
+func loadConfig(args []string) error {
+	fs := flag.NewFlagSet("loil-server", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "путь к файлу конфигурации (.json или плоский .yaml/.yml: \"ключ: значение\", списки [a, b], без вложенности)")
+	port := fs.String("port", "", "порт HTTP-сервера (SERVER_PORT)")
+	clientsDir := fs.String("clients-dir", "", "каталог с клиентами (CLIENTS_DIR)")
+	publicURL := fs.String("public-url", "", "внешний адрес сервера (PUBLIC_URL)")
+	var overrides stringList
+	fs.Var(&overrides, "set", "переопределение параметра KEY=VALUE (можно повторять)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	loader := &configLoader{file: map[string]string{}, dotenv: map[string]string{}, flags: map[string]string{}}
+
+	// .env не обязателен: настройки могут прийти из файла или окружения.
+	// Файл читается без изменения окружения процесса, иначе при
+	// перезагрузке старые значения из .env перекрывали бы новые.
+	dotenv, err := godotenv.Read()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ошибка загрузки .env файла: %v", err)
+	}
+	if dotenv != nil {
+		loader.dotenv = dotenv
+	}
+
+	if *configPath != "" {
+		values, err := readConfigFile(*configPath)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения файла конфигурации %s: %v", *configPath, err)
+		}
+		loader.file = values
+	}
+
+	for key, value := range map[string]string{"SERVER_PORT": *port, "CLIENTS_DIR": *clientsDir, "PUBLIC_URL": *publicURL} {
+		if value != "" {
+			loader.flags[key] = value
+		}
+	}
+	for _, override := range overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok {
+			return fmt.Errorf("флаг -set ожидает KEY=VALUE, получено %q", override)
+		}
+		loader.flags[strings.ToUpper(strings.TrimSpace(key))] = value
+	}
+
+	// Имя экземпляра в журналах по умолчанию — имя хоста
+	hostname, _ := os.Hostname()
+	cfg := Config{
+		ServerPort:            loader.get("SERVER_PORT", "8080"),
+		LauncherClient:        loader.get("LAUNCHER_CLIENT_FILE", "launcher.exe"),
+		GameClient:            loader.get("GAME_CLIENT_FILE", "Loil.exe"),
+		LauncherVersion:       loader.get("LAUNCHER_VERSION", "0.0.0"),
+		GameVersion:           loader.get("GAME_VERSION", "0.0.0"),
+		ClientsDir:            loader.get("CLIENTS_DIR", "clients"),
+		DataDir:               loader.get("DATA_DIR", "data"),
+		DefaultLanguage:       normalizeLanguage(loader.get("DEFAULT_LANG", "ru")),
+		AdminToken:            loader.secret("ADMIN_TOKEN", ""),
+		AdminAddr:             loader.get("ADMIN_ADDR", "127.0.0.1:9090"),
+		MaintenanceMode:       loader.get("MAINTENANCE_MODE", "false") == "true",
+		TLSCertFile:           loader.get("TLS_CERT_FILE", ""),
+		TLSKeyFile:            loader.get("TLS_KEY_FILE", ""),
+		HTTP3:                 loader.get("HTTP3", "false") == "true",
+		GRPCEnabled:           loader.get("GRPC_ENABLED", "false") == "true",
+		GRPCWeb:               loader.get("GRPC_WEB", "false") == "true",
+		PanicWebhookURL:       loader.secret("PANIC_WEBHOOK_URL", ""),
+		DiskAlertWebhookURL:   loader.secret("DISK_ALERT_WEBHOOK_URL", ""),
+		SentryDSN:             loader.secret("SENTRY_DSN", ""),
+		LogInstance:           loader.get("LOG_INSTANCE", hostname),
+		SyslogAddr:            loader.get("SYSLOG_ADDR", ""),
+		SyslogTag:             loader.get("SYSLOG_TAG", "loil-launcher"),
+		LogShipURL:            loader.get("LOG_SHIP_URL", ""),
+		LogShipAuth:           loader.secret("LOG_SHIP_AUTH", ""),
+		AutoBumpBuild:         loader.get("AUTO_BUMP_BUILD", "false") == "true",
+		TorrentTracker:        loader.get("TORRENT_TRACKER", "false") == "true",
+		TelemetryEnabled:      loader.get("TELEMETRY_ENABLED", "false") == "true",
+		HardwareSurveyEnabled: loader.get("HARDWARE_SURVEY_ENABLED", "false") == "true",
+		FeedbackEnabled:       loader.get("FEEDBACK_ENABLED", "true") == "true",
+		DownloadQueueTokens:   loader.get("DOWNLOAD_QUEUE_TOKENS", "false") == "true",
+		GameDir:               loader.get("GAME_DIR", ""),
+		ArchiveCache:          loader.get("ARCHIVE_CACHE", "true") == "true",
+		PrecompressArtifacts:  loader.get("PRECOMPRESS_ARTIFACTS", "true") == "true",
+		ImageWebPCommand:      loader.get("IMAGE_WEBP_COMMAND", ""),
+		ImageAVIFCommand:      loader.get("IMAGE_AVIF_COMMAND", ""),
+		SigningPublicKey:      loader.get("SIGNING_PUBLIC_KEY", ""),
+
+		AccountRegistration: loader.get("ACCOUNT_REGISTRATION", "true") == "true",
+		AdminRequire2FA:     loader.get("ADMIN_REQUIRE_2FA", "false") == "true",
+
+		PlayerListWebhookSecret: loader.secret("PLAYER_LIST_WEBHOOK_SECRET", ""),
+		GameEntitlement:         loader.get("GAME_ENTITLEMENT", ""),
+		StagingDir:              loader.get("STAGING_DIR", ""),
+		StagingEntitlement:      loader.get("STAGING_ENTITLEMENT", "tester"),
+
+		CaptchaSecret:    loader.secret("CAPTCHA_SECRET", ""),
+		CaptchaSiteKey:   loader.get("CAPTCHA_SITE_KEY", ""),
+		CaptchaVerifyURL: loader.get("CAPTCHA_VERIFY_URL", "https://api.hcaptcha.com/siteverify"),
+
+		SMTPHost:          loader.get("SMTP_HOST", ""),
+		SMTPPort:          loader.get("SMTP_PORT", "587"),
+		SMTPUsername:      loader.get("SMTP_USERNAME", ""),
+		SMTPPassword:      loader.secret("SMTP_PASSWORD", ""),
+		SMTPFrom:          loader.get("SMTP_FROM", ""),
+		EmailTemplatesDir: loader.get("EMAIL_TEMPLATES_DIR", ""),
+
+		GameServerSecret:   loader.secret("GAME_SERVER_SECRET", ""),
+		FCMCredentialsFile: loader.get("FCM_CREDENTIALS_FILE", ""),
+		HWIDRequired:       loader.get("HWID_REQUIRED", "false") == "true",
+	}
+	for _, target := range strings.Split(loader.secret("PLAYER_LIST_WEBHOOKS", ""), ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			cfg.PlayerListWebhooks = append(cfg.PlayerListWebhooks, target)
+		}
+	}
+	for _, pattern := range strings.Split(loader.get("VERIFY_IGNORE", "saves/,screenshots/,logs/,mods/,resourcepacks/,options.txt"), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			cfg.VerifyIgnore = append(cfg.VerifyIgnore, pattern)
+		}
+	}
+	levelName := loader.get("LOG_LEVEL", "info")
+	level, ok := parseLogLevel(levelName)
+	if !ok {
+		return fmt.Errorf("некорректный уровень лога LOG_LEVEL=%q, доступны %s", levelName, strings.Join(logLevelNames, ", "))
+	}
+	cfg.LogLevel = level
+	for _, value := range strings.Split(loader.get("LISTEN", ""), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		addr, err := parseListenAddr(value)
+		if err != nil {
+			return fmt.Errorf("ошибка в LISTEN: %v", err)
+		}
+		cfg.Listen = append(cfg.Listen, addr)
+	}
+	for _, sink := range strings.Split(loader.get("LOG_SINKS", "file"), ",") {
+		if sink = strings.TrimSpace(sink); sink != "" {
+			cfg.LogSinks = append(cfg.LogSinks, sink)
+		}
+	}
+	for _, endpoint := range strings.Split(loader.get("CAPTCHA_ENDPOINTS", ""), ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+			continue
+		}
+		if !slices.Contains(captchaEndpoints, endpoint) {
+			return fmt.Errorf("неизвестный адрес в CAPTCHA_ENDPOINTS: %s", endpoint)
+		}
+		cfg.CaptchaEndpoints = append(cfg.CaptchaEndpoints, endpoint)
+	}
+	if len(cfg.CaptchaEndpoints) > 0 && cfg.CaptchaSecret == "" {
+		return fmt.Errorf("для CAPTCHA_ENDPOINTS нужен CAPTCHA_SECRET")
+	}
+	for _, name := range strings.Split(loader.get("DEFAULT_ENTITLEMENTS", "game"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.DefaultEntitlements = append(cfg.DefaultEntitlements, name)
+		}
+	}
+	cfg.OAuthClients = make(map[string]OAuthClient)
+	for name := range oauthProviders {
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+		if id := loader.get(prefix+"CLIENT_ID", ""); id != "" {
+			cfg.OAuthClients[name] = OAuthClient{ClientID: id, ClientSecret: loader.secret(prefix+"CLIENT_SECRET", "")}
+		}
+	}
+	for _, target := range strings.Split(loader.get("OAUTH_REDIRECT_URLS", ""), ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			cfg.OAuthRedirectURLs = append(cfg.OAuthRedirectURLs, target)
+		}
+	}
+	cfg.SigningKey = loader.get("SIGNING_KEY", filepath.Join(cfg.DataDir, "signing_key.pem"))
+	cfg.AccountTokenKey = loader.get("ACCOUNT_TOKEN_KEY", filepath.Join(cfg.DataDir, "account_token_key.pem"))
+	cfg.BlobDir = loader.get("BLOB_DIR", filepath.Join(cfg.DataDir, "blobs"))
+	cfg.PublicURL = strings.TrimRight(loader.get("PUBLIC_URL", "http://localhost:"+cfg.ServerPort), "/")
+	cfg.EmailResetURL = loader.get("EMAIL_RESET_URL", cfg.PublicURL+"/reset-password")
+
+	if cfg.NewsSchedulerInterval, err = loader.getDuration("NEWS_SCHEDULER_INTERVAL", 30*time.Second); err != nil {
+		return err
+	}
+	if cfg.NewsCacheTTL, err = loader.getDuration("NEWS_CACHE_TTL", 2*time.Second); err != nil {
+		return err
+	}
+	if cfg.ImageCacheMaxAge, err = loader.getDuration("IMAGE_CACHE_MAX_AGE", 24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.EventsHeartbeatInterval, err = loader.getDuration("EVENTS_HEARTBEAT_INTERVAL", 25*time.Second); err != nil {
+		return err
+	}
+	if cfg.EventsHistory, err = loader.getInt("EVENTS_HISTORY", 100); err != nil {
+		return err
+	}
+	if cfg.EventsMaxSubscribers, err = loader.getInt("EVENTS_MAX_SUBSCRIBERS", 10000); err != nil {
+		return err
+	}
+	if cfg.EventsHeartbeatInterval <= 0 || cfg.EventsHistory < 1 {
+		return fmt.Errorf("EVENTS_HEARTBEAT_INTERVAL и EVENTS_HISTORY должны быть больше нуля")
+	}
+	if cfg.ClientsWatchInterval, err = loader.getDuration("CLIENTS_WATCH_INTERVAL", 5*time.Second); err != nil {
+		return err
+	}
+	if cfg.TorrentAnnounceInterval, err = loader.getDuration("TORRENT_ANNOUNCE_INTERVAL", 30*time.Minute); err != nil {
+		return err
+	}
+	if cfg.Admin2FASessionTTL, err = loader.getDuration("ADMIN_2FA_SESSION_TTL", 12*time.Hour); err != nil {
+		return err
+	}
+	if cfg.AccountTokenTTL, err = loader.getDuration("ACCOUNT_TOKEN_TTL", time.Hour); err != nil {
+		return err
+	}
+	if cfg.AccountSessionTTL, err = loader.getDuration("ACCOUNT_SESSION_TTL", 30*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.AccountDeletionGrace, err = loader.getDuration("ACCOUNT_DELETION_GRACE", 14*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.LoginAccountMaxFailures, err = loader.getInt("LOGIN_ACCOUNT_MAX_FAILURES", 5); err != nil {
+		return err
+	}
+	if cfg.LoginIPMaxFailures, err = loader.getInt("LOGIN_IP_MAX_FAILURES", 20); err != nil {
+		return err
+	}
+	if cfg.LoginLockoutBase, err = loader.getDuration("LOGIN_LOCKOUT_BASE", 30*time.Second); err != nil {
+		return err
+	}
+	if cfg.LoginLockoutMax, err = loader.getDuration("LOGIN_LOCKOUT_MAX", time.Hour); err != nil {
+		return err
+	}
+	if cfg.LoginCaptchaAfter, err = loader.getInt("LOGIN_CAPTCHA_AFTER", 3); err != nil {
+		return err
+	}
+	if cfg.SyncMaxValueBytes, err = loader.getInt("SYNC_MAX_VALUE_BYTES", 64<<10); err != nil {
+		return err
+	}
+	if cfg.SyncQuotaBytes, err = loader.getInt("SYNC_QUOTA_BYTES", 1<<20); err != nil {
+		return err
+	}
+	if cfg.SavesQuotaBytes, err = loader.getInt("SAVES_QUOTA_BYTES", 256<<20); err != nil {
+		return err
+	}
+	if cfg.SavesChunkSize, err = loader.getInt("SAVES_CHUNK_SIZE", 4<<20); err != nil {
+		return err
+	}
+	if cfg.SavesChunkSize == 0 {
+		return fmt.Errorf("некорректное число SAVES_CHUNK_SIZE=0")
+	}
+	if cfg.SavesKeep, err = loader.getInt("SAVES_KEEP", 5); err != nil {
+		return err
+	}
+	if cfg.SavesMaxAge, err = loader.getDuration("SAVES_MAX_AGE", 90*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.ArtifactKeepVersions, err = loader.getInt("ARTIFACT_KEEP_VERSIONS", 0); err != nil {
+		return err
+	}
+	if cfg.ArtifactKeepDownloaded, err = loader.getDuration("ARTIFACT_KEEP_DOWNLOADED", 30*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.ArtifactGCInterval, err = loader.getDuration("ARTIFACT_GC_INTERVAL", 24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.ArtifactGCInterval <= 0 {
+		return fmt.Errorf("ARTIFACT_GC_INTERVAL должен быть больше нуля")
+	}
+	if cfg.DiskMinFreeBytes, err = loader.getInt("DISK_MIN_FREE_BYTES", 512<<20); err != nil {
+		return err
+	}
+	if cfg.DiskAlertFreeBytes, err = loader.getInt("DISK_ALERT_FREE_BYTES", 2<<30); err != nil {
+		return err
+	}
+	if cfg.DiskCheckInterval, err = loader.getDuration("DISK_CHECK_INTERVAL", time.Minute); err != nil {
+		return err
+	}
+	if cfg.DiskCheckInterval <= 0 {
+		return fmt.Errorf("DISK_CHECK_INTERVAL должен быть больше нуля")
+	}
+	if cfg.ScreenshotMaxBytes, err = loader.getInt("SCREENSHOT_MAX_BYTES", 10<<20); err != nil {
+		return err
+	}
+	if cfg.FeedbackMaxBytes, err = loader.getInt("FEEDBACK_MAX_BYTES", 10<<20); err != nil {
+		return err
+	}
+	if cfg.ScreenshotMaxDimension, err = loader.getInt("SCREENSHOT_MAX_DIMENSION", 1920); err != nil {
+		return err
+	}
+	if cfg.ScreenshotThumbDimension, err = loader.getInt("SCREENSHOT_THUMB_DIMENSION", 320); err != nil {
+		return err
+	}
+	if cfg.ScreenshotMaxDimension == 0 || cfg.ScreenshotThumbDimension == 0 {
+		return fmt.Errorf("размеры скриншотов должны быть больше нуля")
+	}
+	if cfg.ScreenshotPendingLimit, err = loader.getInt("SCREENSHOT_PENDING_LIMIT", 5); err != nil {
+		return err
+	}
+	if cfg.InboxMaxMessages, err = loader.getInt("INBOX_MAX_MESSAGES", 100); err != nil {
+		return err
+	}
+	if cfg.InboxRetention, err = loader.getDuration("INBOX_RETENTION", 90*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.InboxMaxMessages <= 0 || cfg.InboxRetention <= 0 {
+		return fmt.Errorf("INBOX_MAX_MESSAGES и INBOX_RETENTION должны быть больше нуля")
+	}
+	if cfg.FriendsMax, err = loader.getInt("FRIENDS_MAX", 200); err != nil {
+		return err
+	}
+	if cfg.LeaderboardKeepPeriods, err = loader.getInt("LEADERBOARD_KEEP_PERIODS", 12); err != nil {
+		return err
+	}
+	if cfg.LeaderboardKeepPeriods <= 0 {
+		return fmt.Errorf("LEADERBOARD_KEEP_PERIODS должен быть больше нуля")
+	}
+	if cfg.HWIDMatchThreshold, err = loader.getInt("HWID_MATCH_THRESHOLD", 2); err != nil {
+		return err
+	}
+	if cfg.HWIDMatchThreshold <= 0 {
+		return fmt.Errorf("HWID_MATCH_THRESHOLD должен быть больше нуля")
+	}
+	if cfg.AvatarMaxBytes, err = loader.getInt("AVATAR_MAX_BYTES", 2<<20); err != nil {
+		return err
+	}
+	if cfg.AvatarSize, err = loader.getInt("AVATAR_SIZE", 256); err != nil {
+		return err
+	}
+	if cfg.AvatarSize <= 0 {
+		return fmt.Errorf("AVATAR_SIZE должен быть больше нуля")
+	}
+	if cfg.SessionHeartbeatInterval, err = loader.getDuration("SESSION_HEARTBEAT_INTERVAL", time.Minute); err != nil {
+		return err
+	}
+	if cfg.TelemetrySampleRate, err = loader.getRatio("TELEMETRY_SAMPLE_RATE", 1); err != nil {
+		return err
+	}
+	if cfg.PrecompressMinSaving, err = loader.getRatio("PRECOMPRESS_MIN_SAVING", 0.1); err != nil {
+		return err
+	}
+	if cfg.VerifyMaxBytes, err = loader.getInt("VERIFY_MAX_BYTES", 4<<20); err != nil {
+		return err
+	}
+	if cfg.HashWorkers, err = loader.getInt("HASH_WORKERS", 0); err != nil {
+		return err
+	}
+	if cfg.TelemetryMaxBytes, err = loader.getInt("TELEMETRY_MAX_BYTES", 64<<10); err != nil {
+		return err
+	}
+	if cfg.TelemetryMaxEvents, err = loader.getInt("TELEMETRY_MAX_EVENTS", 100); err != nil {
+		return err
+	}
+	if cfg.HardwareSurveyMaxAge, err = loader.getDuration("HARDWARE_SURVEY_MAX_AGE", 180*24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.ReadHeaderTimeout, err = loader.getDuration("READ_HEADER_TIMEOUT", 10*time.Second); err != nil {
+		return err
+	}
+	if cfg.ReadTimeout, err = loader.getDuration("READ_TIMEOUT", 30*time.Second); err != nil {
+		return err
+	}
+	if cfg.IdleTimeout, err = loader.getDuration("IDLE_TIMEOUT", 120*time.Second); err != nil {
+		return err
+	}
+	if cfg.APITimeout, err = loader.getDuration("API_TIMEOUT", 10*time.Second); err != nil {
+		return err
+	}
+	if cfg.DownloadIdleTimeout, err = loader.getDuration("DOWNLOAD_IDLE_TIMEOUT", 60*time.Second); err != nil {
+		return err
+	}
+	if cfg.UpgradeDrainTimeout, err = loader.getDuration("UPGRADE_DRAIN_TIMEOUT", time.Hour); err != nil {
+		return err
+	}
+	if cfg.MaxConcurrentDownloads, err = loader.getInt("MAX_CONCURRENT_DOWNLOADS", 0); err != nil {
+		return err
+	}
+	if cfg.MaxDownloadsPerIP, err = loader.getInt("MAX_DOWNLOADS_PER_IP", 4); err != nil {
+		return err
+	}
+	if cfg.DownloadQueueSize, err = loader.getInt("DOWNLOAD_QUEUE_SIZE", 100); err != nil {
+		return err
+	}
+	if cfg.DownloadQueueTimeout, err = loader.getDuration("DOWNLOAD_QUEUE_TIMEOUT", 10*time.Second); err != nil {
+		return err
+	}
+	if cfg.DownloadQueueMaxTokens, err = loader.getInt("DOWNLOAD_QUEUE_MAX_TOKENS", 10000); err != nil {
+		return err
+	}
+	if cfg.DownloadQueueTokenTTL, err = loader.getDuration("DOWNLOAD_QUEUE_TOKEN_TTL", time.Minute); err != nil {
+		return err
+	}
+	if cfg.DownloadQueueAdmitTTL, err = loader.getDuration("DOWNLOAD_QUEUE_ADMIT_TTL", time.Minute); err != nil {
+		return err
+	}
+	if cfg.DownloadQuotaAccountBytes, err = loader.getInt("DOWNLOAD_QUOTA_ACCOUNT_BYTES", 0); err != nil {
+		return err
+	}
+	if cfg.DownloadQuotaIPBytes, err = loader.getInt("DOWNLOAD_QUOTA_IP_BYTES", 0); err != nil {
+		return err
+	}
+	if cfg.DownloadAbuseIPs, err = loader.getInt("DOWNLOAD_ABUSE_IPS", 50); err != nil {
+		return err
+	}
+	if cfg.DownloadUsageDays, err = loader.getInt("DOWNLOAD_USAGE_DAYS", 14); err != nil {
+		return err
+	}
+	if cfg.DownloadQuotaAccountBytes < 0 || cfg.DownloadQuotaIPBytes < 0 || cfg.DownloadAbuseIPs <= 0 || cfg.DownloadUsageDays <= 0 {
+		return fmt.Errorf("квоты скачивания не могут быть отрицательными, DOWNLOAD_ABUSE_IPS и DOWNLOAD_USAGE_DAYS должны быть больше нуля")
+	}
+	cfg.MirrorPrimaryURL = strings.TrimRight(loader.get("MIRROR_PRIMARY_URL", ""), "/")
+	cfg.MirrorToken = loader.secret("MIRROR_TOKEN", "")
+	// Реплика — зеркало, которое к тому же ничего не принимает на запись
+	cfg.ReplicaOf = strings.TrimRight(loader.get("REPLICA_OF", ""), "/")
+	if cfg.ReplicaOf != "" {
+		if cfg.MirrorPrimaryURL != "" && cfg.MirrorPrimaryURL != cfg.ReplicaOf {
+			return fmt.Errorf("REPLICA_OF и MIRROR_PRIMARY_URL указывают на разные серверы")
+		}
+		cfg.MirrorPrimaryURL = cfg.ReplicaOf
+	}
+	if cfg.MirrorSyncInterval, err = loader.getDuration("MIRROR_SYNC_INTERVAL", 15*time.Minute); err != nil {
+		return err
+	}
+	if cfg.MirrorSyncInterval <= 0 {
+		return fmt.Errorf("MIRROR_SYNC_INTERVAL должен быть больше нуля")
+	}
+	if cfg.MirrorPrimaryURL != "" {
+		if u, err := url.Parse(cfg.MirrorPrimaryURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("MIRROR_PRIMARY_URL: некорректный адрес %q", cfg.MirrorPrimaryURL)
+		}
+		if cfg.MirrorToken == "" {
+			return fmt.Errorf("для MIRROR_PRIMARY_URL нужен MIRROR_TOKEN")
+		}
+	}
+	cfg.RedisURL = loader.secret("REDIS_URL", "")
+	cfg.RedisPrefix = loader.get("REDIS_PREFIX", "loil:")
+	if cfg.RedisTimeout, err = loader.getDuration("REDIS_TIMEOUT", 3*time.Second); err != nil {
+		return err
+	}
+	if cfg.RedisTimeout <= 0 {
+		return fmt.Errorf("REDIS_TIMEOUT должен быть больше нуля")
+	}
+	if cfg.RedisURL != "" {
+		if u, err := url.Parse(cfg.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+			return fmt.Errorf("REDIS_URL: некорректный адрес %q", cfg.RedisURL)
+		}
+	}
+	cfg.S3Endpoint = strings.TrimRight(loader.get("S3_ENDPOINT", "https://s3.amazonaws.com"), "/")
+	cfg.S3Region = loader.get("S3_REGION", "us-east-1")
+	cfg.S3Bucket = loader.get("S3_BUCKET", "")
+	cfg.S3Prefix = loader.get("S3_PREFIX", "")
+	cfg.S3AccessKey = loader.get("S3_ACCESS_KEY", "")
+	cfg.S3SecretKey = loader.secret("S3_SECRET_KEY", "")
+	if cfg.S3PresignTTL, err = loader.getDuration("S3_PRESIGN_TTL", 5*time.Minute); err != nil {
+		return err
+	}
+	// Больше недели SigV4 не позволяет
+	if cfg.S3PresignTTL < time.Second || cfg.S3PresignTTL > 7*24*time.Hour {
+		return fmt.Errorf("S3_PRESIGN_TTL должен быть от 1s до 168h")
+	}
+	if cfg.S3Bucket != "" {
+		if u, err := url.Parse(cfg.S3Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("S3_ENDPOINT: некорректный адрес %q", cfg.S3Endpoint)
+		}
+		if cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return fmt.Errorf("для S3_BUCKET нужны S3_ACCESS_KEY и S3_SECRET_KEY")
+		}
+	}
+	cfg.DownloadDisposition = make(map[string]string)
+	for _, entry := range strings.Split(loader.get("DOWNLOAD_DISPOSITION", "image/*=inline,application/json=inline,text/plain=inline"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, disposition, ok := strings.Cut(entry, "=")
+		name, disposition = strings.TrimSpace(name), strings.TrimSpace(disposition)
+		if !ok || name == "" || (disposition != DispositionInline && disposition != DispositionAttachment) {
+			return fmt.Errorf("DOWNLOAD_DISPOSITION: ожидается тип=inline или тип=attachment, получено %q", entry)
+		}
+		cfg.DownloadDisposition[name] = disposition
+	}
+	if cfg.OverloadMaxGoroutines, err = loader.getInt("OVERLOAD_MAX_GOROUTINES", 10000); err != nil {
+		return err
+	}
+	if cfg.OverloadMaxHeapBytes, err = loader.getInt("OVERLOAD_MAX_HEAP_BYTES", 0); err != nil {
+		return err
+	}
+	if cfg.OverloadMaxDiskLatency, err = loader.getDuration("OVERLOAD_MAX_DISK_LATENCY", time.Second); err != nil {
+		return err
+	}
+	if cfg.OverloadCheckInterval, err = loader.getDuration("OVERLOAD_CHECK_INTERVAL", 5*time.Second); err != nil {
+		return err
+	}
+	if cfg.OverloadRetryAfter, err = loader.getDuration("OVERLOAD_RETRY_AFTER", 30*time.Second); err != nil {
+		return err
+	}
+	if cfg.OverloadMaxGoroutines < 0 || cfg.OverloadMaxHeapBytes < 0 || cfg.OverloadMaxDiskLatency < 0 {
+		return fmt.Errorf("пороги OVERLOAD_MAX_* не могут быть отрицательными")
+	}
+	if cfg.OverloadCheckInterval < time.Second {
+		return fmt.Errorf("OVERLOAD_CHECK_INTERVAL должен быть не меньше 1s")
+	}
+	if cfg.OverloadRetryAfter < time.Second {
+		return fmt.Errorf("OVERLOAD_RETRY_AFTER должен быть не меньше 1s")
+	}
+	if cfg.MaxBodyBytes, err = loader.getInt("MAX_BODY_BYTES", 1<<20); err != nil {
+		return err
+	}
+	if cfg.MaxUploadBytes, err = loader.getInt("MAX_UPLOAD_BYTES", 8<<30); err != nil {
+		return err
+	}
+	if cfg.AdminUploadChunkSize, err = loader.getInt("ADMIN_UPLOAD_CHUNK_SIZE", 64<<20); err != nil {
+		return err
+	}
+	if cfg.AdminUploadChunkSize <= 0 {
+		return fmt.Errorf("ADMIN_UPLOAD_CHUNK_SIZE должен быть больше нуля")
+	}
+	if cfg.TrustedProxies, err = parseCIDRList(loader.get("TRUSTED_PROXIES", "")); err != nil {
+		return fmt.Errorf("ошибка в TRUSTED_PROXIES: %v", err)
+	}
+	if cfg.IPAllowlist, err = parseCIDRList(loader.get("IP_ALLOWLIST", "")); err != nil {
+		return fmt.Errorf("ошибка в IP_ALLOWLIST: %v", err)
+	}
+	if cfg.IPDenylist, err = parseCIDRList(loader.get("IP_DENYLIST", "")); err != nil {
+		return fmt.Errorf("ошибка в IP_DENYLIST: %v", err)
+	}
+	if cfg.StagingAllowedIPs, err = parseCIDRList(loader.get("STAGING_ALLOWED_IPS", "")); err != nil {
+		return fmt.Errorf("ошибка в STAGING_ALLOWED_IPS: %v", err)
+	}
+	if cfg.IPBanNotFoundLimit, err = loader.getInt("IP_BAN_NOT_FOUND_LIMIT", 100); err != nil {
+		return err
+	}
+	if cfg.IPBanAuthFailureLimit, err = loader.getInt("IP_BAN_AUTH_FAILURE_LIMIT", 50); err != nil {
+		return err
+	}
+	if cfg.IPBanRateLimitHits, err = loader.getInt("IP_BAN_RATE_LIMIT_HITS", 20); err != nil {
+		return err
+	}
+	if cfg.IPBanWindow, err = loader.getDuration("IP_BAN_WINDOW", 10*time.Minute); err != nil {
+		return err
+	}
+	if cfg.IPBanDuration, err = loader.getDuration("IP_BAN_DURATION", time.Hour); err != nil {
+		return err
+	}
+	if cfg.LogShipBatchSize, err = loader.getInt("LOG_SHIP_BATCH_SIZE", 500); err != nil {
+		return err
+	}
+	if cfg.LogShipQueueSize, err = loader.getInt("LOG_SHIP_QUEUE_SIZE", 10000); err != nil {
+		return err
+	}
+	if cfg.LogShipFlushInterval, err = loader.getDuration("LOG_SHIP_FLUSH_INTERVAL", 5*time.Second); err != nil {
+		return err
+	}
+
+	if err := applyPublishedVersions(&cfg, loader); err != nil {
+		return err
+	}
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+
+	configSnapshot.Store(&cfg)
+	configArgs = args
+
+	configReportMu.Lock()
+	configReport = loader.entries
+	configReportMu.Unlock()
+	return nil
+}
+
+// Значение параметра с учетом приоритета источников
+func (c *configLoader) get(key, defaultValue string) string {
+	value, source := defaultValue, sourceDefault
+	if v, ok := c.file[strings.ToLower(key)]; ok {
+		value, source = v, sourceFile
+	}
+	if v := c.dotenv[key]; v != "" {
+		value, source = v, sourceDotenv
+	}
+	if v := os.Getenv(key); v != "" {
+		value, source = v, sourceEnv
+	}
+	if v, ok := c.flags[key]; ok {
+		value, source = v, sourceFlag
+	}
+
+	c.entries = append(c.entries, configEntry{Key: key, Value: value, Source: source})
+	return value
+}
+
+// Секретный параметр: читается как обычный, но в отчете скрыт. Секреты
+// объявляются только через этот метод, чтобы новый не попал в лог.
+func (c *configLoader) secret(key, defaultValue string) string {
+	value := c.get(key, defaultValue)
+	c.entries[len(c.entries)-1].Secret = true
+	return value
+}
+
+// Замена значения, уже попавшего в отчет, значением из другого источника
+func (c *configLoader) override(key, value, source string) {
+	for i := range c.entries {
+		if c.entries[i].Key == key {
+			c.entries[i].Value, c.entries[i].Source = value, source
+		}
+	}
+}
+
+func (c *configLoader) getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := c.get(key, defaultValue.String())
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("некорректная длительность %s=%q", key, value)
+	}
+	return d, nil
+}
+
+func (c *configLoader) getInt(key string, defaultValue int) (int, error) {
+	value := c.get(key, strconv.Itoa(defaultValue))
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("некорректное число %s=%q", key, value)
+	}
+	return n, nil
+}
+
+// Доля от 0 до 1, например процент выборки
+func (c *configLoader) getRatio(key string, defaultValue float64) (float64, error) {
+	value := c.get(key, strconv.FormatFloat(defaultValue, 'f', -1, 64))
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 || f > 1 {
+		return 0, fmt.Errorf("некорректная доля %s=%q, ожидается число от 0 до 1", key, value)
+	}
+	return f, nil
+}
+
+// Чтение файла конфигурации. Ключи — имена переменных окружения в нижнем
+// регистре (server_port, game_version, ...). Из YAML поддерживается только
+// плоское подмножество: "ключ: значение" в начале строки, строки в
+// кавычках, комментарии и списки в одну строку [a, b]. Вложенность, списки
+// "- a", многострочные значения, якоря и теги отклоняются с номером
+// строки, а не читаются неправильно.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var raw map[string]interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		for key, value := range raw {
+			switch v := value.(type) {
+			case string:
+				values[strings.ToLower(key)] = v
+			case map[string]interface{}:
+				return nil, fmt.Errorf("%s: вложенные объекты не поддерживаются", key)
+			case []interface{}:
+				parts := make([]string, 0, len(v))
+				for _, item := range v {
+					parts = append(parts, fmt.Sprint(item))
+				}
+				values[strings.ToLower(key)] = strings.Join(parts, ",")
+			default:
+				values[strings.ToLower(key)] = fmt.Sprint(v)
+			}
+		}
+
+	case ".yaml", ".yml":
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for lineNo := 1; scanner.Scan(); lineNo++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+				continue
+			}
+			raw := scanner.Text()
+			if raw[0] == ' ' || raw[0] == '\t' {
+				return nil, fmt.Errorf("строка %d: вложенные структуры не поддерживаются, нужен плоский \"ключ: значение\"", lineNo)
+			}
+			if strings.HasPrefix(line, "-") {
+				return nil, fmt.Errorf("строка %d: списки \"- значение\" не поддерживаются, используйте [a, b]", lineNo)
+			}
+			key, value, ok := strings.Cut(line, ":")
+			key = strings.TrimSpace(key)
+			if !ok || !yamlKeyPattern.MatchString(key) {
+				return nil, fmt.Errorf("строка %d: ожидается \"ключ: значение\"", lineNo)
+			}
+			scalar, err := parseYAMLScalar(value)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %v", lineNo, err)
+			}
+			values[strings.ToLower(key)] = scalar
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат файла %q", filepath.Ext(path))
+	}
+
+	return values, nil
+}
+
+// Ключ плоского YAML: имя параметра без пробелов и вложенности
+var yamlKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Скалярное значение YAML: кавычки, комментарии в конце строки и списки [a, b]
+func parseYAMLScalar(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if len(value) >= 1 && (value[0] == '"' || value[0] == '\'') {
+		end := strings.IndexByte(value[1:], value[0])
+		if end < 0 {
+			return "", fmt.Errorf("не закрыта кавычка, многострочные значения не поддерживаются")
+		}
+		if rest := strings.TrimSpace(value[end+2:]); rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("лишний текст после строки в кавычках: %q", rest)
+		}
+		return value[1 : end+1], nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	if value == "" {
+		return "", nil
+	}
+	switch value[0] {
+	case '|', '>':
+		return "", fmt.Errorf("многострочные значения (%c) не поддерживаются", value[0])
+	case '{':
+		return "", fmt.Errorf("вложенные структуры не поддерживаются, нужен плоский \"ключ: значение\"")
+	case '&', '*', '!':
+		return "", fmt.Errorf("якоря, ссылки и теги YAML не поддерживаются")
+	}
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return "", fmt.Errorf("список [a, b] должен быть записан в одну строку")
+		}
+		parts := strings.Split(value[1:len(value)-1], ",")
+		for i := range parts {
+			parts[i] = strings.Trim(strings.TrimSpace(parts[i]), `"'`)
+			if strings.ContainsAny(parts[i], "[]{}") {
+				return "", fmt.Errorf("вложенные списки не поддерживаются")
+			}
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return value, nil
+}
+
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?$`)
+
+func isSemver(version string) bool {
+	return semverPattern.MatchString(version)
+}
+
+// Проверка конфигурации: ошибки останавливают запуск, предупреждения
+// только выводятся в лог
+func validateConfig(cfg Config) error {
+	var problems []string
+
+	if port, err := strconv.Atoi(cfg.ServerPort); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("SERVER_PORT: некорректный порт %q", cfg.ServerPort))
+	}
+	if !isSemver(cfg.LauncherVersion) {
+		problems = append(problems, fmt.Sprintf("LAUNCHER_VERSION: %q не соответствует semver", cfg.LauncherVersion))
+	}
+	if !isSemver(cfg.GameVersion) {
+		problems = append(problems, fmt.Sprintf("GAME_VERSION: %q не соответствует semver", cfg.GameVersion))
+	}
+	if u, err := url.Parse(cfg.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("PUBLIC_URL: некорректный адрес %q", cfg.PublicURL))
+	}
+	if cfg.SMTPHost != "" {
+		if port, err := strconv.Atoi(cfg.SMTPPort); err != nil || port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("SMTP_PORT: некорректный порт %q", cfg.SMTPPort))
+		}
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			problems = append(problems, fmt.Sprintf("SMTP_FROM: некорректный адрес отправителя %q", cfg.SMTPFrom))
+		}
+	}
+	for name, client := range cfg.OAuthClients {
+		if client.ClientSecret == "" {
+			problems = append(problems, fmt.Sprintf("OAUTH_%s_CLIENT_SECRET: не задан секрет приложения", strings.ToUpper(name)))
+		}
+	}
+	if _, adminPort, err := net.SplitHostPort(cfg.AdminAddr); err != nil || adminPort == cfg.ServerPort {
+		problems = append(problems, fmt.Sprintf("ADMIN_ADDR: нужен отдельный адрес host:port, получено %q", cfg.AdminAddr))
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE и TLS_KEY_FILE задаются вместе")
+	} else if cfg.TLSCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			problems = append(problems, fmt.Sprintf("TLS_CERT_FILE: %v", err))
+		}
+	}
+	if cfg.StagingDir != "" && filepath.Clean(cfg.StagingDir) == filepath.Clean(cfg.ClientsDir) {
+		problems = append(problems, "STAGING_DIR: тестовому каналу нужен свой каталог, отдельный от CLIENTS_DIR")
+	}
+	if cfg.HTTP3 && cfg.TLSCertFile == "" {
+		problems = append(problems, "HTTP3: QUIC работает только с TLS, задайте TLS_CERT_FILE и TLS_KEY_FILE")
+	}
+	if cfg.LauncherClient == "" || cfg.GameClient == "" {
+		problems = append(problems, "LAUNCHER_CLIENT_FILE и GAME_CLIENT_FILE не должны быть пустыми")
+	}
+	if info, err := os.Stat(cfg.ClientsDir); err == nil && !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("CLIENTS_DIR: %s не является каталогом", cfg.ClientsDir))
+	}
+	if network, _, ok := strings.Cut(cfg.SyslogAddr, "://"); cfg.SyslogAddr != "" && (!ok || (network != "udp" && network != "tcp")) {
+		problems = append(problems, fmt.Sprintf("SYSLOG_ADDR: нужен адрес вида udp://host:port или tcp://host:port, получено %q", cfg.SyslogAddr))
+	}
+	for _, sink := range cfg.LogSinks {
+		switch {
+		case !slices.Contains(logSinkNames, sink):
+			problems = append(problems, fmt.Sprintf("LOG_SINKS: неизвестный приемник %q, доступны %s", sink, strings.Join(logSinkNames, ", ")))
+		case (sink == "loki" || sink == "elasticsearch") && cfg.LogShipURL == "":
+			problems = append(problems, fmt.Sprintf("LOG_SHIP_URL: нужен адрес приемника для %s", sink))
+		}
+	}
+	if cfg.LogShipBatchSize == 0 || cfg.LogShipQueueSize < cfg.LogShipBatchSize {
+		problems = append(problems, "LOG_SHIP_BATCH_SIZE: пачка должна быть больше нуля и не больше LOG_SHIP_QUEUE_SIZE")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("некорректная конфигурация:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Предупреждения о конфигурации, не мешающие запуску
+func configWarnings(cfg Config) []string {
+	var warnings []string
+
+	if _, err := os.Stat(cfg.ClientsDir); err != nil {
+		warnings = append(warnings, fmt.Sprintf("каталог клиентов %s недоступен: %v", cfg.ClientsDir, err))
+	} else {
+		for _, name := range []string{cfg.LauncherClient, cfg.GameClient} {
+			if _, err := os.Stat(filepath.Join(cfg.ClientsDir, name)); err != nil {
+				warnings = append(warnings, fmt.Sprintf("файл клиента %s не найден", filepath.Join(cfg.ClientsDir, name)))
+			}
+		}
+	}
+	if _, err := os.Stat(newsFile); err != nil {
+		warnings = append(warnings, fmt.Sprintf("файл новостей %s недоступен: %v", newsFile, err))
+	}
+	if cfg.AdminToken == "" {
+		warnings = append(warnings, "ADMIN_TOKEN не задан, админский API доступен только по ключам из admin_keys.json")
+	}
+
+	return warnings
+}
+
+// Вывод итоговой конфигурации при запуске
+func (l *Logger) printConfigReport() {
+	configReportMu.Lock()
+	entries := append([]configEntry(nil), configReport...)
+	configReportMu.Unlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	l.Println("Итоговая конфигурация:")
+	for _, entry := range entries {
+		value := entry.Value
+		if entry.Secret && value != "" {
+			value = "********"
+		}
+		l.Printf("  %-24s = %-30s [%s]", entry.Key, value, entry.Source)
+	}
+	for _, warning := range configWarnings(*currentConfig()) {
+		l.logWarn("%s", warning)
+	}
+}
+
+// Флаг, который можно указать несколько раз
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Разбор списка CIDR через запятую; одиночный IP считается сетью из одного адреса
+func parseCIDRList(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			ip := net.ParseIP(part)
+			if ip == nil {
+				return nil, fmt.Errorf("некорректный адрес %q", part)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			part = fmt.Sprintf("%s/%d", part, bits)
+		}
+		_, network, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная подсеть %q: %v", part, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"net/url"
+)
+
+// Веб-панель администратора встроена в бинарник и работает поверх
+// админского API; токен вводится в браузере и хранится в sessionStorage.
+// В /admin/docs/ — документация публичного API по спецификации OpenAPI.
+//
+//go:embed web/admin
+var dashboardFiles embed.FS
+
+func dashboardHandler() http.Handler {
+	root, err := fs.Sub(dashboardFiles, "web/admin")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix("/admin/", http.FileServerFS(root))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Документация API делает пробные запросы к публичному API
+		connect := "'self'"
+		if u, err := url.Parse(currentConfig().PublicURL); err == nil && u.Host != "" {
+			connect += " " + u.Scheme + "://" + u.Host
+		}
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:; connect-src "+connect)
+		w.Header().Set("X-Frame-Options", "DENY")
+		files.ServeHTTP(w, r)
+	})
+}
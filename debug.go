@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// Сводка о процессе для разбора проблем с производительностью под
+// нагрузкой, без профилировщика
+type DebugRuntimeResponse struct {
+	GoVersion  string           `json:"go_version"`
+	NumCPU     int              `json:"num_cpu"`
+	GOMAXPROCS int              `json:"gomaxprocs"`
+	Goroutines int              `json:"goroutines"`
+	Uptime     string           `json:"uptime"`
+	Heap       DebugHeapStats   `json:"heap"`
+	GC         DebugGCStats     `json:"gc"`
+	Downloads  DebugStreamStats `json:"downloads"`
+	Overload   OverloadStatus   `json:"overload"`
+}
+
+type DebugHeapStats struct {
+	AllocBytes   uint64 `json:"alloc_bytes"`
+	InUseBytes   uint64 `json:"in_use_bytes"`
+	SysBytes     uint64 `json:"sys_bytes"`
+	Objects      uint64 `json:"objects"`
+	NextGCBytes  uint64 `json:"next_gc_bytes"`
+	TotalAllocMB uint64 `json:"total_alloc_mb"`
+}
+
+type DebugGCStats struct {
+	Count      uint32     `json:"count"`
+	PauseTotal string     `json:"pause_total"`
+	LastPause  string     `json:"last_pause,omitempty"`
+	LastGCAt   *time.Time `json:"last_gc_at,omitempty"`
+}
+
+type DebugStreamStats struct {
+	OpenStreams int64            `json:"open_streams"`
+	ByArtifact  map[string]int64 `json:"by_artifact"`
+}
+
+// Счетчики сервера в /admin/api/debug/vars рядом со стандартными
+// memstats и cmdline
+func publishDebugVars() {
+	expvar.Publish("downloads", expvar.Func(func() any { return downloadStats.Snapshot() }))
+	expvar.Publish("telemetry", expvar.Func(func() any { return telemetryStats.Snapshot() }))
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	expvar.Publish("disk", expvar.Func(func() any { return diskMonitor.Snapshot() }))
+	expvar.Publish("overload", expvar.Func(func() any { return overloadMonitor.Snapshot() }))
+}
+
+func debugRuntimeSnapshot() DebugRuntimeResponse {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	response := DebugRuntimeResponse{
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		Uptime:     time.Since(downloadStats.startedAt).Round(time.Second).String(),
+		Heap: DebugHeapStats{
+			AllocBytes:   mem.HeapAlloc,
+			InUseBytes:   mem.HeapInuse,
+			SysBytes:     mem.Sys,
+			Objects:      mem.HeapObjects,
+			NextGCBytes:  mem.NextGC,
+			TotalAllocMB: mem.TotalAlloc >> 20,
+		},
+		GC: DebugGCStats{
+			Count:      mem.NumGC,
+			PauseTotal: time.Duration(mem.PauseTotalNs).String(),
+		},
+	}
+	if mem.NumGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		response.GC.LastGCAt = &lastGC
+		response.GC.LastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256]).String()
+	}
+	response.Downloads.OpenStreams, response.Downloads.ByArtifact = downloadStats.ActiveStreams()
+	response.Overload = overloadMonitor.Snapshot()
+	return response
+}
+
+// Сводка о процессе: горутины, куча, сборщик мусора, открытые скачивания
+func (l *Logger) adminDebugRuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🩺", "/admin/api/debug/runtime", func() {
+		json.NewEncoder(w).Encode(debugRuntimeSnapshot())
+	})
+}
+
+// Стандартные обработчики net/http/pprof и expvar за админской
+// авторизацией. Слушатель админки отдельный, поэтому на публичный
+// порт они не попадают.
+func (l *Logger) adminDebugHandler(endpoint string, handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopeServerAdmin, "🩺", endpoint, func() {
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Профиль по имени: heap, goroutine, allocs, block, mutex, threadcreate
+func (l *Logger) adminPprofProfileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🩺", "/admin/api/debug/pprof/{profile}", func() {
+		pprof.Handler(r.PathValue("profile")).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Зависимость версии игры (библиотека, нативная библиотека, индекс
+// ресурсов), как в version JSON у Minecraft. Path — куда положить файл
+// относительно каталога игры; OS и Arch — правила платформы, пустое
+// значение подходит для любой.
+type DependencyArtifact struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	URL    string `json:"url,omitempty"`
+	Size   int64  `json:"size"`
+	Hash   string `json:"hash"`
+	SHA256 string `json:"sha256"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+}
+
+type DependencyManifest struct {
+	GameVersion string               `json:"game_version"`
+	Artifacts   []DependencyArtifact `json:"artifacts"`
+	Size        int64                `json:"size"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+type DependencyVersionsResponse struct {
+	Manifests []DependencyManifest `json:"manifests"`
+}
+
+// Сборка манифестов и удаление файлов без ссылок идут под одной
+// блокировкой, чтобы очистка не удалила файл публикуемого манифеста
+var dependenciesMu sync.Mutex
+
+// Манифесты в DATA_DIR/dependencies/<версия>.json, файлы по SHA-256 в
+// DATA_DIR/dependencies/objects/<первые 2 символа>/<sha256>: одинаковые
+// библиотеки разных версий хранятся один раз
+func dependenciesDir() string {
+	return filepath.Join(currentConfig().DataDir, "dependencies")
+}
+
+func dependencyManifestPath(gameVersion string) string {
+	return filepath.Join(dependenciesDir(), gameVersion+".json")
+}
+
+func dependencyObjectPath(hash string) string {
+	return filepath.Join(dependenciesDir(), "objects", hash[:2], hash)
+}
+
+func loadDependencyManifest(gameVersion string) (*DependencyManifest, error) {
+	var manifest *DependencyManifest
+	if err := loadJSONFile(dependencyManifestPath(gameVersion), &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Все опубликованные манифесты по возрастанию версии
+func listDependencyManifests() ([]DependencyManifest, error) {
+	entries, err := os.ReadDir(dependenciesDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var manifests []DependencyManifest
+	for _, entry := range entries {
+		gameVersion, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		manifest, err := loadDependencyManifest(gameVersion)
+		if err != nil {
+			return nil, err
+		}
+		if manifest != nil {
+			manifests = append(manifests, *manifest)
+		}
+	}
+	slices.SortFunc(manifests, func(a, b DependencyManifest) int { return compareVersions(a.GameVersion, b.GameVersion) })
+	return manifests, nil
+}
+
+// Вид и платформа зависимости по пути в архиве сборки: libraries/... —
+// библиотеки, natives/... — нативные, assets/... — ресурсы. Платформу
+// задают каталоги windows, linux, macos и amd64, arm64, x86 или их
+// сочетание вроде natives/windows-amd64/.
+func dependencyRules(name string) (kind, osName, arch string) {
+	parts := strings.Split(name, "/")
+	switch parts[0] {
+	case "libraries":
+		kind = "library"
+	case "natives":
+		kind = "native"
+	case "assets":
+		kind = "asset"
+	default:
+		kind = "file"
+	}
+	for _, dir := range parts[:len(parts)-1] {
+		for _, part := range strings.Split(dir, "-") {
+			switch {
+			case knownRuntimeOS[part]:
+				osName = part
+			case knownRuntimeArch[part]:
+				arch = part
+			}
+		}
+	}
+	return kind, osName, arch
+}
+
+// Манифест из архива зависимостей сборки; файлы раскладываются в
+// хранилище объектов
+func buildDependencyManifest(gameVersion, bundle string) (DependencyManifest, error) {
+	archive, err := zip.OpenReader(bundle)
+	if err != nil {
+		return DependencyManifest{}, err
+	}
+	defer archive.Close()
+
+	manifest := DependencyManifest{GameVersion: gameVersion, Artifacts: []DependencyArtifact{}, CreatedAt: time.Now().UTC()}
+	seen := make(map[string]bool)
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name, ok := normalizeVerifyPath(file.Name)
+		if !ok || seen[name] {
+			return DependencyManifest{}, fmt.Errorf("недопустимый или повторяющийся путь %q", file.Name)
+		}
+		seen[name] = true
+
+		artifact, err := storeDependencyObject(file)
+		if err != nil {
+			return DependencyManifest{}, fmt.Errorf("%s: %w", name, err)
+		}
+		artifact.Path = name
+		artifact.Kind, artifact.OS, artifact.Arch = dependencyRules(name)
+		manifest.Artifacts = append(manifest.Artifacts, artifact)
+		manifest.Size += artifact.Size
+	}
+	slices.SortFunc(manifest.Artifacts, func(a, b DependencyArtifact) int { return strings.Compare(a.Path, b.Path) })
+	return manifest, saveJSONFile(dependencyManifestPath(gameVersion), manifest)
+}
+
+func storeDependencyObject(file *zip.File) (DependencyArtifact, error) {
+	src, err := file.Open()
+	if err != nil {
+		return DependencyArtifact{}, err
+	}
+	defer src.Close()
+
+	dir := filepath.Join(dependenciesDir(), "objects")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return DependencyArtifact{}, err
+	}
+	tmp, err := os.CreateTemp(dir, ".object-*")
+	if err != nil {
+		return DependencyArtifact{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, md5Hash, sha256Hash), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return DependencyArtifact{}, err
+	}
+
+	artifact := DependencyArtifact{
+		Size:   size,
+		Hash:   hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}
+	target := dependencyObjectPath(artifact.SHA256)
+	if _, err := os.Stat(target); err == nil {
+		return artifact, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return DependencyArtifact{}, err
+	}
+	os.Chmod(tmp.Name(), 0644)
+	return artifact, os.Rename(tmp.Name(), target)
+}
+
+// Удаление объектов, на которые не ссылается ни один манифест
+func pruneDependencyObjects() (int, error) {
+	manifests, err := listDependencyManifests()
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool)
+	for _, manifest := range manifests {
+		for _, artifact := range manifest.Artifacts {
+			referenced[artifact.SHA256] = true
+		}
+	}
+
+	removed := 0
+	err = filepath.WalkDir(filepath.Join(dependenciesDir(), "objects"), func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if name := d.Name(); chunkHashPattern.MatchString(name) && !referenced[name] {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// Манифест зависимостей версии игры: /api/dependencies?game_version=1.4.
+// С ?os= и ?arch= остаются только зависимости для этой платформы.
+func (l *Logger) dependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📚", "/api/dependencies", func() {
+		cfg := currentConfig()
+		query := r.URL.Query()
+		gameVersion, osName, arch := query.Get("game_version"), query.Get("os"), query.Get("arch")
+		if gameVersion == "" {
+			gameVersion = cfg.GameVersion
+		}
+		if !versionLabelPattern.MatchString(gameVersion) || (osName != "" && !knownRuntimeOS[osName]) || (arch != "" && !knownRuntimeArch[arch]) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		manifest, err := loadDependencyManifest(gameVersion)
+		if err != nil {
+			l.logError("Ошибка чтения манифеста зависимостей %s: %v", gameVersion, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if manifest == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeDependenciesNotFound, gameVersion)
+			return
+		}
+
+		artifacts := make([]DependencyArtifact, 0, len(manifest.Artifacts))
+		manifest.Size = 0
+		for _, artifact := range manifest.Artifacts {
+			if (osName != "" && artifact.OS != "" && artifact.OS != osName) || (arch != "" && artifact.Arch != "" && artifact.Arch != arch) {
+				continue
+			}
+			artifact.URL = cfg.PublicURL + "/api/dependencies/objects/" + artifact.SHA256
+			artifacts = append(artifacts, artifact)
+			manifest.Size += artifact.Size
+		}
+		manifest.Artifacts = artifacts
+
+		json.NewEncoder(w).Encode(manifest)
+		l.logSuccess("Отправлен манифест зависимостей %s: %d файлов", gameVersion, len(artifacts))
+	})
+}
+
+// Файл зависимости по SHA-256; содержимое неизменно, поэтому кэшируется навсегда
+func (l *Logger) dependencyObjectHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📚", "/api/dependencies/objects/{hash}", func() {
+		hash := r.PathValue("hash")
+		if !chunkHashPattern.MatchString(hash) {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		if l.rejectWithoutEntitlement(w, r, currentConfig().GameEntitlement, "зависимостей игры") {
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		l.serveFileDownload(w, r, dependencyObjectPath(hash), "dependency")
+	})
+}
+
+// Опубликованные манифесты зависимостей
+func (l *Logger) adminListDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📚", "/admin/api/dependencies", func() {
+		manifests, err := listDependencyManifests()
+		if err != nil {
+			l.logError("Ошибка чтения манифестов зависимостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		json.NewEncoder(w).Encode(DependencyVersionsResponse{Manifests: append([]DependencyManifest{}, manifests...)})
+	})
+}
+
+// Публикация зависимостей версии игры: тело — zip с файлами по их путям
+// в каталоге игры. Манифест строится при загрузке; прежний манифест
+// версии заменяется, а файлы, на которые больше никто не ссылается,
+// удаляются.
+func (l *Logger) adminUploadDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📚", "/admin/api/dependencies/{game_version}", func() {
+		gameVersion := r.PathValue("game_version")
+		if !versionLabelPattern.MatchString(gameVersion) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		bundle := filepath.Join(dependenciesDir(), "uploads", gameVersion+".zip")
+		if _, ok := l.receiveUpload(w, r, bundle); !ok {
+			return
+		}
+		defer os.Remove(bundle)
+
+		dependenciesMu.Lock()
+		defer dependenciesMu.Unlock()
+
+		manifest, err := buildDependencyManifest(gameVersion, bundle)
+		if err != nil {
+			l.logError("Ошибка разбора зависимостей %s: %v", gameVersion, err)
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidBundle, err)
+			return
+		}
+		if removed, err := pruneDependencyObjects(); err != nil {
+			l.logError("Ошибка очистки файлов зависимостей: %v", err)
+		} else if removed > 0 {
+			l.logSuccess("Удалено файлов зависимостей без ссылок: %d", removed)
+		}
+
+		json.NewEncoder(w).Encode(manifest)
+		l.logSuccess("Опубликованы зависимости %s: %d файлов (%d bytes)", gameVersion, len(manifest.Artifacts), manifest.Size)
+	})
+}
+
+func (l *Logger) adminDeleteDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📚", "/admin/api/dependencies/{game_version}", func() {
+		gameVersion := r.PathValue("game_version")
+		if !versionLabelPattern.MatchString(gameVersion) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		dependenciesMu.Lock()
+		defer dependenciesMu.Unlock()
+
+		err := os.Remove(dependencyManifestPath(gameVersion))
+		if errors.Is(err, os.ErrNotExist) {
+			writeError(w, r, http.StatusNotFound, ErrCodeDependenciesNotFound, gameVersion)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка удаления манифеста зависимостей %s: %v", gameVersion, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if _, err := pruneDependencyObjects(); err != nil {
+			l.logError("Ошибка очистки файлов зависимостей: %v", err)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Зависимости %s удалены", gameVersion)
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Место на разделе, где лежит каталог
+type DiskUsage struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	FreeBytes  uint64 `json:"free_bytes"`
+	TotalBytes uint64 `json:"total_bytes"`
+	// Свободно меньше DISK_MIN_FREE_BYTES: загрузки из админки отклоняются
+	Low   bool   `json:"low"`
+	Error string `json:"error,omitempty"`
+}
+
+type ReadyResponse struct {
+	Ready bool        `json:"ready"`
+	Disks []DiskUsage `json:"disks"`
+}
+
+// Оповещение в DISK_ALERT_WEBHOOK_URL
+type DiskAlert struct {
+	Name           string    `json:"name"`
+	Path           string    `json:"path"`
+	FreeBytes      uint64    `json:"free_bytes"`
+	TotalBytes     uint64    `json:"total_bytes"`
+	ThresholdBytes int       `json:"threshold_bytes"`
+	Time           time.Time `json:"time"`
+}
+
+// Свободное место в каталогах клиентов, логов и данных. Оповещение
+// отправляется один раз при падении ниже DISK_ALERT_FREE_BYTES и снова —
+// только после того, как место освободилось.
+type DiskMonitor struct {
+	mu      sync.Mutex
+	usage   []DiskUsage
+	alerted map[string]bool
+}
+
+var diskMonitor = &DiskMonitor{alerted: make(map[string]bool)}
+
+func (m *DiskMonitor) Check(cfg *Config) []DiskUsage {
+	dirs := []struct{ name, path string }{
+		{"clients", cfg.ClientsDir},
+		{"logs", "logs"},
+		{"data", cfg.DataDir},
+	}
+	usage := make([]DiskUsage, 0, len(dirs))
+	for _, dir := range dirs {
+		disk := DiskUsage{Name: dir.name, Path: dir.path}
+		free, total, err := diskSpace(dir.path)
+		if err != nil {
+			disk.Error = err.Error()
+		} else {
+			disk.FreeBytes, disk.TotalBytes = free, total
+			disk.Low = free < uint64(cfg.DiskMinFreeBytes)
+		}
+		usage = append(usage, disk)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = usage
+	return usage
+}
+
+// Результат последней проверки (для метрик)
+func (m *DiskMonitor) Snapshot() []DiskUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// Каталоги, о которых пора сообщить: место упало ниже порога впервые
+// с прошлого оповещения
+func (m *DiskMonitor) alerts(cfg *Config, usage []DiskUsage) []DiskUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var fired []DiskUsage
+	for _, disk := range usage {
+		if disk.Error != "" {
+			continue
+		}
+		low := disk.FreeBytes < uint64(cfg.DiskAlertFreeBytes)
+		if low && !m.alerted[disk.Name] {
+			fired = append(fired, disk)
+		}
+		m.alerted[disk.Name] = low
+	}
+	return fired
+}
+
+// Фоновая проверка места раз в DISK_CHECK_INTERVAL
+func (l *Logger) runDiskMonitor() {
+	for {
+		cfg := currentConfig()
+		usage := diskMonitor.Check(cfg)
+		for _, disk := range diskMonitor.alerts(cfg, usage) {
+			l.logError("Мало места на диске для %s (%s): свободно %d из %d bytes", disk.Name, disk.Path, disk.FreeBytes, disk.TotalBytes)
+			if cfg.DiskAlertWebhookURL == "" {
+				continue
+			}
+			alert := DiskAlert{
+				Name:           disk.Name,
+				Path:           disk.Path,
+				FreeBytes:      disk.FreeBytes,
+				TotalBytes:     disk.TotalBytes,
+				ThresholdBytes: cfg.DiskAlertFreeBytes,
+				Time:           time.Now().UTC(),
+			}
+			if err := postJSON(cfg.DiskAlertWebhookURL, alert, nil); err != nil {
+				l.logError("Не удалось отправить оповещение о месте на диске: %v", err)
+			}
+		}
+		time.Sleep(cfg.DiskCheckInterval)
+	}
+}
+
+// Отказ в загрузке, если после нее на разделе останется меньше
+// DISK_MIN_FREE_BYTES. Размер известен заранее из Content-Length; без
+// него проверяется только текущий запас.
+func (l *Logger) rejectLowDisk(w http.ResponseWriter, r *http.Request, dir string) bool {
+	free, _, err := diskSpace(dir)
+	if err != nil {
+		// Не удалось узнать место (например, на неподдерживаемой ОС) —
+		// загрузку не блокируем
+		return false
+	}
+	need := uint64(currentConfig().DiskMinFreeBytes) + uint64(max(r.ContentLength, 0))
+	if free >= need {
+		return false
+	}
+	l.logError("Загрузка в %s отклонена: нужно %d bytes, свободно %d bytes", dir, need, free)
+	writeError(w, r, http.StatusInsufficientStorage, ErrCodeDiskSpaceLow, need, free)
+	return true
+}
+
+// Проверка готовности для балансировщика и оркестратора: 503, пока
+// на каком-либо из разделов меньше DISK_MIN_FREE_BYTES. Пробы приходят
+// часто, поэтому запросы не пишутся в лог.
+func (l *Logger) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	response := ReadyResponse{Ready: true, Disks: diskMonitor.Check(currentConfig())}
+	for _, disk := range response.Disks {
+		if disk.Low {
+			response.Ready = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !response.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
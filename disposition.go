@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Значения Content-Disposition
+const (
+	DispositionInline     = "inline"
+	DispositionAttachment = "attachment"
+)
+
+// Типы файлов лаунчера, которых нет во встроенной таблице Go, а в
+// /etc/mime.types они есть не на каждой машине. Сначала смотрим сюда,
+// чтобы заголовок не зависел от системы, на которой запущен сервер.
+var artifactContentTypes = map[string]string{
+	".jar":      "application/java-archive",
+	".zip":      "application/zip",
+	".gz":       "application/gzip",
+	".tgz":      "application/gzip",
+	".zst":      "application/zstd",
+	".br":       "application/x-brotli",
+	".7z":       "application/x-7z-compressed",
+	".exe":      "application/vnd.microsoft.portable-executable",
+	".msi":      "application/x-msi",
+	".dmg":      "application/x-apple-diskimage",
+	".appimage": "application/vnd.appimage",
+	".deb":      "application/vnd.debian.binary-package",
+	".torrent":  "application/x-bittorrent",
+	".json":     "application/json",
+	".txt":      "text/plain; charset=utf-8",
+	".png":      "image/png",
+	".jpg":      "image/jpeg",
+	".jpeg":     "image/jpeg",
+	".webp":     "image/webp",
+	".avif":     "image/avif",
+}
+
+// MIME-тип по расширению имени файла; неизвестное — application/octet-stream
+func contentTypeByName(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if contentType, ok := artifactContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// inline или attachment для файла: сначала по типу артефакта
+// (launcher, game, mod, проект/артефакт...), затем по MIME-типу
+// (image/png, потом image/*) из DOWNLOAD_DISPOSITION. По умолчанию —
+// attachment.
+func downloadDisposition(cfg *Config, fileType, contentType string) string {
+	if disposition, ok := cfg.DownloadDisposition[fileType]; ok {
+		return disposition
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if disposition, ok := cfg.DownloadDisposition[mediaType]; ok {
+		return disposition
+	}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		if disposition, ok := cfg.DownloadDisposition[major+"/*"]; ok {
+			return disposition
+		}
+	}
+	return DispositionAttachment
+}
+
+// Заголовок Content-Disposition с именем файла по RFC 6266: filename в
+// кавычках для старых клиентов и filename* (RFC 5987) в UTF-8, если в
+// имени есть не-ASCII символы
+func contentDisposition(disposition, filename string) string {
+	var fallback strings.Builder
+	ascii := true
+	for _, r := range filename {
+		switch {
+		case r >= utf8.RuneSelf || r < ' ' || r == 0x7f:
+			ascii = false
+			fallback.WriteByte('_')
+		case r == '"' || r == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(r)
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+
+	header := fmt.Sprintf(`%s; filename="%s"`, disposition, fallback.String())
+	if !ascii {
+		header += "; filename*=UTF-8''" + rfc5987Escape(filename)
+	}
+	return header
+}
+
+// Процентное кодирование байтов UTF-8 для filename*: без кодирования
+// остаются только attr-char из RFC 5987
+func rfc5987Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
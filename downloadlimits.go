@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Через сколько секунд предлагаем повторить скачивание после отказа
+const downloadRetryAfter = 30
+
+// Как часто лаунчеру опрашивать очередь и сколько максимум держать
+// соединение, пока место не освободится
+const (
+	downloadQueuePollAfter = 5
+	downloadQueueMaxWait   = 30 * time.Second
+)
+
+// Открытые потоки полных файлов (клиент игры, лаунчер, рантаймы, моды,
+// ресурспаки, архивы игры) по адресам и в сумме. Адресу достается не
+// больше MAX_DOWNLOADS_PER_IP потоков, всем вместе — MAX_CONCURRENT_DOWNLOADS;
+// при занятом общем лимите запрос до DOWNLOAD_QUEUE_TIMEOUT ждет в очереди.
+// Чанки не учитываются: они маленькие и качаются параллельно по задумке.
+//
+// С DOWNLOAD_QUEUE_TOKENS вместо ожидания на соединении лаунчер встает в
+// очередь через /api/download/queue и получает токен. Очередь допускает
+// по порядку (FIFO), токены адреса тоже считаются в MAX_DOWNLOADS_PER_IP.
+// Допущенному токену место держится DOWNLOAD_QUEUE_ADMIT_TTL.
+type DownloadLimiter struct {
+	mu       sync.Mutex
+	active   int
+	queued   int
+	reserved int
+	perIP    map[string]int
+	tickets  map[string]*downloadTicket
+	waiting  []*downloadTicket
+	// Закрывается и заменяется при каждом освобождении места, чтобы
+	// разбудить ожидающих
+	changed chan struct{}
+}
+
+// Место в очереди на скачивание
+type downloadTicket struct {
+	token         string
+	ip            string
+	seenAt        time.Time
+	admittedUntil time.Time
+}
+
+func (t *downloadTicket) admitted() bool {
+	return !t.admittedUntil.IsZero()
+}
+
+type DownloadStreams struct {
+	Active        int `json:"active"`
+	Queued        int `json:"queued"`
+	Reserved      int `json:"reserved"`
+	MaxConcurrent int `json:"max_concurrent"`
+	MaxPerIP      int `json:"max_per_ip"`
+}
+
+type DownloadQueueStatus struct {
+	Token         string     `json:"token"`
+	Position      int        `json:"position"`
+	Admitted      bool       `json:"admitted"`
+	AdmittedUntil *time.Time `json:"admitted_until,omitempty"`
+	PollAfter     int        `json:"poll_after"`
+}
+
+var downloadLimiter = &DownloadLimiter{
+	perIP:   make(map[string]int),
+	tickets: make(map[string]*downloadTicket),
+	changed: make(chan struct{}),
+}
+
+// Отказ в потоке или месте в очереди: код ошибки и аргументы сообщения
+type downloadRejection struct {
+	code string
+	args []any
+}
+
+// Есть ли свободный поток (под блокировкой)
+func (d *DownloadLimiter) free(cfg *Config) bool {
+	return cfg.MaxConcurrentDownloads == 0 || d.active+d.reserved < cfg.MaxConcurrentDownloads
+}
+
+// Разбудить ожидающих (под блокировкой)
+func (d *DownloadLimiter) broadcast() {
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// Занять поток для адреса. Допущенный токен очереди занимает
+// придержанное для него место. Возвращенную функцию нужно вызвать один раз
+// по окончании отдачи файла.
+func (d *DownloadLimiter) Acquire(ctx context.Context, cfg *Config, ip, token string) (func(), *downloadRejection) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ticket, ok := d.tickets[token]; ok && ticket.admitted() {
+		delete(d.tickets, token)
+		d.reserved--
+		d.active++
+		return d.releaser(ticket.ip), nil
+	}
+
+	// Ожидающие в очереди тоже занимают место адреса, иначе повторы
+	// одного клиента заполнили бы очередь
+	if cfg.MaxDownloadsPerIP > 0 && d.perIP[ip] >= cfg.MaxDownloadsPerIP {
+		return nil, &downloadRejection{ErrCodeDownloadLimitPerIP, []any{cfg.MaxDownloadsPerIP, downloadRetryAfter}}
+	}
+	d.perIP[ip]++
+
+	// Без токена нельзя обойти тех, кто уже стоит в очереди
+	var deadline <-chan time.Time
+	for !d.free(cfg) || len(d.waiting) > 0 {
+		if cfg.DownloadQueueTokens {
+			d.leave(ip)
+			return nil, &downloadRejection{ErrCodeDownloadQueueRequired, nil}
+		}
+		if d.queued >= cfg.DownloadQueueSize {
+			d.leave(ip)
+			return nil, &downloadRejection{ErrCodeDownloadsBusy, []any{cfg.MaxConcurrentDownloads, downloadRetryAfter}}
+		}
+		if deadline == nil {
+			timer := time.NewTimer(cfg.DownloadQueueTimeout)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+
+		d.queued++
+		changed := d.changed
+		d.mu.Unlock()
+		gaveUp := false
+		select {
+		case <-changed:
+		case <-deadline:
+			gaveUp = true
+		case <-ctx.Done():
+			gaveUp = true
+		}
+		d.mu.Lock()
+		d.queued--
+		if gaveUp {
+			d.leave(ip)
+			return nil, &downloadRejection{ErrCodeDownloadsBusy, []any{cfg.MaxConcurrentDownloads, downloadRetryAfter}}
+		}
+	}
+
+	d.active++
+	return d.releaser(ip), nil
+}
+
+func (d *DownloadLimiter) releaser(ip string) func() {
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.active--
+		d.leave(ip)
+		d.admit(currentConfig(), time.Now())
+		d.broadcast()
+	}
+}
+
+// Освобождение места адреса (под блокировкой)
+func (d *DownloadLimiter) leave(ip string) {
+	d.perIP[ip]--
+	if d.perIP[ip] <= 0 {
+		delete(d.perIP, ip)
+	}
+}
+
+// Допуск очереди по порядку, пока есть свободные потоки (под блокировкой)
+func (d *DownloadLimiter) admit(cfg *Config, now time.Time) bool {
+	admitted := false
+	for len(d.waiting) > 0 && d.free(cfg) {
+		ticket := d.waiting[0]
+		d.waiting = d.waiting[1:]
+		ticket.admittedUntil = now.Add(cfg.DownloadQueueAdmitTTL)
+		d.reserved++
+		admitted = true
+	}
+	return admitted
+}
+
+// Встать в очередь; если потоки свободны и очереди нет, токен допускается сразу
+func (d *DownloadLimiter) Join(cfg *Config, ip string, now time.Time) (DownloadQueueStatus, *downloadRejection) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cfg.MaxDownloadsPerIP > 0 && d.perIP[ip] >= cfg.MaxDownloadsPerIP {
+		return DownloadQueueStatus{}, &downloadRejection{ErrCodeDownloadLimitPerIP, []any{cfg.MaxDownloadsPerIP, downloadRetryAfter}}
+	}
+	if len(d.waiting) >= cfg.DownloadQueueMaxTokens {
+		return DownloadQueueStatus{}, &downloadRejection{ErrCodeDownloadQueueFull, []any{downloadRetryAfter}}
+	}
+
+	ticket := &downloadTicket{token: randomID(16), ip: ip, seenAt: now}
+	d.perIP[ip]++
+	d.tickets[ticket.token] = ticket
+	d.waiting = append(d.waiting, ticket)
+	if d.admit(cfg, now) {
+		d.broadcast()
+	}
+	return d.status(ticket), nil
+}
+
+// Положение токена в очереди; опрос продлевает токен на
+// DOWNLOAD_QUEUE_TOKEN_TTL. changed закроется, когда очередь сдвинется.
+func (d *DownloadLimiter) Status(token string, now time.Time) (status DownloadQueueStatus, changed <-chan struct{}, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ticket, ok := d.tickets[token]
+	if !ok {
+		return DownloadQueueStatus{}, nil, false
+	}
+	ticket.seenAt = now
+	return d.status(ticket), d.changed, true
+}
+
+func (d *DownloadLimiter) status(ticket *downloadTicket) DownloadQueueStatus {
+	status := DownloadQueueStatus{Token: ticket.token, PollAfter: downloadQueuePollAfter}
+	if ticket.admitted() {
+		until := ticket.admittedUntil
+		status.Admitted, status.AdmittedUntil = true, &until
+		return status
+	}
+	status.Position = slices.Index(d.waiting, ticket) + 1
+	return status
+}
+
+// Выход из очереди или отказ от допущенного места
+func (d *DownloadLimiter) Leave(cfg *Config, token string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ticket, ok := d.tickets[token]
+	if !ok {
+		return false
+	}
+	d.drop(ticket)
+	if d.admit(cfg, now) {
+		d.broadcast()
+	}
+	return true
+}
+
+// Удаление токена с освобождением его мест (под блокировкой)
+func (d *DownloadLimiter) drop(ticket *downloadTicket) {
+	delete(d.tickets, ticket.token)
+	d.leave(ticket.ip)
+	if ticket.admitted() {
+		d.reserved--
+		return
+	}
+	if i := slices.Index(d.waiting, ticket); i >= 0 {
+		d.waiting = slices.Delete(d.waiting, i, i+1)
+	}
+}
+
+// Забыть брошенные токены: ожидающие без опроса дольше
+// DOWNLOAD_QUEUE_TOKEN_TTL и допущенные, но не начавшие скачивание
+func (d *DownloadLimiter) Sweep(cfg *Config, now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dropped := 0
+	for _, ticket := range d.tickets {
+		if (ticket.admitted() && now.After(ticket.admittedUntil)) ||
+			(!ticket.admitted() && now.Sub(ticket.seenAt) > cfg.DownloadQueueTokenTTL) {
+			d.drop(ticket)
+			dropped++
+		}
+	}
+	if d.admit(cfg, now) || dropped > 0 {
+		d.broadcast()
+	}
+	return dropped
+}
+
+func (d *DownloadLimiter) Snapshot(cfg *Config) DownloadStreams {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return DownloadStreams{
+		Active:        d.active,
+		Queued:        d.queued + len(d.waiting),
+		Reserved:      d.reserved,
+		MaxConcurrent: cfg.MaxConcurrentDownloads,
+		MaxPerIP:      cfg.MaxDownloadsPerIP,
+	}
+}
+
+func (l *Logger) runDownloadQueue() {
+	for {
+		time.Sleep(time.Second)
+		if dropped := downloadLimiter.Sweep(currentConfig(), time.Now()); dropped > 0 {
+			l.Printf("🎟️ Из очереди на скачивание убрано брошенных токенов: %d", dropped)
+		}
+	}
+}
+
+// Отказ в потоке или месте в очереди: 429 с Retry-After
+func (l *Logger) rejectDownload(w http.ResponseWriter, r *http.Request, what string, rejection *downloadRejection) {
+	l.logError("Скачивание %s для %s отклонено: %s", what, getClientIP(r), rejection.code)
+	w.Header().Set("Retry-After", strconv.Itoa(downloadRetryAfter))
+	writeError(w, r, http.StatusTooManyRequests, rejection.code, rejection.args...)
+}
+
+// Поток для скачивания файла. Токен очереди передается в заголовке
+// X-Download-Queue-Token или параметре queue_token. При отказе ответ уже
+// записан.
+func (l *Logger) acquireDownload(w http.ResponseWriter, r *http.Request, what string) (func(), bool) {
+	token := r.Header.Get("X-Download-Queue-Token")
+	if token == "" {
+		token = r.URL.Query().Get("queue_token")
+	}
+	release, rejection := downloadLimiter.Acquire(r.Context(), currentConfig(), getClientIP(r), token)
+	if rejection != nil {
+		l.rejectDownload(w, r, what, rejection)
+		return nil, false
+	}
+	return release, true
+}
+
+// Постановка в очередь на скачивание
+func (l *Logger) downloadQueueJoinHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎟️", "/api/download/queue", func() {
+		cfg := currentConfig()
+		if !cfg.DownloadQueueTokens {
+			writeError(w, r, http.StatusForbidden, ErrCodeDownloadQueueDisabled)
+			return
+		}
+		status, rejection := downloadLimiter.Join(cfg, getClientIP(r), time.Now())
+		if rejection != nil {
+			l.rejectDownload(w, r, "очереди", rejection)
+			return
+		}
+
+		json.NewEncoder(w).Encode(status)
+		l.logSuccess("Выдан токен очереди на скачивание, позиция %d", status.Position)
+	})
+}
+
+// Положение в очереди. С ?wait=<секунды> ответ придет, как только место
+// будет допущено, но не позже wait (до 30 с).
+func (l *Logger) downloadQueueStatusHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎟️", "/api/download/queue/{token}", func() {
+		wait, _ := strconv.Atoi(r.URL.Query().Get("wait"))
+		deadline := time.NewTimer(min(time.Duration(max(wait, 0))*time.Second, downloadQueueMaxWait))
+		defer deadline.Stop()
+
+		for {
+			status, changed, ok := downloadLimiter.Status(r.PathValue("token"), time.Now())
+			if !ok {
+				writeError(w, r, http.StatusNotFound, ErrCodeDownloadQueueTokenNotFound)
+				return
+			}
+			if status.Admitted || wait <= 0 {
+				json.NewEncoder(w).Encode(status)
+				return
+			}
+
+			select {
+			case <-changed:
+			case <-deadline.C:
+				wait = 0
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+}
+
+// Выход из очереди, если скачивание больше не нужно
+func (l *Logger) downloadQueueLeaveHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎟️", "/api/download/queue/{token}", func() {
+		if !downloadLimiter.Leave(currentConfig(), r.PathValue("token"), time.Now()) {
+			writeError(w, r, http.StatusNotFound, ErrCodeDownloadQueueTokenNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
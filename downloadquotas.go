@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Сколько адресов аккаунта помнить за сутки: для отчета важно только,
+// что их больше DOWNLOAD_ABUSE_IPS
+const maxDownloadUsageIPs = 1000
+
+// Отметки отчета об использовании трафика
+const (
+	DownloadFlagManyIPs       = "many_ips"
+	DownloadFlagQuotaExceeded = "quota_exceeded"
+)
+
+// Трафик аккаунта или адреса за сутки (UTC)
+type DownloadUsage struct {
+	Bytes     int64 `json:"bytes"`
+	Downloads int64 `json:"downloads"`
+	// Отказы по исчерпанной квоте
+	Rejected int64 `json:"rejected,omitempty"`
+	// Адреса, с которых качал аккаунт
+	IPs []string `json:"ips,omitempty"`
+}
+
+type downloadUsageDay struct {
+	Accounts map[string]*DownloadUsage `json:"accounts"`
+	IPs      map[string]*DownloadUsage `json:"ips"`
+}
+
+// Строка отчета: аккаунт (Username) или адрес (IP)
+type DownloadUsageEntry struct {
+	Username  string   `json:"username,omitempty"`
+	IP        string   `json:"ip,omitempty"`
+	Bytes     int64    `json:"bytes"`
+	Downloads int64    `json:"downloads"`
+	Rejected  int64    `json:"rejected,omitempty"`
+	IPs       int      `json:"ips,omitempty"`
+	Flags     []string `json:"flags,omitempty"`
+}
+
+// Отчет о трафике за сутки: /admin/api/downloads/usage
+type DownloadUsageResponse struct {
+	Day               string               `json:"day"`
+	QuotaAccountBytes int                  `json:"quota_account_bytes"`
+	QuotaIPBytes      int                  `json:"quota_ip_bytes"`
+	Accounts          []DownloadUsageEntry `json:"accounts"`
+	IPs               []DownloadUsageEntry `json:"ips"`
+	// Аккаунты и адреса с отметками, чтобы не искать их в длинных списках
+	Flagged []DownloadUsageEntry `json:"flagged"`
+}
+
+// Учет трафика по суткам в DATA_DIR/download_usage.json. Считается на
+// каждый запрос, а на диск сбрасывается раз в минуту, как индекс хэшей.
+type DownloadUsageStore struct {
+	mu     sync.Mutex
+	days   map[string]*downloadUsageDay
+	dirty  bool
+	saveMu sync.Mutex
+}
+
+var downloadUsage = &DownloadUsageStore{days: make(map[string]*downloadUsageDay)}
+
+func downloadUsageFile() string {
+	return filepath.Join(currentConfig().DataDir, "download_usage.json")
+}
+
+func usageDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func (s *DownloadUsageStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := loadJSONFile(downloadUsageFile(), &s.days); err != nil {
+		return err
+	}
+	if s.days == nil {
+		s.days = make(map[string]*downloadUsageDay)
+	}
+	return nil
+}
+
+// Сутки по дате (под блокировкой)
+func (s *DownloadUsageStore) day(day string) *downloadUsageDay {
+	d, ok := s.days[day]
+	if !ok {
+		d = &downloadUsageDay{Accounts: make(map[string]*DownloadUsage), IPs: make(map[string]*DownloadUsage)}
+		s.days[day] = d
+	}
+	return d
+}
+
+func usageOf(m map[string]*DownloadUsage, key string) *DownloadUsage {
+	usage, ok := m[key]
+	if !ok {
+		usage = &DownloadUsage{}
+		m[key] = usage
+	}
+	return usage
+}
+
+// Трафик за сегодня: аккаунта, если он есть, иначе адреса
+func (s *DownloadUsageStore) Used(accountID, ip string, now time.Time) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.days[usageDay(now)]
+	if !ok {
+		return 0
+	}
+	if usage := d.Accounts[accountID]; accountID != "" && usage != nil {
+		return usage.Bytes
+	}
+	if usage := d.IPs[ip]; accountID == "" && usage != nil {
+		return usage.Bytes
+	}
+	return 0
+}
+
+// Учет отданного файла или его части. true — у аккаунта только что
+// набралось DOWNLOAD_ABUSE_IPS адресов за сутки.
+func (s *DownloadUsageStore) Record(accountID, ip string, bytes int64, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.day(usageDay(now))
+	s.dirty = true
+	byIP := usageOf(d.IPs, ip)
+	byIP.Bytes += bytes
+	byIP.Downloads++
+	if accountID == "" {
+		return false
+	}
+
+	usage := usageOf(d.Accounts, accountID)
+	usage.Bytes += bytes
+	usage.Downloads++
+	if slices.Contains(usage.IPs, ip) || len(usage.IPs) >= maxDownloadUsageIPs {
+		return false
+	}
+	usage.IPs = append(usage.IPs, ip)
+	return len(usage.IPs) == currentConfig().DownloadAbuseIPs
+}
+
+// Учет отказа по квоте
+func (s *DownloadUsageStore) Reject(accountID, ip string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.day(usageDay(now))
+	s.dirty = true
+	if accountID != "" {
+		usageOf(d.Accounts, accountID).Rejected++
+	} else {
+		usageOf(d.IPs, ip).Rejected++
+	}
+}
+
+// Сброс сегодняшнего трафика аккаунта, например после обращения в поддержку
+func (s *DownloadUsageStore) Reset(accountID string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d, ok := s.days[usageDay(now)]; ok {
+		delete(d.Accounts, accountID)
+		s.dirty = true
+	}
+}
+
+// Копия суток для отчета
+func (s *DownloadUsageStore) Day(day string) (map[string]DownloadUsage, map[string]DownloadUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, ips := make(map[string]DownloadUsage), make(map[string]DownloadUsage)
+	if d, ok := s.days[day]; ok {
+		for id, usage := range d.Accounts {
+			accounts[id] = *usage
+		}
+		for ip, usage := range d.IPs {
+			ips[ip] = *usage
+		}
+	}
+	return accounts, ips
+}
+
+// Трафик аккаунта по суткам, для выгрузки данных
+func (s *DownloadUsageStore) Account(accountID string) map[string]DownloadUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]DownloadUsage)
+	for day, d := range s.days {
+		if usage, ok := d.Accounts[accountID]; ok {
+			result[day] = *usage
+		}
+	}
+	return result
+}
+
+// Удаление учета аккаунта вместе с ним; учет по адресам остается
+func (s *DownloadUsageStore) Forget(accountID string) error {
+	s.mu.Lock()
+	for _, d := range s.days {
+		if _, ok := d.Accounts[accountID]; ok {
+			delete(d.Accounts, accountID)
+			s.dirty = true
+		}
+	}
+	s.mu.Unlock()
+	return s.Save(time.Now())
+}
+
+// Сохранение, если что-то изменилось; сутки старше DOWNLOAD_USAGE_DAYS
+// отбрасываются
+func (s *DownloadUsageStore) Save(now time.Time) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	oldest := usageDay(now.AddDate(0, 0, 1-currentConfig().DownloadUsageDays))
+	for day := range s.days {
+		if day < oldest {
+			delete(s.days, day)
+			s.dirty = true
+		}
+	}
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	days := make(map[string]downloadUsageDay, len(s.days))
+	for day, d := range s.days {
+		copied := downloadUsageDay{Accounts: make(map[string]*DownloadUsage, len(d.Accounts)), IPs: make(map[string]*DownloadUsage, len(d.IPs))}
+		for id, usage := range d.Accounts {
+			u := *usage
+			u.IPs = slices.Clone(usage.IPs)
+			copied.Accounts[id] = &u
+		}
+		for ip, usage := range d.IPs {
+			u := *usage
+			copied.IPs[ip] = &u
+		}
+		days[day] = copied
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	if err := saveJSONFile(downloadUsageFile(), days); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (l *Logger) runDownloadUsage() {
+	for {
+		time.Sleep(time.Minute)
+		if err := downloadUsage.Save(time.Now()); err != nil {
+			l.logError("Ошибка сохранения учета трафика: %v", err)
+		}
+	}
+}
+
+// Квота для запроса: аккаунта, если игрок вошел, иначе адреса
+func downloadQuota(cfg *Config, accountID string) int {
+	if accountID != "" {
+		return cfg.DownloadQuotaAccountBytes
+	}
+	return cfg.DownloadQuotaIPBytes
+}
+
+// Проверка суточной квоты перед отдачей файла; возвращает ID аккаунта для
+// учета трафика. При отказе ответ уже записан: 429 с Retry-After до
+// полуночи UTC, когда квота обновится.
+func (l *Logger) checkDownloadQuota(w http.ResponseWriter, r *http.Request, what string) (string, bool) {
+	account, _ := authenticateAccount(r)
+	accountID := account.ID
+	cfg, now, ip := currentConfig(), time.Now(), getClientIP(r)
+	quota := downloadQuota(cfg, accountID)
+	if quota == 0 || downloadUsage.Used(accountID, ip, now) < int64(quota) {
+		return accountID, true
+	}
+
+	downloadUsage.Reject(accountID, ip, now)
+	midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	retryAfter := int(midnight.Sub(now).Seconds()) + 1
+	l.logWarn("Скачивание %s для %s отклонено: суточная квота исчерпана (аккаунт %q)", what, ip, account.Username)
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeError(w, r, http.StatusTooManyRequests, ErrCodeDownloadQuotaExceeded, (quota+1<<20-1)>>20, retryAfter)
+	return accountID, false
+}
+
+// Учет отданного; о подозрительном аккаунте сообщаем один раз за сутки
+func (l *Logger) recordDownloadUsage(r *http.Request, accountID string, bytes int64) {
+	if downloadUsage.Record(accountID, getClientIP(r), bytes, time.Now()) {
+		username := accountID
+		if account, ok := accounts.ByID(accountID); ok {
+			username = account.Username
+		}
+		l.logWarn("Аккаунт %s за сутки качал с %d адресов: возможна раздача аккаунта", username, currentConfig().DownloadAbuseIPs)
+	}
+}
+
+func downloadUsageEntries(cfg *Config, usage map[string]DownloadUsage, account bool) []DownloadUsageEntry {
+	entries := []DownloadUsageEntry{}
+	for key, u := range usage {
+		entry := DownloadUsageEntry{Bytes: u.Bytes, Downloads: u.Downloads, Rejected: u.Rejected, IPs: len(u.IPs)}
+		if account {
+			entry.Username = key
+			if a, ok := accounts.ByID(key); ok {
+				entry.Username = a.Username
+			}
+			if len(u.IPs) >= cfg.DownloadAbuseIPs {
+				entry.Flags = append(entry.Flags, DownloadFlagManyIPs)
+			}
+		} else {
+			entry.IP = key
+		}
+		if u.Rejected > 0 {
+			entry.Flags = append(entry.Flags, DownloadFlagQuotaExceeded)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Bytes > entries[j].Bytes })
+	return entries
+}
+
+// Отчет о трафике: /admin/api/downloads/usage?day=2026-10-16&limit=50 —
+// самые активные аккаунты и адреса и все отмеченные
+func (l *Logger) adminDownloadUsageHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeStatsRead, "📶", "/admin/api/downloads/usage", func() {
+		query := r.URL.Query()
+		day := query.Get("day")
+		if day == "" {
+			day = usageDay(time.Now())
+		}
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if query.Get("limit") == "" {
+			limit, err = 50, nil
+		}
+		if _, dayErr := time.Parse("2006-01-02", day); dayErr != nil || err != nil || limit <= 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		cfg := currentConfig()
+		byAccount, byIP := downloadUsage.Day(day)
+		response := DownloadUsageResponse{
+			Day:               day,
+			QuotaAccountBytes: cfg.DownloadQuotaAccountBytes,
+			QuotaIPBytes:      cfg.DownloadQuotaIPBytes,
+			Accounts:          downloadUsageEntries(cfg, byAccount, true),
+			IPs:               downloadUsageEntries(cfg, byIP, false),
+			Flagged:           []DownloadUsageEntry{},
+		}
+		for _, entry := range slices.Concat(response.Accounts, response.IPs) {
+			if len(entry.Flags) > 0 {
+				response.Flagged = append(response.Flagged, entry)
+			}
+		}
+		response.Accounts = response.Accounts[:min(limit, len(response.Accounts))]
+		response.IPs = response.IPs[:min(limit, len(response.IPs))]
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Сброс сегодняшней квоты аккаунта
+func (l *Logger) adminResetDownloadUsageHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "📶", "/admin/api/downloads/usage/{username}", func() {
+		username := r.PathValue("username")
+		account, ok := accounts.ByUsername(username)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, username)
+			return
+		}
+		downloadUsage.Reset(account.ID, time.Now())
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Сброшен суточный трафик аккаунта %s", account.Username)
+	})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Итоги скачивания по отчету лаунчера
+const (
+	DownloadReportOK        = "ok"
+	DownloadReportTruncated = "truncated"
+	DownloadReportCorrupted = "corrupted"
+	DownloadReportFailed    = "failed"
+)
+
+const (
+	// Сколько живет токен скачивания: за это время лаунчер докачивает
+	// файл и присылает отчет
+	downloadReceiptTTL = 24 * time.Hour
+	// Сколько разных пар версия/зеркало учитывать; остальные зеркала
+	// попадают в "other", чтобы клиенты не раздували статистику
+	maxDownloadReportKeys = 1000
+	maxMirrorLength       = 100
+)
+
+// Что отдано по токену скачивания: с этим сервер сверяет отчет
+type downloadReceipt struct {
+	Artifact string
+	Version  string
+	Filename string
+	Size     int64
+	Hash     string
+	SHA256   string
+	Resumes  int
+	IssuedAt time.Time
+}
+
+// Отчет лаунчера о скачивании. Token — из заголовка X-Download-Token
+// ответа; без него (файл скачан с зеркала) указывается Artifact, и отчет
+// сверяется с текущей сборкой launcher или game. Hash — MD5 или SHA-256
+// полученного файла.
+type DownloadReportRequest struct {
+	Token         string `json:"token"`
+	Artifact      string `json:"artifact"`
+	Mirror        string `json:"mirror"`
+	Completed     bool   `json:"completed"`
+	BytesReceived int64  `json:"bytes_received"`
+	Hash          string `json:"hash"`
+	Error         string `json:"error,omitempty"`
+}
+
+type DownloadReportResponse struct {
+	Result       string `json:"result"`
+	Artifact     string `json:"artifact"`
+	Version      string `json:"version,omitempty"`
+	ExpectedSize int64  `json:"expected_size"`
+	ExpectedHash string `json:"expected_hash"`
+}
+
+// Итоги скачиваний версии артефакта через одно зеркало
+type DownloadReportStats struct {
+	Artifact  string  `json:"artifact"`
+	Version   string  `json:"version,omitempty"`
+	Mirror    string  `json:"mirror"`
+	Reports   int64   `json:"reports"`
+	OK        int64   `json:"ok"`
+	Truncated int64   `json:"truncated"`
+	Corrupted int64   `json:"corrupted"`
+	Failed    int64   `json:"failed"`
+	Resumed   int64   `json:"resumed"`
+	Bytes     int64   `json:"bytes"`
+	OKRate    float64 `json:"ok_rate"`
+}
+
+type downloadReportKey struct {
+	Artifact, Version, Mirror string
+}
+
+// Токены скачиваний и итоги отчетов с момента запуска сервера
+type DownloadReports struct {
+	mu       sync.Mutex
+	receipts map[string]*downloadReceipt
+	stats    map[downloadReportKey]*DownloadReportStats
+}
+
+var downloadReports = &DownloadReports{
+	receipts: make(map[string]*downloadReceipt),
+	stats:    make(map[downloadReportKey]*DownloadReportStats),
+}
+
+// Версия для статистики; у модов и рантаймов версия в пути, а не в
+// конфигурации, поэтому для них пустая
+func reportedVersion(cfg *Config, artifact string) string {
+	if artifact != "launcher" && artifact != "game" {
+		return ""
+	}
+	return artifactVersion(cfg, artifact)
+}
+
+// Токен для отдачи файла. Докачка с прежним токеном того же файла
+// продолжает его, иначе выдается новый.
+func (d *DownloadReports) Issue(token, artifact, filename string, size int64, file ClientFile) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if receipt, ok := d.receipts[token]; ok && receipt.Artifact == artifact && receipt.SHA256 == file.SHA256 {
+		receipt.Resumes++
+		return token
+	}
+	for id, receipt := range d.receipts {
+		if now.Sub(receipt.IssuedAt) > downloadReceiptTTL {
+			delete(d.receipts, id)
+		}
+	}
+
+	token = randomID(16)
+	d.receipts[token] = &downloadReceipt{
+		Artifact: artifact,
+		Version:  reportedVersion(currentConfig(), artifact),
+		Filename: filename,
+		Size:     size,
+		Hash:     file.Hash,
+		SHA256:   file.SHA256,
+		IssuedAt: now,
+	}
+	return token
+}
+
+// Токен отчета; отчет закрывает токен
+func (d *DownloadReports) take(token string) (downloadReceipt, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	receipt, ok := d.receipts[token]
+	if !ok {
+		return downloadReceipt{}, false
+	}
+	delete(d.receipts, token)
+	return *receipt, true
+}
+
+func (d *DownloadReports) Record(receipt downloadReceipt, mirror, result string, bytes int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := downloadReportKey{receipt.Artifact, receipt.Version, mirror}
+	stats, ok := d.stats[key]
+	if !ok && len(d.stats) >= maxDownloadReportKeys {
+		key.Mirror = "other"
+		stats, ok = d.stats[key]
+	}
+	if !ok {
+		stats = &DownloadReportStats{Artifact: key.Artifact, Version: key.Version, Mirror: key.Mirror}
+		d.stats[key] = stats
+	}
+
+	stats.Reports++
+	stats.Bytes += bytes
+	if receipt.Resumes > 0 {
+		stats.Resumed++
+	}
+	switch result {
+	case DownloadReportOK:
+		stats.OK++
+	case DownloadReportTruncated:
+		stats.Truncated++
+	case DownloadReportCorrupted:
+		stats.Corrupted++
+	default:
+		stats.Failed++
+	}
+	stats.OKRate = float64(stats.OK) / float64(stats.Reports)
+}
+
+// Итоги по версиям и зеркалам, худшие сверху
+func (d *DownloadReports) Snapshot() []DownloadReportStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]DownloadReportStats, 0, len(d.stats))
+	for _, stats := range d.stats {
+		result = append(result, *stats)
+	}
+	slices.SortFunc(result, func(a, b DownloadReportStats) int {
+		if a.OKRate != b.OKRate {
+			if a.OKRate < b.OKRate {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Artifact+a.Version+a.Mirror, b.Artifact+b.Version+b.Mirror)
+	})
+	return result
+}
+
+// Имя зеркала: хост из URL или имя как есть; пустое — сам сервер
+func normalizeMirror(mirror string) string {
+	mirror = strings.TrimSpace(mirror)
+	if u, err := url.Parse(mirror); err == nil && u.Host != "" {
+		mirror = u.Host
+	}
+	if mirror == "" {
+		return "origin"
+	}
+	if len(mirror) > maxMirrorLength {
+		mirror = mirror[:maxMirrorLength]
+	}
+	return strings.ToLower(mirror)
+}
+
+// Итог скачивания: меньше байт — обрыв, другой размер или хэш — порча
+func downloadResult(receipt downloadReceipt, req DownloadReportRequest) string {
+	switch {
+	case !req.Completed:
+		return DownloadReportFailed
+	case req.BytesReceived < receipt.Size:
+		return DownloadReportTruncated
+	case req.BytesReceived != receipt.Size:
+		return DownloadReportCorrupted
+	case req.Hash != "" && !strings.EqualFold(req.Hash, receipt.Hash) && !strings.EqualFold(req.Hash, receipt.SHA256):
+		return DownloadReportCorrupted
+	}
+	return DownloadReportOK
+}
+
+// Отчет лаунчера о завершенном или неудавшемся скачивании. Доли успешных,
+// оборванных и испорченных скачиваний по версиям и зеркалам видны в
+// /admin/api/stats и выдают плохое зеркало или ошибку докачки.
+func (l *Logger) downloadReportHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧾", "/api/download/report", func() {
+		var req DownloadReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		if req.BytesReceived < 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		var receipt downloadReceipt
+		switch {
+		case req.Token != "":
+			var ok bool
+			if receipt, ok = downloadReports.take(req.Token); !ok {
+				writeError(w, r, http.StatusNotFound, ErrCodeDownloadTokenNotFound)
+				return
+			}
+		default:
+			cfg := currentConfig()
+			filename, ok := artifactFilename(cfg, req.Artifact)
+			if !ok {
+				writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, req.Artifact)
+				return
+			}
+			path := filepath.Join(cfg.ClientsDir, filename)
+			info, err := os.Stat(path)
+			if err != nil {
+				writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+				return
+			}
+			file, err := clientIndex.Lookup(path, info)
+			if err != nil {
+				l.logError("Ошибка вычисления хэша файла %s: %v", path, err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+				return
+			}
+			receipt = downloadReceipt{
+				Artifact: req.Artifact,
+				Version:  reportedVersion(cfg, req.Artifact),
+				Filename: filename,
+				Size:     file.Size,
+				Hash:     file.Hash,
+				SHA256:   file.SHA256,
+			}
+		}
+
+		mirror := normalizeMirror(req.Mirror)
+		result := downloadResult(receipt, req)
+		downloadReports.Record(receipt, mirror, result, req.BytesReceived)
+
+		json.NewEncoder(w).Encode(DownloadReportResponse{
+			Result:       result,
+			Artifact:     receipt.Artifact,
+			Version:      receipt.Version,
+			ExpectedSize: receipt.Size,
+			ExpectedHash: receipt.Hash,
+		})
+		if result != DownloadReportOK {
+			l.logError("Скачивание %s через %s: %s (%d из %d bytes) %s", receipt.Filename, mirror, result, req.BytesReceived, receipt.Size, req.Error)
+			return
+		}
+		l.logSuccess("Скачивание %s через %s подтверждено", receipt.Filename, mirror)
+	})
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Назначение одноразовых токенов из писем и сроки их действия
+const (
+	emailTokenVerify = "verify"
+	emailTokenReset  = "reset"
+
+	emailVerifyTTL = 48 * time.Hour
+	emailResetTTL  = time.Hour
+
+	maxEmailLength = 254
+)
+
+// Ограничение писем: на аккаунт и на адрес клиента за час
+var (
+	emailAccountSends = &attemptLimiter{limit: 3, window: time.Hour, failures: make(map[string]*attemptWindow)}
+	emailIPSends      = &attemptLimiter{limit: 10, window: time.Hour, failures: make(map[string]*attemptWindow)}
+)
+
+// Встроенные шаблоны писем: первая строка — тема, дальше текст.
+// Заменяются файлами EMAIL_TEMPLATES_DIR/<шаблон>.<язык>.txt.
+var emailTemplates = map[string]map[string]string{
+	emailTokenVerify: {
+		"ru": `Подтверждение почты LOIL
+
+Здравствуйте, {{.Username}}!
+
+Чтобы подтвердить адрес почты для аккаунта LOIL, откройте ссылку:
+{{.Link}}
+
+Ссылка действует {{.Hours}} ч. Если вы не регистрировались, просто удалите это письмо.
+`,
+		"en": `Confirm your LOIL email
+
+Hello, {{.Username}}!
+
+To confirm the email address of your LOIL account, open this link:
+{{.Link}}
+
+The link is valid for {{.Hours}} h. If you did not sign up, just delete this email.
+`,
+	},
+	emailTokenReset: {
+		"ru": `Восстановление пароля LOIL
+
+Здравствуйте, {{.Username}}!
+
+Для аккаунта LOIL запрошена смена пароля. Чтобы задать новый пароль, откройте ссылку:
+{{.Link}}
+
+Ссылка действует {{.Hours}} ч и сработает один раз. Если вы не запрашивали смену пароля, ничего делать не нужно.
+`,
+		"en": `LOIL password reset
+
+Hello, {{.Username}}!
+
+A password reset was requested for your LOIL account. To set a new password, open this link:
+{{.Link}}
+
+The link is valid for {{.Hours}} h and works once. If you did not request it, no action is needed.
+`,
+	},
+}
+
+// Данные для подстановки в шаблон письма
+type emailTemplateData struct {
+	Username string
+	Link     string
+	Hours    int
+}
+
+type AccountEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type EmailTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// Запрос восстановления: имя аккаунта или адрес почты
+type ForgotPasswordRequest struct {
+	Login string `json:"login"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Содержимое токена из письма. State — отпечаток состояния аккаунта:
+// после подтверждения почты или смены пароля токен перестает подходить.
+type emailTokenClaims struct {
+	Purpose   string `json:"p"`
+	AccountID string `json:"a"`
+	ExpiresAt int64  `json:"e"`
+	State     string `json:"s"`
+}
+
+// Секрет подписи токенов из писем в DATA_DIR/email_token_secret
+var emailTokenSecret []byte
+
+func emailTokenSecretFile() string {
+	return filepath.Join(currentConfig().DataDir, "email_token_secret")
+}
+
+func loadEmailTokenSecret() error {
+	data, err := os.ReadFile(emailTokenSecretFile())
+	if errors.Is(err, os.ErrNotExist) {
+		data = []byte(randomID(32))
+		if err := os.MkdirAll(filepath.Dir(emailTokenSecretFile()), 0700); err != nil {
+			return err
+		}
+		err = os.WriteFile(emailTokenSecretFile(), data, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	emailTokenSecret = bytes.TrimSpace(data)
+	return nil
+}
+
+func emailTokenState(purpose string, account Account) string {
+	var input string
+	switch purpose {
+	case emailTokenVerify:
+		input = fmt.Sprintf("%s\x00%s\x00%t", purpose, strings.ToLower(account.Email), account.EmailVerified)
+	case emailTokenReset:
+		input = purpose + "\x00" + account.PasswordHash
+	}
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:8])
+}
+
+func emailTokenSignature(payload string) []byte {
+	mac := hmac.New(sha256.New, emailTokenSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func issueEmailToken(purpose string, account Account, ttl time.Duration, now time.Time) string {
+	claims, _ := json.Marshal(emailTokenClaims{
+		Purpose:   purpose,
+		AccountID: account.ID,
+		ExpiresAt: now.Add(ttl).Unix(),
+		State:     emailTokenState(purpose, account),
+	})
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(emailTokenSignature(payload))
+}
+
+// Проверка подписи и срока токена; аккаунт должен быть в том же
+// состоянии, что и при выдаче
+func verifyEmailToken(token, purpose string, now time.Time) (Account, bool) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Account{}, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, emailTokenSignature(payload)) {
+		return Account{}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return Account{}, false
+	}
+	var claims emailTokenClaims
+	if err := json.Unmarshal(data, &claims); err != nil || claims.Purpose != purpose || now.Unix() >= claims.ExpiresAt {
+		return Account{}, false
+	}
+	account, found := accounts.ByID(claims.AccountID)
+	if !found || !hmac.Equal([]byte(emailTokenState(purpose, account)), []byte(claims.State)) {
+		return Account{}, false
+	}
+	return account, true
+}
+
+// Адрес почты без имени и лишних пробелов; false, если адрес некорректный
+func normalizeEmail(email string) (string, bool) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || len(email) > maxEmailLength {
+		return "", false
+	}
+	return email, true
+}
+
+// Тема и текст письма по шаблону на нужном языке
+func renderEmail(cfg *Config, name, lang string, data emailTemplateData) (string, string, error) {
+	source, ok := emailTemplates[name][lang]
+	if !ok {
+		lang = "ru"
+		source = emailTemplates[name][lang]
+	}
+	if cfg.EmailTemplatesDir != "" {
+		if custom, err := os.ReadFile(filepath.Join(cfg.EmailTemplatesDir, name+"."+lang+".txt")); err == nil {
+			source = string(custom)
+		}
+	}
+
+	tmpl, err := template.New(name).Parse(source)
+	if err != nil {
+		return "", "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", "", err
+	}
+	subject, body, _ := strings.Cut(buf.String(), "\n")
+	return strings.TrimSpace(subject), strings.TrimLeft(body, "\n"), nil
+}
+
+// Отправка письма через SMTP. На порту 465 соединение сразу шифруется,
+// на остальных — через STARTTLS, если сервер его поддерживает.
+func sendEmail(cfg *Config, to, subject, body string) error {
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return fmt.Errorf("SMTP_FROM: %v", err)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: <%s@%s>\r\n", randomID(16), cfg.SMTPHost)
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&message)
+	qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	qp.Close()
+
+	addr := net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort)
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	tlsConfig := &tls.Config{ServerName: cfg.SMTPHost}
+	var conn net.Conn
+	if cfg.SMTPPort == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	client, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && cfg.SMTPPort != "465" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if cfg.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(message.Bytes()); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Письмо с одноразовой ссылкой. Отправляется в фоне, чтобы время ответа
+// не выдавало, существует ли аккаунт.
+func (l *Logger) sendAccountEmail(r *http.Request, purpose string, account Account) {
+	cfg := currentConfig()
+	now := time.Now()
+	emailAccountSends.Fail("account:"+account.ID, now)
+	emailIPSends.Fail("ip:"+getClientIP(r), now)
+
+	ttl, link := emailVerifyTTL, cfg.PublicURL+"/api/auth/verify"
+	if purpose == emailTokenReset {
+		ttl, link = emailResetTTL, cfg.EmailResetURL
+	}
+	separator := "?"
+	if strings.Contains(link, "?") {
+		separator = "&"
+	}
+	link += separator + "token=" + url.QueryEscape(issueEmailToken(purpose, account, ttl, now))
+
+	subject, body, err := renderEmail(cfg, purpose, messageLanguage(r), emailTemplateData{
+		Username: account.Username,
+		Link:     link,
+		Hours:    int(ttl.Hours()),
+	})
+	if err != nil {
+		l.logError("Ошибка шаблона письма %s: %v", purpose, err)
+		return
+	}
+	go func() {
+		if err := sendEmail(cfg, account.Email, subject, body); err != nil {
+			l.logError("Ошибка отправки письма %s аккаунту %s: %v", purpose, account.Username, err)
+			return
+		}
+		l.logSuccess("Письмо %s отправлено аккаунту %s", purpose, account.Username)
+	}()
+}
+
+// Отказ, если почта не настроена; при отказе ответ уже записан
+func rejectWithoutEmail(w http.ResponseWriter, r *http.Request) bool {
+	if currentConfig().SMTPHost == "" {
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeEmailDisabled)
+		return true
+	}
+	return false
+}
+
+// Привязка или смена почты с отправкой письма для подтверждения. Повторный
+// запрос с той же неподтвержденной почтой отправляет письмо заново.
+// Если у аккаунта есть пароль, его нужно ввести.
+func (l *Logger) accountEmailHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "✉️", "/api/account/email", func() {
+		account, ok := requireAccount(w, r)
+		if !ok || rejectWithoutEmail(w, r) {
+			return
+		}
+		var req AccountEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Password) > maxPasswordLength {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		email, valid := normalizeEmail(req.Email)
+		if !valid {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidEmail)
+			return
+		}
+		if account.PasswordHash != "" && !checkPassword(account.PasswordHash, req.Password) {
+			l.logError("Неверный пароль при смене почты аккаунта %s с %s", account.Username, getClientIP(r))
+			writeError(w, r, http.StatusUnauthorized, ErrCodeInvalidCredentials)
+			return
+		}
+		if account.EmailVerified && strings.EqualFold(account.Email, email) {
+			json.NewEncoder(w).Encode(account.info())
+			return
+		}
+		if emailAccountSends.Reject(w, r, "account:"+account.ID) || emailIPSends.Reject(w, r, "ip:"+getClientIP(r)) {
+			return
+		}
+
+		updated, apiErr, err := accounts.SetEmail(account.ID, email)
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		l.sendAccountEmail(r, emailTokenVerify, updated)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(updated.info())
+	})
+}
+
+// Подтверждение почты: POST с токеном из лаунчера или GET по ссылке из
+// письма — тогда ответом будет короткий текст для браузера
+func (l *Logger) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "✉️", "/api/auth/verify", func() {
+		var req EmailTokenRequest
+		if r.Method == http.MethodGet {
+			req.Token = r.URL.Query().Get("token")
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		account, ok := verifyEmailToken(req.Token, emailTokenVerify, time.Now())
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidEmailToken)
+			return
+		}
+		email := account.Email
+		updated, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			if a.Email != email {
+				return &modError{http.StatusBadRequest, ErrCodeInvalidEmailToken, nil}
+			}
+			a.EmailVerified = true
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		l.logSuccess("Аккаунт %s подтвердил почту", updated.Username)
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintln(w, translate(r, "email_verified"))
+			return
+		}
+		json.NewEncoder(w).Encode(updated.info())
+	})
+}
+
+// Запрос письма для смены пароля. Ответ всегда 202, чтобы по нему нельзя
+// было узнать, есть ли такой аккаунт; письмо уходит только на
+// подтвержденную почту.
+func (l *Logger) forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "✉️", "/api/auth/forgot", func() {
+		if rejectWithoutEmail(w, r) {
+			return
+		}
+		var req ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Login) == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if emailIPSends.Reject(w, r, "ip:"+getClientIP(r)) {
+			return
+		}
+
+		login := strings.TrimSpace(req.Login)
+		account, found := accounts.ByUsername(login)
+		if !found {
+			account, found = accounts.ByEmail(login)
+		}
+		switch {
+		case !found || !account.EmailVerified:
+			l.logError("Восстановление пароля для %s с %s: нет аккаунта с подтвержденной почтой", login, getClientIP(r))
+		case emailAccountSends.Blocked("account:"+account.ID, time.Now()) > 0:
+			l.logError("Восстановление пароля для %s: превышен лимит писем", account.Username)
+		default:
+			l.sendAccountEmail(r, emailTokenReset, account)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Смена пароля по токену из письма. Токен одноразовый: с новым паролем
+// меняется отпечаток аккаунта. Все сессии аккаунта при этом закрываются.
+func (l *Logger) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "✉️", "/api/auth/reset", func() {
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+			writeError(w, r, http.StatusBadRequest, ErrCodeWeakPassword, minPasswordLength)
+			return
+		}
+
+		account, ok := verifyEmailToken(req.Token, emailTokenReset, time.Now())
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidEmailToken)
+			return
+		}
+		hash, err := hashPassword(req.Password)
+		if err != nil {
+			l.logError("Ошибка хэширования пароля: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		previous := account.PasswordHash
+		_, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			// Токен мог быть использован параллельным запросом
+			if a.PasswordHash != previous {
+				return &modError{http.StatusBadRequest, ErrCodeInvalidEmailToken, nil}
+			}
+			a.PasswordHash = hash
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		l.revokeAccountSessions(account)
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Пароль аккаунта %s изменен по письму с %s", account.Username, getClientIP(r))
+	})
+}
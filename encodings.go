@@ -0,0 +1,290 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Сжатый вариант файла для Content-Encoding
+type contentEncoding struct {
+	Name string
+	Ext  string
+}
+
+// В порядке предпочтения сервера при равном q. gzip сервер строит сам
+// при публикации; zstd без внешних зависимостей не сжать, его готовит
+// сборочный конвейер и загружает через /admin/api/upload/{artifact}/encodings/zstd.
+var contentEncodings = []contentEncoding{
+	{Name: "zstd", Ext: ".zst"},
+	{Name: "gzip", Ext: ".gz"},
+}
+
+// Сжатые варианты лежат рядом с файлом скрытыми (.<имя>.gz), чтобы
+// каталог клиентов их не индексировал, а удаление каталога версии мода
+// или ресурспака удаляло и их
+type EncodedVariant struct {
+	Encoding string `json:"encoding"`
+	Size     int64  `json:"size"`
+	Hash     string `json:"hash"`
+	SHA256   string `json:"sha256"`
+}
+
+// Варианты файла; SourceSHA256 привязывает их к версии исходного файла,
+// после замены файла старые варианты не отдаются. Precompressed — сервер
+// уже пробовал сжать эту версию (gzip мог не попасть в список, если файл
+// не сжимается).
+type EncodedVariants struct {
+	SourceSHA256  string           `json:"source_sha256"`
+	Precompressed bool             `json:"precompressed"`
+	Variants      []EncodedVariant `json:"variants"`
+}
+
+var encodingsMu sync.Mutex
+
+func encodedVariantPath(path string, encoding contentEncoding) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+encoding.Ext)
+}
+
+func encodedVariantsPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".encodings.json")
+}
+
+func encodingByName(name string) (contentEncoding, bool) {
+	i := slices.IndexFunc(contentEncodings, func(e contentEncoding) bool { return e.Name == name })
+	if i < 0 {
+		return contentEncoding{}, false
+	}
+	return contentEncodings[i], true
+}
+
+// Варианты текущей версии файла; nil, если их нет или файл заменен
+func loadEncodedVariants(path, sourceSHA256 string) *EncodedVariants {
+	var variants EncodedVariants
+	if err := loadJSONFile(encodedVariantsPath(path), &variants); err != nil || variants.SourceSHA256 != sourceSHA256 {
+		return nil
+	}
+	return &variants
+}
+
+// Запись варианта вместо прежнего того же сжатия (под encodingsMu).
+// Пустой вариант (Size 0) только отмечает, что сжатие не окупилось.
+func saveEncodedVariant(path, sourceSHA256 string, variant EncodedVariant) error {
+	variants := loadEncodedVariants(path, sourceSHA256)
+	if variants == nil {
+		variants = &EncodedVariants{SourceSHA256: sourceSHA256}
+	}
+	if variant.Encoding == "gzip" {
+		variants.Precompressed = true
+	}
+	variants.Variants = slices.DeleteFunc(variants.Variants, func(v EncodedVariant) bool { return v.Encoding == variant.Encoding })
+	if variant.Size > 0 {
+		variants.Variants = append(variants.Variants, variant)
+	}
+	return saveJSONFile(encodedVariantsPath(path), variants)
+}
+
+// Удаление файла вместе с его сжатыми вариантами
+func removeWithEncodings(path string) {
+	os.Remove(path)
+	blobs.Release(path)
+	os.Remove(encodedVariantsPath(path))
+	for _, encoding := range contentEncodings {
+		os.Remove(encodedVariantPath(path, encoding))
+	}
+}
+
+// Сжатие файла в gzip при публикации. Вариант остается, только если
+// экономит не меньше PRECOMPRESS_MIN_SAVING; иначе в списке вариантов
+// gzip нет, и файл отдается как есть.
+func buildGzipVariant(cfg *Config, path string, source ClientFile) (EncodedVariant, error) {
+	encoding, _ := encodingByName("gzip")
+	target := encodedVariantPath(path, encoding)
+
+	in, err := os.Open(path)
+	if err != nil {
+		return EncodedVariant{}, err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return EncodedVariant{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(tmp, md5Hash, sha256Hash)}
+	zw, _ := gzip.NewWriterLevel(counter, gzip.BestCompression)
+	_, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return EncodedVariant{}, err
+	}
+
+	if float64(counter.n) > float64(source.Size)*(1-cfg.PrecompressMinSaving) {
+		os.Remove(target)
+		return EncodedVariant{Encoding: encoding.Name}, nil
+	}
+	os.Chmod(tmp.Name(), 0644)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return EncodedVariant{}, err
+	}
+	return EncodedVariant{
+		Encoding: encoding.Name,
+		Size:     counter.n,
+		Hash:     hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256:   hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Построение gzip-варианта опубликованного файла, если его еще нет для
+// этой версии. Вызывается при загрузке и при появлении файла в каталоге
+// клиентов; долгое, поэтому из обработчиков — в отдельной горутине.
+func (l *Logger) syncEncodedVariants(cfg *Config, path string, source ClientFile) {
+	if !cfg.PrecompressArtifacts {
+		return
+	}
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	variants := loadEncodedVariants(path, source.SHA256)
+	if variants != nil && variants.Precompressed {
+		return
+	}
+	if variants == nil {
+		// Варианты прежней версии файла больше не подходят
+		for _, encoding := range contentEncodings {
+			os.Remove(encodedVariantPath(path, encoding))
+		}
+	}
+
+	variant, err := buildGzipVariant(cfg, path, source)
+	if err != nil {
+		l.logError("Ошибка сжатия %s: %v", path, err)
+		return
+	}
+	if err := saveEncodedVariant(path, source.SHA256, variant); err != nil {
+		l.logError("Ошибка сохранения вариантов %s: %v", path, err)
+		return
+	}
+	if variant.Size == 0 {
+		l.Printf("🗜️ %s почти не сжимается, отдается как есть", filepath.Base(path))
+		return
+	}
+	l.logSuccess("Подготовлен gzip-вариант %s: %d → %d bytes", filepath.Base(path), source.Size, variant.Size)
+}
+
+// Сжатие, которое клиент принимает с наибольшим q; при равном q —
+// по порядку contentEncodings. false — отдавать как есть.
+func negotiateEncoding(r *http.Request, available []EncodedVariant) (EncodedVariant, bool) {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name = strings.ToLower(name); name != "" {
+			accepted[name] = q
+		}
+	}
+
+	var best EncodedVariant
+	bestQ := 0.0
+	for _, encoding := range contentEncodings {
+		q, ok := accepted[encoding.Name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if !ok || q <= bestQ {
+			continue
+		}
+		if i := slices.IndexFunc(available, func(v EncodedVariant) bool { return v.Encoding == encoding.Name }); i >= 0 {
+			best, bestQ = available[i], q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// Загрузка готового zstd-варианта сборки. X-Source-SHA256 — хэш
+// несжатой сборки, из которой сделан вариант: он должен совпасть с
+// текущей, иначе игроки получили бы другую версию.
+func (l *Logger) adminUploadEncodingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🗜️", "/admin/api/upload/{artifact}/encodings/{encoding}", func() {
+		cfg := currentConfig()
+		artifact := r.PathValue("artifact")
+		filename, ok := artifactFilename(cfg, artifact)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+		encoding, ok := encodingByName(r.PathValue("encoding"))
+		if !ok || encoding.Name == "gzip" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeUnknownEncoding, r.PathValue("encoding"))
+			return
+		}
+
+		path := filepath.Join(cfg.ClientsDir, filename)
+		info, err := os.Stat(path)
+		if err != nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		source, err := clientIndex.Lookup(path, info)
+		if err != nil {
+			l.logError("Ошибка вычисления хэша файла %s: %v", path, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+		if !strings.EqualFold(r.Header.Get("X-Source-SHA256"), source.SHA256) {
+			writeError(w, r, http.StatusConflict, ErrCodeEncodingSourceMismatch, source.SHA256)
+			return
+		}
+
+		file, ok := l.receiveUpload(w, r, encodedVariantPath(path, encoding))
+		if !ok {
+			return
+		}
+		variant := EncodedVariant{Encoding: encoding.Name, Size: file.Size, Hash: file.MD5, SHA256: file.SHA256}
+		encodingsMu.Lock()
+		err = saveEncodedVariant(path, source.SHA256, variant)
+		encodingsMu.Unlock()
+		if err != nil {
+			l.logError("Ошибка сохранения вариантов %s: %v", path, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+
+		json.NewEncoder(w).Encode(variant)
+		l.logSuccess("Загружен %s-вариант %s (%d bytes)", encoding.Name, filename, file.Size)
+	})
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Источники прав аккаунта
+const (
+	EntitlementSourceDefault = "default"
+	EntitlementSourceAdmin   = "admin"
+	EntitlementSourcePromo   = "promo"
+)
+
+// Право аккаунта: base game, beta, supporter и т.п.
+type Entitlement struct {
+	Name      string     `json:"name"`
+	Source    string     `json:"source"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type EntitlementsResponse struct {
+	Account      AccountInfo   `json:"account"`
+	Entitlements []Entitlement `json:"entitlements"`
+}
+
+// Для админки: итоговые права и откуда они взялись
+type AdminEntitlementsResponse struct {
+	EntitlementsResponse
+	Grants      []EntitlementGrant `json:"grants"`
+	Redemptions []PromoRedemption  `json:"redemptions"`
+}
+
+// Право, выданное вручную из админки
+type EntitlementGrant struct {
+	Entitlement string     `json:"entitlement"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Note        string     `json:"note,omitempty"`
+	GrantedBy   string     `json:"granted_by"`
+	GrantedAt   time.Time  `json:"granted_at"`
+}
+
+func (g EntitlementGrant) active(now time.Time) bool {
+	return g.ExpiresAt == nil || now.Before(*g.ExpiresAt)
+}
+
+// Запрос на выдачу; срок задается моментом или длительностью ("720h")
+type EntitlementGrantRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	Duration  string     `json:"duration"`
+	Note      string     `json:"note"`
+}
+
+// Выданные права в DATA_DIR/entitlements.json по ID аккаунта
+type EntitlementStore struct {
+	mu     sync.Mutex
+	grants map[string][]EntitlementGrant
+}
+
+var entitlementGrants = &EntitlementStore{}
+
+func entitlementsFile() string {
+	return filepath.Join(currentConfig().DataDir, "entitlements.json")
+}
+
+func (s *EntitlementStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grants = make(map[string][]EntitlementGrant)
+	return loadJSONFile(entitlementsFile(), &s.grants)
+}
+
+func (s *EntitlementStore) Grants(accountID string) []EntitlementGrant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]EntitlementGrant{}, s.grants[accountID]...)
+}
+
+// Выдача права; повторная выдача заменяет срок и заметку
+func (s *EntitlementStore) Grant(accountID string, grant EntitlementGrant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := slices.DeleteFunc(slices.Clone(s.grants[accountID]), func(g EntitlementGrant) bool {
+		return g.Entitlement == grant.Entitlement
+	})
+	return s.replace(accountID, append(list, grant))
+}
+
+// Отзыв права; false, если оно не выдавалось
+func (s *EntitlementStore) Revoke(accountID, entitlement string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.grants[accountID]
+	i := slices.IndexFunc(list, func(g EntitlementGrant) bool { return g.Entitlement == entitlement })
+	if i < 0 {
+		return false, nil
+	}
+	return true, s.replace(accountID, slices.Delete(slices.Clone(list), i, i+1))
+}
+
+// Отзыв всех прав аккаунта, например при его удалении
+func (s *EntitlementStore) Forget(accountID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.grants[accountID]) == 0 {
+		return nil
+	}
+	return s.replace(accountID, nil)
+}
+
+// Замена прав аккаунта с сохранением (под блокировкой)
+func (s *EntitlementStore) replace(accountID string, list []EntitlementGrant) error {
+	next := make(map[string][]EntitlementGrant, len(s.grants))
+	for id, grants := range s.grants {
+		next[id] = grants
+	}
+	if len(list) == 0 {
+		delete(next, accountID)
+	} else {
+		next[accountID] = list
+	}
+	if err := saveJSONFile(entitlementsFile(), next); err != nil {
+		return err
+	}
+	s.grants = next
+	return nil
+}
+
+// Все действующие права аккаунта: общие для всех из DEFAULT_ENTITLEMENTS,
+// выданные из админки и полученные по промокодам
+func accountEntitlements(cfg *Config, account Account, now time.Time) []Entitlement {
+	result := []Entitlement{}
+	seen := make(map[string]bool)
+	add := func(entitlement Entitlement) {
+		if !seen[entitlement.Name] {
+			seen[entitlement.Name] = true
+			result = append(result, entitlement)
+		}
+	}
+
+	for _, name := range cfg.DefaultEntitlements {
+		add(Entitlement{Name: name, Source: EntitlementSourceDefault})
+	}
+	for _, grant := range entitlementGrants.Grants(account.ID) {
+		if grant.active(now) {
+			add(Entitlement{Name: grant.Entitlement, Source: EntitlementSourceAdmin, ExpiresAt: grant.ExpiresAt})
+		}
+	}
+	for _, redemption := range promoCodes.Redemptions(account.ID) {
+		add(Entitlement{Name: redemption.Entitlement, Source: EntitlementSourcePromo})
+	}
+	return result
+}
+
+func hasEntitlement(cfg *Config, account Account, name string) bool {
+	return slices.ContainsFunc(accountEntitlements(cfg, account, time.Now()), func(e Entitlement) bool { return e.Name == name })
+}
+
+// Проверка права на скачивание. Пустое право — скачивание открыто всем;
+// иначе нужен вход в аккаунт с этим правом. При отказе ответ уже записан.
+func (l *Logger) rejectWithoutEntitlement(w http.ResponseWriter, r *http.Request, entitlement, what string) bool {
+	if apiErr := l.checkEntitlement(r, entitlement, what); apiErr != nil {
+		writeModError(w, r, apiErr)
+		return true
+	}
+	return false
+}
+
+func (l *Logger) checkEntitlement(r *http.Request, entitlement, what string) *modError {
+	if entitlement == "" {
+		return nil
+	}
+	account, ok := authenticateAccount(r)
+	if !ok {
+		return &modError{http.StatusUnauthorized, ErrCodeAccountUnauthorized, nil}
+	}
+	if !hasEntitlement(currentConfig(), account, entitlement) {
+		l.logError("Скачивание %s отклонено: у аккаунта %s нет права %s", what, account.Username, entitlement)
+		return &modError{http.StatusForbidden, ErrCodeEntitlementRequired, []interface{}{entitlement}}
+	}
+	return nil
+}
+
+// Права текущего аккаунта
+func (l *Logger) entitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎟️", "/api/entitlements", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(EntitlementsResponse{
+			Account:      account.info(),
+			Entitlements: accountEntitlements(currentConfig(), account, time.Now()),
+		})
+	})
+}
+
+// Аккаунт из пути админского запроса; при отказе ответ уже записан
+func adminAccountFromPath(w http.ResponseWriter, r *http.Request) (Account, bool) {
+	username := r.PathValue("username")
+	account, ok := accounts.ByUsername(username)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeAccountNotFound, username)
+	}
+	return account, ok
+}
+
+// Права аккаунта для админки, включая выдачи с истекшим сроком
+func (l *Logger) adminAccountEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🎟️", "/admin/api/accounts/{username}/entitlements", func() {
+		account, ok := adminAccountFromPath(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(AdminEntitlementsResponse{
+			EntitlementsResponse: EntitlementsResponse{
+				Account:      account.info(),
+				Entitlements: accountEntitlements(currentConfig(), account, time.Now()),
+			},
+			Grants:      entitlementGrants.Grants(account.ID),
+			Redemptions: promoCodes.Redemptions(account.ID),
+		})
+	})
+}
+
+// Выдача права: PUT /admin/api/accounts/{username}/entitlements/{name}
+func (l *Logger) adminGrantEntitlementHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🎟️", "/admin/api/accounts/{username}/entitlements/{name}", func() {
+		name := r.PathValue("name")
+		var req EntitlementGrantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !entitlementPattern.MatchString(name) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		account, ok := adminAccountFromPath(w, r)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		expiresAt := req.ExpiresAt
+		if req.Duration != "" {
+			duration, err := time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 || expiresAt != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			expires := now.Add(duration)
+			expiresAt = &expires
+		}
+
+		actor, _ := adminActor(r, "")
+		grant := EntitlementGrant{
+			Entitlement: name,
+			ExpiresAt:   expiresAt,
+			Note:        req.Note,
+			GrantedBy:   actor,
+			GrantedAt:   now,
+		}
+		if err := entitlementGrants.Grant(account.ID, grant); err != nil {
+			l.logError("Ошибка сохранения прав: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		json.NewEncoder(w).Encode(grant)
+		l.logSuccess("Аккаунту %s выдано право %s (%s)", account.Username, name, actor)
+	})
+}
+
+// Отзыв выданного права. Права по промокодам и общие так не отзываются.
+func (l *Logger) adminRevokeEntitlementHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🎟️", "/admin/api/accounts/{username}/entitlements/{name}", func() {
+		name := r.PathValue("name")
+		account, ok := adminAccountFromPath(w, r)
+		if !ok {
+			return
+		}
+		revoked, err := entitlementGrants.Revoke(account.ID, name)
+		if !revoked {
+			writeError(w, r, http.StatusNotFound, ErrCodeEntitlementNotGranted, name)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения прав: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		actor, _ := adminActor(r, "")
+		l.logSuccess("У аккаунта %s отозвано право %s (%s)", account.Username, name, actor)
+	})
+}
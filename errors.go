@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Машиночитаемые коды ошибок API. Текст ошибки берется из каталога
+// сообщений по ключу — коду в нижнем регистре.
+const (
+	ErrCodeNewsLoad          = "NEWS_LOAD_ERROR"
+	ErrCodeNewsSave          = "NEWS_SAVE_ERROR"
+	ErrCodeNewsNotFound      = "NEWS_NOT_FOUND"
+	ErrCodeFileNotFound      = "FILE_NOT_FOUND"
+	ErrCodeFileOpen          = "FILE_OPEN_ERROR"
+	ErrCodeFileStat          = "FILE_STAT_ERROR"
+	ErrCodeUnauthorized      = "UNAUTHORIZED"
+	ErrCodeInvalidRequest    = "INVALID_REQUEST"
+	ErrCodeInvalidStatus     = "INVALID_STATUS"
+	ErrCodePublishAtRequired = "PUBLISH_AT_REQUIRED"
+	ErrCodeRouteNotFound     = "ROUTE_NOT_FOUND"
+	ErrCodeMaintenance       = "MAINTENANCE"
+	ErrCodeConfigInvalid     = "CONFIG_INVALID"
+	ErrCodeUnknownArtifact   = "UNKNOWN_ARTIFACT"
+	ErrCodeUploadFailed      = "UPLOAD_FAILED"
+	ErrCodeHashMismatch      = "HASH_MISMATCH"
+	ErrCodeDiskSpaceLow      = "DISK_SPACE_LOW"
+	ErrCodeForbidden         = "FORBIDDEN"
+	ErrCodeInvalidScope      = "INVALID_SCOPE"
+	ErrCodeInvalidRole       = "INVALID_ROLE"
+	ErrCodeKeyNotFound       = "KEY_NOT_FOUND"
+	ErrCodeKeysSave          = "KEYS_SAVE_ERROR"
+	ErrCodeInternal          = "INTERNAL_ERROR"
+	ErrCodeTimeout           = "TIMEOUT"
+	ErrCodeChunkNotFound     = "CHUNK_NOT_FOUND"
+	ErrCodeManifestNotFound  = "MANIFEST_NOT_FOUND"
+	ErrCodeSignatureNotFound = "SIGNATURE_NOT_FOUND"
+	ErrCodeInvalidSignature  = "INVALID_SIGNATURE"
+
+	ErrCodeCurrentVersionBlocked = "CURRENT_VERSION_BLOCKED"
+	ErrCodeTelemetryDisabled     = "TELEMETRY_DISABLED"
+	ErrCodeInvalidTelemetry      = "INVALID_TELEMETRY"
+	ErrCodePayloadTooLarge       = "PAYLOAD_TOO_LARGE"
+	ErrCodeRuntimeNotFound       = "RUNTIME_NOT_FOUND"
+	ErrCodeModNotFound           = "MOD_NOT_FOUND"
+	ErrCodeModVersionNotFound    = "MOD_VERSION_NOT_FOUND"
+	ErrCodeModpackNotFound       = "MODPACK_NOT_FOUND"
+	ErrCodeResourcePackNotFound  = "RESOURCE_PACK_NOT_FOUND"
+	ErrCodePlayerNotListed       = "PLAYER_NOT_LISTED"
+	ErrCodeEULANotFound          = "EULA_NOT_FOUND"
+	ErrCodeEULAVersionMismatch   = "EULA_VERSION_MISMATCH"
+	ErrCodeSessionNotFound       = "SESSION_NOT_FOUND"
+
+	ErrCodeAccountUnauthorized         = "ACCOUNT_UNAUTHORIZED"
+	ErrCodeInvalidCredentials          = "INVALID_CREDENTIALS"
+	ErrCodeAccountExists               = "ACCOUNT_EXISTS"
+	ErrCodeWeakPassword                = "WEAK_PASSWORD"
+	ErrCodeRegistrationDisabled        = "REGISTRATION_DISABLED"
+	ErrCodeSyncKeyNotFound             = "SYNC_KEY_NOT_FOUND"
+	ErrCodeSyncConflict                = "SYNC_CONFLICT"
+	ErrCodePreconditionRequired        = "PRECONDITION_REQUIRED"
+	ErrCodeQuotaExceeded               = "QUOTA_EXCEEDED"
+	ErrCodeSaveNotFound                = "SAVE_NOT_FOUND"
+	ErrCodeUploadNotFound              = "UPLOAD_NOT_FOUND"
+	ErrCodeUploadOffsetMismatch        = "UPLOAD_OFFSET_MISMATCH"
+	ErrCodeInvalidImage                = "INVALID_IMAGE"
+	ErrCodeTooManyPending              = "TOO_MANY_PENDING"
+	ErrCodeScreenshotNotFound          = "SCREENSHOT_NOT_FOUND"
+	ErrCodePromoCodeInvalid            = "PROMO_CODE_INVALID"
+	ErrCodePromoCodeExpired            = "PROMO_CODE_EXPIRED"
+	ErrCodePromoCodeExhausted          = "PROMO_CODE_EXHAUSTED"
+	ErrCodePromoCodeRedeemed           = "PROMO_CODE_REDEEMED"
+	ErrCodePromoCodeExists             = "PROMO_CODE_EXISTS"
+	ErrCodeAccountNotFound             = "ACCOUNT_NOT_FOUND"
+	ErrCodeEntitlementRequired         = "ENTITLEMENT_REQUIRED"
+	ErrCodeEntitlementNotGranted       = "ENTITLEMENT_NOT_GRANTED"
+	ErrCodeTooManyAttempts             = "TOO_MANY_ATTEMPTS"
+	ErrCodeTOTPRequired                = "TOTP_REQUIRED"
+	ErrCodeInvalidTOTPCode             = "INVALID_TOTP_CODE"
+	ErrCodeTOTPNotEnrolled             = "TOTP_NOT_ENROLLED"
+	ErrCodeTOTPAlreadyEnabled          = "TOTP_ALREADY_ENABLED"
+	ErrCodeTOTPEnrollmentRequired      = "TOTP_ENROLLMENT_REQUIRED"
+	ErrCodeOAuthProviderNotFound       = "OAUTH_PROVIDER_NOT_FOUND"
+	ErrCodeOAuthStateInvalid           = "OAUTH_STATE_INVALID"
+	ErrCodeOAuthDenied                 = "OAUTH_DENIED"
+	ErrCodeOAuthFailed                 = "OAUTH_FAILED"
+	ErrCodeOAuthTicketInvalid          = "OAUTH_TICKET_INVALID"
+	ErrCodeOAuthIdentityLinked         = "OAUTH_IDENTITY_LINKED"
+	ErrCodeOAuthLastLoginMethod        = "OAUTH_LAST_LOGIN_METHOD"
+	ErrCodeEmailDisabled               = "EMAIL_DISABLED"
+	ErrCodeInvalidEmail                = "INVALID_EMAIL"
+	ErrCodeEmailInUse                  = "EMAIL_IN_USE"
+	ErrCodeInvalidEmailToken           = "INVALID_EMAIL_TOKEN"
+	ErrCodeAccountSessionNotFound      = "ACCOUNT_SESSION_NOT_FOUND"
+	ErrCodeLoginLocked                 = "LOGIN_LOCKED"
+	ErrCodeCaptchaRequired             = "CAPTCHA_REQUIRED"
+	ErrCodeCaptchaInvalid              = "CAPTCHA_INVALID"
+	ErrCodeCaptchaUnavailable          = "CAPTCHA_UNAVAILABLE"
+	ErrCodeAccountDeletionConfirm      = "ACCOUNT_DELETION_CONFIRM"
+	ErrCodeAccountDeletionScheduled    = "ACCOUNT_DELETION_SCHEDULED"
+	ErrCodeAccountDeletionNotScheduled = "ACCOUNT_DELETION_NOT_SCHEDULED"
+	ErrCodeIPForbidden                 = "IP_FORBIDDEN"
+	ErrCodeIPBanned                    = "IP_BANNED"
+	ErrCodeIPBanNotFound               = "IP_BAN_NOT_FOUND"
+	ErrCodeUnsupportedMediaType        = "UNSUPPORTED_MEDIA_TYPE"
+	ErrCodeTooManyParts                = "TOO_MANY_PARTS"
+	ErrCodeDownloadLimitPerIP          = "DOWNLOAD_LIMIT_PER_IP"
+	ErrCodeDownloadsBusy               = "DOWNLOADS_BUSY"
+	ErrCodeDownloadQueueRequired       = "DOWNLOAD_QUEUE_REQUIRED"
+	ErrCodeDownloadQueueFull           = "DOWNLOAD_QUEUE_FULL"
+	ErrCodeDownloadQueueDisabled       = "DOWNLOAD_QUEUE_DISABLED"
+	ErrCodeDownloadQueueTokenNotFound  = "DOWNLOAD_QUEUE_TOKEN_NOT_FOUND"
+	ErrCodeUnknownEncoding             = "UNKNOWN_ENCODING"
+	ErrCodeEncodingSourceMismatch      = "ENCODING_SOURCE_MISMATCH"
+	ErrCodeEventsBusy                  = "EVENTS_BUSY"
+	ErrCodeProjectNotFound             = "PROJECT_NOT_FOUND"
+	ErrCodeUnknownChannel              = "UNKNOWN_CHANNEL"
+	ErrCodeWebhookNotFound             = "WEBHOOK_NOT_FOUND"
+	ErrCodeUnknownWebhookEvent         = "UNKNOWN_WEBHOOK_EVENT"
+	ErrCodeMOTDNotFound                = "MOTD_NOT_FOUND"
+	ErrCodeLaunchProfileNotFound       = "LAUNCH_PROFILE_NOT_FOUND"
+	ErrCodeDependenciesNotFound        = "DEPENDENCIES_NOT_FOUND"
+	ErrCodeDownloadTokenNotFound       = "DOWNLOAD_TOKEN_NOT_FOUND"
+	ErrCodeHashingInProgress           = "HASHING_IN_PROGRESS"
+	ErrCodeInvalidBundle               = "INVALID_BUNDLE"
+	ErrCodeReleaseNotFound             = "RELEASE_NOT_FOUND"
+	ErrCodeReleaseNotDraft             = "RELEASE_NOT_DRAFT"
+	ErrCodeReleaseIncomplete           = "RELEASE_INCOMPLETE"
+	ErrCodeStagingEmpty                = "STAGING_EMPTY"
+	ErrCodeStagingModified             = "STAGING_MODIFIED"
+	ErrCodeUploadIncomplete            = "UPLOAD_INCOMPLETE"
+	ErrCodeVersionNotNewer             = "VERSION_NOT_NEWER"
+	ErrCodeChangelogNotFound           = "CHANGELOG_NOT_FOUND"
+	ErrCodeHardwareSurveyDisabled      = "HARDWARE_SURVEY_DISABLED"
+	ErrCodeFeedbackDisabled            = "FEEDBACK_DISABLED"
+	ErrCodeFeedbackNotFound            = "FEEDBACK_NOT_FOUND"
+	ErrCodeUnknownReaction             = "UNKNOWN_REACTION"
+	ErrCodeProfileNotFound             = "PROFILE_NOT_FOUND"
+	ErrCodeInvalidDisplayName          = "INVALID_DISPLAY_NAME"
+	ErrCodeAchievementNotFound         = "ACHIEVEMENT_NOT_FOUND"
+	ErrCodeGameServerAPIDisabled       = "GAME_SERVER_API_DISABLED"
+	ErrCodeInvalidServerSecret         = "INVALID_SERVER_SECRET"
+	ErrCodeLeaderboardNotFound         = "LEADERBOARD_NOT_FOUND"
+	ErrCodeScoreNotFound               = "SCORE_NOT_FOUND"
+	ErrCodeScoreOutOfRange             = "SCORE_OUT_OF_RANGE"
+	ErrCodeScoreTooFrequent            = "SCORE_TOO_FREQUENT"
+	ErrCodeScoreRejected               = "SCORE_REJECTED"
+	ErrCodeFriendNotFound              = "FRIEND_NOT_FOUND"
+	ErrCodeAlreadyFriends              = "ALREADY_FRIENDS"
+	ErrCodeFriendLimit                 = "FRIEND_LIMIT"
+	ErrCodeMessageNotFound             = "MESSAGE_NOT_FOUND"
+	ErrCodeNotFriends                  = "NOT_FRIENDS"
+	ErrCodePushDisabled                = "PUSH_DISABLED"
+	ErrCodeDeviceNotFound              = "DEVICE_NOT_FOUND"
+	ErrCodeCheatReportNotFound         = "CHEAT_REPORT_NOT_FOUND"
+	ErrCodeCheatReportReviewed         = "CHEAT_REPORT_REVIEWED"
+	ErrCodeHWIDBanned                  = "HWID_BANNED"
+	ErrCodeHWIDRequired                = "HWID_REQUIRED"
+	ErrCodeHWIDBanNotFound             = "HWID_BAN_NOT_FOUND"
+	ErrCodeFeatureDisabled             = "FEATURE_DISABLED"
+	ErrCodeDownloadQuotaExceeded       = "DOWNLOAD_QUOTA_EXCEEDED"
+	ErrCodeMirrorNotFound              = "MIRROR_NOT_FOUND"
+	ErrCodeInvalidMirrorToken          = "INVALID_MIRROR_TOKEN"
+	ErrCodeInvalidMirrorSignature      = "INVALID_MIRROR_SIGNATURE"
+	ErrCodeMirrorSyncDisabled          = "MIRROR_SYNC_DISABLED"
+	ErrCodeReplicaReadOnly             = "REPLICA_READ_ONLY"
+	ErrCodeServerOverloaded            = "SERVER_OVERLOADED"
+)
+
+// Стандартный конверт ошибки
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type requestIDKey struct{}
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Ответ с ошибкой в едином формате
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, args ...interface{}) {
+	if status == http.StatusBadRequest && bodyLimitExceeded(r) {
+		status, code, args = http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, nil
+	}
+	response := ErrorResponse{
+		Error: APIError{
+			Code:      code,
+			Message:   translate(r, strings.ToLower(code), args...),
+			RequestID: requestID(r),
+		},
+	}
+
+	writeJSON(w, status, response)
+}
+
+// Ошибка хранилищ и сервисных функций, общих для REST и gRPC: то, что
+// writeError записал бы в ответ
+type modError struct {
+	status int
+	code   string
+	args   []interface{}
+}
+
+// Заголовки к ошибке: сколько ждать до следующей попытки и ключ CAPTCHA
+// для формы входа
+func (e *modError) headers() http.Header {
+	header := http.Header{}
+	switch e.code {
+	case ErrCodeLoginLocked, ErrCodeTooManyAttempts:
+		header.Set("Retry-After", fmt.Sprint(e.args[0]))
+	case ErrCodeCaptchaRequired, ErrCodeCaptchaInvalid, ErrCodeCaptchaUnavailable:
+		header.Set("X-Captcha-Site-Key", currentConfig().CaptchaSiteKey)
+	}
+	return header
+}
+
+func writeModError(w http.ResponseWriter, r *http.Request, apiErr *modError) {
+	for key, values := range apiErr.headers() {
+		w.Header()[key] = values
+	}
+	writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Идентификатор запроса: берем X-Request-ID от прокси или генерируем свой
+func withRequestID(r *http.Request) *http.Request {
+	id := r.Header.Get("X-Request-ID")
+	if !requestIDPattern.MatchString(id) {
+		id = newRequestID()
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Правила сервера (EULA). Текст в Markdown на языке по умолчанию,
+// переводы по кодам языков. Новая версия требует повторного согласия.
+type EULA struct {
+	Version      string            `json:"version"`
+	Text         string            `json:"text"`
+	Translations map[string]string `json:"translations,omitempty"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+type EULAResponse struct {
+	Version   string    `json:"version"`
+	Text      string    `json:"text"`
+	Language  string    `json:"lang"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Заполняется только при запросе с format=html
+	RenderedHTML string `json:"rendered_html,omitempty"`
+	// Согласие клиента, если в запросе передан его ID
+	Accepted        bool   `json:"accepted"`
+	AcceptedVersion string `json:"accepted_version,omitempty"`
+}
+
+// Запрос согласия: версия, которую видел игрок, и его аккаунт или ID клиента
+type EULAAcceptRequest struct {
+	Version  string `json:"version"`
+	Account  string `json:"account"`
+	ClientID string `json:"client_id"`
+}
+
+// Последнее согласие аккаунта или клиента
+type EULAAcceptance struct {
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	ClientIP   string    `json:"client_ip"`
+}
+
+// Для админки: текущие правила и число согласий по версиям
+type AdminEULAResponse struct {
+	EULA        *EULA          `json:"eula"`
+	Acceptances map[string]int `json:"acceptances"`
+}
+
+var (
+	eulaMu sync.Mutex
+	eula   atomic.Pointer[EULA]
+)
+
+func eulaFile() string {
+	return filepath.Join(currentConfig().DataDir, "eula.json")
+}
+
+func loadEULA() error {
+	var current EULA
+	if err := loadJSONFile(eulaFile(), &current); err != nil {
+		return err
+	}
+	if current.Version != "" {
+		eula.Store(&current)
+	}
+	return nil
+}
+
+// Согласия в DATA_DIR/eula_acceptances.json: ключ "account:<имя>" или "client:<id>"
+type EULAAcceptanceStore struct {
+	mu          sync.Mutex
+	acceptances map[string]EULAAcceptance
+}
+
+var eulaAcceptances = &EULAAcceptanceStore{}
+
+func eulaAcceptancesFile() string {
+	return filepath.Join(currentConfig().DataDir, "eula_acceptances.json")
+}
+
+func (s *EULAAcceptanceStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acceptances = make(map[string]EULAAcceptance)
+	return loadJSONFile(eulaAcceptancesFile(), &s.acceptances)
+}
+
+func (s *EULAAcceptanceStore) Get(subject string) (EULAAcceptance, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acceptance, ok := s.acceptances[subject]
+	return acceptance, ok
+}
+
+func (s *EULAAcceptanceStore) Record(subject string, acceptance EULAAcceptance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.acceptances[subject]
+	s.acceptances[subject] = acceptance
+	if err := saveJSONFile(eulaAcceptancesFile(), s.acceptances); err != nil {
+		if existed {
+			s.acceptances[subject] = previous
+		} else {
+			delete(s.acceptances, subject)
+		}
+		return err
+	}
+	return nil
+}
+
+// Удаление согласий, например вместе с аккаунтом
+func (s *EULAAcceptanceStore) Delete(subjects ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[string]EULAAcceptance, len(s.acceptances))
+	for subject, acceptance := range s.acceptances {
+		if !slices.Contains(subjects, subject) {
+			next[subject] = acceptance
+		}
+	}
+	if len(next) == len(s.acceptances) {
+		return nil
+	}
+	if err := saveJSONFile(eulaAcceptancesFile(), next); err != nil {
+		return err
+	}
+	s.acceptances = next
+	return nil
+}
+
+func (s *EULAAcceptanceStore) CountByVersion() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, acceptance := range s.acceptances {
+		counts[acceptance.Version]++
+	}
+	return counts
+}
+
+// Кто игрок: аккаунт важнее ID клиента
+func playerSubject(account, clientID string) string {
+	if account != "" {
+		return "account:" + account
+	}
+	if clientID != "" {
+		return "client:" + clientID
+	}
+	return ""
+}
+
+// Текущие правила: /api/eula?lang=en&format=html&client_id=...
+func (l *Logger) eulaHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📜", "/api/eula", func() {
+		current := eula.Load()
+		if current == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeEULANotFound)
+			return
+		}
+
+		response := EULAResponse{
+			Version:   current.Version,
+			Text:      current.Text,
+			Language:  currentConfig().DefaultLanguage,
+			UpdatedAt: current.UpdatedAt,
+		}
+		for _, lang := range requestLanguages(r) {
+			if text, ok := current.Translations[lang]; ok {
+				response.Text, response.Language = text, lang
+				break
+			}
+		}
+		if r.URL.Query().Get("format") == "html" {
+			response.RenderedHTML = renderMarkdown(response.Text)
+		}
+
+		subject := playerSubject(r.URL.Query().Get("account"), launcherClientID(r))
+		if acceptance, ok := eulaAcceptances.Get(subject); subject != "" && ok {
+			response.AcceptedVersion = acceptance.Version
+			response.Accepted = acceptance.Version == current.Version
+		}
+
+		w.Header().Set("Vary", "X-Client-ID, Accept-Language")
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Отправлены правила версии %s (%s)", current.Version, response.Language)
+	})
+}
+
+// Согласие с правилами
+func (l *Logger) eulaAcceptHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📜", "/api/eula/accept", func() {
+		var req EULAAcceptRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if req.ClientID == "" {
+			req.ClientID = launcherClientID(r)
+		}
+		if account, ok := authenticateAccount(r); ok {
+			req.Account = account.Username
+		}
+		subject := playerSubject(req.Account, req.ClientID)
+		if subject == "" || req.Version == "" || len(req.ClientID) > 128 ||
+			(req.Account != "" && !playerNamePattern.MatchString(req.Account)) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		current := eula.Load()
+		if current == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeEULANotFound)
+			return
+		}
+		// Согласие со старой версией не засчитывается: лаунчер должен показать новую
+		if req.Version != current.Version {
+			writeError(w, r, http.StatusConflict, ErrCodeEULAVersionMismatch, current.Version)
+			return
+		}
+
+		acceptance := EULAAcceptance{
+			Version:    current.Version,
+			AcceptedAt: time.Now().UTC(),
+			ClientIP:   getClientIP(r),
+		}
+		if err := eulaAcceptances.Record(subject, acceptance); err != nil {
+			l.logError("Ошибка сохранения согласия с правилами: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		json.NewEncoder(w).Encode(acceptance)
+		l.logSuccess("Принято согласие с правилами %s от %s", current.Version, subject)
+	})
+}
+
+// Текущие правила и статистика согласий
+func (l *Logger) adminGetEULAHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "📜", "/admin/api/eula", func() {
+		json.NewEncoder(w).Encode(AdminEULAResponse{
+			EULA:        eula.Load(),
+			Acceptances: eulaAcceptances.CountByVersion(),
+		})
+	})
+}
+
+// Публикация правил. Смена версии требует от всех игроков нового согласия,
+// правка текста без смены версии (опечатки) — нет.
+func (l *Logger) adminSetEULAHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "📜", "/admin/api/eula", func() {
+		var req EULA
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+			!versionLabelPattern.MatchString(req.Version) || req.Text == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		translations := make(map[string]string, len(req.Translations))
+		for lang, text := range req.Translations {
+			translations[normalizeLanguage(lang)] = text
+		}
+		req.Translations = translations
+		req.UpdatedAt = time.Now().UTC()
+
+		eulaMu.Lock()
+		defer eulaMu.Unlock()
+		if err := saveJSONFile(eulaFile(), req); err != nil {
+			l.logError("Ошибка сохранения правил: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		eula.Store(&req)
+
+		json.NewEncoder(w).Encode(req)
+		l.logSuccess("Опубликованы правила версии %s", req.Version)
+	})
+}
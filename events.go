@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Типы событий /api/events
+const (
+	EventNews        = "news"
+	EventVersion     = "version"
+	EventMaintenance = "maintenance"
+	// Аварийные выключатели изменились, лаунчеру не нужно ждать опроса
+	EventSwitches = "switches"
+	// Клиент пропустил события (перезапуск сервера или слишком долгий
+	// обрыв) и должен сам перечитать /api/version и /api/news
+	EventResync = "resync"
+
+	// Личные события игрока, вошедшего в аккаунт: заявки в друзья,
+	// присутствие друзей и новые сообщения
+	EventFriendRequest  = "friend_request"
+	EventFriendAccepted = "friend_accepted"
+	EventPresence       = "presence"
+	EventInboxMessage   = "inbox_message"
+)
+
+// Через сколько секунд повторить подключение при заполненном лимите и
+// с какой задержкой EventSource переподключается после обрыва
+const (
+	eventsRetryAfter     = 30
+	eventsReconnectDelay = 5 * time.Second
+)
+
+type ServerEvent struct {
+	ID   uint64
+	Type string
+	Data []byte
+	// ID аккаунтов-получателей; пусто — событие для всех
+	Accounts []string
+}
+
+func (e ServerEvent) visibleTo(accountID string) bool {
+	return len(e.Accounts) == 0 || slices.Contains(e.Accounts, accountID)
+}
+
+// Новая опубликованная новость; текст на языке игрока лаунчер берет из /api/news
+type NewsEvent struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Image string `json:"image"`
+	Date  string `json:"date"`
+}
+
+// Рассылка объявлений лаунчерам по SSE. Последние EVENTS_HISTORY событий
+// хранятся для повтора по Last-Event-ID после переподключения. Номера
+// начинаются с времени запуска в миллисекундах и растут между
+// перезапусками: номер из прошлого запуска меньше номеров этого, и
+// клиент получает resync вместо молча потерянных событий.
+type EventHub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []ServerEvent
+	subscribers int
+	// Закрывается и заменяется при каждом событии, чтобы разбудить подписчиков
+	changed chan struct{}
+
+	// Что уже объявлено: версии без флага техработ и видимые новости.
+	// nil до первой проверки, которая только запоминает состояние.
+	version []byte
+	news    map[int]bool
+}
+
+var eventHub = &EventHub{
+	nextID:  uint64(time.Now().UnixMilli()),
+	changed: make(chan struct{}),
+}
+
+func (h *EventHub) Publish(eventType string, data any) {
+	h.PublishTo(nil, eventType, data)
+}
+
+// Событие только для подписчиков, вошедших в перечисленные аккаунты
+func (h *EventHub) PublishTo(accountIDs []string, eventType string, data any) {
+	body, _ := json.Marshal(data)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = append(h.history, ServerEvent{ID: h.nextID, Type: eventType, Data: body, Accounts: accountIDs})
+	h.nextID++
+	if limit := currentConfig().EventsHistory; len(h.history) > limit {
+		h.history = append(h.history[:0], h.history[len(h.history)-limit:]...)
+	}
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// События после lastID и канал, который закроется при следующем. Если
+// часть событий уже вытеснена из истории или lastID не из этого запуска,
+// вместо них отдается resync с номером последнего события.
+func (h *EventHub) Since(lastID uint64) ([]ServerEvent, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	latest := h.nextID - 1
+	if lastID == latest {
+		return nil, h.changed
+	}
+	if lastID > latest || len(h.history) == 0 || lastID < h.history[0].ID-1 {
+		return []ServerEvent{{ID: latest, Type: EventResync, Data: []byte("{}")}}, h.changed
+	}
+
+	i := len(h.history)
+	for i > 0 && h.history[i-1].ID > lastID {
+		i--
+	}
+	return append([]ServerEvent(nil), h.history[i:]...), h.changed
+}
+
+// Номер последнего события: с него начинает новый подписчик
+func (h *EventHub) Latest() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nextID - 1
+}
+
+// Занять место подписчика; false — достигнут EVENTS_MAX_SUBSCRIBERS
+func (h *EventHub) Subscribe(cfg *Config) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if cfg.EventsMaxSubscribers > 0 && h.subscribers >= cfg.EventsMaxSubscribers {
+		return false
+	}
+	h.subscribers++
+	return true
+}
+
+func (h *EventHub) Unsubscribe() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers--
+}
+
+// Событие version, если изменились версии, номера сборок или список
+// заблокированных версий. Техработы объявляются отдельным событием.
+func (h *EventHub) PublishVersion() {
+	version := currentVersion(false)
+	version.Maintenance = false
+	key, _ := json.Marshal(version)
+
+	h.mu.Lock()
+	announced := h.version
+	h.version = key
+	h.mu.Unlock()
+
+	if announced != nil && !bytes.Equal(announced, key) {
+		h.Publish(EventVersion, currentVersion(false))
+	}
+}
+
+// События news для новостей, ставших видимыми игрокам. Вызывается при
+// каждом сохранении news.json, в том числе планировщиком публикаций.
+func (h *EventHub) PublishNews(news []NewsItem) {
+	visible := make(map[int]bool)
+	var published []NewsEvent
+	h.mu.Lock()
+	for _, item := range publishedNews(news, time.Now()) {
+		visible[item.ID] = true
+		if h.news != nil && !h.news[item.ID] {
+			published = append(published, NewsEvent{ID: item.ID, Title: item.Title, Image: item.Image, Date: item.Date})
+		}
+	}
+	h.news = visible
+	h.mu.Unlock()
+
+	for _, event := range published {
+		h.Publish(EventNews, event)
+	}
+}
+
+// Поток объявлений для лаунчеров во встроенных веб-страницах: облегченная
+// замена WebSocket на обычном EventSource. Раз в EVENTS_HEARTBEAT_INTERVAL
+// приходит комментарий, чтобы прокси не закрывали тихое соединение.
+// Номер последнего события берется из Last-Event-ID или ?last_event_id=.
+// С токеном аккаунта в Authorization поток получает и личные события, а
+// игрок считается открывшим лаунчер, пока поток подключен.
+func (l *Logger) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📡", "/api/events", func() {
+		cfg := currentConfig()
+		if !eventHub.Subscribe(cfg) {
+			w.Header().Set("Retry-After", strconv.Itoa(eventsRetryAfter))
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeEventsBusy, cfg.EventsMaxSubscribers, eventsRetryAfter)
+			return
+		}
+		defer eventHub.Unsubscribe()
+
+		account, signedIn := authenticateAccount(r)
+		if signedIn {
+			l.launcherConnected(account)
+			defer l.launcherDisconnected(account)
+		}
+
+		lastID, _ := strconv.ParseUint(cmp.Or(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("last_event_id")), 10, 64)
+		if lastID == 0 {
+			lastID = eventHub.Latest()
+		}
+
+		// Поток живет дольше READ_TIMEOUT: без сброса дедлайна чтения
+		// net/http отменил бы контекст запроса по его истечении
+		controller := http.NewResponseController(w)
+		if err := controller.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+		defer controller.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", eventsReconnectDelay.Milliseconds())
+
+		heartbeat := time.NewTicker(cfg.EventsHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			events, changed := eventHub.Since(lastID)
+			for _, event := range events {
+				// Чужие личные события пропускаются, но номер сдвигается
+				if event.visibleTo(account.ID) {
+					fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+				}
+				lastID = event.ID
+			}
+			// Зависший клиент не должен держать место подписчика вечно
+			if err := controller.SetWriteDeadline(time.Now().Add(cfg.DownloadIdleTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-serverDraining:
+				return
+			case <-changed:
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Аварийные выключатели, которые проверяет сам сервер. Остальные имена
+// сервер только хранит и отдает лаунчерам, их смысл знает лаунчер.
+const (
+	SwitchModDownloads = "disable_mod_downloads"
+	SwitchBetaChannel  = "disable_beta_channel"
+	SwitchRegistration = "disable_registration"
+)
+
+const maxKillSwitchReason = 500
+
+var (
+	knownSwitches     = []string{SwitchModDownloads, SwitchBetaChannel, SwitchRegistration}
+	switchNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+)
+
+// Выключатель. Actor и ActorKey — кто переключил последним.
+type KillSwitch struct {
+	Enabled   bool      `json:"enabled"`
+	Reason    string    `json:"reason,omitempty"`
+	Actor     string    `json:"actor"`
+	ActorKey  string    `json:"actor_key"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Выключатели в DATA_DIR/kill_switches.json. Revision растет при каждом
+// переключении и отдается лаунчерам как ETag, как у списков игроков.
+type KillSwitches struct {
+	Revision int64                 `json:"revision"`
+	Switches map[string]KillSwitch `json:"switches"`
+}
+
+type KillSwitchRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+	Actor   string `json:"actor"`
+}
+
+// Выключатели для лаунчера; встроенные есть в ответе всегда
+type KillSwitchesResponse struct {
+	Revision int64           `json:"revision"`
+	Switches map[string]bool `json:"switches"`
+}
+
+var (
+	killSwitchesMu sync.Mutex
+	killSwitches   atomic.Pointer[KillSwitches]
+)
+
+func killSwitchesFile() string {
+	return filepath.Join(currentConfig().DataDir, "kill_switches.json")
+}
+
+func loadKillSwitches() error {
+	var switches KillSwitches
+	if err := loadJSONFile(killSwitchesFile(), &switches); err != nil {
+		return err
+	}
+	if switches.Switches == nil {
+		switches.Switches = make(map[string]KillSwitch)
+	}
+	killSwitches.Store(&switches)
+	return nil
+}
+
+// Включен ли выключатель; обработчики спрашивают на каждый запрос
+func switchOn(name string) bool {
+	switches := killSwitches.Load()
+	return switches != nil && switches.Switches[name].Enabled
+}
+
+// Отказ в отключенной функции; при отказе ответ уже записан
+func rejectSwitchedOff(w http.ResponseWriter, r *http.Request, name string) bool {
+	if !switchOn(name) {
+		return false
+	}
+	writeError(w, r, http.StatusServiceUnavailable, ErrCodeFeatureDisabled, name)
+	return true
+}
+
+func killSwitchesResponse(switches *KillSwitches) KillSwitchesResponse {
+	response := KillSwitchesResponse{Revision: switches.Revision, Switches: make(map[string]bool)}
+	for _, name := range knownSwitches {
+		response.Switches[name] = false
+	}
+	for name, s := range switches.Switches {
+		response.Switches[name] = s.Enabled
+	}
+	return response
+}
+
+// Опрос выключателей лаунчером. С If-None-Match по ревизии отвечает 304.
+func (l *Logger) killSwitchesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧯", "/api/switches", func() {
+		switches := killSwitches.Load()
+		etag := `"` + strconv.FormatInt(switches.Revision, 10) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		json.NewEncoder(w).Encode(killSwitchesResponse(switches))
+	})
+}
+
+// Выключатели с причинами и авторами
+func (l *Logger) adminKillSwitchesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🧯", "/admin/api/switches", func() {
+		json.NewEncoder(w).Encode(killSwitches.Load())
+	})
+}
+
+// Переключение: PUT /admin/api/switches/{name}. Действует сразу, лаунчеры
+// узнают по событию switches или при следующем опросе.
+func (l *Logger) adminSetKillSwitchHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🧯", "/admin/api/switches/{name}", func() {
+		name := r.PathValue("name")
+		var req KillSwitchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !switchNamePattern.MatchString(name) || len(req.Reason) > maxKillSwitchReason {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		actor, actorKey := adminActor(r, req.Actor)
+		updated := KillSwitch{Enabled: req.Enabled, Reason: req.Reason, Actor: actor, ActorKey: actorKey, UpdatedAt: time.Now().UTC()}
+
+		killSwitchesMu.Lock()
+		current := killSwitches.Load()
+		next := &KillSwitches{Revision: current.Revision + 1, Switches: maps.Clone(current.Switches)}
+		next.Switches[name] = updated
+		if err := saveJSONFile(killSwitchesFile(), next); err != nil {
+			killSwitchesMu.Unlock()
+			l.logError("Ошибка сохранения выключателей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		killSwitches.Store(next)
+		killSwitchesMu.Unlock()
+
+		eventHub.Publish(EventSwitches, killSwitchesResponse(next))
+		json.NewEncoder(w).Encode(updated)
+		if updated.Enabled {
+			l.logWarn("Выключатель %s включен (%s): %s", name, actor, req.Reason)
+		} else {
+			l.logSuccess("Выключатель %s выключен (%s)", name, actor)
+		}
+	})
+}
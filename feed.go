@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const feedTitle = "LOIL — новости"
+
+// Структуры RSS 2.0
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// Структуры Atom
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Обработчик RSS-ленты новостей
+func (l *Logger) newsRSSHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📡", "/api/news.rss", func() {
+		langs := requestLanguages(r)
+		body, count, err := newsCache.Response("rss|"+strings.Join(langs, ","), func(news []NewsItem) ([]byte, int, error) {
+			news = localizeNews(publishedNews(news, time.Now()), langs)
+			data, err := marshalXML(buildRSSFeed(news))
+			return data, len(news), err
+		})
+		if err != nil {
+			l.logError("Ошибка загрузки новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write(body)
+
+		l.logSuccess("Отправлена RSS-лента: %d новостей", count)
+	})
+}
+
+// Обработчик Atom-ленты новостей
+func (l *Logger) newsAtomHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📡", "/api/news.atom", func() {
+		langs := requestLanguages(r)
+		body, count, err := newsCache.Response("atom|"+strings.Join(langs, ","), func(news []NewsItem) ([]byte, int, error) {
+			news = localizeNews(publishedNews(news, time.Now()), langs)
+			data, err := marshalXML(buildAtomFeed(news))
+			return data, len(news), err
+		})
+		if err != nil {
+			l.logError("Ошибка загрузки новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write(body)
+
+		l.logSuccess("Отправлена Atom-лента: %d новостей", count)
+	})
+}
+
+func buildRSSFeed(news []NewsItem) rssFeed {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       feedTitle,
+			Link:        currentConfig().PublicURL + "/api/news",
+			Description: "Новости проекта LOIL",
+		},
+	}
+
+	for _, item := range news {
+		link := newsItemURL(item)
+		entry := rssItem{
+			Title:       item.Title,
+			Link:        link,
+			Description: renderMarkdown(item.Content),
+			GUID:        rssGUID{IsPermaLink: false, Value: link},
+		}
+		if date, ok := parseNewsDate(item.Date); ok {
+			entry.PubDate = date.Format(time.RFC1123Z)
+		}
+		if url, size, mimeType, ok := newsImageInfo(item); ok {
+			entry.Enclosure = &rssEnclosure{URL: url, Length: size, Type: mimeType}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, entry)
+	}
+
+	return feed
+}
+
+func buildAtomFeed(news []NewsItem) atomFeed {
+	feed := atomFeed{
+		Title: feedTitle,
+		ID:    currentConfig().PublicURL + "/api/news.atom",
+		Links: []atomLink{
+			{Href: currentConfig().PublicURL + "/api/news.atom", Rel: "self", Type: "application/atom+xml"},
+		},
+	}
+
+	var latest time.Time
+	for _, item := range news {
+		date, _ := parseNewsDate(item.Date)
+		if date.After(latest) {
+			latest = date
+		}
+
+		link := newsItemURL(item)
+		entry := atomEntry{
+			Title:   item.Title,
+			ID:      link,
+			Updated: date.Format(time.RFC3339),
+			Links:   []atomLink{{Href: link, Rel: "alternate"}},
+			Content: atomContent{Type: "html", Value: renderMarkdown(item.Content)},
+		}
+		if url, size, mimeType, ok := newsImageInfo(item); ok {
+			entry.Links = append(entry.Links, atomLink{Href: url, Rel: "enclosure", Type: mimeType, Length: size})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	feed.Updated = latest.Format(time.RFC3339)
+
+	return feed
+}
+
+// Ссылка на конкретную новость (используется как постоянный идентификатор)
+func newsItemURL(item NewsItem) string {
+	return fmt.Sprintf("%s/api/news#%d", currentConfig().PublicURL, item.ID)
+}
+
+// Даты новостей хранятся в формате YYYY-MM-DD
+func parseNewsDate(value string) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// Информация об изображении новости для enclosure
+func newsImageInfo(item NewsItem) (url string, size int64, mimeType string, ok bool) {
+	if item.Image == "" {
+		return "", 0, "", false
+	}
+
+	name := filepath.Base(item.Image)
+	info, err := os.Stat(filepath.Join(imagesDir, name))
+	if err != nil {
+		return "", 0, "", false
+	}
+
+	mimeType = mime.TypeByExtension(filepath.Ext(name))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	return currentConfig().PublicURL + "/images/" + name, info.Size(), mimeType, true
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
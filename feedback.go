@@ -0,0 +1,505 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Категории и статусы отзывов
+const (
+	FeedbackBug        = "bug"
+	FeedbackSuggestion = "suggestion"
+	FeedbackOther      = "other"
+
+	FeedbackOpen     = "open"
+	FeedbackResolved = "resolved"
+	// Отзыв, который не прошел проверки на спам. Отправитель об этом не
+	// узнает, а модератор может вернуть отзыв в open.
+	FeedbackSpam = "spam"
+)
+
+var (
+	feedbackCategories = []string{FeedbackBug, FeedbackSuggestion, FeedbackOther}
+	feedbackStatuses   = []string{FeedbackOpen, FeedbackResolved, FeedbackSpam}
+)
+
+const (
+	minFeedbackText  = 10
+	maxFeedbackText  = 10000
+	maxFeedbackTags  = 20
+	maxFeedbackLinks = 3
+	// Тот же текст с того же адреса за это время считается спамом
+	feedbackDuplicateWindow = 24 * time.Hour
+)
+
+// Вложение: журнал лаунчера, отчет о сбое или снимок экрана
+var feedbackAttachmentTypes = []string{"text/plain", "application/json", "application/zip", "application/gzip", "image/png", "image/jpeg"}
+
+// Не больше пяти отзывов в час с одного адреса
+var feedbackIPSends = &attemptLimiter{limit: 5, window: time.Hour, failures: make(map[string]*attemptWindow)}
+
+var feedbackLinkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// Сообщение об ошибке или предложение от игрока
+type Feedback struct {
+	ID              string `json:"id"`
+	Category        string `json:"category"`
+	Text            string `json:"text"`
+	GameVersion     string `json:"game_version,omitempty"`
+	LauncherVersion string `json:"launcher_version,omitempty"`
+	OS              string `json:"os,omitempty"`
+	// Как связаться с игроком, если он указал
+	Contact   string `json:"contact,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+	Author    string `json:"author,omitempty"`
+	IP        string `json:"ip"`
+	// Вложение лежит в DATA_DIR/feedback/<id>
+	Attachment *FeedbackAttachment `json:"attachment,omitempty"`
+	Status     string              `json:"status"`
+	SpamReason string              `json:"spam_reason,omitempty"`
+	Tags       []string            `json:"tags"`
+	Note       string              `json:"note,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	ResolvedAt *time.Time          `json:"resolved_at,omitempty"`
+	ResolvedBy string              `json:"resolved_by,omitempty"`
+}
+
+type FeedbackAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Отзыв в JSON или в части report запроса multipart/form-data, где
+// часть attachment — вложение. Website — ловушка для ботов: поле скрыто
+// в форме, и человек его не заполняет.
+type FeedbackRequest struct {
+	Category        string `json:"category"`
+	Text            string `json:"text"`
+	GameVersion     string `json:"game_version"`
+	LauncherVersion string `json:"launcher_version"`
+	OS              string `json:"os"`
+	Contact         string `json:"contact"`
+	Website         string `json:"website"`
+}
+
+type FeedbackResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// Изменения модератора; пустые поля не меняются
+type FeedbackUpdateRequest struct {
+	Status *string   `json:"status"`
+	Tags   *[]string `json:"tags"`
+	Note   *string   `json:"note"`
+}
+
+type FeedbackListResponse struct {
+	Feedback []Feedback `json:"feedback"`
+}
+
+// Отзывы в DATA_DIR/feedback.json
+type FeedbackStore struct {
+	*jsonListStore[Feedback]
+}
+
+var feedback = &FeedbackStore{&jsonListStore[Feedback]{file: feedbackFile}}
+
+func feedbackFile() string {
+	return filepath.Join(currentConfig().DataDir, "feedback.json")
+}
+
+func feedbackAttachmentPath(id string) string {
+	return filepath.Join(currentConfig().DataDir, "feedback", id)
+}
+
+func (s *FeedbackStore) Get(id string) (Feedback, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := findFeedback(s.items, id); i >= 0 {
+		return s.items[i], true
+	}
+	return Feedback{}, false
+}
+
+// Новые первыми; пустые фильтры — любые значения
+func (s *FeedbackStore) List(status, category, tag, accountID string) []Feedback {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []Feedback{}
+	for _, item := range s.items {
+		if (status == "" || item.Status == status) && (category == "" || item.Category == category) &&
+			(tag == "" || slices.Contains(item.Tags, tag)) && (accountID == "" || item.AccountID == accountID) {
+			result = append(result, item)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Добавление отзыва; повтор недавнего текста с того же адреса помечается
+// как спам
+func (s *FeedbackStore) Add(item Feedback) (Feedback, error) {
+	_, err := s.update(func(list []Feedback) ([]Feedback, *modError) {
+		if item.Status == FeedbackOpen {
+			normalized := strings.Join(strings.Fields(strings.ToLower(item.Text)), " ")
+			for _, existing := range list {
+				if existing.IP == item.IP && item.CreatedAt.Sub(existing.CreatedAt) < feedbackDuplicateWindow &&
+					strings.Join(strings.Fields(strings.ToLower(existing.Text)), " ") == normalized {
+					item.Status, item.SpamReason = FeedbackSpam, "duplicate"
+					break
+				}
+			}
+		}
+		return append(list, item), nil
+	})
+	return item, err
+}
+
+// Удаление отзывов аккаунта вместе с вложениями
+func (s *FeedbackStore) Forget(accountID string) error {
+	var removed []string
+	_, err := s.update(func(list []Feedback) ([]Feedback, *modError) {
+		return slices.DeleteFunc(list, func(item Feedback) bool {
+			if item.AccountID == accountID {
+				removed = append(removed, item.ID)
+				return true
+			}
+			return false
+		}), nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range removed {
+		os.Remove(feedbackAttachmentPath(id))
+	}
+	return nil
+}
+
+func findFeedback(list []Feedback, id string) int {
+	return slices.IndexFunc(list, func(item Feedback) bool { return item.ID == id })
+}
+
+// Проверка отзыва: ошибка в полях — отказ, признаки спама — причина,
+// по которой отзыв молча уходит в spam
+func (req FeedbackRequest) check() (bool, string) {
+	fields := []string{req.Category, req.GameVersion, req.LauncherVersion, req.OS, req.Contact}
+	for _, field := range fields {
+		if !utf8.ValidString(field) || len(field) > maxTelemetryField {
+			return false, ""
+		}
+	}
+	length := utf8.RuneCountInString(strings.TrimSpace(req.Text))
+	if !slices.Contains(feedbackCategories, req.Category) || !utf8.ValidString(req.Text) ||
+		length < minFeedbackText || length > maxFeedbackText {
+		return false, ""
+	}
+
+	switch {
+	case req.Website != "":
+		return true, "honeypot"
+	case len(feedbackLinkPattern.FindAllStringIndex(req.Text, -1)) > maxFeedbackLinks:
+		return true, "links"
+	case !feedbackHasWords(req.Text):
+		return true, "no_words"
+	}
+	return true, ""
+}
+
+// Есть ли в тексте хоть одно слово из букв: «!!!!!!!!!!!» или набор
+// цифр отзывом не считаются
+func feedbackHasWords(text string) bool {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if letters >= 3 {
+				return true
+			}
+		} else {
+			letters = 0
+		}
+	}
+	return false
+}
+
+// Чтение отзыва: JSON или multipart с частями report и attachment.
+// Вложение сохраняется во временный файл. При отказе ответ уже записан.
+func readFeedback(w http.ResponseWriter, r *http.Request) (FeedbackRequest, *FeedbackAttachment, string, bool) {
+	var req FeedbackRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if !requireContentType(w, r, "application/json") {
+			return req, nil, "", false
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return req, nil, "", false
+		}
+		return req, nil, "", true
+	}
+
+	var attachment *FeedbackAttachment
+	var tmpPath string
+	haveReport := false
+	limits := multipartLimits{MaxParts: 2, FileTypes: feedbackAttachmentTypes}
+	ok := forEachMultipartPart(w, r, limits, func(part *multipart.Part) error {
+		switch part.FormName() {
+		case "report":
+			haveReport = true
+			return json.NewDecoder(part).Decode(&req)
+		case "attachment":
+			if attachment != nil || part.FileName() == "" {
+				return errInvalidFeedbackPart
+			}
+			dir := filepath.Dir(feedbackAttachmentPath("x"))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			tmp, err := os.CreateTemp(dir, ".upload-*")
+			if err != nil {
+				return err
+			}
+			tmpPath = tmp.Name()
+			size, err := io.Copy(tmp, part)
+			if closeErr := tmp.Close(); err == nil {
+				err = closeErr
+			}
+			mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			attachment = &FeedbackAttachment{Filename: filepath.Base(part.FileName()), ContentType: mediaType, Size: size}
+			return err
+		}
+		return errInvalidFeedbackPart
+	})
+	if ok && !haveReport {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+		ok = false
+	}
+	if !ok {
+		if tmpPath != "" {
+			os.Remove(tmpPath)
+		}
+		return req, nil, "", false
+	}
+	return req, attachment, tmpPath, true
+}
+
+var errInvalidFeedbackPart = errors.New("лишняя часть запроса")
+
+// Отзыв или сообщение об ошибке от игрока; вход в аккаунт не обязателен
+func (l *Logger) feedbackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💬", "/api/feedback", func() {
+		cfg := currentConfig()
+		if !cfg.FeedbackEnabled {
+			writeError(w, r, http.StatusForbidden, ErrCodeFeedbackDisabled)
+			return
+		}
+		ip := getClientIP(r)
+		if feedbackIPSends.Reject(w, r, "ip:"+ip) {
+			return
+		}
+
+		req, attachment, tmpPath, ok := readFeedback(w, r)
+		if !ok {
+			return
+		}
+		if tmpPath != "" {
+			defer os.Remove(tmpPath)
+		}
+		valid, spamReason := req.check()
+		if !valid {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		item := Feedback{
+			ID:              randomID(12),
+			Category:        req.Category,
+			Text:            strings.TrimSpace(req.Text),
+			GameVersion:     req.GameVersion,
+			LauncherVersion: req.LauncherVersion,
+			OS:              req.OS,
+			Contact:         req.Contact,
+			IP:              ip,
+			Status:          FeedbackOpen,
+			Tags:            []string{},
+			CreatedAt:       now,
+		}
+		if spamReason != "" {
+			item.Status, item.SpamReason = FeedbackSpam, spamReason
+		}
+		if account, ok := authenticateAccount(r); ok {
+			item.AccountID, item.Author = account.ID, account.Username
+		}
+		// Вложения спама не хранятся
+		if attachment != nil && item.Status == FeedbackOpen {
+			if err := os.Rename(tmpPath, feedbackAttachmentPath(item.ID)); err != nil {
+				l.logError("Ошибка сохранения вложения отзыва: %v", err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+				return
+			}
+			item.Attachment = attachment
+		}
+
+		item, err := feedback.Add(item)
+		if err != nil {
+			os.Remove(feedbackAttachmentPath(item.ID))
+			l.logError("Ошибка сохранения отзывов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		feedbackIPSends.Fail("ip:"+ip, now)
+
+		// Отправитель спама получает тот же ответ, что и все
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(FeedbackResponse{ID: item.ID, Status: "received"})
+		if item.Status == FeedbackSpam {
+			l.logWarn("Отзыв %s от %s помечен как спам: %s", item.ID, ip, item.SpamReason)
+			return
+		}
+		l.dispatchWebhook(WebhookFeedbackReceived, item)
+		l.logSuccess("Получен отзыв %s (%s) от %s", item.ID, item.Category, ip)
+	})
+}
+
+// Отзывы: /admin/api/feedback?status=open (по умолчанию), resolved, spam
+// или all; фильтры category и tag
+func (l *Logger) adminListFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "💬", "/admin/api/feedback", func() {
+		query := r.URL.Query()
+		status, category := query.Get("status"), query.Get("category")
+		switch {
+		case status == "":
+			status = FeedbackOpen
+		case status == "all":
+			status = ""
+		case !slices.Contains(feedbackStatuses, status):
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidStatus)
+			return
+		}
+		if category != "" && !slices.Contains(feedbackCategories, category) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(FeedbackListResponse{Feedback: feedback.List(status, category, query.Get("tag"), "")})
+	})
+}
+
+func (l *Logger) adminFeedbackAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "💬", "/admin/api/feedback/{id}/attachment", func() {
+		item, ok := feedback.Get(r.PathValue("id"))
+		if !ok || item.Attachment == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeFeedbackNotFound, r.PathValue("id"))
+			return
+		}
+		file, err := os.Open(feedbackAttachmentPath(item.ID))
+		if err != nil {
+			l.logError("Ошибка открытия вложения отзыва %s: %v", item.ID, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+			return
+		}
+		defer file.Close()
+
+		// Вложение от игрока не должно открываться в браузере как страница
+		w.Header().Set("Content-Type", item.Attachment.ContentType)
+		w.Header().Set("Content-Disposition", contentDisposition(DispositionAttachment, item.Attachment.Filename))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "no-store")
+		http.ServeContent(w, r, "", item.CreatedAt, file)
+	})
+}
+
+// Статус, теги и заметка модератора
+func (l *Logger) adminUpdateFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "💬", "/admin/api/feedback/{id}", func() {
+		id := r.PathValue("id")
+		var req FeedbackUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		if req.Status != nil && !slices.Contains(feedbackStatuses, *req.Status) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidStatus)
+			return
+		}
+		var tags []string
+		if req.Tags != nil {
+			var ok bool
+			if tags, ok = normalizeTags(*req.Tags, maxFeedbackTags); !ok {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+		}
+
+		actor, _ := adminActor(r, "")
+		var updated Feedback
+		apiErr, err := feedback.update(func(list []Feedback) ([]Feedback, *modError) {
+			i := findFeedback(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeFeedbackNotFound, []interface{}{id}}
+			}
+			item := list[i]
+			if req.Status != nil && *req.Status != item.Status {
+				item.Status, item.ResolvedAt, item.ResolvedBy = *req.Status, nil, ""
+				if item.Status == FeedbackResolved {
+					now := time.Now().UTC()
+					item.ResolvedAt, item.ResolvedBy = &now, actor
+				}
+				if item.Status != FeedbackSpam {
+					item.SpamReason = ""
+				}
+			}
+			if req.Tags != nil {
+				item.Tags = append([]string{}, tags...)
+			}
+			if req.Note != nil {
+				item.Note = *req.Note
+			}
+			list[i], updated = item, item
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(updated)
+		l.logSuccess("Отзыв %s обновлен: %s, теги %v (%s)", id, updated.Status, updated.Tags, actor)
+	})
+}
+
+func (l *Logger) adminDeleteFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "💬", "/admin/api/feedback/{id}", func() {
+		id := r.PathValue("id")
+		apiErr, err := feedback.update(func(list []Feedback) ([]Feedback, *modError) {
+			i := findFeedback(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeFeedbackNotFound, []interface{}{id}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		os.Remove(feedbackAttachmentPath(id))
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Отзыв %s удален", id)
+	})
+}
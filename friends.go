@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Связь двух аккаунтов: заявка от From к To, после принятия — дружба
+type Friendship struct {
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type FriendRequest struct {
+	Username string `json:"username"`
+}
+
+type Friend struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	// Начало дружбы или время заявки
+	Since time.Time `json:"since"`
+	// Только у друзей
+	Presence *Presence `json:"presence,omitempty"`
+}
+
+// Друзья и заявки: /api/account/friends
+type FriendsResponse struct {
+	Friends  []Friend `json:"friends"`
+	Incoming []Friend `json:"incoming"`
+	Outgoing []Friend `json:"outgoing"`
+}
+
+// События friend_request и friend_accepted: кто прислал или принял заявку
+type FriendEvent struct {
+	Username string `json:"username"`
+}
+
+// Связи хранятся по ID аккаунтов в DATA_DIR/friends.json
+type FriendStore struct {
+	*jsonListStore[Friendship]
+}
+
+var friends = &FriendStore{&jsonListStore[Friendship]{file: friendsFile}}
+
+func friendsFile() string {
+	return filepath.Join(currentConfig().DataDir, "friends.json")
+}
+
+// Связи аккаунта: друзья, входящие и исходящие заявки
+func (s *FriendStore) List(accountID string) []Friendship {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Friendship
+	for _, link := range s.items {
+		if link.From == accountID || link.To == accountID {
+			result = append(result, link)
+		}
+	}
+	return result
+}
+
+// ID друзей аккаунта (без заявок)
+func (s *FriendStore) FriendIDs(accountID string) []string {
+	var ids []string
+	for _, link := range s.List(accountID) {
+		if link.AcceptedAt != nil {
+			ids = append(ids, link.other(accountID))
+		}
+	}
+	return ids
+}
+
+// Удаление всех связей аккаунта, например вместе с ним самим
+func (s *FriendStore) Forget(accountID string) error {
+	_, err := s.update(func(list []Friendship) ([]Friendship, *modError) {
+		return slices.DeleteFunc(list, func(link Friendship) bool {
+			return link.From == accountID || link.To == accountID
+		}), nil
+	})
+	return err
+}
+
+func (f Friendship) other(accountID string) string {
+	if f.From == accountID {
+		return f.To
+	}
+	return f.From
+}
+
+// Связь двух аккаунтов в любом направлении
+func findFriendship(list []Friendship, a, b string) int {
+	return slices.IndexFunc(list, func(link Friendship) bool {
+		return (link.From == a && link.To == b) || (link.From == b && link.To == a)
+	})
+}
+
+func friendLinks(list []Friendship, accountID string) int {
+	count := 0
+	for _, link := range list {
+		if link.From == accountID || link.To == accountID {
+			count++
+		}
+	}
+	return count
+}
+
+// Друзья и заявки аккаунта; присутствие видно только друзьям
+func friendsResponse(cfg *Config, accountID string) FriendsResponse {
+	response := FriendsResponse{Friends: []Friend{}, Incoming: []Friend{}, Outgoing: []Friend{}}
+	for _, link := range friends.List(accountID) {
+		other, ok := accounts.ByID(link.other(accountID))
+		if !ok {
+			continue
+		}
+		friend := Friend{
+			Username:    other.Username,
+			DisplayName: other.DisplayName,
+			AvatarURL:   other.avatarURL(cfg),
+			Since:       link.CreatedAt,
+		}
+		switch {
+		case link.AcceptedAt != nil:
+			presence := accountPresence(other)
+			friend.Since, friend.Presence = *link.AcceptedAt, &presence
+			response.Friends = append(response.Friends, friend)
+		case link.To == accountID:
+			response.Incoming = append(response.Incoming, friend)
+		default:
+			response.Outgoing = append(response.Outgoing, friend)
+		}
+	}
+	for _, list := range [][]Friend{response.Friends, response.Incoming, response.Outgoing} {
+		sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Username) < strings.ToLower(list[j].Username) })
+	}
+	return response
+}
+
+// Изменение связей с ответом об ошибке клиенту
+func (l *Logger) updateFriends(w http.ResponseWriter, r *http.Request, fn func(list []Friendship) ([]Friendship, *modError)) bool {
+	apiErr, err := friends.update(fn)
+	if err != nil {
+		l.logError("Ошибка сохранения друзей: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return false
+	}
+	if apiErr != nil {
+		writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+		return false
+	}
+	return true
+}
+
+func (l *Logger) friendsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🤝", "/api/account/friends", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(friendsResponse(currentConfig(), account.ID))
+	})
+}
+
+// Заявка в друзья. Встречная заявка принимается сразу, повторная ничего
+// не меняет.
+func (l *Logger) friendRequestHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🤝", "/api/account/friends", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req FriendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		target, ok := accounts.ByUsername(req.Username)
+		if !ok || target.DeletionScheduledAt != nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, req.Username)
+			return
+		}
+		if target.ID == account.ID {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		limit := currentConfig().FriendsMax
+		event := ""
+		ok = l.updateFriends(w, r, func(list []Friendship) ([]Friendship, *modError) {
+			now := time.Now().UTC()
+			if i := findFriendship(list, account.ID, target.ID); i >= 0 {
+				switch {
+				case list[i].AcceptedAt != nil:
+					return nil, &modError{http.StatusConflict, ErrCodeAlreadyFriends, []interface{}{target.Username}}
+				case list[i].From == target.ID:
+					list[i].AcceptedAt = &now
+					event = EventFriendAccepted
+				}
+				return list, nil
+			}
+			if friendLinks(list, account.ID) >= limit || friendLinks(list, target.ID) >= limit {
+				return nil, &modError{http.StatusConflict, ErrCodeFriendLimit, []interface{}{limit}}
+			}
+			event = EventFriendRequest
+			return append(list, Friendship{From: account.ID, To: target.ID, CreatedAt: now}), nil
+		})
+		if !ok {
+			return
+		}
+
+		if event != "" {
+			eventHub.PublishTo([]string{target.ID}, event, FriendEvent{Username: account.Username})
+		}
+		json.NewEncoder(w).Encode(friendsResponse(currentConfig(), account.ID))
+		l.logSuccess("Аккаунт %s отправил заявку в друзья %s", account.Username, target.Username)
+	})
+}
+
+func (l *Logger) friendAcceptHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🤝", "/api/account/friends/{username}/accept", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		username := r.PathValue("username")
+		target, found := accounts.ByUsername(username)
+		ok = l.updateFriends(w, r, func(list []Friendship) ([]Friendship, *modError) {
+			i := -1
+			if found {
+				i = findFriendship(list, account.ID, target.ID)
+			}
+			if i < 0 || list[i].From != target.ID || list[i].AcceptedAt != nil {
+				return nil, &modError{http.StatusNotFound, ErrCodeFriendNotFound, []interface{}{username}}
+			}
+			now := time.Now().UTC()
+			list[i].AcceptedAt = &now
+			return list, nil
+		})
+		if !ok {
+			return
+		}
+
+		eventHub.PublishTo([]string{target.ID}, EventFriendAccepted, FriendEvent{Username: account.Username})
+		json.NewEncoder(w).Encode(friendsResponse(currentConfig(), account.ID))
+		l.logSuccess("Аккаунты %s и %s теперь друзья", account.Username, target.Username)
+	})
+}
+
+// Удаление из друзей, отклонение входящей или отзыв исходящей заявки
+func (l *Logger) friendRemoveHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🤝", "/api/account/friends/{username}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		username := r.PathValue("username")
+		target, found := accounts.ByUsername(username)
+		ok = l.updateFriends(w, r, func(list []Friendship) ([]Friendship, *modError) {
+			i := -1
+			if found {
+				i = findFriendship(list, account.ID, target.ID)
+			}
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeFriendNotFound, []interface{}{username}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !ok {
+			return
+		}
+
+		json.NewEncoder(w).Encode(friendsResponse(currentConfig(), account.ID))
+		l.logSuccess("Аккаунт %s удалил %s из друзей и заявок", account.Username, target.Username)
+	})
+}
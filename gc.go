@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Время последнего скачивания версий модов и ресурспаков для политики
+// хранения. В DATA_DIR/artifact_downloads.json пишется не чаще раза в
+// час на версию: политике с точностью до дней этого достаточно.
+type ArtifactDownloads struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+var artifactDownloads = &ArtifactDownloads{last: make(map[string]time.Time)}
+
+func artifactDownloadsFile() string {
+	return filepath.Join(currentConfig().DataDir, "artifact_downloads.json")
+}
+
+func artifactKey(kind, name, version string) string {
+	return kind + "/" + name + "/" + version
+}
+
+func (d *ArtifactDownloads) Load() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	last := make(map[string]time.Time)
+	if err := loadJSONFile(artifactDownloadsFile(), &last); err != nil {
+		return err
+	}
+	d.last = last
+	return nil
+}
+
+// Отметка о скачивании версии
+func (d *ArtifactDownloads) Touch(kind, name, version string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key, now := artifactKey(kind, name, version), time.Now().UTC()
+	if now.Sub(d.last[key]) < time.Hour {
+		return nil
+	}
+	d.last[key] = now
+	return saveJSONFile(artifactDownloadsFile(), d.last)
+}
+
+func (d *ArtifactDownloads) Last(kind, name, version string) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last, ok := d.last[artifactKey(kind, name, version)]
+	return last, ok
+}
+
+// Удаленные версии больше не нужны в файле
+func (d *ArtifactDownloads) Forget(keys []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, key := range keys {
+		delete(d.last, key)
+	}
+	return saveJSONFile(artifactDownloadsFile(), d.last)
+}
+
+// Версия, которую политика хранения удалит (или удалила)
+type GCCandidate struct {
+	Kind           string     `json:"kind"`
+	Name           string     `json:"name"`
+	Version        string     `json:"version"`
+	Bytes          int64      `json:"bytes"`
+	UploadedAt     *time.Time `json:"uploaded_at,omitempty"`
+	LastDownloadAt *time.Time `json:"last_download_at,omitempty"`
+}
+
+type GCReport struct {
+	DryRun         bool          `json:"dry_run"`
+	KeepVersions   int           `json:"keep_versions"`
+	KeepDownloaded string        `json:"keep_downloaded"`
+	Candidates     []GCCandidate `json:"candidates"`
+	// Блобы, на которые не осталось ссылок
+	OrphanBlobs  int   `json:"orphan_blobs"`
+	ReclaimBytes int64 `json:"reclaim_bytes"`
+}
+
+// Очистка из админки и фоновая не должны удалять одно и то же одновременно
+var artifactGCMu sync.Mutex
+
+// Кандидаты на удаление. Остаются ARTIFACT_KEEP_VERSIONS новейших версий
+// мода на каждую версию игры из game_versions и ресурспака целиком,
+// версии, скачанные или загруженные за ARTIFACT_KEEP_DOWNLOADED, версии,
+// закрепленные в профилях сборок, и версии мода без файла (загрузка еще
+// не завершена). При ARTIFACT_KEEP_VERSIONS=0 очистка выключена.
+func planArtifactGC(cfg *Config, now time.Time) []GCCandidate {
+	candidates := []GCCandidate{}
+	if cfg.ArtifactKeepVersions == 0 {
+		return candidates
+	}
+	since := now.Add(-cfg.ArtifactKeepDownloaded)
+	recent := func(kind, name, version string, uploadedAt *time.Time) (*time.Time, bool) {
+		last, ok := artifactDownloads.Last(kind, name, version)
+		if ok && last.After(since) || uploadedAt != nil && uploadedAt.After(since) {
+			return nil, true
+		}
+		if !ok {
+			return nil, false
+		}
+		return &last, false
+	}
+
+	pinned := make(map[string]bool)
+	for _, pack := range modpacks.List() {
+		for _, mod := range pack.Mods {
+			if mod.Version != "" {
+				pinned[mod.ID+"/"+mod.Version] = true
+			}
+		}
+	}
+
+	for _, mod := range mods.List() {
+		channels := make(map[string][]string)
+		for _, version := range mod.Versions {
+			for _, game := range version.GameVersions {
+				channels[game] = append(channels[game], version.Version)
+			}
+		}
+		keep := make(map[string]bool)
+		for _, versions := range channels {
+			slices.SortFunc(versions, func(a, b string) int { return compareVersions(b, a) })
+			for _, version := range versions[:min(len(versions), cfg.ArtifactKeepVersions)] {
+				keep[version] = true
+			}
+		}
+
+		for _, version := range mod.Versions {
+			if keep[version.Version] || pinned[mod.ID+"/"+version.Version] || version.Filename == "" {
+				continue
+			}
+			last, ok := recent("mod", mod.ID, version.Version, version.UploadedAt)
+			if ok {
+				continue
+			}
+			candidates = append(candidates, GCCandidate{
+				Kind:           "mod",
+				Name:           mod.ID,
+				Version:        version.Version,
+				Bytes:          dirSize(modVersionDir(mod.ID, version.Version)),
+				UploadedAt:     version.UploadedAt,
+				LastDownloadAt: last,
+			})
+		}
+	}
+
+	for _, pack := range resourcePacks.List() {
+		versions := slices.Clone(pack.Versions)
+		slices.SortFunc(versions, func(a, b ResourcePackVersion) int { return compareVersions(b.Version, a.Version) })
+		for _, version := range versions[min(len(versions), cfg.ArtifactKeepVersions):] {
+			last, ok := recent("resourcepack", pack.Name, version.Version, &version.UploadedAt)
+			if ok {
+				continue
+			}
+			candidates = append(candidates, GCCandidate{
+				Kind:           "resourcepack",
+				Name:           pack.Name,
+				Version:        version.Version,
+				Bytes:          dirSize(resourcePackVersionDir(pack.Name, version.Version)),
+				UploadedAt:     &version.UploadedAt,
+				LastDownloadAt: last,
+			})
+		}
+	}
+	return candidates
+}
+
+// Место на диске под каталогом версии вместе со сжатыми вариантами
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// Отчет о кандидатах; без dryRun версии удаляются из списков и с диска
+func (l *Logger) collectArtifacts(cfg *Config, dryRun bool) GCReport {
+	artifactGCMu.Lock()
+	defer artifactGCMu.Unlock()
+
+	report := GCReport{
+		DryRun:         dryRun,
+		KeepVersions:   cfg.ArtifactKeepVersions,
+		KeepDownloaded: cfg.ArtifactKeepDownloaded.String(),
+		Candidates:     []GCCandidate{},
+	}
+	var forget []string
+	for _, candidate := range planArtifactGC(cfg, time.Now().UTC()) {
+		if !dryRun {
+			if err := deleteArtifactVersion(candidate.Kind, candidate.Name, candidate.Version); err != nil {
+				l.logError("Ошибка удаления версии %s %s %s: %v", candidate.Kind, candidate.Name, candidate.Version, err)
+				continue
+			}
+			forget = append(forget, artifactKey(candidate.Kind, candidate.Name, candidate.Version))
+		}
+		report.Candidates = append(report.Candidates, candidate)
+		report.ReclaimBytes += candidate.Bytes
+	}
+	if len(forget) > 0 {
+		if err := artifactDownloads.Forget(forget); err != nil {
+			l.logError("Ошибка сохранения времени скачиваний: %v", err)
+		}
+	}
+
+	// Удаленные версии освобождают место, только если блоб больше ни на
+	// что не ссылается, поэтому блобы сверяются после них
+	orphans, reclaimed, err := blobs.collect(dryRun)
+	if err != nil {
+		l.logError("Ошибка сохранения хранилища блобов: %v", err)
+	}
+	report.OrphanBlobs = orphans
+	report.ReclaimBytes += reclaimed
+	return report
+}
+
+// Удаление версии мода или ресурспака из списка и ее каталога; версия,
+// уже удаленная из админки, пропускается
+func deleteArtifactVersion(kind, name, version string) error {
+	var err error
+	switch kind {
+	case "mod":
+		_, err = mods.update(func(list []Mod) ([]Mod, *modError) {
+			if i := findMod(list, name); i >= 0 {
+				if j := findModVersion(list[i], version); j >= 0 {
+					list[i].Versions = slices.Delete(list[i].Versions, j, j+1)
+				}
+			}
+			return list, nil
+		})
+		if err == nil {
+			err = os.RemoveAll(modVersionDir(name, version))
+		}
+	case "resourcepack":
+		_, err = resourcePacks.update(func(list []ResourcePack) ([]ResourcePack, *modError) {
+			if i := findResourcePack(list, name); i >= 0 {
+				if j := findResourcePackVersion(list[i], version); j >= 0 {
+					list[i].Versions = slices.Delete(list[i].Versions, j, j+1)
+				}
+			}
+			return list, nil
+		})
+		if err == nil {
+			err = os.RemoveAll(resourcePackVersionDir(name, version))
+		}
+	}
+	return err
+}
+
+// Фоновая очистка старых версий и блобов без ссылок по ARTIFACT_GC_INTERVAL;
+// при ARTIFACT_KEEP_VERSIONS=0 сверяются только блобы. На реплике набор
+// версий задает синхронизация, поэтому очистки нет.
+func (l *Logger) runArtifactGC() {
+	for {
+		cfg := currentConfig()
+		if cfg.ReplicaOf != "" {
+			time.Sleep(cfg.ArtifactGCInterval)
+			continue
+		}
+		report := l.collectArtifacts(cfg, false)
+		if len(report.Candidates) > 0 || report.OrphanBlobs > 0 {
+			l.logSuccess("Очистка старых версий: удалено %d, блобов %d, освобождено %d bytes", len(report.Candidates), report.OrphanBlobs, report.ReclaimBytes)
+		}
+		time.Sleep(cfg.ArtifactGCInterval)
+	}
+}
+
+// Что удалит очистка по текущей политике, ничего не удаляя
+func (l *Logger) adminGCPlanHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧹", "/admin/api/gc", func() {
+		json.NewEncoder(w).Encode(l.collectArtifacts(currentConfig(), true))
+	})
+}
+
+// Очистка по текущей политике, не дожидаясь ARTIFACT_GC_INTERVAL
+func (l *Logger) adminRunGCHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧹", "/admin/api/gc", func() {
+		report := l.collectArtifacts(currentConfig(), false)
+		json.NewEncoder(w).Encode(report)
+		l.logSuccess("Очистка старых версий: удалено %d, блобов %d, освобождено %d bytes", len(report.Candidates), report.OrphanBlobs, report.ReclaimBytes)
+	})
+}
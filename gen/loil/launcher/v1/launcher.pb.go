@@ -0,0 +1,1075 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: loil/launcher/v1/launcher.proto
+
+// gRPC API лаунчера. Данные те же, что в REST /api/v1/..., и
+// собираются теми же функциями сервера.
+
+package launcherv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetVersionRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	LauncherVersion string                 `protobuf:"bytes,1,opt,name=launcher_version,json=launcherVersion,proto3" json:"launcher_version,omitempty"`
+	GameVersion     string                 `protobuf:"bytes,2,opt,name=game_version,json=gameVersion,proto3" json:"game_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetVersionRequest) Reset() {
+	*x = GetVersionRequest{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVersionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVersionRequest) ProtoMessage() {}
+
+func (x *GetVersionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVersionRequest.ProtoReflect.Descriptor instead.
+func (*GetVersionRequest) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetVersionRequest) GetLauncherVersion() string {
+	if x != nil {
+		return x.LauncherVersion
+	}
+	return ""
+}
+
+func (x *GetVersionRequest) GetGameVersion() string {
+	if x != nil {
+		return x.GameVersion
+	}
+	return ""
+}
+
+type BlockedVersions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Launcher      []string               `protobuf:"bytes,1,rep,name=launcher,proto3" json:"launcher,omitempty"`
+	Game          []string               `protobuf:"bytes,2,rep,name=game,proto3" json:"game,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockedVersions) Reset() {
+	*x = BlockedVersions{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockedVersions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockedVersions) ProtoMessage() {}
+
+func (x *BlockedVersions) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockedVersions.ProtoReflect.Descriptor instead.
+func (*BlockedVersions) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlockedVersions) GetLauncher() []string {
+	if x != nil {
+		return x.Launcher
+	}
+	return nil
+}
+
+func (x *BlockedVersions) GetGame() []string {
+	if x != nil {
+		return x.Game
+	}
+	return nil
+}
+
+type GetVersionResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	LauncherVersion string                 `protobuf:"bytes,1,opt,name=launcher_version,json=launcherVersion,proto3" json:"launcher_version,omitempty"`
+	GameVersion     string                 `protobuf:"bytes,2,opt,name=game_version,json=gameVersion,proto3" json:"game_version,omitempty"`
+	Maintenance     bool                   `protobuf:"varint,3,opt,name=maintenance,proto3" json:"maintenance,omitempty"`
+	LauncherBuild   int32                  `protobuf:"varint,4,opt,name=launcher_build,json=launcherBuild,proto3" json:"launcher_build,omitempty"`
+	GameBuild       int32                  `protobuf:"varint,5,opt,name=game_build,json=gameBuild,proto3" json:"game_build,omitempty"`
+	BlockedVersions *BlockedVersions       `protobuf:"bytes,6,opt,name=blocked_versions,json=blockedVersions,proto3" json:"blocked_versions,omitempty"`
+	// Версии клиента из запроса заблокированы, запускать нельзя
+	MustUpdate    bool `protobuf:"varint,7,opt,name=must_update,json=mustUpdate,proto3" json:"must_update,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVersionResponse) Reset() {
+	*x = GetVersionResponse{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVersionResponse) ProtoMessage() {}
+
+func (x *GetVersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVersionResponse.ProtoReflect.Descriptor instead.
+func (*GetVersionResponse) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetVersionResponse) GetLauncherVersion() string {
+	if x != nil {
+		return x.LauncherVersion
+	}
+	return ""
+}
+
+func (x *GetVersionResponse) GetGameVersion() string {
+	if x != nil {
+		return x.GameVersion
+	}
+	return ""
+}
+
+func (x *GetVersionResponse) GetMaintenance() bool {
+	if x != nil {
+		return x.Maintenance
+	}
+	return false
+}
+
+func (x *GetVersionResponse) GetLauncherBuild() int32 {
+	if x != nil {
+		return x.LauncherBuild
+	}
+	return 0
+}
+
+func (x *GetVersionResponse) GetGameBuild() int32 {
+	if x != nil {
+		return x.GameBuild
+	}
+	return 0
+}
+
+func (x *GetVersionResponse) GetBlockedVersions() *BlockedVersions {
+	if x != nil {
+		return x.BlockedVersions
+	}
+	return nil
+}
+
+func (x *GetVersionResponse) GetMustUpdate() bool {
+	if x != nil {
+		return x.MustUpdate
+	}
+	return false
+}
+
+type ListNewsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Lang  string                 `protobuf:"bytes,1,opt,name=lang,proto3" json:"lang,omitempty"`
+	// Заполнить rendered_html (как format=html в REST)
+	Html          bool `protobuf:"varint,2,opt,name=html,proto3" json:"html,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNewsRequest) Reset() {
+	*x = ListNewsRequest{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNewsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNewsRequest) ProtoMessage() {}
+
+func (x *ListNewsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNewsRequest.ProtoReflect.Descriptor instead.
+func (*ListNewsRequest) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListNewsRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *ListNewsRequest) GetHtml() bool {
+	if x != nil {
+		return x.Html
+	}
+	return false
+}
+
+type NewsItem struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title   string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Content string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	// Имя JPG файла в /images/
+	Image string `protobuf:"bytes,4,opt,name=image,proto3" json:"image,omitempty"`
+	Date  string `protobuf:"bytes,5,opt,name=date,proto3" json:"date,omitempty"`
+	// Язык, на котором отдана новость
+	Lang          string                 `protobuf:"bytes,6,opt,name=lang,proto3" json:"lang,omitempty"`
+	RenderedHtml  string                 `protobuf:"bytes,7,opt,name=rendered_html,json=renderedHtml,proto3" json:"rendered_html,omitempty"`
+	PublishAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=publish_at,json=publishAt,proto3" json:"publish_at,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NewsItem) Reset() {
+	*x = NewsItem{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NewsItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NewsItem) ProtoMessage() {}
+
+func (x *NewsItem) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NewsItem.ProtoReflect.Descriptor instead.
+func (*NewsItem) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *NewsItem) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *NewsItem) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *NewsItem) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *NewsItem) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *NewsItem) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *NewsItem) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *NewsItem) GetRenderedHtml() string {
+	if x != nil {
+		return x.RenderedHtml
+	}
+	return ""
+}
+
+func (x *NewsItem) GetPublishAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishAt
+	}
+	return nil
+}
+
+func (x *NewsItem) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type ListNewsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	News          []*NewsItem            `protobuf:"bytes,1,rep,name=news,proto3" json:"news,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNewsResponse) Reset() {
+	*x = ListNewsResponse{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNewsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNewsResponse) ProtoMessage() {}
+
+func (x *ListNewsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNewsResponse.ProtoReflect.Descriptor instead.
+func (*ListNewsResponse) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListNewsResponse) GetNews() []*NewsItem {
+	if x != nil {
+		return x.News
+	}
+	return nil
+}
+
+type Chunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// SHA-256 содержимого; скачивается через GET /api/chunks/{hash}
+	Hash          string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Offset        int64  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Size          int32  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Chunk) Reset() {
+	*x = Chunk{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chunk) ProtoMessage() {}
+
+func (x *Chunk) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chunk.ProtoReflect.Descriptor instead.
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Chunk) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Chunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Chunk) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type Manifest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Artifact string                 `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	Filename string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version  string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Size     int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	// MD5 всего файла для итоговой проверки
+	Hash          string                 `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	Chunks        []*Chunk               `protobuf:"bytes,6,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Manifest) Reset() {
+	*x = Manifest{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Manifest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Manifest) ProtoMessage() {}
+
+func (x *Manifest) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Manifest.ProtoReflect.Descriptor instead.
+func (*Manifest) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Manifest) GetArtifact() string {
+	if x != nil {
+		return x.Artifact
+	}
+	return ""
+}
+
+func (x *Manifest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Manifest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Manifest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Manifest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Manifest) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *Manifest) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type GetManifestRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// launcher или game
+	Artifact      string `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetManifestRequest) Reset() {
+	*x = GetManifestRequest{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetManifestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetManifestRequest) ProtoMessage() {}
+
+func (x *GetManifestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetManifestRequest.ProtoReflect.Descriptor instead.
+func (*GetManifestRequest) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetManifestRequest) GetArtifact() string {
+	if x != nil {
+		return x.Artifact
+	}
+	return ""
+}
+
+type GetManifestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manifest      *Manifest              `protobuf:"bytes,1,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetManifestResponse) Reset() {
+	*x = GetManifestResponse{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetManifestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetManifestResponse) ProtoMessage() {}
+
+func (x *GetManifestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetManifestResponse.ProtoReflect.Descriptor instead.
+func (*GetManifestResponse) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetManifestResponse) GetManifest() *Manifest {
+	if x != nil {
+		return x.Manifest
+	}
+	return nil
+}
+
+type DiffManifestRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Artifact string                 `protobuf:"bytes,1,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	// Хэши чанков, которые уже лежат у клиента
+	Have          []string `protobuf:"bytes,2,rep,name=have,proto3" json:"have,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffManifestRequest) Reset() {
+	*x = DiffManifestRequest{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffManifestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffManifestRequest) ProtoMessage() {}
+
+func (x *DiffManifestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffManifestRequest.ProtoReflect.Descriptor instead.
+func (*DiffManifestRequest) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DiffManifestRequest) GetArtifact() string {
+	if x != nil {
+		return x.Artifact
+	}
+	return ""
+}
+
+func (x *DiffManifestRequest) GetHave() []string {
+	if x != nil {
+		return x.Have
+	}
+	return nil
+}
+
+type DiffManifestResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*DiffManifestResponse_Manifest
+	//	*DiffManifestResponse_Missing
+	Event         isDiffManifestResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiffManifestResponse) Reset() {
+	*x = DiffManifestResponse{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiffManifestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiffManifestResponse) ProtoMessage() {}
+
+func (x *DiffManifestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiffManifestResponse.ProtoReflect.Descriptor instead.
+func (*DiffManifestResponse) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DiffManifestResponse) GetEvent() isDiffManifestResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *DiffManifestResponse) GetManifest() *ManifestSummary {
+	if x != nil {
+		if x, ok := x.Event.(*DiffManifestResponse_Manifest); ok {
+			return x.Manifest
+		}
+	}
+	return nil
+}
+
+func (x *DiffManifestResponse) GetMissing() *ChunkBatch {
+	if x != nil {
+		if x, ok := x.Event.(*DiffManifestResponse_Missing); ok {
+			return x.Missing
+		}
+	}
+	return nil
+}
+
+type isDiffManifestResponse_Event interface {
+	isDiffManifestResponse_Event()
+}
+
+type DiffManifestResponse_Manifest struct {
+	// Первое сообщение: манифест без chunks и число недостающих чанков
+	Manifest *ManifestSummary `protobuf:"bytes,1,opt,name=manifest,proto3,oneof"`
+}
+
+type DiffManifestResponse_Missing struct {
+	// Остальные: недостающие чанки по порядку в файле
+	Missing *ChunkBatch `protobuf:"bytes,2,opt,name=missing,proto3,oneof"`
+}
+
+func (*DiffManifestResponse_Manifest) isDiffManifestResponse_Event() {}
+
+func (*DiffManifestResponse_Missing) isDiffManifestResponse_Event() {}
+
+type ManifestSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manifest      *Manifest              `protobuf:"bytes,1,opt,name=manifest,proto3" json:"manifest,omitempty"`
+	MissingChunks int32                  `protobuf:"varint,2,opt,name=missing_chunks,json=missingChunks,proto3" json:"missing_chunks,omitempty"`
+	MissingBytes  int64                  `protobuf:"varint,3,opt,name=missing_bytes,json=missingBytes,proto3" json:"missing_bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ManifestSummary) Reset() {
+	*x = ManifestSummary{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ManifestSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ManifestSummary) ProtoMessage() {}
+
+func (x *ManifestSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ManifestSummary.ProtoReflect.Descriptor instead.
+func (*ManifestSummary) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ManifestSummary) GetManifest() *Manifest {
+	if x != nil {
+		return x.Manifest
+	}
+	return nil
+}
+
+func (x *ManifestSummary) GetMissingChunks() int32 {
+	if x != nil {
+		return x.MissingChunks
+	}
+	return 0
+}
+
+func (x *ManifestSummary) GetMissingBytes() int64 {
+	if x != nil {
+		return x.MissingBytes
+	}
+	return 0
+}
+
+type ChunkBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chunks        []*Chunk               `protobuf:"bytes,1,rep,name=chunks,proto3" json:"chunks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChunkBatch) Reset() {
+	*x = ChunkBatch{}
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChunkBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChunkBatch) ProtoMessage() {}
+
+func (x *ChunkBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_loil_launcher_v1_launcher_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChunkBatch.ProtoReflect.Descriptor instead.
+func (*ChunkBatch) Descriptor() ([]byte, []int) {
+	return file_loil_launcher_v1_launcher_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ChunkBatch) GetChunks() []*Chunk {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+var File_loil_launcher_v1_launcher_proto protoreflect.FileDescriptor
+
+const file_loil_launcher_v1_launcher_proto_rawDesc = "" +
+	"\n" +
+	"\x1floil/launcher/v1/launcher.proto\x12\x10loil.launcher.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"a\n" +
+	"\x11GetVersionRequest\x12)\n" +
+	"\x10launcher_version\x18\x01 \x01(\tR\x0flauncherVersion\x12!\n" +
+	"\fgame_version\x18\x02 \x01(\tR\vgameVersion\"A\n" +
+	"\x0fBlockedVersions\x12\x1a\n" +
+	"\blauncher\x18\x01 \x03(\tR\blauncher\x12\x12\n" +
+	"\x04game\x18\x02 \x03(\tR\x04game\"\xb9\x02\n" +
+	"\x12GetVersionResponse\x12)\n" +
+	"\x10launcher_version\x18\x01 \x01(\tR\x0flauncherVersion\x12!\n" +
+	"\fgame_version\x18\x02 \x01(\tR\vgameVersion\x12 \n" +
+	"\vmaintenance\x18\x03 \x01(\bR\vmaintenance\x12%\n" +
+	"\x0elauncher_build\x18\x04 \x01(\x05R\rlauncherBuild\x12\x1d\n" +
+	"\n" +
+	"game_build\x18\x05 \x01(\x05R\tgameBuild\x12L\n" +
+	"\x10blocked_versions\x18\x06 \x01(\v2!.loil.launcher.v1.BlockedVersionsR\x0fblockedVersions\x12\x1f\n" +
+	"\vmust_update\x18\a \x01(\bR\n" +
+	"mustUpdate\"9\n" +
+	"\x0fListNewsRequest\x12\x12\n" +
+	"\x04lang\x18\x01 \x01(\tR\x04lang\x12\x12\n" +
+	"\x04html\x18\x02 \x01(\bR\x04html\"\xa3\x02\n" +
+	"\bNewsItem\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x12\x14\n" +
+	"\x05image\x18\x04 \x01(\tR\x05image\x12\x12\n" +
+	"\x04date\x18\x05 \x01(\tR\x04date\x12\x12\n" +
+	"\x04lang\x18\x06 \x01(\tR\x04lang\x12#\n" +
+	"\rrendered_html\x18\a \x01(\tR\frenderedHtml\x129\n" +
+	"\n" +
+	"publish_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tpublishAt\x129\n" +
+	"\n" +
+	"expires_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"B\n" +
+	"\x10ListNewsResponse\x12.\n" +
+	"\x04news\x18\x01 \x03(\v2\x1a.loil.launcher.v1.NewsItemR\x04news\"G\n" +
+	"\x05Chunk\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x05R\x04size\"\xf0\x01\n" +
+	"\bManifest\x12\x1a\n" +
+	"\bartifact\x18\x01 \x01(\tR\bartifact\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hash\x12/\n" +
+	"\x06chunks\x18\x06 \x03(\v2\x17.loil.launcher.v1.ChunkR\x06chunks\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"0\n" +
+	"\x12GetManifestRequest\x12\x1a\n" +
+	"\bartifact\x18\x01 \x01(\tR\bartifact\"M\n" +
+	"\x13GetManifestResponse\x126\n" +
+	"\bmanifest\x18\x01 \x01(\v2\x1a.loil.launcher.v1.ManifestR\bmanifest\"E\n" +
+	"\x13DiffManifestRequest\x12\x1a\n" +
+	"\bartifact\x18\x01 \x01(\tR\bartifact\x12\x12\n" +
+	"\x04have\x18\x02 \x03(\tR\x04have\"\x9a\x01\n" +
+	"\x14DiffManifestResponse\x12?\n" +
+	"\bmanifest\x18\x01 \x01(\v2!.loil.launcher.v1.ManifestSummaryH\x00R\bmanifest\x128\n" +
+	"\amissing\x18\x02 \x01(\v2\x1c.loil.launcher.v1.ChunkBatchH\x00R\amissingB\a\n" +
+	"\x05event\"\x95\x01\n" +
+	"\x0fManifestSummary\x126\n" +
+	"\bmanifest\x18\x01 \x01(\v2\x1a.loil.launcher.v1.ManifestR\bmanifest\x12%\n" +
+	"\x0emissing_chunks\x18\x02 \x01(\x05R\rmissingChunks\x12#\n" +
+	"\rmissing_bytes\x18\x03 \x01(\x03R\fmissingBytes\"=\n" +
+	"\n" +
+	"ChunkBatch\x12/\n" +
+	"\x06chunks\x18\x01 \x03(\v2\x17.loil.launcher.v1.ChunkR\x06chunks2\xfa\x02\n" +
+	"\x0fLauncherService\x12W\n" +
+	"\n" +
+	"GetVersion\x12#.loil.launcher.v1.GetVersionRequest\x1a$.loil.launcher.v1.GetVersionResponse\x12Q\n" +
+	"\bListNews\x12!.loil.launcher.v1.ListNewsRequest\x1a\".loil.launcher.v1.ListNewsResponse\x12Z\n" +
+	"\vGetManifest\x12$.loil.launcher.v1.GetManifestRequest\x1a%.loil.launcher.v1.GetManifestResponse\x12_\n" +
+	"\fDiffManifest\x12%.loil.launcher.v1.DiffManifestRequest\x1a&.loil.launcher.v1.DiffManifestResponse0\x01B6Z4LOIL-launcher-server/gen/loil/launcher/v1;launcherv1b\x06proto3"
+
+var (
+	file_loil_launcher_v1_launcher_proto_rawDescOnce sync.Once
+	file_loil_launcher_v1_launcher_proto_rawDescData []byte
+)
+
+func file_loil_launcher_v1_launcher_proto_rawDescGZIP() []byte {
+	file_loil_launcher_v1_launcher_proto_rawDescOnce.Do(func() {
+		file_loil_launcher_v1_launcher_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_loil_launcher_v1_launcher_proto_rawDesc), len(file_loil_launcher_v1_launcher_proto_rawDesc)))
+	})
+	return file_loil_launcher_v1_launcher_proto_rawDescData
+}
+
+var file_loil_launcher_v1_launcher_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_loil_launcher_v1_launcher_proto_goTypes = []any{
+	(*GetVersionRequest)(nil),     // 0: loil.launcher.v1.GetVersionRequest
+	(*BlockedVersions)(nil),       // 1: loil.launcher.v1.BlockedVersions
+	(*GetVersionResponse)(nil),    // 2: loil.launcher.v1.GetVersionResponse
+	(*ListNewsRequest)(nil),       // 3: loil.launcher.v1.ListNewsRequest
+	(*NewsItem)(nil),              // 4: loil.launcher.v1.NewsItem
+	(*ListNewsResponse)(nil),      // 5: loil.launcher.v1.ListNewsResponse
+	(*Chunk)(nil),                 // 6: loil.launcher.v1.Chunk
+	(*Manifest)(nil),              // 7: loil.launcher.v1.Manifest
+	(*GetManifestRequest)(nil),    // 8: loil.launcher.v1.GetManifestRequest
+	(*GetManifestResponse)(nil),   // 9: loil.launcher.v1.GetManifestResponse
+	(*DiffManifestRequest)(nil),   // 10: loil.launcher.v1.DiffManifestRequest
+	(*DiffManifestResponse)(nil),  // 11: loil.launcher.v1.DiffManifestResponse
+	(*ManifestSummary)(nil),       // 12: loil.launcher.v1.ManifestSummary
+	(*ChunkBatch)(nil),            // 13: loil.launcher.v1.ChunkBatch
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
+}
+var file_loil_launcher_v1_launcher_proto_depIdxs = []int32{
+	1,  // 0: loil.launcher.v1.GetVersionResponse.blocked_versions:type_name -> loil.launcher.v1.BlockedVersions
+	14, // 1: loil.launcher.v1.NewsItem.publish_at:type_name -> google.protobuf.Timestamp
+	14, // 2: loil.launcher.v1.NewsItem.expires_at:type_name -> google.protobuf.Timestamp
+	4,  // 3: loil.launcher.v1.ListNewsResponse.news:type_name -> loil.launcher.v1.NewsItem
+	6,  // 4: loil.launcher.v1.Manifest.chunks:type_name -> loil.launcher.v1.Chunk
+	14, // 5: loil.launcher.v1.Manifest.created_at:type_name -> google.protobuf.Timestamp
+	7,  // 6: loil.launcher.v1.GetManifestResponse.manifest:type_name -> loil.launcher.v1.Manifest
+	12, // 7: loil.launcher.v1.DiffManifestResponse.manifest:type_name -> loil.launcher.v1.ManifestSummary
+	13, // 8: loil.launcher.v1.DiffManifestResponse.missing:type_name -> loil.launcher.v1.ChunkBatch
+	7,  // 9: loil.launcher.v1.ManifestSummary.manifest:type_name -> loil.launcher.v1.Manifest
+	6,  // 10: loil.launcher.v1.ChunkBatch.chunks:type_name -> loil.launcher.v1.Chunk
+	0,  // 11: loil.launcher.v1.LauncherService.GetVersion:input_type -> loil.launcher.v1.GetVersionRequest
+	3,  // 12: loil.launcher.v1.LauncherService.ListNews:input_type -> loil.launcher.v1.ListNewsRequest
+	8,  // 13: loil.launcher.v1.LauncherService.GetManifest:input_type -> loil.launcher.v1.GetManifestRequest
+	10, // 14: loil.launcher.v1.LauncherService.DiffManifest:input_type -> loil.launcher.v1.DiffManifestRequest
+	2,  // 15: loil.launcher.v1.LauncherService.GetVersion:output_type -> loil.launcher.v1.GetVersionResponse
+	5,  // 16: loil.launcher.v1.LauncherService.ListNews:output_type -> loil.launcher.v1.ListNewsResponse
+	9,  // 17: loil.launcher.v1.LauncherService.GetManifest:output_type -> loil.launcher.v1.GetManifestResponse
+	11, // 18: loil.launcher.v1.LauncherService.DiffManifest:output_type -> loil.launcher.v1.DiffManifestResponse
+	15, // [15:19] is the sub-list for method output_type
+	11, // [11:15] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_loil_launcher_v1_launcher_proto_init() }
+func file_loil_launcher_v1_launcher_proto_init() {
+	if File_loil_launcher_v1_launcher_proto != nil {
+		return
+	}
+	file_loil_launcher_v1_launcher_proto_msgTypes[11].OneofWrappers = []any{
+		(*DiffManifestResponse_Manifest)(nil),
+		(*DiffManifestResponse_Missing)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_loil_launcher_v1_launcher_proto_rawDesc), len(file_loil_launcher_v1_launcher_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_loil_launcher_v1_launcher_proto_goTypes,
+		DependencyIndexes: file_loil_launcher_v1_launcher_proto_depIdxs,
+		MessageInfos:      file_loil_launcher_v1_launcher_proto_msgTypes,
+	}.Build()
+	File_loil_launcher_v1_launcher_proto = out.File
+	file_loil_launcher_v1_launcher_proto_goTypes = nil
+	file_loil_launcher_v1_launcher_proto_depIdxs = nil
+}
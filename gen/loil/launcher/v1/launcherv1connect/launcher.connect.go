@@ -0,0 +1,212 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: loil/launcher/v1/launcher.proto
+
+// gRPC API лаунчера. Данные те же, что в REST /api/v1/..., и
+// собираются теми же функциями сервера.
+package launcherv1connect
+
+import (
+	v1 "LOIL-launcher-server/gen/loil/launcher/v1"
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// LauncherServiceName is the fully-qualified name of the LauncherService service.
+	LauncherServiceName = "loil.launcher.v1.LauncherService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// LauncherServiceGetVersionProcedure is the fully-qualified name of the LauncherService's
+	// GetVersion RPC.
+	LauncherServiceGetVersionProcedure = "/loil.launcher.v1.LauncherService/GetVersion"
+	// LauncherServiceListNewsProcedure is the fully-qualified name of the LauncherService's ListNews
+	// RPC.
+	LauncherServiceListNewsProcedure = "/loil.launcher.v1.LauncherService/ListNews"
+	// LauncherServiceGetManifestProcedure is the fully-qualified name of the LauncherService's
+	// GetManifest RPC.
+	LauncherServiceGetManifestProcedure = "/loil.launcher.v1.LauncherService/GetManifest"
+	// LauncherServiceDiffManifestProcedure is the fully-qualified name of the LauncherService's
+	// DiffManifest RPC.
+	LauncherServiceDiffManifestProcedure = "/loil.launcher.v1.LauncherService/DiffManifest"
+)
+
+// LauncherServiceClient is a client for the loil.launcher.v1.LauncherService service.
+type LauncherServiceClient interface {
+	// Актуальные версии (GET /api/version). Лаунчер передает свои версии,
+	// чтобы узнать, не заблокированы ли они.
+	GetVersion(context.Context, *connect.Request[v1.GetVersionRequest]) (*connect.Response[v1.GetVersionResponse], error)
+	// Опубликованные новости на языке клиента (GET /api/news). Язык
+	// берется из lang, затем из заголовка Accept-Language.
+	ListNews(context.Context, *connect.Request[v1.ListNewsRequest]) (*connect.Response[v1.ListNewsResponse], error)
+	// Манифест чанков артефакта (GET /api/manifest/{artifact})
+	GetManifest(context.Context, *connect.Request[v1.GetManifestRequest]) (*connect.Response[v1.GetManifestResponse], error)
+	// Разница манифеста с тем, что уже есть у клиента: первым приходит
+	// манифест без списка чанков, затем недостающие чанки пачками.
+	DiffManifest(context.Context, *connect.Request[v1.DiffManifestRequest]) (*connect.ServerStreamForClient[v1.DiffManifestResponse], error)
+}
+
+// NewLauncherServiceClient constructs a client for the loil.launcher.v1.LauncherService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewLauncherServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) LauncherServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	launcherServiceMethods := v1.File_loil_launcher_v1_launcher_proto.Services().ByName("LauncherService").Methods()
+	return &launcherServiceClient{
+		getVersion: connect.NewClient[v1.GetVersionRequest, v1.GetVersionResponse](
+			httpClient,
+			baseURL+LauncherServiceGetVersionProcedure,
+			connect.WithSchema(launcherServiceMethods.ByName("GetVersion")),
+			connect.WithClientOptions(opts...),
+		),
+		listNews: connect.NewClient[v1.ListNewsRequest, v1.ListNewsResponse](
+			httpClient,
+			baseURL+LauncherServiceListNewsProcedure,
+			connect.WithSchema(launcherServiceMethods.ByName("ListNews")),
+			connect.WithClientOptions(opts...),
+		),
+		getManifest: connect.NewClient[v1.GetManifestRequest, v1.GetManifestResponse](
+			httpClient,
+			baseURL+LauncherServiceGetManifestProcedure,
+			connect.WithSchema(launcherServiceMethods.ByName("GetManifest")),
+			connect.WithClientOptions(opts...),
+		),
+		diffManifest: connect.NewClient[v1.DiffManifestRequest, v1.DiffManifestResponse](
+			httpClient,
+			baseURL+LauncherServiceDiffManifestProcedure,
+			connect.WithSchema(launcherServiceMethods.ByName("DiffManifest")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// launcherServiceClient implements LauncherServiceClient.
+type launcherServiceClient struct {
+	getVersion   *connect.Client[v1.GetVersionRequest, v1.GetVersionResponse]
+	listNews     *connect.Client[v1.ListNewsRequest, v1.ListNewsResponse]
+	getManifest  *connect.Client[v1.GetManifestRequest, v1.GetManifestResponse]
+	diffManifest *connect.Client[v1.DiffManifestRequest, v1.DiffManifestResponse]
+}
+
+// GetVersion calls loil.launcher.v1.LauncherService.GetVersion.
+func (c *launcherServiceClient) GetVersion(ctx context.Context, req *connect.Request[v1.GetVersionRequest]) (*connect.Response[v1.GetVersionResponse], error) {
+	return c.getVersion.CallUnary(ctx, req)
+}
+
+// ListNews calls loil.launcher.v1.LauncherService.ListNews.
+func (c *launcherServiceClient) ListNews(ctx context.Context, req *connect.Request[v1.ListNewsRequest]) (*connect.Response[v1.ListNewsResponse], error) {
+	return c.listNews.CallUnary(ctx, req)
+}
+
+// GetManifest calls loil.launcher.v1.LauncherService.GetManifest.
+func (c *launcherServiceClient) GetManifest(ctx context.Context, req *connect.Request[v1.GetManifestRequest]) (*connect.Response[v1.GetManifestResponse], error) {
+	return c.getManifest.CallUnary(ctx, req)
+}
+
+// DiffManifest calls loil.launcher.v1.LauncherService.DiffManifest.
+func (c *launcherServiceClient) DiffManifest(ctx context.Context, req *connect.Request[v1.DiffManifestRequest]) (*connect.ServerStreamForClient[v1.DiffManifestResponse], error) {
+	return c.diffManifest.CallServerStream(ctx, req)
+}
+
+// LauncherServiceHandler is an implementation of the loil.launcher.v1.LauncherService service.
+type LauncherServiceHandler interface {
+	// Актуальные версии (GET /api/version). Лаунчер передает свои версии,
+	// чтобы узнать, не заблокированы ли они.
+	GetVersion(context.Context, *connect.Request[v1.GetVersionRequest]) (*connect.Response[v1.GetVersionResponse], error)
+	// Опубликованные новости на языке клиента (GET /api/news). Язык
+	// берется из lang, затем из заголовка Accept-Language.
+	ListNews(context.Context, *connect.Request[v1.ListNewsRequest]) (*connect.Response[v1.ListNewsResponse], error)
+	// Манифест чанков артефакта (GET /api/manifest/{artifact})
+	GetManifest(context.Context, *connect.Request[v1.GetManifestRequest]) (*connect.Response[v1.GetManifestResponse], error)
+	// Разница манифеста с тем, что уже есть у клиента: первым приходит
+	// манифест без списка чанков, затем недостающие чанки пачками.
+	DiffManifest(context.Context, *connect.Request[v1.DiffManifestRequest], *connect.ServerStream[v1.DiffManifestResponse]) error
+}
+
+// NewLauncherServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewLauncherServiceHandler(svc LauncherServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	launcherServiceMethods := v1.File_loil_launcher_v1_launcher_proto.Services().ByName("LauncherService").Methods()
+	launcherServiceGetVersionHandler := connect.NewUnaryHandler(
+		LauncherServiceGetVersionProcedure,
+		svc.GetVersion,
+		connect.WithSchema(launcherServiceMethods.ByName("GetVersion")),
+		connect.WithHandlerOptions(opts...),
+	)
+	launcherServiceListNewsHandler := connect.NewUnaryHandler(
+		LauncherServiceListNewsProcedure,
+		svc.ListNews,
+		connect.WithSchema(launcherServiceMethods.ByName("ListNews")),
+		connect.WithHandlerOptions(opts...),
+	)
+	launcherServiceGetManifestHandler := connect.NewUnaryHandler(
+		LauncherServiceGetManifestProcedure,
+		svc.GetManifest,
+		connect.WithSchema(launcherServiceMethods.ByName("GetManifest")),
+		connect.WithHandlerOptions(opts...),
+	)
+	launcherServiceDiffManifestHandler := connect.NewServerStreamHandler(
+		LauncherServiceDiffManifestProcedure,
+		svc.DiffManifest,
+		connect.WithSchema(launcherServiceMethods.ByName("DiffManifest")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/loil.launcher.v1.LauncherService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case LauncherServiceGetVersionProcedure:
+			launcherServiceGetVersionHandler.ServeHTTP(w, r)
+		case LauncherServiceListNewsProcedure:
+			launcherServiceListNewsHandler.ServeHTTP(w, r)
+		case LauncherServiceGetManifestProcedure:
+			launcherServiceGetManifestHandler.ServeHTTP(w, r)
+		case LauncherServiceDiffManifestProcedure:
+			launcherServiceDiffManifestHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedLauncherServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedLauncherServiceHandler struct{}
+
+func (UnimplementedLauncherServiceHandler) GetVersion(context.Context, *connect.Request[v1.GetVersionRequest]) (*connect.Response[v1.GetVersionResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("loil.launcher.v1.LauncherService.GetVersion is not implemented"))
+}
+
+func (UnimplementedLauncherServiceHandler) ListNews(context.Context, *connect.Request[v1.ListNewsRequest]) (*connect.Response[v1.ListNewsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("loil.launcher.v1.LauncherService.ListNews is not implemented"))
+}
+
+func (UnimplementedLauncherServiceHandler) GetManifest(context.Context, *connect.Request[v1.GetManifestRequest]) (*connect.Response[v1.GetManifestResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("loil.launcher.v1.LauncherService.GetManifest is not implemented"))
+}
+
+func (UnimplementedLauncherServiceHandler) DiffManifest(context.Context, *connect.Request[v1.DiffManifestRequest], *connect.ServerStream[v1.DiffManifestResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("loil.launcher.v1.LauncherService.DiffManifest is not implemented"))
+}
@@ -2,4 +2,18 @@ module LOIL-launcher-server
 
 go 1.25.1
 
-require github.com/joho/godotenv v1.5.1
+require (
+	connectrpc.com/connect v1.19.1
+	github.com/fsnotify/fsnotify v1.10.1
+	github.com/joho/godotenv v1.5.1
+	github.com/quic-go/quic-go v0.59.1
+	google.golang.org/protobuf v1.36.9
+)
+
+require (
+	github.com/quic-go/qpack v0.6.0 // indirect
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+)
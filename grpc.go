@@ -0,0 +1,299 @@
+package main
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	launcherv1 "LOIL-launcher-server/gen/loil/launcher/v1"
+	"LOIL-launcher-server/gen/loil/launcher/v1/launcherv1connect"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Сколько недостающих чанков в одном сообщении DiffManifest
+const grpcChunkBatch = 256
+
+// gRPC-сервисы поверх тех же функций, что и REST-обработчики. connect
+// принимает gRPC, gRPC-Web и Connect на обычном net/http, поэтому сервисы
+// висят на публичном роутере рядом с /api/ (пути /loil.launcher.v1.*/).
+type launcherService struct{ l *Logger }
+
+type authService struct{ l *Logger }
+
+type grpcRequestKey struct{}
+
+func (l *Logger) registerGRPC(router *Router) {
+	path, handler := launcherv1connect.NewLauncherServiceHandler(launcherService{l})
+	router.Handle(path, l.grpcRoute(handler))
+	// Вызовы gRPC — всегда POST; LauncherService только читает
+	router.AllowOnReplica(path)
+	path, handler = launcherv1connect.NewAuthServiceHandler(authService{l})
+	router.Handle(path, l.grpcRoute(handler))
+}
+
+// Журнал запросов как у REST, CORS для gRPC-Web из браузера и отказ
+// gRPC-Web, если он выключен. Исходный запрос кладется в контекст:
+// сервисные функции берут из него адрес клиента, Authorization и
+// Accept-Language.
+func (l *Logger) grpcRoute(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if currentConfig().GRPCWeb {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Accept-Language, X-Grpc-Web, X-User-Agent, X-Captcha-Token, Grpc-Timeout, Connect-Protocol-Version, Connect-Timeout-Ms")
+			w.Header().Set("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin, X-Error-Code, X-Request-ID, Retry-After, X-Captcha-Site-Key")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		} else if strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc-web") {
+			writeError(w, r, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, r.Header.Get("Content-Type"))
+			return
+		}
+
+		clientIP := getClientIP(r)
+		l.Printf("🛰️ Запрос %s от %s", r.URL.Path, clientIP)
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), grpcRequestKey{}, r)))
+		l.logAccess(clientIP, r.URL.Path, "🛰️")
+	})
+}
+
+func grpcRequest(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(grpcRequestKey{}).(*http.Request)
+	return r
+}
+
+// Ошибка сервисной функции в статус gRPC. Текст переводится так же,
+// как в REST; код API и заголовки ошибки уходят в метаданные.
+func grpcError(r *http.Request, apiErr *modError) error {
+	err := connect.NewError(grpcCode(apiErr.status), errors.New(translate(r, strings.ToLower(apiErr.code), apiErr.args...)))
+	err.Meta().Set("X-Error-Code", apiErr.code)
+	err.Meta().Set("X-Request-ID", requestID(r))
+	for key, values := range apiErr.headers() {
+		err.Meta()[key] = values
+	}
+	return err
+}
+
+func grpcCode(status int) connect.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return connect.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return connect.CodeUnauthenticated
+	case http.StatusForbidden:
+		return connect.CodePermissionDenied
+	case http.StatusNotFound:
+		return connect.CodeNotFound
+	case http.StatusConflict:
+		return connect.CodeAlreadyExists
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return connect.CodeResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return connect.CodeUnavailable
+	}
+	return connect.CodeInternal
+}
+
+func protoTime(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func (s launcherService) GetVersion(ctx context.Context, req *connect.Request[launcherv1.GetVersionRequest]) (*connect.Response[launcherv1.GetVersionResponse], error) {
+	mustUpdate := currentBlockedVersions().mustUpdate(req.Msg.LauncherVersion, req.Msg.GameVersion)
+	if mustUpdate {
+		s.l.logError("Клиент с заблокированной версией: лаунчер=%s, игра=%s", req.Msg.LauncherVersion, req.Msg.GameVersion)
+	}
+
+	version := currentVersion(mustUpdate)
+	return connect.NewResponse(&launcherv1.GetVersionResponse{
+		LauncherVersion: version.LauncherVersion,
+		GameVersion:     version.GameVersion,
+		Maintenance:     version.Maintenance,
+		LauncherBuild:   int32(version.LauncherBuild),
+		GameBuild:       int32(version.GameBuild),
+		BlockedVersions: &launcherv1.BlockedVersions{
+			Launcher: version.BlockedVersions.Launcher,
+			Game:     version.BlockedVersions.Game,
+		},
+		MustUpdate: version.MustUpdate,
+	}), nil
+}
+
+func (s launcherService) ListNews(ctx context.Context, req *connect.Request[launcherv1.ListNewsRequest]) (*connect.Response[launcherv1.ListNewsResponse], error) {
+	r := grpcRequest(ctx)
+	news, err := newsCache.Get()
+	if err != nil {
+		s.l.logError("Ошибка загрузки новостей: %v", err)
+		return nil, grpcError(r, &modError{http.StatusInternalServerError, ErrCodeNewsLoad, []interface{}{err}})
+	}
+
+	langs := requestLanguages(r)
+	if lang := normalizeLanguage(req.Msg.Lang); lang != "" {
+		langs = append([]string{lang}, langs...)
+	}
+	response := &launcherv1.ListNewsResponse{}
+	for _, item := range playerNews(news, langs, req.Msg.Html) {
+		response.News = append(response.News, &launcherv1.NewsItem{
+			Id:           int32(item.ID),
+			Title:        item.Title,
+			Content:      item.Content,
+			Image:        item.Image,
+			Date:         item.Date,
+			Lang:         item.Language,
+			RenderedHtml: item.RenderedHTML,
+			PublishAt:    protoTime(item.PublishAt),
+			ExpiresAt:    protoTime(item.ExpiresAt),
+		})
+	}
+	return connect.NewResponse(response), nil
+}
+
+func protoChunks(chunks []ChunkRef) []*launcherv1.Chunk {
+	result := make([]*launcherv1.Chunk, len(chunks))
+	for i, chunk := range chunks {
+		result[i] = &launcherv1.Chunk{Hash: chunk.Hash, Offset: chunk.Offset, Size: int32(chunk.Size)}
+	}
+	return result
+}
+
+func protoManifest(manifest *ChunkManifest, chunks []ChunkRef) *launcherv1.Manifest {
+	return &launcherv1.Manifest{
+		Artifact:  manifest.Artifact,
+		Filename:  manifest.Filename,
+		Version:   manifest.Version,
+		Size:      manifest.Size,
+		Hash:      manifest.Hash,
+		Chunks:    protoChunks(chunks),
+		CreatedAt: timestamppb.New(manifest.CreatedAt),
+	}
+}
+
+func (s launcherService) GetManifest(ctx context.Context, req *connect.Request[launcherv1.GetManifestRequest]) (*connect.Response[launcherv1.GetManifestResponse], error) {
+	r := grpcRequest(ctx)
+	manifest, apiErr := s.l.artifactManifest(r, req.Msg.Artifact)
+	if apiErr != nil {
+		return nil, grpcError(r, apiErr)
+	}
+
+	s.l.logSuccess("Отправлен манифест %s: %d чанков", req.Msg.Artifact, len(manifest.Chunks))
+	return connect.NewResponse(&launcherv1.GetManifestResponse{Manifest: protoManifest(manifest, manifest.Chunks)}), nil
+}
+
+func (s launcherService) DiffManifest(ctx context.Context, req *connect.Request[launcherv1.DiffManifestRequest], stream *connect.ServerStream[launcherv1.DiffManifestResponse]) error {
+	r := grpcRequest(ctx)
+	manifest, apiErr := s.l.artifactManifest(r, req.Msg.Artifact)
+	if apiErr != nil {
+		return grpcError(r, apiErr)
+	}
+
+	missing := missingChunks(manifest, req.Msg.Have)
+	var missingBytes int64
+	for _, chunk := range missing {
+		missingBytes += int64(chunk.Size)
+	}
+	if err := stream.Send(&launcherv1.DiffManifestResponse{Event: &launcherv1.DiffManifestResponse_Manifest{Manifest: &launcherv1.ManifestSummary{
+		Manifest:      protoManifest(manifest, nil),
+		MissingChunks: int32(len(missing)),
+		MissingBytes:  missingBytes,
+	}}}); err != nil {
+		return err
+	}
+	for batch := range slices.Chunk(missing, grpcChunkBatch) {
+		if err := stream.Send(&launcherv1.DiffManifestResponse{Event: &launcherv1.DiffManifestResponse_Missing{Missing: &launcherv1.ChunkBatch{
+			Chunks: protoChunks(batch),
+		}}}); err != nil {
+			return err
+		}
+	}
+
+	s.l.logSuccess("Отправлена разница манифеста %s: недостает %d из %d чанков (%d bytes)",
+		req.Msg.Artifact, len(missing), len(manifest.Chunks), missingBytes)
+	return nil
+}
+
+func protoAccountToken(token AccountTokenResponse) *launcherv1.AccountToken {
+	info := token.Account
+	account := &launcherv1.AccountInfo{
+		Id:                  info.ID,
+		Username:            info.Username,
+		Email:               info.Email,
+		EmailVerified:       info.EmailVerified,
+		CreatedAt:           timestamppb.New(info.CreatedAt),
+		TotpEnabled:         info.TOTPEnabled,
+		HasPassword:         info.HasPassword,
+		DeletionScheduledAt: protoTime(info.DeletionScheduledAt),
+	}
+	for _, identity := range info.Identities {
+		account.Identities = append(account.Identities, &launcherv1.AccountIdentity{
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Name:     identity.Name,
+			LinkedAt: timestamppb.New(identity.LinkedAt),
+		})
+	}
+	return &launcherv1.AccountToken{
+		Token:            token.Token,
+		ExpiresAt:        timestamppb.New(token.ExpiresAt),
+		RefreshToken:     token.RefreshToken,
+		RefreshExpiresAt: timestamppb.New(token.RefreshExpiresAt),
+		SessionId:        token.SessionID,
+		Account:          account,
+	}
+}
+
+func tokenResponse(r *http.Request, token AccountTokenResponse, apiErr *modError) (*connect.Response[launcherv1.AccountToken], error) {
+	if apiErr != nil {
+		return nil, grpcError(r, apiErr)
+	}
+	return connect.NewResponse(protoAccountToken(token)), nil
+}
+
+func (s authService) Register(ctx context.Context, req *connect.Request[launcherv1.RegisterRequest]) (*connect.Response[launcherv1.AccountToken], error) {
+	r := grpcRequest(ctx)
+	// Токен CAPTCHA приходит в метаданных X-Captcha-Token, как в REST
+	if apiErr := s.l.checkEndpointCaptcha(r, CaptchaRegister); apiErr != nil {
+		return nil, grpcError(r, apiErr)
+	}
+	token, apiErr := s.l.registerAccount(r, AccountCredentials{
+		Username: req.Msg.Username,
+		Password: req.Msg.Password,
+		Email:    req.Msg.Email,
+	})
+	return tokenResponse(r, token, apiErr)
+}
+
+func (s authService) Login(ctx context.Context, req *connect.Request[launcherv1.LoginRequest]) (*connect.Response[launcherv1.AccountToken], error) {
+	r := grpcRequest(ctx)
+	token, apiErr := s.l.loginAccount(r, AccountCredentials{
+		Username:     req.Msg.Username,
+		Password:     req.Msg.Password,
+		TOTPCode:     req.Msg.TotpCode,
+		CaptchaToken: req.Msg.CaptchaToken,
+	})
+	return tokenResponse(r, token, apiErr)
+}
+
+func (s authService) Refresh(ctx context.Context, req *connect.Request[launcherv1.RefreshRequest]) (*connect.Response[launcherv1.AccountToken], error) {
+	r := grpcRequest(ctx)
+	token, apiErr := s.l.refreshAccountSession(r, req.Msg.RefreshToken)
+	return tokenResponse(r, token, apiErr)
+}
+
+func (s authService) Logout(ctx context.Context, req *connect.Request[launcherv1.LogoutRequest]) (*connect.Response[launcherv1.LogoutResponse], error) {
+	r := grpcRequest(ctx)
+	if apiErr := s.l.logoutAccount(r); apiErr != nil {
+		return nil, grpcError(r, apiErr)
+	}
+	return connect.NewResponse(&launcherv1.LogoutResponse{}), nil
+}
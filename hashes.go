@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Как часто писать в лог ход подсчета хэшей
+const hashProgressInterval = 10 * time.Second
+
+// Ход подсчета хэшей каталога клиентов и каталога игры
+type HashIndexStatus struct {
+	Running bool `json:"running"`
+	Workers int  `json:"workers"`
+	Files   int  `json:"files"`
+	Done    int  `json:"done"`
+	// Посчитано заново; остальные файлы не менялись и взяты из индекса
+	Hashed     int        `json:"hashed"`
+	Failed     int        `json:"failed"`
+	Bytes      int64      `json:"bytes"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type HashIndexer struct {
+	mu     sync.Mutex
+	status HashIndexStatus
+}
+
+var hashIndexer = &HashIndexer{}
+
+func (h *HashIndexer) Status() HashIndexStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// Начало прохода; false — предыдущий еще не закончился
+func (h *HashIndexer) begin(workers, files int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.status.Running {
+		return false
+	}
+	now := time.Now().UTC()
+	h.status = HashIndexStatus{Running: true, Workers: workers, Files: files, StartedAt: &now}
+	return true
+}
+
+func (h *HashIndexer) record(size int64, computed bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.status.Done++
+	switch {
+	case err != nil:
+		h.status.Failed++
+	case computed:
+		h.status.Hashed++
+		h.status.Bytes += size
+	}
+}
+
+func (h *HashIndexer) finish() HashIndexStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	h.status.Running, h.status.FinishedAt = false, &now
+	return h.status
+}
+
+// Файлы, хэши которых нужны лаунчерам: сборки из каталога клиентов и
+// файлы каталога игры (архивы, /api/verify)
+func hashTargets(cfg *Config) ([]string, error) {
+	var targets []string
+	entries, err := os.ReadDir(cfg.ClientsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			targets = append(targets, filepath.Join(cfg.ClientsDir, entry.Name()))
+		}
+	}
+
+	if cfg.GameDir != "" {
+		game, _, err := scanGameDir(cfg.GameDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range game {
+			targets = append(targets, entry.Path)
+		}
+	}
+	return targets, nil
+}
+
+// Подсчет хэшей всех файлов пулом из HASH_WORKERS горутин. Неизменившиеся
+// файлы берутся из сохраненного индекса; с force хэши считаются заново.
+// Возвращает сам подсчет или false, если предыдущий еще идет.
+func (l *Logger) beginFileHashes(force bool) (func(), bool) {
+	cfg := currentConfig()
+	targets, err := hashTargets(cfg)
+	if err != nil {
+		l.logError("Ошибка поиска файлов для подсчета хэшей: %v", err)
+	}
+	workers := cfg.HashWorkers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	if !hashIndexer.begin(workers, len(targets)) {
+		return nil, false
+	}
+	return func() { l.indexFileHashes(targets, workers, force) }, true
+}
+
+func (l *Logger) indexFileHashes(targets []string, workers int, force bool) {
+	if force {
+		clientIndex.Forget(targets)
+	}
+	l.Printf("#️⃣ Подсчет хэшей: %d файлов, %d потоков", len(targets), workers)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				info, err := os.Stat(path)
+				var computed bool
+				if err == nil {
+					_, computed, err = clientIndex.lookup(path, info)
+				}
+				if err != nil {
+					l.logError("Ошибка вычисления хэша файла %s: %v", path, err)
+					hashIndexer.record(0, false, err)
+					continue
+				}
+				hashIndexer.record(info.Size(), computed, nil)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(hashProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				status := hashIndexer.Status()
+				l.Printf("#️⃣ Подсчет хэшей: %d/%d файлов, посчитано %d (%d bytes)", status.Done, status.Files, status.Hashed, status.Bytes)
+			}
+		}
+	}()
+
+	for _, path := range targets {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+	close(done)
+
+	clientIndex.Prune()
+	if err := clientIndex.Save(); err != nil {
+		l.logError("Ошибка сохранения индекса хэшей: %v", err)
+	}
+	status := hashIndexer.finish()
+	l.logSuccess("Хэши посчитаны за %v: %d файлов, заново %d (%d bytes), ошибок %d",
+		status.FinishedAt.Sub(*status.StartedAt).Round(time.Millisecond), status.Files, status.Hashed, status.Bytes, status.Failed)
+}
+
+// Ход последнего подсчета хэшей
+func (l *Logger) adminHashesStatusHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "#️⃣", "/admin/api/hashes", func() {
+		json.NewEncoder(w).Encode(hashIndexer.Status())
+	})
+}
+
+// Пересчет хэшей в фоне, например после замены файлов в обход загрузки
+// с сохранением времени изменения. Ход — в GET /admin/api/hashes.
+func (l *Logger) adminRecomputeHashesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "#️⃣", "/admin/api/hashes/recompute", func() {
+		run, ok := l.beginFileHashes(true)
+		if !ok {
+			writeError(w, r, http.StatusConflict, ErrCodeHashingInProgress)
+			return
+		}
+		go run()
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(hashIndexer.Status())
+		l.logSuccess("Запущен пересчет хэшей")
+	})
+}
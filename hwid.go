@@ -0,0 +1,466 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxHWIDComponents = 8
+	// Сколько разных отпечатков помнить у аккаунта
+	maxAccountHWIDs = 10
+)
+
+var (
+	hwidComponentPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+	// Лаунчер передает не серийные номера, а их хэши
+	hwidValuePattern = regexp.MustCompile(`^[0-9a-f]{8,128}$`)
+)
+
+// Отпечаток железа: компонент (cpu, board, disk, mac) → хэш его серийного
+// номера. Заголовок X-Client-HWID: cpu=<hex>;board=<hex>;disk=<hex>
+type HWID map[string]string
+
+// Отпечаток, с которого входили в аккаунт
+type HWIDLink struct {
+	AccountID  string    `json:"account_id"`
+	Components HWID      `json:"components"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// Бан железа. Совпадение нечеткое (см. HWID.matches), так что замена
+// одного диска или сетевой карты бан не снимает.
+type HWIDBan struct {
+	ID         string `json:"id"`
+	Components HWID   `json:"components"`
+	// Аккаунт, за которым железо было замечено при бане
+	Account   string     `json:"account,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Actor     string     `json:"actor"`
+	ActorKey  string     `json:"actor_key"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (b HWIDBan) active(now time.Time) bool {
+	return b.ExpiresAt == nil || now.Before(*b.ExpiresAt)
+}
+
+// Бан железа вручную: отпечаток в формате заголовка X-Client-HWID
+type HWIDBanRequest struct {
+	PlayerListRequest
+	Fingerprint string `json:"fingerprint"`
+}
+
+type HWIDBansResponse struct {
+	Bans []HWIDBan `json:"bans"`
+}
+
+// Другой аккаунт с тем же железом
+type HWIDRelatedAccount struct {
+	Username string `json:"username"`
+	// Сколько компонентов совпало в лучшей паре отпечатков
+	Matched  int       `json:"matched"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Железо аккаунта для модератора: /admin/api/hwid/accounts/{username}
+type HWIDAccountResponse struct {
+	Username     string               `json:"username"`
+	Fingerprints []HWIDLink           `json:"fingerprints"`
+	Related      []HWIDRelatedAccount `json:"related"`
+	Bans         []HWIDBan            `json:"bans"`
+}
+
+type hwidData struct {
+	Links []HWIDLink `json:"links"`
+	Bans  []HWIDBan  `json:"bans"`
+}
+
+// Отпечатки аккаунтов и баны железа в DATA_DIR/hwids.json
+type HWIDStore struct {
+	mu   sync.Mutex
+	data hwidData
+}
+
+var hwids = &HWIDStore{}
+
+func hwidsFile() string {
+	return filepath.Join(currentConfig().DataDir, "hwids.json")
+}
+
+func (s *HWIDStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(hwidsFile(), &s.data)
+}
+
+// Изменение данных под блокировкой, как в jsonListStore.update
+func (s *HWIDStore) update(fn func(data hwidData) (hwidData, *modError)) (*modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, apiErr := fn(hwidData{Links: slices.Clone(s.data.Links), Bans: slices.Clone(s.data.Bans)})
+	if apiErr != nil {
+		return apiErr, nil
+	}
+	if err := saveJSONFile(hwidsFile(), next); err != nil {
+		return nil, err
+	}
+	s.data = next
+	return nil, nil
+}
+
+// Отпечатки аккаунта от последнего входа к первому
+func (s *HWIDStore) Links(accountID string) []HWIDLink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []HWIDLink{}
+	for _, link := range s.data.Links {
+		if link.AccountID == accountID {
+			result = append(result, link)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastSeen.After(result[j].LastSeen) })
+	return result
+}
+
+// Аккаунты, входившие с похожим железом, кроме самого аккаунта
+func (s *HWIDStore) Related(accountID string, threshold int) map[string]HWIDRelatedAccount {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	related := make(map[string]HWIDRelatedAccount)
+	for _, own := range s.data.Links {
+		if own.AccountID != accountID {
+			continue
+		}
+		for _, other := range s.data.Links {
+			if other.AccountID == accountID || !own.Components.matches(other.Components, threshold) {
+				continue
+			}
+			current := related[other.AccountID]
+			current.Matched = max(current.Matched, own.Components.common(other.Components))
+			if other.LastSeen.After(current.LastSeen) {
+				current.LastSeen = other.LastSeen
+			}
+			related[other.AccountID] = current
+		}
+	}
+	return related
+}
+
+// Действующий бан, под который попадает отпечаток
+func (s *HWIDStore) Banned(hwid HWID, threshold int, now time.Time) (HWIDBan, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ban := range s.data.Bans {
+		if ban.active(now) && ban.Components.matches(hwid, threshold) {
+			return ban, true
+		}
+	}
+	return HWIDBan{}, false
+}
+
+// Баны, включая истекшие, если просили, от новых к старым
+func (s *HWIDStore) Bans(includeExpired bool, now time.Time) []HWIDBan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []HWIDBan{}
+	for i := len(s.data.Bans) - 1; i >= 0; i-- {
+		if includeExpired || s.data.Bans[i].active(now) {
+			result = append(result, s.data.Bans[i])
+		}
+	}
+	return result
+}
+
+// Запоминает вход с отпечатка; у аккаунта остаются последние
+// maxAccountHWIDs отпечатков
+func (s *HWIDStore) Record(accountID string, hwid HWID, now time.Time) error {
+	_, err := s.update(func(data hwidData) (hwidData, *modError) {
+		i := slices.IndexFunc(data.Links, func(link HWIDLink) bool {
+			return link.AccountID == accountID && maps.Equal(link.Components, hwid)
+		})
+		if i >= 0 {
+			data.Links[i].LastSeen = now
+			return data, nil
+		}
+		data.Links = append(data.Links, HWIDLink{AccountID: accountID, Components: hwid, FirstSeen: now, LastSeen: now})
+
+		var own []int
+		for j, link := range data.Links {
+			if link.AccountID == accountID {
+				own = append(own, j)
+			}
+		}
+		if len(own) > maxAccountHWIDs {
+			sort.Slice(own, func(a, b int) bool { return data.Links[own[a]].LastSeen.Before(data.Links[own[b]].LastSeen) })
+			oldest := own[0]
+			data.Links = slices.Delete(data.Links, oldest, oldest+1)
+		}
+		return data, nil
+	})
+	return err
+}
+
+// Новые баны железа
+func (s *HWIDStore) Ban(bans []HWIDBan) error {
+	_, err := s.update(func(data hwidData) (hwidData, *modError) {
+		data.Bans = append(data.Bans, bans...)
+		return data, nil
+	})
+	return err
+}
+
+func (s *HWIDStore) Unban(id string) (HWIDBan, bool, error) {
+	var removed HWIDBan
+	apiErr, err := s.update(func(data hwidData) (hwidData, *modError) {
+		i := slices.IndexFunc(data.Bans, func(b HWIDBan) bool { return b.ID == id })
+		if i < 0 {
+			return data, &modError{http.StatusNotFound, ErrCodeHWIDBanNotFound, []interface{}{id}}
+		}
+		removed = data.Bans[i]
+		data.Bans = slices.Delete(data.Bans, i, i+1)
+		return data, nil
+	})
+	return removed, apiErr == nil, err
+}
+
+// Удаление отпечатков аккаунта вместе с ним; баны железа остаются
+func (s *HWIDStore) Forget(accountID string) error {
+	_, err := s.update(func(data hwidData) (hwidData, *modError) {
+		data.Links = slices.DeleteFunc(data.Links, func(link HWIDLink) bool { return link.AccountID == accountID })
+		return data, nil
+	})
+	return err
+}
+
+// Разбор отпечатка "cpu=<hex>;board=<hex>"; false — формат неверный
+func parseHWID(value string) (HWID, bool) {
+	hwid := make(HWID)
+	for _, part := range strings.Split(value, ";") {
+		name, hash, ok := strings.Cut(strings.TrimSpace(part), "=")
+		hash = strings.ToLower(hash)
+		if !ok || !hwidComponentPattern.MatchString(name) || !hwidValuePattern.MatchString(hash) {
+			return nil, false
+		}
+		if _, dup := hwid[name]; dup {
+			return nil, false
+		}
+		hwid[name] = hash
+	}
+	return hwid, len(hwid) <= maxHWIDComponents
+}
+
+// Число одинаковых компонентов двух отпечатков
+func (h HWID) common(other HWID) int {
+	count := 0
+	for name, hash := range h {
+		if other[name] == hash {
+			count++
+		}
+	}
+	return count
+}
+
+// Нечеткое совпадение: одинаковых компонентов не меньше
+// HWID_MATCH_THRESHOLD, а у коротких отпечатков — всех компонентов
+// меньшего из них
+func (h HWID) matches(other HWID, threshold int) bool {
+	need := min(threshold, len(h), len(other))
+	return need > 0 && h.common(other) >= need
+}
+
+// Отпечаток из запроса лаунчера; nil без заголовка
+func requestHWID(r *http.Request) (HWID, *modError) {
+	value := r.Header.Get("X-Client-HWID")
+	if value == "" {
+		if currentConfig().HWIDRequired {
+			return nil, &modError{http.StatusBadRequest, ErrCodeHWIDRequired, nil}
+		}
+		return nil, nil
+	}
+	hwid, ok := parseHWID(value)
+	if !ok {
+		return nil, &modError{http.StatusBadRequest, ErrCodeInvalidRequest, nil}
+	}
+	return hwid, nil
+}
+
+// Проверка железа перед входом или регистрацией; username — для журнала
+func (l *Logger) checkHWID(r *http.Request, username string) (HWID, *modError) {
+	hwid, apiErr := requestHWID(r)
+	if apiErr != nil || hwid == nil {
+		return nil, apiErr
+	}
+	ban, banned := hwids.Banned(hwid, currentConfig().HWIDMatchThreshold, time.Now())
+	if !banned {
+		return hwid, nil
+	}
+	l.logWarn("Вход %s с забаненного железа (бан %s) с %s", username, ban.ID, getClientIP(r))
+	reason := ban.Reason
+	if reason == "" {
+		reason = ban.ID
+	}
+	return nil, &modError{http.StatusForbidden, ErrCodeHWIDBanned, []interface{}{reason}}
+}
+
+// Бан всех отпечатков аккаунта. Сессии аккаунтов с тем же железом
+// завершаются, чтобы твинки не оставались в игре по старым токенам.
+func (l *Logger) banAccountHWIDs(account Account, entry PlayerListEntry) (int, error) {
+	links := hwids.Links(account.ID)
+	if len(links) == 0 {
+		return 0, nil
+	}
+	bans := make([]HWIDBan, 0, len(links))
+	for _, link := range links {
+		bans = append(bans, HWIDBan{
+			ID:         randomID(8),
+			Components: link.Components,
+			Account:    account.Username,
+			Reason:     entry.Reason,
+			ExpiresAt:  entry.ExpiresAt,
+			Actor:      entry.Actor,
+			ActorKey:   entry.ActorKey,
+			CreatedAt:  entry.CreatedAt,
+		})
+	}
+	if err := hwids.Ban(bans); err != nil {
+		return 0, err
+	}
+	l.revokeHWIDSessions(bans)
+	return len(bans), nil
+}
+
+// Завершение сессий всех аккаунтов, входивших с забаненного железа
+func (l *Logger) revokeHWIDSessions(bans []HWIDBan) {
+	threshold, now := currentConfig().HWIDMatchThreshold, time.Now().UTC()
+	revoked := make(map[string]bool)
+	for _, id := range accounts.IDs() {
+		if revoked[id] {
+			continue
+		}
+		for _, link := range hwids.Links(id) {
+			if slices.ContainsFunc(bans, func(b HWIDBan) bool { return b.Components.matches(link.Components, threshold) }) {
+				revoked[id] = true
+				break
+			}
+		}
+	}
+	for id := range revoked {
+		if _, err := accountSessions.Revoke(id, func(AccountSession) bool { return true }, now); err != nil {
+			l.logError("Ошибка сохранения сессий аккаунтов: %v", err)
+		}
+	}
+}
+
+// Баны железа: /admin/api/hwid/bans?include_expired=true
+func (l *Logger) adminHWIDBansHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🖥️", "/admin/api/hwid/bans", func() {
+		includeExpired := r.URL.Query().Get("include_expired") == "true"
+		json.NewEncoder(w).Encode(HWIDBansResponse{Bans: hwids.Bans(includeExpired, time.Now())})
+	})
+}
+
+// Бан железа по отпечатку, например из журнала античита; срок и причина
+// как у PUT /admin/api/bans/{player}
+func (l *Logger) adminHWIDBanHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🖥️", "/admin/api/hwid/bans", func() {
+		var req HWIDBanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		hwid, valid := parseHWID(req.Fingerprint)
+		entry, ok := req.entry(r, "", time.Now().UTC())
+		if !valid || !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		ban := HWIDBan{
+			ID:         randomID(8),
+			Components: hwid,
+			Reason:     entry.Reason,
+			ExpiresAt:  entry.ExpiresAt,
+			Actor:      entry.Actor,
+			ActorKey:   entry.ActorKey,
+			CreatedAt:  entry.CreatedAt,
+		}
+		if err := hwids.Ban([]HWIDBan{ban}); err != nil {
+			l.logError("Ошибка сохранения банов железа: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		l.revokeHWIDSessions([]HWIDBan{ban})
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ban)
+		l.logSuccess("Забанено железо %s (%s): %s", ban.ID, ban.Actor, ban.Reason)
+	})
+}
+
+func (l *Logger) adminHWIDUnbanHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🖥️", "/admin/api/hwid/bans/{id}", func() {
+		id := r.PathValue("id")
+		ban, found, err := hwids.Unban(id)
+		if err != nil {
+			l.logError("Ошибка сохранения банов железа: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeHWIDBanNotFound, id)
+			return
+		}
+		actor, _ := adminActor(r, r.URL.Query().Get("actor"))
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Снят бан железа %s (%s), аккаунт %s", ban.ID, actor, ban.Account)
+	})
+}
+
+// Железо аккаунта и твинки на нем: /admin/api/hwid/accounts/{username}
+func (l *Logger) adminHWIDAccountHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🖥️", "/admin/api/hwid/accounts/{username}", func() {
+		username := r.PathValue("username")
+		account, ok := accounts.ByUsername(username)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, username)
+			return
+		}
+
+		threshold, now := currentConfig().HWIDMatchThreshold, time.Now()
+		response := HWIDAccountResponse{
+			Username:     account.Username,
+			Fingerprints: hwids.Links(account.ID),
+			Related:      []HWIDRelatedAccount{},
+			Bans:         []HWIDBan{},
+		}
+		for id, related := range hwids.Related(account.ID, threshold) {
+			if other, ok := accounts.ByID(id); ok {
+				related.Username = other.Username
+				response.Related = append(response.Related, related)
+			}
+		}
+		sort.Slice(response.Related, func(i, j int) bool { return response.Related[i].LastSeen.After(response.Related[j].LastSeen) })
+		for _, ban := range hwids.Bans(false, now) {
+			if slices.ContainsFunc(response.Fingerprints, func(link HWIDLink) bool { return ban.Components.matches(link.Components, threshold) }) {
+				response.Bans = append(response.Bans, ban)
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Перевод новости на конкретный язык
+type NewsTranslation struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// Каталог сообщений, которые сервер отдает клиентам
+var messages = map[string]map[string]string{
+	"ru": {
+		"news_load_error": "Ошибка загрузки новостей: %v",
+		"file_not_found":  "Файл не найден",
+		"file_open_error": "Ошибка открытия файла",
+		"file_stat_error": "Ошибка получения информации о файле",
+
+		"unauthorized":        "Требуется авторизация администратора",
+		"invalid_request":     "Некорректный запрос",
+		"invalid_status":      "Недопустимый статус новости",
+		"publish_at_required": "Для отложенной публикации нужно указать publish_at",
+		"news_not_found":      "Новость не найдена",
+		"news_save_error":     "Ошибка сохранения новостей",
+		"route_not_found":     "Маршрут не найден",
+		"maintenance":         "Ведутся технические работы, попробуйте позже",
+		"config_invalid":      "Конфигурация не применена: %v",
+		"unknown_artifact":    "Неизвестный артефакт: %s",
+		"upload_failed":       "Ошибка загрузки файла",
+		"disk_space_low":      "Недостаточно места на диске: нужно %d bytes, свободно %d bytes",
+		"hash_mismatch":       "Хэш загруженного файла не совпадает с X-File-Hash",
+		"forbidden":           "У ключа нет права %s",
+		"invalid_scope":       "Неизвестное право доступа: %s",
+		"invalid_role":        "Неизвестная роль: %s",
+		"key_not_found":       "Ключ не найден",
+		"keys_save_error":     "Ошибка сохранения ключей",
+		"internal_error":      "Внутренняя ошибка сервера",
+		"timeout":             "Сервер не успел обработать запрос",
+		"chunk_not_found":     "Чанк не найден",
+		"manifest_not_found":  "Манифест чанков еще не построен",
+		"signature_not_found": "Подпись для текущей сборки не загружена",
+		"invalid_signature":   "Подпись не проходит проверку открытым ключом",
+
+		"current_version_blocked":        "Нельзя заблокировать текущую версию %s",
+		"telemetry_disabled":             "Сбор телеметрии отключен",
+		"invalid_telemetry":              "Телеметрия не соответствует схеме: %v",
+		"payload_too_large":              "Слишком большой запрос",
+		"runtime_not_found":              "Рантайм для %s/%s не загружен",
+		"mod_not_found":                  "Мод %s не найден",
+		"mod_version_not_found":          "Версия мода %s %s не найдена",
+		"modpack_not_found":              "Профиль сборки %s не найден",
+		"resource_pack_not_found":        "Ресурспак %s не найден",
+		"player_not_listed":              "Игрока %s нет в списке",
+		"eula_not_found":                 "Правила сервера не опубликованы",
+		"eula_version_mismatch":          "Правила обновились, текущая версия %s",
+		"session_not_found":              "Игровая сессия не найдена или истекла",
+		"account_unauthorized":           "Требуется вход в аккаунт",
+		"invalid_credentials":            "Неверное имя пользователя или пароль",
+		"account_exists":                 "Имя %s уже занято",
+		"weak_password":                  "Пароль должен быть не короче %d символов",
+		"registration_disabled":          "Регистрация закрыта",
+		"sync_key_not_found":             "Ключ %s не сохранен",
+		"sync_conflict":                  "Данные изменились на другом устройстве",
+		"precondition_required":          "Для изменения нужен заголовок If-Match",
+		"quota_exceeded":                 "Превышена квота хранилища",
+		"save_not_found":                 "Сохранение не найдено",
+		"upload_not_found":               "Загрузка не найдена или истекла",
+		"upload_offset_mismatch":         "Неверное смещение, принято байт: %d",
+		"invalid_image":                  "Поддерживаются только изображения PNG и JPEG",
+		"too_many_pending":               "На модерации уже %d скриншотов, дождитесь проверки",
+		"screenshot_not_found":           "Скриншот не найден",
+		"promo_code_invalid":             "Неверный промокод",
+		"promo_code_expired":             "Срок действия промокода истек",
+		"promo_code_exhausted":           "Промокод уже использован",
+		"promo_code_redeemed":            "Вы уже активировали этот промокод",
+		"promo_code_exists":              "Промокод %s уже существует",
+		"account_not_found":              "Аккаунт %s не найден",
+		"entitlement_required":           "Для скачивания нужно право %s",
+		"entitlement_not_granted":        "Право %s не выдавалось",
+		"too_many_attempts":              "Слишком много попыток, повторите через %d с",
+		"totp_required":                  "Требуется код двухфакторной аутентификации",
+		"invalid_totp_code":              "Неверный код двухфакторной аутентификации",
+		"totp_not_enrolled":              "Двухфакторная аутентификация не подключена",
+		"totp_already_enabled":           "Двухфакторная аутентификация уже включена",
+		"totp_enrollment_required":       "Для доступа нужно подключить двухфакторную аутентификацию",
+		"oauth_provider_not_found":       "Вход через %s недоступен",
+		"oauth_state_invalid":            "Сеанс входа истек, начните вход заново",
+		"oauth_denied":                   "Вход отменен",
+		"oauth_failed":                   "Не удалось получить данные от провайдера входа",
+		"oauth_ticket_invalid":           "Билет входа недействителен или истек",
+		"oauth_identity_linked":          "Этот аккаунт %s уже привязан к другому игроку",
+		"oauth_last_login_method":        "Нельзя отвязать единственный способ входа: сначала задайте пароль",
+		"email_disabled":                 "Отправка писем не настроена",
+		"invalid_email":                  "Некорректный адрес почты",
+		"email_in_use":                   "Эта почта уже привязана к другому аккаунту",
+		"invalid_email_token":            "Ссылка недействительна, устарела или уже использована",
+		"email_verified":                 "Почта подтверждена, можно вернуться в лаунчер",
+		"account_session_not_found":      "Сессия не найдена",
+		"login_locked":                   "Слишком много неудачных попыток входа, повторите через %d с",
+		"captcha_required":               "Подтвердите, что вы не робот",
+		"captcha_invalid":                "Проверка CAPTCHA не пройдена",
+		"captcha_unavailable":            "Сервис CAPTCHA недоступен, повторите позже",
+		"account_deletion_confirm":       "Для подтверждения введите имя аккаунта",
+		"account_deletion_scheduled":     "Удаление аккаунта уже запрошено",
+		"account_deletion_not_scheduled": "Удаление аккаунта не запрашивалось",
+		"ip_forbidden":                   "Доступ с вашего адреса запрещен",
+		"ip_banned":                      "Ваш адрес временно заблокирован, повторите через %d с",
+		"ip_ban_not_found":               "Адрес %s не заблокирован",
+		"unsupported_media_type":         "Неподдерживаемый тип содержимого: %q",
+		"too_many_parts":                 "Слишком много частей в запросе: не больше %d",
+		"download_limit_per_ip":          "Слишком много одновременных скачиваний с вашего адреса (не больше %d), повторите через %d с",
+		"downloads_busy":                 "Сервер занят скачиваниями (не больше %d одновременно), повторите через %d с",
+		"download_queue_required":        "Сервер занят скачиваниями, встаньте в очередь",
+		"download_queue_full":            "Очередь на скачивание заполнена, повторите через %d с",
+		"download_queue_disabled":        "Очередь на скачивание отключена",
+		"download_queue_token_not_found": "Место в очереди не найдено или истекло",
+		"unknown_encoding":               "Неизвестное или строящееся сервером сжатие: %q",
+		"encoding_source_mismatch":       "Вариант сделан не из текущей сборки: ожидался X-Source-SHA256 %s",
+		"events_busy":                    "Слишком много подписчиков на события (не больше %d), повторите через %d с",
+		"project_not_found":              "Проект %q не найден",
+		"unknown_channel":                "Неизвестный канал: %q",
+		"webhook_not_found":              "Вебхук %s не найден",
+		"unknown_webhook_event":          "Неизвестное событие вебхука: %q",
+		"motd_not_found":                 "Объявление #%d не найдено",
+		"launch_profile_not_found":       "Нет профиля запуска для версии игры %s",
+		"dependencies_not_found":         "Нет зависимостей для версии игры %s",
+		"invalid_bundle":                 "Некорректный архив зависимостей: %v",
+		"hashing_in_progress":            "Подсчет хэшей уже идет",
+		"download_token_not_found":       "Токен скачивания не найден или истек",
+		"release_not_found":              "Релиз %s не найден",
+		"release_not_draft":              "Релиз %s уже опубликован",
+		"release_incomplete":             "В релиз не загружен артефакт %s",
+		"staging_empty":                  "В тестовом канале нет сборок для продвижения",
+		"staging_modified":               "Сборка %s в тестовом канале изменилась после загрузки",
+		"upload_incomplete":              "Загрузка по частям не завершена, принято байт: %d",
+		"version_not_newer":              "Версия %s %s не новее текущей %s",
+		"changelog_not_found":            "Нет описания изменений версии %s",
+		"hardware_survey_disabled":       "Опрос о железе отключен",
+		"feedback_disabled":              "Прием отзывов отключен",
+		"feedback_not_found":             "Отзыв %s не найден",
+		"unknown_reaction":               "Неизвестная реакция: %q",
+		"profile_not_found":              "Профиль %s не найден",
+		"invalid_display_name":           "Отображаемое имя — не длиннее %d символов, без управляющих символов",
+		"achievement_not_found":          "Достижение %s не найдено",
+		"game_server_api_disabled":       "API игровых серверов выключено: не задан GAME_SERVER_SECRET",
+		"invalid_server_secret":          "Неверный секрет игрового сервера",
+		"leaderboard_not_found":          "Таблица рекордов %s не найдена",
+		"score_not_found":                "Нет результатов игрока %s",
+		"score_out_of_range":             "Результат %d вне допустимого диапазона",
+		"score_too_frequent":             "Результаты можно отправлять не чаще раза в %d с",
+		"score_rejected":                 "Результат отклонен проверкой: %v",
+		"friend_not_found":               "Игрока %s нет среди друзей и заявок",
+		"already_friends":                "Вы уже друзья с %s",
+		"friend_limit":                   "Достигнут лимит друзей и заявок: %d",
+		"message_not_found":              "Сообщение %s не найдено",
+		"not_friends":                    "Писать можно только друзьям, %s нет в списке друзей",
+		"push_disabled":                  "Push-уведомления выключены: не задан FCM_CREDENTIALS_FILE",
+		"device_not_found":               "Устройство не найдено",
+		"cheat_report_not_found":         "Жалоба античита %s не найдена",
+		"cheat_report_reviewed":          "Жалоба античита %s уже рассмотрена: %s",
+		"hwid_banned":                    "Вход с этого компьютера запрещен: %s",
+		"hwid_required":                  "Лаунчер не передал отпечаток компьютера, обновите лаунчер",
+		"hwid_ban_not_found":             "Бан по железу %s не найден",
+		"feature_disabled":               "Функция временно отключена администратором (%s)",
+		"download_quota_exceeded":        "Суточный лимит скачивания (%d МБ) исчерпан, повторите через %d с",
+		"mirror_not_found":               "Зеркало %s не найдено",
+		"invalid_mirror_token":           "Неверный токен зеркала",
+		"invalid_mirror_signature":       "Неверная подпись уведомления основного сервера",
+		"mirror_sync_disabled":           "Сервер не является зеркалом: не задан MIRROR_PRIMARY_URL",
+		"replica_read_only":              "Сервер — реплика %s только для чтения",
+		"server_overloaded":              "Сервер перегружен, повторите через %d с",
+
+		"push_server_online_title": "Сервер снова работает",
+		"push_server_online_body":  "Технические работы завершены, ждем в игре",
+		"push_friend_online_title": "Друг в сети",
+		"push_friend_online_body":  "%s сейчас в сети",
+		"push_release_title":       "Вышла версия %s",
+		"push_release_body":        "Обновите игру в лаунчере",
+	},
+	"en": {
+		"news_load_error": "Failed to load news: %v",
+		"file_not_found":  "File not found",
+		"file_open_error": "Failed to open file",
+		"file_stat_error": "Failed to read file information",
+
+		"unauthorized":        "Administrator authorization required",
+		"invalid_request":     "Invalid request",
+		"invalid_status":      "Invalid news status",
+		"publish_at_required": "publish_at is required for scheduled news",
+		"news_not_found":      "News item not found",
+		"news_save_error":     "Failed to save news",
+		"route_not_found":     "Route not found",
+		"maintenance":         "Maintenance in progress, please try again later",
+		"config_invalid":      "Configuration was not applied: %v",
+		"unknown_artifact":    "Unknown artifact: %s",
+		"upload_failed":       "File upload failed",
+		"disk_space_low":      "Not enough disk space: %d bytes needed, %d bytes free",
+		"hash_mismatch":       "Uploaded file hash does not match X-File-Hash",
+		"forbidden":           "Key lacks the %s scope",
+		"invalid_scope":       "Unknown scope: %s",
+		"invalid_role":        "Unknown role: %s",
+		"key_not_found":       "Key not found",
+		"keys_save_error":     "Failed to save keys",
+		"internal_error":      "Internal server error",
+		"timeout":             "The server timed out processing the request",
+		"chunk_not_found":     "Chunk not found",
+		"manifest_not_found":  "Chunk manifest has not been built yet",
+		"signature_not_found": "No signature has been uploaded for the current build",
+		"invalid_signature":   "Signature does not verify against the public key",
+
+		"current_version_blocked":        "Cannot block the current version %s",
+		"telemetry_disabled":             "Telemetry collection is disabled",
+		"invalid_telemetry":              "Telemetry does not match the schema: %v",
+		"payload_too_large":              "Request body is too large",
+		"runtime_not_found":              "No runtime uploaded for %s/%s",
+		"mod_not_found":                  "Mod %s not found",
+		"mod_version_not_found":          "Mod version %s %s not found",
+		"modpack_not_found":              "Modpack %s not found",
+		"resource_pack_not_found":        "Resource pack %s not found",
+		"player_not_listed":              "Player %s is not on the list",
+		"eula_not_found":                 "Server rules are not published",
+		"eula_version_mismatch":          "The rules have changed, current version is %s",
+		"session_not_found":              "Play session not found or expired",
+		"account_unauthorized":           "Account login required",
+		"invalid_credentials":            "Invalid username or password",
+		"account_exists":                 "Username %s is already taken",
+		"weak_password":                  "Password must be at least %d characters long",
+		"registration_disabled":          "Registration is closed",
+		"sync_key_not_found":             "Key %s is not stored",
+		"sync_conflict":                  "Data was changed on another device",
+		"precondition_required":          "An If-Match header is required to modify this",
+		"quota_exceeded":                 "Storage quota exceeded",
+		"save_not_found":                 "Save not found",
+		"upload_not_found":               "Upload not found or expired",
+		"upload_offset_mismatch":         "Offset mismatch, bytes received: %d",
+		"invalid_image":                  "Only PNG and JPEG images are supported",
+		"too_many_pending":               "%d screenshots are already awaiting moderation",
+		"screenshot_not_found":           "Screenshot not found",
+		"promo_code_invalid":             "Invalid promo code",
+		"promo_code_expired":             "This promo code has expired",
+		"promo_code_exhausted":           "This promo code has already been used",
+		"promo_code_redeemed":            "You have already redeemed this promo code",
+		"promo_code_exists":              "Promo code %s already exists",
+		"account_not_found":              "Account %s not found",
+		"entitlement_required":           "Downloading requires the %s entitlement",
+		"entitlement_not_granted":        "Entitlement %s was not granted",
+		"too_many_attempts":              "Too many attempts, try again in %d s",
+		"totp_required":                  "Two-factor authentication code required",
+		"invalid_totp_code":              "Invalid two-factor authentication code",
+		"totp_not_enrolled":              "Two-factor authentication is not set up",
+		"totp_already_enabled":           "Two-factor authentication is already enabled",
+		"totp_enrollment_required":       "Two-factor authentication must be set up for access",
+		"oauth_provider_not_found":       "Sign-in with %s is not available",
+		"oauth_state_invalid":            "Sign-in session expired, please start again",
+		"oauth_denied":                   "Sign-in was cancelled",
+		"oauth_failed":                   "Failed to get data from the sign-in provider",
+		"oauth_ticket_invalid":           "Sign-in ticket is invalid or expired",
+		"oauth_identity_linked":          "This %s account is already linked to another player",
+		"oauth_last_login_method":        "Cannot unlink the only sign-in method: set a password first",
+		"email_disabled":                 "Email delivery is not configured",
+		"invalid_email":                  "Invalid email address",
+		"email_in_use":                   "This email is already linked to another account",
+		"invalid_email_token":            "The link is invalid, expired or already used",
+		"email_verified":                 "Email confirmed, you can return to the launcher",
+		"account_session_not_found":      "Session not found",
+		"login_locked":                   "Too many failed sign-in attempts, try again in %d s",
+		"captcha_required":               "Please confirm you are not a robot",
+		"captcha_invalid":                "CAPTCHA check failed",
+		"captcha_unavailable":            "CAPTCHA service is unavailable, try again later",
+		"account_deletion_confirm":       "Enter the account name to confirm",
+		"account_deletion_scheduled":     "Account deletion has already been requested",
+		"account_deletion_not_scheduled": "Account deletion has not been requested",
+		"ip_forbidden":                   "Access from your address is not allowed",
+		"ip_banned":                      "Your address is temporarily blocked, try again in %d s",
+		"ip_ban_not_found":               "Address %s is not blocked",
+		"unsupported_media_type":         "Unsupported content type: %q",
+		"too_many_parts":                 "Too many parts in the request: at most %d",
+		"download_limit_per_ip":          "Too many simultaneous downloads from your address (at most %d), try again in %d s",
+		"downloads_busy":                 "The server is busy with downloads (at most %d at once), try again in %d s",
+		"download_queue_required":        "The server is busy with downloads, join the queue",
+		"download_queue_full":            "The download queue is full, try again in %d s",
+		"download_queue_disabled":        "The download queue is disabled",
+		"download_queue_token_not_found": "Queue place not found or expired",
+		"unknown_encoding":               "Unknown or server-built encoding: %q",
+		"encoding_source_mismatch":       "The variant was not built from the current build: expected X-Source-SHA256 %s",
+		"events_busy":                    "Too many event subscribers (at most %d), try again in %d s",
+		"project_not_found":              "Project %q not found",
+		"unknown_channel":                "Unknown channel: %q",
+		"webhook_not_found":              "Webhook %s not found",
+		"unknown_webhook_event":          "Unknown webhook event: %q",
+		"motd_not_found":                 "Announcement #%d not found",
+		"launch_profile_not_found":       "No launch profile for game version %s",
+		"dependencies_not_found":         "No dependencies for game version %s",
+		"invalid_bundle":                 "Invalid dependency bundle: %v",
+		"hashing_in_progress":            "Hash computation is already running",
+		"download_token_not_found":       "Download token not found or expired",
+		"release_not_found":              "Release %s not found",
+		"release_not_draft":              "Release %s is already published",
+		"release_incomplete":             "Artifact %s has not been uploaded to the release",
+		"staging_empty":                  "The staging channel has no builds to promote",
+		"staging_modified":               "Staging build %s has changed since it was uploaded",
+		"upload_incomplete":              "Chunked upload is not completed, bytes received: %d",
+		"version_not_newer":              "Version %s %s is not newer than the current %s",
+		"changelog_not_found":            "No changelog for version %s",
+		"hardware_survey_disabled":       "Hardware survey is disabled",
+		"feedback_disabled":              "Feedback submission is disabled",
+		"feedback_not_found":             "Feedback %s not found",
+		"unknown_reaction":               "Unknown reaction: %q",
+		"profile_not_found":              "Profile %s not found",
+		"invalid_display_name":           "Display name must be at most %d characters without control characters",
+		"achievement_not_found":          "Achievement %s not found",
+		"game_server_api_disabled":       "Game server API is disabled: GAME_SERVER_SECRET is not set",
+		"invalid_server_secret":          "Invalid game server secret",
+		"leaderboard_not_found":          "Leaderboard %s not found",
+		"score_not_found":                "No scores for player %s",
+		"score_out_of_range":             "Score %d is outside the allowed range",
+		"score_too_frequent":             "Scores can be submitted no more than once every %d seconds",
+		"score_rejected":                 "Score rejected by validation: %v",
+		"friend_not_found":               "%s is not among your friends or friend requests",
+		"already_friends":                "You are already friends with %s",
+		"friend_limit":                   "Friend and request limit reached: %d",
+		"message_not_found":              "Message %s not found",
+		"not_friends":                    "Messages can only be sent to friends, %s is not on your friends list",
+		"push_disabled":                  "Push notifications are disabled: FCM_CREDENTIALS_FILE is not set",
+		"device_not_found":               "Device not found",
+		"cheat_report_not_found":         "Anti-cheat report %s not found",
+		"cheat_report_reviewed":          "Anti-cheat report %s has already been reviewed: %s",
+		"hwid_banned":                    "Logins from this computer are banned: %s",
+		"hwid_required":                  "The launcher did not send a hardware fingerprint, please update the launcher",
+		"hwid_ban_not_found":             "Hardware ban %s not found",
+		"feature_disabled":               "This feature is temporarily disabled by the administrator (%s)",
+		"download_quota_exceeded":        "Daily download quota (%d MB) is used up, try again in %d s",
+		"mirror_not_found":               "Mirror %s not found",
+		"invalid_mirror_token":           "Invalid mirror token",
+		"invalid_mirror_signature":       "Invalid primary server notification signature",
+		"mirror_sync_disabled":           "This server is not a mirror: MIRROR_PRIMARY_URL is not set",
+		"replica_read_only":              "This server is a read-only replica of %s",
+		"server_overloaded":              "The server is overloaded, retry in %d s",
+
+		"push_server_online_title": "Server is back online",
+		"push_server_online_body":  "Maintenance is over, see you in game",
+		"push_friend_online_title": "Friend online",
+		"push_friend_online_body":  "%s is online",
+		"push_release_title":       "Version %s is out",
+		"push_release_body":        "Update the game in the launcher",
+	},
+}
+
+// Список предпочитаемых клиентом языков в порядке убывания приоритета.
+// Параметр ?lang= важнее заголовка Accept-Language, язык по умолчанию
+// всегда добавляется последним.
+func requestLanguages(r *http.Request) []string {
+	var langs []string
+
+	if lang := normalizeLanguage(r.URL.Query().Get("lang")); lang != "" {
+		langs = append(langs, lang)
+	}
+
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang := normalizeLanguage(tag)
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			accepted = append(accepted, weighted{lang, q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+	for _, a := range accepted {
+		langs = append(langs, a.lang)
+	}
+
+	// Для региональных вариантов (en-us) пробуем и базовый язык (en)
+	var expanded []string
+	seen := make(map[string]bool)
+	add := func(lang string) {
+		if !seen[lang] {
+			seen[lang] = true
+			expanded = append(expanded, lang)
+		}
+	}
+	for _, lang := range langs {
+		add(lang)
+		if base, _, found := strings.Cut(lang, "-"); found {
+			add(base)
+		}
+	}
+	add(currentConfig().DefaultLanguage)
+
+	return expanded
+}
+
+func normalizeLanguage(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(tag)), "_", "-")
+}
+
+// Первый язык из списка, для которого есть каталог сообщений
+func messageLanguage(r *http.Request) string {
+	for _, lang := range requestLanguages(r) {
+		if _, ok := messages[lang]; ok {
+			return lang
+		}
+	}
+	return "ru"
+}
+
+// Локализованное сообщение для ответа клиенту
+func translate(r *http.Request, key string, args ...interface{}) string {
+	return translateTo(messageLanguage(r), key, args...)
+}
+
+// То же для известного заранее языка, например для push-уведомлений
+func translateTo(lang, key string, args ...interface{}) string {
+	format, ok := messages[lang][key]
+	if !ok {
+		format = messages["ru"][key]
+	}
+	if format == "" {
+		format = key
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Подстановка перевода новости; если подходящего перевода нет,
+// остается исходный текст
+func localizeNews(news []NewsItem, langs []string) []NewsItem {
+	localized := make([]NewsItem, 0, len(news))
+	for _, item := range news {
+		for _, lang := range langs {
+			if tr, ok := item.Translations[lang]; ok {
+				if tr.Title != "" {
+					item.Title = tr.Title
+				}
+				if tr.Content != "" {
+					item.Content = tr.Content
+				}
+				item.Language = lang
+				break
+			}
+		}
+		if item.Language == "" {
+			item.Language = currentConfig().DefaultLanguage
+		}
+		item.Translations = nil
+		localized = append(localized, item)
+	}
+	return localized
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Каталог изображений новостей (относительно рабочего каталога)
+const imagesDir = "images"
+
+// Имя с хэшем содержимого: abc123def456-banner.jpg. Такой файл никогда
+// не меняется, поэтому кэшируется навсегда.
+var hashedImagePattern = regexp.MustCompile(`^([0-9a-f]{12})-`)
+
+var imageExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
+
+// Сколько ждать внешний кодировщик
+const imageEncodeTimeout = time.Minute
+
+// Формат варианта изображения для согласования по Accept. В порядке
+// предпочтения сервера при равном q. Кодировщиков WebP и AVIF без внешних
+// зависимостей нет, поэтому варианты строят внешние программы из
+// IMAGE_WEBP_COMMAND и IMAGE_AVIF_COMMAND.
+type imageFormat struct {
+	Name        string
+	ContentType string
+	Command     func(cfg *Config) string
+}
+
+var imageFormats = []imageFormat{
+	{Name: "avif", ContentType: "image/avif", Command: func(cfg *Config) string { return cfg.ImageAVIFCommand }},
+	{Name: "webp", ContentType: "image/webp", Command: func(cfg *Config) string { return cfg.ImageWebPCommand }},
+}
+
+// Варианты лежат рядом с изображением скрытыми (.<имя>.webp)
+func imageVariantPath(path string, format imageFormat) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+"."+format.Name)
+}
+
+// Построение варианта внешним кодировщиком. Вариант остается, только если
+// он меньше исходного файла.
+func buildImageVariant(cfg *Config, path string, format imageFormat) (int64, error) {
+	command := strings.Fields(format.Command(cfg))
+	if len(command) == 0 {
+		return 0, nil
+	}
+	source, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	target := imageVariantPath(path, format)
+	tmp := filepath.Join(filepath.Dir(path), "."+randomID(12)+"."+format.Name)
+	defer os.Remove(tmp)
+	for i, arg := range command {
+		command[i] = strings.NewReplacer("{input}", path, "{output}", tmp).Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), imageEncodeTimeout)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput(); err != nil {
+		return 0, fmt.Errorf("%v: %s", err, bytes.TrimSpace(output))
+	}
+
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() >= source.Size() {
+		os.Remove(target)
+		return 0, nil
+	}
+	os.Chmod(tmp, 0644)
+	return info.Size(), os.Rename(tmp, target)
+}
+
+// Варианты загруженного изображения; долгое, поэтому в отдельной горутине
+func (l *Logger) syncImageVariants(cfg *Config, path string) {
+	for _, format := range imageFormats {
+		size, err := buildImageVariant(cfg, path, format)
+		if err != nil {
+			l.logError("Ошибка построения %s-варианта %s: %v", format.Name, filepath.Base(path), err)
+			continue
+		}
+		if size > 0 {
+			l.logSuccess("Подготовлен %s-вариант %s (%d bytes)", format.Name, filepath.Base(path), size)
+		}
+	}
+}
+
+// Вариант, который клиент явно принимает с наибольшим q (image/* не
+// считается: его шлют и клиенты без поддержки WebP). Вариант старше
+// изображения не отдается — изображение заменили.
+func negotiateImageVariant(r *http.Request, path string, source os.FileInfo) (imageFormat, string, os.FileInfo, bool) {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[strings.ToLower(name)] = q
+	}
+
+	var best imageFormat
+	var bestPath string
+	var bestInfo os.FileInfo
+	bestQ := 0.0
+	for _, format := range imageFormats {
+		q := accepted[format.ContentType]
+		if q <= bestQ {
+			continue
+		}
+		variant := imageVariantPath(path, format)
+		if info, err := os.Stat(variant); err == nil && !info.ModTime().Before(source.ModTime()) {
+			best, bestPath, bestInfo, bestQ = format, variant, info, q
+		}
+	}
+	return best, bestPath, bestInfo, bestQ > 0
+}
+
+// Хэши изображений без хэша в имени для ETag. Живут только в памяти и
+// пересчитываются при замене файла: индекс каталога клиентов
+// (file_hashes.json) описывает сборки, изображениям там не место.
+var imageHashes = struct {
+	mu    sync.Mutex
+	files map[string]ClientFile
+}{files: make(map[string]ClientFile)}
+
+func imageHash(path string, info os.FileInfo) (string, error) {
+	imageHashes.mu.Lock()
+	cached, ok := imageHashes.files[path]
+	imageHashes.mu.Unlock()
+	if ok && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+		return cached.SHA256, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	entry := ClientFile{Size: info.Size(), ModTime: info.ModTime(), SHA256: hex.EncodeToString(hash.Sum(nil))}
+	imageHashes.mu.Lock()
+	imageHashes.files[path] = entry
+	imageHashes.mu.Unlock()
+	return entry.SHA256, nil
+}
+
+type ImageUploadResponse struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Изображение новости. Имена с хэшем содержимого отдаются с immutable,
+// остальные — с IMAGE_CACHE_MAX_AGE и ETag, чтобы веб-вью лаунчера и
+// CDN не скачивали баннер заново на каждое открытие. Клиентам с
+// image/avif или image/webp в Accept отдается вариант, если он есть.
+func (l *Logger) imageHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !uploadFilenamePattern.MatchString(name) {
+		writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+		return
+	}
+	path := filepath.Join(imagesDir, name)
+	file, err := os.Open(path)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	suffix := ""
+	if format, variantPath, variantInfo, ok := negotiateImageVariant(r, path, info); ok {
+		variant, err := os.Open(variantPath)
+		if err == nil {
+			defer variant.Close()
+			file, path, info, suffix = variant, variantPath, variantInfo, "-"+format.Name
+			w.Header().Set("Content-Type", format.ContentType)
+		}
+	}
+
+	if match := hashedImagePattern.FindStringSubmatch(name); match != nil {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("ETag", `"`+match[1]+suffix+`"`)
+	} else {
+		maxAge := int(currentConfig().ImageCacheMaxAge.Seconds())
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+		if hash, err := imageHash(path, info); err == nil {
+			w.Header().Set("ETag", `"`+hash+`"`)
+		}
+	}
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// PNG или JPEG по содержимому, а не только по расширению
+func isNewsImage(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := http.DetectContentType(head[:n])
+	return contentType == "image/png" || contentType == "image/jpeg"
+}
+
+// Загрузка изображения для новостей: файл сохраняется под именем с
+// хэшем содержимого, которое затем указывается в поле image новости
+func (l *Logger) adminUploadImageHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "🖼️", "/admin/api/images/{name}", func() {
+		name := r.PathValue("name")
+		if !uploadFilenamePattern.MatchString(name) || hashedImagePattern.MatchString(name) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if !imageExtensions[strings.ToLower(filepath.Ext(name))] {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidImage)
+			return
+		}
+
+		pending := filepath.Join(imagesDir, "."+randomID(12))
+		file, ok := l.receiveUpload(w, r, pending)
+		if !ok {
+			return
+		}
+		defer os.Remove(pending)
+		if !isNewsImage(pending) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidImage)
+			return
+		}
+
+		hashed := file.SHA256[:12] + "-" + name
+		if err := os.Rename(pending, filepath.Join(imagesDir, hashed)); err != nil {
+			l.logError("Ошибка сохранения изображения %s: %v", hashed, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(ImageUploadResponse{
+			Name:   hashed,
+			URL:    currentConfig().PublicURL + "/images/" + hashed,
+			Size:   file.Size,
+			SHA256: file.SHA256,
+		})
+		go l.syncImageVariants(currentConfig(), filepath.Join(imagesDir, hashed))
+		l.logSuccess("Загружено изображение %s (%d bytes)", hashed, file.Size)
+	})
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxInboxSubject = 120
+	maxInboxBody    = 4000
+)
+
+// Сообщение во входящих игрока. Без From — от сервера: компенсации,
+// предупреждения, рассылки администраторов.
+type InboxMessage struct {
+	ID        string     `json:"id"`
+	From      string     `json:"from,omitempty"`
+	Subject   string     `json:"subject,omitempty"`
+	Body      string     `json:"body"`
+	Broadcast bool       `json:"broadcast,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+type InboxResponse struct {
+	Messages []InboxMessage `json:"messages"`
+	Unread   int            `json:"unread"`
+}
+
+// Сообщение другу: POST /api/inbox
+type InboxSendRequest struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Сообщение от сервера: игрокам из To или, с All, всем аккаунтам
+type AdminInboxRequest struct {
+	To      []string `json:"to"`
+	All     bool     `json:"all"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+type AdminInboxResponse struct {
+	Sent int `json:"sent"`
+}
+
+// Событие inbox_message: новое сообщение, текст лаунчер берет из /api/inbox
+type InboxEvent struct {
+	ID      string `json:"id"`
+	From    string `json:"from,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// Входящие по ID аккаунтов в DATA_DIR/inbox.json. Рассылка копируется
+// в ящик каждого аккаунта, так что новые игроки старых рассылок не видят.
+type InboxStore struct {
+	mu    sync.Mutex
+	inbox map[string][]InboxMessage
+}
+
+var inbox = &InboxStore{inbox: make(map[string][]InboxMessage)}
+
+func inboxFile() string {
+	return filepath.Join(currentConfig().DataDir, "inbox.json")
+}
+
+func (s *InboxStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(inboxFile(), &s.inbox)
+}
+
+// Сообщения аккаунта от новых к старым без просроченных
+func (s *InboxStore) List(accountID string) []InboxMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := pruneInbox(slices.Clone(s.inbox[accountID]), currentConfig(), time.Now())
+	slices.Reverse(messages)
+	return messages
+}
+
+// Изменение ящиков перечисленных аккаунтов под блокировкой: fn получает
+// копию сообщений, после нее действуют лимиты хранения
+func (s *InboxStore) update(accountIDs []string, fn func(messages []InboxMessage) ([]InboxMessage, *modError)) (*modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, now := currentConfig(), time.Now()
+	next := maps.Clone(s.inbox)
+	if next == nil {
+		next = make(map[string][]InboxMessage)
+	}
+	for _, id := range accountIDs {
+		messages, apiErr := fn(slices.Clone(s.inbox[id]))
+		if apiErr != nil {
+			return apiErr, nil
+		}
+		if messages = pruneInbox(messages, cfg, now); len(messages) > 0 {
+			next[id] = messages
+		} else {
+			delete(next, id)
+		}
+	}
+	if err := saveJSONFile(inboxFile(), next); err != nil {
+		return nil, err
+	}
+	s.inbox = next
+	return nil, nil
+}
+
+// Доставка сообщения; у копий рассылки общий ID
+func (s *InboxStore) Send(accountIDs []string, message InboxMessage) error {
+	_, err := s.update(accountIDs, func(messages []InboxMessage) ([]InboxMessage, *modError) {
+		return append(messages, message), nil
+	})
+	return err
+}
+
+// Удаление ящика, например вместе с аккаунтом
+func (s *InboxStore) Forget(accountID string) error {
+	_, err := s.update([]string{accountID}, func([]InboxMessage) ([]InboxMessage, *modError) {
+		return nil, nil
+	})
+	return err
+}
+
+// Без сообщений старше INBOX_RETENTION и сверх INBOX_MAX_MESSAGES самых
+// новых; сообщения лежат в порядке получения
+func pruneInbox(messages []InboxMessage, cfg *Config, now time.Time) []InboxMessage {
+	messages = slices.DeleteFunc(messages, func(m InboxMessage) bool {
+		return now.Sub(m.CreatedAt) > cfg.InboxRetention
+	})
+	if len(messages) > cfg.InboxMaxMessages {
+		messages = messages[len(messages)-cfg.InboxMaxMessages:]
+	}
+	return messages
+}
+
+func newInboxMessage(from, subject, body string) (InboxMessage, bool) {
+	subject, body = strings.TrimSpace(subject), strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(subject) > maxInboxSubject || utf8.RuneCountInString(body) > maxInboxBody {
+		return InboxMessage{}, false
+	}
+	return InboxMessage{ID: randomID(8), From: from, Subject: subject, Body: body, CreatedAt: time.Now().UTC()}, true
+}
+
+// Изменение своего ящика с ответом об ошибке клиенту
+func (l *Logger) updateInbox(w http.ResponseWriter, r *http.Request, accountID string, fn func(messages []InboxMessage) ([]InboxMessage, *modError)) bool {
+	apiErr, err := inbox.update([]string{accountID}, fn)
+	if err != nil {
+		l.logError("Ошибка сохранения входящих: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return false
+	}
+	if apiErr != nil {
+		writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+		return false
+	}
+	return true
+}
+
+// Входящие: /api/inbox?unread=true — только непрочитанные
+func (l *Logger) inboxHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📬", "/api/inbox", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(inboxResponse(account.ID, r.URL.Query().Get("unread") == "true"))
+	})
+}
+
+func inboxResponse(accountID string, unreadOnly bool) InboxResponse {
+	response := InboxResponse{Messages: []InboxMessage{}}
+	for _, message := range inbox.List(accountID) {
+		if message.ReadAt == nil {
+			response.Unread++
+		} else if unreadOnly {
+			continue
+		}
+		response.Messages = append(response.Messages, message)
+	}
+	return response
+}
+
+// Сообщение от игрока игроку. Писать можно только друзьям, чтобы ящик
+// не превращался в канал для спама.
+func (l *Logger) inboxSendHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📬", "/api/inbox", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req InboxSendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		message, valid := newInboxMessage(account.Username, req.Subject, req.Body)
+		if !valid {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		target, found := accounts.ByUsername(req.To)
+		if !found || !slices.Contains(friends.FriendIDs(account.ID), target.ID) {
+			writeError(w, r, http.StatusForbidden, ErrCodeNotFriends, req.To)
+			return
+		}
+
+		if err := inbox.Send([]string{target.ID}, message); err != nil {
+			l.logError("Ошибка сохранения входящих: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		eventHub.PublishTo([]string{target.ID}, EventInboxMessage, InboxEvent{ID: message.ID, From: message.From, Subject: message.Subject})
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(message)
+		l.logSuccess("Аккаунт %s написал %s", account.Username, target.Username)
+	})
+}
+
+// Отметка прочитанным: /api/inbox/{id}/read, /api/inbox/read — все сразу
+func (l *Logger) inboxReadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📬", "/api/inbox/read", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		id := r.PathValue("id")
+		ok = l.updateInbox(w, r, account.ID, func(messages []InboxMessage) ([]InboxMessage, *modError) {
+			now := time.Now().UTC()
+			found := false
+			for i := range messages {
+				if id != "" && messages[i].ID != id {
+					continue
+				}
+				found = true
+				if messages[i].ReadAt == nil {
+					messages[i].ReadAt = &now
+				}
+			}
+			if id != "" && !found {
+				return nil, &modError{http.StatusNotFound, ErrCodeMessageNotFound, []interface{}{id}}
+			}
+			return messages, nil
+		})
+		if ok {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+func (l *Logger) inboxDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📬", "/api/inbox/{id}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		id := r.PathValue("id")
+		ok = l.updateInbox(w, r, account.ID, func(messages []InboxMessage) ([]InboxMessage, *modError) {
+			i := slices.IndexFunc(messages, func(m InboxMessage) bool { return m.ID == id })
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeMessageNotFound, []interface{}{id}}
+			}
+			return slices.Delete(messages, i, i+1), nil
+		})
+		if ok {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
+
+// Сообщение от сервера: уведомление о компенсации, предупреждение или
+// рассылка всем аккаунтам
+func (l *Logger) adminInboxSendHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "📬", "/admin/api/inbox", func() {
+		var req AdminInboxRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.All == (len(req.To) > 0) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		message, valid := newInboxMessage("", req.Subject, req.Body)
+		if !valid {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		message.Broadcast = req.All
+
+		ids := accounts.IDs()
+		if !req.All {
+			ids = nil
+			for _, username := range req.To {
+				account, ok := accounts.ByUsername(username)
+				if !ok {
+					writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, username)
+					return
+				}
+				ids = append(ids, account.ID)
+			}
+		}
+		if err := inbox.Send(ids, message); err != nil {
+			l.logError("Ошибка сохранения входящих: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		eventHub.PublishTo(ids, EventInboxMessage, InboxEvent{ID: message.ID, Subject: message.Subject})
+
+		json.NewEncoder(w).Encode(AdminInboxResponse{Sent: len(ids)})
+		l.logSuccess("Сообщение «%s» отправлено в ящики: %d", message.Subject, len(ids))
+	})
+}
+
+// Ящик игрока для разбора жалоб: /admin/api/inbox/{player}
+func (l *Logger) adminInboxHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "📬", "/admin/api/inbox/{player}", func() {
+		account, ok := accounts.ByUsername(r.PathValue("player"))
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, r.PathValue("player"))
+			return
+		}
+		json.NewEncoder(w).Encode(inboxResponse(account.ID, false))
+	})
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// За что адрес блокируется автоматически
+const (
+	IPBanNotFound    = "not_found"
+	IPBanAuthFailure = "auth_failure"
+	IPBanRateLimit   = "rate_limit"
+)
+
+// Сколько адресов помним одновременно; при переполнении забываем тех,
+// у кого окно учета уже закончилось
+const maxTrackedIPs = 100000
+
+// Временная блокировка адреса
+type IPBan struct {
+	IP        string    `json:"ip"`
+	Reason    string    `json:"reason"`
+	Count     int       `json:"count"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type IPBansResponse struct {
+	Bans []IPBan `json:"bans"`
+}
+
+// Нарушения адреса в текущем окне IP_BAN_WINDOW
+type ipOffenses struct {
+	windowEnd time.Time
+	counts    map[string]int
+}
+
+// Блокировки в DATA_DIR/ip_bans.json, счетчики нарушений — в памяти
+type IPGuard struct {
+	mu       sync.Mutex
+	bans     map[string]IPBan
+	offenses map[string]*ipOffenses
+}
+
+var ipGuard = &IPGuard{bans: make(map[string]IPBan), offenses: make(map[string]*ipOffenses)}
+
+func ipBansFile() string {
+	return filepath.Join(currentConfig().DataDir, "ip_bans.json")
+}
+
+func (g *IPGuard) Load() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var bans []IPBan
+	if err := loadJSONFile(ipBansFile(), &bans); err != nil {
+		return err
+	}
+	g.bans = make(map[string]IPBan, len(bans))
+	for _, ban := range bans {
+		g.bans[ban.IP] = ban
+	}
+	return nil
+}
+
+// Действующая блокировка адреса
+func (g *IPGuard) Banned(ip string, now time.Time) (IPBan, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ban, ok := g.bans[ip]
+	return ban, ok && now.Before(ban.ExpiresAt)
+}
+
+// Какое нарушение означает ответ: сканирование несуществующих путей,
+// неудачный вход или упор в ограничение частоты
+func ipOffense(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return IPBanNotFound
+	case http.StatusUnauthorized:
+		return IPBanAuthFailure
+	case http.StatusTooManyRequests:
+		return IPBanRateLimit
+	}
+	return ""
+}
+
+func ipOffenseLimit(cfg *Config, offense string) int {
+	switch offense {
+	case IPBanNotFound:
+		return cfg.IPBanNotFoundLimit
+	case IPBanAuthFailure:
+		return cfg.IPBanAuthFailureLimit
+	case IPBanRateLimit:
+		return cfg.IPBanRateLimitHits
+	}
+	return 0
+}
+
+// Учет ответа адресу; при превышении порога адрес блокируется на
+// IP_BAN_DURATION. Возвращает новую блокировку, если она появилась.
+func (g *IPGuard) Observe(cfg *Config, ip string, status int, now time.Time) (IPBan, bool, error) {
+	offense := ipOffense(status)
+	limit := ipOffenseLimit(cfg, offense)
+	if limit == 0 {
+		return IPBan{}, false, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entry, ok := g.offenses[ip]
+	if !ok || !now.Before(entry.windowEnd) {
+		if !ok && len(g.offenses) >= maxTrackedIPs {
+			for key, e := range g.offenses {
+				if !now.Before(e.windowEnd) {
+					delete(g.offenses, key)
+				}
+			}
+		}
+		entry = &ipOffenses{windowEnd: now.Add(cfg.IPBanWindow), counts: make(map[string]int)}
+		g.offenses[ip] = entry
+	}
+	entry.counts[offense]++
+	if entry.counts[offense] < limit {
+		return IPBan{}, false, nil
+	}
+
+	delete(g.offenses, ip)
+	ban := IPBan{IP: ip, Reason: offense, Count: limit, CreatedAt: now, ExpiresAt: now.Add(cfg.IPBanDuration)}
+	next := g.active(now)
+	next[ip] = ban
+	return ban, true, g.replace(next)
+}
+
+// Действующие блокировки, новые первыми
+func (g *IPGuard) List(now time.Time) []IPBan {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bans := []IPBan{}
+	for _, ban := range g.active(now) {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].CreatedAt.After(bans[j].CreatedAt) })
+	return bans
+}
+
+// Снятие блокировки; false, если адрес не заблокирован
+func (g *IPGuard) Lift(ip string, now time.Time) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	next := g.active(now)
+	if _, ok := next[ip]; !ok {
+		return false, nil
+	}
+	delete(next, ip)
+	delete(g.offenses, ip)
+	return true, g.replace(next)
+}
+
+// Копия блокировок без истекших (под блокировкой)
+func (g *IPGuard) active(now time.Time) map[string]IPBan {
+	next := make(map[string]IPBan, len(g.bans))
+	for ip, ban := range g.bans {
+		if now.Before(ban.ExpiresAt) {
+			next[ip] = ban
+		}
+	}
+	return next
+}
+
+func (g *IPGuard) replace(next map[string]IPBan) error {
+	bans := make([]IPBan, 0, len(next))
+	for _, ban := range next {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].IP < bans[j].IP })
+	if err := saveJSONFile(ipBansFile(), bans); err != nil {
+		return err
+	}
+	g.bans = next
+	return nil
+}
+
+func ipInNetworks(ip string, networks []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Проверка адреса перед обработкой запроса: белый и черный списки из
+// конфигурации, затем автоматическая блокировка. При отказе ответ уже записан.
+func (l *Logger) rejectByIP(w http.ResponseWriter, r *http.Request) bool {
+	cfg := currentConfig()
+	ip := getClientIP(r)
+	if (len(cfg.IPAllowlist) > 0 && !ipInNetworks(ip, cfg.IPAllowlist)) || ipInNetworks(ip, cfg.IPDenylist) {
+		writeError(w, r, http.StatusForbidden, ErrCodeIPForbidden)
+		return true
+	}
+	if ban, ok := ipGuard.Banned(ip, time.Now()); ok {
+		seconds := int(time.Until(ban.ExpiresAt).Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeError(w, r, http.StatusForbidden, ErrCodeIPBanned, seconds)
+		return true
+	}
+	return false
+}
+
+// Учет ответа для автоматической блокировки
+func (l *Logger) observeIP(r *http.Request, status int) {
+	ip := getClientIP(r)
+	ban, banned, err := ipGuard.Observe(currentConfig(), ip, status, time.Now().UTC())
+	if err != nil {
+		l.logError("Ошибка сохранения блокировок адресов: %v", err)
+	}
+	if banned {
+		l.logError("Адрес %s заблокирован до %s: %s (%d раз)", ip, ban.ExpiresAt.Format(time.RFC3339), ban.Reason, ban.Count)
+	}
+}
+
+// Код ответа для учета нарушений; Unwrap оставляет доступными
+// возможности исходного ResponseWriter через http.ResponseController
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(data)
+	s.written += int64(n)
+	return n, err
+}
+
+// Без ReadFrom отдача файлов потеряла бы sendfile (см. idleTimeoutWriter)
+func (s *statusRecorder) ReadFrom(src io.Reader) (int64, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := io.Copy(s.ResponseWriter, src)
+	s.written += n
+	return n, err
+}
+
+// Потоковые ответы (gRPC) проверяют http.Flusher напрямую, без Unwrap
+func (s *statusRecorder) Flush() {
+	http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Действующие блокировки адресов
+func (l *Logger) adminIPBansHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🚷", "/admin/api/ip-bans", func() {
+		json.NewEncoder(w).Encode(IPBansResponse{Bans: ipGuard.List(time.Now())})
+	})
+}
+
+// Снятие блокировки адреса
+func (l *Logger) adminLiftIPBanHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🚷", "/admin/api/ip-bans/{ip}", func() {
+		ip := r.PathValue("ip")
+		lifted, err := ipGuard.Lift(ip, time.Now())
+		if err != nil {
+			l.logError("Ошибка сохранения блокировок адресов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !lifted {
+			writeError(w, r, http.StatusNotFound, ErrCodeIPBanNotFound, ip)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		actor, _ := adminActor(r, "")
+		l.logSuccess("Снята блокировка адреса %s (%s)", ip, actor)
+	})
+}
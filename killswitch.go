@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Заблокированные версии: лаунчер с такой версией игры или своей
+// собственной должен отказаться запускаться и обновиться
+type BlockedVersions struct {
+	Launcher []string `json:"launcher"`
+	Game     []string `json:"game"`
+}
+
+var (
+	blockedVersionsMu sync.Mutex
+	blockedVersions   atomic.Pointer[BlockedVersions]
+)
+
+func blockedVersionsFile() string {
+	return filepath.Join(currentConfig().DataDir, "blocked_versions.json")
+}
+
+func loadBlockedVersions() error {
+	blocked := BlockedVersions{Launcher: []string{}, Game: []string{}}
+	if err := loadJSONFile(blockedVersionsFile(), &blocked); err != nil {
+		return err
+	}
+	blockedVersions.Store(&blocked)
+	return nil
+}
+
+func currentBlockedVersions() *BlockedVersions {
+	return blockedVersions.Load()
+}
+
+// Нужно ли клиенту с этими версиями обязательно обновиться
+func (b *BlockedVersions) mustUpdate(launcherVersion, gameVersion string) bool {
+	return (launcherVersion != "" && slices.Contains(b.Launcher, launcherVersion)) ||
+		(gameVersion != "" && slices.Contains(b.Game, gameVersion))
+}
+
+// Текущий список заблокированных версий
+func (l *Logger) adminGetBlockedVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "⛔", "/admin/api/blocked-versions", func() {
+		json.NewEncoder(w).Encode(currentBlockedVersions())
+	})
+}
+
+// Замена списка заблокированных версий; применяется сразу, без перезапуска
+func (l *Logger) adminSetBlockedVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "⛔", "/admin/api/blocked-versions", func() {
+		var req BlockedVersions
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if req.Launcher == nil {
+			req.Launcher = []string{}
+		}
+		if req.Game == nil {
+			req.Game = []string{}
+		}
+		for _, version := range append(slices.Clone(req.Launcher), req.Game...) {
+			if !isSemver(version) {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+		}
+
+		// Блокировка текущего релиза заперла бы всех игроков без выхода
+		cfg := currentConfig()
+		if slices.Contains(req.Launcher, cfg.LauncherVersion) {
+			writeError(w, r, http.StatusConflict, ErrCodeCurrentVersionBlocked, cfg.LauncherVersion)
+			return
+		}
+		if slices.Contains(req.Game, cfg.GameVersion) {
+			writeError(w, r, http.StatusConflict, ErrCodeCurrentVersionBlocked, cfg.GameVersion)
+			return
+		}
+
+		blockedVersionsMu.Lock()
+		defer blockedVersionsMu.Unlock()
+		if err := saveJSONFile(blockedVersionsFile(), req); err != nil {
+			l.logError("Ошибка сохранения заблокированных версий: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		blockedVersions.Store(&req)
+		eventHub.PublishVersion()
+
+		json.NewEncoder(w).Encode(req)
+		l.logSuccess("Заблокированные версии: лаунчер %v, игра %v", req.Launcher, req.Game)
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Удаленная конфигурация лаунчера: произвольные настройки и флаги
+// функций. Хранится в DATA_DIR/launcher_config.json и меняется через
+// админский API без выпуска новой версии лаунчера.
+type LauncherConfig struct {
+	Settings map[string]json.RawMessage `json:"settings"`
+	Flags    map[string]FeatureFlag     `json:"flags"`
+}
+
+// Флаг функции. Rollout — процент клиентов, которым флаг включен;
+// без него флаг включен всем.
+type FeatureFlag struct {
+	Enabled bool `json:"enabled"`
+	Rollout *int `json:"rollout,omitempty"`
+}
+
+// Конфигурация, которую видит конкретный лаунчер
+type LauncherConfigResponse struct {
+	Settings map[string]json.RawMessage `json:"settings"`
+	Flags    map[string]bool            `json:"flags"`
+	// Можно ли этому лаунчеру отправлять телеметрию (если игрок согласился)
+	Telemetry TelemetrySettings `json:"telemetry"`
+	// Принимает ли сервер опрос о железе (тоже с согласия игрока)
+	HardwareSurvey bool `json:"hardware_survey"`
+	// Аварийные выключатели, как в /api/switches
+	Switches map[string]bool `json:"switches"`
+	// Где нужна CAPTCHA и ключ сайта для ее формы
+	Captcha CaptchaSettings `json:"captcha"`
+}
+
+var (
+	launcherConfigMu sync.Mutex
+	launcherConfig   atomic.Pointer[LauncherConfig]
+)
+
+func launcherConfigFile() string {
+	return filepath.Join(currentConfig().DataDir, "launcher_config.json")
+}
+
+func loadLauncherConfig() error {
+	var config LauncherConfig
+	if err := loadJSONFile(launcherConfigFile(), &config); err != nil {
+		return err
+	}
+	config.normalize()
+	launcherConfig.Store(&config)
+	return nil
+}
+
+func (c *LauncherConfig) normalize() {
+	if c.Settings == nil {
+		c.Settings = make(map[string]json.RawMessage)
+	}
+	if c.Flags == nil {
+		c.Flags = make(map[string]FeatureFlag)
+	}
+}
+
+// Корзина клиента 0..99 для флага. Хэш от имени флага и ID клиента:
+// клиент стабильно попадает в одну корзину, а разные флаги раскатываются
+// на разные подмножества игроков.
+func rolloutBucket(flag, clientID string) int {
+	sum := sha256.Sum256([]byte(flag + ":" + clientID))
+	return int(binary.BigEndian.Uint32(sum[:4]) % 100)
+}
+
+func (f FeatureFlag) enabledFor(name, clientID string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if f.Rollout == nil || *f.Rollout >= 100 {
+		return true
+	}
+	// Без ID клиента частичная раскатка не применяется
+	if clientID == "" {
+		return false
+	}
+	return rolloutBucket(name, clientID) < *f.Rollout
+}
+
+// ID клиента: стабильный идентификатор установки лаунчера
+func launcherClientID(r *http.Request) string {
+	if id := r.Header.Get("X-Client-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("client_id")
+}
+
+// Обработчик конфигурации лаунчера
+func (l *Logger) launcherConfigHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎛️", "/api/launcher-config", func() {
+		config := launcherConfig.Load()
+		clientID := launcherClientID(r)
+
+		response := LauncherConfigResponse{
+			Settings:       config.Settings,
+			Flags:          make(map[string]bool, len(config.Flags)),
+			Telemetry:      telemetrySettings(currentConfig(), clientID),
+			HardwareSurvey: currentConfig().HardwareSurveyEnabled,
+			Switches:       killSwitchesResponse(killSwitches.Load()).Switches,
+			Captcha:        captchaSettings(currentConfig()),
+		}
+		for name, flag := range config.Flags {
+			response.Flags[name] = flag.enabledFor(name, clientID)
+		}
+
+		w.Header().Set("Vary", "X-Client-ID")
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Отправлена конфигурация лаунчера: флагов %d", len(response.Flags))
+	})
+}
+
+// Конфигурация лаунчера с процентами раскатки
+func (l *Logger) adminGetLauncherConfigHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🎛️", "/admin/api/launcher-config", func() {
+		json.NewEncoder(w).Encode(launcherConfig.Load())
+	})
+}
+
+// Замена конфигурации лаунчера целиком
+func (l *Logger) adminSetLauncherConfigHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🎛️", "/admin/api/launcher-config", func() {
+		var req LauncherConfig
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		req.normalize()
+		for name, flag := range req.Flags {
+			if name == "" || (flag.Rollout != nil && (*flag.Rollout < 0 || *flag.Rollout > 100)) {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+		}
+
+		launcherConfigMu.Lock()
+		defer launcherConfigMu.Unlock()
+		if err := saveJSONFile(launcherConfigFile(), req); err != nil {
+			l.logError("Ошибка сохранения конфигурации лаунчера: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		launcherConfig.Store(&req)
+
+		json.NewEncoder(w).Encode(req)
+		l.logSuccess("Конфигурация лаунчера обновлена: настроек %d, флагов %d", len(req.Settings), len(req.Flags))
+	})
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Поэтапная раскатка релиза лаунчера. Действует только для версии
+// Version: после выхода следующего релиза без своей раскатки обновление
+// снова получают все. Halted останавливает раскатку, не меняя процент.
+type LauncherRollout struct {
+	Version    string    `json:"version"`
+	Percentage int       `json:"percentage"`
+	Halted     bool      `json:"halted"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type LauncherRolloutRequest struct {
+	Percentage int  `json:"percentage"`
+	Halted     bool `json:"halted"`
+}
+
+// Сведения о самообновлении для конкретного лаунчера. Version — последний
+// релиз; UpdateAvailable — можно ли этому клиенту обновиться сейчас.
+type LauncherUpdateResponse struct {
+	Version         string `json:"version"`
+	UpdateAvailable bool   `json:"update_available"`
+	// Версия клиента заблокирована: обновление обязательно при любой раскатке
+	Mandatory bool   `json:"mandatory"`
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	Hash      string `json:"hash"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature,omitempty"`
+	KeyID     string `json:"key_id,omitempty"`
+	// Процент раскатки релиза; 0 при остановленной раскатке
+	Rollout int `json:"rollout"`
+}
+
+var (
+	launcherRolloutMu sync.Mutex
+	launcherRollout   atomic.Pointer[LauncherRollout]
+)
+
+func launcherRolloutFile() string {
+	return filepath.Join(currentConfig().DataDir, "launcher_rollout.json")
+}
+
+func loadLauncherRollout() error {
+	var rollout *LauncherRollout
+	if err := loadJSONFile(launcherRolloutFile(), &rollout); err != nil {
+		return err
+	}
+	launcherRollout.Store(rollout)
+	return nil
+}
+
+// Процент раскатки версии: без раскатки для нее — 100
+func rolloutPercentage(version string) int {
+	rollout := launcherRollout.Load()
+	switch {
+	case rollout == nil || rollout.Version != version:
+		return 100
+	case rollout.Halted:
+		return 0
+	}
+	return rollout.Percentage
+}
+
+// Попадает ли клиент в раскатку. Корзина зависит от версии, чтобы
+// первыми новые релизы получали не всегда одни и те же игроки.
+func inLauncherRollout(version, clientID string) bool {
+	percentage := rolloutPercentage(version)
+	if percentage >= 100 {
+		return true
+	}
+	// Без ID клиента частичная раскатка не применяется, как у флагов
+	if clientID == "" {
+		return false
+	}
+	return rolloutBucket("launcher@"+version, clientID) < percentage
+}
+
+// Самообновление лаунчера: /api/launcher/update?current_version=1.2.0
+// с X-Client-ID. Лаунчеры вне раскатки получают update_available: false
+// и остаются на своей версии, пока раскатку не расширят.
+func (l *Logger) launcherUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🆙", "/api/launcher/update", func() {
+		cfg := currentConfig()
+		current := r.URL.Query().Get("current_version")
+		if current != "" && !isSemver(current) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		path := filepath.Join(cfg.ClientsDir, cfg.LauncherClient)
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		var file ClientFile
+		if err == nil {
+			file, err = clientIndex.Lookup(path, info)
+		}
+		if err != nil {
+			l.logError("Ошибка вычисления хэша файла %s: %v", path, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+
+		clientID := launcherClientID(r)
+		response := LauncherUpdateResponse{
+			Version:   cfg.LauncherVersion,
+			Mandatory: current != "" && currentBlockedVersions().mustUpdate(current, ""),
+			URL:       cfg.PublicURL + "/api/download/launcher",
+			Size:      file.Size,
+			Hash:      file.Hash,
+			SHA256:    file.SHA256,
+			Rollout:   rolloutPercentage(cfg.LauncherVersion),
+		}
+		newer := current == "" || compareVersions(cfg.LauncherVersion, current) > 0
+		response.UpdateAvailable = newer && (response.Mandatory || inLauncherRollout(cfg.LauncherVersion, clientID))
+
+		signer := releaseSigner.Load()
+		if signature, ok := l.artifactSignature(signer, "launcher", file.SHA256); ok {
+			response.Signature, response.KeyID = base64.StdEncoding.EncodeToString(signature), signer.keyID
+		}
+
+		w.Header().Set("Vary", "X-Client-ID")
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Самообновление лаунчера %s → %s: доступно %v (раскатка %d%%)", current, cfg.LauncherVersion, response.UpdateAvailable, response.Rollout)
+	})
+}
+
+// Текущая раскатка; null — релиз раскатан на всех
+func (l *Logger) adminGetLauncherRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🆙", "/admin/api/launcher/rollout", func() {
+		json.NewEncoder(w).Encode(launcherRollout.Load())
+	})
+}
+
+// Раскатка текущего релиза лаунчера (LAUNCHER_VERSION): процент клиентов
+// и остановка. Применяется сразу; подписчики /api/events узнают о ней.
+func (l *Logger) adminSetLauncherRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🆙", "/admin/api/launcher/rollout", func() {
+		var req LauncherRolloutRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Percentage < 0 || req.Percentage > 100 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		rollout := &LauncherRollout{
+			Version:    currentConfig().LauncherVersion,
+			Percentage: req.Percentage,
+			Halted:     req.Halted,
+			UpdatedAt:  time.Now().UTC(),
+		}
+		launcherRolloutMu.Lock()
+		defer launcherRolloutMu.Unlock()
+		if err := saveJSONFile(launcherRolloutFile(), rollout); err != nil {
+			l.logError("Ошибка сохранения раскатки лаунчера: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		launcherRollout.Store(rollout)
+		eventHub.PublishVersion()
+
+		json.NewEncoder(w).Encode(rollout)
+		if rollout.Halted {
+			l.logSuccess("Раскатка лаунчера %s остановлена на %d%%", rollout.Version, rollout.Percentage)
+			return
+		}
+		l.logSuccess("Раскатка лаунчера %s: %d%%", rollout.Version, rollout.Percentage)
+	})
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Подстановки в аргументах и переменных окружения; значения знает
+// только лаунчер, сервер их не раскрывает
+var launchPlaceholders = []string{"username", "account_id", "access_token", "game_dir", "runtime_dir", "game_version"}
+
+var (
+	launchPlaceholderPattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+	envNamePattern           = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Как запускать игру данной версии. Пути — относительно каталога игры.
+// Версия "1.4" подходит и для 1.4, и для 1.4.2, как у модов.
+type LaunchProfile struct {
+	GameVersion string            `json:"game_version"`
+	Executable  string            `json:"executable"`
+	Arguments   []string          `json:"arguments"`
+	Env         map[string]string `json:"env"`
+	WorkingDir  string            `json:"working_dir"`
+	// Отличия для windows, linux, macos
+	Platforms map[string]LaunchPlatform `json:"platforms,omitempty"`
+}
+
+// Пустые поля берутся из общего профиля, Env дополняет общий
+type LaunchPlatform struct {
+	Executable string            `json:"executable,omitempty"`
+	Arguments  []string          `json:"arguments,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+}
+
+type LaunchProfileRequest struct {
+	Executable string                    `json:"executable"`
+	Arguments  []string                  `json:"arguments"`
+	Env        map[string]string         `json:"env"`
+	WorkingDir string                    `json:"working_dir"`
+	Platforms  map[string]LaunchPlatform `json:"platforms"`
+}
+
+// Профиль для платформы лаунчера
+type LaunchProfileResponse struct {
+	GameVersion  string            `json:"game_version"`
+	OS           string            `json:"os,omitempty"`
+	Executable   string            `json:"executable"`
+	Arguments    []string          `json:"arguments"`
+	Env          map[string]string `json:"env"`
+	WorkingDir   string            `json:"working_dir"`
+	Placeholders []string          `json:"placeholders"`
+}
+
+type LaunchProfilesResponse struct {
+	Profiles []LaunchProfile `json:"profiles"`
+}
+
+// Профили в DATA_DIR/launch_profiles.json
+type LaunchProfileStore struct {
+	*jsonListStore[LaunchProfile]
+}
+
+var launchProfiles = &LaunchProfileStore{&jsonListStore[LaunchProfile]{file: launchProfilesFile}}
+
+func launchProfilesFile() string {
+	return filepath.Join(currentConfig().DataDir, "launch_profiles.json")
+}
+
+func findLaunchProfile(profiles []LaunchProfile, gameVersion string) int {
+	return slices.IndexFunc(profiles, func(p LaunchProfile) bool { return p.GameVersion == gameVersion })
+}
+
+// Профиль для версии игры: точное совпадение или самый длинный префикс
+func matchLaunchProfile(profiles []LaunchProfile, gameVersion string) (LaunchProfile, bool) {
+	var best LaunchProfile
+	found := false
+	for _, profile := range profiles {
+		if (gameVersion == profile.GameVersion || strings.HasPrefix(gameVersion, profile.GameVersion+".")) &&
+			(!found || len(profile.GameVersion) > len(best.GameVersion)) {
+			best, found = profile, true
+		}
+	}
+	return best, found
+}
+
+// Профиль с учетом отличий платформы
+func (p LaunchProfile) forPlatform(osName string) LaunchProfileResponse {
+	response := LaunchProfileResponse{
+		GameVersion:  p.GameVersion,
+		OS:           osName,
+		Executable:   p.Executable,
+		Arguments:    append([]string{}, p.Arguments...),
+		Env:          maps.Clone(p.Env),
+		WorkingDir:   p.WorkingDir,
+		Placeholders: launchPlaceholders,
+	}
+	if response.Env == nil {
+		response.Env = make(map[string]string)
+	}
+	if platform, ok := p.Platforms[osName]; ok {
+		if platform.Executable != "" {
+			response.Executable = platform.Executable
+		}
+		if platform.Arguments != nil {
+			response.Arguments = append([]string{}, platform.Arguments...)
+		}
+		maps.Copy(response.Env, platform.Env)
+	}
+	return response
+}
+
+// Пустой путь разрешен только для рабочего каталога (корень игры)
+func validLaunchPath(name string, allowEmpty bool) bool {
+	if name == "" {
+		return allowEmpty
+	}
+	_, ok := normalizeVerifyPath(name)
+	return ok
+}
+
+func validLaunchStrings(values ...string) bool {
+	for _, value := range values {
+		for _, match := range launchPlaceholderPattern.FindAllStringSubmatch(value, -1) {
+			if !slices.Contains(launchPlaceholders, match[1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validLaunchEnv(env map[string]string) bool {
+	for name, value := range env {
+		if !envNamePattern.MatchString(name) || !validLaunchStrings(value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (req LaunchProfileRequest) valid() bool {
+	if !validLaunchPath(req.Executable, false) || !validLaunchPath(req.WorkingDir, true) ||
+		!validLaunchStrings(req.Arguments...) || !validLaunchEnv(req.Env) {
+		return false
+	}
+	for osName, platform := range req.Platforms {
+		if !knownRuntimeOS[osName] || !validLaunchPath(platform.Executable, true) ||
+			!validLaunchStrings(platform.Arguments...) || !validLaunchEnv(platform.Env) {
+			return false
+		}
+	}
+	return true
+}
+
+// Как запустить игру: /api/launch-profile?game_version=1.4&os=windows
+func (l *Logger) launchProfileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🚀", "/api/launch-profile", func() {
+		gameVersion, osName := r.URL.Query().Get("game_version"), r.URL.Query().Get("os")
+		if gameVersion == "" {
+			gameVersion = currentConfig().GameVersion
+		}
+		if osName != "" && !knownRuntimeOS[osName] {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		profile, ok := matchLaunchProfile(launchProfiles.List(), gameVersion)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeLaunchProfileNotFound, gameVersion)
+			return
+		}
+		json.NewEncoder(w).Encode(profile.forPlatform(osName))
+		l.logSuccess("Отправлен профиль запуска %s для %s", profile.GameVersion, gameVersion)
+	})
+}
+
+func (l *Logger) adminListLaunchProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🚀", "/admin/api/launch-profiles", func() {
+		json.NewEncoder(w).Encode(LaunchProfilesResponse{Profiles: append([]LaunchProfile{}, launchProfiles.List()...)})
+	})
+}
+
+// Создание или замена профиля версии игры
+func (l *Logger) adminPutLaunchProfileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🚀", "/admin/api/launch-profiles/{game_version}", func() {
+		gameVersion := r.PathValue("game_version")
+		var req LaunchProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !versionLabelPattern.MatchString(gameVersion) || !req.valid() {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		profile := LaunchProfile{
+			GameVersion: gameVersion,
+			Executable:  req.Executable,
+			Arguments:   append([]string{}, req.Arguments...),
+			Env:         req.Env,
+			WorkingDir:  req.WorkingDir,
+			Platforms:   req.Platforms,
+		}
+		if profile.Env == nil {
+			profile.Env = make(map[string]string)
+		}
+		apiErr, err := launchProfiles.update(func(list []LaunchProfile) ([]LaunchProfile, *modError) {
+			if i := findLaunchProfile(list, gameVersion); i >= 0 {
+				list[i] = profile
+				return list, nil
+			}
+			return append(list, profile), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(profile)
+		l.logSuccess("Профиль запуска %s сохранен: %s", gameVersion, profile.Executable)
+	})
+}
+
+func (l *Logger) adminDeleteLaunchProfileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🚀", "/admin/api/launch-profiles/{game_version}", func() {
+		gameVersion := r.PathValue("game_version")
+		apiErr, err := launchProfiles.update(func(list []LaunchProfile) ([]LaunchProfile, *modError) {
+			i := findLaunchProfile(list, gameVersion)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeLaunchProfileNotFound, []interface{}{gameVersion}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Профиль запуска %s удален", gameVersion)
+	})
+}
@@ -0,0 +1,629 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Тип результата подсказывает лаунчеру, как его показывать
+var (
+	leaderboardScoreTypes = []string{"points", "time_ms", "count"}
+	leaderboardOrders     = []string{LeaderboardDesc, LeaderboardAsc}
+	leaderboardResets     = []string{LeaderboardNever, LeaderboardDaily, LeaderboardWeekly, LeaderboardMonthly}
+)
+
+const (
+	// desc — больше лучше (очки), asc — меньше лучше (время прохождения)
+	LeaderboardDesc = "desc"
+	LeaderboardAsc  = "asc"
+
+	LeaderboardNever   = "never"
+	LeaderboardDaily   = "daily"
+	LeaderboardWeekly  = "weekly"
+	LeaderboardMonthly = "monthly"
+
+	maxLeaderboardPage = 100
+)
+
+// Таблица рекордов. Результаты делятся на периоды по Reset (неделя
+// по ISO, в UTC); в каждом периоде у игрока хранится лучший результат.
+type Leaderboard struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ScoreType string `json:"score_type"`
+	Order     string `json:"order"`
+	Reset     string `json:"reset"`
+	// Проверки от подделки результатов: допустимый диапазон, пауза между
+	// результатами игрока и внешняя проверка (POST с результатом; ответ
+	// не 2xx — результат отклоняется)
+	MinScore    *int64 `json:"min_score,omitempty"`
+	MaxScore    *int64 `json:"max_score,omitempty"`
+	MinInterval int    `json:"min_interval_seconds,omitempty"`
+	ValidateURL string `json:"validate_url,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type LeaderboardRequest struct {
+	Name        string `json:"name"`
+	ScoreType   string `json:"score_type"`
+	Order       string `json:"order"`
+	Reset       string `json:"reset"`
+	MinScore    *int64 `json:"min_score"`
+	MaxScore    *int64 `json:"max_score"`
+	MinInterval int    `json:"min_interval_seconds"`
+	ValidateURL string `json:"validate_url"`
+}
+
+// Таблица для лаунчера: без настроек проверок
+type LeaderboardInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ScoreType string `json:"score_type"`
+	Order     string `json:"order"`
+	Reset     string `json:"reset"`
+}
+
+type LeaderboardsResponse struct {
+	Leaderboards []LeaderboardInfo `json:"leaderboards"`
+}
+
+// Лучший результат игрока за период
+type LeaderboardEntry struct {
+	Player string `json:"player"`
+	Score  int64  `json:"score"`
+	// Когда показан лучший результат; при равенстве выше тот, кто раньше
+	AchievedAt   time.Time `json:"achieved_at"`
+	LastSubmitAt time.Time `json:"last_submit_at"`
+	Submissions  int       `json:"submissions"`
+}
+
+type RankedEntry struct {
+	Rank        int       `json:"rank"`
+	Player      string    `json:"player"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Score       int64     `json:"score"`
+	AchievedAt  time.Time `json:"achieved_at"`
+}
+
+// Страница таблицы: /api/leaderboards/{id}?period=previous&offset=0&limit=50
+type LeaderboardPage struct {
+	Leaderboard LeaderboardInfo `json:"leaderboard"`
+	Period      string          `json:"period"`
+	StartsAt    *time.Time      `json:"starts_at,omitempty"`
+	EndsAt      *time.Time      `json:"ends_at,omitempty"`
+	Total       int             `json:"total"`
+	Entries     []RankedEntry   `json:"entries"`
+	NextOffset  *int            `json:"next_offset,omitempty"`
+	// Место игрока из ?player=
+	Player *RankedEntry `json:"player,omitempty"`
+}
+
+type ScoreSubmission struct {
+	Player string `json:"player"`
+	Score  int64  `json:"score"`
+}
+
+type ScoreSubmitResponse struct {
+	Period   string `json:"period"`
+	Best     int64  `json:"best"`
+	Improved bool   `json:"improved"`
+	Rank     int    `json:"rank"`
+}
+
+// Результат, который отправляется на внешнюю проверку
+type ScoreValidationRequest struct {
+	Leaderboard string `json:"leaderboard"`
+	Period      string `json:"period"`
+	ScoreSubmission
+	// Прежний лучший результат игрока в периоде
+	Previous *LeaderboardEntry `json:"previous,omitempty"`
+}
+
+// Определения в DATA_DIR/leaderboards.json, результаты каждой таблицы в
+// DATA_DIR/leaderboards/<id>.json: период → игрок → лучший результат
+type LeaderboardStore struct {
+	*jsonListStore[Leaderboard]
+	scores map[string]map[string]map[string]LeaderboardEntry
+}
+
+var leaderboards = &LeaderboardStore{jsonListStore: &jsonListStore[Leaderboard]{file: leaderboardsFile}}
+
+func leaderboardsFile() string {
+	return filepath.Join(currentConfig().DataDir, "leaderboards.json")
+}
+
+func leaderboardScoresFile(id string) string {
+	return filepath.Join(currentConfig().DataDir, "leaderboards", id+".json")
+}
+
+func (s *LeaderboardStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := loadJSONFile(s.file(), &s.items); err != nil {
+		return err
+	}
+	s.scores = make(map[string]map[string]map[string]LeaderboardEntry)
+	for _, board := range s.items {
+		periods := make(map[string]map[string]LeaderboardEntry)
+		if err := loadJSONFile(leaderboardScoresFile(board.ID), &periods); err != nil {
+			return err
+		}
+		s.scores[board.ID] = periods
+	}
+	return nil
+}
+
+func (s *LeaderboardStore) Get(id string) (Leaderboard, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := findLeaderboard(s.items, id); i >= 0 {
+		return s.items[i], true
+	}
+	return Leaderboard{}, false
+}
+
+func findLeaderboard(list []Leaderboard, id string) int {
+	return slices.IndexFunc(list, func(b Leaderboard) bool { return b.ID == id })
+}
+
+// Удаление таблицы вместе с результатами
+func (s *LeaderboardStore) Delete(id string) (bool, error) {
+	apiErr, err := s.update(func(list []Leaderboard) ([]Leaderboard, *modError) {
+		i := findLeaderboard(list, id)
+		if i < 0 {
+			return nil, &modError{http.StatusNotFound, ErrCodeLeaderboardNotFound, []interface{}{id}}
+		}
+		return slices.Delete(list, i, i+1), nil
+	})
+	if apiErr != nil || err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scores, id)
+	if err := os.Remove(leaderboardScoresFile(id)); err != nil && !os.IsNotExist(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+// Изменение результатов таблицы под блокировкой: fn меняет копию и
+// возвращает false, если сохранять нечего
+func (s *LeaderboardStore) updateScores(id string, fn func(periods map[string]map[string]LeaderboardEntry) bool) error {
+	next := make(map[string]map[string]LeaderboardEntry, len(s.scores[id]))
+	for period, entries := range s.scores[id] {
+		next[period] = maps.Clone(entries)
+	}
+	if !fn(next) {
+		return nil
+	}
+	path := leaderboardScoresFile(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := saveJSONFile(path, next); err != nil {
+		return err
+	}
+	s.scores[id] = next
+	return nil
+}
+
+// Лучший результат игрока в периоде
+func (s *LeaderboardStore) Entry(id, period, player string) (LeaderboardEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.scores[id][period][achievementPlayer(player)]
+	return entry, ok
+}
+
+// Запись результата. Лучший результат меняется, только если новый лучше;
+// периоды старше keep последних удаляются.
+func (s *LeaderboardStore) Submit(board Leaderboard, period string, submission ScoreSubmission, keep int, now time.Time) (ScoreSubmitResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := achievementPlayer(submission.Player)
+	response := ScoreSubmitResponse{Period: period}
+	err := s.updateScores(board.ID, func(periods map[string]map[string]LeaderboardEntry) bool {
+		if periods[period] == nil {
+			periods[period] = make(map[string]LeaderboardEntry)
+		}
+		entry, exists := periods[period][key]
+		if !exists || board.better(submission.Score, entry.Score) {
+			entry.Player, entry.Score, entry.AchievedAt = submission.Player, submission.Score, now
+			response.Improved = true
+		}
+		entry.LastSubmitAt = now
+		entry.Submissions++
+		periods[period][key] = entry
+		response.Best = entry.Score
+
+		if keep > 0 && len(periods) > keep {
+			keys := slices.Sorted(maps.Keys(periods))
+			for _, old := range keys[:len(keys)-keep] {
+				delete(periods, old)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return ScoreSubmitResponse{}, err
+	}
+	ranked := s.ranked(board, period)
+	response.Rank = slices.IndexFunc(ranked, func(e LeaderboardEntry) bool { return achievementPlayer(e.Player) == key }) + 1
+	return response, nil
+}
+
+// Результаты периода от лучшего к худшему (под блокировкой)
+func (s *LeaderboardStore) ranked(board Leaderboard, period string) []LeaderboardEntry {
+	entries := slices.Collect(maps.Values(s.scores[board.ID][period]))
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return board.better(entries[i].Score, entries[j].Score)
+		}
+		if !entries[i].AchievedAt.Equal(entries[j].AchievedAt) {
+			return entries[i].AchievedAt.Before(entries[j].AchievedAt)
+		}
+		return entries[i].Player < entries[j].Player
+	})
+	return entries
+}
+
+func (s *LeaderboardStore) Ranked(board Leaderboard, period string) []LeaderboardEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ranked(board, period)
+}
+
+// Удаление результатов игрока: во всех периодах или только в period
+func (s *LeaderboardStore) Remove(id, player, period string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := achievementPlayer(player)
+	found := false
+	err := s.updateScores(id, func(periods map[string]map[string]LeaderboardEntry) bool {
+		for name, entries := range periods {
+			if period != "" && name != period {
+				continue
+			}
+			if _, ok := entries[key]; ok {
+				delete(entries, key)
+				found = true
+			}
+		}
+		return found
+	})
+	return found, err
+}
+
+// Результаты игрока во всех таблицах для выгрузки данных аккаунта
+func (s *LeaderboardStore) PlayerEntries(player string) map[string]map[string]LeaderboardEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := achievementPlayer(player)
+	result := make(map[string]map[string]LeaderboardEntry)
+	for id, periods := range s.scores {
+		for period, entries := range periods {
+			if entry, ok := entries[key]; ok {
+				if result[id] == nil {
+					result[id] = make(map[string]LeaderboardEntry)
+				}
+				result[id][period] = entry
+			}
+		}
+	}
+	return result
+}
+
+// Удаление результатов игрока во всех таблицах
+func (s *LeaderboardStore) Forget(player string) error {
+	for _, board := range s.List() {
+		if _, err := s.Remove(board.ID, player, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b Leaderboard) better(a, than int64) bool {
+	if b.Order == LeaderboardAsc {
+		return a < than
+	}
+	return a > than
+}
+
+func (b Leaderboard) info() LeaderboardInfo {
+	return LeaderboardInfo{ID: b.ID, Name: b.Name, ScoreType: b.ScoreType, Order: b.Order, Reset: b.Reset}
+}
+
+// Период, в который попадает t: ключ и границы (для never — без границ)
+func leaderboardPeriod(reset string, t time.Time) (string, *time.Time, *time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	var start, end time.Time
+	var key string
+	switch reset {
+	case LeaderboardDaily:
+		start, end, key = day, day.AddDate(0, 0, 1), day.Format("2006-01-02")
+	case LeaderboardWeekly:
+		// Неделя по ISO начинается с понедельника
+		start = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		end = start.AddDate(0, 0, 7)
+		year, week := t.ISOWeek()
+		key = fmt.Sprintf("%d-W%02d", year, week)
+	case LeaderboardMonthly:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end, key = start.AddDate(0, 1, 0), start.Format("2006-01")
+	default:
+		return "all", nil, nil
+	}
+	return key, &start, &end
+}
+
+// Проверка результата перед записью. Проверки вызываются по порядку,
+// первая ошибка отклоняет результат; новые проверки добавляются в
+// scoreValidators.
+type scoreValidator func(board Leaderboard, req ScoreValidationRequest, now time.Time) *modError
+
+var scoreValidators = []scoreValidator{validateScoreRange, validateScoreInterval, validateScoreHook}
+
+func validateScoreRange(board Leaderboard, req ScoreValidationRequest, now time.Time) *modError {
+	if (board.MinScore != nil && req.Score < *board.MinScore) || (board.MaxScore != nil && req.Score > *board.MaxScore) {
+		return &modError{http.StatusUnprocessableEntity, ErrCodeScoreOutOfRange, []interface{}{req.Score}}
+	}
+	return nil
+}
+
+func validateScoreInterval(board Leaderboard, req ScoreValidationRequest, now time.Time) *modError {
+	if board.MinInterval > 0 && req.Previous != nil && now.Sub(req.Previous.LastSubmitAt) < time.Duration(board.MinInterval)*time.Second {
+		return &modError{http.StatusTooManyRequests, ErrCodeScoreTooFrequent, []interface{}{board.MinInterval}}
+	}
+	return nil
+}
+
+// Внешняя проверка: тело подписано GAME_SERVER_SECRET, как доставки
+// вебхуков. Недоступная проверка тоже отклоняет результат.
+func validateScoreHook(board Leaderboard, req ScoreValidationRequest, now time.Time) *modError {
+	if board.ValidateURL == "" {
+		return nil
+	}
+	body, _ := json.Marshal(req)
+	mac := hmac.New(sha256.New, []byte(currentConfig().GameServerSecret))
+	mac.Write(body)
+	headers := map[string]string{"X-Loil-Signature": "sha256=" + hex.EncodeToString(mac.Sum(nil))}
+	if err := postJSON(board.ValidateURL, req, headers); err != nil {
+		return &modError{http.StatusUnprocessableEntity, ErrCodeScoreRejected, []interface{}{err}}
+	}
+	return nil
+}
+
+func (req LeaderboardRequest) valid() bool {
+	if req.ValidateURL != "" {
+		if u, err := url.Parse(req.ValidateURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return false
+		}
+	}
+	return req.Name != "" && utf8.RuneCountInString(req.Name) <= maxAchievementName &&
+		slices.Contains(leaderboardScoreTypes, req.ScoreType) && slices.Contains(leaderboardOrders, req.Order) &&
+		slices.Contains(leaderboardResets, req.Reset) && req.MinInterval >= 0 &&
+		(req.MinScore == nil || req.MaxScore == nil || *req.MinScore <= *req.MaxScore)
+}
+
+// Список таблиц: /api/leaderboards
+func (l *Logger) leaderboardsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🥇", "/api/leaderboards", func() {
+		response := LeaderboardsResponse{Leaderboards: []LeaderboardInfo{}}
+		for _, board := range leaderboards.List() {
+			response.Leaderboards = append(response.Leaderboards, board.info())
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Страница таблицы. period: пусто — текущий, previous — прошлый или ключ
+// периода (2025-W14, 2025-04, 2025-04-07)
+func (l *Logger) leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🥇", "/api/leaderboards/{id}", func() {
+		id := r.PathValue("id")
+		board, ok := leaderboards.Get(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeLeaderboardNotFound, id)
+			return
+		}
+		query := r.URL.Query()
+		offset, limit := 0, 50
+		if value := query.Get("offset"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			offset = n
+		}
+		if value := query.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			limit = min(n, maxLeaderboardPage)
+		}
+
+		page := LeaderboardPage{Leaderboard: board.info(), Entries: []RankedEntry{}}
+		page.Period, page.StartsAt, page.EndsAt = leaderboardPeriod(board.Reset, time.Now())
+		switch period := query.Get("period"); {
+		case period == "previous" && page.StartsAt != nil:
+			page.Period, page.StartsAt, page.EndsAt = leaderboardPeriod(board.Reset, page.StartsAt.Add(-time.Nanosecond))
+		case period != "" && period != page.Period:
+			page.Period, page.StartsAt, page.EndsAt = period, nil, nil
+		}
+
+		ranked := leaderboards.Ranked(board, page.Period)
+		page.Total = len(ranked)
+		rankedEntry := func(i int) RankedEntry {
+			entry := RankedEntry{Rank: i + 1, Player: ranked[i].Player, Score: ranked[i].Score, AchievedAt: ranked[i].AchievedAt}
+			if account, ok := accounts.ByUsername(entry.Player); ok {
+				entry.DisplayName = account.DisplayName
+			}
+			return entry
+		}
+		for i := offset; i < len(ranked) && i < offset+limit; i++ {
+			page.Entries = append(page.Entries, rankedEntry(i))
+		}
+		if next := offset + limit; next < len(ranked) {
+			page.NextOffset = &next
+		}
+		if player := query.Get("player"); player != "" {
+			if i := slices.IndexFunc(ranked, func(e LeaderboardEntry) bool { return achievementPlayer(e.Player) == achievementPlayer(player) }); i >= 0 {
+				entry := rankedEntry(i)
+				page.Player = &entry
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+}
+
+// Результат от игрового сервера: POST /api/leaderboards/{id}/scores с
+// Authorization: Bearer GAME_SERVER_SECRET
+func (l *Logger) leaderboardSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🥇", "/api/leaderboards/{id}/scores", func() {
+		if !requireGameServerSecret(w, r) {
+			return
+		}
+		id := r.PathValue("id")
+		var submission ScoreSubmission
+		if err := json.NewDecoder(r.Body).Decode(&submission); err != nil || !playerNamePattern.MatchString(submission.Player) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		board, ok := leaderboards.Get(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeLeaderboardNotFound, id)
+			return
+		}
+
+		now := time.Now().UTC()
+		period, _, _ := leaderboardPeriod(board.Reset, now)
+		validation := ScoreValidationRequest{Leaderboard: board.ID, Period: period, ScoreSubmission: submission}
+		if previous, ok := leaderboards.Entry(board.ID, period, submission.Player); ok {
+			validation.Previous = &previous
+		}
+		for _, validate := range scoreValidators {
+			if apiErr := validate(board, validation, now); apiErr != nil {
+				l.logWarn("Результат %d игрока %s в таблице %s отклонен: %s", submission.Score, submission.Player, board.ID, apiErr.code)
+				writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+				return
+			}
+		}
+
+		response, err := leaderboards.Submit(board, period, submission, currentConfig().LeaderboardKeepPeriods, now)
+		if err != nil {
+			l.logError("Ошибка сохранения результатов таблицы %s: %v", board.ID, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+		if response.Improved {
+			l.logSuccess("Новый рекорд игрока %s в таблице %s: %d (место %d)", submission.Player, board.ID, submission.Score, response.Rank)
+		}
+	})
+}
+
+func (l *Logger) adminListLeaderboardsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🥇", "/admin/api/leaderboards", func() {
+		json.NewEncoder(w).Encode(map[string][]Leaderboard{"leaderboards": append([]Leaderboard{}, leaderboards.List()...)})
+	})
+}
+
+// Создание или изменение таблицы. Смена reset не переносит результаты:
+// они остаются под ключами прежних периодов.
+func (l *Logger) adminPutLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🥇", "/admin/api/leaderboards/{id}", func() {
+		id := r.PathValue("id")
+		req := LeaderboardRequest{ScoreType: "points", Order: LeaderboardDesc, Reset: LeaderboardNever}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !modIDPattern.MatchString(id) || !req.valid() {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		saved := Leaderboard{
+			ID: id, Name: req.Name, ScoreType: req.ScoreType, Order: req.Order, Reset: req.Reset,
+			MinScore: req.MinScore, MaxScore: req.MaxScore, MinInterval: req.MinInterval, ValidateURL: req.ValidateURL,
+			CreatedAt: now, UpdatedAt: now,
+		}
+		apiErr, err := leaderboards.update(func(list []Leaderboard) ([]Leaderboard, *modError) {
+			if i := findLeaderboard(list, id); i >= 0 {
+				saved.CreatedAt = list[i].CreatedAt
+				list[i] = saved
+				return list, nil
+			}
+			return append(list, saved), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(saved)
+		l.logSuccess("Таблица рекордов %s «%s» сохранена", id, req.Name)
+	})
+}
+
+func (l *Logger) adminDeleteLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🥇", "/admin/api/leaderboards/{id}", func() {
+		id := r.PathValue("id")
+		found, err := leaderboards.Delete(id)
+		if err != nil {
+			l.logError("Ошибка удаления таблицы рекордов %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeLeaderboardNotFound, id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Таблица рекордов %s удалена", id)
+	})
+}
+
+// Удаление результатов нечестного игрока: ?period= — только за период
+func (l *Logger) adminRemoveScoreHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🥇", "/admin/api/leaderboards/{id}/scores/{player}", func() {
+		id, player := r.PathValue("id"), r.PathValue("player")
+		if _, ok := leaderboards.Get(id); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeLeaderboardNotFound, id)
+			return
+		}
+		found, err := leaderboards.Remove(id, player, r.URL.Query().Get("period"))
+		if err != nil {
+			l.logError("Ошибка сохранения результатов таблицы %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeScoreNotFound, player)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Результаты игрока %s удалены из таблицы %s", player, id)
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Первый дескриптор, переданный systemd (SD_LISTEN_FDS_START)
+const systemdListenFDsStart = 3
+
+// Адрес публичного слушателя из LISTEN: host:port, tcp://host:port,
+// unix:/path/to.sock или systemd — сокеты, переданные systemd при
+// активации по сокету
+type listenAddr struct {
+	Network string
+	Address string
+}
+
+func (a listenAddr) String() string {
+	if a.Network == "systemd" {
+		return "systemd"
+	}
+	return a.Network + "://" + a.Address
+}
+
+func parseListenAddr(value string) (listenAddr, error) {
+	switch {
+	case value == "systemd":
+		return listenAddr{Network: "systemd"}, nil
+	case strings.HasPrefix(value, "unix:"):
+		path := strings.TrimPrefix(strings.TrimPrefix(value, "unix:"), "//")
+		if path == "" {
+			return listenAddr{}, fmt.Errorf("не указан путь сокета в %q", value)
+		}
+		return listenAddr{Network: "unix", Address: path}, nil
+	}
+	address := strings.TrimPrefix(value, "tcp://")
+	if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+		return listenAddr{}, fmt.Errorf("нужен адрес host:port, получено %q", value)
+	}
+	return listenAddr{Network: "tcp", Address: address}, nil
+}
+
+// Сокеты, переданные systemd (LISTEN_PID, LISTEN_FDS). Пока они открыты,
+// systemd держит порт и копит подключения, поэтому перезапуск сервера за
+// nginx проходит без отказов.
+func systemdListeners() ([]net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, fmt.Errorf("сокеты от systemd не переданы (нет LISTEN_PID этого процесса)")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("некорректный LISTEN_FDS=%q", os.Getenv("LISTEN_FDS"))
+	}
+	// Дочерним процессам сокеты не предназначены
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := systemdListenFDsStart; fd < systemdListenFDsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "systemd-"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("дескриптор %d: %v", fd, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// Открытие всех адресов из LISTEN; без LISTEN — SERVER_PORT на всех
+// интерфейсах, как раньше
+func openListeners(cfg *Config) ([]net.Listener, error) {
+	addrs := cfg.Listen
+	if len(addrs) == 0 {
+		addrs = []listenAddr{{Network: "tcp", Address: ":" + cfg.ServerPort}}
+	}
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}
+	for _, addr := range addrs {
+		switch addr.Network {
+		case "systemd":
+			inherited, err := systemdListeners()
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			listeners = append(listeners, inherited...)
+			continue
+		case "unix":
+			// Сокет, оставшийся от прошлого запуска, мешает bind
+			if info, err := os.Lstat(addr.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+				os.Remove(addr.Address)
+			}
+		}
+
+		listener, err := net.Listen(addr.Network, addr.Address)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("%s: %v", addr, err)
+		}
+		if addr.Network == "unix" {
+			// Доступ к сокету — как к порту на localhost; ограничивается
+			// правами каталога сокета
+			os.Chmod(addr.Address, 0666)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Уровень подробности лога сервера
+type LogLevel int32
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func parseLogLevel(name string) (LogLevel, bool) {
+	i := slices.Index(logLevelNames, strings.ToLower(name))
+	return LogLevel(i), i >= 0
+}
+
+func (level LogLevel) String() string {
+	return logLevelNames[level]
+}
+
+const (
+	// Сколько по умолчанию и сколько самое большее длится отладка адресов:
+	// забытая отладка не должна навсегда раздувать лог
+	debugTraceDefault = time.Hour
+	debugTraceMax     = 24 * time.Hour
+)
+
+// Заголовки, значения которых не выводятся в отладочный лог
+var redactedHeaders = map[string]bool{
+	"Authorization":   true,
+	"Cookie":          true,
+	"Set-Cookie":      true,
+	"X-Api-Key":       true,
+	"X-Admin-Session": true,
+}
+
+// Отладка отдельных адресов: запросы с них логируются целиком
+// (метаданные запроса и ответа) при любом уровне лога
+type DebugTrace struct {
+	IPs   []string  `json:"ips"`
+	Until time.Time `json:"until"`
+}
+
+type LoggingResponse struct {
+	Level      string      `json:"level"`
+	DebugTrace *DebugTrace `json:"debug_trace"`
+}
+
+// Пустой уровень не меняется; пустой список адресов выключает отладку
+type LoggingRequest struct {
+	Level    string   `json:"level"`
+	DebugIPs []string `json:"debug_ips"`
+	// Длительность отладки, например 30m; по умолчанию час
+	DebugFor string `json:"debug_for"`
+}
+
+// Уровень из LOG_LEVEL; из админки меняется до перезагрузки конфигурации
+var (
+	logLevel   atomic.Int32
+	debugTrace atomic.Pointer[DebugTrace]
+)
+
+func setLogLevel(level LogLevel) {
+	logLevel.Store(int32(level))
+}
+
+func currentLogLevel() LogLevel {
+	return LogLevel(logLevel.Load())
+}
+
+// Отлаживается ли адрес сейчас
+func debugTraced(ip string) bool {
+	trace := debugTrace.Load()
+	return trace != nil && time.Now().Before(trace.Until) && slices.Contains(trace.IPs, ip)
+}
+
+func (l *Logger) logf(level LogLevel, format string, v ...interface{}) {
+	if level < currentLogLevel() {
+		return
+	}
+	l.Logger.Printf(format, v...)
+}
+
+// Обычные сообщения сервера идут с уровнем info
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.logf(LogLevelInfo, format, v...)
+}
+
+func (l *Logger) Println(v ...interface{}) {
+	if LogLevelInfo >= currentLogLevel() {
+		l.Logger.Println(v...)
+	}
+}
+
+// Заголовки одной строкой, секретные — без значений
+func formatHeaders(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		if redactedHeaders[key] {
+			value = "***"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %q", key, value))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Запрос с отлаживаемого адреса
+func (l *Logger) traceRequest(r *http.Request) {
+	l.Logger.Printf("🐞 [%s] → %s %s %s от %s (%s), %d bytes; %s",
+		requestID(r), r.Method, r.URL.RequestURI(), r.Proto, getClientIP(r), r.RemoteAddr, r.ContentLength, formatHeaders(r.Header))
+}
+
+// Ответ на запрос с отлаживаемого адреса
+func (l *Logger) traceResponse(r *http.Request, recorder *statusRecorder, elapsed time.Duration) {
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	l.Logger.Printf("🐞 [%s] ← %d за %v, %d bytes; %s",
+		requestID(r), status, elapsed.Round(time.Microsecond), recorder.written, formatHeaders(recorder.Header()))
+}
+
+func (l *Logger) adminGetLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🐞", "/admin/api/logging", func() {
+		json.NewEncoder(w).Encode(currentLogging())
+	})
+}
+
+func currentLogging() LoggingResponse {
+	response := LoggingResponse{Level: currentLogLevel().String()}
+	if trace := debugTrace.Load(); trace != nil && time.Now().Before(trace.Until) {
+		response.DebugTrace = trace
+	}
+	return response
+}
+
+// Смена уровня лога и отладка адресов без перезапуска
+func (l *Logger) adminSetLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🐞", "/admin/api/logging", func() {
+		var req LoggingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		level, ok := currentLogLevel(), true
+		if req.Level != "" {
+			level, ok = parseLogLevel(req.Level)
+		}
+		duration := debugTraceDefault
+		if req.DebugFor != "" {
+			var err error
+			if duration, err = time.ParseDuration(req.DebugFor); err != nil || duration <= 0 || duration > debugTraceMax {
+				ok = false
+			}
+		}
+		for i, ip := range req.DebugIPs {
+			parsed := net.ParseIP(ip)
+			if parsed == nil {
+				ok = false
+				continue
+			}
+			req.DebugIPs[i] = parsed.String()
+		}
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		setLogLevel(level)
+		if len(req.DebugIPs) == 0 {
+			debugTrace.Store(nil)
+		} else {
+			debugTrace.Store(&DebugTrace{IPs: req.DebugIPs, Until: time.Now().Add(duration).UTC()})
+		}
+
+		json.NewEncoder(w).Encode(currentLogging())
+		// Смена уровня видна в логе при любом уровне
+		l.Logger.Printf("✅ Уровень лога: %s, отладка адресов: %v", level, req.DebugIPs)
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сколько помним неудачные входы после последней ошибки
+const loginFailureWindow = 24 * time.Hour
+
+// Неудачные входы по имени аккаунта или адресу клиента. После
+// LOGIN_*_MAX_FAILURES ошибок вход блокируется, и каждая следующая ошибка
+// удваивает блокировку: от LOGIN_LOCKOUT_BASE до LOGIN_LOCKOUT_MAX.
+// С REDIS_URL счетчики общие для всех экземпляров.
+type loginFailures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+type LoginGuard struct {
+	mu      sync.Mutex
+	entries map[string]*loginFailures
+}
+
+var loginGuard = &LoginGuard{entries: make(map[string]*loginFailures)}
+
+var captchaClient = &http.Client{Timeout: 10 * time.Second}
+
+// Ключи учета: имя аккаунта (даже несуществующего, чтобы ответ не выдавал,
+// есть ли аккаунт) и адрес клиента
+func loginSubjects(username string, r *http.Request) (string, string) {
+	return "account:" + strings.ToLower(username), "ip:" + getClientIP(r)
+}
+
+func (g *LoginGuard) entry(subject string, now time.Time) *loginFailures {
+	entry, ok := g.entries[subject]
+	if ok && now.Sub(entry.lastFailure) >= loginFailureWindow {
+		delete(g.entries, subject)
+		return nil
+	}
+	return entry
+}
+
+// Сколько ждать до следующей попытки и сколько ошибок уже накоплено
+func (g *LoginGuard) Status(subject string, now time.Time) (time.Duration, int) {
+	if sharedState != nil {
+		return sharedState.loginStatus(subject, now)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entry := g.entry(subject, now)
+	if entry == nil {
+		return 0, 0
+	}
+	return max(entry.lockedUntil.Sub(now), 0), entry.count
+}
+
+// Учет ошибки; limit 0 отключает блокировку для этого вида ключей
+func (g *LoginGuard) Fail(cfg *Config, subject string, limit int, now time.Time) {
+	if sharedState != nil {
+		sharedState.loginFail(cfg, subject, limit, now)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entry := g.entry(subject, now)
+	if entry == nil {
+		for key, e := range g.entries {
+			if now.Sub(e.lastFailure) >= loginFailureWindow {
+				delete(g.entries, key)
+			}
+		}
+		entry = &loginFailures{}
+		g.entries[subject] = entry
+	}
+	entry.count++
+	entry.lastFailure = now
+	if limit > 0 && entry.count >= limit {
+		lockout := cfg.LoginLockoutBase << min(entry.count-limit, 20)
+		entry.lockedUntil = now.Add(min(lockout, cfg.LoginLockoutMax))
+	}
+}
+
+func (g *LoginGuard) Reset(subject string) {
+	if sharedState != nil {
+		if _, err := sharedState.Do("DEL", sharedState.key("login", subject)); err != nil {
+			sharedState.logError("login", err)
+		}
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.entries, subject)
+}
+
+// Те же счетчики в Redis: хэш count/locked_until (мс Unix), который
+// живет loginFailureWindow после последней ошибки
+func (c *RedisClient) loginStatus(subject string, now time.Time) (time.Duration, int) {
+	reply, err := c.Do("HMGET", c.key("login", subject), "count", "locked_until")
+	if err != nil {
+		c.logError("login", err)
+		return 0, 0
+	}
+	values, _ := reply.([]any)
+	if len(values) != 2 {
+		return 0, 0
+	}
+	lockedUntil := time.UnixMilli(redisInt(values[1]))
+	return max(lockedUntil.Sub(now), 0), int(redisInt(values[0]))
+}
+
+func (c *RedisClient) loginFail(cfg *Config, subject string, limit int, now time.Time) {
+	key := c.key("login", subject)
+	reply, err := c.Do("HINCRBY", key, "count", "1")
+	if err != nil {
+		c.logError("login", err)
+		return
+	}
+	if count := int(redisInt(reply)); limit > 0 && count >= limit {
+		lockout := cfg.LoginLockoutBase << min(count-limit, 20)
+		lockedUntil := now.Add(min(lockout, cfg.LoginLockoutMax)).UnixMilli()
+		if _, err := c.Do("HSET", key, "locked_until", strconv.FormatInt(lockedUntil, 10)); err != nil {
+			c.logError("login", err)
+		}
+	}
+	if _, err := c.Do("PEXPIRE", key, strconv.FormatInt(loginFailureWindow.Milliseconds(), 10)); err != nil {
+		c.logError("login", err)
+	}
+}
+
+// Проверка CAPTCHA через siteverify провайдера (hCaptcha, Turnstile,
+// reCAPTCHA — у всех одинаковый протокол)
+func verifyCaptcha(ctx context.Context, cfg *Config, token, remoteIP string) (bool, error) {
+	form := url.Values{"secret": {cfg.CaptchaSecret}, "response": {token}, "remoteip": {remoteIP}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.CaptchaVerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := captchaClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Success, nil
+}
+
+// Проверка перед входом: блокировка по аккаунту или адресу, затем
+// CAPTCHA, если ошибок уже LOGIN_CAPTCHA_AFTER. При отказе ответ уже
+// записан; для CAPTCHA ключ сайта передается в X-Captcha-Site-Key.
+func (l *Logger) rejectLoginAttempt(w http.ResponseWriter, r *http.Request, username, captchaToken string) bool {
+	if apiErr := l.checkLoginAttempt(r, username, captchaToken); apiErr != nil {
+		writeModError(w, r, apiErr)
+		return true
+	}
+	return false
+}
+
+func (l *Logger) checkLoginAttempt(r *http.Request, username, captchaToken string) *modError {
+	cfg := currentConfig()
+	now := time.Now()
+	accountSubject, ipSubject := loginSubjects(username, r)
+	accountWait, accountFailures := loginGuard.Status(accountSubject, now)
+	ipWait, ipFailures := loginGuard.Status(ipSubject, now)
+
+	if wait := max(accountWait, ipWait); wait > 0 {
+		seconds := int(wait.Seconds()) + 1
+		l.logError("Вход в аккаунт %s с %s заблокирован еще на %d с", username, getClientIP(r), seconds)
+		return &modError{http.StatusTooManyRequests, ErrCodeLoginLocked, []interface{}{seconds}}
+	}
+
+	if cfg.CaptchaSecret == "" || max(accountFailures, ipFailures) < cfg.LoginCaptchaAfter {
+		return nil
+	}
+	return l.checkCaptcha(r, captchaToken)
+}
+
+// Проверка токена CAPTCHA у провайдера; nil — пройдена
+func (l *Logger) checkCaptcha(r *http.Request, captchaToken string) *modError {
+	if captchaToken == "" {
+		return &modError{http.StatusUnauthorized, ErrCodeCaptchaRequired, nil}
+	}
+	ok, err := verifyCaptcha(r.Context(), currentConfig(), captchaToken, getClientIP(r))
+	if err != nil {
+		l.logError("Ошибка проверки CAPTCHA: %v", err)
+		return &modError{http.StatusBadGateway, ErrCodeCaptchaUnavailable, nil}
+	}
+	if !ok {
+		return &modError{http.StatusUnauthorized, ErrCodeCaptchaInvalid, nil}
+	}
+	return nil
+}
+
+// Учет неудачного входа по аккаунту и адресу
+func recordLoginFailure(username string, r *http.Request) {
+	cfg := currentConfig()
+	now := time.Now()
+	accountSubject, ipSubject := loginSubjects(username, r)
+	loginGuard.Fail(cfg, accountSubject, cfg.LoginAccountMaxFailures, now)
+	loginGuard.Fail(cfg, ipSubject, cfg.LoginIPMaxFailures, now)
+}
+
+// Снятие блокировки входа в аккаунт, например по просьбе игрока
+func (l *Logger) adminUnlockAccountHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🔒", "/admin/api/accounts/{username}/lockout", func() {
+		account, ok := adminAccountFromPath(w, r)
+		if !ok {
+			return
+		}
+		accountSubject, _ := loginSubjects(account.Username, r)
+		loginGuard.Reset(accountSubject)
+		codeAttempts.Reset("account:" + account.ID)
+
+		w.WriteHeader(http.StatusNoContent)
+		actor, _ := adminActor(r, "")
+		l.logSuccess("Снята блокировка входа в аккаунт %s (%s)", account.Username, actor)
+	})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Приемники журнала доступа из LOG_SINKS
+var logSinkNames = []string{"file", "syslog", "loki", "elasticsearch"}
+
+const (
+	// Сколько раз повторять отправку пачки и пауза перед первым повтором;
+	// пауза удваивается
+	logShipRetries      = 3
+	logShipRetryBackoff = time.Second
+	logShipTimeout      = 10 * time.Second
+)
+
+// Запись журнала доступа
+type AccessLogEntry struct {
+	Time     time.Time `json:"@timestamp"`
+	Instance string    `json:"instance"`
+	IP       string    `json:"ip"`
+	Endpoint string    `json:"endpoint"`
+	Emoji    string    `json:"emoji"`
+}
+
+// Строка записи для файла и syslog
+func (e AccessLogEntry) line() string {
+	return fmt.Sprintf("%s %s - %s", e.IP, e.Endpoint, e.Emoji)
+}
+
+// Приемник журнала доступа. Write не должен задерживать запрос: медленные
+// приемники копят записи в своей очереди.
+type accessLogSink interface {
+	Write(entry AccessLogEntry)
+}
+
+// Приемники задаются один раз при запуске; смена LOG_SINKS требует
+// перезапуска
+var accessLogSinks atomic.Pointer[[]accessLogSink]
+
+func (l *Logger) startLogSinks(cfg *Config) error {
+	var sinks []accessLogSink
+	for _, name := range cfg.LogSinks {
+		switch name {
+		case "file":
+			sinks = append(sinks, fileLogSink{logger: l})
+		case "syslog":
+			sink, err := newSyslogSink(cfg)
+			if err != nil {
+				return fmt.Errorf("ошибка подключения к syslog: %v", err)
+			}
+			sinks = append(sinks, sink)
+		case "loki", "elasticsearch":
+			shipper := &logShipper{
+				logger:        l,
+				format:        name,
+				url:           cfg.LogShipURL,
+				auth:          cfg.LogShipAuth,
+				instance:      cfg.LogInstance,
+				batchSize:     cfg.LogShipBatchSize,
+				flushInterval: cfg.LogShipFlushInterval,
+				queue:         make(chan AccessLogEntry, cfg.LogShipQueueSize),
+				client:        &http.Client{Timeout: logShipTimeout},
+			}
+			go shipper.run()
+			sinks = append(sinks, shipper)
+		}
+	}
+	accessLogSinks.Store(&sinks)
+	return nil
+}
+
+// Запись обращения во все приемники журнала доступа
+func (l *Logger) logAccess(clientIP, endpoint, emoji string) {
+	sinks := accessLogSinks.Load()
+	if sinks == nil {
+		return
+	}
+	entry := AccessLogEntry{
+		Time:     time.Now(),
+		Instance: currentConfig().LogInstance,
+		IP:       clientIP,
+		Endpoint: endpoint,
+		Emoji:    emoji,
+	}
+	for _, sink := range *sinks {
+		sink.Write(entry)
+	}
+}
+
+// Файл logs/access_<дата>.log
+type fileLogSink struct {
+	logger *Logger
+}
+
+func (s fileLogSink) Write(entry AccessLogEntry) {
+	logDir := "logs"
+	logFile := filepath.Join(logDir, fmt.Sprintf("access_%s.log", entry.Time.Format("2006-01-02")))
+
+	// Создаем директорию если не существует
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		s.logger.logError("Ошибка создания директории логов: %v", err)
+		return
+	}
+
+	// Открываем файл для добавления логов
+	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		s.logger.logError("Ошибка открытия файла логов: %v", err)
+		return
+	}
+	defer file.Close()
+
+	logEntry := fmt.Sprintf("[%s] %s\n", entry.Time.Format("2006-01-02 15:04:05"), entry.line())
+	if _, err := file.WriteString(logEntry); err != nil {
+		s.logger.logError("Ошибка записи в файл логов: %v", err)
+	}
+}
+
+// Отправка журнала в Loki или Elasticsearch пачками по LOG_SHIP_BATCH_SIZE
+// записей или раз в LOG_SHIP_FLUSH_INTERVAL. Пока пачка отправляется
+// (с повторами), новые записи ждут в очереди; переполненная очередь не
+// задерживает запросы — записи отбрасываются и учитываются в логе.
+type logShipper struct {
+	logger        *Logger
+	format        string
+	url           string
+	auth          string
+	instance      string
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan AccessLogEntry
+	client        *http.Client
+	dropped       atomic.Int64
+}
+
+func (s *logShipper) Write(entry AccessLogEntry) {
+	select {
+	case s.queue <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *logShipper) run() {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]AccessLogEntry, 0, s.batchSize)
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) < s.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if dropped := s.dropped.Swap(0); dropped > 0 {
+				s.logger.logWarn("Очередь журнала для %s переполнена, отброшено записей: %d", s.format, dropped)
+			}
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		s.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (s *logShipper) flush(batch []AccessLogEntry) {
+	body, contentType, err := s.encode(batch)
+	if err != nil {
+		s.logger.logError("Ошибка подготовки журнала для %s: %v", s.format, err)
+		return
+	}
+
+	backoff := logShipRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err = s.send(body, contentType)
+		if err == nil {
+			return
+		}
+		if attempt == logShipRetries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	s.logger.logError("Журнал не отправлен в %s, отброшено записей: %d: %v", s.format, len(batch), err)
+}
+
+// Тело запроса: push API Loki или _bulk Elasticsearch
+func (s *logShipper) encode(batch []AccessLogEntry) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if s.format == "elasticsearch" {
+		for _, entry := range batch {
+			buf.WriteString("{\"index\":{}}\n")
+			doc, err := json.Marshal(entry)
+			if err != nil {
+				return nil, "", err
+			}
+			buf.Write(doc)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+
+	values := make([][2]string, 0, len(batch))
+	for _, entry := range batch {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, "", err
+		}
+		values = append(values, [2]string{strconv.FormatInt(entry.Time.UnixNano(), 10), string(line)})
+	}
+	err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"streams": []map[string]interface{}{{
+			"stream": map[string]string{"job": "loil-launcher", "instance": s.instance},
+			"values": values,
+		}},
+	})
+	return buf.Bytes(), "application/json", err
+}
+
+func (s *logShipper) send(body []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.auth != "" {
+		req.Header.Set("Authorization", s.auth)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ответ %s", resp.Status)
+	}
+	// _bulk отвечает 200 и при ошибках отдельных записей
+	if s.format == "elasticsearch" {
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err == nil && result.Errors {
+			return fmt.Errorf("Elasticsearch отклонил часть записей")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"log/syslog"
+	"strings"
+)
+
+// Журнал доступа в syslog: локальный демон или удаленный по SYSLOG_ADDR
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+func newSyslogSink(cfg *Config) (accessLogSink, error) {
+	var network, addr string
+	if cfg.SyslogAddr != "" {
+		network, addr, _ = strings.Cut(cfg.SyslogAddr, "://")
+	}
+	writer, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, cfg.SyslogTag)
+	if err != nil {
+		return nil, err
+	}
+	return syslogSink{writer: writer}, nil
+}
+
+// Ошибка записи не выводится в лог: при недоступном syslog она была бы
+// на каждый запрос, а log/syslog сам переподключается
+func (s syslogSink) Write(entry AccessLogEntry) {
+	s.writer.Info(entry.line())
+}
@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package main
+
+import "errors"
+
+func newSyslogSink(cfg *Config) (accessLogSink, error) {
+	return nil, errors.New("syslog недоступен на этой платформе")
+}
@@ -4,29 +4,21 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
-// Структура для конфигурации
-type Config struct {
-	ServerPort      string
-	LauncherClient  string
-	GameClient      string
-	LauncherVersion string
-	GameVersion     string
-	ClientsDir      string
-}
-
 // Структура для новостей
 type NewsItem struct {
 	ID      int    `json:"id"`
@@ -34,6 +26,28 @@ type NewsItem struct {
 	Content string `json:"content"`
 	Image   string `json:"image"` // имя JPG файла
 	Date    string `json:"date"`
+
+	// Статус публикации (draft/scheduled/published/expired) и расписание
+	Status    string     `json:"status,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Закрепленные новости идут первыми; категория (patch, event, ...) и
+	// теги — для фильтров в лаунчере
+	Pinned   bool     `json:"pinned,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// Переводы по кодам языков (en, ru, ...); в публичный ответ не попадают
+	Translations map[string]NewsTranslation `json:"translations,omitempty"`
+	// Язык, на котором отдана новость
+	Language string `json:"lang,omitempty"`
+
+	// Заполняется только при запросе с format=html
+	RenderedHTML string `json:"rendered_html,omitempty"`
+
+	// Просмотры и реакции игроков; только в публичном ответе
+	Engagement *NewsEngagement `json:"engagement,omitempty"`
 }
 
 type NewsResponse struct {
@@ -43,6 +57,13 @@ type NewsResponse struct {
 type VersionResponse struct {
 	LauncherVersion string `json:"launcher_version"`
 	GameVersion     string `json:"game_version"`
+	Maintenance     bool   `json:"maintenance"`
+	LauncherBuild   int    `json:"launcher_build,omitempty"`
+	GameBuild       int    `json:"game_build,omitempty"`
+
+	BlockedVersions BlockedVersions `json:"blocked_versions"`
+	// Версии клиента из запроса заблокированы, запускать нельзя
+	MustUpdate bool `json:"must_update"`
 }
 
 type FileInfoResponse struct {
@@ -56,12 +77,17 @@ type Logger struct {
 	*log.Logger
 }
 
-var config Config
-
 func main() {
+	if err := runCLI(os.Args[1:]); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+}
+
+// Команда serve: запуск HTTP-сервера
+func runServe(args []string) error {
 	// Загружаем конфигурацию
-	if err := loadConfig(); err != nil {
-		log.Fatalf("❌ Ошибка загрузки конфигурации: %v", err)
+	if err := loadConfig(args); err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации: %v", err)
 	}
 
 	// Создаем логгер с префиксом и датой
@@ -69,84 +95,702 @@ func main() {
 		Logger: log.New(os.Stdout, "[LAUNCHER] ", log.Ldate|log.Ltime),
 	}
 
-	// Статика для изображений
-	http.Handle("/images/", http.StripPrefix("/images/", http.FileServer(http.Dir("./images"))))
+	setLogLevel(currentConfig().LogLevel)
+	logger.printConfigReport()
+	if err := logger.startLogSinks(currentConfig()); err != nil {
+		return err
+	}
 
-	// API эндпоинты с логированием
-	http.HandleFunc("/api/news", logger.newsHandler)
-	http.HandleFunc("/api/version", logger.versionHandler)
-	http.HandleFunc("/api/download/launcher", logger.downloadLauncherHandler)
-	http.HandleFunc("/api/download/game", logger.downloadGameHandler)
+	// Общее состояние с другими экземплярами
+	if cfg := currentConfig(); cfg.RedisURL != "" {
+		client, err := newRedisClient(cfg, logger)
+		if err != nil {
+			return fmt.Errorf("ошибка подключения к Redis: %v", err)
+		}
+		sharedState = client
+		sessions.active = &redisSessions{client}
+	}
+	initMaintenance(currentConfig())
 
-	// Запуск сервера
-	port := ":" + config.ServerPort
-	logger.Printf("Сервер лаунчера запущен на http://localhost%s", port)
-	logger.Println("Готов к приему запросов...")
-	log.Fatal(http.ListenAndServe(port, nil))
-}
+	// Ключи админского API
+	if err := adminKeys.Load(filepath.Join(currentConfig().DataDir, "admin_keys.json")); err != nil {
+		return fmt.Errorf("ошибка загрузки ключей админского API: %v", err)
+	}
 
-// Загрузка конфигурации из .env файла
-func loadConfig() error {
-	if err := godotenv.Load(); err != nil {
-		return fmt.Errorf("ошибка загрузки .env файла: %v", err)
+	// Хэши файлов с прошлого запуска
+	if err := clientIndex.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки индекса хэшей: %v", err)
 	}
 
-	config = Config{
-		ServerPort:      getEnv("SERVER_PORT", "8080"),
-		LauncherClient:  getEnv("LAUNCHER_CLIENT_FILE", "launcher.exe"),
-		GameClient:      getEnv("GAME_CLIENT_FILE", "Loil.exe"),
-		LauncherVersion: getEnv("LAUNCHER_VERSION", "0.0.0"),
-		GameVersion:     getEnv("GAME_VERSION", "0.0.0"),
-		ClientsDir:      getEnv("CLIENTS_DIR", "clients"),
+	// Номера сборок
+	if err := loadBuilds(); err != nil {
+		return fmt.Errorf("ошибка загрузки номеров сборок: %v", err)
+	}
+	if err := loadTorrentMeta(); err != nil {
+		return fmt.Errorf("ошибка загрузки сведений о .torrent: %v", err)
 	}
 
-	return nil
-}
+	// Другие игры на этом сервере
+	if err := projects.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки проектов: %v", err)
+	}
+
+	// Заблокированные версии клиентов
+	if err := loadBlockedVersions(); err != nil {
+		return fmt.Errorf("ошибка загрузки заблокированных версий: %v", err)
+	}
+	// Точка отсчета для событий /api/events: объявляются только изменения
+	eventHub.PublishVersion()
+
+	// Рантаймы для разных платформ
+	if err := runtimes.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки списка рантаймов: %v", err)
+	}
+
+	// Репозиторий модов
+	if err := mods.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки списка модов: %v", err)
+	}
+	if err := resourcePacks.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки списка ресурспаков: %v", err)
+	}
+	if err := modpacks.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки профилей сборок: %v", err)
+	}
+	if err := downloadUsage.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки учета трафика: %v", err)
+	}
+	if err := artifactDownloads.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки времени скачиваний: %v", err)
+	}
+	if err := webhooks.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки вебхуков: %v", err)
+	}
+	if err := mirrors.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки зеркал: %v", err)
+	}
+	if err := motd.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки объявлений: %v", err)
+	}
+	if err := launchProfiles.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки профилей запуска: %v", err)
+	}
+	if err := releases.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки релизов: %v", err)
+	}
+	if err := changelogs.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки описаний изменений: %v", err)
+	}
+	if err := hardwareSurvey.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки опроса о железе: %v", err)
+	}
+	if err := achievements.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки достижений: %v", err)
+	}
+	if err := cheatReports.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки жалоб античита: %v", err)
+	}
+	if err := hwids.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки отпечатков железа: %v", err)
+	}
+	if err := pushDevices.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки устройств push-уведомлений: %v", err)
+	}
+	if err := inbox.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки входящих: %v", err)
+	}
+	if err := friends.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки друзей: %v", err)
+	}
+	if err := leaderboards.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки таблиц рекордов: %v", err)
+	}
+	if err := newsEngagement.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки счетчиков новостей: %v", err)
+	}
+	if err := feedback.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки отзывов: %v", err)
+	}
+	if err := loadStaging(); err != nil {
+		return fmt.Errorf("ошибка загрузки тестового канала: %v", err)
+	}
+	if err := blobs.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки хранилища блобов: %v", err)
+	}
+	if err := adminUploads.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки списка загрузок по частям: %v", err)
+	}
+
+	// Баны и белый список игроков
+	if err := playerLists.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки списков игроков: %v", err)
+	}
+
+	// Правила сервера и согласия игроков
+	if err := loadEULA(); err != nil {
+		return fmt.Errorf("ошибка загрузки правил: %v", err)
+	}
+	if err := eulaAcceptances.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки согласий с правилами: %v", err)
+	}
+
+	// Аккаунты игроков и ключ подписи их токенов
+	if err := accounts.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки аккаунтов: %v", err)
+	}
+	if err := logger.loadAccountTokenKey(currentConfig()); err != nil {
+		return fmt.Errorf("ошибка загрузки ключа токенов: %v", err)
+	}
+	if err := accountSessions.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки сессий аккаунтов: %v", err)
+	}
+	if err := loadEmailTokenSecret(); err != nil {
+		return fmt.Errorf("ошибка загрузки секрета писем: %v", err)
+	}
+
+	// Скриншоты галереи
+	if err := screenshots.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки скриншотов: %v", err)
+	}
+
+	// Автоматические блокировки адресов
+	if err := ipGuard.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки блокировок адресов: %v", err)
+	}
+
+	// Промокоды и их активации
+	if err := promoCodes.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки промокодов: %v", err)
+	}
+
+	// Права аккаунтов, выданные из админки
+	if err := entitlementGrants.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки прав аккаунтов: %v", err)
+	}
+
+	// Накопленное время игры
+	if err := sessions.Load(); err != nil {
+		return fmt.Errorf("ошибка загрузки времени игры: %v", err)
+	}
+
+	// Удаленная конфигурация лаунчера
+	if err := loadLauncherConfig(); err != nil {
+		return fmt.Errorf("ошибка загрузки конфигурации лаунчера: %v", err)
+	}
+	if err := loadLauncherRollout(); err != nil {
+		return fmt.Errorf("ошибка загрузки раскатки лаунчера: %v", err)
+	}
+	if err := loadKillSwitches(); err != nil {
+		return fmt.Errorf("ошибка загрузки выключателей: %v", err)
+	}
+
+	// Ключ подписи релизов
+	if err := logger.loadReleaseSigner(currentConfig()); err != nil {
+		return fmt.Errorf("ошибка загрузки ключа подписи: %v", err)
+	}
+
+	router := NewRouter(logger)
+	router.FilterIPs()
+	router.ProjectPaths()
+	router.ReadOnlyOnReplica()
+	logger.registerPublicRoutes(router)
+
+	// gRPC для лаунчеров со сгенерированными клиентами: версии, новости,
+	// манифесты и вход поверх тех же функций, что и REST
+	if currentConfig().GRPCEnabled {
+		logger.registerGRPC(router)
+	}
+
+	// Админский API живет на отдельном слушателе (по умолчанию только
+	// localhost), публичный порт его вообще не маршрутизирует
+	adminRouter := NewRouter(logger)
+	adminRouter.ReadOnlyOnReplica()
+	adminRouter.Handle("GET /admin/{$}", dashboardHandler())
+	adminRouter.Handle("GET /admin/assets/", dashboardHandler())
+	adminRouter.Handle("GET /admin/docs/", dashboardHandler())
+	admin := adminRouter.Group("/admin/api")
+	// Сборки, рантаймы и моды больше общего лимита тела запроса
+	uploads := admin.WithBodyLimit(uploadBodyLimit)
+	admin.HandleFunc("GET /news", logger.adminListNewsHandler)
+	admin.HandleFunc("POST /news", logger.adminCreateNewsHandler)
+	admin.HandleFunc("PUT /news/{id}", logger.adminUpdateNewsHandler)
+	admin.HandleFunc("DELETE /news/{id}", logger.adminDeleteNewsHandler)
+	admin.HandleFunc("PUT /news/{id}/status", logger.adminNewsStatusHandler)
+	uploads.HandleFunc("PUT /images/{name}", logger.adminUploadImageHandler)
+	admin.HandleFunc("GET /motd", logger.adminListMOTDHandler)
+	admin.HandleFunc("POST /motd", logger.adminCreateMOTDHandler)
+	admin.HandleFunc("PUT /motd/{id}", logger.adminUpdateMOTDHandler)
+	admin.HandleFunc("DELETE /motd/{id}", logger.adminDeleteMOTDHandler)
+	admin.HandleFunc("GET /maintenance", logger.adminGetMaintenanceHandler)
+	admin.HandleFunc("PUT /maintenance", logger.adminSetMaintenanceHandler)
+	admin.HandleFunc("GET /ip-bans", logger.adminIPBansHandler)
+	admin.HandleFunc("DELETE /ip-bans/{ip}", logger.adminLiftIPBanHandler)
+	admin.HandleFunc("GET /blocked-versions", logger.adminGetBlockedVersionsHandler)
+	admin.HandleFunc("PUT /blocked-versions", logger.adminSetBlockedVersionsHandler)
+	admin.HandleFunc("GET /launcher-config", logger.adminGetLauncherConfigHandler)
+	admin.HandleFunc("PUT /launcher-config", logger.adminSetLauncherConfigHandler)
+	admin.HandleFunc("GET /switches", logger.adminKillSwitchesHandler)
+	admin.HandleFunc("PUT /switches/{name}", logger.adminSetKillSwitchHandler)
+	admin.HandleFunc("GET /launcher/rollout", logger.adminGetLauncherRolloutHandler)
+	admin.HandleFunc("PUT /launcher/rollout", logger.adminSetLauncherRolloutHandler)
+	// Перезагрузка конфигурации и уровень логов не трогают синхронизируемые
+	// данные и нужны на реплике
+	admin.OnReplica().HandleFunc("POST /reload", logger.adminReloadHandler)
+	admin.HandleFunc("GET /logging", logger.adminGetLoggingHandler)
+	admin.OnReplica().HandleFunc("PUT /logging", logger.adminSetLoggingHandler)
+	admin.HandleFunc("GET /webhooks", logger.adminListWebhooksHandler)
+	admin.HandleFunc("POST /webhooks", logger.adminCreateWebhookHandler)
+	admin.HandleFunc("DELETE /webhooks/{id}", logger.adminDeleteWebhookHandler)
+	admin.HandleFunc("GET /webhooks/{id}/deliveries", logger.adminWebhookDeliveriesHandler)
+	admin.HandleFunc("GET /mirrors", logger.adminListMirrorsHandler)
+	admin.HandleFunc("POST /mirrors", logger.adminCreateMirrorHandler)
+	admin.HandleFunc("DELETE /mirrors/{id}", logger.adminDeleteMirrorHandler)
+	uploads.HandleFunc("PUT /upload/{artifact}", logger.adminUploadHandler)
+	uploads.HandleFunc("PUT /upload/{artifact}/encodings/{encoding}", logger.adminUploadEncodingHandler)
+	// Загрузка по частям для любого маршрута загрузки (X-Upload-ID)
+	admin.HandleFunc("GET /uploads", logger.adminListUploadsHandler)
+	admin.HandleFunc("POST /uploads", logger.adminStartUploadHandler)
+	admin.HandleFunc("GET /uploads/{id}", logger.adminUploadStatusHandler)
+	admin.WithBodyLimit(adminChunkBodyLimit).HandleFunc("PUT /uploads/{id}", logger.adminUploadChunkHandler)
+	admin.HandleFunc("POST /uploads/{id}/complete", logger.adminCompleteUploadHandler)
+	admin.HandleFunc("DELETE /uploads/{id}", logger.adminDeleteUploadHandler)
+	admin.HandleFunc("GET /changelog", logger.adminListChangelogHandler)
+	admin.HandleFunc("PUT /changelog/{version}", logger.adminPutChangelogHandler)
+	admin.HandleFunc("DELETE /changelog/{version}", logger.adminDeleteChangelogHandler)
+	admin.HandleFunc("GET /releases", logger.adminListReleasesHandler)
+	admin.HandleFunc("POST /releases", logger.adminCreateReleaseHandler)
+	uploads.HandleFunc("PUT /releases/{id}/artifacts/{artifact}", logger.adminUploadReleaseArtifactHandler)
+	admin.HandleFunc("POST /releases/{id}/publish", logger.adminPublishReleaseHandler)
+	admin.HandleFunc("DELETE /releases/{id}", logger.adminDeleteReleaseHandler)
+	admin.HandleFunc("GET /staging", logger.adminGetStagingHandler)
+	admin.HandleFunc("PUT /staging/changelog", logger.adminSetStagingChangelogHandler)
+	uploads.HandleFunc("PUT /staging/{artifact}/{version}", logger.adminUploadStagingHandler)
+	admin.HandleFunc("DELETE /staging/{artifact}", logger.adminDeleteStagingHandler)
+	admin.HandleFunc("POST /staging/promote", logger.adminPromoteStagingHandler)
+	admin.HandleFunc("GET /runtimes", logger.adminListRuntimesHandler)
+	uploads.HandleFunc("PUT /runtimes/{os}/{arch}", logger.adminUploadRuntimeHandler)
+	admin.HandleFunc("DELETE /runtimes/{os}/{arch}", logger.adminDeleteRuntimeHandler)
+	admin.HandleFunc("GET /launch-profiles", logger.adminListLaunchProfilesHandler)
+	admin.HandleFunc("PUT /launch-profiles/{game_version}", logger.adminPutLaunchProfileHandler)
+	admin.HandleFunc("DELETE /launch-profiles/{game_version}", logger.adminDeleteLaunchProfileHandler)
+	admin.HandleFunc("GET /hashes", logger.adminHashesStatusHandler)
+	admin.HandleFunc("POST /hashes/recompute", logger.adminRecomputeHashesHandler)
+	admin.HandleFunc("GET /dependencies", logger.adminListDependenciesHandler)
+	uploads.HandleFunc("PUT /dependencies/{game_version}", logger.adminUploadDependenciesHandler)
+	admin.HandleFunc("DELETE /dependencies/{game_version}", logger.adminDeleteDependenciesHandler)
+	admin.HandleFunc("GET /mods", logger.adminListModsHandler)
+	admin.HandleFunc("PUT /mods/{id}", logger.adminPutModHandler)
+	admin.HandleFunc("DELETE /mods/{id}", logger.adminDeleteModHandler)
+	admin.HandleFunc("PUT /mods/{id}/versions/{version}", logger.adminPutModVersionHandler)
+	admin.HandleFunc("DELETE /mods/{id}/versions/{version}", logger.adminDeleteModVersionHandler)
+	uploads.HandleFunc("PUT /mods/{id}/versions/{version}/file", logger.adminUploadModFileHandler)
+	admin.HandleFunc("GET /modpacks", logger.adminListModpacksHandler)
+	admin.HandleFunc("PUT /modpacks/{id}", logger.adminPutModpackHandler)
+	admin.HandleFunc("DELETE /modpacks/{id}", logger.adminDeleteModpackHandler)
+	admin.HandleFunc("GET /resourcepacks", logger.adminListResourcePacksHandler)
+	admin.HandleFunc("DELETE /resourcepacks/{name}", logger.adminDeleteResourcePackHandler)
+	uploads.HandleFunc("PUT /resourcepacks/{name}/versions/{version}", logger.adminUploadResourcePackHandler)
+	admin.HandleFunc("DELETE /resourcepacks/{name}/versions/{version}", logger.adminDeleteResourcePackVersionHandler)
+	admin.HandleFunc("GET /gc", logger.adminGCPlanHandler)
+	admin.HandleFunc("POST /gc", logger.adminRunGCHandler)
+	admin.HandleFunc("GET /blobs", logger.adminListBlobsHandler)
+	admin.HandleFunc("GET /sessions", logger.adminSessionsHandler)
+	admin.HandleFunc("GET /eula", logger.adminGetEULAHandler)
+	admin.HandleFunc("PUT /eula", logger.adminSetEULAHandler)
+	for _, list := range []string{PlayerListBans, PlayerListWhitelist} {
+		admin.HandleFunc("GET /"+list, logger.adminListPlayersHandler(list))
+		admin.HandleFunc("GET /"+list+"/{player}", logger.adminGetPlayerHandler(list))
+		admin.HandleFunc("PUT /"+list+"/{player}", logger.adminPutPlayerHandler(list))
+		admin.HandleFunc("DELETE /"+list+"/{player}", logger.adminDeletePlayerHandler(list))
+	}
+	admin.HandleFunc("GET /achievements", logger.adminListAchievementsHandler)
+	admin.HandleFunc("PUT /achievements/{id}", logger.adminPutAchievementHandler)
+	admin.HandleFunc("DELETE /achievements/{id}", logger.adminDeleteAchievementHandler)
+	admin.HandleFunc("PUT /achievements/{id}/icon", logger.adminAchievementIconHandler)
+	admin.HandleFunc("GET /achievements/players/{player}", logger.adminPlayerAchievementsHandler)
+	admin.HandleFunc("DELETE /achievements/players/{player}", logger.adminRevokeAchievementsHandler)
+	admin.HandleFunc("GET /anticheat/reports", logger.adminCheatReportsHandler)
+	admin.HandleFunc("POST /anticheat/reports/{id}/confirm", logger.adminReviewCheatReportHandler(CheatReportConfirmed))
+	admin.HandleFunc("POST /anticheat/reports/{id}/dismiss", logger.adminReviewCheatReportHandler(CheatReportDismissed))
+	admin.HandleFunc("GET /hwid/bans", logger.adminHWIDBansHandler)
+	admin.HandleFunc("POST /hwid/bans", logger.adminHWIDBanHandler)
+	admin.HandleFunc("DELETE /hwid/bans/{id}", logger.adminHWIDUnbanHandler)
+	admin.HandleFunc("GET /hwid/accounts/{username}", logger.adminHWIDAccountHandler)
+	admin.HandleFunc("POST /inbox", logger.adminInboxSendHandler)
+	admin.HandleFunc("GET /inbox/{player}", logger.adminInboxHandler)
+	admin.HandleFunc("GET /leaderboards", logger.adminListLeaderboardsHandler)
+	admin.HandleFunc("PUT /leaderboards/{id}", logger.adminPutLeaderboardHandler)
+	admin.HandleFunc("DELETE /leaderboards/{id}", logger.adminDeleteLeaderboardHandler)
+	admin.HandleFunc("DELETE /leaderboards/{id}/scores/{player}", logger.adminRemoveScoreHandler)
+	admin.HandleFunc("GET /screenshots", logger.adminScreenshotsHandler)
+	admin.HandleFunc("GET /screenshots/{id}/image", logger.adminScreenshotImageHandler("image"))
+	admin.HandleFunc("GET /screenshots/{id}/thumb", logger.adminScreenshotImageHandler("thumb"))
+	admin.HandleFunc("POST /screenshots/{id}/approve", logger.adminApproveScreenshotHandler)
+	admin.HandleFunc("DELETE /screenshots/{id}", logger.adminDeleteScreenshotHandler)
+	admin.HandleFunc("GET /feedback", logger.adminListFeedbackHandler)
+	admin.HandleFunc("GET /feedback/{id}/attachment", logger.adminFeedbackAttachmentHandler)
+	admin.HandleFunc("PUT /feedback/{id}", logger.adminUpdateFeedbackHandler)
+	admin.HandleFunc("DELETE /feedback/{id}", logger.adminDeleteFeedbackHandler)
+	admin.HandleFunc("GET /accounts/{username}/entitlements", logger.adminAccountEntitlementsHandler)
+	admin.HandleFunc("PUT /accounts/{username}/entitlements/{name}", logger.adminGrantEntitlementHandler)
+	admin.HandleFunc("DELETE /accounts/{username}/entitlements/{name}", logger.adminRevokeEntitlementHandler)
+	admin.HandleFunc("DELETE /accounts/{username}/lockout", logger.adminUnlockAccountHandler)
+	admin.HandleFunc("GET /promocodes", logger.adminListPromoCodesHandler)
+	admin.HandleFunc("POST /promocodes", logger.adminCreatePromoCodesHandler)
+	admin.HandleFunc("GET /promocodes/{code}", logger.adminGetPromoCodeHandler)
+	admin.HandleFunc("DELETE /promocodes/{code}", logger.adminDeletePromoCodeHandler)
+	admin.HandleFunc("PUT /signature/{artifact}", logger.adminUploadSignatureHandler)
+	admin.HandleFunc("GET /stats", logger.adminStatsHandler)
+	admin.HandleFunc("GET /downloads/usage", logger.adminDownloadUsageHandler)
+	admin.HandleFunc("DELETE /downloads/usage/{username}", logger.adminResetDownloadUsageHandler)
+	admin.HandleFunc("GET /hardware-survey", logger.adminHardwareSurveyHandler)
+	// Диагностика под нагрузкой без передеплоя: профили pprof и expvar
+	publishDebugVars()
+	admin.HandleFunc("GET /debug/runtime", logger.adminDebugRuntimeHandler)
+	admin.HandleFunc("GET /debug/vars", logger.adminDebugHandler("/admin/api/debug/vars", expvar.Handler()))
+	admin.HandleFunc("GET /debug/pprof/{$}", logger.adminDebugHandler("/admin/api/debug/pprof/", http.HandlerFunc(pprof.Index)))
+	admin.HandleFunc("GET /debug/pprof/cmdline", logger.adminDebugHandler("/admin/api/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline)))
+	admin.HandleFunc("GET /debug/pprof/profile", logger.adminDebugHandler("/admin/api/debug/pprof/profile", http.HandlerFunc(pprof.Profile)))
+	admin.HandleFunc("GET /debug/pprof/trace", logger.adminDebugHandler("/admin/api/debug/pprof/trace", http.HandlerFunc(pprof.Trace)))
+	admin.HandleFunc("GET /debug/pprof/symbol", logger.adminDebugHandler("/admin/api/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol)))
+	admin.HandleFunc("POST /debug/pprof/symbol", logger.adminDebugHandler("/admin/api/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol)))
+	admin.HandleFunc("GET /debug/pprof/{profile}", logger.adminPprofProfileHandler)
+	admin.HandleFunc("GET /keys", logger.adminListKeysHandler)
+	admin.HandleFunc("POST /keys", logger.adminCreateKeyHandler)
+	admin.HandleFunc("POST /keys/{id}/rotate", logger.adminRotateKeyHandler)
+	admin.HandleFunc("PUT /keys/{id}/permissions", logger.adminKeyPermissionsHandler)
+	admin.HandleFunc("DELETE /keys/{id}", logger.adminDeleteKeyHandler)
+	admin.HandleFunc("DELETE /keys/{id}/2fa", logger.adminResetKeyTOTPHandler)
+	admin.HandleFunc("GET /roles", logger.adminRolesHandler)
+	// Спецификация публичного API для документации в панели
+	admin.HandleFunc("GET /openapi.json", logger.adminOpenAPIHandler(router))
+	admin.HandleFunc("GET /account-token-keys", logger.adminAccountTokenKeysHandler)
+	admin.HandleFunc("POST /account-token-keys/rotate", logger.adminRotateAccountTokenKeyHandler)
+	admin.HandleFunc("POST /2fa/enroll", logger.adminTOTPEnrollHandler)
+	admin.HandleFunc("POST /2fa/verify", logger.adminTOTPVerifyHandler)
+	// Без сессии второго фактора ключ с TOTP не попадет в админку реплики
+	admin.OnReplica().HandleFunc("POST /2fa/session", logger.adminSessionHandler)
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+	// Планировщик публикации новостей; уже опубликованные к запуску
+	// новости в /api/events не объявляются
+	if news, err := newsCache.Get(); err == nil {
+		eventHub.PublishNews(news)
+	}
+	go logger.runNewsScheduler()
+
+	// Перезагрузка конфигурации по SIGHUP
+	go logger.watchReloadSignal()
+	go logger.watchClientsDir()
+	go logger.runSessionSweeper()
+	go logger.runSavesRetention()
+	go logger.runArtifactGC()
+	go logger.runDiskMonitor()
+	go logger.runAccountPurge()
+	go logger.runDownloadQueue()
+	go logger.runDownloadUsage()
+	go logger.runMirrorSync()
+	go logger.runSharedState()
+	go logger.runOverloadMonitor()
+
+	// Запуск сервера
+	cfg := currentConfig()
+	port := ":" + cfg.ServerPort
+	listeners, err := openServerListeners(cfg)
+	if err != nil {
+		return err
+	}
+	// После обновления без остановки прежние серверы закрываются штатно
+	errs := make(chan error, len(listeners.public)+2)
+	serve := func(run func() error) {
+		if err := run(); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}
+	// Публичные эндпоинты не принимают больших тел запросов
+	public := newHTTPServer(port, router, cfg.ReadTimeout)
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		if err := serverCertificate.Load(cfg); err != nil {
+			return fmt.Errorf("ошибка загрузки сертификата TLS: %v", err)
+		}
+		public.TLSConfig = serverTLSConfig()
+		scheme = "https"
+		if cfg.HTTP3 {
+			quic := newHTTP3Server(port, router)
+			public.Handler = withAltSvc(router, quic)
+			go func() {
+				errs <- quic.ListenAndServe()
+			}()
+			logger.Printf("HTTP/3 (QUIC) слушает UDP%s", port)
+		}
+	}
+	if cfg.GRPCEnabled && public.TLSConfig == nil {
+		// gRPC требует HTTP/2; без TLS — h2c рядом с HTTP/1.1
+		public.Protocols = new(http.Protocols)
+		public.Protocols.SetHTTP1(true)
+		public.Protocols.SetUnencryptedHTTP2(true)
+	}
+	// Serve заполняет TLSConfig при настройке HTTP/2, поэтому режим
+	// выбирается до запуска первого слушателя
+	useTLS := public.TLSConfig != nil
+	for _, listener := range listeners.public {
+		go serve(func() error {
+			if useTLS {
+				return public.ServeTLS(listener, "", "")
+			}
+			return public.Serve(listener)
+		})
+		logger.Printf("Сервер лаунчера слушает %s %s (%s)", listener.Addr().Network(), listener.Addr(), scheme)
+	}
+	// Загрузка сборок в админке может быть долгой, поэтому без ReadTimeout
+	adminServer := newHTTPServer(cfg.AdminAddr, adminRouter, 0)
+	go serve(func() error {
+		return adminServer.Serve(listeners.admin)
+	})
+
+	logger.Printf("Админский API доступен на http://%s/admin/api/", cfg.AdminAddr)
+	logger.Println("Готов к приему запросов...")
+	listeners.notifyReady()
+
+	// Замена бинарника без остановки по SIGUSR2
+	server := &upgradableServer{listeners: listeners, public: public, admin: adminServer, drained: make(chan struct{})}
+	go logger.watchUpgradeSignal(server)
+	select {
+	case err := <-errs:
+		return err
+	case <-server.drained:
+		return nil
 	}
-	return defaultValue
+}
+
+// Маршруты публичного API. Вынесены из runServe, чтобы спецификацию
+// OpenAPI можно было собрать без запуска сервера (loil-server openapi).
+func (l *Logger) registerPublicRoutes(router *Router) {
+	// Изображения новостей
+	router.HandleFunc("GET /images/{name}", l.imageHandler)
+	router.HandleFunc("GET /readyz", l.readyzHandler)
+
+	// API v1; старые пути /api/... остаются алиасами
+	v1 := router.Version("v1", "/api")
+	v1.HandleFunc("/news", withAPITimeout(l.newsHandler))
+	v1.HandleFunc("/news.rss", withAPITimeout(l.newsRSSHandler))
+	v1.HandleFunc("/news.atom", withAPITimeout(l.newsAtomHandler))
+	// Телеметрия, опросы и отметки просмотров первыми отклоняются при
+	// перегрузке (OVERLOAD_*), чтобы не мешать скачиваниям
+	low := v1.LowPriority()
+	low.HandleFunc("POST /news/{id}/view", withAPITimeout(l.newsViewHandler))
+	v1.HandleFunc("PUT /news/{id}/reactions/{reaction}", withAPITimeout(l.newsReactionHandler))
+	v1.HandleFunc("DELETE /news/{id}/reactions/{reaction}", withAPITimeout(l.newsReactionHandler))
+	v1.HandleFunc("/version", withAPITimeout(l.versionHandler))
+	v1.HandleFunc("GET /launcher-config", withAPITimeout(l.launcherConfigHandler))
+	v1.HandleFunc("GET /switches", withAPITimeout(l.killSwitchesHandler))
+	v1.HandleFunc("GET /launcher/update", withAPITimeout(l.launcherUpdateHandler))
+	v1.HandleFunc("GET /motd", withAPITimeout(l.motdHandler))
+	low.WithBodyLimit(telemetryBodyLimit).HandleFunc("POST /telemetry", withAPITimeout(l.telemetryHandler))
+	low.HandleFunc("POST /survey/hardware", withAPITimeout(l.hardwareSurveyHandler))
+	v1.HandleFunc("GET /runtime", withAPITimeout(l.runtimeHandler))
+	v1.HandleFunc("GET /launch-profile", withAPITimeout(l.launchProfileHandler))
+	v1.HandleFunc("GET /changelog", withAPITimeout(l.changelogHandler))
+	v1.HandleFunc("GET /dependencies", withAPITimeout(l.dependenciesHandler))
+	v1.HandleFunc("GET /mods", withAPITimeout(l.modsHandler))
+	v1.HandleFunc("GET /modpacks", withAPITimeout(l.modpacksHandler))
+	v1.HandleFunc("GET /resourcepacks", withAPITimeout(l.resourcePacksHandler))
+	v1.HandleFunc("GET /resourcepack/{name}/hash", withAPITimeout(l.resourcePackHashHandler))
+	v1.HandleFunc("GET /eula", withAPITimeout(l.eulaHandler))
+	v1.HandleFunc("POST /eula/accept", withAPITimeout(l.eulaAcceptHandler))
+	v1.HandleFunc("POST /session/start", withAPITimeout(l.sessionStartHandler))
+	v1.HandleFunc("POST /session/heartbeat", withAPITimeout(l.sessionHeartbeatHandler))
+	v1.HandleFunc("POST /session/end", withAPITimeout(l.sessionEndHandler))
+	low.HandleFunc("GET /online", withAPITimeout(l.onlineHandler))
+	v1.HandleFunc("POST /auth/register", withAPITimeout(l.withCaptcha(CaptchaRegister, l.registerHandler)))
+	v1.HandleFunc("POST /auth/login", withAPITimeout(l.loginHandler))
+	v1.HandleFunc("POST /auth/refresh", withAPITimeout(l.refreshTokenHandler))
+	v1.HandleFunc("POST /auth/logout", withAPITimeout(l.logoutHandler))
+	v1.HandleFunc("GET /account/sessions", withAPITimeout(l.accountSessionsHandler))
+	v1.HandleFunc("DELETE /account/sessions", withAPITimeout(l.revokeAccountSessionsHandler))
+	v1.HandleFunc("DELETE /account/sessions/{id}", withAPITimeout(l.revokeAccountSessionHandler))
+	v1.HandleFunc("POST /account/delete", withAPITimeout(l.accountDeleteHandler))
+	v1.HandleFunc("POST /account/delete/cancel", withAPITimeout(l.accountDeleteCancelHandler))
+	v1.HandleFunc("POST /auth/verify", withAPITimeout(l.verifyEmailHandler))
+	v1.HandleFunc("GET /auth/verify", withAPITimeout(l.verifyEmailHandler))
+	v1.HandleFunc("POST /auth/forgot", withAPITimeout(l.forgotPasswordHandler))
+	v1.HandleFunc("POST /auth/reset", withAPITimeout(l.resetPasswordHandler))
+	v1.HandleFunc("PUT /account/email", withAPITimeout(l.accountEmailHandler))
+	v1.HandleFunc("GET /.well-known/jwks.json", withAPITimeout(l.jwksHandler))
+	v1.HandleFunc("POST /auth/oauth/token", withAPITimeout(l.oauthTokenHandler))
+	v1.HandleFunc("GET /auth/oauth/{provider}", withAPITimeout(l.oauthStartHandler))
+	v1.HandleFunc("GET /auth/oauth/{provider}/callback", withAPITimeout(l.oauthCallbackHandler))
+	v1.HandleFunc("POST /auth/oauth/{provider}/link", withAPITimeout(l.oauthLinkHandler))
+	v1.HandleFunc("DELETE /account/identities/{provider}", withAPITimeout(l.oauthUnlinkHandler))
+	v1.HandleFunc("GET /account", withAPITimeout(l.accountHandler))
+	v1.HandleFunc("POST /account/2fa/enroll", withAPITimeout(l.accountTOTPEnrollHandler))
+	v1.HandleFunc("POST /account/2fa/verify", withAPITimeout(l.accountTOTPVerifyHandler))
+	v1.HandleFunc("POST /account/2fa/recovery-codes", withAPITimeout(l.accountRecoveryCodesHandler))
+	v1.HandleFunc("POST /account/2fa/disable", withAPITimeout(l.accountTOTPDisableHandler))
+	v1.HandleFunc("GET /sync", withAPITimeout(l.syncListHandler))
+	v1.HandleFunc("GET /sync/{key}", withAPITimeout(l.syncGetHandler))
+	v1.WithBodyLimit(syncBodyLimit).HandleFunc("PUT /sync/{key}", withAPITimeout(l.syncPutHandler))
+	v1.HandleFunc("DELETE /sync/{key}", withAPITimeout(l.syncDeleteHandler))
+	v1.HandleFunc("GET /saves", withAPITimeout(l.savesListHandler))
+	v1.HandleFunc("POST /saves/uploads", withAPITimeout(l.saveUploadStartHandler))
+	v1.HandleFunc("GET /saves/uploads/{id}", withAPITimeout(l.saveUploadStatusHandler))
+	v1.HandleFunc("POST /saves/uploads/{id}/complete", withAPITimeout(l.saveUploadCompleteHandler))
+	v1.HandleFunc("DELETE /saves/{id}", withAPITimeout(l.saveDeleteHandler))
+	// Части архивов, скачивание сохранений и выгрузка данных аккаунта идут
+	// без общего таймаута API
+	v1.WithBodyLimit(saveChunkBodyLimit).HandleFunc("PUT /saves/uploads/{id}", l.saveUploadChunkHandler)
+	v1.HandleFunc("GET /saves/{id}", l.saveDownloadHandler)
+	v1.HandleFunc("GET /account/export", l.accountExportHandler)
+	v1.HandleFunc("GET /screenshots", withAPITimeout(l.screenshotsFeedHandler))
+	v1.HandleFunc("GET /account/screenshots", withAPITimeout(l.accountScreenshotsHandler))
+	v1.HandleFunc("GET /entitlements", withAPITimeout(l.entitlementsHandler))
+	v1.HandleFunc("POST /redeem", withAPITimeout(l.redeemHandler))
+	v1.WithBodyLimit(screenshotBodyLimit).HandleFunc("POST /screenshots", l.screenshotUploadHandler)
+	v1.WithBodyLimit(feedbackBodyLimit).HandleFunc("POST /feedback", l.withCaptcha(CaptchaFeedback, l.feedbackHandler))
+	v1.HandleFunc("GET /achievements", withAPITimeout(l.achievementsHandler))
+	v1.HandleFunc("GET /achievements/{id}/icon", l.achievementIconHandler)
+	v1.HandleFunc("POST /achievements/unlock", withAPITimeout(l.achievementUnlockHandler))
+	v1.HandleFunc("GET /account/achievements", withAPITimeout(l.accountAchievementsHandler))
+	v1.HandleFunc("GET /account/friends", withAPITimeout(l.friendsHandler))
+	v1.HandleFunc("POST /account/friends", withAPITimeout(l.friendRequestHandler))
+	v1.HandleFunc("POST /account/friends/{username}/accept", withAPITimeout(l.friendAcceptHandler))
+	v1.HandleFunc("DELETE /account/friends/{username}", withAPITimeout(l.friendRemoveHandler))
+	v1.HandleFunc("GET /account/notifications", withAPITimeout(l.notificationsHandler))
+	v1.HandleFunc("PUT /account/notifications", withAPITimeout(l.notificationSettingsHandler))
+	v1.HandleFunc("POST /account/devices", withAPITimeout(l.pushDeviceRegisterHandler))
+	v1.HandleFunc("DELETE /account/devices/{token}", withAPITimeout(l.pushDeviceDeleteHandler))
+	v1.HandleFunc("GET /inbox", withAPITimeout(l.inboxHandler))
+	v1.HandleFunc("POST /inbox", withAPITimeout(l.inboxSendHandler))
+	v1.HandleFunc("POST /inbox/read", withAPITimeout(l.inboxReadHandler))
+	v1.HandleFunc("POST /inbox/{id}/read", withAPITimeout(l.inboxReadHandler))
+	v1.HandleFunc("DELETE /inbox/{id}", withAPITimeout(l.inboxDeleteHandler))
+	v1.HandleFunc("POST /anticheat/reports", withAPITimeout(l.cheatReportHandler))
+	v1.HandleFunc("GET /leaderboards", withAPITimeout(l.leaderboardsHandler))
+	v1.HandleFunc("GET /leaderboards/{id}", withAPITimeout(l.leaderboardHandler))
+	v1.HandleFunc("POST /leaderboards/{id}/scores", withAPITimeout(l.leaderboardSubmitHandler))
+	v1.HandleFunc("GET /profile/{username}", withAPITimeout(l.profileHandler))
+	v1.HandleFunc("GET /profile/{username}/avatar", l.profileAvatarHandler)
+	v1.HandleFunc("PUT /account/profile", withAPITimeout(l.accountProfileHandler))
+	v1.WithBodyLimit(avatarBodyLimit).HandleFunc("PUT /account/avatar", l.accountAvatarUploadHandler)
+	v1.HandleFunc("DELETE /account/avatar", withAPITimeout(l.accountAvatarDeleteHandler))
+	v1.HandleFunc("GET /screenshots/{id}/image", l.screenshotImageHandler("image"))
+	v1.HandleFunc("GET /screenshots/{id}/thumb", l.screenshotImageHandler("thumb"))
+	// Очередь скачиваний и починка ничего не меняют и нужны на реплике
+	v1.OnReplica().HandleFunc("POST /download/queue", withAPITimeout(l.downloadQueueJoinHandler))
+	low.HandleFunc("POST /download/report", withAPITimeout(l.downloadReportHandler))
+	v1.HandleFunc("GET /download/queue/{token}", l.downloadQueueStatusHandler)
+	v1.OnReplica().HandleFunc("DELETE /download/queue/{token}", withAPITimeout(l.downloadQueueLeaveHandler))
+	v1.HandleFunc("/download/launcher", l.downloadLauncherHandler)
+	v1.HandleFunc("/download/game", l.downloadGameHandler)
+	v1.HandleFunc("GET /download/runtime/{os}/{arch}", l.downloadRuntimeHandler)
+	v1.HandleFunc("GET /download/mod/{id}/{version}", l.downloadModHandler)
+	v1.HandleFunc("GET /resourcepack/{name}", l.downloadResourcePackHandler)
+	v1.HandleFunc("GET /resourcepack/{name}/{version}", l.downloadResourcePackHandler)
+	v1.HandleFunc("/download/game.torrent", l.downloadGameTorrentHandler)
+	v1.HandleFunc("/download/game.zip", l.downloadGameArchiveHandler("zip"))
+	v1.HandleFunc("/download/game.tar.gz", l.downloadGameArchiveHandler("tar.gz"))
+	// Архив для починки собирается дольше общего таймаута API
+	v1.WithBodyLimit(verifyBodyLimit).OnReplica().HandleFunc("POST /verify", l.verifyHandler)
+	v1.HandleFunc("GET /announce", l.trackerAnnounceHandler)
+	v1.HandleFunc("GET /checksums/{artifact}", l.checksumsHandler)
+	v1.HandleFunc("GET /signature/{artifact}", withAPITimeout(l.signatureHandler))
+	v1.HandleFunc("GET /pubkey", withAPITimeout(l.pubkeyHandler))
+	v1.HandleFunc("GET /manifest/{artifact}", withAPITimeout(l.chunkManifestHandler))
+	v1.HandleFunc("GET /chunks/{hash}", l.chunkHandler)
+	v1.HandleFunc("GET /dependencies/objects/{hash}", l.dependencyObjectHandler)
+	// API синхронизации зеркал; сборки отдаются без таймаута API
+	v1.HandleFunc("POST /mirror/register", withAPITimeout(l.mirrorRegisterHandler))
+	v1.HandleFunc("GET /mirror/manifest", withAPITimeout(l.mirrorManifestHandler))
+	v1.HandleFunc("GET /mirror/files/{artifact}", l.mirrorFileHandler)
+	v1.HandleFunc("GET /mirror/news", withAPITimeout(l.mirrorNewsHandler))
+	v1.OnReplica().HandleFunc("POST /mirror/notify", withAPITimeout(l.mirrorNotifyHandler))
+	// Поток объявлений держит соединение, поэтому без таймаута API
+	v1.HandleFunc("GET /events", l.eventsHandler)
+	// Другие игры на этом же сервере; короткие пути /api/{project}/...
+	// переписываются на эти маршруты в Router
+	v1.HandleFunc("GET /projects", withAPITimeout(l.projectsHandler))
+	v1.HandleFunc("GET /projects/{project}/version", withAPITimeout(l.projectVersionHandler))
+	v1.HandleFunc("GET /projects/{project}/news", withAPITimeout(l.projectNewsHandler))
+	v1.HandleFunc("GET /projects/{project}/download/launcher", l.projectDownloadHandler("launcher"))
+	v1.HandleFunc("GET /projects/{project}/download/game", l.projectDownloadHandler("game"))
+	v1.HandleFunc("GET /openapi.json", withAPITimeout(l.openAPIHandler(router)))
 }
 
 // Обработчик новостей с логированием
 func (l *Logger) newsHandler(w http.ResponseWriter, r *http.Request) {
 	l.handleWithCORS(w, r, "📰", "/api/news", func() {
-		// Загружаем новости
-		news, err := loadNews()
+		langs := requestLanguages(r)
+		format := r.URL.Query().Get("format")
+
+		// Готовый JSON берем из кэша, собираем только при промахе. Счетчики
+		// берутся из снимка, который обновляется не чаще раза в NEWS_CACHE_TTL.
+		filter := requestNewsFilter(r)
+		revision, counts := newsEngagement.Snapshot(currentConfig().NewsCacheTTL)
+		key := "json|" + format + "|" + strings.Join(langs, ",") + "|" + filter.key() + "|" + strconv.FormatInt(revision, 10)
+		body, count, err := newsCache.Response(key, func(news []NewsItem) ([]byte, int, error) {
+			news = withNewsEngagement(filter.apply(playerNews(news, langs, format == "html")), counts)
+			data, err := json.Marshal(NewsResponse{News: news})
+			return append(data, '\n'), len(news), err
+		})
 		if err != nil {
 			l.logError("Ошибка загрузки новостей: %v", err)
-			http.Error(w, fmt.Sprintf("Ошибка загрузки новостей: %v", err), http.StatusInternalServerError)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
 			return
 		}
 
 		// Отправляем ответ
-		response := NewsResponse{News: news}
-		json.NewEncoder(w).Encode(response)
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write(body)
 
-		l.logSuccess("Отправлено новостей: %d", len(news))
+		l.logSuccess("Отправлено новостей: %d", count)
 	})
 }
 
 // Обработчик версий
 func (l *Logger) versionHandler(w http.ResponseWriter, r *http.Request) {
 	l.handleWithCORS(w, r, "🔖", "/api/version", func() {
-		response := VersionResponse{
-			LauncherVersion: config.LauncherVersion,
-			GameVersion:     config.GameVersion,
+		cfg := currentConfig()
+		staging, ok := l.requestStaging(w, r)
+		if !ok {
+			return
+		}
+		if staging {
+			l.stagingVersionHandler(w, r)
+			return
 		}
 
-		json.NewEncoder(w).Encode(response)
+		// Лаунчер сообщает свои версии, чтобы узнать, не заблокированы ли они
+		query := r.URL.Query()
+		mustUpdate := currentBlockedVersions().mustUpdate(query.Get("launcher_version"), query.Get("game_version"))
+		w.Write(versionResponseBody(mustUpdate))
+
+		if mustUpdate {
+			l.logWarn("Клиент с заблокированной версией: лаунчер=%s, игра=%s",
+				query.Get("launcher_version"), query.Get("game_version"))
+		}
 		l.logSuccess("Отправлены версии: лаунчер=%s, игра=%s",
-			config.LauncherVersion, config.GameVersion)
+			cfg.LauncherVersion, cfg.GameVersion)
 	})
 }
 
 // Обработчик скачивания лаунчера
 func (l *Logger) downloadLauncherHandler(w http.ResponseWriter, r *http.Request) {
 	l.handleWithCORS(w, r, "⬇️", "/api/download/launcher", func() {
-		filePath := filepath.Join(config.ClientsDir, config.LauncherClient)
+		cfg := currentConfig()
+		staging, ok := l.requestStaging(w, r)
+		if !ok {
+			return
+		}
+		filePath := filepath.Join(cfg.ClientsDir, cfg.LauncherClient)
+		if staging {
+			path, err := currentStaging().file(cfg, "launcher")
+			if err != nil {
+				l.logError("Сборка launcher тестового канала недоступна: %v", err)
+				writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+				return
+			}
+			filePath = path
+		}
 		l.serveFileDownload(w, r, filePath, "launcher")
 	})
 }
@@ -154,17 +798,47 @@ func (l *Logger) downloadLauncherHandler(w http.ResponseWriter, r *http.Request)
 // Обработчик скачивания игры
 func (l *Logger) downloadGameHandler(w http.ResponseWriter, r *http.Request) {
 	l.handleWithCORS(w, r, "⬇️", "/api/download/game", func() {
-		filePath := filepath.Join(config.ClientsDir, config.GameClient)
+		cfg := currentConfig()
+		if l.rejectWithoutEntitlement(w, r, cfg.GameEntitlement, "игры") {
+			return
+		}
+		staging, ok := l.requestStaging(w, r)
+		if !ok {
+			return
+		}
+		filePath := filepath.Join(cfg.ClientsDir, cfg.GameClient)
+		if staging {
+			path, err := currentStaging().file(cfg, "game")
+			if err != nil {
+				l.logError("Сборка game тестового канала недоступна: %v", err)
+				writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+				return
+			}
+			filePath = path
+		}
 		l.serveFileDownload(w, r, filePath, "game")
 	})
 }
 
 // Общая логика для скачивания файлов
 func (l *Logger) serveFileDownload(w http.ResponseWriter, r *http.Request, filePath, fileType string) {
+	if l.rejectDuringMaintenance(w, r, fileType) {
+		return
+	}
+	accountID, ok := l.checkDownloadQuota(w, r, fileType)
+	if !ok {
+		return
+	}
+	release, ok := l.acquireDownload(w, r, fileType)
+	if !ok {
+		return
+	}
+	defer release()
+
 	// Проверяем существование файла
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		l.logError("Файл не найден: %s", filePath)
-		http.Error(w, "Файл не найден", http.StatusNotFound)
+		writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
 		return
 	}
 
@@ -172,7 +846,7 @@ func (l *Logger) serveFileDownload(w http.ResponseWriter, r *http.Request, fileP
 	file, err := os.Open(filePath)
 	if err != nil {
 		l.logError("Ошибка открытия файла %s: %v", filePath, err)
-		http.Error(w, "Ошибка открытия файла", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
 		return
 	}
 	defer file.Close()
@@ -181,39 +855,112 @@ func (l *Logger) serveFileDownload(w http.ResponseWriter, r *http.Request, fileP
 	fileInfo, err := file.Stat()
 	if err != nil {
 		l.logError("Ошибка получения информации о файле %s: %v", filePath, err)
-		http.Error(w, "Ошибка получения информации о файле", http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
 		return
 	}
 
-	// Вычисляем хэш файла
-	hash, err := calculateFileHash(filePath)
+	// Хэш берем из индекса каталога клиентов
+	indexed, err := clientIndex.Lookup(filePath, fileInfo)
 	if err != nil {
 		l.logError("Ошибка вычисления хэша файла %s: %v", filePath, err)
 		// Не прерываем выполнение, хэш не обязателен для скачивания
 	}
+	hash := indexed.Hash
 
 	// Получаем только имя файла для заголовка
 	filename := filepath.Base(filePath)
 
-	// Устанавливаем заголовки
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
+	// Устанавливаем заголовки; Content-Length, Last-Modified и
+	// Accept-Ranges выставит http.ServeContent
+	contentType := contentTypeByName(filename)
+	disposition := contentDisposition(downloadDisposition(currentConfig(), fileType, contentType), filename)
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Type", contentType)
 
-	// Добавляем информацию о хэше в заголовок, если удалось вычислить
+	// Добавляем информацию о хэше в заголовок, если удалось вычислить;
+	// хэш же служит ETag для If-None-Match и докачки с If-Range
 	if hash != "" {
 		w.Header().Set("X-File-Hash", hash)
+		w.Header().Set("ETag", `"`+hash+`"`)
+	}
+
+	// Токен скачивания: с ним лаунчер продолжает докачку и присылает
+	// отчет в /api/download/report
+	if r.Method == http.MethodGet && hash != "" {
+		w.Header().Set("X-Download-Token", downloadReports.Issue(r.Header.Get("X-Download-Token"), fileType, filename, fileInfo.Size(), indexed))
+	}
+
+	// С S3 байты отдает хранилище. Перенаправление считается скачиванием
+	// целого файла: докачка и обрывы идут уже мимо сервера.
+	if l.redirectToS3(w, r, indexed, disposition, contentType) {
+		if r.Method == http.MethodGet {
+			downloadStats.Begin(fileType)
+			downloadStats.Record(fileType, fileInfo.Size(), true)
+			l.recordDownloadUsage(r, accountID, fileInfo.Size())
+		}
+		l.logSuccess("Скачивание %s перенаправлено в S3 (размер: %d bytes)", filename, fileInfo.Size())
+		return
 	}
 
-	// Копируем файл в ответ
-	_, err = io.Copy(w, file)
+	// Заранее сжатый вариант, если клиент его принимает. X-File-Hash
+	// остается хэшем несжатого файла, хэши сжатого — в X-Encoded-*.
+	// Range и ETag относятся к сжатому представлению.
+	var content io.ReadSeeker = file
+	size, encodingName := fileInfo.Size(), "identity"
+	if variants := loadEncodedVariants(filePath, indexed.SHA256); hash != "" && variants != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if variant, ok := negotiateEncoding(r, variants.Variants); ok {
+			encoding, _ := encodingByName(variant.Encoding)
+			if encoded, err := os.Open(encodedVariantPath(filePath, encoding)); err == nil {
+				defer encoded.Close()
+				content, size, encodingName = encoded, variant.Size, variant.Encoding
+				w.Header().Set("Content-Encoding", variant.Encoding)
+				w.Header().Set("ETag", `"`+variant.Hash+`"`)
+				w.Header().Set("X-Encoded-Hash", variant.Hash)
+				w.Header().Set("X-Encoded-SHA256", variant.SHA256)
+			}
+		}
+	}
+
+	// Отдаем файл: Range для докачки, условные запросы и sendfile на Linux
+	downloadStats.Begin(fileType)
+	out := newIdleTimeoutWriter(w, currentConfig().DownloadIdleTimeout)
+	http.ServeContent(out, r, filename, fileInfo.ModTime(), content)
+	written, err := out.written, out.err
+	if r.Method == http.MethodHead || (out.status != http.StatusOK && out.status != http.StatusPartialContent) {
+		// Заголовки без тела (HEAD, 304, 416) скачиванием не считаем
+		downloadStats.Skip(fileType)
+		return
+	}
+	downloadStats.Record(fileType, written, err == nil)
+	l.recordDownloadUsage(r, accountID, written)
+	if errors.Is(err, errClientStalled) {
+		l.logError("Клиент %s завис на скачивании %s (отдано %d из %d bytes), соединение закрыто",
+			getClientIP(r), filename, written, size)
+		return
+	}
 	if err != nil {
 		l.logError("Ошибка отправки файла %s: %v", filePath, err)
 		return
 	}
+	if out.status == http.StatusPartialContent {
+		l.logSuccess("Отправлена часть файла %s (%d из %d bytes, %s)", filename, written, size, encodingName)
+		return
+	}
+
+	l.logSuccess("Отправлен файл %s (размер: %d bytes, %s, хэш: %s)",
+		filename, size, encodingName, hash)
+}
 
-	l.logSuccess("Отправлен файл %s (размер: %d bytes, хэш: %s)",
-		filename, fileInfo.Size(), hash)
+// Во время техработ раздача клиентов приостановлена
+func (l *Logger) rejectDuringMaintenance(w http.ResponseWriter, r *http.Request, what string) bool {
+	if !maintenanceMode.Load() {
+		return false
+	}
+	l.logError("Скачивание %s отклонено: техработы", what)
+	w.Header().Set("Retry-After", "300")
+	writeError(w, r, http.StatusServiceUnavailable, ErrCodeMaintenance)
+	return true
 }
 
 // Общая обработка CORS и логирования
@@ -236,72 +983,79 @@ func (l *Logger) handleWithCORS(w http.ResponseWriter, r *http.Request, emoji, e
 	// Выполняем основной обработчик
 	handler()
 
-	// Логируем в файл
-	l.logToFile(clientIP, endpoint, emoji)
+	// Логируем в журнал доступа
+	l.logAccess(clientIP, endpoint, emoji)
 }
 
 // Логирование ошибки
 func (l *Logger) logError(format string, v ...interface{}) {
 	message := fmt.Sprintf(format, v...)
-	l.Printf("❌ %s", message)
+	l.logf(LogLevelError, "❌ %s", message)
+}
+
+// Логирование того, что стоит проверить, но не является сбоем сервера
+func (l *Logger) logWarn(format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	l.logf(LogLevelWarn, "⚠️ %s", message)
 }
 
 // Логирование успеха
 func (l *Logger) logSuccess(format string, v ...interface{}) {
 	message := fmt.Sprintf(format, v...)
-	l.Printf("✅ %s", message)
+	l.logf(LogLevelInfo, "✅ %s", message)
 }
 
-// Логирование в файл с датой
-func (l *Logger) logToFile(clientIP, endpoint, emoji string) {
-	date := time.Now().Format("2006-01-02")
-	logDir := "logs"
-	logFile := filepath.Join(logDir, fmt.Sprintf("access_%s.log", date))
-
-	// Создаем директорию если не существует
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		l.Printf("❌ Ошибка создания директории логов: %v", err)
-		return
-	}
+// Подробности для разбора проблем; видны только с LOG_LEVEL=debug
+func (l *Logger) logDebug(format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	l.logf(LogLevelDebug, "🐞 %s", message)
+}
 
-	// Открываем файл для добавления логов
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// Функция для получения реального IP клиента.
+// Заголовки X-Forwarded-For и X-Real-IP учитываются, только если запрос
+// пришел от доверенного прокси (TRUSTED_PROXIES), иначе их можно подделать.
+func getClientIP(r *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		l.Printf("❌ Ошибка открытия файла логов: %v", err)
-		return
+		remoteIP = r.RemoteAddr
 	}
-	defer file.Close()
-
-	logEntry := fmt.Sprintf("[%s] %s %s - %s\n",
-		time.Now().Format("2006-01-02 15:04:05"),
-		clientIP,
-		endpoint,
-		emoji)
-
-	if _, err := file.WriteString(logEntry); err != nil {
-		l.Printf("❌ Ошибка записи в файл логов: %v", err)
+	// Через unix-сокет подключается только локальный прокси: адреса у
+	// такого подключения нет, и его заголовкам доверяем всегда
+	if net.ParseIP(remoteIP) == nil {
+		remoteIP = "127.0.0.1"
+	} else if !isTrustedProxy(remoteIP) {
+		return remoteIP
 	}
-}
 
-// Функция для получения реального IP клиента
-func getClientIP(r *http.Request) string {
-	// Пробуем получить IP из заголовков (если за прокси/балансировщиком)
-	ip := r.Header.Get("X-Real-IP")
-	if ip == "" {
-		ip = r.Header.Get("X-Forwarded-For")
-		if ip != "" {
-			// Берем первый IP из списка
-			ips := strings.Split(ip, ",")
-			ip = strings.TrimSpace(ips[0])
+	// X-Forwarded-For разбираем справа налево: каждый прокси дописывает
+	// адрес своего клиента в конец, поэтому первый недоверенный адрес
+	// справа и есть клиент. Левые значения мог подставить сам клиент.
+	var chain []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, part := range strings.Split(header, ",") {
+			if ip := strings.TrimSpace(part); net.ParseIP(ip) != nil {
+				chain = append(chain, ip)
+			}
 		}
 	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !isTrustedProxy(chain[i]) {
+			return chain[i]
+		}
+	}
+	if len(chain) > 0 {
+		return chain[0]
+	}
 
-	// Если в заголовках нет, берем RemoteAddr
-	if ip == "" {
-		ip, _, _ = net.SplitHostPort(r.RemoteAddr)
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
 	}
 
-	return ip
+	return remoteIP
+}
+
+func isTrustedProxy(ip string) bool {
+	return ipInNetworks(ip, currentConfig().TrustedProxies)
 }
 
 // Вычисление хэша файла
@@ -319,15 +1073,3 @@ func calculateFileHash(filename string) (string, error) {
 
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
-
-func loadNews() ([]NewsItem, error) {
-	// Читаем JSON файл
-	data, err := os.ReadFile("news/news.json")
-	if err != nil {
-		return nil, err
-	}
-
-	var news []NewsItem
-	err = json.Unmarshal(data, &news)
-	return news, err
-}
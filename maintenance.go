@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Режим технических работ: скачивание клиентов приостанавливается,
+// а лаунчер видит флаг maintenance в /api/version. С REDIS_URL это копия
+// общего значения, которую обновляет runSharedState.
+var maintenanceMode atomic.Bool
+
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Начальное значение: MAINTENANCE_MODE, а с Redis — общее значение, если
+// другой экземпляр его уже задал
+func initMaintenance(cfg *Config) {
+	maintenanceMode.Store(cfg.MaintenanceMode)
+	if sharedState == nil {
+		return
+	}
+	if _, err := sharedState.Do("SET", sharedState.key("maintenance"), strconv.FormatBool(cfg.MaintenanceMode), "NX"); err != nil {
+		sharedState.logError("maintenance", err)
+	}
+	if enabled, ok := sharedState.Maintenance(); ok {
+		maintenanceMode.Store(enabled)
+	}
+}
+
+// Общее значение режима техработ
+func (c *RedisClient) Maintenance() (bool, bool) {
+	reply, err := c.Do("GET", c.key("maintenance"))
+	if err != nil {
+		c.logError("maintenance", err)
+		return false, false
+	}
+	value, ok := redisString(reply)
+	return value == "true", ok
+}
+
+// Переключение режима; возвращает прежнее значение
+func setMaintenance(enabled bool) bool {
+	previous := maintenanceMode.Swap(enabled)
+	if sharedState == nil {
+		return previous
+	}
+	reply, err := sharedState.Do("GETSET", sharedState.key("maintenance"), strconv.FormatBool(enabled))
+	if err != nil {
+		sharedState.logError("maintenance", err)
+		return previous
+	}
+	// Прежним считается общее значение: иначе экземпляр, еще не
+	// получивший чужое переключение, разослал бы уведомления повторно
+	value, ok := redisString(reply)
+	return ok && value == "true"
+}
+
+// Текущее состояние режима техработ
+func (l *Logger) adminGetMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🚧", "/admin/api/maintenance", func() {
+		json.NewEncoder(w).Encode(MaintenanceStatus{Enabled: maintenanceMode.Load()})
+	})
+}
+
+// Включение и выключение режима техработ
+func (l *Logger) adminSetMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🚧", "/admin/api/maintenance", func() {
+		var req MaintenanceStatus
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		if setMaintenance(req.Enabled) != req.Enabled {
+			eventHub.Publish(EventMaintenance, req)
+			l.dispatchWebhook(WebhookMaintenanceToggled, req)
+			if !req.Enabled {
+				l.pushNotify(nil, PushServerOnline, "push_server_online")
+			}
+		}
+		json.NewEncoder(w).Encode(req)
+		l.logSuccess("Режим техработ: %v", req.Enabled)
+	})
+}
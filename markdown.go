@@ -0,0 +1,245 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Рендер Markdown для текстов новостей.
+//
+// Поддерживается подмножество разметки: заголовки, абзацы, списки, цитаты,
+// блоки кода, горизонтальные линии, **жирный**, *курсив*, `код`, ссылки и
+// изображения. Санитизация встроена в рендер: любой текст экранируется,
+// «сырой» HTML не пропускается, а в ссылках разрешены только http(s), mailto
+// и относительные пути. На выходе могут быть только теги из белого списка.
+
+var (
+	mdHeadingRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdUnorderedRe   = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	mdOrderedRe     = regexp.MustCompile(`^\s{0,3}\d{1,9}[.)]\s+(.*)$`)
+	mdRuleRe        = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdEscapableRune = "\\`*_{}[]()#+-.!>"
+)
+
+// Преобразование Markdown в безопасный HTML
+func renderMarkdown(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	return renderMarkdownBlocks(strings.Split(source, "\n"))
+}
+
+func renderMarkdownBlocks(lines []string) string {
+	var b strings.Builder
+	var paragraph []string
+
+	flushParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		b.WriteString(renderInline(strings.Join(paragraph, "\n")))
+		b.WriteString("</p>\n")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flushParagraph()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+
+		case mdHeadingRe.MatchString(trimmed):
+			flushParagraph()
+			m := mdHeadingRe.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+		case mdRuleRe.MatchString(line):
+			flushParagraph()
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				text := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(text, " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			b.WriteString(renderMarkdownBlocks(quote))
+			b.WriteString("</blockquote>\n")
+
+		case mdUnorderedRe.MatchString(line), mdOrderedRe.MatchString(line):
+			flushParagraph()
+			re, tag := mdUnorderedRe, "ul"
+			if !mdUnorderedRe.MatchString(line) {
+				re, tag = mdOrderedRe, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && re.MatchString(lines[i]); i++ {
+				m := re.FindStringSubmatch(lines[i])
+				b.WriteString("<li>" + renderInline(m[1]) + "</li>\n")
+			}
+			i--
+			b.WriteString("</" + tag + ">\n")
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flushParagraph()
+
+	return b.String()
+}
+
+// Рендер строчной разметки; весь текст экранируется
+func renderInline(s string) string {
+	var b strings.Builder
+	plainStart := 0
+
+	flushPlain := func(end int) {
+		if end > plainStart {
+			b.WriteString(html.EscapeString(s[plainStart:end]))
+		}
+	}
+
+	for i := 0; i < len(s); {
+		var (
+			rendered string
+			consumed int
+		)
+
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte(mdEscapableRune, s[i+1]) >= 0:
+			rendered, consumed = html.EscapeString(s[i+1:i+2]), 2
+
+		case s[i] == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				rendered = "<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>"
+				consumed = end + 2
+			}
+
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__"):
+			delim := s[i : i+2]
+			if end := strings.Index(s[i+2:], delim); end > 0 {
+				rendered = "<strong>" + renderInline(s[i+2:i+2+end]) + "</strong>"
+				consumed = end + 4
+			}
+
+		case (s[i] == '*' || s[i] == '_') && isWordBoundary(s, i):
+			if end := strings.IndexByte(s[i+1:], s[i]); end > 0 {
+				rendered = "<em>" + renderInline(s[i+1:i+1+end]) + "</em>"
+				consumed = end + 2
+			}
+
+		case s[i] == '!' && strings.HasPrefix(s[i+1:], "["):
+			if text, target, n, ok := parseMarkdownLink(s[i+1:]); ok {
+				if href, safe := sanitizeURL(target); safe {
+					rendered = `<img src="` + html.EscapeString(href) + `" alt="` + html.EscapeString(text) + `">`
+				} else {
+					rendered = html.EscapeString(text)
+				}
+				consumed = n + 1
+			}
+
+		case s[i] == '[':
+			if text, target, n, ok := parseMarkdownLink(s[i:]); ok {
+				if href, safe := sanitizeURL(target); safe {
+					rendered = renderAnchor(href, renderInline(text))
+				} else {
+					rendered = renderInline(text)
+				}
+				consumed = n
+			}
+
+		case (strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")) && isWordBoundary(s, i):
+			end := i
+			for end < len(s) && !isSpaceByte(s[end]) && s[end] != '<' {
+				end++
+			}
+			target := strings.TrimRight(s[i:end], ".,;:!?)")
+			if href, safe := sanitizeURL(target); safe {
+				rendered = renderAnchor(href, html.EscapeString(target))
+				consumed = len(target)
+			}
+		}
+
+		if consumed == 0 {
+			i++
+			continue
+		}
+
+		flushPlain(i)
+		b.WriteString(rendered)
+		i += consumed
+		plainStart = i
+	}
+	flushPlain(len(s))
+
+	return b.String()
+}
+
+// Разбор конструкции [текст](адрес); возвращает число прочитанных байт
+func parseMarkdownLink(s string) (text, target string, consumed int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if !strings.HasPrefix(s, "[") || closeText < 0 {
+		return "", "", 0, false
+	}
+	closeTarget := strings.IndexByte(s[closeText+2:], ')')
+	if closeTarget < 0 {
+		return "", "", 0, false
+	}
+
+	text = s[1:closeText]
+	target = strings.TrimSpace(s[closeText+2 : closeText+2+closeTarget])
+	return text, target, closeText + 3 + closeTarget, true
+}
+
+// Белый список схем для ссылок и изображений
+func sanitizeURL(raw string) (string, bool) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto":
+		return parsed.String(), true
+	case "":
+		// Относительные ссылки допускаются только от корня сервера
+		if strings.HasPrefix(raw, "/") && !strings.HasPrefix(raw, "//") {
+			return parsed.String(), true
+		}
+	}
+	return "", false
+}
+
+func renderAnchor(href, inner string) string {
+	return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer" target="_blank">` + inner + `</a>`
+}
+
+func isWordBoundary(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	c := s[i-1]
+	return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,675 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Зеркала — отдельные экземпляры сервера у сообщества, которые раздают
+// те же сборки. Основной сервер выдает зеркалу токен, зеркало с ним
+// регистрирует адрес для уведомлений и забирает сборки, версии и новости
+// через /api/mirror/*. Зеркалом экземпляр становится, если задан
+// MIRROR_PRIMARY_URL или REPLICA_OF. Синхронизируется только основная игра: проекты
+// зеркало не раздает.
+
+// События, о которых основной сервер уведомляет зеркала
+var mirrorEvents = []string{WebhookBuildPublished, WebhookNewsCreated}
+
+// Зеркало, зарегистрированное на основном сервере. Token выдается один
+// раз при создании; им же подписываются уведомления.
+type Mirror struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Адрес для уведомлений; зеркало сообщает его при регистрации
+	NotifyURL    string     `json:"notify_url,omitempty"`
+	Token        string     `json:"token,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RegisteredAt *time.Time `json:"registered_at,omitempty"`
+	LastSyncAt   *time.Time `json:"last_sync_at,omitempty"`
+	LastSyncIP   string     `json:"last_sync_ip,omitempty"`
+}
+
+type MirrorRequest struct {
+	Name string `json:"name"`
+}
+
+type MirrorsResponse struct {
+	Mirrors []Mirror `json:"mirrors"`
+}
+
+type MirrorRegisterRequest struct {
+	NotifyURL string `json:"notify_url"`
+}
+
+// Файл, который зеркало должно раздавать
+type MirrorFile struct {
+	Artifact string `json:"artifact"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// Состояние основного сервера, к которому зеркало подтягивается
+type MirrorManifest struct {
+	LauncherVersion string       `json:"launcher_version"`
+	GameVersion     string       `json:"game_version"`
+	Files           []MirrorFile `json:"files"`
+	// SHA-256 news.json как он лежит на диске
+	NewsSHA256  string    `json:"news_sha256"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Тело уведомления зеркалу: что изменилось, зеркало само решает, что
+// забрать
+type MirrorNotification struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+}
+
+// Зеркала в DATA_DIR/mirrors.json
+type MirrorStore struct {
+	*jsonListStore[Mirror]
+}
+
+var mirrors = &MirrorStore{&jsonListStore[Mirror]{file: mirrorsFile}}
+
+// Сборки бывают по несколько гигабайт, поэтому общий таймаут большой
+var mirrorClient = &http.Client{Timeout: time.Hour}
+
+// Запросы синхронизации на зеркале: уведомление не ждет окончания
+// текущей синхронизации, а оставляет одну отложенную
+var mirrorSyncRequests = make(chan struct{}, 1)
+
+func mirrorsFile() string {
+	return filepath.Join(currentConfig().DataDir, "mirrors.json")
+}
+
+// Зеркала без токенов для админки
+func (s *MirrorStore) List() []Mirror {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Mirror, len(s.items))
+	for i, mirror := range s.items {
+		list[i] = mirror
+		list[i].Token = ""
+	}
+	return list
+}
+
+// Зеркало по токену из Authorization: Bearer
+func (s *MirrorStore) ByToken(token string) (Mirror, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mirror := range s.items {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(mirror.Token)) == 1 {
+			return mirror, true
+		}
+	}
+	return Mirror{}, false
+}
+
+// Зеркала, которым есть куда слать уведомления
+func (s *MirrorStore) subscribers() []Mirror {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Mirror
+	for _, mirror := range s.items {
+		if mirror.NotifyURL != "" {
+			list = append(list, mirror)
+		}
+	}
+	return list
+}
+
+// Подпись тела уведомления, как у вебхуков: sha256=<hex HMAC-SHA256>
+func mirrorSignature(token string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Уведомление зеркал о публикации. Повторов нет: пропущенное уведомление
+// зеркало наверстает при плановой синхронизации (MIRROR_SYNC_INTERVAL).
+func (l *Logger) notifyMirrors(event string) {
+	if !slices.Contains(mirrorEvents, event) {
+		return
+	}
+	body, err := json.Marshal(MirrorNotification{Event: event, Time: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	for _, mirror := range mirrors.subscribers() {
+		go func() {
+			_, err := postWebhook(mirror.NotifyURL, body, map[string]string{
+				"X-Loil-Event":     event,
+				"X-Loil-Signature": mirrorSignature(mirror.Token, body),
+			})
+			if err != nil {
+				l.logWarn("Не удалось уведомить зеркало %s о %s: %v", mirror.Name, event, err)
+			}
+		}()
+	}
+}
+
+// Проверка токена зеркала для /api/mirror/*
+func requireMirrorToken(w http.ResponseWriter, r *http.Request) (Mirror, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		writeError(w, r, http.StatusUnauthorized, ErrCodeInvalidMirrorToken)
+		return Mirror{}, false
+	}
+	mirror, ok := mirrors.ByToken(token)
+	if !ok {
+		writeError(w, r, http.StatusUnauthorized, ErrCodeInvalidMirrorToken)
+		return Mirror{}, false
+	}
+	return mirror, true
+}
+
+// Состояние основного сервера для зеркала
+func (l *Logger) buildMirrorManifest(cfg *Config) (MirrorManifest, error) {
+	manifest := MirrorManifest{LauncherVersion: cfg.LauncherVersion, GameVersion: cfg.GameVersion, Files: []MirrorFile{}, GeneratedAt: time.Now().UTC()}
+	for _, artifact := range []string{"launcher", "game"} {
+		name, _ := artifactFilename(cfg, artifact)
+		path := filepath.Join(cfg.ClientsDir, name)
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return manifest, err
+		}
+		indexed, err := clientIndex.Lookup(path, info)
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, MirrorFile{Artifact: artifact, Filename: name, Size: info.Size(), SHA256: indexed.SHA256})
+	}
+
+	data, err := os.ReadFile(newsFile)
+	if err != nil && !os.IsNotExist(err) {
+		return manifest, err
+	}
+	if err == nil {
+		sum := sha256.Sum256(data)
+		manifest.NewsSHA256 = hex.EncodeToString(sum[:])
+	}
+	return manifest, nil
+}
+
+// Регистрация зеркала: адрес, куда слать уведомления о публикации
+func (l *Logger) mirrorRegisterHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪞", "/api/mirror/register", func() {
+		mirror, ok := requireMirrorToken(w, r)
+		if !ok {
+			return
+		}
+		var req MirrorRegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		if u, err := url.Parse(req.NotifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		now := time.Now().UTC()
+		apiErr, err := mirrors.update(func(list []Mirror) ([]Mirror, *modError) {
+			i := slices.IndexFunc(list, func(m Mirror) bool { return m.ID == mirror.ID })
+			if i < 0 {
+				return nil, &modError{http.StatusUnauthorized, ErrCodeInvalidMirrorToken, nil}
+			}
+			list[i].NotifyURL, list[i].RegisteredAt = req.NotifyURL, &now
+			return list, nil
+		})
+		if apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения зеркал: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Зеркало %s зарегистрировано: %s", mirror.Name, req.NotifyURL)
+	})
+}
+
+// Что должно быть на зеркале: версии, файлы с хэшами и хэш новостей
+func (l *Logger) mirrorManifestHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪞", "/api/mirror/manifest", func() {
+		mirror, ok := requireMirrorToken(w, r)
+		if !ok {
+			return
+		}
+		manifest, err := l.buildMirrorManifest(currentConfig())
+		if err != nil {
+			l.logError("Ошибка подготовки состояния для зеркала %s: %v", mirror.Name, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+		json.NewEncoder(w).Encode(manifest)
+
+		now, ip := time.Now().UTC(), getClientIP(r)
+		_, err = mirrors.update(func(list []Mirror) ([]Mirror, *modError) {
+			if i := slices.IndexFunc(list, func(m Mirror) bool { return m.ID == mirror.ID }); i >= 0 {
+				list[i].LastSyncAt, list[i].LastSyncIP = &now, ip
+			}
+			return list, nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения зеркал: %v", err)
+		}
+	})
+}
+
+// Файл сборки для зеркала. Квоты и статистика скачиваний игроков его не
+// касаются.
+func (l *Logger) mirrorFileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪞", "/api/mirror/files/{artifact}", func() {
+		mirror, ok := requireMirrorToken(w, r)
+		if !ok {
+			return
+		}
+		cfg := currentConfig()
+		artifact := r.PathValue("artifact")
+		name, ok := artifactFilename(cfg, artifact)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+
+		path := filepath.Join(cfg.ClientsDir, name)
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка открытия %s для зеркала: %v", path, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+		indexed, err := clientIndex.Lookup(path, info)
+		if err != nil {
+			l.logError("Ошибка вычисления хэша файла %s: %v", path, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("X-File-SHA256", indexed.SHA256)
+		http.ServeContent(w, r, name, info.ModTime(), file)
+		l.logSuccess("Зеркало %s забирает %s", mirror.Name, name)
+	})
+}
+
+// news.json как есть, со всеми статусами: планировщик зеркала публикует
+// отложенные новости сам
+func (l *Logger) mirrorNewsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪞", "/api/mirror/news", func() {
+		if _, ok := requireMirrorToken(w, r); !ok {
+			return
+		}
+		data, err := os.ReadFile(newsFile)
+		if err != nil {
+			l.logError("Ошибка загрузки новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+			return
+		}
+		w.Write(data)
+	})
+}
+
+// Уведомление от основного сервера на зеркале; подпись проверяется
+// MIRROR_TOKEN
+func (l *Logger) mirrorNotifyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪞", "/api/mirror/notify", func() {
+		cfg := currentConfig()
+		if cfg.MirrorPrimaryURL == "" {
+			writeError(w, r, http.StatusNotFound, ErrCodeMirrorSyncDisabled)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		if !hmac.Equal([]byte(r.Header.Get("X-Loil-Signature")), []byte(mirrorSignature(cfg.MirrorToken, body))) {
+			writeError(w, r, http.StatusUnauthorized, ErrCodeInvalidMirrorSignature)
+			return
+		}
+		var notification MirrorNotification
+		if err := json.Unmarshal(body, &notification); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		select {
+		case mirrorSyncRequests <- struct{}{}:
+		default:
+		}
+		w.WriteHeader(http.StatusAccepted)
+		l.logSuccess("Основной сервер сообщил о %s, синхронизация запрошена", notification.Event)
+	})
+}
+
+// Запрос к API синхронизации основного сервера с токеном зеркала
+func mirrorGet(cfg *Config, path string) (*http.Response, error) {
+	return mirrorRequest(cfg, http.MethodGet, path, nil)
+}
+
+func mirrorRequest(cfg *Config, method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, cfg.MirrorPrimaryURL+"/api/v1/mirror/"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.MirrorToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := mirrorClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: ответ %s", path, resp.Status)
+	}
+	return resp, nil
+}
+
+// Регистрация на основном сервере с адресом для уведомлений
+func registerMirror(cfg *Config) error {
+	body, err := json.Marshal(MirrorRegisterRequest{NotifyURL: cfg.PublicURL + "/api/v1/mirror/notify"})
+	if err != nil {
+		return err
+	}
+	resp, err := mirrorRequest(cfg, http.MethodPost, "register", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Скачивание сборки во временный файл рядом с целевым с проверкой
+// размера и SHA-256 из манифеста. Имя начинается с точки, поэтому
+// наблюдатель каталога клиентов его не видит.
+func downloadMirrorFile(cfg *Config, file MirrorFile) (string, uploadedFile, error) {
+	resp, err := mirrorGet(cfg, "files/"+file.Artifact)
+	if err != nil {
+		return "", uploadedFile{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(cfg.ClientsDir, 0755); err != nil {
+		return "", uploadedFile{}, err
+	}
+	tmp, err := os.CreateTemp(cfg.ClientsDir, ".mirror-"+file.Filename+"-*")
+	if err != nil {
+		return "", uploadedFile{}, err
+	}
+	sha, md := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, sha, md), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	os.Chmod(tmp.Name(), 0644)
+	uploaded := uploadedFile{Size: size, MD5: hex.EncodeToString(md.Sum(nil)), SHA256: hex.EncodeToString(sha.Sum(nil))}
+	if err == nil && (size != file.Size || uploaded.SHA256 != file.SHA256) {
+		err = fmt.Errorf("%s: получено %d bytes с SHA-256 %s, ожидалось %d bytes с %s", file.Filename, size, uploaded.SHA256, file.Size, file.SHA256)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", uploadedFile{}, err
+	}
+	return tmp.Name(), uploaded, nil
+}
+
+// Одна синхронизация с основным сервером: сначала скачиваются и
+// проверяются все изменившиеся сборки, затем они подменяются вместе с
+// версиями, как при публикации релиза. Манифесты чанков, .torrent и
+// сжатые варианты достраивает наблюдатель каталога клиентов.
+func (l *Logger) syncMirror(cfg *Config) error {
+	resp, err := mirrorGet(cfg, "manifest")
+	if err != nil {
+		return err
+	}
+	var manifest MirrorManifest
+	err = json.NewDecoder(resp.Body).Decode(&manifest)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("manifest: %v", err)
+	}
+	if !isSemver(manifest.LauncherVersion) || !isSemver(manifest.GameVersion) {
+		return fmt.Errorf("manifest: некорректные версии %q, %q", manifest.LauncherVersion, manifest.GameVersion)
+	}
+
+	type staged struct {
+		file     MirrorFile
+		tmp      string
+		uploaded uploadedFile
+	}
+	var changed []staged
+	defer func() {
+		for _, s := range changed {
+			os.Remove(s.tmp)
+		}
+	}()
+	for _, file := range manifest.Files {
+		// Имена берутся из своей конфигурации: на зеркале они могут отличаться
+		name, ok := artifactFilename(cfg, file.Artifact)
+		if !ok {
+			continue
+		}
+		path := filepath.Join(cfg.ClientsDir, name)
+		if info, err := os.Stat(path); err == nil {
+			if indexed, err := clientIndex.Lookup(path, info); err == nil && indexed.SHA256 == file.SHA256 {
+				continue
+			}
+		}
+		tmp, uploaded, err := downloadMirrorFile(cfg, file)
+		if err != nil {
+			return err
+		}
+		file.Filename = name
+		changed = append(changed, staged{file, tmp, uploaded})
+	}
+
+	newsChanged, err := l.syncMirrorNews(cfg, manifest.NewsSHA256)
+	if err != nil {
+		l.logError("Ошибка синхронизации новостей с основным сервером: %v", err)
+	}
+
+	versionsChanged := manifest.LauncherVersion != cfg.LauncherVersion || manifest.GameVersion != cfg.GameVersion
+	if len(changed) == 0 && !versionsChanged {
+		if newsChanged {
+			l.logSuccess("Зеркало: новости обновлены")
+		}
+		return nil
+	}
+
+	publishMu.Lock()
+	defer publishMu.Unlock()
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	for i, s := range changed {
+		target := filepath.Join(cfg.ClientsDir, s.file.Filename)
+		if err := renameFile(s.tmp, target); err != nil {
+			changed = changed[i:]
+			return err
+		}
+		clientIndex.Remember(target, s.uploaded)
+	}
+	names := make([]string, len(changed))
+	for i, s := range changed {
+		names[i] = s.file.Filename
+	}
+	changed = nil
+
+	next := *currentConfig()
+	next.LauncherVersion, next.GameVersion = manifest.LauncherVersion, manifest.GameVersion
+	if versionsChanged {
+		published := PublishedVersions{Release: "mirror", LauncherVersion: next.LauncherVersion, GameVersion: next.GameVersion, PublishedAt: time.Now().UTC()}
+		if err := saveJSONFile(publishedVersionsFile(next.DataDir), published); err != nil {
+			return err
+		}
+		configSnapshot.Store(&next)
+	}
+	versionCache.Store(nil)
+	eventHub.PublishVersion()
+	l.logSuccess("Зеркало синхронизировано: лаунчер %s, игра %s, обновлены файлы %v", next.LauncherVersion, next.GameVersion, names)
+	return nil
+}
+
+// Новости зеркала: news.json основного сервера байт в байт, если его
+// SHA-256 отличается от своего
+func (l *Logger) syncMirrorNews(cfg *Config, want string) (bool, error) {
+	if want == "" {
+		return false, nil
+	}
+	if data, err := os.ReadFile(newsFile); err == nil {
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) == want {
+			return false, nil
+		}
+	}
+
+	resp, err := mirrorGet(cfg, "news")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	// Новости могли измениться между манифестом и этим запросом;
+	// тогда их заберет следующая синхронизация
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != want {
+		return false, fmt.Errorf("SHA-256 news.json не совпал с манифестом")
+	}
+	var news []NewsItem
+	if err := json.Unmarshal(data, &news); err != nil {
+		return false, err
+	}
+
+	newsMu.Lock()
+	defer newsMu.Unlock()
+	if err := writeFileAtomic(newsFile, data); err != nil {
+		return false, err
+	}
+	newsCache.Invalidate()
+	eventHub.PublishNews(news)
+	return true, nil
+}
+
+// Фоновая синхронизация зеркала: при запуске, по уведомлению основного
+// сервера и раз в MIRROR_SYNC_INTERVAL. Регистрация повторяется, пока не
+// пройдет, и после смены MIRROR_PRIMARY_URL.
+func (l *Logger) runMirrorSync() {
+	registered := ""
+	for {
+		cfg := currentConfig()
+		if cfg.MirrorPrimaryURL != "" {
+			if registered != cfg.MirrorPrimaryURL {
+				if err := registerMirror(cfg); err != nil {
+					l.logError("Ошибка регистрации на основном сервере %s: %v", cfg.MirrorPrimaryURL, err)
+				} else {
+					registered = cfg.MirrorPrimaryURL
+					l.logSuccess("Зеркало зарегистрировано на %s", cfg.MirrorPrimaryURL)
+				}
+			}
+			if err := l.syncMirror(cfg); err != nil {
+				l.logError("Ошибка синхронизации с основным сервером: %v", err)
+			}
+		}
+
+		select {
+		case <-mirrorSyncRequests:
+		case <-time.After(cfg.MirrorSyncInterval):
+		}
+	}
+}
+
+func (l *Logger) adminListMirrorsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪞", "/admin/api/mirrors", func() {
+		json.NewEncoder(w).Encode(MirrorsResponse{Mirrors: mirrors.List()})
+	})
+}
+
+// Новое зеркало; токен для MIRROR_TOKEN есть только в этом ответе
+func (l *Logger) adminCreateMirrorHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪞", "/admin/api/mirrors", func() {
+		var req MirrorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" || len(req.Name) > 128 {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+
+		mirror := Mirror{ID: randomID(6), Name: strings.TrimSpace(req.Name), Token: randomID(24), CreatedAt: time.Now().UTC()}
+		_, err := mirrors.update(func(list []Mirror) ([]Mirror, *modError) {
+			return append(list, mirror), nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения зеркал: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(mirror)
+		l.logSuccess("Добавлено зеркало %s: %s", mirror.ID, mirror.Name)
+	})
+}
+
+// Удаление зеркала отзывает его токен
+func (l *Logger) adminDeleteMirrorHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪞", "/admin/api/mirrors/{id}", func() {
+		id := r.PathValue("id")
+		apiErr, err := mirrors.update(func(list []Mirror) ([]Mirror, *modError) {
+			i := slices.IndexFunc(list, func(m Mirror) bool { return m.ID == id })
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeMirrorNotFound, []interface{}{id}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения зеркал: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Зеркало %s удалено", id)
+	})
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Профиль сборки: набор модов, переопределения конфигов и ресурспаки.
+// Один сервер может раздавать несколько профилей («Ванилла+», «Хардкор»).
+type Modpack struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Mods        []ModpackMod `json:"mods"`
+	// Путь файла конфигурации относительно каталога игры → содержимое
+	ConfigOverrides map[string]string `json:"config_overrides"`
+	ResourcePacks   []string          `json:"resource_packs"`
+}
+
+// Мод в профиле; без версии берется самая новая для версии игры
+type ModpackMod struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+}
+
+// Профиль для лаунчера с уже разрешенными версиями и ссылками
+type ModpackResponse struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	Description     string             `json:"description,omitempty"`
+	GameVersion     string             `json:"game_version"`
+	Mods            []ModManifestEntry `json:"mods"`
+	ConfigOverrides map[string]string  `json:"config_overrides"`
+	ResourcePacks   []ResourcePackInfo `json:"resource_packs"`
+	// Моды и ресурспаки, для которых нет подходящей загруженной версии
+	Missing []string `json:"missing,omitempty"`
+}
+
+type ModpacksResponse struct {
+	GameVersion string            `json:"game_version"`
+	Modpacks    []ModpackResponse `json:"modpacks"`
+}
+
+type AdminModpacksResponse struct {
+	Modpacks []Modpack `json:"modpacks"`
+}
+
+// Профили хранятся в DATA_DIR/modpacks.json
+type ModpackStore struct {
+	mu       sync.Mutex
+	modpacks []Modpack
+}
+
+var modpacks = &ModpackStore{}
+
+func modpacksFile() string {
+	return filepath.Join(currentConfig().DataDir, "modpacks.json")
+}
+
+func (s *ModpackStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(modpacksFile(), &s.modpacks)
+}
+
+func (s *ModpackStore) List() []Modpack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.modpacks)
+}
+
+func (s *ModpackStore) Put(pack Modpack) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := slices.Clone(s.modpacks)
+	if i := slices.IndexFunc(next, func(p Modpack) bool { return p.ID == pack.ID }); i >= 0 {
+		next[i] = pack
+	} else {
+		next = append(next, pack)
+	}
+	if err := saveJSONFile(modpacksFile(), next); err != nil {
+		return err
+	}
+	s.modpacks = next
+	return nil
+}
+
+func (s *ModpackStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.modpacks, func(p Modpack) bool { return p.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	next := slices.Delete(slices.Clone(s.modpacks), i, i+1)
+	if err := saveJSONFile(modpacksFile(), next); err != nil {
+		return true, err
+	}
+	s.modpacks = next
+	return true, nil
+}
+
+// Путь переопределения должен оставаться внутри каталога игры
+func validOverridePath(p string) bool {
+	if p == "" || strings.Contains(p, "\\") || path.IsAbs(p) {
+		return false
+	}
+	clean := path.Clean(p)
+	return clean == p && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// Разрешение профиля: закрепленные версии, обязательные моды репозитория
+// и зависимости (транзитивно) для указанной версии игры, текущие версии ресурспаков
+func resolveModpack(cfg *Config, pack Modpack, list []Mod, gameVersion string) ModpackResponse {
+	response := ModpackResponse{
+		ID:              pack.ID,
+		Name:            pack.Name,
+		Description:     pack.Description,
+		GameVersion:     gameVersion,
+		Mods:            []ModManifestEntry{},
+		ConfigOverrides: pack.ConfigOverrides,
+		ResourcePacks:   []ResourcePackInfo{},
+	}
+
+	for _, name := range pack.ResourcePacks {
+		resourcePack, version, ok := lookupResourcePack(name, "")
+		if !ok {
+			response.Missing = append(response.Missing, name)
+			continue
+		}
+		response.ResourcePacks = append(response.ResourcePacks, resourcePackInfo(cfg, resourcePack, version))
+	}
+
+	queue := slices.Clone(pack.Mods)
+	for _, mod := range list {
+		if mod.Required {
+			queue = append(queue, ModpackMod{ID: mod.ID})
+		}
+	}
+
+	added := make(map[string]bool)
+	for len(queue) > 0 {
+		wanted := queue[0]
+		queue = queue[1:]
+		if added[wanted.ID] {
+			continue
+		}
+		added[wanted.ID] = true
+
+		i := findMod(list, wanted.ID)
+		if i < 0 {
+			response.Missing = append(response.Missing, wanted.ID)
+			continue
+		}
+		mod := list[i]
+
+		var version ModVersion
+		ok := false
+		if wanted.Version != "" {
+			if j := findModVersion(mod, wanted.Version); j >= 0 && mod.Versions[j].Filename != "" {
+				version, ok = mod.Versions[j], true
+			}
+		} else {
+			version, ok = mod.latestFor(gameVersion)
+		}
+		if !ok {
+			response.Missing = append(response.Missing, wanted.ID)
+			continue
+		}
+
+		response.Mods = append(response.Mods, modManifestEntry(cfg, mod, version))
+		for _, dep := range version.Dependencies {
+			queue = append(queue, ModpackMod{ID: dep.ID})
+		}
+	}
+	return response
+}
+
+// Все профили для версии игры: /api/modpacks?game_version=1.4
+func (l *Logger) modpacksHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎒", "/api/modpacks", func() {
+		cfg := currentConfig()
+		gameVersion := r.URL.Query().Get("game_version")
+		if gameVersion == "" {
+			gameVersion = cfg.GameVersion
+		}
+
+		list := mods.List()
+		response := ModpacksResponse{GameVersion: gameVersion, Modpacks: []ModpackResponse{}}
+		for _, pack := range modpacks.List() {
+			response.Modpacks = append(response.Modpacks, resolveModpack(cfg, pack, list, gameVersion))
+		}
+
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Отправлены профили сборок для %s: %d", gameVersion, len(response.Modpacks))
+	})
+}
+
+// Профили в исходном виде для админки
+func (l *Logger) adminListModpacksHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎒", "/admin/api/modpacks", func() {
+		json.NewEncoder(w).Encode(AdminModpacksResponse{Modpacks: modpacks.List()})
+	})
+}
+
+// Создание или замена профиля
+func (l *Logger) adminPutModpackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎒", "/admin/api/modpacks/{id}", func() {
+		var pack Modpack
+		if err := json.NewDecoder(r.Body).Decode(&pack); err != nil || pack.Name == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		pack.ID = r.PathValue("id")
+		if !modIDPattern.MatchString(pack.ID) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if pack.Mods == nil {
+			pack.Mods = []ModpackMod{}
+		}
+		if pack.ConfigOverrides == nil {
+			pack.ConfigOverrides = map[string]string{}
+		}
+		if pack.ResourcePacks == nil {
+			pack.ResourcePacks = []string{}
+		}
+
+		list := mods.List()
+		for _, mod := range pack.Mods {
+			if findMod(list, mod.ID) < 0 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeModNotFound, mod.ID)
+				return
+			}
+		}
+		packs := resourcePacks.List()
+		for _, name := range pack.ResourcePacks {
+			if findResourcePack(packs, name) < 0 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeResourcePackNotFound, name)
+				return
+			}
+		}
+		for p := range pack.ConfigOverrides {
+			if !validOverridePath(p) {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+		}
+
+		if err := modpacks.Put(pack); err != nil {
+			l.logError("Ошибка сохранения профилей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		json.NewEncoder(w).Encode(pack)
+		l.logSuccess("Профиль %s «%s» сохранен: модов %d", pack.ID, pack.Name, len(pack.Mods))
+	})
+}
+
+// Удаление профиля
+func (l *Logger) adminDeleteModpackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎒", "/admin/api/modpacks/{id}", func() {
+		id := r.PathValue("id")
+		found, err := modpacks.Delete(id)
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeModpackNotFound, id)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения профилей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Профиль %s удален", id)
+	})
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var modIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Мод в репозитории. Required — мод обязателен для игры на сервере,
+// остальные игрок включает по желанию.
+type Mod struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	// Право аккаунта, без которого мод не скачать (косметика для поддержавших)
+	Entitlement string       `json:"entitlement,omitempty"`
+	Versions    []ModVersion `json:"versions"`
+}
+
+type ModVersion struct {
+	Version string `json:"version"`
+	// Версии игры: "1.4" подходит и для 1.4, и для 1.4.2
+	GameVersions []string        `json:"game_versions"`
+	Dependencies []ModDependency `json:"dependencies"`
+	Filename     string          `json:"filename,omitempty"`
+	Size         int64           `json:"size,omitempty"`
+	Hash         string          `json:"hash,omitempty"`
+	SHA256       string          `json:"sha256,omitempty"`
+	UploadedAt   *time.Time      `json:"uploaded_at,omitempty"`
+}
+
+type ModDependency struct {
+	ID string `json:"id"`
+	// Минимальная версия зависимости; пустая — любая
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// Запросы админки
+type ModRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+	Entitlement string `json:"entitlement"`
+}
+
+type ModVersionRequest struct {
+	GameVersions []string        `json:"game_versions"`
+	Dependencies []ModDependency `json:"dependencies"`
+}
+
+// Манифест модов для конкретной версии игры
+type ModsManifest struct {
+	GameVersion string             `json:"game_version"`
+	Mods        []ModManifestEntry `json:"mods"`
+}
+
+type ModManifestEntry struct {
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	Required     bool            `json:"required"`
+	Entitlement  string          `json:"entitlement,omitempty"`
+	Version      string          `json:"version"`
+	Dependencies []ModDependency `json:"dependencies"`
+	URL          string          `json:"url"`
+	Size         int64           `json:"size"`
+	Hash         string          `json:"hash"`
+	SHA256       string          `json:"sha256"`
+}
+
+type ModsResponse struct {
+	Mods []Mod `json:"mods"`
+}
+
+// Хранилище модов: список в DATA_DIR/mods.json, файлы в
+// DATA_DIR/mods/<id>/<version>/
+type ModStore struct {
+	*jsonListStore[Mod]
+}
+
+var mods = &ModStore{&jsonListStore[Mod]{file: modsFile}}
+
+func modsFile() string {
+	return filepath.Join(currentConfig().DataDir, "mods.json")
+}
+
+func modVersionDir(id, version string) string {
+	return filepath.Join(currentConfig().DataDir, "mods", id, version)
+}
+
+func findMod(mods []Mod, id string) int {
+	return slices.IndexFunc(mods, func(m Mod) bool { return m.ID == id })
+}
+
+func findModVersion(mod Mod, version string) int {
+	return slices.IndexFunc(mod.Versions, func(v ModVersion) bool { return v.Version == version })
+}
+
+// Сравнение версий по числовым компонентам: 1.10 новее 1.9.
+// Нечисловые компоненты сравниваются как строки.
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '+' })
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y string
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errX := strconv.Atoi(x)
+		ny, errY := strconv.Atoi(y)
+		switch {
+		case errX == nil && errY == nil && nx != ny:
+			if nx < ny {
+				return -1
+			}
+			return 1
+		case (errX != nil || errY != nil) && x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Подходит ли версия мода под версию игры
+func (v ModVersion) supportsGame(gameVersion string) bool {
+	for _, target := range v.GameVersions {
+		if gameVersion == target || strings.HasPrefix(gameVersion, target+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Самая новая загруженная версия мода для версии игры
+func (m Mod) latestFor(gameVersion string) (ModVersion, bool) {
+	var best ModVersion
+	found := false
+	for _, version := range m.Versions {
+		if version.Filename == "" || !version.supportsGame(gameVersion) {
+			continue
+		}
+		if !found || compareVersions(version.Version, best.Version) > 0 {
+			best, found = version, true
+		}
+	}
+	return best, found
+}
+
+func buildModsManifest(cfg *Config, list []Mod, gameVersion string) ModsManifest {
+	manifest := ModsManifest{GameVersion: gameVersion, Mods: []ModManifestEntry{}}
+	for _, mod := range list {
+		version, ok := mod.latestFor(gameVersion)
+		if !ok {
+			continue
+		}
+		manifest.Mods = append(manifest.Mods, modManifestEntry(cfg, mod, version))
+	}
+	return manifest
+}
+
+func modManifestEntry(cfg *Config, mod Mod, version ModVersion) ModManifestEntry {
+	return ModManifestEntry{
+		ID:           mod.ID,
+		Name:         mod.Name,
+		Description:  mod.Description,
+		Required:     mod.Required,
+		Entitlement:  mod.Entitlement,
+		Version:      version.Version,
+		Dependencies: version.Dependencies,
+		URL:          cfg.PublicURL + "/api/download/mod/" + mod.ID + "/" + version.Version,
+		Size:         version.Size,
+		Hash:         version.Hash,
+		SHA256:       version.SHA256,
+	}
+}
+
+// Манифест модов: /api/mods?game_version=1.4
+func (l *Logger) modsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧩", "/api/mods", func() {
+		cfg := currentConfig()
+		gameVersion := r.URL.Query().Get("game_version")
+		if gameVersion == "" {
+			gameVersion = cfg.GameVersion
+		}
+
+		manifest := buildModsManifest(cfg, mods.List(), gameVersion)
+		json.NewEncoder(w).Encode(manifest)
+		l.logSuccess("Отправлен манифест модов для %s: %d модов", gameVersion, len(manifest.Mods))
+	})
+}
+
+// Скачивание файла мода
+func (l *Logger) downloadModHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧩", "/api/download/mod/{id}/{version}", func() {
+		if rejectSwitchedOff(w, r, SwitchModDownloads) {
+			return
+		}
+		id, version := r.PathValue("id"), r.PathValue("version")
+		list := mods.List()
+		i := findMod(list, id)
+		if i < 0 {
+			writeError(w, r, http.StatusNotFound, ErrCodeModNotFound, id)
+			return
+		}
+		j := findModVersion(list[i], version)
+		if j < 0 || list[i].Versions[j].Filename == "" {
+			writeError(w, r, http.StatusNotFound, ErrCodeModVersionNotFound, id, version)
+			return
+		}
+		if l.rejectWithoutEntitlement(w, r, list[i].Entitlement, "мода "+id) {
+			return
+		}
+		if err := artifactDownloads.Touch("mod", id, version); err != nil {
+			l.logError("Ошибка сохранения времени скачивания мода %s %s: %v", id, version, err)
+		}
+		l.serveFileDownload(w, r, filepath.Join(modVersionDir(id, version), list[i].Versions[j].Filename), "mod")
+	})
+}
+
+// Полный список модов со всеми версиями
+func (l *Logger) adminListModsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧩", "/admin/api/mods", func() {
+		json.NewEncoder(w).Encode(ModsResponse{Mods: mods.List()})
+	})
+}
+
+// Ответ на результат ModStore.update
+func (l *Logger) writeModUpdateResult(w http.ResponseWriter, r *http.Request, apiErr *modError, err error) bool {
+	if err != nil {
+		l.logError("Ошибка сохранения модов: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return false
+	}
+	if apiErr != nil {
+		writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+		return false
+	}
+	return true
+}
+
+// Создание или изменение описания мода
+func (l *Logger) adminPutModHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧩", "/admin/api/mods/{id}", func() {
+		id := r.PathValue("id")
+		var req ModRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !modIDPattern.MatchString(id) || req.Name == "" ||
+			(req.Entitlement != "" && !entitlementPattern.MatchString(req.Entitlement)) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		var result Mod
+		apiErr, err := mods.update(func(list []Mod) ([]Mod, *modError) {
+			i := findMod(list, id)
+			if i < 0 {
+				list = append(list, Mod{ID: id, Versions: []ModVersion{}})
+				i = len(list) - 1
+			}
+			list[i].Name = req.Name
+			list[i].Description = req.Description
+			list[i].Required = req.Required
+			list[i].Entitlement = req.Entitlement
+			result = list[i]
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(result)
+		l.logSuccess("Мод %s «%s» сохранен", id, req.Name)
+	})
+}
+
+// Создание или изменение версии мода (без файла)
+func (l *Logger) adminPutModVersionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧩", "/admin/api/mods/{id}/versions/{version}", func() {
+		id, version := r.PathValue("id"), r.PathValue("version")
+		var req ModVersionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !versionLabelPattern.MatchString(version) || len(req.GameVersions) == 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if req.Dependencies == nil {
+			req.Dependencies = []ModDependency{}
+		}
+
+		var result ModVersion
+		apiErr, err := mods.update(func(list []Mod) ([]Mod, *modError) {
+			i := findMod(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeModNotFound, []interface{}{id}}
+			}
+			for _, dep := range req.Dependencies {
+				if dep.ID == id || findMod(list, dep.ID) < 0 {
+					return nil, &modError{http.StatusBadRequest, ErrCodeModNotFound, []interface{}{dep.ID}}
+				}
+			}
+
+			j := findModVersion(list[i], version)
+			if j < 0 {
+				list[i].Versions = append(list[i].Versions, ModVersion{Version: version})
+				j = len(list[i].Versions) - 1
+			}
+			list[i].Versions[j].GameVersions = req.GameVersions
+			list[i].Versions[j].Dependencies = req.Dependencies
+			result = list[i].Versions[j]
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(result)
+		l.logSuccess("Версия %s мода %s сохранена", version, id)
+	})
+}
+
+// Загрузка файла версии мода: PUT /admin/api/mods/{id}/versions/{version}/file?filename=mod.jar
+func (l *Logger) adminUploadModFileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧩", "/admin/api/mods/{id}/versions/{version}/file", func() {
+		id, version := r.PathValue("id"), r.PathValue("version")
+		filename := r.URL.Query().Get("filename")
+		if filename == "" {
+			filename = id + "-" + version + ".jar"
+		}
+		if !uploadFilenamePattern.MatchString(filename) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		list := mods.List()
+		i := findMod(list, id)
+		if i < 0 || findModVersion(list[i], version) < 0 {
+			writeError(w, r, http.StatusNotFound, ErrCodeModVersionNotFound, id, version)
+			return
+		}
+
+		file, ok := l.receiveUpload(w, r, filepath.Join(modVersionDir(id, version), filename))
+		if !ok {
+			return
+		}
+		l.adoptBlob(BlobRef{Path: filepath.Join(modVersionDir(id, version), filename), Channel: "mod", Artifact: id, Version: version}, file)
+
+		var result ModVersion
+		var previous string
+		apiErr, err := mods.update(func(list []Mod) ([]Mod, *modError) {
+			i := findMod(list, id)
+			j := -1
+			if i >= 0 {
+				j = findModVersion(list[i], version)
+			}
+			if j < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeModVersionNotFound, []interface{}{id, version}}
+			}
+			now := time.Now().UTC()
+			v := &list[i].Versions[j]
+			previous = v.Filename
+			v.Filename, v.Size, v.Hash, v.SHA256, v.UploadedAt = filename, file.Size, file.MD5, file.SHA256, &now
+			result = *v
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		if previous != "" && previous != filename {
+			removeWithEncodings(filepath.Join(modVersionDir(id, version), previous))
+		}
+		go l.syncEncodedVariants(currentConfig(), filepath.Join(modVersionDir(id, version), filename), ClientFile{Size: file.Size, SHA256: file.SHA256})
+
+		json.NewEncoder(w).Encode(result)
+		l.logSuccess("Загружен файл мода %s %s: %s (%d bytes)", id, version, filename, file.Size)
+	})
+}
+
+// Удаление мода целиком
+func (l *Logger) adminDeleteModHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧩", "/admin/api/mods/{id}", func() {
+		id := r.PathValue("id")
+		apiErr, err := mods.update(func(list []Mod) ([]Mod, *modError) {
+			i := findMod(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeModNotFound, []interface{}{id}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		os.RemoveAll(filepath.Join(currentConfig().DataDir, "mods", id))
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Мод %s удален", id)
+	})
+}
+
+// Удаление версии мода
+func (l *Logger) adminDeleteModVersionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🧩", "/admin/api/mods/{id}/versions/{version}", func() {
+		id, version := r.PathValue("id"), r.PathValue("version")
+		apiErr, err := mods.update(func(list []Mod) ([]Mod, *modError) {
+			i := findMod(list, id)
+			j := -1
+			if i >= 0 {
+				j = findModVersion(list[i], version)
+			}
+			if j < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeModVersionNotFound, []interface{}{id, version}}
+			}
+			list[i].Versions = slices.Delete(list[i].Versions, j, j+1)
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		os.RemoveAll(modVersionDir(id, version))
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Версия %s мода %s удалена", version, id)
+	})
+}
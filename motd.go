@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Важность сообщения дня: от нее зависит цвет баннера в лаунчере
+const (
+	MOTDSeverityInfo     = "info"
+	MOTDSeverityWarning  = "warning"
+	MOTDSeverityCritical = "critical"
+)
+
+var motdSeverities = []string{MOTDSeverityInfo, MOTDSeverityWarning, MOTDSeverityCritical}
+
+// Сообщение дня — короткая строка для баннера, а не новость
+const motdMaxLength = 280
+
+// Объявление для баннера лаунчера («техработы сегодня 22:00–23:00 МСК»).
+// Показывается с StartsAt до EndsAt; без них — сразу и бессрочно.
+type MOTDMessage struct {
+	ID       int    `json:"id"`
+	Text     string `json:"text"`
+	Severity string `json:"severity"`
+	// Переводы текста по языкам
+	Translations map[string]string `json:"translations,omitempty"`
+	StartsAt     *time.Time        `json:"starts_at,omitempty"`
+	EndsAt       *time.Time        `json:"ends_at,omitempty"`
+}
+
+type MOTDRequest struct {
+	Text         string            `json:"text"`
+	Severity     string            `json:"severity"`
+	Translations map[string]string `json:"translations"`
+	StartsAt     *time.Time        `json:"starts_at"`
+	EndsAt       *time.Time        `json:"ends_at"`
+}
+
+// Сообщение для лаунчера на языке игрока
+type MOTDInfo struct {
+	ID       int        `json:"id"`
+	Text     string     `json:"text"`
+	Severity string     `json:"severity"`
+	Language string     `json:"language"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// null, если сейчас показывать нечего
+type MOTDResponse struct {
+	MOTD *MOTDInfo `json:"motd"`
+}
+
+type AdminMOTDResponse struct {
+	Messages []MOTDMessage `json:"messages"`
+}
+
+// Объявления в DATA_DIR/motd.json
+type MOTDStore struct {
+	*jsonListStore[MOTDMessage]
+}
+
+var motd = &MOTDStore{&jsonListStore[MOTDMessage]{file: motdFile}}
+
+func motdFile() string {
+	return filepath.Join(currentConfig().DataDir, "motd.json")
+}
+
+func (m MOTDMessage) isActive(now time.Time) bool {
+	return (m.StartsAt == nil || !now.Before(*m.StartsAt)) && (m.EndsAt == nil || now.Before(*m.EndsAt))
+}
+
+// Сообщение, которое показывается сейчас: самое важное, из равных по
+// важности — начавшееся позже
+func activeMOTD(messages []MOTDMessage, now time.Time) (MOTDMessage, bool) {
+	var best MOTDMessage
+	found := false
+	for _, message := range messages {
+		if !message.isActive(now) {
+			continue
+		}
+		if !found || motdMoreImportant(message, best) {
+			best, found = message, true
+		}
+	}
+	return best, found
+}
+
+func motdMoreImportant(a, b MOTDMessage) bool {
+	if ia, ib := slices.Index(motdSeverities, a.Severity), slices.Index(motdSeverities, b.Severity); ia != ib {
+		return ia > ib
+	}
+	var sa, sb time.Time
+	if a.StartsAt != nil {
+		sa = *a.StartsAt
+	}
+	if b.StartsAt != nil {
+		sb = *b.StartsAt
+	}
+	return sa.After(sb)
+}
+
+func (req MOTDRequest) valid() bool {
+	if req.Text == "" || utf8.RuneCountInString(req.Text) > motdMaxLength || !slices.Contains(motdSeverities, req.Severity) {
+		return false
+	}
+	for _, text := range req.Translations {
+		if text == "" || utf8.RuneCountInString(text) > motdMaxLength {
+			return false
+		}
+	}
+	return req.StartsAt == nil || req.EndsAt == nil || req.EndsAt.After(*req.StartsAt)
+}
+
+func (req MOTDRequest) message(id int) MOTDMessage {
+	return MOTDMessage{
+		ID:           id,
+		Text:         req.Text,
+		Severity:     req.Severity,
+		Translations: req.Translations,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+	}
+}
+
+// Сообщение дня для баннера лаунчера
+func (l *Logger) motdHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📢", "/api/motd", func() {
+		message, ok := activeMOTD(motd.List(), time.Now())
+		if !ok {
+			json.NewEncoder(w).Encode(MOTDResponse{})
+			return
+		}
+
+		info := &MOTDInfo{ID: message.ID, Text: message.Text, Severity: message.Severity, Language: currentConfig().DefaultLanguage, EndsAt: message.EndsAt}
+		for _, lang := range requestLanguages(r) {
+			if text, ok := message.Translations[lang]; ok {
+				info.Text, info.Language = text, lang
+				break
+			}
+		}
+		w.Header().Set("Vary", "Accept-Language")
+		json.NewEncoder(w).Encode(MOTDResponse{MOTD: info})
+	})
+}
+
+// Все объявления, включая запланированные и завершившиеся
+func (l *Logger) adminListMOTDHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📢", "/admin/api/motd", func() {
+		json.NewEncoder(w).Encode(AdminMOTDResponse{Messages: append([]MOTDMessage{}, motd.List()...)})
+	})
+}
+
+// Разбор и проверка тела запроса; важность по умолчанию — info
+func decodeMOTDRequest(w http.ResponseWriter, r *http.Request) (MOTDRequest, bool) {
+	var req MOTDRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if req.Severity == "" {
+		req.Severity = MOTDSeverityInfo
+	}
+	if err != nil || !req.valid() {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+		return MOTDRequest{}, false
+	}
+	return req, true
+}
+
+func (l *Logger) adminCreateMOTDHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📢", "/admin/api/motd", func() {
+		req, ok := decodeMOTDRequest(w, r)
+		if !ok {
+			return
+		}
+
+		var created MOTDMessage
+		apiErr, err := motd.update(func(list []MOTDMessage) ([]MOTDMessage, *modError) {
+			id := 1
+			for _, message := range list {
+				id = max(id, message.ID+1)
+			}
+			created = req.message(id)
+			return append(list, created), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+		l.logSuccess("Создано объявление #%d (%s): %s", created.ID, created.Severity, created.Text)
+	})
+}
+
+func (l *Logger) adminUpdateMOTDHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📢", "/admin/api/motd/{id}", func() {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		req, ok := decodeMOTDRequest(w, r)
+		if !ok {
+			return
+		}
+
+		apiErr, err := motd.update(func(list []MOTDMessage) ([]MOTDMessage, *modError) {
+			i := slices.IndexFunc(list, func(m MOTDMessage) bool { return m.ID == id })
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeMOTDNotFound, []interface{}{id}}
+			}
+			list[i] = req.message(id)
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(req.message(id))
+		l.logSuccess("Объявление #%d обновлено", id)
+	})
+}
+
+func (l *Logger) adminDeleteMOTDHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeNewsWrite, "📢", "/admin/api/motd/{id}", func() {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		apiErr, err := motd.update(func(list []MOTDMessage) ([]MOTDMessage, *modError) {
+			i := slices.IndexFunc(list, func(m MOTDMessage) bool { return m.ID == id })
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeMOTDNotFound, []interface{}{id}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Объявление #%d удалено", id)
+	})
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Статусы публикации новостей
+const (
+	NewsStatusDraft     = "draft"
+	NewsStatusScheduled = "scheduled"
+	NewsStatusPublished = "published"
+	NewsStatusExpired   = "expired"
+)
+
+const newsFile = "news/news.json"
+
+const maxNewsTags = 20
+
+// Защищает news.json от одновременной записи из админки и планировщика
+var newsMu sync.Mutex
+
+func loadNews() ([]NewsItem, error) {
+	return loadNewsFile(newsFile)
+}
+
+func loadNewsFile(path string) ([]NewsItem, error) {
+	// Читаем JSON файл
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var news []NewsItem
+	err = json.Unmarshal(data, &news)
+	return news, err
+}
+
+// Атомарная запись новостей
+func saveNews(news []NewsItem) error {
+	defer newsCache.Invalidate()
+	// Счетчики хранятся отдельно, в news_engagement.json
+	for i := range news {
+		news[i].Engagement = nil
+	}
+	if err := saveJSONFile(newsFile, news); err != nil {
+		return err
+	}
+	eventHub.PublishNews(news)
+	return nil
+}
+
+// Изменение новостей под блокировкой; fn возвращает true, если нужно сохранить
+func updateNews(fn func(news []NewsItem) ([]NewsItem, bool, error)) error {
+	newsMu.Lock()
+	defer newsMu.Unlock()
+
+	news, err := loadNews()
+	if err != nil {
+		return err
+	}
+
+	news, changed, err := fn(news)
+	if err != nil || !changed {
+		return err
+	}
+
+	return saveNews(news)
+}
+
+// Видна ли новость игрокам в данный момент.
+// Новости без статуса (старый формат news.json) считаются опубликованными.
+func (n NewsItem) isVisible(now time.Time) bool {
+	switch n.Status {
+	case "", NewsStatusPublished:
+	case NewsStatusScheduled:
+		// Планировщик мог еще не успеть сработать
+		if n.PublishAt == nil || now.Before(*n.PublishAt) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	return n.ExpiresAt == nil || now.Before(*n.ExpiresAt)
+}
+
+// Новости для игроков: опубликованные, на языке клиента и, если
+// клиент просит HTML, с отрендеренным Markdown
+func playerNews(news []NewsItem, langs []string, html bool) []NewsItem {
+	news = localizeNews(publishedNews(news, time.Now()), langs)
+	sortNews(news)
+	if html {
+		for i := range news {
+			news[i].RenderedHTML = renderMarkdown(news[i].Content)
+		}
+	}
+	return news
+}
+
+// Только опубликованные новости для публичного API
+func publishedNews(news []NewsItem, now time.Time) []NewsItem {
+	visible := make([]NewsItem, 0, len(news))
+	for _, item := range news {
+		if item.isVisible(now) {
+			visible = append(visible, item)
+		}
+	}
+	return visible
+}
+
+// Порядок в публичном API: закрепленные, затем от новых к старым
+func sortNews(news []NewsItem) {
+	slices.SortStableFunc(news, func(a, b NewsItem) int {
+		switch {
+		case a.Pinned != b.Pinned:
+			if a.Pinned {
+				return -1
+			}
+			return 1
+		case a.Date != b.Date:
+			// Даты в формате 2006-01-02 сравниваются как строки
+			return strings.Compare(b.Date, a.Date)
+		}
+		return b.ID - a.ID
+	})
+}
+
+// Фильтр публичных новостей: ?category=patch&tag=event
+type newsFilter struct {
+	Category string
+	Tag      string
+}
+
+func requestNewsFilter(r *http.Request) newsFilter {
+	query := r.URL.Query()
+	return newsFilter{
+		Category: strings.ToLower(strings.TrimSpace(query.Get("category"))),
+		Tag:      strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+	}
+}
+
+// Часть ключа кэша ответов
+func (f newsFilter) key() string {
+	return f.Category + "|" + f.Tag
+}
+
+func (f newsFilter) apply(news []NewsItem) []NewsItem {
+	if f.Category == "" && f.Tag == "" {
+		return news
+	}
+	filtered := make([]NewsItem, 0, len(news))
+	for _, item := range news {
+		if (f.Category == "" || item.Category == f.Category) && (f.Tag == "" || slices.Contains(item.Tags, f.Tag)) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// Приведение категории и тегов к нижнему регистру без повторов;
+// false — значения некорректны
+func (n *NewsItem) normalizeTaxonomy() bool {
+	n.Category = strings.ToLower(strings.TrimSpace(n.Category))
+	if len(n.Category) > maxTelemetryField {
+		return false
+	}
+	tags, ok := normalizeTags(n.Tags, maxNewsTags)
+	n.Tags = tags
+	return ok
+}
+
+// Теги в нижнем регистре без повторов; false — пустой или слишком
+// длинный тег либо тегов больше limit
+func normalizeTags(tags []string, limit int) ([]string, bool) {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTelemetryField {
+			return nil, false
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, len(normalized) <= limit
+}
+
+// Перевод запланированных новостей в опубликованные и снятие устаревших
+func applyNewsSchedule(news []NewsItem, now time.Time) ([]NewsItem, []string) {
+	var changes []string
+	for i := range news {
+		item := &news[i]
+		switch {
+		case item.Status == NewsStatusScheduled && item.PublishAt != nil && !now.Before(*item.PublishAt):
+			item.Status = NewsStatusPublished
+			if item.Date == "" {
+				item.Date = item.PublishAt.Format("2006-01-02")
+			}
+			changes = append(changes, "опубликована «"+item.Title+"»")
+
+		case (item.Status == "" || item.Status == NewsStatusPublished) && item.ExpiresAt != nil && !now.Before(*item.ExpiresAt):
+			item.Status = NewsStatusExpired
+			changes = append(changes, "снята с публикации «"+item.Title+"»")
+		}
+	}
+	return news, changes
+}
+
+// Горутина планировщика публикаций; интервал перечитывается из текущей
+// конфигурации, чтобы его можно было поменять без перезапуска. Реплика
+// получает уже опубликованные новости от основного сервера и сама их не
+// трогает.
+func (l *Logger) runNewsScheduler() {
+	for {
+		if currentConfig().ReplicaOf == "" {
+			err := updateNews(func(news []NewsItem) ([]NewsItem, bool, error) {
+				news, changes := applyNewsSchedule(news, time.Now())
+				for _, change := range changes {
+					l.logSuccess("Планировщик новостей: %s", change)
+				}
+				return news, len(changes) > 0, nil
+			})
+			if err != nil {
+				l.logError("Ошибка планировщика новостей: %v", err)
+			}
+		}
+
+		time.Sleep(currentConfig().NewsSchedulerInterval)
+	}
+}
+
+func validNewsStatus(status string) bool {
+	switch status {
+	case NewsStatusDraft, NewsStatusScheduled, NewsStatusPublished, NewsStatusExpired:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Просмотры и реакции на новости: лаунчер отмечает новость прочитанной,
+// когда игрок ее открыл, и может поставить реакцию. Каждый игрок
+// (аккаунт или установка лаунчера) учитывается по одному разу, поэтому
+// повторные запросы счетчики не накручивают.
+var newsReactions = []string{"like", "heart"}
+
+// Счетчики новости в публичном ответе и статистике
+type NewsEngagement struct {
+	Views     int64            `json:"views"`
+	Reactions map[string]int64 `json:"reactions"`
+}
+
+// Счетчики и реакции того, кто спрашивает
+type NewsEngagementResponse struct {
+	NewsID int `json:"news_id"`
+	NewsEngagement
+	MyReactions []string `json:"my_reactions"`
+}
+
+// Статистика новости для админки
+type NewsEngagementStats struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status,omitempty"`
+	NewsEngagement
+}
+
+// Действия игрока с новостью для выгрузки данных аккаунта
+type NewsActivity struct {
+	NewsID    int        `json:"news_id"`
+	ViewedAt  *time.Time `json:"viewed_at,omitempty"`
+	Reactions []string   `json:"reactions,omitempty"`
+}
+
+// Запись новости в DATA_DIR/news_engagement.json: кто и когда ее открыл
+// и поставил реакции. Вместо ID клиента хранится его хэш, как в журнале
+// телеметрии.
+type newsEngagementRecord struct {
+	Viewers   map[string]time.Time            `json:"viewers,omitempty"`
+	Reactions map[string]map[string]time.Time `json:"reactions,omitempty"`
+}
+
+type NewsEngagementStore struct {
+	mu      sync.Mutex
+	records map[int]*newsEngagementRecord
+	// Номер снимка счетчиков для ключа кэша новостей: меняется не чаще
+	// раза в NEWS_CACHE_TTL, чтобы готовые ответы не собирались заново
+	// на каждый просмотр
+	revision  int64
+	changed   bool
+	revisedAt time.Time
+	snapshot  map[int]NewsEngagement
+}
+
+var newsEngagement = &NewsEngagementStore{}
+
+func newsEngagementFile() string {
+	return filepath.Join(currentConfig().DataDir, "news_engagement.json")
+}
+
+func (s *NewsEngagementStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = make(map[int]*newsEngagementRecord)
+	s.changed = true
+	return loadJSONFile(newsEngagementFile(), &s.records)
+}
+
+// Кто отмечает новость: аккаунт или установка лаунчера
+func newsViewer(r *http.Request) string {
+	if account, ok := authenticateAccount(r); ok {
+		return playerSubject(account.Username, "")
+	}
+	if clientID := launcherClientID(r); clientID != "" && len(clientID) <= 128 {
+		return playerSubject("", telemetryClient(clientID))
+	}
+	return ""
+}
+
+// Кем игрок мог быть при отметках новостей
+func newsViewerSubjects(account Account, clientID string) []string {
+	subjects := []string{playerSubject(account.Username, "")}
+	if clientID != "" {
+		subjects = append(subjects, playerSubject("", telemetryClient(clientID)))
+	}
+	return subjects
+}
+
+func (rec *newsEngagementRecord) engagement() NewsEngagement {
+	engagement := NewsEngagement{Views: int64(len(rec.Viewers)), Reactions: make(map[string]int64, len(newsReactions))}
+	for _, reaction := range newsReactions {
+		engagement.Reactions[reaction] = int64(len(rec.Reactions[reaction]))
+	}
+	return engagement
+}
+
+func (rec *newsEngagementRecord) mine(subject string) []string {
+	mine := []string{}
+	for _, reaction := range newsReactions {
+		if _, ok := rec.Reactions[reaction][subject]; ok {
+			mine = append(mine, reaction)
+		}
+	}
+	return mine
+}
+
+func (s *NewsEngagementStore) record(id int) *newsEngagementRecord {
+	rec, ok := s.records[id]
+	if !ok {
+		rec = &newsEngagementRecord{}
+		s.records[id] = rec
+	}
+	return rec
+}
+
+// Изменение записи новости под блокировкой. fn возвращает false, если
+// ничего не изменилось; при ошибке записи изменение откатывается через undo.
+func (s *NewsEngagementStore) update(id int, subject string, fn func(rec *newsEngagementRecord) (undo func(), changed bool)) (NewsEngagementResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec := s.record(id)
+	undo, changed := fn(rec)
+	if changed {
+		if err := saveJSONFile(newsEngagementFile(), s.records); err != nil {
+			undo()
+			return NewsEngagementResponse{}, err
+		}
+		s.changed = true
+	}
+	return NewsEngagementResponse{NewsID: id, NewsEngagement: rec.engagement(), MyReactions: rec.mine(subject)}, nil
+}
+
+// Первый просмотр новости игроком; повторные не учитываются
+func (s *NewsEngagementStore) View(id int, subject string, now time.Time) (NewsEngagementResponse, error) {
+	return s.update(id, subject, func(rec *newsEngagementRecord) (func(), bool) {
+		if _, ok := rec.Viewers[subject]; ok {
+			return nil, false
+		}
+		if rec.Viewers == nil {
+			rec.Viewers = make(map[string]time.Time)
+		}
+		rec.Viewers[subject] = now
+		return func() { delete(rec.Viewers, subject) }, true
+	})
+}
+
+// Установка или снятие реакции игрока. Реакция засчитывает и просмотр:
+// отреагировать на новость, не открыв ее, нельзя.
+func (s *NewsEngagementStore) React(id int, subject, reaction string, set bool, now time.Time) (NewsEngagementResponse, error) {
+	return s.update(id, subject, func(rec *newsEngagementRecord) (func(), bool) {
+		_, exists := rec.Reactions[reaction][subject]
+		if !set {
+			if !exists {
+				return nil, false
+			}
+			at := rec.Reactions[reaction][subject]
+			delete(rec.Reactions[reaction], subject)
+			return func() { rec.Reactions[reaction][subject] = at }, true
+		}
+		if exists {
+			return nil, false
+		}
+
+		if rec.Reactions == nil {
+			rec.Reactions = make(map[string]map[string]time.Time)
+		}
+		if rec.Reactions[reaction] == nil {
+			rec.Reactions[reaction] = make(map[string]time.Time)
+		}
+		rec.Reactions[reaction][subject] = now
+		_, viewed := rec.Viewers[subject]
+		if !viewed {
+			if rec.Viewers == nil {
+				rec.Viewers = make(map[string]time.Time)
+			}
+			rec.Viewers[subject] = now
+		}
+		return func() {
+			delete(rec.Reactions[reaction], subject)
+			if !viewed {
+				delete(rec.Viewers, subject)
+			}
+		}, true
+	})
+}
+
+// Счетчики всех новостей и номер их снимка. Снимок обновляется не чаще
+// раза в ttl, так что в /api/news счетчики отстают не больше чем на ttl.
+func (s *NewsEngagementStore) Snapshot(ttl time.Duration) (int64, map[int]NewsEngagement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshot == nil || (s.changed && time.Since(s.revisedAt) >= ttl) {
+		snapshot := make(map[int]NewsEngagement, len(s.records))
+		for id, rec := range s.records {
+			snapshot[id] = rec.engagement()
+		}
+		s.snapshot = snapshot
+		s.revision++
+		s.revisedAt = time.Now()
+		s.changed = false
+	}
+	return s.revision, s.snapshot
+}
+
+// Счетчики для админки — без задержки снимка
+func (s *NewsEngagementStore) Counts() map[int]NewsEngagement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[int]NewsEngagement, len(s.records))
+	for id, rec := range s.records {
+		counts[id] = rec.engagement()
+	}
+	return counts
+}
+
+// Удаление счетчиков вместе с новостью
+func (s *NewsEngagementStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[id]
+	if !ok {
+		return nil
+	}
+	delete(s.records, id)
+	if err := saveJSONFile(newsEngagementFile(), s.records); err != nil {
+		s.records[id] = rec
+		return err
+	}
+	s.changed = true
+	return nil
+}
+
+// Просмотры и реакции игрока для выгрузки данных аккаунта
+func (s *NewsEngagementStore) Activity(subjects ...string) []NewsActivity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activity := []NewsActivity{}
+	for id, rec := range s.records {
+		entry := NewsActivity{NewsID: id}
+		for _, subject := range subjects {
+			if at, ok := rec.Viewers[subject]; ok {
+				entry.ViewedAt = &at
+			}
+			for _, reaction := range rec.mine(subject) {
+				if !slices.Contains(entry.Reactions, reaction) {
+					entry.Reactions = append(entry.Reactions, reaction)
+				}
+			}
+		}
+		if entry.ViewedAt != nil || len(entry.Reactions) > 0 {
+			activity = append(activity, entry)
+		}
+	}
+	sort.Slice(activity, func(i, j int) bool { return activity[i].NewsID < activity[j].NewsID })
+	return activity
+}
+
+// Удаление отметок игрока при удалении аккаунта; счетчики уменьшаются
+func (s *NewsEngagementStore) Forget(subjects ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make(map[int]*newsEngagementRecord, len(s.records))
+	changed := false
+	for id, rec := range s.records {
+		copied := &newsEngagementRecord{Viewers: maps.Clone(rec.Viewers), Reactions: make(map[string]map[string]time.Time, len(rec.Reactions))}
+		for reaction, subjectsAt := range rec.Reactions {
+			copied.Reactions[reaction] = maps.Clone(subjectsAt)
+		}
+		for _, subject := range subjects {
+			if _, ok := copied.Viewers[subject]; ok {
+				delete(copied.Viewers, subject)
+				changed = true
+			}
+			for _, subjectsAt := range copied.Reactions {
+				if _, ok := subjectsAt[subject]; ok {
+					delete(subjectsAt, subject)
+					changed = true
+				}
+			}
+		}
+		next[id] = copied
+	}
+	if !changed {
+		return nil
+	}
+	if err := saveJSONFile(newsEngagementFile(), next); err != nil {
+		return err
+	}
+	s.records = next
+	s.changed = true
+	return nil
+}
+
+// Счетчики в публичных новостях
+func withNewsEngagement(news []NewsItem, counts map[int]NewsEngagement) []NewsItem {
+	for i := range news {
+		engagement, ok := counts[news[i].ID]
+		if !ok {
+			engagement = (&newsEngagementRecord{}).engagement()
+		}
+		news[i].Engagement = &engagement
+	}
+	return news
+}
+
+// Статистика новостей для админки: от самых просматриваемых
+func newsEngagementStats() ([]NewsEngagementStats, error) {
+	news, err := newsCache.Get()
+	if err != nil {
+		return nil, err
+	}
+	counts := newsEngagement.Counts()
+	stats := make([]NewsEngagementStats, 0, len(news))
+	for _, item := range news {
+		engagement, ok := counts[item.ID]
+		if !ok {
+			engagement = (&newsEngagementRecord{}).engagement()
+		}
+		stats = append(stats, NewsEngagementStats{ID: item.ID, Title: item.Title, Status: item.Status, NewsEngagement: engagement})
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Views > stats[j].Views })
+	return stats, nil
+}
+
+// Новость из запроса, если она видна игрокам, и тот, кто ее отмечает
+func (l *Logger) newsEngagementTarget(w http.ResponseWriter, r *http.Request) (int, string, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	subject := newsViewer(r)
+	if err != nil || subject == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+		return 0, "", false
+	}
+
+	news, err := newsCache.Get()
+	if err != nil {
+		l.logError("Ошибка загрузки новостей: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+		return 0, "", false
+	}
+	now := time.Now()
+	if !slices.ContainsFunc(news, func(item NewsItem) bool { return item.ID == id && item.isVisible(now) }) {
+		writeError(w, r, http.StatusNotFound, ErrCodeNewsNotFound)
+		return 0, "", false
+	}
+	return id, subject, true
+}
+
+// Игрок открыл новость: POST /api/news/{id}/view с X-Client-ID или
+// токеном аккаунта
+func (l *Logger) newsViewHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "👁️", "/api/news/{id}/view", func() {
+		id, subject, ok := l.newsEngagementTarget(w, r)
+		if !ok {
+			return
+		}
+		response, err := newsEngagement.View(id, subject, time.Now().UTC())
+		if err != nil {
+			l.logError("Ошибка сохранения просмотра новости #%d: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Реакция на новость: PUT ставит, DELETE снимает
+func (l *Logger) newsReactionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "❤️", "/api/news/{id}/reactions/{reaction}", func() {
+		reaction := r.PathValue("reaction")
+		if !slices.Contains(newsReactions, reaction) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeUnknownReaction, reaction)
+			return
+		}
+		id, subject, ok := l.newsEngagementTarget(w, r)
+		if !ok {
+			return
+		}
+		set := r.Method == http.MethodPut
+		response, err := newsEngagement.React(id, subject, reaction, set, time.Now().UTC())
+		if err != nil {
+			l.logError("Ошибка сохранения реакции на новость #%d: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Реакция %s на новость #%d: %t", reaction, id, set)
+	})
+}
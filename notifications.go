@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Что отправляется push-уведомлениями в приложение-компаньон
+const (
+	PushServerOnline = "server_online"
+	PushFriendOnline = "friend_online"
+	PushRelease      = "release"
+)
+
+const (
+	maxPushDevices  = 10
+	maxPushTokenLen = 4096
+	fcmScope        = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmSendURL      = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+)
+
+var pushPlatforms = []string{"android", "ios"}
+
+// Устройство приложения-компаньона. Токен выдает Firebase Cloud Messaging;
+// iOS получает уведомления через APNs, подключенный в проекте Firebase.
+type PushDevice struct {
+	Token     string    `json:"token"`
+	Platform  string    `json:"platform"`
+	AccountID string    `json:"account_id"`
+	Lang      string    `json:"lang"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PushDeviceRequest struct {
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
+
+// Какие уведомления получает аккаунт; без настроек — все
+type PushSettings struct {
+	ServerOnline bool `json:"server_online"`
+	FriendOnline bool `json:"friend_online"`
+	Release      bool `json:"release"`
+}
+
+func (s PushSettings) allows(category string) bool {
+	switch category {
+	case PushServerOnline:
+		return s.ServerOnline
+	case PushFriendOnline:
+		return s.FriendOnline
+	case PushRelease:
+		return s.Release
+	}
+	return false
+}
+
+// Настройки и устройства аккаунта: /api/account/notifications
+type NotificationsResponse struct {
+	Enabled  bool         `json:"enabled"`
+	Settings PushSettings `json:"settings"`
+	Devices  []PushDevice `json:"devices"`
+}
+
+type pushData struct {
+	Devices  []PushDevice            `json:"devices"`
+	Settings map[string]PushSettings `json:"settings"`
+}
+
+// Устройства и настройки в DATA_DIR/push_devices.json
+type PushStore struct {
+	mu   sync.Mutex
+	data pushData
+}
+
+var pushDevices = &PushStore{}
+
+func pushDevicesFile() string {
+	return filepath.Join(currentConfig().DataDir, "push_devices.json")
+}
+
+func (s *PushStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(pushDevicesFile(), &s.data)
+}
+
+// Изменение под блокировкой, как в jsonListStore.update
+func (s *PushStore) update(fn func(data *pushData) *modError) (*modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := pushData{Devices: slices.Clone(s.data.Devices), Settings: make(map[string]PushSettings, len(s.data.Settings))}
+	for id, settings := range s.data.Settings {
+		next.Settings[id] = settings
+	}
+	if apiErr := fn(&next); apiErr != nil {
+		return apiErr, nil
+	}
+	if err := saveJSONFile(pushDevicesFile(), next); err != nil {
+		return nil, err
+	}
+	s.data = next
+	return nil, nil
+}
+
+func (s *PushStore) Settings(accountID string) PushSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if settings, ok := s.data.Settings[accountID]; ok {
+		return settings
+	}
+	return PushSettings{ServerOnline: true, FriendOnline: true, Release: true}
+}
+
+func (s *PushStore) Devices(accountID string) []PushDevice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var devices []PushDevice
+	for _, device := range s.data.Devices {
+		if device.AccountID == accountID {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// Устройства получателей уведомления; accountIDs nil — всех аккаунтов
+func (s *PushStore) Recipients(accountIDs []string, category string) []PushDevice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var devices []PushDevice
+	for _, device := range s.data.Devices {
+		if accountIDs != nil && !slices.Contains(accountIDs, device.AccountID) {
+			continue
+		}
+		settings, ok := s.data.Settings[device.AccountID]
+		if !ok || settings.allows(category) {
+			devices = append(devices, device)
+		}
+	}
+	return devices
+}
+
+// Удаление устройств по токенам, например отозванных FCM
+func (s *PushStore) Remove(tokens ...string) error {
+	_, err := s.update(func(data *pushData) *modError {
+		data.Devices = slices.DeleteFunc(data.Devices, func(d PushDevice) bool { return slices.Contains(tokens, d.Token) })
+		return nil
+	})
+	return err
+}
+
+// Удаление устройств и настроек аккаунта
+func (s *PushStore) Forget(accountID string) error {
+	_, err := s.update(func(data *pushData) *modError {
+		data.Devices = slices.DeleteFunc(data.Devices, func(d PushDevice) bool { return d.AccountID == accountID })
+		delete(data.Settings, accountID)
+		return nil
+	})
+	return err
+}
+
+// Ключ сервисного аккаунта Firebase (JSON из консоли Google Cloud)
+type fcmCredentials struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Отправка через FCM HTTP v1. Токен доступа выпускается по ключу
+// сервисного аккаунта и переиспользуется до истечения.
+type FCMRelay struct {
+	mu          sync.Mutex
+	path        string
+	credentials fcmCredentials
+	key         *rsa.PrivateKey
+	token       string
+	expires     time.Time
+}
+
+var (
+	fcmRelay       = &FCMRelay{}
+	fcmClient      = &http.Client{Timeout: 10 * time.Second}
+	errPushExpired = errors.New("токен устройства больше не действует")
+)
+
+// Токен доступа и проект; ключ перечитывается при смене FCM_CREDENTIALS_FILE
+func (f *FCMRelay) accessToken(path string, now time.Time) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path != path {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", err
+		}
+		var credentials fcmCredentials
+		if err := json.Unmarshal(data, &credentials); err != nil {
+			return "", "", fmt.Errorf("ключ сервисного аккаунта: %v", err)
+		}
+		block, _ := pem.Decode([]byte(credentials.PrivateKey))
+		if block == nil {
+			return "", "", fmt.Errorf("ключ сервисного аккаунта: нет private_key")
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return "", "", fmt.Errorf("ключ сервисного аккаунта: %v", err)
+		}
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", "", fmt.Errorf("ключ сервисного аккаунта: нужен ключ RSA")
+		}
+		f.path, f.credentials, f.key, f.token = path, credentials, key, ""
+	}
+	if f.token != "" && now.Before(f.expires) {
+		return f.token, f.credentials.ProjectID, nil
+	}
+
+	// JWT-утверждение сервисного аккаунта (RFC 7523), RS256
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   f.credentials.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.credentials.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+	req, err := http.NewRequest(http.MethodPost, f.credentials.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := oauthRequest(req, &token); err != nil {
+		return "", "", fmt.Errorf("токен доступа FCM: %w", err)
+	}
+	// Минута запаса, чтобы токен не истек посреди рассылки
+	f.token, f.expires = token.AccessToken, now.Add(time.Duration(token.ExpiresIn)*time.Second-time.Minute)
+	return f.token, f.credentials.ProjectID, nil
+}
+
+func (f *FCMRelay) Send(path string, device PushDevice, title, body, category string) error {
+	token, project, err := f.accessToken(path, time.Now())
+	if err != nil {
+		return err
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        device.Token,
+			"notification": map[string]string{"title": title, "body": body},
+			"data":         map[string]string{"type": category},
+		},
+	})
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(fcmSendURL, url.PathEscape(project)), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := fcmClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	// 404 UNREGISTERED: приложение удалено или токен сменился
+	if resp.StatusCode == http.StatusNotFound {
+		return errPushExpired
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ответ FCM %s", resp.Status)
+	}
+	return nil
+}
+
+// Рассылка уведомления в фоне: заголовок и текст берутся из каталога
+// сообщений по ключам key_title и key_body на языке устройства.
+// accountIDs nil — всем аккаунтам.
+func (l *Logger) pushNotify(accountIDs []string, category, key string, args ...interface{}) {
+	path := currentConfig().FCMCredentialsFile
+	if path == "" || (accountIDs != nil && len(accountIDs) == 0) {
+		return
+	}
+	go func() {
+		var expired []string
+		failed := 0
+		for _, device := range pushDevices.Recipients(accountIDs, category) {
+			title, body := translateTo(device.Lang, key+"_title", args...), translateTo(device.Lang, key+"_body", args...)
+			err := fcmRelay.Send(path, device, title, body, category)
+			switch {
+			case errors.Is(err, errPushExpired):
+				expired = append(expired, device.Token)
+			case err != nil:
+				if failed == 0 {
+					l.logError("Ошибка отправки push-уведомления %s: %v", category, err)
+				}
+				failed++
+			}
+		}
+		if len(expired) > 0 {
+			if err := pushDevices.Remove(expired...); err != nil {
+				l.logError("Ошибка сохранения устройств: %v", err)
+			}
+		}
+		if failed > 0 {
+			l.logWarn("Push-уведомление %s не доставлено на устройств: %d", category, failed)
+		}
+	}()
+}
+
+func notificationsResponse(cfg *Config, accountID string) NotificationsResponse {
+	return NotificationsResponse{
+		Enabled:  cfg.FCMCredentialsFile != "",
+		Settings: pushDevices.Settings(accountID),
+		Devices:  append([]PushDevice{}, pushDevices.Devices(accountID)...),
+	}
+}
+
+// Изменение устройств и настроек с ответом об ошибке клиенту
+func (l *Logger) updatePushDevices(w http.ResponseWriter, r *http.Request, fn func(data *pushData) *modError) bool {
+	apiErr, err := pushDevices.update(fn)
+	if err != nil {
+		l.logError("Ошибка сохранения устройств: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return false
+	}
+	if apiErr != nil {
+		writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+		return false
+	}
+	return true
+}
+
+func (l *Logger) notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔔", "/api/account/notifications", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(notificationsResponse(currentConfig(), account.ID))
+	})
+}
+
+func (l *Logger) notificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔔", "/api/account/notifications", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		settings := pushDevices.Settings(account.ID)
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		ok = l.updatePushDevices(w, r, func(data *pushData) *modError {
+			data.Settings[account.ID] = settings
+			return nil
+		})
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(notificationsResponse(currentConfig(), account.ID))
+	})
+}
+
+// Регистрация устройства. Токен, уже привязанный к другому аккаунту,
+// переходит к текущему; у аккаунта остаются десять последних устройств.
+func (l *Logger) pushDeviceRegisterHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔔", "/api/account/devices", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		cfg := currentConfig()
+		if cfg.FCMCredentialsFile == "" {
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodePushDisabled)
+			return
+		}
+		var req PushDeviceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" || len(req.Token) > maxPushTokenLen ||
+			!slices.Contains(pushPlatforms, req.Platform) {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+
+		device := PushDevice{Token: req.Token, Platform: req.Platform, AccountID: account.ID, Lang: messageLanguage(r), CreatedAt: time.Now().UTC()}
+		ok = l.updatePushDevices(w, r, func(data *pushData) *modError {
+			data.Devices = slices.DeleteFunc(data.Devices, func(d PushDevice) bool { return d.Token == req.Token })
+			data.Devices = append(data.Devices, device)
+			owned := 0
+			for i := len(data.Devices) - 1; i >= 0; i-- {
+				if data.Devices[i].AccountID != account.ID {
+					continue
+				}
+				if owned++; owned > maxPushDevices {
+					data.Devices = slices.Delete(data.Devices, i, i+1)
+				}
+			}
+			return nil
+		})
+		if !ok {
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(notificationsResponse(cfg, account.ID))
+		l.logSuccess("Аккаунт %s зарегистрировал устройство %s", account.Username, req.Platform)
+	})
+}
+
+func (l *Logger) pushDeviceDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔔", "/api/account/devices/{token}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		token := r.PathValue("token")
+		ok = l.updatePushDevices(w, r, func(data *pushData) *modError {
+			i := slices.IndexFunc(data.Devices, func(d PushDevice) bool { return d.Token == token && d.AccountID == account.ID })
+			if i < 0 {
+				return &modError{http.StatusNotFound, ErrCodeDeviceNotFound, nil}
+			}
+			data.Devices = slices.Delete(data.Devices, i, i+1)
+			return nil
+		})
+		if ok {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Сколько ждем возврата игрока от провайдера
+	oauthFlowTTL = 10 * time.Minute
+	// Сколько живет одноразовый билет для обмена на токен
+	oauthTicketTTL = 5 * time.Minute
+	// Cookie, связывающая вход с браузером, в котором он начат
+	oauthStateCookie = "loil_oauth_state"
+)
+
+// Внешний провайдер входа. SubjectField и NameField — поля ответа
+// userinfo с постоянным ID пользователя и его отображаемым именем.
+type oauthProvider struct {
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scope        string
+	SubjectField string
+	NameField    string
+}
+
+var oauthProviders = map[string]oauthProvider{
+	"discord": {
+		AuthURL:      "https://discord.com/oauth2/authorize",
+		TokenURL:     "https://discord.com/api/oauth2/token",
+		UserInfoURL:  "https://discord.com/api/users/@me",
+		Scope:        "identify",
+		SubjectField: "id",
+		NameField:    "username",
+	},
+	"google": {
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+		Scope:        "openid profile",
+		SubjectField: "sub",
+		NameField:    "name",
+	},
+}
+
+// Приложение сервера у провайдера
+type OAuthClient struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// Внешний аккаунт, привязанный к аккаунту игрока
+type AccountIdentity struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	Name     string    `json:"name"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type OAuthLinkRequest struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+type OAuthLinkResponse struct {
+	URL string `json:"url"`
+}
+
+// Обмен билета из редиректа на токен; TOTPCode нужен, если включена 2FA
+type OAuthTokenRequest struct {
+	Ticket   string `json:"ticket"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// Вход, ожидающий возврата от провайдера. accountID задан при привязке
+// провайдера к уже существующему аккаунту.
+type oauthFlow struct {
+	provider  string
+	redirect  string
+	accountID string
+	expiresAt time.Time
+}
+
+type oauthTicket struct {
+	accountID string
+	expiresAt time.Time
+}
+
+var (
+	oauthMu      sync.Mutex
+	oauthFlows   = make(map[string]oauthFlow)
+	oauthTickets = make(map[string]oauthTicket)
+)
+
+var oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func newOAuthFlow(flow oauthFlow, now time.Time) string {
+	state := randomID(16)
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	for id, f := range oauthFlows {
+		if !now.Before(f.expiresAt) {
+			delete(oauthFlows, id)
+		}
+	}
+	oauthFlows[state] = flow
+	return state
+}
+
+// Одноразовое получение входа по state
+func takeOAuthFlow(state string, now time.Time) (oauthFlow, bool) {
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	flow, ok := oauthFlows[state]
+	delete(oauthFlows, state)
+	return flow, ok && now.Before(flow.expiresAt)
+}
+
+func newOAuthTicket(accountID string, now time.Time) string {
+	ticket := randomID(24)
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	for id, t := range oauthTickets {
+		if !now.Before(t.expiresAt) {
+			delete(oauthTickets, id)
+		}
+	}
+	oauthTickets[ticket] = oauthTicket{accountID: accountID, expiresAt: now.Add(oauthTicketTTL)}
+	return ticket
+}
+
+func lookupOAuthTicket(ticket string, now time.Time) (string, bool) {
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	t, ok := oauthTickets[ticket]
+	return t.accountID, ok && now.Before(t.expiresAt)
+}
+
+func dropOAuthTicket(ticket string) {
+	oauthMu.Lock()
+	defer oauthMu.Unlock()
+	delete(oauthTickets, ticket)
+}
+
+// Адрес возврата к провайдеру, зарегистрированный в его приложении
+func oauthCallbackURL(cfg *Config, provider string) string {
+	return cfg.PublicURL + "/api/auth/oauth/" + provider + "/callback"
+}
+
+// Куда можно вернуть игрока: локальный адрес лаунчера (RFC 8252)
+// или адрес из OAUTH_REDIRECT_URLS без учета параметров запроса
+func oauthRedirectAllowed(cfg *Config, target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil || u.Fragment != "" || u.Host == "" {
+		return false
+	}
+	if u.Scheme == "http" && slices.Contains([]string{"127.0.0.1", "::1", "localhost"}, u.Hostname()) {
+		return true
+	}
+	base := *u
+	base.RawQuery = ""
+	return slices.Contains(cfg.OAuthRedirectURLs, base.String())
+}
+
+// Провайдер из пути запроса; при отказе ответ уже записан
+func oauthProviderFromPath(w http.ResponseWriter, r *http.Request, cfg *Config) (string, oauthProvider, OAuthClient, bool) {
+	name := r.PathValue("provider")
+	provider, known := oauthProviders[name]
+	client, configured := cfg.OAuthClients[name]
+	if !known || !configured {
+		writeError(w, r, http.StatusNotFound, ErrCodeOAuthProviderNotFound, name)
+		return name, provider, client, false
+	}
+	return name, provider, client, true
+}
+
+func (p oauthProvider) authorizeURL(client OAuthClient, callbackURL, state string) string {
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ClientID},
+		"redirect_uri":  {callbackURL},
+		"scope":         {p.Scope},
+		"state":         {state},
+	}
+	return p.AuthURL + "?" + query.Encode()
+}
+
+// Обмен кода авторизации на внешний аккаунт
+func (p oauthProvider) fetchIdentity(ctx context.Context, client OAuthClient, callbackURL, code string) (AccountIdentity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {callbackURL},
+		"client_id":     {client.ClientID},
+		"client_secret": {client.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return AccountIdentity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := oauthRequest(req, &token); err != nil {
+		return AccountIdentity{}, fmt.Errorf("обмен кода: %w", err)
+	}
+	if token.AccessToken == "" {
+		return AccountIdentity{}, fmt.Errorf("обмен кода: нет access_token")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return AccountIdentity{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	var info map[string]interface{}
+	if err := oauthRequest(req, &info); err != nil {
+		return AccountIdentity{}, fmt.Errorf("данные пользователя: %w", err)
+	}
+	subject, _ := info[p.SubjectField].(string)
+	name, _ := info[p.NameField].(string)
+	if subject == "" {
+		return AccountIdentity{}, fmt.Errorf("данные пользователя: нет поля %s", p.SubjectField)
+	}
+	return AccountIdentity{Subject: subject, Name: name}, nil
+}
+
+func oauthRequest(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := oauthHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ответ %s", resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// Имя нового аккаунта из имени у провайдера: только допустимые символы,
+// при занятом имени добавляется случайный суффикс
+func oauthUsername(name string) string {
+	var b strings.Builder
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+			b.WriteRune(c)
+		case c == ' ', c == '-', c == '.':
+			b.WriteByte('_')
+		}
+	}
+	username := strings.Trim(b.String(), "_")
+	if len(username) > 24 {
+		username = username[:24]
+	}
+	if len(username) < 3 {
+		username = "player"
+	}
+	return username
+}
+
+// Аккаунт для внешнего входа: уже привязанный или новый без пароля
+func createOAuthAccount(identity AccountIdentity, now time.Time) (Account, error) {
+	base := oauthUsername(identity.Name)
+	username := base
+	for range 10 {
+		account := Account{
+			ID:         randomID(12),
+			Username:   username,
+			CreatedAt:  now,
+			Identities: []AccountIdentity{identity},
+		}
+		created, err := accounts.Create(account)
+		if err != nil {
+			return Account{}, err
+		}
+		if created {
+			return account, nil
+		}
+		username = base + "_" + randomID(2)
+	}
+	return Account{}, fmt.Errorf("не удалось подобрать свободное имя для %s", base)
+}
+
+// Возврат игрока на адрес лаунчера с результатом в параметрах запроса
+func oauthRedirect(w http.ResponseWriter, r *http.Request, target string, params url.Values) {
+	u, _ := url.Parse(target)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// Начало входа через провайдера: редирект на его страницу авторизации.
+// redirect_uri — куда вернуть игрока с билетом для обмена на токен.
+// При привязке вместо redirect_uri приходит link — одноразовый ключ из
+// /link; браузер, открывший его первым, получает cookie входа.
+func (l *Logger) oauthStartHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔑", "/api/auth/oauth/{provider}", func() {
+		cfg := currentConfig()
+		name, provider, client, ok := oauthProviderFromPath(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		flow := oauthFlow{provider: name, redirect: r.URL.Query().Get("redirect_uri"), expiresAt: now.Add(oauthFlowTTL)}
+		if link := r.URL.Query().Get("link"); link != "" {
+			linkFlow, ok := takeOAuthFlow(link, now)
+			if !ok || linkFlow.provider != name || linkFlow.accountID == "" {
+				writeError(w, r, http.StatusBadRequest, ErrCodeOAuthStateInvalid)
+				return
+			}
+			flow.redirect, flow.accountID = linkFlow.redirect, linkFlow.accountID
+		} else if !oauthRedirectAllowed(cfg, flow.redirect) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		// state для провайдера отличается от ключа привязки: ключ мог
+		// увидеть кто угодно, а state знает только этот браузер
+		state := newOAuthFlow(flow, now)
+		http.SetCookie(w, &http.Cookie{
+			Name:     oauthStateCookie,
+			Value:    state,
+			Path:     "/api/",
+			MaxAge:   int(oauthFlowTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(cfg.PublicURL, "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, provider.authorizeURL(client, oauthCallbackURL(cfg, name), state), http.StatusFound)
+	})
+}
+
+// Привязка провайдера к текущему аккаунту: одноразовый адрес на этом
+// сервере, который лаунчер открывает в браузере. Он ставит cookie входа
+// и уводит на страницу авторизации провайдера.
+func (l *Logger) oauthLinkHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔑", "/api/auth/oauth/{provider}/link", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		cfg := currentConfig()
+		name, _, _, ok := oauthProviderFromPath(w, r, cfg)
+		if !ok {
+			return
+		}
+		var req OAuthLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !oauthRedirectAllowed(cfg, req.RedirectURI) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		now := time.Now()
+		link := newOAuthFlow(oauthFlow{provider: name, redirect: req.RedirectURI, accountID: account.ID, expiresAt: now.Add(oauthFlowTTL)}, now)
+		json.NewEncoder(w).Encode(OAuthLinkResponse{URL: cfg.PublicURL + "/api/auth/oauth/" + name + "?" + url.Values{"link": {link}}.Encode()})
+	})
+}
+
+// Возврат от провайдера. Игрок уходит на адрес лаунчера с ticket=...
+// (вход), linked=<провайдер> (привязка) или error=<код ошибки>.
+func (l *Logger) oauthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔑", "/api/auth/oauth/{provider}/callback", func() {
+		cfg := currentConfig()
+		name, provider, client, ok := oauthProviderFromPath(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		query := r.URL.Query()
+		state := query.Get("state")
+		// И вход, и привязка завершаются только в браузере, который их начал
+		flow, ok := takeOAuthFlow(state, now)
+		if ok {
+			cookie, err := r.Cookie(oauthStateCookie)
+			ok = err == nil && cookie.Value == state
+			http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/api/", MaxAge: -1})
+		}
+		if !ok || flow.provider != name {
+			writeError(w, r, http.StatusBadRequest, ErrCodeOAuthStateInvalid)
+			return
+		}
+		fail := func(code string) {
+			oauthRedirect(w, r, flow.redirect, url.Values{"error": {code}})
+		}
+
+		if query.Get("error") != "" || query.Get("code") == "" {
+			l.logError("Вход через %s отменен: %s", name, query.Get("error"))
+			fail(ErrCodeOAuthDenied)
+			return
+		}
+		identity, err := provider.fetchIdentity(r.Context(), client, oauthCallbackURL(cfg, name), query.Get("code"))
+		if err != nil {
+			l.logError("Ошибка входа через %s: %v", name, err)
+			fail(ErrCodeOAuthFailed)
+			return
+		}
+		identity.Provider = name
+		identity.LinkedAt = now
+
+		if flow.accountID != "" {
+			account, apiErr, err := accounts.LinkIdentity(flow.accountID, identity)
+			if err != nil {
+				l.logError("Ошибка сохранения аккаунтов: %v", err)
+				fail(ErrCodeInternal)
+				return
+			}
+			if apiErr != nil {
+				fail(apiErr.code)
+				return
+			}
+			oauthRedirect(w, r, flow.redirect, url.Values{"linked": {name}})
+			l.logSuccess("К аккаунту %s привязан %s (%s)", account.Username, name, identity.Name)
+			return
+		}
+
+		account, found := accounts.ByIdentity(name, identity.Subject)
+		if !found {
+			if !cfg.AccountRegistration || switchOn(SwitchRegistration) {
+				fail(ErrCodeRegistrationDisabled)
+				return
+			}
+			if account, err = createOAuthAccount(identity, now); err != nil {
+				l.logError("Ошибка создания аккаунта через %s: %v", name, err)
+				fail(ErrCodeInternal)
+				return
+			}
+			l.logSuccess("Зарегистрирован аккаунт %s (%s) через %s", account.Username, account.ID, name)
+		}
+
+		oauthRedirect(w, r, flow.redirect, url.Values{"ticket": {newOAuthTicket(account.ID, now)}})
+	})
+}
+
+// Обмен билета на токен сессии — тот же, что выдает вход по паролю
+func (l *Logger) oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔑", "/api/auth/oauth/token", func() {
+		var req OAuthTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Ticket == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		accountID, ok := lookupOAuthTicket(req.Ticket, time.Now())
+		account, found := accounts.ByID(accountID)
+		if !ok || !found {
+			writeError(w, r, http.StatusUnauthorized, ErrCodeOAuthTicketInvalid)
+			return
+		}
+		// Билет остается действительным, пока не введен верный код 2FA
+		if account.TOTPEnabled {
+			if req.TOTPCode == "" {
+				writeError(w, r, http.StatusUnauthorized, ErrCodeTOTPRequired)
+				return
+			}
+			if account, found = l.verifyAccountCode(w, r, account, req.TOTPCode); !found {
+				return
+			}
+		}
+		dropOAuthTicket(req.Ticket)
+
+		l.writeAccountToken(w, r, http.StatusOK, account)
+		l.logSuccess("Вход в аккаунт %s через внешний провайдер", account.Username)
+	})
+}
+
+// Отвязка провайдера. Последний способ входа аккаунта без пароля не отвязать.
+func (l *Logger) oauthUnlinkHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔑", "/api/account/identities/{provider}", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		name := r.PathValue("provider")
+		updated, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			i := slices.IndexFunc(a.Identities, func(id AccountIdentity) bool { return id.Provider == name })
+			if i < 0 {
+				return &modError{http.StatusNotFound, ErrCodeOAuthProviderNotFound, []interface{}{name}}
+			}
+			if a.PasswordHash == "" && len(a.Identities) == 1 {
+				return &modError{http.StatusConflict, ErrCodeOAuthLastLoginMethod, nil}
+			}
+			a.Identities = slices.Delete(slices.Clone(a.Identities), i, i+1)
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		json.NewEncoder(w).Encode(updated.info())
+		l.logSuccess("От аккаунта %s отвязан %s", account.Username, name)
+	})
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Описание операции публичного API для спецификации OpenAPI. Пути и
+// параметры пути берутся из зарегистрированных маршрутов, схемы тел —
+// из Go-типов запросов и ответов, поэтому спецификация не расходится с
+// сервером. Маршрут без описания все равно попадает в спецификацию.
+type openAPIOperation struct {
+	Summary string
+	Tag     string
+	// Нужен Bearer-токен аккаунта
+	Auth  bool
+	Query []openAPIParam
+	// Тело запроса: Go-тип для JSON или RequestContent для остального
+	Request        reflect.Type
+	RequestContent string
+	// Успешный ответ: Go-тип для JSON или Content для файлов и потоков
+	Response reflect.Type
+	Content  string
+	// Статус успешного ответа; по умолчанию 200 (204 без тела)
+	Status int
+}
+
+type openAPIParam struct {
+	Name        string
+	Description string
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+var (
+	langParam    = openAPIParam{"lang", "Язык (ru, en, ...); важнее заголовка Accept-Language"}
+	channelParam = openAPIParam{"channel", "Канал проекта (beta, ...); по умолчанию основной"}
+	stagingParam = openAPIParam{"channel", "staging — тестовый канал, виден только тестерам"}
+)
+
+// Операции публичного API по шаблонам маршрутов относительно /api/{version}
+var openAPIOperations = map[string]openAPIOperation{
+	"/news":                                  {Summary: "Опубликованные новости на языке клиента", Tag: "news", Query: []openAPIParam{langParam, {"format", "html — заполнить rendered_html"}, {"category", "Только новости категории"}, {"tag", "Только новости с тегом"}}, Response: typeOf[NewsResponse]()},
+	"/news.rss":                              {Summary: "Новости в RSS", Tag: "news", Query: []openAPIParam{langParam}, Content: "application/rss+xml"},
+	"/news.atom":                             {Summary: "Новости в Atom", Tag: "news", Query: []openAPIParam{langParam}, Content: "application/atom+xml"},
+	"POST /news/{id}/view":                   {Summary: "Отметка о прочтении новости (аккаунт или X-Client-ID)", Tag: "news", Response: typeOf[NewsEngagementResponse]()},
+	"PUT /news/{id}/reactions/{reaction}":    {Summary: "Реакция на новость: like или heart", Tag: "news", Response: typeOf[NewsEngagementResponse]()},
+	"DELETE /news/{id}/reactions/{reaction}": {Summary: "Снятие реакции на новость", Tag: "news", Response: typeOf[NewsEngagementResponse]()},
+	"/version": {Summary: "Актуальные версии, номера сборок и режим техработ", Tag: "version", Query: []openAPIParam{
+		{"launcher_version", "Версия лаунчера клиента, для must_update"},
+		{"game_version", "Версия игры клиента, для must_update"},
+		stagingParam,
+	}, Response: typeOf[VersionResponse]()},
+	"GET /launcher/update":                      {Summary: "Самообновление лаунчера с поэтапной раскаткой", Tag: "version", Query: []openAPIParam{{"current_version", "Версия лаунчера клиента"}, {"client_id", "ID установки, если нет заголовка X-Client-ID"}}, Response: typeOf[LauncherUpdateResponse]()},
+	"GET /launcher-config":                      {Summary: "Удаленная конфигурация и флаги функций лаунчера", Tag: "version", Response: typeOf[LauncherConfigResponse]()},
+	"GET /switches":                             {Summary: "Аварийные выключатели функций (ETag по ревизии)", Tag: "version", Response: typeOf[KillSwitchesResponse]()},
+	"GET /motd":                                 {Summary: "Сообщение дня для баннера лаунчера", Tag: "news", Query: []openAPIParam{langParam}, Response: typeOf[MOTDResponse]()},
+	"POST /telemetry":                           {Summary: "Пакет событий телеметрии", Tag: "telemetry", Request: typeOf[TelemetryBatch](), Response: typeOf[TelemetryResponse](), Status: http.StatusAccepted},
+	"POST /survey/hardware":                     {Summary: "Ответ на опрос о железе (с согласия игрока)", Tag: "telemetry", Request: typeOf[HardwareSurvey](), Response: typeOf[HardwareSurveyResponse](), Status: http.StatusAccepted},
+	"GET /runtime":                              {Summary: "Рантайм для платформы клиента", Tag: "downloads", Query: []openAPIParam{{"os", "windows, linux или macos"}, {"arch", "amd64 или arm64"}}, Response: typeOf[RuntimeResponse]()},
+	"GET /launch-profile":                       {Summary: "Как запускать игру: исполняемый файл, аргументы, окружение", Tag: "version", Query: []openAPIParam{{"game_version", "Версия игры; по умолчанию текущая"}, {"os", "windows, linux или macos"}}, Response: typeOf[LaunchProfileResponse]()},
+	"GET /changelog":                            {Summary: "Что изменилось между версиями игры", Tag: "version", Query: []openAPIParam{{"from", "Версия игрока; без нее — все версии"}, {"to", "Версия после обновления; по умолчанию текущая"}, {"format", "html — заполнить rendered_html"}, stagingParam}, Response: typeOf[ChangelogResponse]()},
+	"GET /dependencies":                         {Summary: "Зависимости версии игры: библиотеки, нативные библиотеки, ресурсы", Tag: "version", Query: []openAPIParam{{"game_version", "Версия игры; по умолчанию текущая"}, {"os", "windows, linux или macos"}, {"arch", "amd64, arm64 или x86"}}, Response: typeOf[DependencyManifest]()},
+	"GET /mods":                                 {Summary: "Манифест модов для версии игры", Tag: "mods", Query: []openAPIParam{{"game_version", "Версия игры; по умолчанию текущая"}}, Response: typeOf[ModsManifest]()},
+	"GET /modpacks":                             {Summary: "Профили сборок модов", Tag: "mods", Query: []openAPIParam{{"game_version", "Версия игры; по умолчанию текущая"}}, Response: typeOf[ModpacksResponse]()},
+	"GET /resourcepacks":                        {Summary: "Ресурспаки", Tag: "mods", Response: typeOf[ResourcePacksResponse]()},
+	"GET /resourcepack/{name}/hash":             {Summary: "Хэш текущей версии ресурспака", Tag: "mods", Response: typeOf[ResourcePackInfo]()},
+	"GET /eula":                                 {Summary: "Правила сервера", Tag: "eula", Query: []openAPIParam{langParam, {"format", "html — отдать правила в HTML"}, {"account", "Проверить согласие аккаунта"}}, Response: typeOf[EULAResponse]()},
+	"POST /eula/accept":                         {Summary: "Согласие с правилами", Tag: "eula", Auth: true, Request: typeOf[EULAAcceptRequest](), Response: typeOf[EULAAcceptance]()},
+	"POST /session/start":                       {Summary: "Начало игровой сессии", Tag: "sessions", Request: typeOf[SessionStartRequest](), Response: typeOf[SessionStartResponse]()},
+	"POST /session/heartbeat":                   {Summary: "Подтверждение игровой сессии", Tag: "sessions", Request: typeOf[SessionRequest]()},
+	"POST /session/end":                         {Summary: "Завершение игровой сессии", Tag: "sessions", Request: typeOf[SessionRequest]()},
+	"GET /online":                               {Summary: "Число игроков онлайн", Tag: "sessions", Response: typeOf[OnlineResponse]()},
+	"POST /auth/register":                       {Summary: "Регистрация аккаунта (отпечаток железа в X-Client-HWID, CAPTCHA в X-Captcha-Token)", Tag: "auth", Request: typeOf[AccountCredentials](), Response: typeOf[AccountTokenResponse](), Status: http.StatusCreated},
+	"POST /auth/login":                          {Summary: "Вход по имени и паролю (отпечаток железа в X-Client-HWID)", Tag: "auth", Request: typeOf[AccountCredentials](), Response: typeOf[AccountTokenResponse]()},
+	"POST /auth/refresh":                        {Summary: "Новый токен доступа по токену обновления", Tag: "auth", Request: typeOf[RefreshTokenRequest](), Response: typeOf[AccountTokenResponse]()},
+	"POST /auth/logout":                         {Summary: "Закрытие сессии текущего токена", Tag: "auth", Auth: true},
+	"POST /auth/verify":                         {Summary: "Подтверждение адреса почты", Tag: "auth", Request: typeOf[EmailTokenRequest](), Response: typeOf[AccountInfo]()},
+	"GET /auth/verify":                          {Summary: "Подтверждение адреса почты по ссылке из письма", Tag: "auth", Query: []openAPIParam{{"token", "Токен из письма"}}, Response: typeOf[AccountInfo]()},
+	"POST /auth/forgot":                         {Summary: "Письмо для сброса пароля", Tag: "auth", Request: typeOf[ForgotPasswordRequest]()},
+	"POST /auth/reset":                          {Summary: "Сброс пароля по токену из письма", Tag: "auth", Request: typeOf[ResetPasswordRequest]()},
+	"GET /.well-known/jwks.json":                {Summary: "Открытые ключи токенов доступа", Tag: "auth", Response: typeOf[JWKSet]()},
+	"POST /auth/oauth/token":                    {Summary: "Токен по билету входа через внешнего провайдера", Tag: "auth", Request: typeOf[OAuthTokenRequest](), Response: typeOf[AccountTokenResponse]()},
+	"GET /auth/oauth/{provider}":                {Summary: "Переход к входу через внешнего провайдера", Tag: "auth", Query: []openAPIParam{{"redirect_uri", "Куда вернуть билет входа"}, {"link", "Одноразовый ключ привязки из /link"}}, Status: http.StatusFound},
+	"GET /auth/oauth/{provider}/callback":       {Summary: "Возврат от внешнего провайдера", Tag: "auth", Query: []openAPIParam{{"code", "Код авторизации"}, {"state", "Состояние из перехода"}, {"error", "Ошибка провайдера"}}, Status: http.StatusFound},
+	"POST /auth/oauth/{provider}/link":          {Summary: "Привязка внешнего провайдера к аккаунту", Tag: "account", Auth: true, Request: typeOf[OAuthLinkRequest](), Response: typeOf[OAuthLinkResponse]()},
+	"DELETE /account/identities/{provider}":     {Summary: "Отвязка внешнего провайдера", Tag: "account", Auth: true, Response: typeOf[AccountInfo]()},
+	"GET /account":                              {Summary: "Текущий аккаунт", Tag: "account", Auth: true, Response: typeOf[AccountInfo]()},
+	"GET /account/sessions":                     {Summary: "Открытые сессии аккаунта", Tag: "account", Auth: true, Response: typeOf[[]AccountSessionInfo]()},
+	"DELETE /account/sessions":                  {Summary: "Закрытие сессий аккаунта", Tag: "account", Auth: true, Query: []openAPIParam{{"all", "true — вместе с текущей"}}, Response: typeOf[RevokeSessionsResponse]()},
+	"DELETE /account/sessions/{id}":             {Summary: "Закрытие одной сессии", Tag: "account", Auth: true},
+	"POST /account/delete":                      {Summary: "Запрос удаления аккаунта", Tag: "account", Auth: true, Request: typeOf[AccountDeleteRequest](), Response: typeOf[AccountInfo]()},
+	"POST /account/delete/cancel":               {Summary: "Отмена удаления аккаунта", Tag: "account", Auth: true, Response: typeOf[AccountInfo]()},
+	"GET /achievements":                         {Summary: "Достижения (без скрытых)", Tag: "achievements", Response: typeOf[AchievementsResponse]()},
+	"GET /achievements/{id}/icon":               {Summary: "Иконка достижения", Tag: "achievements", Content: "image/jpeg"},
+	"POST /achievements/unlock":                 {Summary: "Прогресс игрока от игрового сервера (Bearer GAME_SERVER_SECRET)", Tag: "achievements", Request: typeOf[AchievementUnlockRequest](), Response: typeOf[AchievementUnlockResponse]()},
+	"GET /account/achievements":                 {Summary: "Достижения с прогрессом игрока", Tag: "achievements", Auth: true, Response: typeOf[AchievementsResponse]()},
+	"GET /account/friends":                      {Summary: "Друзья с присутствием и заявки", Tag: "friends", Auth: true, Response: typeOf[FriendsResponse]()},
+	"POST /account/friends":                     {Summary: "Заявка в друзья; встречная заявка принимается", Tag: "friends", Auth: true, Request: typeOf[FriendRequest](), Response: typeOf[FriendsResponse]()},
+	"POST /account/friends/{username}/accept":   {Summary: "Принять заявку в друзья", Tag: "friends", Auth: true, Response: typeOf[FriendsResponse]()},
+	"DELETE /account/friends/{username}":        {Summary: "Удалить из друзей или отклонить заявку", Tag: "friends", Auth: true, Response: typeOf[FriendsResponse]()},
+	"GET /account/notifications":                {Summary: "Настройки push-уведомлений и устройства", Tag: "notifications", Auth: true, Response: typeOf[NotificationsResponse]()},
+	"PUT /account/notifications":                {Summary: "Выбор push-уведомлений", Tag: "notifications", Auth: true, Request: typeOf[PushSettings](), Response: typeOf[NotificationsResponse]()},
+	"POST /account/devices":                     {Summary: "Регистрация устройства с токеном FCM", Tag: "notifications", Auth: true, Request: typeOf[PushDeviceRequest](), Response: typeOf[NotificationsResponse](), Status: http.StatusCreated},
+	"DELETE /account/devices/{token}":           {Summary: "Отвязка устройства", Tag: "notifications", Auth: true, Status: http.StatusNoContent},
+	"GET /inbox":                                {Summary: "Входящие сообщения", Tag: "inbox", Auth: true, Query: []openAPIParam{{"unread", "true — только непрочитанные"}}, Response: typeOf[InboxResponse]()},
+	"POST /inbox":                               {Summary: "Сообщение другу", Tag: "inbox", Auth: true, Request: typeOf[InboxSendRequest](), Response: typeOf[InboxMessage](), Status: http.StatusCreated},
+	"POST /inbox/read":                          {Summary: "Отметить все сообщения прочитанными", Tag: "inbox", Auth: true, Status: http.StatusNoContent},
+	"POST /inbox/{id}/read":                     {Summary: "Отметить сообщение прочитанным", Tag: "inbox", Auth: true, Status: http.StatusNoContent},
+	"DELETE /inbox/{id}":                        {Summary: "Удалить сообщение", Tag: "inbox", Auth: true, Status: http.StatusNoContent},
+	"POST /anticheat/reports":                   {Summary: "Жалоба античита от игрового сервера (Bearer GAME_SERVER_SECRET)", Tag: "anticheat", Request: typeOf[CheatReportRequest](), Response: typeOf[CheatReportResponse](), Status: http.StatusAccepted},
+	"GET /leaderboards":                         {Summary: "Таблицы рекордов", Tag: "leaderboards", Response: typeOf[LeaderboardsResponse]()},
+	"GET /leaderboards/{id}":                    {Summary: "Страница таблицы рекордов", Tag: "leaderboards", Query: []openAPIParam{{"period", "previous — прошлый период или ключ периода (2025-W14); по умолчанию текущий"}, {"offset", "Сколько мест пропустить"}, {"limit", "Размер страницы, до 100"}, {"player", "Добавить место игрока"}}, Response: typeOf[LeaderboardPage]()},
+	"POST /leaderboards/{id}/scores":            {Summary: "Результат игрока от игрового сервера (Bearer GAME_SERVER_SECRET)", Tag: "leaderboards", Request: typeOf[ScoreSubmission](), Response: typeOf[ScoreSubmitResponse]()},
+	"GET /profile/{username}":                   {Summary: "Публичный профиль игрока", Tag: "profile", Response: typeOf[PlayerProfile]()},
+	"GET /profile/{username}/avatar":            {Summary: "Аватар игрока", Tag: "profile", Content: "image/jpeg"},
+	"PUT /account/profile":                      {Summary: "Смена отображаемого имени", Tag: "profile", Auth: true, Request: typeOf[AccountProfileRequest](), Response: typeOf[PlayerProfile]()},
+	"PUT /account/avatar":                       {Summary: "Загрузка аватара: обрезается до квадрата и уменьшается", Tag: "profile", Auth: true, RequestContent: "image/*", Response: typeOf[PlayerProfile]()},
+	"DELETE /account/avatar":                    {Summary: "Удаление аватара", Tag: "profile", Auth: true, Response: typeOf[PlayerProfile]()},
+	"PUT /account/email":                        {Summary: "Смена адреса почты", Tag: "account", Auth: true, Request: typeOf[AccountEmailRequest](), Response: typeOf[AccountInfo]()},
+	"GET /account/export":                       {Summary: "Выгрузка данных аккаунта", Tag: "account", Auth: true, Content: "application/zip"},
+	"POST /account/2fa/enroll":                  {Summary: "Начало подключения 2FA", Tag: "account", Auth: true, Response: typeOf[TOTPEnrollResponse]()},
+	"POST /account/2fa/verify":                  {Summary: "Включение 2FA кодом из приложения", Tag: "account", Auth: true, Request: typeOf[TOTPCodeRequest](), Response: typeOf[RecoveryCodesResponse]()},
+	"POST /account/2fa/recovery-codes":          {Summary: "Новые коды восстановления", Tag: "account", Auth: true, Request: typeOf[TOTPCodeRequest](), Response: typeOf[RecoveryCodesResponse]()},
+	"POST /account/2fa/disable":                 {Summary: "Отключение 2FA", Tag: "account", Auth: true, Request: typeOf[TOTPCodeRequest]()},
+	"GET /sync":                                 {Summary: "Синхронизируемые настройки", Tag: "sync", Auth: true, Response: typeOf[SyncListResponse]()},
+	"GET /sync/{key}":                           {Summary: "Значение настройки", Tag: "sync", Auth: true, Content: "application/octet-stream"},
+	"PUT /sync/{key}":                           {Summary: "Запись настройки", Tag: "sync", Auth: true, RequestContent: "application/octet-stream", Response: typeOf[SyncEntry]()},
+	"DELETE /sync/{key}":                        {Summary: "Удаление настройки", Tag: "sync", Auth: true},
+	"GET /saves":                                {Summary: "Облачные сохранения", Tag: "saves", Auth: true, Response: typeOf[SavesResponse]()},
+	"POST /saves/uploads":                       {Summary: "Начало загрузки сохранения", Tag: "saves", Auth: true, Request: typeOf[SaveUploadRequest](), Response: typeOf[SaveUploadResponse](), Status: http.StatusCreated},
+	"GET /saves/uploads/{id}":                   {Summary: "Состояние загрузки сохранения", Tag: "saves", Auth: true, Response: typeOf[SaveUploadResponse]()},
+	"PUT /saves/uploads/{id}":                   {Summary: "Часть архива сохранения", Tag: "saves", Auth: true, Query: []openAPIParam{{"offset", "Смещение части в архиве"}}, RequestContent: "application/octet-stream", Response: typeOf[SaveUploadResponse]()},
+	"POST /saves/uploads/{id}/complete":         {Summary: "Завершение загрузки сохранения", Tag: "saves", Auth: true, Response: typeOf[Save](), Status: http.StatusCreated},
+	"GET /saves/{id}":                           {Summary: "Скачивание сохранения", Tag: "saves", Auth: true, Content: "application/octet-stream"},
+	"DELETE /saves/{id}":                        {Summary: "Удаление сохранения", Tag: "saves", Auth: true},
+	"GET /screenshots":                          {Summary: "Лента скриншотов", Tag: "screenshots", Query: []openAPIParam{{"before", "Курсор: скриншоты старше этого id"}, {"limit", "Размер страницы"}}, Response: typeOf[ScreenshotFeedResponse]()},
+	"GET /account/screenshots":                  {Summary: "Скриншоты аккаунта", Tag: "screenshots", Auth: true, Response: typeOf[[]ScreenshotInfo]()},
+	"POST /screenshots":                         {Summary: "Загрузка скриншота", Tag: "screenshots", Auth: true, Query: []openAPIParam{{"caption", "Подпись"}}, RequestContent: "image/*", Response: typeOf[ScreenshotInfo](), Status: http.StatusCreated},
+	"GET /screenshots/{id}/image":               {Summary: "Скриншот", Tag: "screenshots", Content: "image/jpeg"},
+	"GET /screenshots/{id}/thumb":               {Summary: "Миниатюра скриншота", Tag: "screenshots", Content: "image/jpeg"},
+	"POST /feedback":                            {Summary: "Сообщение об ошибке или предложение (CAPTCHA в X-Captcha-Token, если включена)", Tag: "feedback", Request: typeOf[FeedbackRequest](), Response: typeOf[FeedbackResponse](), Status: http.StatusCreated},
+	"GET /entitlements":                         {Summary: "Права аккаунта", Tag: "account", Auth: true, Response: typeOf[EntitlementsResponse]()},
+	"POST /redeem":                              {Summary: "Активация промокода", Tag: "account", Auth: true, Request: typeOf[RedeemRequest](), Response: typeOf[PromoRedemption](), Status: http.StatusCreated},
+	"POST /download/report":                     {Summary: "Отчет лаунчера о скачивании: получено байт, хэш файла", Tag: "downloads", Request: typeOf[DownloadReportRequest](), Response: typeOf[DownloadReportResponse]()},
+	"POST /download/queue":                      {Summary: "Место в очереди на скачивание", Tag: "downloads", Response: typeOf[DownloadQueueStatus]()},
+	"GET /download/queue/{token}":               {Summary: "Позиция в очереди на скачивание", Tag: "downloads", Query: []openAPIParam{{"wait", "true — ждать изменения позиции"}}, Response: typeOf[DownloadQueueStatus]()},
+	"DELETE /download/queue/{token}":            {Summary: "Выход из очереди на скачивание", Tag: "downloads"},
+	"/download/launcher":                        {Summary: "Скачивание лаунчера", Tag: "downloads", Query: []openAPIParam{stagingParam}, Content: "application/octet-stream"},
+	"/download/game":                            {Summary: "Скачивание клиента игры", Tag: "downloads", Auth: true, Query: []openAPIParam{stagingParam}, Content: "application/octet-stream"},
+	"GET /download/runtime/{os}/{arch}":         {Summary: "Скачивание рантайма", Tag: "downloads", Content: "application/octet-stream"},
+	"GET /download/mod/{id}/{version}":          {Summary: "Скачивание мода", Tag: "mods", Content: "application/octet-stream"},
+	"GET /resourcepack/{name}":                  {Summary: "Скачивание текущей версии ресурспака", Tag: "mods", Content: "application/octet-stream"},
+	"GET /resourcepack/{name}/{version}":        {Summary: "Скачивание версии ресурспака", Tag: "mods", Content: "application/octet-stream"},
+	"/download/game.torrent":                    {Summary: ".torrent клиента игры", Tag: "downloads", Content: "application/x-bittorrent"},
+	"/download/game.zip":                        {Summary: "Архив каталога игры", Tag: "downloads", Content: "application/zip"},
+	"/download/game.tar.gz":                     {Summary: "Архив каталога игры", Tag: "downloads", Content: "application/gzip"},
+	"POST /verify":                              {Summary: "Проверка установки игры; с ?archive= — архив файлов для починки", Tag: "downloads", Query: []openAPIParam{{"archive", "zip или tar.gz — вместо списка отдать архив"}}, Request: typeOf[VerifyRequest](), Response: typeOf[VerifyResponse]()},
+	"GET /announce":                             {Summary: "BitTorrent-трекер", Tag: "downloads", Content: "text/plain"},
+	"GET /checksums/{artifact}":                 {Summary: "Контрольные суммы артефакта", Tag: "downloads", Content: "text/plain"},
+	"GET /signature/{artifact}":                 {Summary: "Подпись артефакта", Tag: "downloads", Response: typeOf[SignatureResponse]()},
+	"GET /pubkey":                               {Summary: "Открытый ключ подписи релизов", Tag: "downloads", Response: typeOf[PublicKeyResponse]()},
+	"GET /manifest/{artifact}":                  {Summary: "Манифест чанков артефакта", Tag: "downloads", Response: typeOf[ChunkManifest]()},
+	"GET /chunks/{hash}":                        {Summary: "Чанк по SHA-256", Tag: "downloads", Content: "application/octet-stream"},
+	"GET /dependencies/objects/{hash}":          {Summary: "Файл зависимости по SHA-256", Tag: "downloads", Content: "application/octet-stream"},
+	"POST /mirror/register":                     {Summary: "Регистрация зеркала (Bearer токен зеркала)", Tag: "mirrors", Request: typeOf[MirrorRegisterRequest](), Status: http.StatusNoContent},
+	"GET /mirror/manifest":                      {Summary: "Версии, сборки и хэш новостей для зеркала", Tag: "mirrors", Response: typeOf[MirrorManifest]()},
+	"GET /mirror/files/{artifact}":              {Summary: "Сборка для зеркала", Tag: "mirrors", Content: "application/octet-stream"},
+	"GET /mirror/news":                          {Summary: "news.json для зеркала", Tag: "mirrors", Response: typeOf[[]NewsItem]()},
+	"POST /mirror/notify":                       {Summary: "Уведомление зеркала о публикации (X-Loil-Signature)", Tag: "mirrors", Request: typeOf[MirrorNotification](), Status: http.StatusAccepted},
+	"GET /events":                               {Summary: "Поток объявлений (SSE): новости, версии, техработы; с токеном аккаунта — заявки в друзья и присутствие друзей", Tag: "version", Query: []openAPIParam{{"last_event_id", "Вместо заголовка Last-Event-ID"}}, Content: "text/event-stream"},
+	"GET /projects":                             {Summary: "Игры, которые обслуживает сервер", Tag: "projects", Response: typeOf[ProjectsResponse]()},
+	"GET /projects/{project}/version":           {Summary: "Версии проекта; короткий путь /api/{project}/version", Tag: "projects", Query: []openAPIParam{channelParam}, Response: typeOf[VersionResponse]()},
+	"GET /projects/{project}/news":              {Summary: "Новости проекта; короткий путь /api/{project}/news", Tag: "projects", Query: []openAPIParam{langParam, {"format", "html — заполнить rendered_html"}, {"category", "Только новости категории"}, {"tag", "Только новости с тегом"}}, Response: typeOf[NewsResponse]()},
+	"GET /projects/{project}/download/launcher": {Summary: "Скачивание лаунчера проекта", Tag: "projects", Query: []openAPIParam{channelParam}, Content: "application/octet-stream"},
+	"GET /projects/{project}/download/game":     {Summary: "Скачивание клиента игры проекта", Tag: "projects", Auth: true, Query: []openAPIParam{channelParam}, Content: "application/octet-stream"},
+	"GET /openapi.json":                         {Summary: "Эта спецификация", Tag: "meta", Content: "application/json"},
+}
+
+var openAPIPathParam = regexp.MustCompile(`\{(\w+)(?:\.\.\.)?\}`)
+
+// Спецификация OpenAPI 3.1 версии API по маршрутам роутера
+func buildOpenAPISpec(cfg *Config, router *Router, version string) map[string]any {
+	schemas := openAPISchemas{}
+	errorSchema := schemas.schema(typeOf[ErrorResponse]())
+	paths := map[string]map[string]any{}
+
+	for _, pattern := range router.routes[version] {
+		method, path, found := strings.Cut(pattern, " ")
+		if !found {
+			// Маршрут без метода отвечает на любой; описываем как GET
+			method, path = "GET", pattern
+		}
+		op := openAPIOperations[pattern]
+
+		path = strings.TrimSuffix(path, "{$}")
+		operation := map[string]any{
+			"operationId": openAPIOperationID(method, path),
+			"responses": map[string]any{
+				"default": map[string]any{
+					"description": "Ошибка в стандартном формате API",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errorSchema}},
+				},
+			},
+		}
+		if op.Summary != "" {
+			operation["summary"] = op.Summary
+		}
+		if op.Tag != "" {
+			operation["tags"] = []string{op.Tag}
+		}
+		if op.Auth {
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		var parameters []map[string]any
+		for _, match := range openAPIPathParam.FindAllStringSubmatch(path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, param := range op.Query {
+			parameters = append(parameters, map[string]any{
+				"name": param.Name, "in": "query", "description": param.Description, "schema": map[string]any{"type": "string"},
+			})
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+
+		switch {
+		case op.Request != nil:
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemas.schema(op.Request)}},
+			}
+		case op.RequestContent != "":
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{op.RequestContent: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}},
+			}
+		}
+
+		response := map[string]any{"description": "Успешный ответ"}
+		status := op.Status
+		switch {
+		case op.Response != nil:
+			response["content"] = map[string]any{"application/json": map[string]any{"schema": schemas.schema(op.Response)}}
+		case op.Content != "":
+			response["content"] = map[string]any{op.Content: map[string]any{"schema": map[string]any{"type": "string"}}}
+		case status == 0:
+			status = http.StatusNoContent
+		}
+		if status == 0 {
+			status = http.StatusOK
+		}
+		operation["responses"].(map[string]any)[strconv.Itoa(status)] = response
+
+		path = openAPIPathParam.ReplaceAllString(path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "LOIL launcher API",
+			"version": buildVersion,
+		},
+		"servers": []map[string]any{{"url": cfg.PublicURL + "/api/" + version}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// Имя операции для генераторов клиентов: метод и сегменты пути,
+// параметры пути через By (GET /resourcepack/{name}/hash → getResourcepackByNameHash)
+func openAPIOperationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if match := openAPIPathParam.FindStringSubmatch(segment); match != nil {
+			id.WriteString("By")
+			segment = match[1]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			id.WriteString(string(runes))
+		}
+	}
+	return id.String()
+}
+
+// Схемы именованных структур по имени Go-типа
+type openAPISchemas map[string]any
+
+func (s openAPISchemas) schema(t reflect.Type) map[string]any {
+	switch t {
+	case typeOf[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	case typeOf[json.RawMessage]():
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		if _, ok := s[t.Name()]; !ok {
+			// Заглушка до построения, чтобы рекурсивные типы не зациклились
+			s[t.Name()] = nil
+			s[t.Name()] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+func (s openAPISchemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.fields(t, properties, &required)
+
+	object := map[string]any{"type": "object", "properties": properties}
+	if required != nil {
+		object["required"] = required
+	}
+	return object
+}
+
+// Поля структуры по правилам encoding/json; встроенные структуры без
+// имени в теге раскрываются в поля внешней
+func (s openAPISchemas) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			s.fields(embedded, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = s.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// Собранная спецификация; пересобирается при смене конфигурации
+// (PUBLIC_URL попадает в servers)
+type openAPICacheEntry struct {
+	cfg  *Config
+	body []byte
+}
+
+var openAPICache atomic.Pointer[openAPICacheEntry]
+
+func openAPISpecBody(router *Router) []byte {
+	cfg := currentConfig()
+	if cached := openAPICache.Load(); cached != nil && cached.cfg == cfg {
+		return cached.body
+	}
+
+	body, _ := json.MarshalIndent(buildOpenAPISpec(cfg, router, "v1"), "", "  ")
+	body = append(body, '\n')
+	openAPICache.Store(&openAPICacheEntry{cfg: cfg, body: body})
+	return body
+}
+
+// Спецификация OpenAPI публичного API для генераторов клиентов и
+// документации. Маршруты регистрируются до запуска сервера, поэтому
+// спецификация собирается при первом запросе.
+func (l *Logger) openAPIHandler(router *Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleWithCORS(w, r, "📘", "/api/openapi.json", func() {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write(openAPISpecBody(router))
+		})
+	}
+}
+
+// Та же спецификация на админском слушателе для документации в панели
+// (/admin/docs/)
+func (l *Logger) adminOpenAPIHandler(router *Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, "", "📘", "/admin/api/openapi.json", func() {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write(openAPISpecBody(router))
+		})
+	}
+}
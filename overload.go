@@ -0,0 +1,159 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Защита от перегрузки: раз в OVERLOAD_CHECK_INTERVAL смотрим число
+// горутин, кучу и задержку записи на диск. Пока хоть один показатель выше
+// порога, маршруты с низким приоритетом (телеметрия, статистика, отметки
+// просмотров) получают 503 с Retry-After, а скачивания и проверка версий
+// работают как обычно.
+
+// Перегрузка снимается, когда все показатели опустились ниже этой доли
+// порога, чтобы сервер не переключался туда-обратно на каждой проверке
+const overloadRecoverRatio = 0.8
+
+// Пробный файл для замера задержки диска в DATA_DIR
+const overloadProbeFile = ".overload-probe"
+
+// Состояние защиты: /admin/api/debug/runtime и expvar overload
+type OverloadStatus struct {
+	Overloaded bool `json:"overloaded"`
+	// Показатели выше порога: goroutines, heap, disk
+	Reasons     []string   `json:"reasons,omitempty"`
+	Since       *time.Time `json:"since,omitempty"`
+	Goroutines  int        `json:"goroutines"`
+	HeapBytes   uint64     `json:"heap_bytes"`
+	DiskLatency string     `json:"disk_latency"`
+	DiskError   string     `json:"disk_error,omitempty"`
+	// Отклонено запросов с запуска
+	Shed      int64     `json:"shed"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type OverloadMonitor struct {
+	overloaded atomic.Bool
+	shed       atomic.Int64
+	mu         sync.Mutex
+	status     OverloadStatus
+}
+
+var overloadMonitor = &OverloadMonitor{}
+
+// Запрос с низким приоритетом сейчас нужно отклонить
+func (m *OverloadMonitor) Shedding() bool {
+	if !m.overloaded.Load() {
+		return false
+	}
+	m.shed.Add(1)
+	return true
+}
+
+func (m *OverloadMonitor) Snapshot() OverloadStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := m.status
+	status.Shed = m.shed.Load()
+	return status
+}
+
+// Время записи и fsync небольшого файла: на перегруженном диске оно
+// растет раньше, чем начинают тормозить скачивания
+func probeDiskLatency(dir string) (time.Duration, error) {
+	started := time.Now()
+	file, err := os.OpenFile(filepath.Join(dir, overloadProbeFile), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if _, err := file.Write(make([]byte, 4096)); err != nil {
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, err
+	}
+	return time.Since(started), nil
+}
+
+// Показатель выше порога; при перегрузке — выше доли порога, с которой
+// она снимается. Нулевой порог — показатель не проверяется.
+func overThreshold(value, threshold float64, overloaded bool) bool {
+	if threshold <= 0 {
+		return false
+	}
+	if overloaded {
+		threshold *= overloadRecoverRatio
+	}
+	return value > threshold
+}
+
+// Замер показателей и переключение режима. Возвращает true, если режим
+// изменился.
+func (m *OverloadMonitor) Check(cfg *Config, now time.Time) (OverloadStatus, bool) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	status := OverloadStatus{Goroutines: runtime.NumGoroutine(), HeapBytes: mem.HeapInuse, CheckedAt: now}
+	latency, err := probeDiskLatency(cfg.DataDir)
+	status.DiskLatency = latency.Round(time.Microsecond).String()
+	if err != nil {
+		status.DiskError = err.Error()
+	}
+
+	overloaded := m.overloaded.Load()
+	if overThreshold(float64(status.Goroutines), float64(cfg.OverloadMaxGoroutines), overloaded) {
+		status.Reasons = append(status.Reasons, "goroutines")
+	}
+	if overThreshold(float64(status.HeapBytes), float64(cfg.OverloadMaxHeapBytes), overloaded) {
+		status.Reasons = append(status.Reasons, "heap")
+	}
+	if err == nil && overThreshold(float64(latency), float64(cfg.OverloadMaxDiskLatency), overloaded) {
+		status.Reasons = append(status.Reasons, "disk")
+	}
+	status.Overloaded = len(status.Reasons) > 0
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if status.Overloaded {
+		status.Since = m.status.Since
+		if status.Since == nil {
+			status.Since = &now
+		}
+	}
+	m.status = status
+	m.overloaded.Store(status.Overloaded)
+	return status, status.Overloaded != overloaded
+}
+
+// Фоновая проверка нагрузки раз в OVERLOAD_CHECK_INTERVAL
+func (l *Logger) runOverloadMonitor() {
+	for {
+		cfg := currentConfig()
+		status, changed := overloadMonitor.Check(cfg, time.Now())
+		if status.DiskError != "" {
+			l.logError("Не удалось замерить задержку диска: %s", status.DiskError)
+		}
+		switch {
+		case changed && status.Overloaded:
+			l.logWarn("Сервер перегружен (%v: горутин %d, куча %d bytes, диск %s): запросы с низким приоритетом отклоняются",
+				status.Reasons, status.Goroutines, status.HeapBytes, status.DiskLatency)
+		case changed:
+			l.logSuccess("Нагрузка снизилась, все запросы снова принимаются (отклонено с запуска: %d)", overloadMonitor.shed.Load())
+		}
+		time.Sleep(cfg.OverloadCheckInterval)
+	}
+}
+
+// Отказ запросу с низким приоритетом: 503 с Retry-After
+func (l *Logger) rejectOverloaded(w http.ResponseWriter, r *http.Request) {
+	retryAfter := int(currentConfig().OverloadRetryAfter.Seconds())
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeError(w, r, http.StatusServiceUnavailable, ErrCodeServerOverloaded, retryAfter)
+}
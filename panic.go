@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Сведения о панике для внешних систем оповещения
+type PanicEvent struct {
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	ClientIP  string    `json:"client_ip"`
+	Error     string    `json:"error"`
+	Stack     string    `json:"stack"`
+	Time      time.Time `json:"time"`
+}
+
+var panicReportClient = &http.Client{Timeout: 10 * time.Second}
+
+// Перехват паники в обработчике: стек в лог, клиенту JSON 500 с request_id,
+// событие — в вебхук и/или Sentry, если они настроены
+func (l *Logger) recoverPanic(w http.ResponseWriter, r *http.Request) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	// Штатный способ оборвать ответ, не ошибка
+	if rec == http.ErrAbortHandler {
+		panic(rec)
+	}
+
+	event := PanicEvent{
+		RequestID: requestID(r),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		ClientIP:  getClientIP(r),
+		Error:     fmt.Sprint(rec),
+		Stack:     string(debug.Stack()),
+		Time:      time.Now().UTC(),
+	}
+
+	l.logError("Паника при обработке %s %s (request_id=%s): %s\n%s",
+		event.Method, event.Path, event.RequestID, event.Error, event.Stack)
+	writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+
+	go l.reportPanic(event)
+}
+
+func (l *Logger) reportPanic(event PanicEvent) {
+	cfg := currentConfig()
+
+	if cfg.PanicWebhookURL != "" {
+		if err := postJSON(cfg.PanicWebhookURL, event, nil); err != nil {
+			l.logError("Не удалось отправить панику в вебхук: %v", err)
+		}
+	}
+
+	if cfg.SentryDSN != "" {
+		if err := sendToSentry(cfg.SentryDSN, event); err != nil {
+			l.logError("Не удалось отправить панику в Sentry: %v", err)
+		}
+	}
+}
+
+func postJSON(target string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := panicReportClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ответ %s", resp.Status)
+	}
+	return nil
+}
+
+// Отправка события через store API Sentry. DSN имеет вид
+// https://<public_key>@<host>/<project_id>
+func sendToSentry(dsn string, event PanicEvent) error {
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.User == nil {
+		return fmt.Errorf("некорректный SENTRY_DSN")
+	}
+	projectID := strings.Trim(parsed.Path, "/")
+	publicKey := parsed.User.Username()
+	endpoint := fmt.Sprintf("%s://%s/api/%s/store/", parsed.Scheme, parsed.Host, projectID)
+
+	payload := map[string]interface{}{
+		"event_id":  randomID(16),
+		"timestamp": event.Time.Format(time.RFC3339),
+		"level":     "fatal",
+		"platform":  "go",
+		"logger":    "loil-server",
+		"release":   buildVersion,
+		"message":   event.Error,
+		"request": map[string]string{
+			"method": event.Method,
+			"url":    event.Path,
+		},
+		"tags": map[string]string{
+			"request_id": event.RequestID,
+		},
+		"extra": map[string]string{
+			"stack":     event.Stack,
+			"client_ip": event.ClientIP,
+		},
+	}
+
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=loil-server/%s, sentry_key=%s", buildVersion, publicKey)
+	return postJSON(endpoint, payload, map[string]string{"X-Sentry-Auth": auth})
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Списки игроков, которые централизованно ведутся на сервере лаунчера
+// и забираются игровыми серверами
+const (
+	PlayerListBans      = "bans"
+	PlayerListWhitelist = "whitelist"
+)
+
+var playerNamePattern = regexp.MustCompile(`^[0-9A-Za-z_.-]{1,64}$`)
+
+// Запись в списке. Actor — кто внес запись (модератор или имя ключа),
+// ActorKey — ID ключа админского API, через который это сделано.
+type PlayerListEntry struct {
+	Player    string     `json:"player"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Actor     string     `json:"actor"`
+	ActorKey  string     `json:"actor_key"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (e PlayerListEntry) active(now time.Time) bool {
+	return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+}
+
+// Запрос на добавление; срок задается моментом или длительностью ("72h").
+// HWID в бане — забанить и железо аккаунта с именем игрока.
+type PlayerListRequest struct {
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Duration  string     `json:"duration"`
+	Actor     string     `json:"actor"`
+	HWID      bool       `json:"hwid"`
+}
+
+// Ответ для игровых серверов. Revision растет при любом изменении списков;
+// вместе с числом записей (они меняются и по истечении сроков) отдается
+// как ETag, чтобы опрос без изменений стоил 304.
+type PlayerListResponse struct {
+	Revision int64             `json:"revision"`
+	Entries  []PlayerListEntry `json:"entries"`
+}
+
+// Событие вебхука об изменении списка
+type PlayerListEvent struct {
+	Event    string          `json:"event"`
+	List     string          `json:"list"`
+	Revision int64           `json:"revision"`
+	Entry    PlayerListEntry `json:"entry"`
+	// Кто внес изменение (для удаления — не тот, кто добавлял запись)
+	Actor string    `json:"actor"`
+	Time  time.Time `json:"time"`
+}
+
+type playerListsData struct {
+	Revision  int64             `json:"revision"`
+	Bans      []PlayerListEntry `json:"bans"`
+	Whitelist []PlayerListEntry `json:"whitelist"`
+}
+
+// Списки в DATA_DIR/player_lists.json
+type PlayerListStore struct {
+	mu   sync.Mutex
+	data playerListsData
+}
+
+var playerLists = &PlayerListStore{}
+
+func playerListsFile() string {
+	return filepath.Join(currentConfig().DataDir, "player_lists.json")
+}
+
+func (s *PlayerListStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(playerListsFile(), &s.data)
+}
+
+func (d *playerListsData) list(name string) *[]PlayerListEntry {
+	if name == PlayerListBans {
+		return &d.Bans
+	}
+	return &d.Whitelist
+}
+
+// Записи списка; истекшие отбрасываются, если не просили все
+func (s *PlayerListStore) Entries(list string, includeExpired bool, now time.Time) PlayerListResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := PlayerListResponse{Revision: s.data.Revision, Entries: []PlayerListEntry{}}
+	for _, entry := range *s.data.list(list) {
+		if includeExpired || entry.active(now) {
+			response.Entries = append(response.Entries, entry)
+		}
+	}
+	return response
+}
+
+func (s *PlayerListStore) Get(list, player string) (PlayerListEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := *s.data.list(list)
+	if i := findPlayerEntry(entries, player); i >= 0 {
+		return entries[i], true
+	}
+	return PlayerListEntry{}, false
+}
+
+// Добавление или замена записи; возвращает новую ревизию
+func (s *PlayerListStore) Put(list string, entry PlayerListEntry) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.data
+	entries := slices.Clone(*next.list(list))
+	if i := findPlayerEntry(entries, entry.Player); i >= 0 {
+		entries[i] = entry
+	} else {
+		entries = append(entries, entry)
+	}
+	*next.list(list) = entries
+	next.Revision++
+
+	if err := saveJSONFile(playerListsFile(), next); err != nil {
+		return 0, err
+	}
+	s.data = next
+	return next.Revision, nil
+}
+
+func (s *PlayerListStore) Delete(list, player string) (PlayerListEntry, int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := s.data
+	entries := *next.list(list)
+	i := findPlayerEntry(entries, player)
+	if i < 0 {
+		return PlayerListEntry{}, 0, false, nil
+	}
+	removed := entries[i]
+	*next.list(list) = slices.Delete(slices.Clone(entries), i, i+1)
+	next.Revision++
+
+	if err := saveJSONFile(playerListsFile(), next); err != nil {
+		return PlayerListEntry{}, 0, true, err
+	}
+	s.data = next
+	return removed, next.Revision, true, nil
+}
+
+// Имена игроков сравниваются без учета регистра
+func findPlayerEntry(entries []PlayerListEntry, player string) int {
+	return slices.IndexFunc(entries, func(e PlayerListEntry) bool { return strings.EqualFold(e.Player, player) })
+}
+
+// Рассылка события по PLAYER_LIST_WEBHOOKS. Тело подписывается
+// HMAC-SHA256 с PLAYER_LIST_WEBHOOK_SECRET в заголовке X-Loil-Signature.
+func (l *Logger) notifyPlayerList(event PlayerListEvent) {
+	cfg := currentConfig()
+	if len(cfg.PlayerListWebhooks) == 0 {
+		return
+	}
+
+	headers := map[string]string{"X-Loil-Event": event.Event}
+	if cfg.PlayerListWebhookSecret != "" {
+		body, _ := json.Marshal(event)
+		mac := hmac.New(sha256.New, []byte(cfg.PlayerListWebhookSecret))
+		mac.Write(body)
+		headers["X-Loil-Signature"] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	for _, target := range cfg.PlayerListWebhooks {
+		go func(target string) {
+			var err error
+			for attempt := 0; attempt < 3; attempt++ {
+				if attempt > 0 {
+					time.Sleep(time.Duration(attempt) * 5 * time.Second)
+				}
+				if err = postJSON(target, event, headers); err == nil {
+					return
+				}
+			}
+			l.logError("Не удалось доставить событие %s в вебхук %s: %v", event.Event, target, err)
+		}(target)
+	}
+}
+
+// Запись из запроса; false — срок задан неверно или дважды
+func (req PlayerListRequest) entry(r *http.Request, player string, now time.Time) (PlayerListEntry, bool) {
+	expiresAt := req.ExpiresAt
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 || expiresAt != nil {
+			return PlayerListEntry{}, false
+		}
+		expires := now.Add(duration)
+		expiresAt = &expires
+	}
+	actor, actorKey := adminActor(r, req.Actor)
+	return PlayerListEntry{
+		Player:    player,
+		Reason:    req.Reason,
+		ExpiresAt: expiresAt,
+		Actor:     actor,
+		ActorKey:  actorKey,
+		CreatedAt: now,
+	}, true
+}
+
+// Добавление записи с уведомлением игровых серверов и вебхуком о бане;
+// с hwid бан распространяется на железо аккаунта игрока
+func (l *Logger) putPlayerListEntry(list string, entry PlayerListEntry, hwid bool) error {
+	revision, err := playerLists.Put(list, entry)
+	if err != nil {
+		return err
+	}
+	event := PlayerListEvent{Event: list + ".added", List: list, Revision: revision, Entry: entry, Actor: entry.Actor, Time: entry.CreatedAt}
+	l.notifyPlayerList(event)
+	if list != PlayerListBans {
+		return nil
+	}
+	l.dispatchWebhook(WebhookPlayerBanned, event)
+
+	if account, ok := accounts.ByUsername(entry.Player); ok && hwid {
+		count, err := l.banAccountHWIDs(account, entry)
+		if err != nil {
+			return err
+		}
+		l.logSuccess("Забанено железо аккаунта %s: отпечатков %d", account.Username, count)
+	}
+	return nil
+}
+
+// Кто выполняет действие: ключ админского API и, если передан, модератор
+func adminActor(r *http.Request, actor string) (string, string) {
+	key, _ := authenticateAdmin(r)
+	if actor == "" {
+		actor = key.Name
+	}
+	return actor, key.ID
+}
+
+// Список для игровых серверов: GET /admin/api/bans, /admin/api/whitelist.
+// С If-None-Match по ревизии отвечает 304, если ничего не менялось.
+func (l *Logger) adminListPlayersHandler(list string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopePlayersRead, "🚫", "/admin/api/"+list, func() {
+			includeExpired := r.URL.Query().Get("include_expired") == "true"
+			response := playerLists.Entries(list, includeExpired, time.Now())
+
+			etag := `"` + strconv.FormatInt(response.Revision, 10) + "-" + strconv.Itoa(len(response.Entries)) + `"`
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			json.NewEncoder(w).Encode(response)
+		})
+	}
+}
+
+// Проверка одного игрока при входе на игровой сервер
+func (l *Logger) adminGetPlayerHandler(list string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopePlayersRead, "🚫", "/admin/api/"+list+"/{player}", func() {
+			player := r.PathValue("player")
+			entry, ok := playerLists.Get(list, player)
+			if !ok || !entry.active(time.Now()) {
+				writeError(w, r, http.StatusNotFound, ErrCodePlayerNotListed, player)
+				return
+			}
+			json.NewEncoder(w).Encode(entry)
+		})
+	}
+}
+
+// Бан или добавление в белый список
+func (l *Logger) adminPutPlayerHandler(list string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopePlayersWrite, "🚫", "/admin/api/"+list+"/{player}", func() {
+			player := r.PathValue("player")
+			var req PlayerListRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !playerNamePattern.MatchString(player) {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+
+			entry, ok := req.entry(r, player, time.Now().UTC())
+			if !ok {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			if err := l.putPlayerListEntry(list, entry, req.HWID); err != nil {
+				l.logError("Ошибка сохранения списков игроков: %v", err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+				return
+			}
+
+			json.NewEncoder(w).Encode(entry)
+			l.logSuccess("Игрок %s добавлен в список %s (%s): %s", player, list, entry.Actor, req.Reason)
+		})
+	}
+}
+
+// Разбан или удаление из белого списка
+func (l *Logger) adminDeletePlayerHandler(list string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopePlayersWrite, "🚫", "/admin/api/"+list+"/{player}", func() {
+			player := r.PathValue("player")
+			entry, revision, found, err := playerLists.Delete(list, player)
+			if !found {
+				writeError(w, r, http.StatusNotFound, ErrCodePlayerNotListed, player)
+				return
+			}
+			if err != nil {
+				l.logError("Ошибка сохранения списков игроков: %v", err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+				return
+			}
+
+			actor, _ := adminActor(r, r.URL.Query().Get("actor"))
+			l.notifyPlayerList(PlayerListEvent{Event: list + ".removed", List: list, Revision: revision, Entry: entry, Actor: actor, Time: time.Now().UTC()})
+
+			w.WriteHeader(http.StatusNoContent)
+			l.logSuccess("Игрок %s удален из списка %s (%s)", player, list, actor)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Состояния присутствия игрока для друзей
+const (
+	PresenceOffline  = "offline"
+	PresenceLauncher = "launcher"
+	PresenceInGame   = "in_game"
+)
+
+type Presence struct {
+	Status string `json:"status"`
+	// Сборка, модпак и сервер текущей игры
+	GameVersion string `json:"game_version,omitempty"`
+	Modpack     string `json:"modpack,omitempty"`
+	Server      string `json:"server,omitempty"`
+	// Начало игры
+	Since *time.Time `json:"since,omitempty"`
+	// Когда игрок был в сети последний раз, для offline
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// Событие presence: друг сменил состояние
+type PresenceEvent struct {
+	Username string `json:"username"`
+	Presence
+}
+
+// Открытые лаунчеры: аккаунты с подключенным потоком /api/events. Живут
+// в памяти, после перезапуска лаунчеры переподключаются сами.
+type LauncherPresence struct {
+	mu          sync.Mutex
+	connections map[string]int
+	lastSeen    map[string]time.Time
+	// Последнее разосланное друзьям состояние
+	announced map[string]string
+}
+
+var launcherPresence = &LauncherPresence{
+	connections: make(map[string]int),
+	lastSeen:    make(map[string]time.Time),
+	announced:   make(map[string]string),
+}
+
+// true — первое подключение аккаунта, игрок появился в сети
+func (p *LauncherPresence) connect(accountID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.connections[accountID]++
+	return p.connections[accountID] == 1
+}
+
+// true — закрыт последний лаунчер аккаунта
+func (p *LauncherPresence) disconnect(accountID string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastSeen[accountID] = now
+	if p.connections[accountID]--; p.connections[accountID] > 0 {
+		return false
+	}
+	delete(p.connections, accountID)
+	return true
+}
+
+func (p *LauncherPresence) state(accountID string) (bool, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connections[accountID] > 0, p.lastSeen[accountID]
+}
+
+// Запоминает разосланное состояние; true — игрок только что появился в сети
+func (p *LauncherPresence) announce(accountID, status string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous := p.announced[accountID]
+	if status == PresenceOffline {
+		delete(p.announced, accountID)
+	} else {
+		p.announced[accountID] = status
+	}
+	return previous == "" && status != PresenceOffline
+}
+
+// Присутствие по игровой сессии и открытому лаунчеру
+func accountPresence(a Account) Presence {
+	player := playerSubject(a.Username, "")
+	if session, ok := sessions.Current(player); ok {
+		return Presence{
+			Status:      PresenceInGame,
+			GameVersion: session.GameVersion,
+			Modpack:     session.Modpack,
+			Server:      session.Server,
+			Since:       &session.StartedAt,
+		}
+	}
+	online, lastSeen := launcherPresence.state(a.ID)
+	if online {
+		return Presence{Status: PresenceLauncher}
+	}
+	presence := Presence{Status: PresenceOffline}
+	if stats, ok := sessions.Playtime(player); ok && stats.LastSeen.After(lastSeen) {
+		lastSeen = stats.LastSeen
+	}
+	if !lastSeen.IsZero() {
+		presence.LastSeen = &lastSeen
+	}
+	return presence
+}
+
+// Рассылка присутствия друзьям игрока по /api/events; о появлении в
+// сети друзья узнают и push-уведомлением
+func (l *Logger) publishAccountPresence(a Account) {
+	presence := accountPresence(a)
+	cameOnline := launcherPresence.announce(a.ID, presence.Status)
+	ids := friends.FriendIDs(a.ID)
+	if len(ids) == 0 {
+		return
+	}
+	eventHub.PublishTo(ids, EventPresence, PresenceEvent{Username: a.Username, Presence: presence})
+	if cameOnline {
+		l.pushNotify(ids, PushFriendOnline, "push_friend_online", a.Username)
+	}
+}
+
+// То же по игроку игровой сессии; сессии без аккаунта пропускаются
+func (l *Logger) publishPresence(player string) {
+	username, ok := strings.CutPrefix(player, "account:")
+	if !ok {
+		return
+	}
+	if account, ok := accounts.ByUsername(username); ok {
+		l.publishAccountPresence(account)
+	}
+}
+
+func (l *Logger) launcherConnected(a Account) {
+	if launcherPresence.connect(a.ID) {
+		l.publishAccountPresence(a)
+	}
+}
+
+func (l *Logger) launcherDisconnected(a Account) {
+	if launcherPresence.disconnect(a.ID, time.Now().UTC()) {
+		l.publishAccountPresence(a)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Выгрузка тяжелая, поэтому не чаще трех раз в час на аккаунт
+var accountExports = &attemptLimiter{limit: 3, window: time.Hour, failures: make(map[string]*attemptWindow)}
+
+// Все, что сервер хранит об игроке, кроме файлов: они лежат в архиве
+// рядом (screenshots/, saves/, sync/, telemetry.jsonl). Секреты входа
+// (хэш пароля, ключ 2FA, коды восстановления) не выгружаются.
+type AccountExport struct {
+	ExportedAt   time.Time                      `json:"exported_at"`
+	Account      AccountInfo                    `json:"account"`
+	Sessions     []AccountSessionInfo           `json:"sessions"`
+	Entitlements []EntitlementGrant             `json:"entitlement_grants"`
+	Redemptions  []PromoRedemption              `json:"promo_redemptions"`
+	EULA         map[string]EULAAcceptance      `json:"eula_acceptances"`
+	Playtime     map[string]Playtime            `json:"playtime"`
+	PlayerLists  map[string]PlayerListEntry     `json:"player_lists"`
+	Screenshots  []Screenshot                   `json:"screenshots"`
+	Saves        []Save                         `json:"saves"`
+	Sync         []SyncEntry                    `json:"sync"`
+	Feedback     []Feedback                     `json:"feedback"`
+	NewsActivity []NewsActivity                 `json:"news_activity"`
+	Achievements map[string]AchievementProgress `json:"achievements"`
+	// Таблица → период → лучший результат
+	Leaderboards  map[string]map[string]LeaderboardEntry `json:"leaderboards"`
+	Friends       FriendsResponse                        `json:"friends"`
+	Inbox         []InboxMessage                         `json:"inbox"`
+	Notifications NotificationsResponse                  `json:"notifications"`
+	// Отпечатки железа, с которого входили в аккаунт
+	HWIDs []HWIDLink `json:"hwids"`
+	// Суточный трафик скачиваний с адресами
+	DownloadUsage map[string]DownloadUsage `json:"download_usage"`
+	// Ответ лаунчера, с которого пришел запрос, на опрос о железе
+	HardwareSurvey *HardwareReport `json:"hardware_survey,omitempty"`
+}
+
+// Запрос удаления: имя аккаунта для подтверждения и, как при смене почты,
+// пароль; при включенной 2FA еще и код
+type AccountDeleteRequest struct {
+	Confirm  string `json:"confirm"`
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// Кем игрок мог быть в учете EULA и времени игры: аккаунтом и лаунчером
+func accountPlayerSubjects(account Account, clientID string) []string {
+	subjects := []string{playerSubject(account.Username, "")}
+	if clientID != "" {
+		subjects = append(subjects, playerSubject("", clientID))
+	}
+	return subjects
+}
+
+func writeZipJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Копия файла в архив; файлы, удаленные во время выгрузки, пропускаются.
+// Уже сжатые данные (JPEG, архивы сохранений) кладутся без сжатия.
+func writeZipFile(zw *zip.Writer, name, path string, method uint16) error {
+	src, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	return err
+}
+
+// Архив с данными аккаунта. Телеметрия не связана с аккаунтом, поэтому
+// выгружается только для лаунчера, с которого пришел запрос.
+func writeAccountExport(cfg *Config, zw *zip.Writer, account Account, sessionID, clientID string, now time.Time) error {
+	export := AccountExport{
+		ExportedAt:    now,
+		Account:       account.info(),
+		Sessions:      []AccountSessionInfo{},
+		Entitlements:  entitlementGrants.Grants(account.ID),
+		Redemptions:   promoCodes.Redemptions(account.ID),
+		EULA:          make(map[string]EULAAcceptance),
+		Playtime:      make(map[string]Playtime),
+		PlayerLists:   make(map[string]PlayerListEntry),
+		Screenshots:   screenshots.List("", account.ID),
+		Saves:         []Save{},
+		Feedback:      feedback.List("", "", "", account.ID),
+		NewsActivity:  newsEngagement.Activity(newsViewerSubjects(account, clientID)...),
+		Achievements:  achievements.Progress(account.Username),
+		Leaderboards:  leaderboards.PlayerEntries(account.Username),
+		Friends:       friendsResponse(currentConfig(), account.ID),
+		Inbox:         inbox.List(account.ID),
+		Notifications: notificationsResponse(currentConfig(), account.ID),
+		HWIDs:         hwids.Links(account.ID),
+		DownloadUsage: downloadUsage.Account(account.ID),
+		Sync:          []SyncEntry{},
+	}
+	for _, session := range accountSessions.List(account.ID, now) {
+		export.Sessions = append(export.Sessions, AccountSessionInfo{
+			ID:        session.ID,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+			CreatedAt: session.CreatedAt,
+			LastSeen:  session.LastSeen,
+			ExpiresAt: session.ExpiresAt,
+			Current:   session.ID == sessionID,
+		})
+	}
+	for _, subject := range accountPlayerSubjects(account, clientID) {
+		if acceptance, ok := eulaAcceptances.Get(subject); ok {
+			export.EULA[subject] = acceptance
+		}
+		if stats, ok := sessions.Playtime(subject); ok {
+			export.Playtime[subject] = stats
+		}
+	}
+	if report, ok := hardwareSurvey.Get(clientID); ok && clientID != "" {
+		export.HardwareSurvey = &report
+	}
+	for _, list := range []string{PlayerListBans, PlayerListWhitelist} {
+		if entry, ok := playerLists.Get(list, account.Username); ok {
+			entry.ActorKey = ""
+			export.PlayerLists[list] = entry
+		}
+	}
+
+	unlock := lockSaves(account.ID)
+	idx, err := loadSaveIndex(account.ID)
+	unlock()
+	if err != nil {
+		return err
+	}
+	export.Saves = idx.Saves
+
+	entries, _, err := listSyncEntries(account.ID)
+	if err != nil {
+		return err
+	}
+	if entries != nil {
+		export.Sync = entries
+	}
+
+	if err := writeZipJSON(zw, "account.json", export); err != nil {
+		return err
+	}
+	for _, shot := range export.Screenshots {
+		if err := writeZipFile(zw, "screenshots/"+shot.ID+".jpg", screenshotPath(shot.ID, "image"), zip.Store); err != nil {
+			return err
+		}
+	}
+	if account.AvatarUpdatedAt != nil {
+		if err := writeZipFile(zw, "avatar.jpg", avatarPath(account.ID), zip.Store); err != nil {
+			return err
+		}
+	}
+	for _, save := range export.Saves {
+		if err := writeZipFile(zw, "saves/"+save.ID+".bin", saveArchivePath(account.ID, save.ID), zip.Store); err != nil {
+			return err
+		}
+	}
+	for _, entry := range export.Sync {
+		if err := writeZipFile(zw, "sync/"+entry.Key, filepath.Join(syncDir(account.ID), entry.Key), zip.Deflate); err != nil {
+			return err
+		}
+	}
+	if clientID == "" {
+		return nil
+	}
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "telemetry.jsonl", Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	return exportTelemetry(cfg, clientID, f)
+}
+
+// Удаление всех данных аккаунта. Сам аккаунт удаляется последним: после
+// сбоя следующий проход задачи удаления начнет заново.
+func purgeAccount(cfg *Config, account Account) error {
+	if _, err := accountSessions.Revoke(account.ID, func(AccountSession) bool { return true }, time.Now()); err != nil {
+		return err
+	}
+	for _, shot := range screenshots.List("", account.ID) {
+		if _, _, err := screenshots.Delete(shot.ID); err != nil {
+			return err
+		}
+	}
+
+	unlock := lockSaves(account.ID)
+	err := os.RemoveAll(savesDir(account.ID))
+	unlock()
+	if err != nil {
+		return err
+	}
+	syncMu.Lock()
+	err = os.RemoveAll(syncDir(account.ID))
+	syncMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(avatarPath(account.ID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := achievements.Revoke(account.Username, ""); err != nil {
+		return err
+	}
+	if err := leaderboards.Forget(account.Username); err != nil {
+		return err
+	}
+	if err := friends.Forget(account.ID); err != nil {
+		return err
+	}
+	if err := inbox.Forget(account.ID); err != nil {
+		return err
+	}
+	if err := pushDevices.Forget(account.ID); err != nil {
+		return err
+	}
+	if err := hwids.Forget(account.ID); err != nil {
+		return err
+	}
+	if err := downloadUsage.Forget(account.ID); err != nil {
+		return err
+	}
+
+	if err := entitlementGrants.Forget(account.ID); err != nil {
+		return err
+	}
+	if err := feedback.Forget(account.ID); err != nil {
+		return err
+	}
+	if err := promoCodes.Forget(account.ID); err != nil {
+		return err
+	}
+	subjects := accountPlayerSubjects(account, account.DeletionClientID)
+	if err := eulaAcceptances.Delete(subjects...); err != nil {
+		return err
+	}
+	if err := sessions.Forget(subjects...); err != nil {
+		return err
+	}
+	if err := newsEngagement.Forget(newsViewerSubjects(account, account.DeletionClientID)...); err != nil {
+		return err
+	}
+	if account.DeletionClientID != "" {
+		if _, err := purgeTelemetry(cfg, account.DeletionClientID); err != nil {
+			return err
+		}
+		if err := hardwareSurvey.Forget(account.DeletionClientID); err != nil {
+			return err
+		}
+	}
+
+	_, err = accounts.Delete(account.ID)
+	return err
+}
+
+// Фоновое удаление аккаунтов, срок удаления которых наступил. Реплика
+// аккаунтов не меняет: их удаляет основной сервер.
+func (l *Logger) runAccountPurge() {
+	for {
+		for _, account := range accounts.DueForDeletion(time.Now()) {
+			if currentConfig().ReplicaOf != "" {
+				break
+			}
+			if err := purgeAccount(currentConfig(), account); err != nil {
+				l.logError("Ошибка удаления аккаунта %s: %v", account.Username, err)
+				continue
+			}
+			l.logSuccess("Аккаунт %s и его данные удалены по запросу игрока", account.Username)
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// Выгрузка данных аккаунта одним ZIP-архивом
+func (l *Logger) accountExportHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📦", "/api/account/export", func() {
+		account, claims, ok := requireAccountClaims(w, r)
+		if !ok {
+			return
+		}
+		subject := "account:" + account.ID
+		if accountExports.Reject(w, r, subject) {
+			return
+		}
+		now := time.Now().UTC()
+		accountExports.Fail(subject, now)
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", contentDisposition(DispositionAttachment, fmt.Sprintf("loil-%s-%s.zip", account.Username, now.Format("2006-01-02"))))
+		zw := zip.NewWriter(w)
+		if err := writeAccountExport(currentConfig(), zw, account, claims.SessionID, launcherClientID(r), now); err != nil {
+			// Ответ уже начат: оборванный архив клиент не сможет открыть
+			l.logError("Ошибка выгрузки данных аккаунта %s: %v", account.Username, err)
+			return
+		}
+		if err := zw.Close(); err != nil {
+			l.logError("Ошибка выгрузки данных аккаунта %s: %v", account.Username, err)
+			return
+		}
+		l.logSuccess("Аккаунт %s выгрузил свои данные", account.Username)
+	})
+}
+
+// Запрос удаления аккаунта. Аккаунт удаляется через ACCOUNT_DELETION_GRACE;
+// до этого игрок может войти и отменить удаление.
+func (l *Logger) accountDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🗑️", "/api/account/delete", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req AccountDeleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Password) > maxPasswordLength {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if !strings.EqualFold(req.Confirm, account.Username) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeAccountDeletionConfirm)
+			return
+		}
+		if account.DeletionScheduledAt != nil {
+			writeError(w, r, http.StatusConflict, ErrCodeAccountDeletionScheduled)
+			return
+		}
+		if account.PasswordHash != "" && !checkPassword(account.PasswordHash, req.Password) {
+			l.logError("Неверный пароль при удалении аккаунта %s с %s", account.Username, getClientIP(r))
+			writeError(w, r, http.StatusUnauthorized, ErrCodeInvalidCredentials)
+			return
+		}
+		if account.TOTPEnabled {
+			if req.TOTPCode == "" {
+				writeError(w, r, http.StatusUnauthorized, ErrCodeTOTPRequired)
+				return
+			}
+			if _, ok := l.verifyAccountCode(w, r, account, req.TOTPCode); !ok {
+				return
+			}
+		}
+
+		clientID := launcherClientID(r)
+		if len(clientID) > maxTelemetryField {
+			clientID = ""
+		}
+		scheduledAt := time.Now().UTC().Add(currentConfig().AccountDeletionGrace)
+		updated, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			if a.DeletionScheduledAt != nil {
+				return &modError{http.StatusConflict, ErrCodeAccountDeletionScheduled, nil}
+			}
+			a.DeletionScheduledAt = &scheduledAt
+			a.DeletionClientID = clientID
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(updated.info())
+		l.logSuccess("Аккаунт %s будет удален %s", account.Username, scheduledAt.Format(time.RFC3339))
+	})
+}
+
+// Отмена запрошенного удаления
+func (l *Logger) accountDeleteCancelHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🗑️", "/api/account/delete/cancel", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		updated, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			if a.DeletionScheduledAt == nil {
+				return &modError{http.StatusConflict, ErrCodeAccountDeletionNotScheduled, nil}
+			}
+			a.DeletionScheduledAt, a.DeletionClientID = nil, ""
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		json.NewEncoder(w).Encode(updated.info())
+		l.logSuccess("Аккаунт %s отменил удаление", account.Username)
+	})
+}
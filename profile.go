@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxDisplayNameLength = 32
+
+// Публичный профиль игрока для экрана профиля в лаунчере. Почта, входы
+// и прочие данные аккаунта сюда не попадают.
+type PlayerProfile struct {
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	JoinedAt    time.Time `json:"joined_at"`
+	// Время игры по сессиям с аккаунтом; нет — игрок еще не играл
+	Playtime    *Playtime `json:"playtime,omitempty"`
+	Screenshots int       `json:"screenshots"`
+	// Открытые достижения
+	Achievements []AchievementInfo `json:"achievements"`
+}
+
+type AccountProfileRequest struct {
+	// Пустая строка — показывать имя аккаунта
+	DisplayName string `json:"display_name"`
+}
+
+// Аватары в DATA_DIR/avatars/<id аккаунта>.jpg
+func avatarPath(accountID string) string {
+	return filepath.Join(currentConfig().DataDir, "avatars", accountID+".jpg")
+}
+
+func (a Account) profile(cfg *Config) PlayerProfile {
+	profile := PlayerProfile{
+		Username:    a.Username,
+		DisplayName: a.DisplayName,
+		AvatarURL:   a.avatarURL(cfg),
+		JoinedAt:    a.CreatedAt,
+		Screenshots: len(screenshots.List(ScreenshotApproved, a.ID)),
+
+		Achievements: []AchievementInfo{},
+	}
+	for _, achievement := range achievements.Infos(cfg, a.Username) {
+		if achievement.UnlockedAt != nil {
+			profile.Achievements = append(profile.Achievements, achievement)
+		}
+	}
+	if stats, ok := sessions.Playtime(playerSubject(a.Username, "")); ok {
+		profile.Playtime = &stats
+	}
+	return profile
+}
+
+// Адрес аватара с версией, чтобы новый аватар не застревал в кэшах;
+// пусто, если аватар не загружен
+func (a Account) avatarURL(cfg *Config) string {
+	if a.AvatarUpdatedAt == nil {
+		return ""
+	}
+	return cfg.PublicURL + "/api/profile/" + url.PathEscape(a.Username) + "/avatar?v=" + strconv.FormatInt(a.AvatarUpdatedAt.Unix(), 10)
+}
+
+// Отображаемое имя без пробелов по краям; пустое допустимо
+func normalizeDisplayName(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) > maxDisplayNameLength {
+		return "", false
+	}
+	for _, r := range name {
+		if !unicode.IsGraphic(r) {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// Квадрат из середины картинки, уменьшенный до size
+func avatarImage(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := range side {
+		for x := range side {
+			square.Set(x, y, src.At(x0+x, y0+y))
+		}
+	}
+	return resizeImage(square, size)
+}
+
+// Аккаунт игрока по имени; аккаунты, ожидающие удаления, не показываются
+func profileAccount(w http.ResponseWriter, r *http.Request) (Account, bool) {
+	username := r.PathValue("username")
+	account, ok := accounts.ByUsername(username)
+	if !ok || account.DeletionScheduledAt != nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, username)
+		return Account{}, false
+	}
+	return account, true
+}
+
+// Изменение профиля своего аккаунта с ответом об ошибке клиенту
+func (l *Logger) updateProfile(w http.ResponseWriter, r *http.Request, account Account, fn func(a *Account)) (Account, bool) {
+	updated, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+		fn(a)
+		return nil
+	})
+	if err != nil {
+		l.logError("Ошибка сохранения аккаунтов: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return Account{}, false
+	}
+	if apiErr != nil {
+		writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+		return Account{}, false
+	}
+	return updated, true
+}
+
+// Профиль игрока: /api/profile/{username}
+func (l *Logger) profileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪪", "/api/profile/{username}", func() {
+		account, ok := profileAccount(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(account.profile(currentConfig()))
+	})
+}
+
+func (l *Logger) profileAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪪", "/api/profile/{username}/avatar", func() {
+		account, ok := profileAccount(w, r)
+		if !ok {
+			return
+		}
+		if account.AvatarUpdatedAt == nil {
+			writeError(w, r, http.StatusNotFound, ErrCodeProfileNotFound, account.Username)
+			return
+		}
+		file, err := os.Open(avatarPath(account.ID))
+		if err != nil {
+			l.logError("Ошибка открытия аватара %s: %v", account.Username, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		http.ServeContent(w, r, "", *account.AvatarUpdatedAt, file)
+	})
+}
+
+// Смена отображаемого имени
+func (l *Logger) accountProfileHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪪", "/api/account/profile", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req AccountProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		name, valid := normalizeDisplayName(req.DisplayName)
+		if !valid {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidDisplayName, maxDisplayNameLength)
+			return
+		}
+
+		updated, ok := l.updateProfile(w, r, account, func(a *Account) { a.DisplayName = name })
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(updated.profile(currentConfig()))
+		l.logSuccess("Аккаунт %s сменил отображаемое имя на %q", account.Username, name)
+	})
+}
+
+// Загрузка аватара: тело — PNG или JPEG. Картинка обрезается до квадрата,
+// уменьшается до AVATAR_SIZE и перекодируется в JPEG без метаданных.
+func (l *Logger) accountAvatarUploadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪪", "/api/account/avatar", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		cfg := currentConfig()
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(cfg.AvatarMaxBytes)))
+		if err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		img, err := decodeScreenshot(data)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidImage)
+			return
+		}
+
+		path := avatarPath(account.ID)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			l.logError("Ошибка создания каталога аватаров: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		if err := writeJPEG(path, avatarImage(img, cfg.AvatarSize), 85); err != nil {
+			l.logError("Ошибка сохранения аватара: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+
+		now := time.Now().UTC()
+		updated, ok := l.updateProfile(w, r, account, func(a *Account) { a.AvatarUpdatedAt = &now })
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(updated.profile(cfg))
+		l.logSuccess("Аккаунт %s обновил аватар", account.Username)
+	})
+}
+
+func (l *Logger) accountAvatarDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🪪", "/api/account/avatar", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		updated, ok := l.updateProfile(w, r, account, func(a *Account) { a.AvatarUpdatedAt = nil })
+		if !ok {
+			return
+		}
+		if err := os.Remove(avatarPath(account.ID)); err != nil && !os.IsNotExist(err) {
+			l.logError("Ошибка удаления аватара %s: %v", account.Username, err)
+		}
+		json.NewEncoder(w).Encode(updated.profile(currentConfig()))
+		l.logSuccess("Аккаунт %s удалил аватар", account.Username)
+	})
+}
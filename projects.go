@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Дополнительная игра на том же сервере: свой каталог клиентов, версии,
+// каналы и новости. Основная игра по-прежнему описывается конфигурацией
+// и отдается по /api/...; проекты — по /api/{project}/... Аккаунты, моды,
+// рантаймы и блокировка версий пока общие и относятся к основной игре.
+type Project struct {
+	Name            string `json:"name"`
+	Title           string `json:"title"`
+	ClientsDir      string `json:"clients_dir"`
+	LauncherClient  string `json:"launcher_client"`
+	GameClient      string `json:"game_client"`
+	LauncherVersion string `json:"launcher_version"`
+	GameVersion     string `json:"game_version"`
+	// Пусто — у проекта нет новостей
+	NewsFile string `json:"news_file,omitempty"`
+	// Право, без которого не скачать игру проекта (пусто — доступна всем)
+	GameEntitlement string `json:"game_entitlement,omitempty"`
+	// Каналы (beta, staging, ...) со своими версиями и каталогом клиентов;
+	// без ?channel= отдается основной канал проекта
+	Channels map[string]ProjectChannel `json:"channels,omitempty"`
+}
+
+type ProjectChannel struct {
+	ClientsDir      string `json:"clients_dir"`
+	LauncherVersion string `json:"launcher_version"`
+	GameVersion     string `json:"game_version"`
+}
+
+type ProjectInfo struct {
+	Name     string   `json:"name"`
+	Title    string   `json:"title"`
+	Channels []string `json:"channels"`
+}
+
+type ProjectsResponse struct {
+	Projects []ProjectInfo `json:"projects"`
+}
+
+// Проекты из DATA_DIR/projects.json; перечитываются при перезагрузке
+// конфигурации. Кэши новостей живут по путям файлов, чтобы переименование
+// проекта не теряло уже прочитанные новости.
+type ProjectStore struct {
+	mu       sync.RWMutex
+	projects []Project
+	news     map[string]*NewsCache
+}
+
+var projects = &ProjectStore{news: make(map[string]*NewsCache)}
+
+func projectsFile() string {
+	return filepath.Join(currentConfig().DataDir, "projects.json")
+}
+
+func (s *ProjectStore) Load() error {
+	var list []Project
+	if err := loadJSONFile(projectsFile(), &list); err != nil {
+		return err
+	}
+	if err := validateProjects(list); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projects = list
+	return nil
+}
+
+func validateProjects(list []Project) error {
+	var problems []string
+	seen := make(map[string]bool)
+	for _, project := range list {
+		switch {
+		case !projectNamePattern.MatchString(project.Name):
+			problems = append(problems, fmt.Sprintf("%q: имя проекта — строчные латинские буквы, цифры и дефис", project.Name))
+			continue
+		case project.Name == "v1" || project.Name == "projects":
+			problems = append(problems, fmt.Sprintf("%q: имя занято путями API", project.Name))
+		case seen[project.Name]:
+			problems = append(problems, fmt.Sprintf("%q: повторяющееся имя", project.Name))
+		}
+		seen[project.Name] = true
+
+		if project.ClientsDir == "" || project.LauncherClient == "" || project.GameClient == "" {
+			problems = append(problems, fmt.Sprintf("%s: нужны clients_dir, launcher_client и game_client", project.Name))
+		}
+		if !isSemver(project.LauncherVersion) || !isSemver(project.GameVersion) {
+			problems = append(problems, fmt.Sprintf("%s: версии должны соответствовать semver", project.Name))
+		}
+		for name, channel := range project.Channels {
+			if !projectNamePattern.MatchString(name) {
+				problems = append(problems, fmt.Sprintf("%s: некорректное имя канала %q", project.Name, name))
+			}
+			if channel.ClientsDir == "" || !isSemver(channel.LauncherVersion) || !isSemver(channel.GameVersion) {
+				problems = append(problems, fmt.Sprintf("%s/%s: нужны clients_dir и версии в semver", project.Name, name))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("ошибки в %s:\n  - %s", projectsFile(), strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func (s *ProjectStore) Get(name string) (Project, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := slices.IndexFunc(s.projects, func(p Project) bool { return p.Name == name })
+	if i < 0 {
+		return Project{}, false
+	}
+	return s.projects[i], true
+}
+
+func (s *ProjectStore) List() []Project {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.projects)
+}
+
+// Новые версии канала проекта после публикации релиза; пустая версия не
+// меняется. Записывается в projects.json, чтобы пережить перезапуск.
+func (s *ProjectStore) SetVersions(name, channelName, launcherVersion, gameVersion string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := slices.Clone(s.projects)
+	i := slices.IndexFunc(list, func(p Project) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("проект %s не найден", name)
+	}
+	project := list[i]
+	if channelName == "" {
+		if launcherVersion != "" {
+			project.LauncherVersion = launcherVersion
+		}
+		if gameVersion != "" {
+			project.GameVersion = gameVersion
+		}
+	} else {
+		channel, ok := project.Channels[channelName]
+		if !ok {
+			return fmt.Errorf("канал %s/%s не найден", name, channelName)
+		}
+		if launcherVersion != "" {
+			channel.LauncherVersion = launcherVersion
+		}
+		if gameVersion != "" {
+			channel.GameVersion = gameVersion
+		}
+		channels := make(map[string]ProjectChannel, len(project.Channels))
+		for key, value := range project.Channels {
+			channels[key] = value
+		}
+		channels[channelName] = channel
+		project.Channels = channels
+	}
+	list[i] = project
+
+	if err := validateProjects(list); err != nil {
+		return err
+	}
+	if err := saveJSONFile(projectsFile(), list); err != nil {
+		return err
+	}
+	s.projects = list
+	return nil
+}
+
+// Кэш новостей проекта
+func (s *ProjectStore) News(project Project) *NewsCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cache, ok := s.news[project.NewsFile]
+	if !ok {
+		cache = &NewsCache{path: project.NewsFile}
+		s.news[project.NewsFile] = cache
+	}
+	return cache
+}
+
+// Версии и каталог клиентов канала; false — у проекта нет такого канала
+func (p Project) channel(name string) (ProjectChannel, bool) {
+	if name == "" {
+		return ProjectChannel{ClientsDir: p.ClientsDir, LauncherVersion: p.LauncherVersion, GameVersion: p.GameVersion}, true
+	}
+	channel, ok := p.Channels[name]
+	return channel, ok
+}
+
+// Короткие пути проектов /api/{project}/... и /api/v1/{project}/...
+// ведут на /api/v1/projects/{project}/... Маршруты с шаблоном
+// /api/{project}/ пересекались бы в ServeMux с /api/chunks/{hash} и
+// подобными, поэтому путь переписывается до маршрутизации, и только
+// для известных проектов и путей, которым не нашелся свой маршрут.
+func rewriteProjectPath(r *http.Request) *http.Request {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/")
+	if !ok {
+		return r
+	}
+	rest = strings.TrimPrefix(rest, "v1/")
+	name, tail, ok := strings.Cut(rest, "/")
+	if !ok || tail == "" {
+		return r
+	}
+	if _, ok := projects.Get(name); !ok {
+		return r
+	}
+
+	rewritten := new(http.Request)
+	*rewritten = *r
+	u := *r.URL
+	u.Path, u.RawPath = "/api/v1/projects/"+name+"/"+tail, ""
+	rewritten.URL = &u
+	return rewritten
+}
+
+// Проект из пути запроса или ошибка 404
+func requestProject(w http.ResponseWriter, r *http.Request) (Project, ProjectChannel, bool) {
+	project, ok := projects.Get(r.PathValue("project"))
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeProjectNotFound, r.PathValue("project"))
+		return Project{}, ProjectChannel{}, false
+	}
+	channelName := r.URL.Query().Get("channel")
+	channel, ok := project.channel(channelName)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeUnknownChannel, channelName)
+		return Project{}, ProjectChannel{}, false
+	}
+	return project, channel, true
+}
+
+// Список проектов для лаунчера, который обслуживает несколько игр
+func (l *Logger) projectsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🗂️", "/api/projects", func() {
+		response := ProjectsResponse{Projects: []ProjectInfo{}}
+		for _, project := range projects.List() {
+			info := ProjectInfo{Name: project.Name, Title: project.Title, Channels: []string{}}
+			for name := range project.Channels {
+				info.Channels = append(info.Channels, name)
+			}
+			slices.Sort(info.Channels)
+			response.Projects = append(response.Projects, info)
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Версии проекта; номера сборок и блокировка версий есть только у
+// основной игры, режим техработ общий
+func (l *Logger) projectVersionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔖", "/api/{project}/version", func() {
+		project, channel, ok := requestProject(w, r)
+		if !ok {
+			return
+		}
+		json.NewEncoder(w).Encode(VersionResponse{
+			LauncherVersion: channel.LauncherVersion,
+			GameVersion:     channel.GameVersion,
+			Maintenance:     maintenanceMode.Load(),
+			BlockedVersions: BlockedVersions{Launcher: []string{}, Game: []string{}},
+		})
+		l.logSuccess("Отправлены версии %s: лаунчер=%s, игра=%s", project.Name, channel.LauncherVersion, channel.GameVersion)
+	})
+}
+
+// Новости проекта на языке клиента
+func (l *Logger) projectNewsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📰", "/api/{project}/news", func() {
+		project, _, ok := requestProject(w, r)
+		if !ok {
+			return
+		}
+		if project.NewsFile == "" {
+			json.NewEncoder(w).Encode(NewsResponse{News: []NewsItem{}})
+			return
+		}
+
+		langs := requestLanguages(r)
+		format := r.URL.Query().Get("format")
+		filter := requestNewsFilter(r)
+		body, count, err := projects.News(project).Response("json|"+format+"|"+strings.Join(langs, ",")+"|"+filter.key(), func(news []NewsItem) ([]byte, int, error) {
+			news = filter.apply(playerNews(news, langs, format == "html"))
+			data, err := json.Marshal(NewsResponse{News: news})
+			return append(data, '\n'), len(news), err
+		})
+		if err != nil {
+			l.logError("Ошибка загрузки новостей %s: %v", project.Name, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+			return
+		}
+
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write(body)
+		l.logSuccess("Отправлено новостей %s: %d", project.Name, count)
+	})
+}
+
+// Скачивание лаунчера или клиента игры проекта; в статистике скачиваний
+// артефакты проекта идут как <проект>/launcher и <проект>/game
+func (l *Logger) projectDownloadHandler(artifact string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleWithCORS(w, r, "⬇️", "/api/{project}/download/"+artifact, func() {
+			project, channel, ok := requestProject(w, r)
+			if !ok {
+				return
+			}
+
+			filename := project.LauncherClient
+			if artifact == "game" {
+				if l.rejectWithoutEntitlement(w, r, project.GameEntitlement, "игры "+project.Name) {
+					return
+				}
+				filename = project.GameClient
+			}
+			l.serveFileDownload(w, r, filepath.Join(channel.ClientsDir, filename), project.Name+"/"+artifact)
+		})
+	}
+}
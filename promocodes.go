@@ -0,0 +1,382 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Право, которое дает код: beta, supporter, cosmetic:cape и т.п.
+var entitlementPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,63}$`)
+
+// Свой код для раздач вроде SUMMER2025
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9-]{4,32}$`)
+
+// Алфавит сгенерированных кодов без похожих символов (0/O, 1/I)
+const promoCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const maxPromoCodesBatch = 1000
+
+// Промокод. MaxUses — сколько аккаунтов могут его активировать,
+// один аккаунт активирует код только один раз.
+type PromoCode struct {
+	Code        string     `json:"code"`
+	Entitlement string     `json:"entitlement"`
+	MaxUses     int        `json:"max_uses"`
+	Uses        int        `json:"uses"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Note        string     `json:"note,omitempty"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Активация кода аккаунтом
+type PromoRedemption struct {
+	Code        string    `json:"code"`
+	AccountID   string    `json:"account_id"`
+	Username    string    `json:"username"`
+	Entitlement string    `json:"entitlement"`
+	RedeemedAt  time.Time `json:"redeemed_at"`
+}
+
+// Выпуск кодов: count случайных или один свой code
+type PromoCodeRequest struct {
+	Code        string     `json:"code"`
+	Count       int        `json:"count"`
+	Entitlement string     `json:"entitlement"`
+	MaxUses     int        `json:"max_uses"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Duration    string     `json:"duration"`
+	Note        string     `json:"note"`
+}
+
+type RedeemRequest struct {
+	Code string `json:"code"`
+}
+
+type AdminPromoCodeResponse struct {
+	PromoCode
+	Redemptions []PromoRedemption `json:"redemptions"`
+}
+
+type promoData struct {
+	Codes       []PromoCode       `json:"codes"`
+	Redemptions []PromoRedemption `json:"redemptions"`
+}
+
+// Коды и активации в DATA_DIR/promocodes.json
+type PromoCodeStore struct {
+	mu   sync.Mutex
+	data promoData
+}
+
+var promoCodes = &PromoCodeStore{}
+
+func promoCodesFile() string {
+	return filepath.Join(currentConfig().DataDir, "promocodes.json")
+}
+
+func (s *PromoCodeStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(promoCodesFile(), &s.data)
+}
+
+// Код без дефисов и пробелов в верхнем регистре: игроки вводят как попало
+func normalizePromoCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func generatePromoCode() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	var b strings.Builder
+	for i, c := range buf {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(promoCodeAlphabet[int(c)%len(promoCodeAlphabet)])
+	}
+	return b.String()
+}
+
+func (s *PromoCodeStore) List() []PromoCode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PromoCode{}, s.data.Codes...)
+}
+
+func (s *PromoCodeStore) Get(code string) (AdminPromoCodeResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code = normalizePromoCode(code)
+	i := slices.IndexFunc(s.data.Codes, func(c PromoCode) bool { return normalizePromoCode(c.Code) == code })
+	if i < 0 {
+		return AdminPromoCodeResponse{}, false
+	}
+	response := AdminPromoCodeResponse{PromoCode: s.data.Codes[i], Redemptions: []PromoRedemption{}}
+	for _, redemption := range s.data.Redemptions {
+		if redemption.Code == response.Code {
+			response.Redemptions = append(response.Redemptions, redemption)
+		}
+	}
+	return response, true
+}
+
+// Добавление кодов; false, если какой-то из них уже существует
+func (s *PromoCodeStore) Add(codes []PromoCode) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		normalized := normalizePromoCode(code.Code)
+		if slices.ContainsFunc(s.data.Codes, func(c PromoCode) bool { return normalizePromoCode(c.Code) == normalized }) {
+			return false, nil
+		}
+	}
+	next := s.data
+	next.Codes = append(slices.Clone(s.data.Codes), codes...)
+	if err := saveJSONFile(promoCodesFile(), next); err != nil {
+		return true, err
+	}
+	s.data = next
+	return true, nil
+}
+
+// Отзыв кода; уже выданные по нему права остаются
+func (s *PromoCodeStore) Delete(code string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code = normalizePromoCode(code)
+	i := slices.IndexFunc(s.data.Codes, func(c PromoCode) bool { return normalizePromoCode(c.Code) == code })
+	if i < 0 {
+		return false, nil
+	}
+	next := s.data
+	next.Codes = slices.Delete(slices.Clone(s.data.Codes), i, i+1)
+	if err := saveJSONFile(promoCodesFile(), next); err != nil {
+		return true, err
+	}
+	s.data = next
+	return true, nil
+}
+
+// Активация кода аккаунтом. Ошибка *modError — ответ для игрока.
+func (s *PromoCodeStore) Redeem(code string, account Account, now time.Time) (PromoRedemption, *modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code = normalizePromoCode(code)
+	i := slices.IndexFunc(s.data.Codes, func(c PromoCode) bool { return normalizePromoCode(c.Code) == code })
+	if i < 0 {
+		return PromoRedemption{}, &modError{http.StatusNotFound, ErrCodePromoCodeInvalid, nil}, nil
+	}
+	promo := s.data.Codes[i]
+	if promo.ExpiresAt != nil && !now.Before(*promo.ExpiresAt) {
+		return PromoRedemption{}, &modError{http.StatusGone, ErrCodePromoCodeExpired, nil}, nil
+	}
+	if slices.ContainsFunc(s.data.Redemptions, func(r PromoRedemption) bool {
+		return r.Code == promo.Code && r.AccountID == account.ID
+	}) {
+		return PromoRedemption{}, &modError{http.StatusConflict, ErrCodePromoCodeRedeemed, nil}, nil
+	}
+	if promo.Uses >= promo.MaxUses {
+		return PromoRedemption{}, &modError{http.StatusGone, ErrCodePromoCodeExhausted, nil}, nil
+	}
+
+	redemption := PromoRedemption{
+		Code:        promo.Code,
+		AccountID:   account.ID,
+		Username:    account.Username,
+		Entitlement: promo.Entitlement,
+		RedeemedAt:  now,
+	}
+	next := promoData{
+		Codes:       slices.Clone(s.data.Codes),
+		Redemptions: append(slices.Clone(s.data.Redemptions), redemption),
+	}
+	next.Codes[i].Uses++
+	if err := saveJSONFile(promoCodesFile(), next); err != nil {
+		return PromoRedemption{}, nil, err
+	}
+	s.data = next
+	return redemption, nil, nil
+}
+
+// Активации аккаунта
+func (s *PromoCodeStore) Redemptions(accountID string) []PromoRedemption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []PromoRedemption{}
+	for _, redemption := range s.data.Redemptions {
+		if redemption.AccountID == accountID {
+			result = append(result, redemption)
+		}
+	}
+	return result
+}
+
+// Удаление активаций аккаунта; счетчики использований кодов остаются
+func (s *PromoCodeStore) Forget(accountID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.data.Redemptions, func(r PromoRedemption) bool { return r.AccountID == accountID }) {
+		return nil
+	}
+	next := promoData{
+		Codes: s.data.Codes,
+		Redemptions: slices.DeleteFunc(slices.Clone(s.data.Redemptions), func(r PromoRedemption) bool {
+			return r.AccountID == accountID
+		}),
+	}
+	if err := saveJSONFile(promoCodesFile(), next); err != nil {
+		return err
+	}
+	s.data = next
+	return nil
+}
+
+// Активация промокода: POST /api/redeem {"code": "ABCD-EFGH-..."}
+func (l *Logger) redeemHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎁", "/api/redeem", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req RedeemRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" || len(req.Code) > 64 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		redemption, apiErr, err := promoCodes.Redeem(req.Code, account, time.Now().UTC())
+		if err != nil {
+			l.logError("Ошибка сохранения промокодов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			l.logError("Отклонена активация кода аккаунтом %s с %s: %s", account.Username, getClientIP(r), apiErr.code)
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+
+		json.NewEncoder(w).Encode(redemption)
+		l.logSuccess("Аккаунт %s активировал код %s: %s", account.Username, redemption.Code, redemption.Entitlement)
+	})
+}
+
+// Список кодов
+func (l *Logger) adminListPromoCodesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🎁", "/admin/api/promocodes", func() {
+		json.NewEncoder(w).Encode(promoCodes.List())
+	})
+}
+
+// Код с активациями
+func (l *Logger) adminGetPromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🎁", "/admin/api/promocodes/{code}", func() {
+		promo, ok := promoCodes.Get(r.PathValue("code"))
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodePromoCodeInvalid)
+			return
+		}
+		json.NewEncoder(w).Encode(promo)
+	})
+}
+
+// Выпуск кодов. Без code генерируется count случайных кодов (по умолчанию
+// один), max_uses по умолчанию 1; срок — моментом или длительностью.
+func (l *Logger) adminCreatePromoCodesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🎁", "/admin/api/promocodes", func() {
+		var req PromoCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !entitlementPattern.MatchString(req.Entitlement) ||
+			req.Count < 0 || req.Count > maxPromoCodesBatch || req.MaxUses < 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if req.Code != "" {
+			req.Code = strings.ToUpper(req.Code)
+			if !promoCodePattern.MatchString(req.Code) || req.Count > 1 {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+		}
+
+		now := time.Now().UTC()
+		expiresAt := req.ExpiresAt
+		if req.Duration != "" {
+			duration, err := time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 || expiresAt != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			expires := now.Add(duration)
+			expiresAt = &expires
+		}
+
+		actor, _ := adminActor(r, "")
+		template := PromoCode{
+			Code:        req.Code,
+			Entitlement: req.Entitlement,
+			MaxUses:     max(req.MaxUses, 1),
+			ExpiresAt:   expiresAt,
+			Note:        req.Note,
+			CreatedBy:   actor,
+			CreatedAt:   now,
+		}
+		codes := []PromoCode{template}
+		if req.Code == "" {
+			codes = make([]PromoCode, max(req.Count, 1))
+			for i := range codes {
+				codes[i] = template
+				codes[i].Code = generatePromoCode()
+			}
+		}
+
+		added, err := promoCodes.Add(codes)
+		if !added {
+			writeError(w, r, http.StatusConflict, ErrCodePromoCodeExists, req.Code)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения промокодов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(codes)
+		l.logSuccess("Выпущено промокодов: %d (%s, %s)", len(codes), req.Entitlement, actor)
+	})
+}
+
+// Отзыв кода
+func (l *Logger) adminDeletePromoCodeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🎁", "/admin/api/promocodes/{code}", func() {
+		code := r.PathValue("code")
+		deleted, err := promoCodes.Delete(code)
+		if !deleted {
+			writeError(w, r, http.StatusNotFound, ErrCodePromoCodeInvalid)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения промокодов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Отозван промокод %s", code)
+	})
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Общее состояние экземпляров за балансировщиком (REDIS_URL): блокировки
+// входа, игровые сессии, счетчики скачиваний и режим техработ. Без
+// REDIS_URL все это живет в памяти экземпляра, как раньше. Остальные
+// данные по-прежнему в DATA_DIR каждого экземпляра.
+var sharedState *RedisClient
+
+// Соединений в пуле; при нехватке открываются лишние и закрываются после
+// запроса
+const redisPoolSize = 16
+
+// Клиент Redis на RESP2: нужны только простые команды и EVAL, ради этого
+// тянуть зависимость незачем
+type RedisClient struct {
+	addr     string
+	tls      bool
+	username string
+	password string
+	db       int
+	prefix   string
+	timeout  time.Duration
+	logger   *Logger
+	pool     chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Ответ Redis с ошибкой (-ERR ...): соединение остается рабочим
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// redis://[user:password@]host:port/db, rediss:// — через TLS
+func newRedisClient(cfg *Config, logger *Logger) (*RedisClient, error) {
+	u, err := url.Parse(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &RedisClient{
+		addr:    u.Host,
+		tls:     u.Scheme == "rediss",
+		prefix:  cfg.RedisPrefix,
+		timeout: cfg.RedisTimeout,
+		logger:  logger,
+		pool:    make(chan *redisConn, redisPoolSize),
+	}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("некорректный номер базы %q", db)
+		}
+	}
+
+	if _, err := c.Do("PING"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *RedisClient) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	var conn net.Conn
+	var err error
+	if c.tls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.addr, &tls.Config{ServerName: strings.Split(c.addr, ":")[0]})
+	} else {
+		conn, err = dialer.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &redisConn{Conn: conn, reader: bufio.NewReader(conn)}
+	var setup [][]string
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []string{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		if _, err := rc.do(c.timeout, args); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s: %v", args[0], err)
+		}
+	}
+	return rc, nil
+}
+
+// Команда Redis. Ответ: string, int64, []any или nil для пустого значения.
+func (c *RedisClient) Do(args ...string) (any, error) {
+	var conn *redisConn
+	select {
+	case conn = <-c.pool:
+	default:
+		var err error
+		if conn, err = c.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := conn.do(c.timeout, args)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// Сетевая ошибка: соединение могло остаться посреди ответа
+		conn.Close()
+		return nil, err
+	}
+	select {
+	case c.pool <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (rc *redisConn) do(timeout time.Duration, args []string) (any, error) {
+	rc.SetDeadline(time.Now().Add(timeout))
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(rc.Conn, cmd.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(rc.reader)
+}
+
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("пустой ответ Redis")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]any, count)
+		var itemErr error
+		for i := range items {
+			items[i], err = readRedisReply(r)
+			// Ошибка внутри массива (например, из EVAL) не портит
+			// соединение: дочитываем массив до конца
+			var replyErr redisError
+			if errors.As(err, &replyErr) {
+				itemErr = err
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return items, itemErr
+	}
+	return nil, fmt.Errorf("неизвестный ответ Redis: %q", line)
+}
+
+// Ключ с префиксом REDIS_PREFIX, чтобы несколько серверов делили один Redis
+func (c *RedisClient) key(parts ...string) string {
+	return c.prefix + strings.Join(parts, ":")
+}
+
+// Ошибки Redis не должны ронять запросы: общее состояние деградирует до
+// «ничего не известно», а ошибка попадает в лог
+func (c *RedisClient) logError(op string, err error) {
+	c.logger.logError("Ошибка Redis (%s): %v", op, err)
+}
+
+func redisInt(reply any) int64 {
+	switch v := reply.(type) {
+	case int64:
+		return v
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
+
+func redisString(reply any) (string, bool) {
+	s, ok := reply.(string)
+	return s, ok
+}
+
+// Ответ HGETALL как map
+func redisHash(reply any) map[string]string {
+	items, _ := reply.([]any)
+	hash := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		field, _ := items[i].(string)
+		value, _ := items[i+1].(string)
+		hash[field] = value
+	}
+	return hash
+}
+
+// Режим техработ из Redis раз в пару секунд: переключение в админке
+// любого экземпляра доходит до лаунчеров, подключенных к остальным
+func (l *Logger) runSharedState() {
+	if sharedState == nil {
+		return
+	}
+	for {
+		time.Sleep(2 * time.Second)
+		enabled, ok := sharedState.Maintenance()
+		if ok && maintenanceMode.Swap(enabled) != enabled {
+			eventHub.Publish(EventMaintenance, MaintenanceStatus{Enabled: enabled})
+			l.logSuccess("Режим техработ изменен на другом экземпляре: %v", enabled)
+		}
+	}
+}
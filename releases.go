@@ -0,0 +1,538 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Статусы релиза
+const (
+	ReleaseDraft     = "draft"
+	ReleasePublished = "published"
+)
+
+// Релиз: сборки лаунчера и игры, которые публикуются вместе. Пока релиз
+// в черновике, сборки лежат скрытыми файлами (.release-<id>-<файл>) в
+// каталоге клиентов цели, поэтому публикация — это переименование в
+// пределах одного каталога и смена версий, без копирования.
+type Release struct {
+	ID string `json:"id"`
+	// Цель: пусто — основная игра, иначе проект и его канал
+	Project string `json:"project,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	// Пустая версия — артефакт в релиз не входит
+	LauncherVersion string `json:"launcher_version,omitempty"`
+	GameVersion     string `json:"game_version,omitempty"`
+	Changelog       string `json:"changelog"`
+	// Создать новость с описанием изменений при публикации
+	Announce bool `json:"announce"`
+	// Публиковать, даже если версия не новее текущей (откат)
+	Force       bool                       `json:"force,omitempty"`
+	Status      string                     `json:"status"`
+	Artifacts   map[string]ReleaseArtifact `json:"artifacts"`
+	CreatedAt   time.Time                  `json:"created_at"`
+	PublishedAt *time.Time                 `json:"published_at,omitempty"`
+}
+
+type ReleaseArtifact struct {
+	Size       int64     `json:"size"`
+	Hash       string    `json:"hash"`
+	SHA256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Версии — semver или patch/minor/major от текущей версии цели
+type ReleaseRequest struct {
+	Project         string `json:"project"`
+	Channel         string `json:"channel"`
+	LauncherVersion string `json:"launcher_version"`
+	GameVersion     string `json:"game_version"`
+	Changelog       string `json:"changelog"`
+	Announce        bool   `json:"announce"`
+	Force           bool   `json:"force"`
+}
+
+type ReleasesResponse struct {
+	Releases []Release `json:"releases"`
+}
+
+// Версии основной игры из последнего опубликованного релиза. Они важнее
+// LAUNCHER_VERSION и GAME_VERSION из конфигурации, иначе после
+// перезапуска вернулись бы прежние версии при новых файлах.
+type PublishedVersions struct {
+	Release         string    `json:"release"`
+	LauncherVersion string    `json:"launcher_version"`
+	GameVersion     string    `json:"game_version"`
+	PublishedAt     time.Time `json:"published_at"`
+}
+
+func publishedVersionsFile(dataDir string) string {
+	return filepath.Join(dataDir, "published_versions.json")
+}
+
+// Подстановка опубликованных версий при загрузке конфигурации
+func applyPublishedVersions(cfg *Config, loader *configLoader) error {
+	var published PublishedVersions
+	if err := loadJSONFile(publishedVersionsFile(cfg.DataDir), &published); err != nil {
+		return fmt.Errorf("ошибка чтения опубликованных версий: %v", err)
+	}
+	if published.LauncherVersion != "" {
+		cfg.LauncherVersion = published.LauncherVersion
+		loader.override("LAUNCHER_VERSION", published.LauncherVersion, sourceRelease)
+	}
+	if published.GameVersion != "" {
+		cfg.GameVersion = published.GameVersion
+		loader.override("GAME_VERSION", published.GameVersion, sourceRelease)
+	}
+	return nil
+}
+
+// Новая версия артефакта основной игры без релиза — после прямой
+// загрузки сборки с ?version=. Вызывается под publishMu.
+func publishArtifactVersion(artifact, version string) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	cfg := currentConfig()
+	var published PublishedVersions
+	if err := loadJSONFile(publishedVersionsFile(cfg.DataDir), &published); err != nil {
+		return err
+	}
+	next := *cfg
+	if artifact == "launcher" {
+		next.LauncherVersion, published.LauncherVersion = version, version
+	} else {
+		next.GameVersion, published.GameVersion = version, version
+	}
+	published.Release, published.PublishedAt = "", time.Now().UTC()
+	if err := saveJSONFile(publishedVersionsFile(cfg.DataDir), published); err != nil {
+		return err
+	}
+	configSnapshot.Store(&next)
+	versionCache.Store(nil)
+	eventHub.PublishVersion()
+	return nil
+}
+
+// Релизы в DATA_DIR/releases.json
+type ReleaseStore struct {
+	*jsonListStore[Release]
+}
+
+var releases = &ReleaseStore{&jsonListStore[Release]{file: releasesFile}}
+
+// Публикации не должны идти одновременно друг с другом и с перезагрузкой
+// конфигурации
+var publishMu sync.Mutex
+
+func releasesFile() string {
+	return filepath.Join(currentConfig().DataDir, "releases.json")
+}
+
+func (s *ReleaseStore) Get(id string) (Release, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := findRelease(s.items, id)
+	if i < 0 {
+		return Release{}, false
+	}
+	return s.items[i], true
+}
+
+func findRelease(list []Release, id string) int {
+	return slices.IndexFunc(list, func(r Release) bool { return r.ID == id })
+}
+
+// Каталог клиентов и имена файлов цели релиза
+func (rel Release) target(cfg *Config) (string, func(artifact string) string, bool) {
+	if rel.Project == "" {
+		return cfg.ClientsDir, func(artifact string) string {
+			filename, _ := artifactFilename(cfg, artifact)
+			return filename
+		}, rel.Channel == ""
+	}
+	project, ok := projects.Get(rel.Project)
+	if !ok {
+		return "", nil, false
+	}
+	channel, ok := project.channel(rel.Channel)
+	return channel.ClientsDir, func(artifact string) string {
+		if artifact == "launcher" {
+			return project.LauncherClient
+		}
+		return project.GameClient
+	}, ok
+}
+
+// Текущая версия артефакта в канале цели
+func (rel Release) currentVersion(cfg *Config, artifact string) string {
+	if rel.Project == "" {
+		return artifactVersion(cfg, artifact)
+	}
+	project, _ := projects.Get(rel.Project)
+	versions, _ := project.channel(rel.Channel)
+	if artifact == "launcher" {
+		return versions.LauncherVersion
+	}
+	return versions.GameVersion
+}
+
+// Подстановка patch/minor/major и проверка, что версии новее текущих
+// в канале цели (если релиз не помечен force)
+func (rel *Release) resolveVersions(cfg *Config) *modError {
+	for _, artifact := range rel.artifacts() {
+		version := &rel.GameVersion
+		if artifact == "launcher" {
+			version = &rel.LauncherVersion
+		}
+		resolved, apiErr := resolveVersion(artifact, *version, rel.currentVersion(cfg, artifact), rel.Force)
+		if apiErr != nil {
+			return apiErr
+		}
+		*version = resolved
+	}
+	return nil
+}
+
+// Ссылка на блоб сборки релиза: в черновике — со скрытого файла, после
+// публикации — из канала цели
+func (rel Release) blobRef(path, artifact string, published bool) BlobRef {
+	ref := BlobRef{Path: path, Channel: "release/" + rel.ID, Artifact: artifact, Version: rel.GameVersion}
+	if artifact == "launcher" {
+		ref.Version = rel.LauncherVersion
+	}
+	if published {
+		ref.Channel = "stable"
+		if rel.Project != "" {
+			ref.Channel = "project/" + rel.Project
+			if rel.Channel != "" {
+				ref.Channel += "/" + rel.Channel
+			}
+		}
+	}
+	return ref
+}
+
+// Артефакты, которые входят в релиз
+func (rel Release) artifacts() []string {
+	var list []string
+	if rel.LauncherVersion != "" {
+		list = append(list, "launcher")
+	}
+	if rel.GameVersion != "" {
+		list = append(list, "game")
+	}
+	return list
+}
+
+func releaseStagingPath(dir, id, filename string) string {
+	return filepath.Join(dir, ".release-"+id+"-"+filename)
+}
+
+func (req ReleaseRequest) valid(cfg *Config) bool {
+	if req.LauncherVersion == "" && req.GameVersion == "" {
+		return false
+	}
+	if (req.LauncherVersion != "" && !isVersionOrBump(req.LauncherVersion)) || (req.GameVersion != "" && !isVersionOrBump(req.GameVersion)) {
+		return false
+	}
+	// Новость создается только в новостях основной игры
+	if req.Announce && req.Project != "" {
+		return false
+	}
+	_, _, ok := Release{Project: req.Project, Channel: req.Channel}.target(cfg)
+	return ok
+}
+
+// Публикация: сборки заранее нарезаются на чанки, затем файлы
+// переименовываются, манифесты и версии подменяются разом, и только
+// после этого лаунчеры узнают о новой версии. Окно, когда версия уже
+// новая, а файл еще старый (или наоборот), сводится к нескольким
+// переименованиям.
+func (l *Logger) publishRelease(id string) (Release, *modError, error) {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	rel, ok := releases.Get(id)
+	if !ok {
+		return Release{}, &modError{http.StatusNotFound, ErrCodeReleaseNotFound, []interface{}{id}}, nil
+	}
+	if rel.Status != ReleaseDraft {
+		return Release{}, &modError{http.StatusConflict, ErrCodeReleaseNotDraft, []interface{}{id}}, nil
+	}
+	cfg := currentConfig()
+	dir, filename, ok := rel.target(cfg)
+	if !ok {
+		return Release{}, &modError{http.StatusNotFound, ErrCodeUnknownChannel, []interface{}{rel.Channel}}, nil
+	}
+	// Пока релиз лежал черновиком, канал мог уйти вперед
+	if apiErr := rel.resolveVersions(cfg); apiErr != nil {
+		return Release{}, apiErr, nil
+	}
+	for _, artifact := range rel.artifacts() {
+		if _, err := os.Stat(releaseStagingPath(dir, id, filename(artifact))); err != nil {
+			return Release{}, &modError{http.StatusConflict, ErrCodeReleaseIncomplete, []interface{}{artifact}}, nil
+		}
+	}
+
+	next := *cfg
+	if rel.LauncherVersion != "" {
+		next.LauncherVersion = rel.LauncherVersion
+	}
+	if rel.GameVersion != "" {
+		next.GameVersion = rel.GameVersion
+	}
+
+	// Чанки основной игры нарезаются до подмены: это долго
+	manifests := make(map[string]ChunkManifest)
+	if rel.Project == "" {
+		for _, artifact := range rel.artifacts() {
+			manifest, _, err := chunkFile(&next, artifact, releaseStagingPath(dir, id, filename(artifact)), rel.Artifacts[artifact].Hash)
+			if err != nil {
+				return Release{}, nil, err
+			}
+			manifest.Filename = filename(artifact)
+			manifests[artifact] = manifest
+		}
+	}
+
+	for _, artifact := range rel.artifacts() {
+		target := filepath.Join(dir, filename(artifact))
+		staged := releaseStagingPath(dir, id, filename(artifact))
+		if err := renameFile(staged, target); err != nil {
+			return Release{}, nil, err
+		}
+		if err := blobs.Move(staged, rel.blobRef(target, artifact, true)); err != nil {
+			l.logError("Ошибка сохранения хранилища блобов: %v", err)
+		}
+		uploaded := rel.Artifacts[artifact]
+		clientIndex.Remember(target, uploadedFile{Size: uploaded.Size, MD5: uploaded.Hash, SHA256: uploaded.SHA256})
+	}
+
+	now := time.Now().UTC()
+	if rel.Project == "" {
+		for artifact, manifest := range manifests {
+			if err := saveJSONFile(chunkManifestPath(artifact), manifest); err != nil {
+				l.logError("Ошибка сохранения манифеста чанков %s: %v", artifact, err)
+			}
+		}
+		published := PublishedVersions{Release: id, LauncherVersion: next.LauncherVersion, GameVersion: next.GameVersion, PublishedAt: now}
+		if err := saveJSONFile(publishedVersionsFile(cfg.DataDir), published); err != nil {
+			return Release{}, nil, err
+		}
+		configSnapshot.Store(&next)
+	} else if err := projects.SetVersions(rel.Project, rel.Channel, rel.LauncherVersion, rel.GameVersion); err != nil {
+		return Release{}, nil, err
+	}
+	versionCache.Store(nil)
+	eventHub.PublishVersion()
+
+	rel.Status, rel.PublishedAt = ReleasePublished, &now
+	apiErr, err := releases.update(func(list []Release) ([]Release, *modError) {
+		if i := findRelease(list, id); i >= 0 {
+			list[i] = rel
+		}
+		return list, nil
+	})
+	if apiErr != nil || err != nil {
+		return Release{}, apiErr, err
+	}
+
+	// Уведомления и то, что можно достроить после подмены
+	for _, artifact := range rel.artifacts() {
+		uploaded := rel.Artifacts[artifact]
+		target := filepath.Join(dir, filename(artifact))
+		l.dispatchWebhook(WebhookBuildPublished, BuildPublishedEvent{Artifact: artifact, Filename: filename(artifact), Size: uploaded.Size, Hash: uploaded.Hash, SHA256: uploaded.SHA256})
+		if rel.Project == "" {
+			go l.syncEncodedVariants(&next, target, ClientFile{Size: uploaded.Size, SHA256: uploaded.SHA256})
+			if artifact == "game" {
+				go l.syncGameTorrent(&next, target, uploaded.Hash)
+			}
+		}
+	}
+	l.recordReleaseChangelog(rel)
+	if rel.GameVersion != "" {
+		l.pushNotify(nil, PushRelease, "push_release", rel.GameVersion)
+	}
+	if rel.Announce {
+		l.announceRelease(rel)
+	}
+	return rel, nil, nil
+}
+
+// Новость о релизе с описанием изменений
+func (l *Logger) announceRelease(rel Release) {
+	title := "Обновление " + rel.GameVersion
+	if rel.GameVersion == "" {
+		title = "Обновление лаунчера " + rel.LauncherVersion
+	}
+	item := NewsItem{Title: title, Content: rel.Changelog, Date: rel.PublishedAt.Format("2006-01-02"), Status: NewsStatusPublished}
+	err := updateNews(func(news []NewsItem) ([]NewsItem, bool, error) {
+		item.ID = 1
+		for _, existing := range news {
+			item.ID = max(item.ID, existing.ID+1)
+		}
+		return append(news, item), true, nil
+	})
+	if err != nil {
+		l.logError("Ошибка создания новости о релизе %s: %v", rel.ID, err)
+		return
+	}
+	l.dispatchWebhook(WebhookNewsCreated, item)
+}
+
+func (l *Logger) adminListReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📦", "/admin/api/releases", func() {
+		json.NewEncoder(w).Encode(ReleasesResponse{Releases: append([]Release{}, releases.List()...)})
+	})
+}
+
+// Черновик релиза
+func (l *Logger) adminCreateReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📦", "/admin/api/releases", func() {
+		var req ReleaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !req.valid(currentConfig()) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		rel := Release{
+			ID:              randomID(8),
+			Project:         req.Project,
+			Channel:         req.Channel,
+			LauncherVersion: req.LauncherVersion,
+			GameVersion:     req.GameVersion,
+			Changelog:       req.Changelog,
+			Announce:        req.Announce,
+			Force:           req.Force,
+			Status:          ReleaseDraft,
+			Artifacts:       make(map[string]ReleaseArtifact),
+			CreatedAt:       time.Now().UTC(),
+		}
+		if apiErr := rel.resolveVersions(currentConfig()); apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+		apiErr, err := releases.update(func(list []Release) ([]Release, *modError) {
+			return append(list, rel), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rel)
+		l.logSuccess("Создан черновик релиза %s: лаунчер=%s, игра=%s", rel.ID, rel.LauncherVersion, rel.GameVersion)
+	})
+}
+
+// Загрузка сборки в черновик; до публикации игроки ее не видят
+func (l *Logger) adminUploadReleaseArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📦", "/admin/api/releases/{id}/artifacts/{artifact}", func() {
+		id, artifact := r.PathValue("id"), r.PathValue("artifact")
+		rel, ok := releases.Get(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeReleaseNotFound, id)
+			return
+		}
+		if rel.Status != ReleaseDraft {
+			writeError(w, r, http.StatusConflict, ErrCodeReleaseNotDraft, id)
+			return
+		}
+		if !slices.Contains(rel.artifacts(), artifact) {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+		dir, filename, ok := rel.target(currentConfig())
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownChannel, rel.Channel)
+			return
+		}
+
+		staged := releaseStagingPath(dir, id, filename(artifact))
+		file, ok := l.receiveUpload(w, r, staged)
+		if !ok {
+			return
+		}
+		l.adoptBlob(rel.blobRef(staged, artifact, false), file)
+		uploaded := ReleaseArtifact{Size: file.Size, Hash: file.MD5, SHA256: file.SHA256, UploadedAt: time.Now().UTC()}
+		apiErr, err := releases.update(func(list []Release) ([]Release, *modError) {
+			i := findRelease(list, id)
+			if i < 0 || list[i].Status != ReleaseDraft {
+				return nil, &modError{http.StatusConflict, ErrCodeReleaseNotDraft, []interface{}{id}}
+			}
+			if list[i].Artifacts == nil {
+				list[i].Artifacts = make(map[string]ReleaseArtifact)
+			}
+			list[i].Artifacts[artifact] = uploaded
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+
+		json.NewEncoder(w).Encode(uploaded)
+		l.logSuccess("В релиз %s загружен %s (%d bytes, хэш: %s)", id, artifact, file.Size, file.MD5)
+	})
+}
+
+// Публикация черновика одним переключением
+func (l *Logger) adminPublishReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📦", "/admin/api/releases/{id}/publish", func() {
+		rel, apiErr, err := l.publishRelease(r.PathValue("id"))
+		if err != nil {
+			l.logError("Ошибка публикации релиза %s: %v", r.PathValue("id"), err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+
+		json.NewEncoder(w).Encode(rel)
+		l.logSuccess("Опубликован релиз %s: лаунчер=%s, игра=%s", rel.ID, rel.LauncherVersion, rel.GameVersion)
+	})
+}
+
+// Удаление черновика вместе с загруженными сборками
+func (l *Logger) adminDeleteReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "📦", "/admin/api/releases/{id}", func() {
+		id := r.PathValue("id")
+		var removed Release
+		apiErr, err := releases.update(func(list []Release) ([]Release, *modError) {
+			i := findRelease(list, id)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeReleaseNotFound, []interface{}{id}}
+			}
+			if list[i].Status != ReleaseDraft {
+				return nil, &modError{http.StatusConflict, ErrCodeReleaseNotDraft, []interface{}{id}}
+			}
+			removed = list[i]
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		if dir, filename, ok := removed.target(currentConfig()); ok {
+			for _, artifact := range removed.artifacts() {
+				staged := releaseStagingPath(dir, id, filename(artifact))
+				os.Remove(staged)
+				if err := blobs.Release(staged); err != nil {
+					l.logError("Ошибка сохранения хранилища блобов: %v", err)
+				}
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Черновик релиза %s удален", id)
+	})
+}
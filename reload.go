@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Результат перезагрузки конфигурации
+type ReloadResponse struct {
+	LauncherVersion string `json:"launcher_version"`
+	GameVersion     string `json:"game_version"`
+	ClientsDir      string `json:"clients_dir"`
+	NewsCount       int    `json:"news_count"`
+}
+
+// Перезагрузки из SIGHUP и админки не должны выполняться одновременно
+var reloadMu sync.Mutex
+
+// Перечитывает конфигурацию с теми же аргументами командной строки.
+// При ошибке продолжает работать прежний снимок.
+func (l *Logger) reload() (ReloadResponse, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	previous := currentConfig()
+	if err := loadConfig(configArgs); err != nil {
+		return ReloadResponse{}, err
+	}
+	// Правка .env не проверяется, как загрузка через админку, поэтому
+	// откат версии хотя бы виден в логе
+	for _, artifact := range []string{"launcher", "game"} {
+		before, after := artifactVersion(previous, artifact), artifactVersion(currentConfig(), artifact)
+		if isSemver(before) && isSemver(after) && compareSemver(after, before) < 0 {
+			l.logWarn("Версия %s после перезагрузки ниже прежней: %s → %s", artifact, before, after)
+		}
+	}
+	setLogLevel(currentConfig().LogLevel)
+	if err := serverCertificate.Load(currentConfig()); err != nil {
+		l.logError("Сертификат TLS после перезагрузки не читается: %v", err)
+	}
+	if err := projects.Load(); err != nil {
+		l.logError("Проекты после перезагрузки не читаются, остаются прежние: %v", err)
+	}
+
+	// Сбрасываем кэш, чтобы правка news.json применилась сразу,
+	// и заодно проверяем, что файл остался корректным
+	newsCache.Invalidate()
+	news, err := newsCache.Get()
+	if err != nil {
+		l.logError("Новости после перезагрузки не читаются: %v", err)
+	} else {
+		eventHub.PublishNews(news)
+	}
+	eventHub.PublishVersion()
+
+	cfg := currentConfig()
+	l.logSuccess("Конфигурация перезагружена: лаунчер=%s, игра=%s", cfg.LauncherVersion, cfg.GameVersion)
+	l.printConfigReport()
+
+	return ReloadResponse{
+		LauncherVersion: cfg.LauncherVersion,
+		GameVersion:     cfg.GameVersion,
+		ClientsDir:      cfg.ClientsDir,
+		NewsCount:       len(news),
+	}, nil
+}
+
+// Перезагрузка по сигналу SIGHUP
+func (l *Logger) watchReloadSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		l.Println("🔄 Получен SIGHUP, перезагружаем конфигурацию...")
+		if _, err := l.reload(); err != nil {
+			l.logError("Ошибка перезагрузки конфигурации: %v", err)
+		}
+	}
+}
+
+// Перезагрузка через админский API
+func (l *Logger) adminReloadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🔄", "/admin/api/reload", func() {
+		response, err := l.reload()
+		if err != nil {
+			l.logError("Ошибка перезагрузки конфигурации: %v", err)
+			writeError(w, r, http.StatusUnprocessableEntity, ErrCodeConfigInvalid, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(response)
+	})
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Ресурспак (текстуры, звуки). Раздается и лаунчеру, и игровым серверам:
+// сервер объявляет клиентам ссылку и SHA-1, а клиент проверяет архив.
+type ResourcePack struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description,omitempty"`
+	Versions    []ResourcePackVersion `json:"versions"`
+}
+
+type ResourcePackVersion struct {
+	Version    string    `json:"version"`
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	Hash       string    `json:"hash"`
+	SHA1       string    `json:"sha1"`
+	SHA256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Сведения о версии ресурспака для лаунчера и игровых серверов.
+// URL указывает на конкретную версию, чтобы хэш всегда совпадал с архивом.
+type ResourcePackInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+	URL         string `json:"url"`
+	Size        int64  `json:"size"`
+	Hash        string `json:"hash"`
+	SHA1        string `json:"sha1"`
+	SHA256      string `json:"sha256"`
+}
+
+type ResourcePacksResponse struct {
+	ResourcePacks []ResourcePackInfo `json:"resource_packs"`
+}
+
+type AdminResourcePacksResponse struct {
+	ResourcePacks []ResourcePack `json:"resource_packs"`
+}
+
+// Список в DATA_DIR/resourcepacks.json, архивы в DATA_DIR/resourcepacks/<name>/<version>/
+type ResourcePackStore struct {
+	*jsonListStore[ResourcePack]
+}
+
+var resourcePacks = &ResourcePackStore{&jsonListStore[ResourcePack]{file: resourcePacksFile}}
+
+func resourcePacksFile() string {
+	return filepath.Join(currentConfig().DataDir, "resourcepacks.json")
+}
+
+func resourcePackVersionDir(name, version string) string {
+	return filepath.Join(currentConfig().DataDir, "resourcepacks", name, version)
+}
+
+func findResourcePack(packs []ResourcePack, name string) int {
+	return slices.IndexFunc(packs, func(p ResourcePack) bool { return p.Name == name })
+}
+
+func findResourcePackVersion(pack ResourcePack, version string) int {
+	return slices.IndexFunc(pack.Versions, func(v ResourcePackVersion) bool { return v.Version == version })
+}
+
+// Текущая версия ресурспака — самая новая из загруженных
+func (p ResourcePack) latest() (ResourcePackVersion, bool) {
+	if len(p.Versions) == 0 {
+		return ResourcePackVersion{}, false
+	}
+	best := p.Versions[0]
+	for _, version := range p.Versions[1:] {
+		if compareVersions(version.Version, best.Version) > 0 {
+			best = version
+		}
+	}
+	return best, true
+}
+
+func resourcePackInfo(cfg *Config, pack ResourcePack, version ResourcePackVersion) ResourcePackInfo {
+	return ResourcePackInfo{
+		Name:        pack.Name,
+		Description: pack.Description,
+		Version:     version.Version,
+		URL:         cfg.PublicURL + "/api/resourcepack/" + pack.Name + "/" + version.Version,
+		Size:        version.Size,
+		Hash:        version.Hash,
+		SHA1:        version.SHA1,
+		SHA256:      version.SHA256,
+	}
+}
+
+// Поиск версии ресурспака; пустая версия — текущая
+func lookupResourcePack(name, version string) (ResourcePack, ResourcePackVersion, bool) {
+	list := resourcePacks.List()
+	i := findResourcePack(list, name)
+	if i < 0 {
+		return ResourcePack{}, ResourcePackVersion{}, false
+	}
+	if version == "" {
+		latest, ok := list[i].latest()
+		return list[i], latest, ok
+	}
+	j := findResourcePackVersion(list[i], version)
+	if j < 0 {
+		return ResourcePack{}, ResourcePackVersion{}, false
+	}
+	return list[i], list[i].Versions[j], true
+}
+
+// Текущие версии всех ресурспаков
+func (l *Logger) resourcePacksHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎨", "/api/resourcepacks", func() {
+		cfg := currentConfig()
+		response := ResourcePacksResponse{ResourcePacks: []ResourcePackInfo{}}
+		for _, pack := range resourcePacks.List() {
+			if version, ok := pack.latest(); ok {
+				response.ResourcePacks = append(response.ResourcePacks, resourcePackInfo(cfg, pack, version))
+			}
+		}
+
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Отправлен список ресурспаков: %d", len(response.ResourcePacks))
+	})
+}
+
+// Хэши текущей версии для игрового сервера: /api/resourcepack/{name}/hash
+func (l *Logger) resourcePackHashHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎨", "/api/resourcepack/{name}/hash", func() {
+		name := r.PathValue("name")
+		pack, version, ok := lookupResourcePack(name, "")
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeResourcePackNotFound, name)
+			return
+		}
+
+		json.NewEncoder(w).Encode(resourcePackInfo(currentConfig(), pack, version))
+		l.logSuccess("Отправлены хэши ресурспака %s %s", name, version.Version)
+	})
+}
+
+// Скачивание ресурспака: текущей версии или конкретной
+func (l *Logger) downloadResourcePackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎨", "/api/resourcepack/{name}", func() {
+		name, requested := r.PathValue("name"), r.PathValue("version")
+		_, version, ok := lookupResourcePack(name, requested)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeResourcePackNotFound, name)
+			return
+		}
+		// По ссылке без версии лаунчер узнает, какую версию получил
+		if requested == "" {
+			w.Header().Set("X-Resource-Pack-Version", version.Version)
+		}
+		if err := artifactDownloads.Touch("resourcepack", name, version.Version); err != nil {
+			l.logError("Ошибка сохранения времени скачивания ресурспака %s %s: %v", name, version.Version, err)
+		}
+		l.serveFileDownload(w, r, filepath.Join(resourcePackVersionDir(name, version.Version), version.Filename), "resourcepack")
+	})
+}
+
+// Все ресурспаки со всеми версиями
+func (l *Logger) adminListResourcePacksHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎨", "/admin/api/resourcepacks", func() {
+		json.NewEncoder(w).Encode(AdminResourcePacksResponse{ResourcePacks: resourcePacks.List()})
+	})
+}
+
+// Загрузка версии: PUT /admin/api/resourcepacks/{name}/versions/{version}?filename=pack.zip&description=...
+func (l *Logger) adminUploadResourcePackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎨", "/admin/api/resourcepacks/{name}/versions/{version}", func() {
+		name, version := r.PathValue("name"), r.PathValue("version")
+		query := r.URL.Query()
+		filename := query.Get("filename")
+		if filename == "" {
+			filename = name + ".zip"
+		}
+		if !modIDPattern.MatchString(name) || !versionLabelPattern.MatchString(version) || !uploadFilenamePattern.MatchString(filename) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		file, ok := l.receiveUpload(w, r, filepath.Join(resourcePackVersionDir(name, version), filename))
+		if !ok {
+			return
+		}
+		l.adoptBlob(BlobRef{Path: filepath.Join(resourcePackVersionDir(name, version), filename), Channel: "resourcepack", Artifact: name, Version: version}, file)
+
+		result := ResourcePackVersion{
+			Version:    version,
+			Filename:   filename,
+			Size:       file.Size,
+			Hash:       file.MD5,
+			SHA1:       file.SHA1,
+			SHA256:     file.SHA256,
+			UploadedAt: time.Now().UTC(),
+		}
+		var previous string
+		apiErr, err := resourcePacks.update(func(list []ResourcePack) ([]ResourcePack, *modError) {
+			i := findResourcePack(list, name)
+			if i < 0 {
+				list = append(list, ResourcePack{Name: name})
+				i = len(list) - 1
+			}
+			if description := query.Get("description"); description != "" {
+				list[i].Description = description
+			}
+			if j := findResourcePackVersion(list[i], version); j >= 0 {
+				previous = list[i].Versions[j].Filename
+				list[i].Versions[j] = result
+			} else {
+				list[i].Versions = append(list[i].Versions, result)
+			}
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		if previous != "" && previous != filename {
+			removeWithEncodings(filepath.Join(resourcePackVersionDir(name, version), previous))
+		}
+		go l.syncEncodedVariants(currentConfig(), filepath.Join(resourcePackVersionDir(name, version), filename), ClientFile{Size: file.Size, SHA256: file.SHA256})
+
+		json.NewEncoder(w).Encode(result)
+		l.logSuccess("Загружен ресурспак %s %s: %s (%d bytes)", name, version, filename, file.Size)
+	})
+}
+
+// Удаление ресурспака целиком
+func (l *Logger) adminDeleteResourcePackHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎨", "/admin/api/resourcepacks/{name}", func() {
+		name := r.PathValue("name")
+		apiErr, err := resourcePacks.update(func(list []ResourcePack) ([]ResourcePack, *modError) {
+			i := findResourcePack(list, name)
+			if i < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeResourcePackNotFound, []interface{}{name}}
+			}
+			return slices.Delete(list, i, i+1), nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		os.RemoveAll(filepath.Join(currentConfig().DataDir, "resourcepacks", name))
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Ресурспак %s удален", name)
+	})
+}
+
+// Удаление версии ресурспака (например, для отката на предыдущую)
+func (l *Logger) adminDeleteResourcePackVersionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeModsWrite, "🎨", "/admin/api/resourcepacks/{name}/versions/{version}", func() {
+		name, version := r.PathValue("name"), r.PathValue("version")
+		apiErr, err := resourcePacks.update(func(list []ResourcePack) ([]ResourcePack, *modError) {
+			i := findResourcePack(list, name)
+			j := -1
+			if i >= 0 {
+				j = findResourcePackVersion(list[i], version)
+			}
+			if j < 0 {
+				return nil, &modError{http.StatusNotFound, ErrCodeResourcePackNotFound, []interface{}{name}}
+			}
+			list[i].Versions = slices.Delete(list[i].Versions, j, j+1)
+			return list, nil
+		})
+		if !l.writeModUpdateResult(w, r, apiErr, err) {
+			return
+		}
+		os.RemoveAll(resourcePackVersionDir(name, version))
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Версия %s ресурспака %s удалена", version, name)
+	})
+}
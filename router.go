@@ -0,0 +1,218 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Маршрутизатор поверх http.ServeMux с группами маршрутов.
+// Версии API оформляются группами: /api/v1/... и т.д. Старые пути без
+// версии остаются алиасами первой версии, чтобы не сломать уже
+// установленные лаунчеры.
+type Router struct {
+	mux    *http.ServeMux
+	logger *Logger
+	// Проверка адресов и автоматическая блокировка (см. ipfilter.go)
+	filterIPs bool
+	// Короткие пути проектов /api/{project}/... (см. projects.go)
+	projectPaths bool
+	// Лимиты тела запроса по шаблонам маршрутов вместо MAX_BODY_BYTES
+	bodyLimits map[string]bodyLimit
+	// Шаблоны маршрутов каждой версии API относительно /api/{version},
+	// по порядку регистрации; из них собирается спецификация OpenAPI
+	routes map[string][]string
+	// На реплике (REPLICA_OF) запросы, меняющие данные, отклоняются,
+	// кроме маршрутов из replicaWritable
+	readOnly        bool
+	replicaWritable map[string]bool
+	// Маршруты, которые отклоняются первыми при перегрузке (см. overload.go)
+	lowPriority map[string]bool
+}
+
+// Группа маршрутов с общим префиксом
+type RouteGroup struct {
+	router    *Router
+	prefix    string
+	aliases   []string
+	version   string
+	bodyLimit bodyLimit
+	// Маршрут работает и на реплике, хотя метод не GET
+	replicaWritable bool
+	lowPriority     bool
+}
+
+func NewRouter(logger *Logger) *Router {
+	return &Router{mux: http.NewServeMux(), logger: logger, bodyLimits: make(map[string]bodyLimit), routes: make(map[string][]string), replicaWritable: make(map[string]bool), lowPriority: make(map[string]bool)}
+}
+
+// Фильтр адресов для публичного API. Админку не фильтруем: через нее
+// снимают блокировки, а слушает она по умолчанию только localhost.
+func (rt *Router) FilterIPs() {
+	rt.filterIPs = true
+}
+
+// Короткие пути проектов для публичного API
+func (rt *Router) ProjectPaths() {
+	rt.projectPaths = true
+}
+
+// Только чтение, пока сервер работает репликой. Проверяется на каждый
+// запрос, поэтому REPLICA_OF применяется и при перезагрузке конфигурации.
+func (rt *Router) ReadOnlyOnReplica() {
+	rt.readOnly = true
+}
+
+// Маршрут, который на реплике работает при любом методе: он ничего не
+// меняет, хотя и принимает POST
+func (rt *Router) AllowOnReplica(pattern string) {
+	rt.replicaWritable[pattern] = true
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = withRequestID(r)
+	w.Header().Set("X-Request-ID", requestID(r))
+	defer rt.logger.recoverPanic(w, r)
+
+	if rt.filterIPs {
+		if rt.logger.rejectByIP(w, r) {
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() { rt.logger.observeIP(r, recorder.status) }()
+		w = recorder
+	}
+	if debugTraced(getClientIP(r)) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		started := time.Now()
+		rt.logger.traceRequest(r)
+		defer func() { rt.logger.traceResponse(r, recorder, time.Since(started)) }()
+		w = recorder
+	}
+
+	_, pattern := rt.mux.Handler(r)
+	if pattern == "" && rt.projectPaths {
+		r = rewriteProjectPath(r)
+		_, pattern = rt.mux.Handler(r)
+	}
+	// Вместо текстового "404 page not found" отдаем ошибку в формате API
+	if pattern == "" {
+		writeError(w, r, http.StatusNotFound, ErrCodeRouteNotFound)
+		return
+	}
+
+	cfg := currentConfig()
+	if rt.readOnly && cfg.ReplicaOf != "" && !rt.replicaWritable[pattern] {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			writeError(w, r, http.StatusForbidden, ErrCodeReplicaReadOnly, cfg.ReplicaOf)
+			return
+		}
+	}
+
+	if rt.lowPriority[pattern] && overloadMonitor.Shedding() {
+		rt.logger.rejectOverloaded(w, r)
+		return
+	}
+
+	limit := int64(cfg.MaxBodyBytes)
+	if routeLimit, ok := rt.bodyLimits[pattern]; ok {
+		limit = routeLimit(cfg)
+	}
+	r, ok := limitRequestBody(w, r, limit)
+	if !ok {
+		return
+	}
+
+	rt.mux.ServeHTTP(w, r)
+}
+
+func (rt *Router) Handle(pattern string, handler http.Handler) {
+	rt.mux.Handle(pattern, handler)
+}
+
+func (rt *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	rt.mux.HandleFunc(pattern, handler)
+}
+
+// Группа маршрутов без версии (например, админский API)
+func (rt *Router) Group(prefix string) *RouteGroup {
+	return &RouteGroup{router: rt, prefix: strings.TrimRight(prefix, "/")}
+}
+
+// Версия публичного API: маршруты доступны по /api/{version}/... и по
+// каждому из алиасов (например, /api/... для v1)
+func (rt *Router) Version(version string, aliases ...string) *RouteGroup {
+	group := &RouteGroup{
+		router:  rt,
+		prefix:  "/api/" + version,
+		version: version,
+	}
+	for _, alias := range aliases {
+		group.aliases = append(group.aliases, strings.TrimRight(alias, "/"))
+	}
+	return group
+}
+
+// Маршруты группы со своим лимитом тела запроса, например для загрузок
+func (g *RouteGroup) WithBodyLimit(limit bodyLimit) *RouteGroup {
+	group := *g
+	group.bodyLimit = limit
+	return &group
+}
+
+// Маршруты группы, которые работают и на реплике (см. AllowOnReplica)
+func (g *RouteGroup) OnReplica() *RouteGroup {
+	group := *g
+	group.replicaWritable = true
+	return &group
+}
+
+// Маршруты группы, без которых можно обойтись под нагрузкой: пока сервер
+// перегружен, они получают 503
+func (g *RouteGroup) LowPriority() *RouteGroup {
+	group := *g
+	group.lowPriority = true
+	return &group
+}
+
+// Регистрация обработчика; pattern в формате ServeMux: "[METHOD ]/path"
+func (g *RouteGroup) HandleFunc(pattern string, handler http.HandlerFunc) {
+	g.Handle(pattern, handler)
+}
+
+func (g *RouteGroup) Handle(pattern string, handler http.Handler) {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	} else {
+		method += " "
+	}
+
+	if g.version != "" {
+		handler = withAPIVersion(g.version, handler)
+		g.router.routes[g.version] = append(g.router.routes[g.version], method+path)
+	}
+
+	for _, prefix := range append([]string{g.prefix}, g.aliases...) {
+		g.router.mux.Handle(method+prefix+path, handler)
+		if g.bodyLimit != nil {
+			g.router.bodyLimits[method+prefix+path] = g.bodyLimit
+		}
+		if g.replicaWritable {
+			g.router.AllowOnReplica(method + prefix + path)
+		}
+		if g.lowPriority {
+			g.router.lowPriority[method+prefix+path] = true
+		}
+	}
+}
+
+// Клиент видит, какой версией API обработан запрос
+func withAPIVersion(version string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-API-Version", version)
+		next.ServeHTTP(w, r)
+	})
+}
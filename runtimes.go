@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Платформы, для которых раздаются рантаймы
+var (
+	knownRuntimeOS   = map[string]bool{"windows": true, "linux": true, "macos": true}
+	knownRuntimeArch = map[string]bool{"amd64": true, "arm64": true, "x86": true}
+)
+
+var (
+	versionLabelPattern   = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.+_-]{0,63}$`)
+	uploadFilenamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}$`)
+)
+
+// Рантайм (например, JRE), который лаунчер ставит рядом с игрой
+type Runtime struct {
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	Hash       string    `json:"hash"`
+	SHA256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Ответ /api/runtime для конкретной платформы
+type RuntimeResponse struct {
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	Size    int64  `json:"size"`
+	Hash    string `json:"hash"`
+	SHA256  string `json:"sha256"`
+}
+
+type RuntimesResponse struct {
+	Runtimes []Runtime `json:"runtimes"`
+}
+
+// Список рантаймов в DATA_DIR/runtimes.json, архивы в DATA_DIR/runtimes/<os>-<arch>/
+type RuntimeStore struct {
+	mu       sync.Mutex
+	runtimes []Runtime
+}
+
+var runtimes = &RuntimeStore{}
+
+func runtimesFile() string {
+	return filepath.Join(currentConfig().DataDir, "runtimes.json")
+}
+
+func runtimeDir(osName, arch string) string {
+	return filepath.Join(currentConfig().DataDir, "runtimes", osName+"-"+arch)
+}
+
+func (s *RuntimeStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(runtimesFile(), &s.runtimes)
+}
+
+func (s *RuntimeStore) Get(osName, arch string) (Runtime, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, runtime := range s.runtimes {
+		if runtime.OS == osName && runtime.Arch == arch {
+			return runtime, true
+		}
+	}
+	return Runtime{}, false
+}
+
+func (s *RuntimeStore) List() []Runtime {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Runtime, len(s.runtimes))
+	copy(list, s.runtimes)
+	return list
+}
+
+// Замена рантайма платформы; возвращает предыдущий, если он был
+func (s *RuntimeStore) Put(runtime Runtime) (Runtime, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := make([]Runtime, 0, len(s.runtimes)+1)
+	var previous Runtime
+	replaced := false
+	for _, existing := range s.runtimes {
+		if existing.OS == runtime.OS && existing.Arch == runtime.Arch {
+			previous, replaced = existing, true
+			continue
+		}
+		next = append(next, existing)
+	}
+	next = append(next, runtime)
+
+	if err := saveJSONFile(runtimesFile(), next); err != nil {
+		return Runtime{}, false, err
+	}
+	s.runtimes = next
+	return previous, replaced, nil
+}
+
+func (s *RuntimeStore) Delete(osName, arch string) (Runtime, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.runtimes {
+		if existing.OS == osName && existing.Arch == arch {
+			next := append(append([]Runtime{}, s.runtimes[:i]...), s.runtimes[i+1:]...)
+			if err := saveJSONFile(runtimesFile(), next); err != nil {
+				return Runtime{}, true, err
+			}
+			s.runtimes = next
+			return existing, true, nil
+		}
+	}
+	return Runtime{}, false, nil
+}
+
+func validRuntimePlatform(osName, arch string) bool {
+	return knownRuntimeOS[osName] && knownRuntimeArch[arch]
+}
+
+// Какой рантайм нужен лаунчеру на этой платформе
+func (l *Logger) runtimeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "☕", "/api/runtime", func() {
+		osName, arch := r.URL.Query().Get("os"), r.URL.Query().Get("arch")
+		if !validRuntimePlatform(osName, arch) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		runtime, ok := runtimes.Get(osName, arch)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeRuntimeNotFound, osName, arch)
+			return
+		}
+
+		json.NewEncoder(w).Encode(RuntimeResponse{
+			OS:      runtime.OS,
+			Arch:    runtime.Arch,
+			Name:    runtime.Name,
+			Version: runtime.Version,
+			URL:     currentConfig().PublicURL + "/api/download/runtime/" + osName + "/" + arch,
+			Size:    runtime.Size,
+			Hash:    runtime.Hash,
+			SHA256:  runtime.SHA256,
+		})
+		l.logSuccess("Отправлены сведения о рантайме %s %s для %s/%s", runtime.Name, runtime.Version, osName, arch)
+	})
+}
+
+// Скачивание архива рантайма
+func (l *Logger) downloadRuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "☕", "/api/download/runtime/{os}/{arch}", func() {
+		osName, arch := r.PathValue("os"), r.PathValue("arch")
+		runtime, ok := runtimes.Get(osName, arch)
+		if !validRuntimePlatform(osName, arch) || !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeRuntimeNotFound, osName, arch)
+			return
+		}
+		l.serveFileDownload(w, r, filepath.Join(runtimeDir(osName, arch), runtime.Filename), "runtime")
+	})
+}
+
+// Список рантаймов для админки
+func (l *Logger) adminListRuntimesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "☕", "/admin/api/runtimes", func() {
+		json.NewEncoder(w).Encode(RuntimesResponse{Runtimes: runtimes.List()})
+	})
+}
+
+// Загрузка архива рантайма: PUT /admin/api/runtimes/{os}/{arch}?name=java&version=17.0.9&filename=jre.zip
+func (l *Logger) adminUploadRuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "☕", "/admin/api/runtimes/{os}/{arch}", func() {
+		osName, arch := r.PathValue("os"), r.PathValue("arch")
+		query := r.URL.Query()
+		name, version, filename := query.Get("name"), query.Get("version"), query.Get("filename")
+		if filename == "" {
+			filename = name + "-" + osName + "-" + arch + ".zip"
+		}
+		if !validRuntimePlatform(osName, arch) || name == "" || !versionLabelPattern.MatchString(version) ||
+			!uploadFilenamePattern.MatchString(name) || !uploadFilenamePattern.MatchString(filename) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		file, ok := l.receiveUpload(w, r, filepath.Join(runtimeDir(osName, arch), filename))
+		if !ok {
+			return
+		}
+
+		runtime := Runtime{
+			OS:         osName,
+			Arch:       arch,
+			Name:       name,
+			Version:    version,
+			Filename:   filename,
+			Size:       file.Size,
+			Hash:       file.MD5,
+			SHA256:     file.SHA256,
+			UploadedAt: time.Now().UTC(),
+		}
+		previous, replaced, err := runtimes.Put(runtime)
+		if err != nil {
+			l.logError("Ошибка сохранения списка рантаймов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		if replaced && previous.Filename != filename {
+			removeWithEncodings(filepath.Join(runtimeDir(osName, arch), previous.Filename))
+		}
+		go l.syncEncodedVariants(currentConfig(), filepath.Join(runtimeDir(osName, arch), filename), ClientFile{Size: file.Size, SHA256: file.SHA256})
+
+		json.NewEncoder(w).Encode(runtime)
+		l.logSuccess("Загружен рантайм %s %s для %s/%s (%d bytes)", name, version, osName, arch, file.Size)
+	})
+}
+
+// Удаление рантайма платформы
+func (l *Logger) adminDeleteRuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "☕", "/admin/api/runtimes/{os}/{arch}", func() {
+		osName, arch := r.PathValue("os"), r.PathValue("arch")
+		runtime, found, err := runtimes.Delete(osName, arch)
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeRuntimeNotFound, osName, arch)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения списка рантаймов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		removeWithEncodings(filepath.Join(runtimeDir(osName, arch), runtime.Filename))
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Рантайм для %s/%s удален", osName, arch)
+	})
+}
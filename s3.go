@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Раздача через S3/MinIO: вместо отдачи байтов сервер отвечает 302 на
+// короткоживущую подписанную ссылку (SigV4), и трафик идет мимо процесса.
+// Объекты лежат в S3_BUCKET по SHA-256 в той же раскладке, что и BLOB_DIR
+// (<S3_PREFIX><2 символа>/<sha256>), так что хранилище заполняется
+// копированием BLOB_DIR (например, mc mirror) и не может отдать не ту
+// версию файла. Пока объекта нет, файл отдается как обычно.
+
+// Сколько помним, что объекта в S3 нет, прежде чем проверить снова
+const s3MissingRecheck = time.Minute
+
+var s3Client = &http.Client{Timeout: 10 * time.Second}
+
+// Проверенные объекты: есть — навсегда (содержимое по хэшу не меняется),
+// нет — до s3MissingRecheck
+var s3Objects = struct {
+	mu      sync.Mutex
+	present map[string]bool
+	missing map[string]time.Time
+}{present: make(map[string]bool), missing: make(map[string]time.Time)}
+
+func s3ObjectKey(cfg *Config, sha string) string {
+	return cfg.S3Prefix + sha[:2] + "/" + sha
+}
+
+// Кодирование по правилам SigV4: все, кроме A-Z a-z 0-9 - _ . ~
+func s3Escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Подписанная ссылка на объект (path-style: <endpoint>/<bucket>/<key>,
+// так работают и AWS, и MinIO). params — дополнительные параметры
+// запроса вроде response-content-disposition.
+func presignS3(cfg *Config, method, key string, params map[string]string, now time.Time) string {
+	endpoint, _ := url.Parse(cfg.S3Endpoint)
+	date := now.UTC().Format("20060102")
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := date + "/" + cfg.S3Region + "/s3/aws4_request"
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    cfg.S3AccessKey + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(cfg.S3PresignTTL.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	for name, value := range params {
+		query[name] = value
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = s3Escape(name, false) + "=" + s3Escape(query[name], false)
+	}
+	canonicalQuery := strings.Join(pairs, "&")
+
+	path := strings.TrimRight(endpoint.Path, "/") + "/" + cfg.S3Bucket + "/" + key
+	canonicalRequest := strings.Join([]string{
+		method,
+		s3Escape(path, true),
+		canonicalQuery,
+		"host:" + endpoint.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+cfg.S3SecretKey), date)
+	signingKey = hmacSHA256(signingKey, cfg.S3Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return endpoint.Scheme + "://" + endpoint.Host + s3Escape(path, true) + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// Есть ли объект в S3; ответ запоминается, чтобы не спрашивать на
+// каждое скачивание
+func (l *Logger) s3HasObject(cfg *Config, sha string) bool {
+	now := time.Now()
+	s3Objects.mu.Lock()
+	present := s3Objects.present[sha]
+	checkedAt, missing := s3Objects.missing[sha]
+	s3Objects.mu.Unlock()
+	if present {
+		return true
+	}
+	if missing && now.Sub(checkedAt) < s3MissingRecheck {
+		return false
+	}
+
+	req, err := http.NewRequest(http.MethodHead, presignS3(cfg, http.MethodHead, s3ObjectKey(cfg, sha), nil, now), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s3Client.Do(req)
+	if err != nil {
+		l.logError("Ошибка проверки объекта %s в S3: %v", sha, err)
+		return false
+	}
+	resp.Body.Close()
+
+	s3Objects.mu.Lock()
+	defer s3Objects.mu.Unlock()
+	if resp.StatusCode == http.StatusOK {
+		s3Objects.present[sha] = true
+		delete(s3Objects.missing, sha)
+		return true
+	}
+	if !missing {
+		l.logWarn("Объекта %s нет в S3 (%s), файл отдается сервером", s3ObjectKey(cfg, sha), resp.Status)
+	}
+	s3Objects.missing[sha] = now
+	return false
+}
+
+// Перенаправление скачивания на подписанную ссылку S3. Заголовки
+// Content-Disposition и Content-Type хранилище подставит из ссылки:
+// объект назван хэшем, а имя файла должно быть настоящим.
+// false — S3 не настроен или объекта там нет, и файл нужно отдать самому.
+func (l *Logger) redirectToS3(w http.ResponseWriter, r *http.Request, file ClientFile, disposition, contentType string) bool {
+	cfg := currentConfig()
+	if cfg.S3Bucket == "" || file.SHA256 == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+	if !l.s3HasObject(cfg, file.SHA256) {
+		return false
+	}
+
+	target := presignS3(cfg, http.MethodGet, s3ObjectKey(cfg, file.SHA256), map[string]string{
+		"response-content-disposition": disposition,
+		"response-content-type":        contentType,
+	}, time.Now())
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+	return true
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Незавершенная загрузка живет сутки, потом удаляется
+const saveUploadTTL = 24 * time.Hour
+
+// Резервная копия сохранения. Name — мир или слот сохранения: копии
+// с одним именем образуют историю, из которой можно восстановиться.
+type Save struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Загрузка по частям: лаунчер шлет части по порядку и после обрыва
+// продолжает с Received
+type SaveUpload struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Received  int64     `json:"received"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SaveUploadRequest struct {
+	Name     string `json:"name"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+type SaveUploadResponse struct {
+	SaveUpload
+	ChunkSize int `json:"chunk_size"`
+}
+
+type SavesResponse struct {
+	Saves     []Save `json:"saves"`
+	UsedBytes int64  `json:"used_bytes"`
+	Quota     int64  `json:"quota"`
+}
+
+// Индекс сохранений аккаунта: DATA_DIR/saves/<id аккаунта>/index.json,
+// архивы рядом как <id>.bin, загрузки в uploads/<id>.part
+type saveIndex struct {
+	Saves   []Save       `json:"saves"`
+	Uploads []SaveUpload `json:"uploads"`
+}
+
+func (idx *saveIndex) usedBytes() int64 {
+	var used int64
+	for _, save := range idx.Saves {
+		used += save.Size
+	}
+	for _, upload := range idx.Uploads {
+		used += upload.Size
+	}
+	return used
+}
+
+// Блокировки по аккаунтам: запись частей одного аккаунта идет по очереди
+var (
+	savesLocksMu sync.Mutex
+	savesLocks   = make(map[string]*sync.Mutex)
+)
+
+func lockSaves(accountID string) func() {
+	savesLocksMu.Lock()
+	lock, ok := savesLocks[accountID]
+	if !ok {
+		lock = &sync.Mutex{}
+		savesLocks[accountID] = lock
+	}
+	savesLocksMu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+func savesDir(accountID string) string {
+	return filepath.Join(currentConfig().DataDir, "saves", accountID)
+}
+
+func saveArchivePath(accountID, saveID string) string {
+	return filepath.Join(savesDir(accountID), saveID+".bin")
+}
+
+func saveUploadPath(accountID, uploadID string) string {
+	return filepath.Join(savesDir(accountID), "uploads", uploadID+".part")
+}
+
+func loadSaveIndex(accountID string) (*saveIndex, error) {
+	idx := &saveIndex{Saves: []Save{}, Uploads: []SaveUpload{}}
+	if err := loadJSONFile(filepath.Join(savesDir(accountID), "index.json"), idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (idx *saveIndex) save(accountID string) error {
+	return saveJSONFile(filepath.Join(savesDir(accountID), "index.json"), idx)
+}
+
+// Политика хранения: не больше keep копий одного сохранения и не старше
+// maxAge, но последняя копия каждого сохранения остается всегда.
+// Возвращает удаленные копии; файлы удаляет вызывающий.
+func (idx *saveIndex) applyRetention(keep int, maxAge time.Duration, now time.Time) []Save {
+	sort.SliceStable(idx.Saves, func(i, j int) bool { return idx.Saves[i].CreatedAt.After(idx.Saves[j].CreatedAt) })
+
+	var kept, removed []Save
+	perName := make(map[string]int)
+	for _, save := range idx.Saves {
+		perName[save.Name]++
+		n := perName[save.Name]
+		expired := maxAge > 0 && now.Sub(save.CreatedAt) > maxAge
+		if n > 1 && ((keep > 0 && n > keep) || expired) {
+			removed = append(removed, save)
+			continue
+		}
+		kept = append(kept, save)
+	}
+	idx.Saves = kept
+	if idx.Saves == nil {
+		idx.Saves = []Save{}
+	}
+	return removed
+}
+
+// Удаление брошенных загрузок; возвращает их для удаления файлов
+func (idx *saveIndex) expireUploads(now time.Time) []SaveUpload {
+	var expired []SaveUpload
+	idx.Uploads = slices.DeleteFunc(idx.Uploads, func(upload SaveUpload) bool {
+		if now.Sub(upload.CreatedAt) > saveUploadTTL {
+			expired = append(expired, upload)
+			return true
+		}
+		return false
+	})
+	return expired
+}
+
+func removeSaveFiles(accountID string, saves []Save, uploads []SaveUpload) {
+	for _, save := range saves {
+		os.Remove(saveArchivePath(accountID, save.ID))
+	}
+	for _, upload := range uploads {
+		os.Remove(saveUploadPath(accountID, upload.ID))
+	}
+}
+
+// Фоновое применение политики хранения ко всем аккаунтам
+func (l *Logger) runSavesRetention() {
+	for {
+		cfg := currentConfig()
+		entries, err := os.ReadDir(filepath.Join(cfg.DataDir, "saves"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			l.logError("Ошибка чтения каталога сохранений: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				l.applySavesRetention(cfg, entry.Name(), time.Now().UTC())
+			}
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+func (l *Logger) applySavesRetention(cfg *Config, accountID string, now time.Time) {
+	unlock := lockSaves(accountID)
+	defer unlock()
+
+	idx, err := loadSaveIndex(accountID)
+	if err != nil {
+		l.logError("Ошибка чтения сохранений %s: %v", accountID, err)
+		return
+	}
+	removed := idx.applyRetention(cfg.SavesKeep, cfg.SavesMaxAge, now)
+	expired := idx.expireUploads(now)
+	if len(removed) == 0 && len(expired) == 0 {
+		return
+	}
+	if err := idx.save(accountID); err != nil {
+		l.logError("Ошибка сохранения индекса сохранений %s: %v", accountID, err)
+		return
+	}
+	removeSaveFiles(accountID, removed, expired)
+	l.logSuccess("Политика хранения сохранений %s: удалено копий %d, загрузок %d", accountID, len(removed), len(expired))
+}
+
+// Обертка обработчиков сохранений: вход в аккаунт и блокировка его индекса
+func (l *Logger) withSaves(w http.ResponseWriter, r *http.Request, fn func(account Account, idx *saveIndex)) {
+	account, ok := requireAccount(w, r)
+	if !ok {
+		return
+	}
+	unlock := lockSaves(account.ID)
+	defer unlock()
+
+	idx, err := loadSaveIndex(account.ID)
+	if err != nil {
+		l.logError("Ошибка чтения сохранений %s: %v", account.Username, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return
+	}
+	fn(account, idx)
+}
+
+func (l *Logger) writeSaveIndex(w http.ResponseWriter, r *http.Request, account Account, idx *saveIndex) bool {
+	if err := idx.save(account.ID); err != nil {
+		l.logError("Ошибка сохранения индекса сохранений %s: %v", account.Username, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+		return false
+	}
+	return true
+}
+
+// Список резервных копий, новые первыми
+func (l *Logger) savesListHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves", func() {
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			sort.SliceStable(idx.Saves, func(i, j int) bool { return idx.Saves[i].CreatedAt.After(idx.Saves[j].CreatedAt) })
+			json.NewEncoder(w).Encode(SavesResponse{
+				Saves:     idx.Saves,
+				UsedBytes: idx.usedBytes(),
+				Quota:     int64(currentConfig().SavesQuotaBytes),
+			})
+		})
+	})
+}
+
+// Начало загрузки: размер и SHA-256 архива известны заранее, место
+// под него резервируется в квоте сразу
+func (l *Logger) saveUploadStartHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves/uploads", func() {
+		var req SaveUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" || len(req.Name) > 128 ||
+			!utf8.ValidString(req.Name) || !uploadFilenamePattern.MatchString(req.Filename) ||
+			req.Size <= 0 || len(req.SHA256) != 64 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if _, err := hex.DecodeString(req.SHA256); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			cfg := currentConfig()
+			removeSaveFiles(account.ID, nil, idx.expireUploads(time.Now()))
+			if idx.usedBytes()+req.Size > int64(cfg.SavesQuotaBytes) {
+				writeError(w, r, http.StatusInsufficientStorage, ErrCodeQuotaExceeded)
+				return
+			}
+
+			upload := SaveUpload{
+				ID:        randomID(12),
+				Name:      req.Name,
+				Filename:  req.Filename,
+				Size:      req.Size,
+				SHA256:    strings.ToLower(req.SHA256),
+				CreatedAt: time.Now().UTC(),
+			}
+			idx.Uploads = append(idx.Uploads, upload)
+			if !l.writeSaveIndex(w, r, account, idx) {
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(SaveUploadResponse{SaveUpload: upload, ChunkSize: cfg.SavesChunkSize})
+			l.logSuccess("Начата загрузка сохранения «%s» аккаунта %s (%d bytes)", req.Name, account.Username, req.Size)
+		})
+	})
+}
+
+// Состояние загрузки: с какого смещения продолжать
+func (l *Logger) saveUploadStatusHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves/uploads/{id}", func() {
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			i := slices.IndexFunc(idx.Uploads, func(u SaveUpload) bool { return u.ID == r.PathValue("id") })
+			if i < 0 {
+				writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(SaveUploadResponse{SaveUpload: idx.Uploads[i], ChunkSize: currentConfig().SavesChunkSize})
+		})
+	})
+}
+
+// Часть архива: PUT /api/saves/uploads/{id}?offset=N. Смещение должно
+// совпадать с уже принятым объемом, повтор последней части безопасен.
+func (l *Logger) saveUploadChunkHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves/uploads/{id}", func() {
+		offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+		if err != nil || offset < 0 {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			i := slices.IndexFunc(idx.Uploads, func(u SaveUpload) bool { return u.ID == r.PathValue("id") })
+			if i < 0 {
+				writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+				return
+			}
+			upload := &idx.Uploads[i]
+			if offset > upload.Received {
+				writeError(w, r, http.StatusConflict, ErrCodeUploadOffsetMismatch, upload.Received)
+				return
+			}
+
+			cfg := currentConfig()
+			limit := min(int64(cfg.SavesChunkSize), upload.Size-offset)
+			path := saveUploadPath(account.ID, upload.ID)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				l.logError("Ошибка создания каталога загрузок: %v", err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+				return
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				l.logError("Ошибка открытия загрузки %s: %v", upload.ID, err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+				return
+			}
+			// Лишнее после offset отбрасывается: часть могла дойти не целиком
+			file.Truncate(offset)
+			file.Seek(offset, io.SeekStart)
+			written, err := io.Copy(file, http.MaxBytesReader(w, r.Body, limit))
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			upload.Received = offset + written
+			if saveErr := idx.save(account.ID); saveErr != nil && err == nil {
+				err = saveErr
+			}
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge)
+					return
+				}
+				l.logError("Ошибка приема части сохранения %s: %v", upload.ID, err)
+				writeError(w, r, http.StatusBadRequest, ErrCodeUploadFailed)
+				return
+			}
+
+			json.NewEncoder(w).Encode(SaveUploadResponse{SaveUpload: *upload, ChunkSize: cfg.SavesChunkSize})
+		})
+	})
+}
+
+// Завершение загрузки: проверка размера и SHA-256, затем политика хранения
+func (l *Logger) saveUploadCompleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves/uploads/{id}/complete", func() {
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			i := slices.IndexFunc(idx.Uploads, func(u SaveUpload) bool { return u.ID == r.PathValue("id") })
+			if i < 0 {
+				writeError(w, r, http.StatusNotFound, ErrCodeUploadNotFound)
+				return
+			}
+			upload := idx.Uploads[i]
+			if upload.Received != upload.Size {
+				writeError(w, r, http.StatusConflict, ErrCodeUploadOffsetMismatch, upload.Received)
+				return
+			}
+
+			path := saveUploadPath(account.ID, upload.ID)
+			sum, err := fileSHA256(path)
+			if err != nil {
+				l.logError("Ошибка чтения загрузки %s: %v", upload.ID, err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+				return
+			}
+			if sum != upload.SHA256 {
+				// Битый архив не восстановить докачкой: загрузка начинается заново
+				idx.Uploads = slices.Delete(idx.Uploads, i, i+1)
+				if l.writeSaveIndex(w, r, account, idx) {
+					os.Remove(path)
+					writeError(w, r, http.StatusBadRequest, ErrCodeHashMismatch)
+				}
+				return
+			}
+
+			save := Save{
+				ID:        upload.ID,
+				Name:      upload.Name,
+				Filename:  upload.Filename,
+				Size:      upload.Size,
+				SHA256:    upload.SHA256,
+				CreatedAt: time.Now().UTC(),
+			}
+			if err := os.Rename(path, saveArchivePath(account.ID, save.ID)); err != nil {
+				l.logError("Ошибка сохранения архива %s: %v", save.ID, err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+				return
+			}
+			idx.Uploads = slices.Delete(idx.Uploads, i, i+1)
+			idx.Saves = append(idx.Saves, save)
+			cfg := currentConfig()
+			removed := idx.applyRetention(cfg.SavesKeep, cfg.SavesMaxAge, save.CreatedAt)
+			if !l.writeSaveIndex(w, r, account, idx) {
+				return
+			}
+			removeSaveFiles(account.ID, removed, nil)
+
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(save)
+			l.logSuccess("Сохранение «%s» аккаунта %s загружено (%d bytes)", save.Name, account.Username, save.Size)
+		})
+	})
+}
+
+// Скачивание копии для восстановления; поддерживает Range для докачки
+func (l *Logger) saveDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves/{id}", func() {
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			i := slices.IndexFunc(idx.Saves, func(s Save) bool { return s.ID == r.PathValue("id") })
+			if i < 0 {
+				writeError(w, r, http.StatusNotFound, ErrCodeSaveNotFound)
+				return
+			}
+			save := idx.Saves[i]
+
+			file, err := os.Open(saveArchivePath(account.ID, save.ID))
+			if err != nil {
+				l.logError("Ошибка открытия сохранения %s: %v", save.ID, err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+				return
+			}
+			defer file.Close()
+
+			w.Header().Set("Content-Type", contentTypeByName(save.Filename))
+			w.Header().Set("Content-Disposition", contentDisposition(DispositionAttachment, save.Filename))
+			w.Header().Set("X-File-SHA256", save.SHA256)
+			http.ServeContent(w, r, save.Filename, save.CreatedAt, file)
+			l.logSuccess("Отдано сохранение «%s» аккаунта %s", save.Name, account.Username)
+		})
+	})
+}
+
+// Удаление копии
+func (l *Logger) saveDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "💾", "/api/saves/{id}", func() {
+		l.withSaves(w, r, func(account Account, idx *saveIndex) {
+			i := slices.IndexFunc(idx.Saves, func(s Save) bool { return s.ID == r.PathValue("id") })
+			if i < 0 {
+				writeError(w, r, http.StatusNotFound, ErrCodeSaveNotFound)
+				return
+			}
+			save := idx.Saves[i]
+			idx.Saves = slices.Delete(idx.Saves, i, i+1)
+			if !l.writeSaveIndex(w, r, account, idx) {
+				return
+			}
+			removeSaveFiles(account.ID, []Save{save}, nil)
+
+			w.WriteHeader(http.StatusNoContent)
+			l.logSuccess("Удалено сохранение «%s» аккаунта %s", save.Name, account.Username)
+		})
+	})
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
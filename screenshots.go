@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ScreenshotPending  = "pending"
+	ScreenshotApproved = "approved"
+
+	// Больше 8K не принимаем: декодирование такой картинки съест слишком много памяти
+	maxScreenshotPixels = 7680 * 4320
+	maxCaptionLength    = 200
+)
+
+// Скриншот игрока. В галерею попадает только после одобрения модератором.
+// Картинка хранится перекодированной в JPEG: метаданные (EXIF, GPS)
+// при этом теряются.
+type Screenshot struct {
+	ID          string     `json:"id"`
+	AccountID   string     `json:"account_id"`
+	Author      string     `json:"author"`
+	Caption     string     `json:"caption,omitempty"`
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`
+	ModeratedBy string     `json:"moderated_by,omitempty"`
+}
+
+// Скриншот для лаунчера
+type ScreenshotInfo struct {
+	ID        string    `json:"id"`
+	Author    string    `json:"author"`
+	Caption   string    `json:"caption,omitempty"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	URL       string    `json:"url"`
+	ThumbURL  string    `json:"thumb_url"`
+	CreatedAt time.Time `json:"created_at"`
+	// Только в списке своих скриншотов
+	Status string `json:"status,omitempty"`
+}
+
+func (s Screenshot) info(cfg *Config) ScreenshotInfo {
+	base := cfg.PublicURL + "/api/screenshots/" + s.ID
+	return ScreenshotInfo{
+		ID:        s.ID,
+		Author:    s.Author,
+		Caption:   s.Caption,
+		Width:     s.Width,
+		Height:    s.Height,
+		URL:       base + "/image",
+		ThumbURL:  base + "/thumb",
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+type ScreenshotFeedResponse struct {
+	Screenshots []ScreenshotInfo `json:"screenshots"`
+	// Курсор следующей страницы: передается как before
+	NextBefore *time.Time `json:"next_before,omitempty"`
+}
+
+// Скриншоты в DATA_DIR/screenshots.json, картинки в DATA_DIR/screenshots/
+type ScreenshotStore struct {
+	mu          sync.Mutex
+	screenshots []Screenshot
+}
+
+var screenshots = &ScreenshotStore{}
+
+func screenshotsFile() string {
+	return filepath.Join(currentConfig().DataDir, "screenshots.json")
+}
+
+// Путь к картинке: kind — "image" или "thumb"
+func screenshotPath(id, kind string) string {
+	name := id + ".jpg"
+	if kind == "thumb" {
+		name = id + "_thumb.jpg"
+	}
+	return filepath.Join(currentConfig().DataDir, "screenshots", name)
+}
+
+func (s *ScreenshotStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(screenshotsFile(), &s.screenshots)
+}
+
+func (s *ScreenshotStore) Get(id string) (Screenshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.screenshots, func(shot Screenshot) bool { return shot.ID == id }); i >= 0 {
+		return s.screenshots[i], true
+	}
+	return Screenshot{}, false
+}
+
+// Новые первыми; фильтр по статусу и аккаунту, пустой — любые
+func (s *ScreenshotStore) List(status, accountID string) []Screenshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []Screenshot{}
+	for _, shot := range s.screenshots {
+		if (status == "" || shot.Status == status) && (accountID == "" || shot.AccountID == accountID) {
+			result = append(result, shot)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	return result
+}
+
+// Добавление скриншота; false, если у аккаунта уже слишком много
+// скриншотов на модерации
+func (s *ScreenshotStore) Add(shot Screenshot, pendingLimit int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := 0
+	for _, existing := range s.screenshots {
+		if existing.AccountID == shot.AccountID && existing.Status == ScreenshotPending {
+			pending++
+		}
+	}
+	if pending >= pendingLimit {
+		return false, nil
+	}
+	next := append(slices.Clone(s.screenshots), shot)
+	if err := saveJSONFile(screenshotsFile(), next); err != nil {
+		return true, err
+	}
+	s.screenshots = next
+	return true, nil
+}
+
+func (s *ScreenshotStore) Approve(id, actor string, now time.Time) (Screenshot, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.screenshots, func(shot Screenshot) bool { return shot.ID == id })
+	if i < 0 {
+		return Screenshot{}, false, nil
+	}
+	next := slices.Clone(s.screenshots)
+	next[i].Status = ScreenshotApproved
+	next[i].ModeratedAt = &now
+	next[i].ModeratedBy = actor
+	if err := saveJSONFile(screenshotsFile(), next); err != nil {
+		return Screenshot{}, true, err
+	}
+	s.screenshots = next
+	return next[i], true, nil
+}
+
+func (s *ScreenshotStore) Delete(id string) (Screenshot, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.screenshots, func(shot Screenshot) bool { return shot.ID == id })
+	if i < 0 {
+		return Screenshot{}, false, nil
+	}
+	shot := s.screenshots[i]
+	next := slices.Delete(slices.Clone(s.screenshots), i, i+1)
+	if err := saveJSONFile(screenshotsFile(), next); err != nil {
+		return shot, true, err
+	}
+	s.screenshots = next
+	os.Remove(screenshotPath(id, "image"))
+	os.Remove(screenshotPath(id, "thumb"))
+	return shot, true, nil
+}
+
+// Декодирование PNG или JPEG с проверкой размеров до выделения памяти
+func decodeScreenshot(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxScreenshotPixels {
+		return nil, errors.New("недопустимый размер изображения")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Уменьшение с усреднением по области, чтобы не было ступенек.
+// Картинки меньше maxSide не увеличиваются. Прозрачность заливается белым.
+func resizeImage(src image.Image, maxSide int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Over)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return rgba
+	}
+	tw, th := maxSide, h*maxSide/w
+	if h > w {
+		tw, th = w*maxSide/h, maxSide
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := range th {
+		y0, y1 := y*h/th, max((y+1)*h/th, y*h/th+1)
+		for x := range tw {
+			x0, x1 := x*w/tw, max((x+1)*w/tw, x*w/tw+1)
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(row[sx*4])
+					g += uint32(row[sx*4+1])
+					b += uint32(row[sx*4+2])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), 255
+		}
+	}
+	return dst
+}
+
+func writeJPEG(path string, img image.Image, quality int) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// Галерея: /api/screenshots?limit=50&before=2025-01-01T00:00:00Z
+func (l *Logger) screenshotsFeedHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🖼️", "/api/screenshots", func() {
+		query := r.URL.Query()
+		limit := 50
+		if value, err := strconv.Atoi(query.Get("limit")); err == nil && value > 0 {
+			limit = min(value, 100)
+		}
+		var before time.Time
+		if value := query.Get("before"); value != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			before = parsed
+		}
+
+		cfg := currentConfig()
+		response := ScreenshotFeedResponse{Screenshots: []ScreenshotInfo{}}
+		for _, shot := range screenshots.List(ScreenshotApproved, "") {
+			if !before.IsZero() && !shot.CreatedAt.Before(before) {
+				continue
+			}
+			if len(response.Screenshots) == limit {
+				last := response.Screenshots[limit-1].CreatedAt
+				response.NextBefore = &last
+				break
+			}
+			response.Screenshots = append(response.Screenshots, shot.info(cfg))
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// Свои скриншоты, включая ожидающие модерации
+func (l *Logger) accountScreenshotsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🖼️", "/api/account/screenshots", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		cfg := currentConfig()
+		result := []ScreenshotInfo{}
+		for _, shot := range screenshots.List("", account.ID) {
+			info := shot.info(cfg)
+			info.Status = shot.Status
+			result = append(result, info)
+		}
+		json.NewEncoder(w).Encode(result)
+	})
+}
+
+// Загрузка скриншота: тело — PNG или JPEG, подпись в ?caption=
+func (l *Logger) screenshotUploadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🖼️", "/api/screenshots", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		caption := r.URL.Query().Get("caption")
+		if !utf8.ValidString(caption) || utf8.RuneCountInString(caption) > maxCaptionLength {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		cfg := currentConfig()
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(cfg.ScreenshotMaxBytes)))
+		if err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		img, err := decodeScreenshot(data)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidImage)
+			return
+		}
+
+		full := resizeImage(img, cfg.ScreenshotMaxDimension)
+		shot := Screenshot{
+			ID:        randomID(12),
+			AccountID: account.ID,
+			Author:    account.Username,
+			Caption:   caption,
+			Width:     full.Bounds().Dx(),
+			Height:    full.Bounds().Dy(),
+			Status:    ScreenshotPending,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := os.MkdirAll(filepath.Dir(screenshotPath(shot.ID, "image")), 0755); err != nil {
+			l.logError("Ошибка создания каталога скриншотов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		if err := writeJPEG(screenshotPath(shot.ID, "image"), full, 90); err != nil {
+			l.logError("Ошибка сохранения скриншота: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+		if err := writeJPEG(screenshotPath(shot.ID, "thumb"), resizeImage(full, cfg.ScreenshotThumbDimension), 80); err != nil {
+			os.Remove(screenshotPath(shot.ID, "image"))
+			l.logError("Ошибка сохранения миниатюры: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+
+		added, err := screenshots.Add(shot, cfg.ScreenshotPendingLimit)
+		if !added || err != nil {
+			os.Remove(screenshotPath(shot.ID, "image"))
+			os.Remove(screenshotPath(shot.ID, "thumb"))
+		}
+		if !added {
+			writeError(w, r, http.StatusTooManyRequests, ErrCodeTooManyPending, cfg.ScreenshotPendingLimit)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения скриншотов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		info := shot.info(cfg)
+		info.Status = shot.Status
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(info)
+		l.logSuccess("Скриншот %s от %s отправлен на модерацию (%dx%d)", shot.ID, account.Username, shot.Width, shot.Height)
+	})
+}
+
+func (l *Logger) serveScreenshot(w http.ResponseWriter, r *http.Request, shot Screenshot, kind string) {
+	file, err := os.Open(screenshotPath(shot.ID, kind))
+	if err != nil {
+		l.logError("Ошибка открытия скриншота %s: %v", shot.ID, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, r, "", shot.CreatedAt, file)
+}
+
+// Картинка одобренного скриншота: /api/screenshots/{id}/image или /thumb
+func (l *Logger) screenshotImageHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleWithCORS(w, r, "🖼️", "/api/screenshots/{id}/"+kind, func() {
+			shot, ok := screenshots.Get(r.PathValue("id"))
+			if !ok || shot.Status != ScreenshotApproved {
+				writeError(w, r, http.StatusNotFound, ErrCodeScreenshotNotFound)
+				return
+			}
+			// Снятый с публикации скриншот должен пропасть из кэшей за разумное время
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+			l.serveScreenshot(w, r, shot, kind)
+		})
+	}
+}
+
+// Очередь модерации: /admin/api/screenshots?status=pending (по умолчанию)
+func (l *Logger) adminScreenshotsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersRead, "🖼️", "/admin/api/screenshots", func() {
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = ScreenshotPending
+		case "all":
+			status = ""
+		case ScreenshotPending, ScreenshotApproved:
+		default:
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidStatus)
+			return
+		}
+		json.NewEncoder(w).Encode(screenshots.List(status, ""))
+	})
+}
+
+// Просмотр картинки модератором, в том числе до одобрения
+func (l *Logger) adminScreenshotImageHandler(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l.handleAdmin(w, r, ScopePlayersRead, "🖼️", "/admin/api/screenshots/{id}/"+kind, func() {
+			shot, ok := screenshots.Get(r.PathValue("id"))
+			if !ok {
+				writeError(w, r, http.StatusNotFound, ErrCodeScreenshotNotFound)
+				return
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			l.serveScreenshot(w, r, shot, kind)
+		})
+	}
+}
+
+// Одобрение: скриншот появляется в галерее
+func (l *Logger) adminApproveScreenshotHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🖼️", "/admin/api/screenshots/{id}/approve", func() {
+		actor, _ := adminActor(r, "")
+		shot, found, err := screenshots.Approve(r.PathValue("id"), actor, time.Now().UTC())
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeScreenshotNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения скриншотов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		json.NewEncoder(w).Encode(shot)
+		l.logSuccess("Скриншот %s от %s одобрен (%s)", shot.ID, shot.Author, actor)
+	})
+}
+
+// Удаление: отклонение из очереди или снятие из галереи
+func (l *Logger) adminDeleteScreenshotHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopePlayersWrite, "🖼️", "/admin/api/screenshots/{id}", func() {
+		actor, _ := adminActor(r, "")
+		shot, found, err := screenshots.Delete(r.PathValue("id"))
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeScreenshotNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения скриншотов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Скриншот %s от %s удален (%s)", shot.ID, shot.Author, actor)
+	})
+}
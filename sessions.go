@@ -0,0 +1,550 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Игровая сессия: лаунчер сообщает о запуске игры, периодически
+// подтверждает, что игра идет, и сообщает о выходе. Сессия без
+// подтверждений дольше трех интервалов считается оборванной.
+type PlaySession struct {
+	ID          string `json:"id"`
+	Player      string `json:"player"`
+	GameVersion string `json:"game_version,omitempty"`
+	Modpack     string `json:"modpack,omitempty"`
+	// Адрес игрового сервера, на котором играет игрок
+	Server        string    `json:"server,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+type SessionStartRequest struct {
+	Account     string `json:"account"`
+	ClientID    string `json:"client_id"`
+	GameVersion string `json:"game_version"`
+	Modpack     string `json:"modpack"`
+	Server      string `json:"server"`
+}
+
+type SessionRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+type SessionStartResponse struct {
+	SessionID string `json:"session_id"`
+	// Как часто слать /api/session/heartbeat, в секундах
+	HeartbeatInterval int `json:"heartbeat_interval"`
+}
+
+type OnlineResponse struct {
+	Online int `json:"online"`
+}
+
+// Накопленное время игры одного игрока
+type Playtime struct {
+	TotalSeconds int64     `json:"total_seconds"`
+	Sessions     int       `json:"sessions"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+type PlayerPlaytime struct {
+	Player string `json:"player"`
+	Playtime
+}
+
+// Для админки: текущие сессии и игроки с наибольшим временем игры
+type AdminSessionsResponse struct {
+	Online       int              `json:"online"`
+	Sessions     []PlaySession    `json:"sessions"`
+	TopPlayers   []PlayerPlaytime `json:"top_players"`
+	Players      int              `json:"players"`
+	TotalHours   float64          `json:"total_hours"`
+	ByVersion    map[string]int   `json:"by_version"`
+	PeakOnline   int              `json:"peak_online"`
+	PeakOnlineAt *time.Time       `json:"peak_online_at,omitempty"`
+}
+
+// Активные сессии живут в памяти или в Redis, время игры — в
+// DATA_DIR/playtime.json экземпляра, закрывшего сессию
+type SessionTracker struct {
+	mu           sync.Mutex
+	active       activeSessions
+	playtime     map[string]Playtime
+	peakOnline   int
+	peakOnlineAt *time.Time
+}
+
+var sessions = &SessionTracker{
+	active:   &memorySessions{sessions: make(map[string]PlaySession), byPlayer: make(map[string]string)},
+	playtime: make(map[string]Playtime),
+}
+
+// Хранилище активных сессий. У игрока одна текущая сессия: новая
+// закрывает предыдущую. Remove возвращает false, если сессию уже закрыли
+// (с Redis — другой экземпляр), и время игры тогда не засчитывается.
+type activeSessions interface {
+	Get(id string) (PlaySession, bool)
+	Current(player string) (PlaySession, bool)
+	Add(session PlaySession)
+	Touch(id string, now time.Time) bool
+	Remove(session PlaySession) bool
+	All() []PlaySession
+	Count() int
+}
+
+// Сессии в памяти экземпляра (под блокировкой SessionTracker)
+type memorySessions struct {
+	sessions map[string]PlaySession
+	byPlayer map[string]string
+}
+
+func (m *memorySessions) Get(id string) (PlaySession, bool) {
+	session, ok := m.sessions[id]
+	return session, ok
+}
+
+func (m *memorySessions) Current(player string) (PlaySession, bool) {
+	return m.Get(m.byPlayer[player])
+}
+
+func (m *memorySessions) Add(session PlaySession) {
+	m.sessions[session.ID] = session
+	m.byPlayer[session.Player] = session.ID
+}
+
+func (m *memorySessions) Touch(id string, now time.Time) bool {
+	session, ok := m.sessions[id]
+	if ok {
+		session.LastHeartbeat = now
+		m.sessions[id] = session
+	}
+	return ok
+}
+
+func (m *memorySessions) Remove(session PlaySession) bool {
+	if _, ok := m.sessions[session.ID]; !ok {
+		return false
+	}
+	delete(m.sessions, session.ID)
+	if m.byPlayer[session.Player] == session.ID {
+		delete(m.byPlayer, session.Player)
+	}
+	return true
+}
+
+func (m *memorySessions) All() []PlaySession {
+	return slices.Collect(maps.Values(m.sessions))
+}
+
+func (m *memorySessions) Count() int {
+	return len(m.sessions)
+}
+
+// Сессии в Redis: хэш sessions (ID → JSON сессии) и session_players
+// (игрок → ID). Подтверждение и закрытие — скриптами, чтобы сессия,
+// закрытая одним экземпляром, не воскресла от подтверждения на другом.
+type redisSessions struct {
+	c *RedisClient
+}
+
+const (
+	redisTouchSession  = `if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then redis.call('HSET', KEYS[1], ARGV[1], ARGV[2]) return 1 end return 0`
+	redisRemoveSession = `if redis.call('HGET', KEYS[2], ARGV[2]) == ARGV[1] then redis.call('HDEL', KEYS[2], ARGV[2]) end return redis.call('HDEL', KEYS[1], ARGV[1])`
+)
+
+func (s *redisSessions) decode(reply any) (PlaySession, bool) {
+	data, ok := redisString(reply)
+	if !ok {
+		return PlaySession{}, false
+	}
+	var session PlaySession
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		s.c.logError("sessions", err)
+		return PlaySession{}, false
+	}
+	return session, true
+}
+
+func (s *redisSessions) Get(id string) (PlaySession, bool) {
+	reply, err := s.c.Do("HGET", s.c.key("sessions"), id)
+	if err != nil {
+		s.c.logError("sessions", err)
+		return PlaySession{}, false
+	}
+	return s.decode(reply)
+}
+
+func (s *redisSessions) Current(player string) (PlaySession, bool) {
+	reply, err := s.c.Do("HGET", s.c.key("session_players"), player)
+	if err != nil {
+		s.c.logError("sessions", err)
+		return PlaySession{}, false
+	}
+	id, ok := redisString(reply)
+	if !ok {
+		return PlaySession{}, false
+	}
+	return s.Get(id)
+}
+
+func (s *redisSessions) Add(session PlaySession) {
+	data, _ := json.Marshal(session)
+	if _, err := s.c.Do("HSET", s.c.key("sessions"), session.ID, string(data)); err != nil {
+		s.c.logError("sessions", err)
+		return
+	}
+	if _, err := s.c.Do("HSET", s.c.key("session_players"), session.Player, session.ID); err != nil {
+		s.c.logError("sessions", err)
+	}
+}
+
+func (s *redisSessions) Touch(id string, now time.Time) bool {
+	session, ok := s.Get(id)
+	if !ok {
+		return false
+	}
+	session.LastHeartbeat = now
+	data, _ := json.Marshal(session)
+	reply, err := s.c.Do("EVAL", redisTouchSession, "1", s.c.key("sessions"), id, string(data))
+	if err != nil {
+		s.c.logError("sessions", err)
+		return false
+	}
+	return redisInt(reply) == 1
+}
+
+func (s *redisSessions) Remove(session PlaySession) bool {
+	reply, err := s.c.Do("EVAL", redisRemoveSession, "2", s.c.key("sessions"), s.c.key("session_players"), session.ID, session.Player)
+	if err != nil {
+		s.c.logError("sessions", err)
+		return false
+	}
+	return redisInt(reply) == 1
+}
+
+func (s *redisSessions) All() []PlaySession {
+	reply, err := s.c.Do("HGETALL", s.c.key("sessions"))
+	if err != nil {
+		s.c.logError("sessions", err)
+		return nil
+	}
+	var list []PlaySession
+	for _, data := range redisHash(reply) {
+		if session, ok := s.decode(data); ok {
+			list = append(list, session)
+		}
+	}
+	return list
+}
+
+func (s *redisSessions) Count() int {
+	reply, err := s.c.Do("HLEN", s.c.key("sessions"))
+	if err != nil {
+		s.c.logError("sessions", err)
+		return 0
+	}
+	return int(redisInt(reply))
+}
+
+func playtimeFile() string {
+	return filepath.Join(currentConfig().DataDir, "playtime.json")
+}
+
+func (t *SessionTracker) Load() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return loadJSONFile(playtimeFile(), &t.playtime)
+}
+
+func (t *SessionTracker) Start(player, gameVersion, modpack, server string, now time.Time) (PlaySession, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous, replaced := t.active.Current(player)
+	if replaced {
+		replaced = t.finish(previous, now)
+	}
+
+	session := PlaySession{
+		ID:            randomID(16),
+		Player:        player,
+		GameVersion:   gameVersion,
+		Modpack:       modpack,
+		Server:        server,
+		StartedAt:     now,
+		LastHeartbeat: now,
+	}
+	t.active.Add(session)
+	if online := t.active.Count(); online > t.peakOnline {
+		t.peakOnline = online
+		t.peakOnlineAt = &now
+	}
+	if replaced {
+		return session, t.save()
+	}
+	return session, nil
+}
+
+func (t *SessionTracker) Heartbeat(id string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active.Touch(id, now)
+}
+
+func (t *SessionTracker) End(id string, now time.Time) (PlaySession, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	session, ok := t.active.Get(id)
+	if !ok || !t.finish(session, now) {
+		return PlaySession{}, false, nil
+	}
+	return session, true, t.save()
+}
+
+// Закрытие сессии с зачетом времени игры (под блокировкой); false —
+// сессию уже закрыли
+func (t *SessionTracker) finish(session PlaySession, end time.Time) bool {
+	if !t.active.Remove(session) {
+		return false
+	}
+
+	stats := t.playtime[session.Player]
+	stats.TotalSeconds += int64(end.Sub(session.StartedAt).Seconds())
+	stats.Sessions++
+	stats.LastSeen = end
+	t.playtime[session.Player] = stats
+	return true
+}
+
+// Текущая сессия игрока
+func (t *SessionTracker) Current(player string) (PlaySession, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active.Current(player)
+}
+
+func (t *SessionTracker) Playtime(player string) (Playtime, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats, ok := t.playtime[player]
+	return stats, ok
+}
+
+// Удаление времени игры и текущих сессий игроков, например вместе с аккаунтом
+func (t *SessionTracker) Forget(players ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changed := false
+	for _, player := range players {
+		if session, ok := t.active.Current(player); ok {
+			t.active.Remove(session)
+		}
+		if _, ok := t.playtime[player]; ok {
+			delete(t.playtime, player)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return t.save()
+}
+
+func (t *SessionTracker) save() error {
+	return saveJSONFile(playtimeFile(), t.playtime)
+}
+
+// Закрытие сессий без подтверждений; время засчитывается до последнего.
+// Возвращает игроков закрытых сессий.
+func (t *SessionTracker) expire(timeout time.Duration, now time.Time) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var expired []string
+	for _, session := range t.active.All() {
+		if now.Sub(session.LastHeartbeat) > timeout && t.finish(session, session.LastHeartbeat) {
+			expired = append(expired, session.Player)
+		}
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	return expired, t.save()
+}
+
+func (t *SessionTracker) Online() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.active.Count()
+}
+
+func (t *SessionTracker) Summary(top int) AdminSessionsResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active := t.active.All()
+	response := AdminSessionsResponse{
+		Online:       len(active),
+		Sessions:     make([]PlaySession, 0, len(active)),
+		TopPlayers:   make([]PlayerPlaytime, 0, len(t.playtime)),
+		Players:      len(t.playtime),
+		ByVersion:    make(map[string]int),
+		PeakOnline:   t.peakOnline,
+		PeakOnlineAt: t.peakOnlineAt,
+	}
+	for _, session := range active {
+		response.Sessions = append(response.Sessions, session)
+		response.ByVersion[session.GameVersion]++
+	}
+	sort.Slice(response.Sessions, func(i, j int) bool {
+		return response.Sessions[i].StartedAt.Before(response.Sessions[j].StartedAt)
+	})
+
+	var total int64
+	for player, stats := range t.playtime {
+		total += stats.TotalSeconds
+		response.TopPlayers = append(response.TopPlayers, PlayerPlaytime{Player: player, Playtime: stats})
+	}
+	response.TotalHours = float64(total) / 3600
+	sort.Slice(response.TopPlayers, func(i, j int) bool {
+		return response.TopPlayers[i].TotalSeconds > response.TopPlayers[j].TotalSeconds
+	})
+	if len(response.TopPlayers) > top {
+		response.TopPlayers = response.TopPlayers[:top]
+	}
+	return response
+}
+
+// Фоновое закрытие оборванных сессий
+func (l *Logger) runSessionSweeper() {
+	for {
+		interval := currentConfig().SessionHeartbeatInterval
+		time.Sleep(interval)
+
+		expired, err := sessions.expire(3*interval, time.Now())
+		if err != nil {
+			l.logError("Ошибка сохранения времени игры: %v", err)
+		}
+		for _, player := range expired {
+			l.publishPresence(player)
+		}
+		if len(expired) > 0 {
+			l.logSuccess("Закрыто оборванных сессий: %d", len(expired))
+		}
+	}
+}
+
+// Разбор запроса с ID сессии
+func decodeSessionRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req SessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SessionID == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+		return "", false
+	}
+	return req.SessionID, true
+}
+
+// Начало игровой сессии
+func (l *Logger) sessionStartHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎮", "/api/session/start", func() {
+		var req SessionStartRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if req.ClientID == "" {
+			req.ClientID = launcherClientID(r)
+		}
+		if account, ok := authenticateAccount(r); ok {
+			req.Account = account.Username
+		}
+		// Время игры копится на аккаунт, а без него — на установку лаунчера
+		player := playerSubject(req.Account, req.ClientID)
+		if player == "" || len(req.ClientID) > 128 || len(req.GameVersion) > 64 || len(req.Modpack) > 64 || len(req.Server) > 128 ||
+			(req.Account != "" && !playerNamePattern.MatchString(req.Account)) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		cfg := currentConfig()
+		session, err := sessions.Start(player, req.GameVersion, req.Modpack, req.Server, time.Now().UTC())
+		if err != nil {
+			l.logError("Ошибка сохранения времени игры: %v", err)
+		}
+		l.publishPresence(player)
+
+		json.NewEncoder(w).Encode(SessionStartResponse{
+			SessionID:         session.ID,
+			HeartbeatInterval: int(cfg.SessionHeartbeatInterval.Seconds()),
+		})
+		l.logSuccess("Начата игровая сессия %s (%s)", session.ID, player)
+	})
+}
+
+// Подтверждение, что игра продолжается
+func (l *Logger) sessionHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎮", "/api/session/heartbeat", func() {
+		id, ok := decodeSessionRequest(w, r)
+		if !ok {
+			return
+		}
+		// Сессия могла быть закрыта по таймауту: лаунчер начинает новую
+		if !sessions.Heartbeat(id, time.Now().UTC()) {
+			writeError(w, r, http.StatusNotFound, ErrCodeSessionNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Завершение игровой сессии
+func (l *Logger) sessionEndHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎮", "/api/session/end", func() {
+		id, ok := decodeSessionRequest(w, r)
+		if !ok {
+			return
+		}
+		now := time.Now().UTC()
+		session, found, err := sessions.End(id, now)
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeSessionNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка сохранения времени игры: %v", err)
+		}
+		l.publishPresence(session.Player)
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Завершена игровая сессия %s (%s), %s", id, session.Player, now.Sub(session.StartedAt).Round(time.Second))
+	})
+}
+
+// Число игроков онлайн
+func (l *Logger) onlineHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🎮", "/api/online", func() {
+		json.NewEncoder(w).Encode(OnlineResponse{Online: sessions.Online()})
+	})
+}
+
+// Сессии и время игры для админки: /admin/api/sessions?top=20
+func (l *Logger) adminSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeStatsRead, "🎮", "/admin/api/sessions", func() {
+		top := 20
+		if value, err := strconv.Atoi(r.URL.Query().Get("top")); err == nil && value > 0 {
+			top = value
+		}
+		json.NewEncoder(w).Encode(sessions.Summary(top))
+	})
+}
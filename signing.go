@@ -0,0 +1,301 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Ключ подписи релизов. Если задан только открытый ключ
+// (SIGNING_PUBLIC_KEY), закрытый хранится офлайн, а подписи загружаются
+// через админский API.
+type ReleaseSigner struct {
+	public  ed25519.PublicKey
+	private ed25519.PrivateKey
+	keyID   string
+}
+
+var releaseSigner atomic.Pointer[ReleaseSigner]
+
+// Подпись артефакта в ответе /api/signature/{artifact}
+type SignatureResponse struct {
+	Artifact  string `json:"artifact"`
+	Filename  string `json:"filename"`
+	Version   string `json:"version"`
+	SHA256    string `json:"sha256"`
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	// Подписывается строка loil-release:<artifact>:<sha256>
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+type PublicKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	PEM       string `json:"pem"`
+}
+
+// Подпись, сделанная офлайн-ключом: DATA_DIR/signatures/<artifact>.json
+type StoredSignature struct {
+	SHA256    string    `json:"sha256"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SignatureUploadRequest struct {
+	Signature string `json:"signature"`
+}
+
+// Сообщение включает имя артефакта, чтобы подпись лаунчера нельзя было
+// выдать за подпись игры
+func signatureMessage(artifact, sha256Hex string) string {
+	return "loil-release:" + artifact + ":" + sha256Hex
+}
+
+func newReleaseSigner(public ed25519.PublicKey, private ed25519.PrivateKey) *ReleaseSigner {
+	sum := sha256.Sum256(public)
+	return &ReleaseSigner{public: public, private: private, keyID: hex.EncodeToString(sum[:8])}
+}
+
+func (s *ReleaseSigner) offline() bool {
+	return s.private == nil
+}
+
+// Загрузка ключа подписи; закрытый ключ сервера создается при первом запуске
+func (l *Logger) loadReleaseSigner(cfg *Config) error {
+	if cfg.SigningPublicKey != "" {
+		public, err := readEd25519PublicKey(cfg.SigningPublicKey)
+		if err != nil {
+			return err
+		}
+		releaseSigner.Store(newReleaseSigner(public, nil))
+		return nil
+	}
+
+	private, err := readEd25519PrivateKey(cfg.SigningKey)
+	if errors.Is(err, os.ErrNotExist) {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		if err := writeEd25519PrivateKey(cfg.SigningKey, private); err != nil {
+			return err
+		}
+		l.Printf("🔏 Создан ключ подписи релизов: %s", cfg.SigningKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	releaseSigner.Store(newReleaseSigner(private.Public().(ed25519.PublicKey), private))
+	return nil
+}
+
+func readEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: ожидался PEM-блок PRIVATE KEY", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: ключ не Ed25519", path)
+	}
+	return private, nil
+}
+
+func readEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s: ожидался PEM-блок PUBLIC KEY", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: ключ не Ed25519", path)
+	}
+	return public, nil
+}
+
+func writeEd25519PrivateKey(path string, private ed25519.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return os.WriteFile(path, data, 0600)
+}
+
+func encodePublicKeyPEM(public ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signaturePath(artifact string) string {
+	return filepath.Join(currentConfig().DataDir, "signatures", artifact+".json")
+}
+
+// SHA-256 текущего файла артефакта
+func artifactSHA256(cfg *Config, artifact string) (string, string, error) {
+	filename, _ := artifactFilename(cfg, artifact)
+	path := filepath.Join(cfg.ClientsDir, filename)
+	info, err := os.Stat(path)
+	if err != nil {
+		return filename, "", err
+	}
+	entry, err := clientIndex.Lookup(path, info)
+	return filename, entry.SHA256, err
+}
+
+// Подпись сборки с таким SHA-256; false — ключ офлайн, а подпись этой
+// сборки не загружена
+func (l *Logger) artifactSignature(signer *ReleaseSigner, artifact, sum string) ([]byte, bool) {
+	if !signer.offline() {
+		return ed25519.Sign(signer.private, []byte(signatureMessage(artifact, sum))), true
+	}
+	// Подпись должна быть загружена для этой самой сборки
+	var stored *StoredSignature
+	if err := loadJSONFile(signaturePath(artifact), &stored); err != nil {
+		l.logError("Ошибка чтения подписи %s: %v", artifact, err)
+	}
+	if stored == nil || stored.SHA256 != sum {
+		return nil, false
+	}
+	signature, _ := base64.StdEncoding.DecodeString(stored.Signature)
+	return signature, true
+}
+
+// Подпись артефакта для лаунчера
+func (l *Logger) signatureHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔏", "/api/signature/{artifact}", func() {
+		cfg := currentConfig()
+		signer := releaseSigner.Load()
+		artifact := r.PathValue("artifact")
+		if _, ok := artifactFilename(cfg, artifact); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+
+		filename, sum, err := artifactSHA256(cfg, artifact)
+		if os.IsNotExist(err) {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка вычисления хэша %s: %v", artifact, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+
+		signature, ok := l.artifactSignature(signer, artifact, sum)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeSignatureNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(SignatureResponse{
+			Artifact:  artifact,
+			Filename:  filename,
+			Version:   artifactVersion(cfg, artifact),
+			SHA256:    sum,
+			Algorithm: "ed25519",
+			KeyID:     signer.keyID,
+			Message:   signatureMessage(artifact, sum),
+			Signature: base64.StdEncoding.EncodeToString(signature),
+		})
+		l.logSuccess("Отправлена подпись %s (ключ %s)", artifact, signer.keyID)
+	})
+}
+
+// Открытый ключ подписи релизов
+func (l *Logger) pubkeyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔏", "/api/pubkey", func() {
+		signer := releaseSigner.Load()
+		json.NewEncoder(w).Encode(PublicKeyResponse{
+			Algorithm: "ed25519",
+			KeyID:     signer.keyID,
+			PublicKey: base64.StdEncoding.EncodeToString(signer.public),
+			PEM:       encodePublicKeyPEM(signer.public),
+		})
+		l.logSuccess("Отправлен открытый ключ %s", signer.keyID)
+	})
+}
+
+// Загрузка подписи, сделанной офлайн-ключом (loil-server sign)
+func (l *Logger) adminUploadSignatureHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🔏", "/admin/api/signature/{artifact}", func() {
+		cfg := currentConfig()
+		signer := releaseSigner.Load()
+		artifact := r.PathValue("artifact")
+		if _, ok := artifactFilename(cfg, artifact); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+
+		var req SignatureUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Signature == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		_, sum, err := artifactSHA256(cfg, artifact)
+		if os.IsNotExist(err) {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка вычисления хэша %s: %v", artifact, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+
+		// Принимаем только подпись, которая проверяется текущим ключом
+		signature, err := base64.StdEncoding.DecodeString(req.Signature)
+		if err != nil || !ed25519.Verify(signer.public, []byte(signatureMessage(artifact, sum)), signature) {
+			l.logError("Подпись %s не прошла проверку", artifact)
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidSignature)
+			return
+		}
+
+		stored := StoredSignature{SHA256: sum, Signature: req.Signature, CreatedAt: time.Now().UTC()}
+		if err := saveJSONFile(signaturePath(artifact), stored); err != nil {
+			l.logError("Ошибка сохранения подписи %s: %v", artifact, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return
+		}
+
+		json.NewEncoder(w).Encode(stored)
+		l.logSuccess("Сохранена подпись %s для sha256 %s", artifact, sum)
+	})
+}
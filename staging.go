@@ -0,0 +1,460 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Имя тестового канала основной игры в ?channel=
+const stagingChannel = "staging"
+
+// Сборки тестового канала: лежат в STAGING_DIR под теми же именами, что в
+// CLIENTS_DIR. Артефакт без своей сборки канал отдает из основного.
+type StagingBuild struct {
+	LauncherVersion string                     `json:"launcher_version,omitempty"`
+	GameVersion     string                     `json:"game_version,omitempty"`
+	Changelog       string                     `json:"changelog"`
+	Artifacts       map[string]ReleaseArtifact `json:"artifacts"`
+	UpdatedAt       time.Time                  `json:"updated_at"`
+	// Релиз, которым канал последний раз продвинут в основной
+	PromotedRelease string     `json:"promoted_release,omitempty"`
+	PromotedAt      *time.Time `json:"promoted_at,omitempty"`
+}
+
+type StagingChangelogRequest struct {
+	Changelog string `json:"changelog"`
+}
+
+type StagingPromoteRequest struct {
+	Announce bool `json:"announce"`
+	// Продвинуть, даже если версии не новее основного канала
+	Force bool `json:"force"`
+}
+
+// Сборка изменилась на диске после загрузки: тестеры проверяли другую
+var errStagingModified = errors.New("сборка тестового канала изменилась после загрузки")
+
+var (
+	// Держится на время загрузки и продвижения, чтобы файлы и сведения
+	// о них не разошлись
+	stagingMu    sync.Mutex
+	stagingBuild atomic.Pointer[StagingBuild]
+)
+
+func stagingFile() string {
+	return filepath.Join(currentConfig().DataDir, "staging.json")
+}
+
+func loadStaging() error {
+	var build *StagingBuild
+	if err := loadJSONFile(stagingFile(), &build); err != nil {
+		return err
+	}
+	if build == nil {
+		build = &StagingBuild{Artifacts: map[string]ReleaseArtifact{}}
+	}
+	stagingBuild.Store(build)
+	return nil
+}
+
+func currentStaging() *StagingBuild {
+	return stagingBuild.Load()
+}
+
+// Изменение сведений о канале; вызывается под stagingMu
+func updateStaging(fn func(build *StagingBuild)) (*StagingBuild, error) {
+	next := *currentStaging()
+	next.Artifacts = make(map[string]ReleaseArtifact, len(next.Artifacts))
+	for name, artifact := range currentStaging().Artifacts {
+		next.Artifacts[name] = artifact
+	}
+	fn(&next)
+	next.UpdatedAt = time.Now().UTC()
+
+	if err := saveJSONFile(stagingFile(), &next); err != nil {
+		return nil, err
+	}
+	stagingBuild.Store(&next)
+	return &next, nil
+}
+
+// Версия артефакта в канале; пусто — своей сборки нет
+func (b *StagingBuild) version(artifact string) string {
+	if _, ok := b.Artifacts[artifact]; !ok {
+		return ""
+	}
+	if artifact == "launcher" {
+		return b.LauncherVersion
+	}
+	return b.GameVersion
+}
+
+// Файл и версия артефакта для тестового канала
+func (b *StagingBuild) artifact(cfg *Config, artifact string) (string, string) {
+	filename, _ := artifactFilename(cfg, artifact)
+	if version := b.version(artifact); version != "" {
+		return filepath.Join(cfg.StagingDir, filename), version
+	}
+	return filepath.Join(cfg.ClientsDir, filename), artifactVersion(cfg, artifact)
+}
+
+// Файл артефакта тестового канала для скачивания. Ошибка — артефакт
+// неизвестен или файла, который канал считает своим, нет на диске.
+func (b *StagingBuild) file(cfg *Config, artifact string) (string, error) {
+	if _, ok := artifactFilename(cfg, artifact); !ok {
+		return "", fmt.Errorf("неизвестный артефакт %q", artifact)
+	}
+	path, _ := b.artifact(cfg, artifact)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Виден ли тестовый канал клиенту: адрес из STAGING_ALLOWED_IPS или вход
+// в аккаунт с правом STAGING_ENTITLEMENT. Выключателем канал скрывается
+// от всех сразу.
+func stagingVisible(cfg *Config, r *http.Request) bool {
+	if cfg.StagingDir == "" || switchOn(SwitchBetaChannel) {
+		return false
+	}
+	if ipInNetworks(getClientIP(r), cfg.StagingAllowedIPs) {
+		return true
+	}
+	if cfg.StagingEntitlement == "" {
+		return false
+	}
+	account, ok := authenticateAccount(r)
+	return ok && hasEntitlement(cfg, account, cfg.StagingEntitlement)
+}
+
+// Канал из ?channel=: true — тестовый. Посторонним тестовый канал не
+// виден вовсе: для них он такой же неизвестный, как любой другой.
+func (l *Logger) requestStaging(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	channel := r.URL.Query().Get("channel")
+	switch {
+	case channel == "":
+		return false, true
+	case channel == stagingChannel && stagingVisible(currentConfig(), r):
+		w.Header().Set("Cache-Control", "private, no-store")
+		return true, true
+	case channel == stagingChannel:
+		l.logWarn("Тестовый канал скрыт от %s: адрес не в списке и нет права тестера", getClientIP(r))
+	}
+	writeError(w, r, http.StatusNotFound, ErrCodeUnknownChannel, channel)
+	return false, false
+}
+
+// Версии тестового канала; кэш /api/version не используется, ответ
+// собирается на каждый запрос тестера
+func (l *Logger) stagingVersionHandler(w http.ResponseWriter, r *http.Request) {
+	cfg, build := currentConfig(), currentStaging()
+	_, launcherVersion := build.artifact(cfg, "launcher")
+	_, gameVersion := build.artifact(cfg, "game")
+
+	query := r.URL.Query()
+	blocked := currentBlockedVersions()
+	json.NewEncoder(w).Encode(VersionResponse{
+		LauncherVersion: launcherVersion,
+		GameVersion:     gameVersion,
+		Maintenance:     maintenanceMode.Load(),
+		BlockedVersions: *blocked,
+		MustUpdate:      blocked.mustUpdate(query.Get("launcher_version"), query.Get("game_version")),
+	})
+	l.logSuccess("Отправлены версии тестового канала: лаунчер=%s, игра=%s", launcherVersion, gameVersion)
+}
+
+// Копия сборки тестового канала для релиза с проверкой, что байты те
+// же, что были загружены. Сборка из хранилища блобов не копируется:
+// после сверки в релиз попадает ссылка на тот же блоб.
+func copyStagedBuild(source, target, sha string) error {
+	if blobs.Has(sha) {
+		sum, err := fileSHA256(source)
+		if err != nil {
+			return err
+		}
+		if sum != sha {
+			return errStagingModified
+		}
+		if linkFile(source, target) == nil {
+			return nil
+		}
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), in)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != sha {
+		return errStagingModified
+	}
+	os.Chmod(tmp.Name(), 0644)
+	return os.Rename(tmp.Name(), target)
+}
+
+// Продвижение тестового канала в основной: сборки копируются побайтно с
+// проверкой хэша и публикуются обычным релизом, поэтому игроки получают
+// ровно то, что проверили тестеры, вместе с версиями и описанием изменений
+func (l *Logger) promoteStaging(announce, force bool) (Release, *modError, error) {
+	stagingMu.Lock()
+	defer stagingMu.Unlock()
+
+	cfg, build := currentConfig(), currentStaging()
+	now := time.Now().UTC()
+	rel := Release{
+		ID:              randomID(8),
+		LauncherVersion: build.version("launcher"),
+		GameVersion:     build.version("game"),
+		Changelog:       build.Changelog,
+		Announce:        announce,
+		Force:           force,
+		Status:          ReleaseDraft,
+		Artifacts:       make(map[string]ReleaseArtifact),
+		CreatedAt:       now,
+	}
+	if len(rel.artifacts()) == 0 {
+		return Release{}, &modError{http.StatusConflict, ErrCodeStagingEmpty, nil}, nil
+	}
+	// До копирования, чтобы не оставить черновик, который не опубликовать
+	if apiErr := rel.resolveVersions(cfg); apiErr != nil {
+		return Release{}, apiErr, nil
+	}
+
+	dir, filename, _ := rel.target(cfg)
+	var copied []string
+	removeCopied := func() {
+		for _, path := range copied {
+			os.Remove(path)
+			blobs.Release(path)
+		}
+	}
+	for _, artifact := range rel.artifacts() {
+		staged := build.Artifacts[artifact]
+		source := filepath.Join(cfg.StagingDir, filename(artifact))
+		target := releaseStagingPath(dir, rel.ID, filename(artifact))
+		err := copyStagedBuild(source, target, staged.SHA256)
+		if err != nil {
+			removeCopied()
+		}
+		if errors.Is(err, errStagingModified) {
+			return Release{}, &modError{http.StatusConflict, ErrCodeStagingModified, []interface{}{artifact}}, nil
+		}
+		if err != nil {
+			return Release{}, nil, err
+		}
+		copied = append(copied, target)
+		l.adoptBlob(rel.blobRef(target, artifact, false), uploadedFile{Size: staged.Size, MD5: staged.Hash, SHA256: staged.SHA256})
+		staged.UploadedAt = now
+		rel.Artifacts[artifact] = staged
+	}
+
+	apiErr, err := releases.update(func(list []Release) ([]Release, *modError) {
+		return append(list, rel), nil
+	})
+	if apiErr != nil || err != nil {
+		removeCopied()
+		return Release{}, apiErr, err
+	}
+	// Если публикация не удалась, черновик остается: его можно
+	// опубликовать повторно из /admin/api/releases
+	published, apiErr, err := l.publishRelease(rel.ID)
+	if apiErr != nil || err != nil {
+		return Release{}, apiErr, err
+	}
+
+	if _, err := updateStaging(func(build *StagingBuild) {
+		build.PromotedRelease, build.PromotedAt = published.ID, published.PublishedAt
+	}); err != nil {
+		l.logError("Ошибка сохранения тестового канала: %v", err)
+	}
+	return published, nil, nil
+}
+
+// Проверка, что тестовый канал включен; при отказе ответ уже записан
+func rejectWithoutStaging(w http.ResponseWriter, r *http.Request) bool {
+	if currentConfig().StagingDir == "" {
+		writeError(w, r, http.StatusNotFound, ErrCodeUnknownChannel, stagingChannel)
+		return true
+	}
+	return false
+}
+
+func (l *Logger) adminGetStagingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🧪", "/admin/api/staging", func() {
+		if rejectWithoutStaging(w, r) {
+			return
+		}
+		json.NewEncoder(w).Encode(currentStaging())
+	})
+}
+
+// Описание изменений тестового канала; уйдет в релиз при продвижении
+func (l *Logger) adminSetStagingChangelogHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🧪", "/admin/api/staging/changelog", func() {
+		if rejectWithoutStaging(w, r) {
+			return
+		}
+		var req StagingChangelogRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+
+		stagingMu.Lock()
+		defer stagingMu.Unlock()
+		build, err := updateStaging(func(build *StagingBuild) { build.Changelog = req.Changelog })
+		if err != nil {
+			l.logError("Ошибка сохранения тестового канала: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		json.NewEncoder(w).Encode(build)
+		l.logSuccess("Обновлено описание изменений тестового канала")
+	})
+}
+
+// Загрузка сборки в тестовый канал вместе с ее версией. Версия — semver
+// или patch/minor/major от текущей в канале; с ?force=true принимается и
+// версия не новее текущей.
+func (l *Logger) adminUploadStagingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🧪", "/admin/api/staging/{artifact}/{version}", func() {
+		if rejectWithoutStaging(w, r) {
+			return
+		}
+		cfg := currentConfig()
+		artifact, version := r.PathValue("artifact"), r.PathValue("version")
+		filename, ok := artifactFilename(cfg, artifact)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+		if !isVersionOrBump(version) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		stagingMu.Lock()
+		defer stagingMu.Unlock()
+		_, current := currentStaging().artifact(cfg, artifact)
+		version, apiErr := resolveVersion(artifact, version, current, r.URL.Query().Get("force") == "true")
+		if apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+		target := filepath.Join(cfg.StagingDir, filename)
+		file, ok := l.receiveUpload(w, r, target)
+		if !ok {
+			return
+		}
+		l.adoptBlob(BlobRef{Path: target, Channel: stagingChannel, Artifact: artifact, Version: version}, file)
+		clientIndex.Remember(target, file)
+
+		build, err := updateStaging(func(build *StagingBuild) {
+			build.Artifacts[artifact] = ReleaseArtifact{Size: file.Size, Hash: file.MD5, SHA256: file.SHA256, UploadedAt: time.Now().UTC()}
+			if artifact == "launcher" {
+				build.LauncherVersion = version
+			} else {
+				build.GameVersion = version
+			}
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения тестового канала: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		eventHub.PublishVersion()
+
+		json.NewEncoder(w).Encode(build)
+		l.logSuccess("В тестовый канал загружен %s %s (%d bytes, хэш: %s)", artifact, version, file.Size, file.MD5)
+	})
+}
+
+// Снятие сборки с тестового канала: артефакт снова отдается из основного
+func (l *Logger) adminDeleteStagingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🧪", "/admin/api/staging/{artifact}", func() {
+		if rejectWithoutStaging(w, r) {
+			return
+		}
+		cfg := currentConfig()
+		artifact := r.PathValue("artifact")
+		filename, ok := artifactFilename(cfg, artifact)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+
+		stagingMu.Lock()
+		defer stagingMu.Unlock()
+		if _, err := updateStaging(func(build *StagingBuild) { delete(build.Artifacts, artifact) }); err != nil {
+			l.logError("Ошибка сохранения тестового канала: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		os.Remove(filepath.Join(cfg.StagingDir, filename))
+		if err := blobs.Release(filepath.Join(cfg.StagingDir, filename)); err != nil {
+			l.logError("Ошибка сохранения хранилища блобов: %v", err)
+		}
+		eventHub.PublishVersion()
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Сборка %s снята с тестового канала", artifact)
+	})
+}
+
+// Продвижение проверенных сборок тестового канала в основной
+func (l *Logger) adminPromoteStagingHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "🧪", "/admin/api/staging/promote", func() {
+		if rejectWithoutStaging(w, r) {
+			return
+		}
+		var req StagingPromoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+
+		rel, apiErr, err := l.promoteStaging(req.Announce, req.Force)
+		if err != nil {
+			l.logError("Ошибка продвижения тестового канала: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeModError(w, r, apiErr)
+			return
+		}
+
+		json.NewEncoder(w).Encode(rel)
+		l.logSuccess("Тестовый канал продвинут релизом %s: лаунчер=%s, игра=%s", rel.ID, rel.LauncherVersion, rel.GameVersion)
+	})
+}
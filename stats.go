@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Счетчики скачиваний с момента запуска сервера. С REDIS_URL итоги
+// (скачивания, ошибки, байты) общие для всех экземпляров и копятся с
+// первого запуска, открытые потоки — по-прежнему свои у каждого.
+type DownloadStats struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	artifacts map[string]*ArtifactStats
+}
+
+type ArtifactStats struct {
+	// Открытые сейчас потоки скачивания
+	Active         int64      `json:"active"`
+	Downloads      int64      `json:"downloads"`
+	Failed         int64      `json:"failed"`
+	BytesSent      int64      `json:"bytes_sent"`
+	LastDownloadAt *time.Time `json:"last_download_at,omitempty"`
+}
+
+type StatsResponse struct {
+	StartedAt time.Time                `json:"started_at"`
+	Uptime    string                   `json:"uptime"`
+	Downloads map[string]ArtifactStats `json:"downloads"`
+	Streams   DownloadStreams          `json:"streams"`
+	Telemetry TelemetrySummary         `json:"telemetry"`
+	// Итоги по отчетам лаунчеров о скачиваниях
+	Reports []DownloadReportStats `json:"reports"`
+	// Просмотры и реакции новостей, от самых читаемых
+	News []NewsEngagementStats `json:"news"`
+}
+
+var downloadStats = &DownloadStats{
+	startedAt: time.Now(),
+	artifacts: make(map[string]*ArtifactStats),
+}
+
+// Счетчики артефакта (под блокировкой)
+func (s *DownloadStats) artifact(name string) *ArtifactStats {
+	stats, exists := s.artifacts[name]
+	if !exists {
+		stats = &ArtifactStats{}
+		s.artifacts[name] = stats
+	}
+	return stats
+}
+
+// Начало отдачи файла; каждому Begin соответствует один Record
+func (s *DownloadStats) Begin(artifact string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifact(artifact).Active++
+}
+
+// Ответ без тела (HEAD, 304): поток закрыт, скачивания не было
+func (s *DownloadStats) Skip(artifact string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.artifact(artifact)
+	stats.Active = max(stats.Active-1, 0)
+}
+
+// Учет завершенного (или оборванного) скачивания
+func (s *DownloadStats) Record(artifact string, bytes int64, ok bool) {
+	now := time.Now()
+	if sharedState != nil {
+		sharedState.recordDownload(artifact, bytes, ok, now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.artifact(artifact)
+	stats.Active = max(stats.Active-1, 0)
+	stats.BytesSent += bytes
+	if !ok {
+		stats.Failed++
+		return
+	}
+	stats.Downloads++
+	stats.LastDownloadAt = &now
+}
+
+func (s *DownloadStats) Snapshot() StatsResponse {
+	s.mu.Lock()
+	response := StatsResponse{
+		StartedAt: s.startedAt,
+		Uptime:    time.Since(s.startedAt).Round(time.Second).String(),
+		Downloads: make(map[string]ArtifactStats, len(s.artifacts)),
+	}
+	for name, stats := range s.artifacts {
+		response.Downloads[name] = *stats
+	}
+	s.mu.Unlock()
+
+	if sharedState != nil {
+		for name, totals := range sharedState.downloadTotals() {
+			totals.Active = response.Downloads[name].Active
+			response.Downloads[name] = totals
+		}
+	}
+	return response
+}
+
+// Итоги скачиваний в Redis: один хэш с полями <артефакт>:<счетчик>
+func (c *RedisClient) recordDownload(artifact string, bytes int64, ok bool, now time.Time) {
+	key := c.key("downloads")
+	commands := [][]string{{"HINCRBY", key, artifact + ":bytes_sent", strconv.FormatInt(bytes, 10)}}
+	if ok {
+		commands = append(commands,
+			[]string{"HINCRBY", key, artifact + ":downloads", "1"},
+			[]string{"HSET", key, artifact + ":last_download_at", strconv.FormatInt(now.UnixMilli(), 10)})
+	} else {
+		commands = append(commands, []string{"HINCRBY", key, artifact + ":failed", "1"})
+	}
+	for _, args := range commands {
+		if _, err := c.Do(args...); err != nil {
+			c.logError("downloads", err)
+			return
+		}
+	}
+}
+
+func (c *RedisClient) downloadTotals() map[string]ArtifactStats {
+	reply, err := c.Do("HGETALL", c.key("downloads"))
+	if err != nil {
+		c.logError("downloads", err)
+		return nil
+	}
+	totals := make(map[string]ArtifactStats)
+	for field, value := range redisHash(reply) {
+		i := strings.LastIndex(field, ":")
+		if i < 0 {
+			continue
+		}
+		name, n := field[:i], redisInt(value)
+		stats := totals[name]
+		switch field[i+1:] {
+		case "downloads":
+			stats.Downloads = n
+		case "failed":
+			stats.Failed = n
+		case "bytes_sent":
+			stats.BytesSent = n
+		case "last_download_at":
+			at := time.UnixMilli(n)
+			stats.LastDownloadAt = &at
+		}
+		totals[name] = stats
+	}
+	return totals
+}
+
+// Открытые потоки скачивания по артефактам
+func (s *DownloadStats) ActiveStreams() (int64, map[string]int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total int64
+	streams := make(map[string]int64)
+	for name, stats := range s.artifacts {
+		if stats.Active > 0 {
+			streams[name] = stats.Active
+			total += stats.Active
+		}
+	}
+	return total, streams
+}
+
+// Сводка по телеметрии лаунчеров с момента запуска сервера. Сырые
+// события лежат в DATA_DIR/telemetry для подробного анализа.
+type TelemetryStats struct {
+	mu              sync.Mutex
+	events          map[string]int64
+	os              map[string]int64
+	downloadCount   int64
+	downloadTotalMS int64
+}
+
+type TelemetrySummary struct {
+	Events        map[string]int64 `json:"events"`
+	OS            map[string]int64 `json:"os"`
+	AvgDownloadMS int64            `json:"avg_download_ms"`
+}
+
+var telemetryStats = &TelemetryStats{
+	events: make(map[string]int64),
+	os:     make(map[string]int64),
+}
+
+func (s *TelemetryStats) Record(batch TelemetryBatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, event := range batch.Events {
+		s.events[event.Type]++
+		s.os[batch.OS]++
+		if event.Type == TelemetryDownloadCompleted && event.DurationMS > 0 {
+			s.downloadCount++
+			s.downloadTotalMS += event.DurationMS
+		}
+	}
+}
+
+func (s *TelemetryStats) Snapshot() TelemetrySummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := TelemetrySummary{
+		Events: make(map[string]int64, len(s.events)),
+		OS:     make(map[string]int64, len(s.os)),
+	}
+	for name, count := range s.events {
+		summary.Events[name] = count
+	}
+	for name, count := range s.os {
+		summary.OS[name] = count
+	}
+	if s.downloadCount > 0 {
+		summary.AvgDownloadMS = s.downloadTotalMS / s.downloadCount
+	}
+	return summary
+}
+
+// Статистика скачиваний для админки
+func (l *Logger) adminStatsHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeStatsRead, "📊", "/admin/api/stats", func() {
+		response := downloadStats.Snapshot()
+		response.Streams = downloadLimiter.Snapshot(currentConfig())
+		response.Telemetry = telemetryStats.Snapshot()
+		response.Reports = downloadReports.Snapshot()
+		news, err := newsEngagementStats()
+		if err != nil {
+			l.logError("Ошибка загрузки новостей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeNewsLoad, err)
+			return
+		}
+		response.News = news
+		json.NewEncoder(w).Encode(response)
+	})
+}
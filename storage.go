@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// Атомарная запись файла: сначала во временный файл рядом, затем rename
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Чтение JSON-файла; отсутствующий файл не считается ошибкой
+func loadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func saveJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Список в JSON-файле DATA_DIR под одной блокировкой. Хранилища встраивают
+// его и добавляют свои методы поверх items под mu.
+type jsonListStore[T any] struct {
+	mu    sync.Mutex
+	items []T
+	// Путь к файлу берется из текущей конфигурации при каждой записи
+	file func() string
+}
+
+func (s *jsonListStore[T]) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(s.file(), &s.items)
+}
+
+func (s *jsonListStore[T]) List() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.items)
+}
+
+// Изменение списка под блокировкой: fn работает с глубокой копией и
+// возвращает ошибку API (код и аргументы), если изменение недопустимо.
+// Копия заменяет список только после записи, так что при ошибке диска
+// память и файл не расходятся, а читатели List не видят изменений раньше.
+func (s *jsonListStore[T]) update(fn func(list []T) ([]T, *modError)) (*modError, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := cloneJSON(s.items)
+	if err != nil {
+		return nil, err
+	}
+	next, apiErr := fn(list)
+	if apiErr != nil {
+		return apiErr, nil
+	}
+	if err := s.save(next); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// Глубокая копия через JSON: у элементов бывают вложенные срезы и карты,
+// а в файл они все равно пишутся как JSON, так что копия не теряет полей
+func cloneJSON[T any](v T) (T, error) {
+	var clone T
+	data, err := json.Marshal(v)
+	if err != nil {
+		return clone, err
+	}
+	err = json.Unmarshal(data, &clone)
+	return clone, err
+}
+
+// Запись списка и замена им текущего; вызывается под mu
+func (s *jsonListStore[T]) save(items []T) error {
+	if err := saveJSONFile(s.file(), items); err != nil {
+		return err
+	}
+	s.items = items
+	return nil
+}
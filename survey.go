@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Опрос о железе: лаунчер с согласия игрока присылает сведения о системе,
+// а админка видит распределения — сколько игроков потянут новые
+// минимальные требования. От установки хранится только последний ответ
+// под хэшем ID клиента, без адреса и аккаунта.
+type HardwareSurvey struct {
+	ClientID        string `json:"client_id"`
+	LauncherVersion string `json:"launcher_version"`
+	GameVersion     string `json:"game_version,omitempty"`
+	OS              string `json:"os"`
+	OSVersion       string `json:"os_version,omitempty"`
+	Arch            string `json:"arch,omitempty"`
+	CPU             string `json:"cpu,omitempty"`
+	Cores           int    `json:"cores,omitempty"`
+	RAMMB           int    `json:"ram_mb,omitempty"`
+	GPU             string `json:"gpu,omitempty"`
+	VRAMMB          int    `json:"vram_mb,omitempty"`
+	// Разрешение основного монитора, например 1920x1080
+	Resolution string `json:"resolution,omitempty"`
+}
+
+// Ответ установки в DATA_DIR/hardware_survey.json; вместо ID клиента
+// хранится его хэш, как в журнале телеметрии
+type HardwareReport struct {
+	HardwareSurvey
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Доля установок с данным значением
+type SurveyShare struct {
+	Value   string  `json:"value"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// Сколько установок проходят требования из запроса
+type SurveyRequirements struct {
+	MinCores  int     `json:"min_cores,omitempty"`
+	MinRAMMB  int     `json:"min_ram_mb,omitempty"`
+	MinVRAMMB int     `json:"min_vram_mb,omitempty"`
+	Matching  int     `json:"matching"`
+	Percent   float64 `json:"percent"`
+}
+
+type HardwareSurveyReport struct {
+	Reports      int                 `json:"reports"`
+	Since        time.Time           `json:"since"`
+	GameVersion  string              `json:"game_version,omitempty"`
+	OS           []SurveyShare       `json:"os"`
+	OSVersion    []SurveyShare       `json:"os_version"`
+	Arch         []SurveyShare       `json:"arch"`
+	CPU          []SurveyShare       `json:"cpu"`
+	Cores        []SurveyShare       `json:"cores"`
+	RAM          []SurveyShare       `json:"ram"`
+	GPU          []SurveyShare       `json:"gpu"`
+	VRAM         []SurveyShare       `json:"vram"`
+	Resolution   []SurveyShare       `json:"resolution"`
+	Requirements *SurveyRequirements `json:"requirements,omitempty"`
+}
+
+type HardwareSurveyResponse struct {
+	Accepted bool `json:"accepted"`
+}
+
+// Группы памяти в гигабайтах: 8 ГБ — это и 7.9 ГБ, которые видит
+// система с встроенной видеокартой
+var (
+	surveyRAMBuckets  = []int{2, 4, 6, 8, 12, 16, 24, 32, 64}
+	surveyVRAMBuckets = []int{1, 2, 3, 4, 6, 8, 12, 16, 24}
+)
+
+type HardwareSurveyStore struct {
+	mu      sync.Mutex
+	reports map[string]HardwareReport
+}
+
+var hardwareSurvey = &HardwareSurveyStore{}
+
+func hardwareSurveyFile() string {
+	return filepath.Join(currentConfig().DataDir, "hardware_survey.json")
+}
+
+func (s *HardwareSurveyStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports = make(map[string]HardwareReport)
+	return loadJSONFile(hardwareSurveyFile(), &s.reports)
+}
+
+// Ответ установки заменяет прежний; ответы старше maxAge удаляются
+func (s *HardwareSurveyStore) Submit(report HardwareReport, maxAge time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client := telemetryClient(report.ClientID)
+	report.ClientID = client
+	next := make(map[string]HardwareReport, len(s.reports)+1)
+	for key, existing := range s.reports {
+		if maxAge <= 0 || report.SubmittedAt.Sub(existing.SubmittedAt) < maxAge {
+			next[key] = existing
+		}
+	}
+	next[client] = report
+	return s.save(next)
+}
+
+// Ответ установки для выгрузки данных игрока
+func (s *HardwareSurveyStore) Get(clientID string) (HardwareReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report, ok := s.reports[telemetryClient(clientID)]
+	return report, ok
+}
+
+// Удаление ответа установки при удалении аккаунта
+func (s *HardwareSurveyStore) Forget(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client := telemetryClient(clientID)
+	if _, ok := s.reports[client]; !ok {
+		return nil
+	}
+	next := make(map[string]HardwareReport, len(s.reports))
+	for key, report := range s.reports {
+		if key != client {
+			next[key] = report
+		}
+	}
+	return s.save(next)
+}
+
+func (s *HardwareSurveyStore) save(next map[string]HardwareReport) error {
+	if err := saveJSONFile(hardwareSurveyFile(), next); err != nil {
+		return err
+	}
+	s.reports = next
+	return nil
+}
+
+// Распределения по ответам не старше since; пустая версия игры — все
+func (s *HardwareSurveyStore) Report(since time.Time, gameVersion string, requirements *SurveyRequirements) HardwareSurveyReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]map[string]int)
+	count := func(dimension, value string) {
+		if value == "" {
+			value = "unknown"
+		}
+		if counts[dimension] == nil {
+			counts[dimension] = make(map[string]int)
+		}
+		counts[dimension][value]++
+	}
+
+	report := HardwareSurveyReport{Since: since, GameVersion: gameVersion, Requirements: requirements}
+	for _, entry := range s.reports {
+		if entry.SubmittedAt.Before(since) || (gameVersion != "" && entry.GameVersion != gameVersion) {
+			continue
+		}
+		report.Reports++
+		count("os", entry.OS)
+		count("os_version", strings.TrimSpace(entry.OS+" "+entry.OSVersion))
+		count("arch", entry.Arch)
+		count("cpu", entry.CPU)
+		count("cores", surveyCount(entry.Cores))
+		count("ram", surveyBucket(entry.RAMMB, surveyRAMBuckets))
+		count("gpu", entry.GPU)
+		count("vram", surveyBucket(entry.VRAMMB, surveyVRAMBuckets))
+		count("resolution", entry.Resolution)
+		if requirements != nil && entry.Cores >= requirements.MinCores &&
+			entry.RAMMB >= requirements.MinRAMMB && entry.VRAMMB >= requirements.MinVRAMMB {
+			requirements.Matching++
+		}
+	}
+
+	shares := func(dimension string) []SurveyShare {
+		list := []SurveyShare{}
+		for value, n := range counts[dimension] {
+			list = append(list, SurveyShare{Value: value, Count: n, Percent: surveyPercent(n, report.Reports)})
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Value < list[j].Value
+		})
+		return list
+	}
+	report.OS, report.OSVersion, report.Arch = shares("os"), shares("os_version"), shares("arch")
+	report.CPU, report.Cores = shares("cpu"), shares("cores")
+	report.RAM, report.GPU, report.VRAM = shares("ram"), shares("gpu"), shares("vram")
+	report.Resolution = shares("resolution")
+	if requirements != nil {
+		requirements.Percent = surveyPercent(requirements.Matching, report.Reports)
+	}
+	return report
+}
+
+func surveyPercent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*1000/float64(total)) / 10
+}
+
+func surveyCount(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// Наименьшая группа, в которую помещается объем памяти
+func surveyBucket(mb int, bucketsGB []int) string {
+	if mb <= 0 {
+		return ""
+	}
+	for _, bucket := range bucketsGB {
+		if mb <= bucket*1024 {
+			return fmt.Sprintf("%d GB", bucket)
+		}
+	}
+	return fmt.Sprintf("%d+ GB", bucketsGB[len(bucketsGB)-1])
+}
+
+// Названия процессоров и видеокарт у разных драйверов отличаются лишними
+// пробелами; без нормализации одна модель разбилась бы на несколько строк
+func normalizeSurveyField(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// Проверка и нормализация ответа
+func (s *HardwareSurvey) validate() error {
+	for _, field := range []*string{&s.LauncherVersion, &s.GameVersion, &s.OS, &s.OSVersion, &s.Arch, &s.CPU, &s.GPU, &s.Resolution} {
+		*field = normalizeSurveyField(*field)
+		if len(*field) > maxTelemetryField {
+			return errors.New("слишком длинное поле")
+		}
+	}
+	if s.ClientID == "" || len(s.ClientID) > maxTelemetryField {
+		return errors.New("client_id")
+	}
+	if s.LauncherVersion == "" || s.OS == "" {
+		return errors.New("launcher_version, os")
+	}
+	if s.Cores < 0 || s.RAMMB < 0 || s.VRAMMB < 0 {
+		return errors.New("cores, ram_mb, vram_mb")
+	}
+	return nil
+}
+
+// Ответ лаунчера на опрос о железе
+func (l *Logger) hardwareSurveyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🖥️", "/api/survey/hardware", func() {
+		cfg := currentConfig()
+		if !cfg.HardwareSurveyEnabled {
+			writeError(w, r, http.StatusForbidden, ErrCodeHardwareSurveyDisabled)
+			return
+		}
+
+		var survey HardwareSurvey
+		if err := json.NewDecoder(r.Body).Decode(&survey); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		if survey.ClientID == "" {
+			survey.ClientID = launcherClientID(r)
+		}
+		if err := survey.validate(); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		report := HardwareReport{HardwareSurvey: survey, SubmittedAt: time.Now().UTC()}
+		if err := hardwareSurvey.Submit(report, cfg.HardwareSurveyMaxAge); err != nil {
+			l.logError("Ошибка сохранения опроса о железе: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(HardwareSurveyResponse{Accepted: true})
+		l.logSuccess("Принят опрос о железе: %s, %d MB, %s", survey.OS, survey.RAMMB, survey.GPU)
+	})
+}
+
+// Распределения железа: /admin/api/hardware-survey?days=30&game_version=1.4.0
+// и, чтобы прикинуть требования, &min_ram_mb=8192&min_cores=4&min_vram_mb=2048
+func (l *Logger) adminHardwareSurveyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeStatsRead, "🖥️", "/admin/api/hardware-survey", func() {
+		query := r.URL.Query()
+		number := func(name string) (int, bool) {
+			value := query.Get(name)
+			if value == "" {
+				return 0, true
+			}
+			n, err := strconv.Atoi(value)
+			return n, err == nil && n >= 0
+		}
+		days, ok := number("days")
+		var requirements SurveyRequirements
+		for name, target := range map[string]*int{"min_cores": &requirements.MinCores, "min_ram_mb": &requirements.MinRAMMB, "min_vram_mb": &requirements.MinVRAMMB} {
+			var valid bool
+			if *target, valid = number(name); !valid {
+				ok = false
+			}
+		}
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		// Без days — все ответы, которые еще хранятся
+		var since time.Time
+		if maxAge := currentConfig().HardwareSurveyMaxAge; maxAge > 0 {
+			since = time.Now().UTC().Add(-maxAge)
+		}
+		if days > 0 {
+			since = time.Now().UTC().AddDate(0, 0, -days)
+		}
+		var filter *SurveyRequirements
+		if requirements != (SurveyRequirements{}) {
+			filter = &requirements
+		}
+		json.NewEncoder(w).Encode(hardwareSurvey.Report(since, query.Get("game_version"), filter))
+	})
+}
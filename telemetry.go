@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Типы событий, которые принимает сервер
+const (
+	TelemetryLaunchSucceeded   = "launch_succeeded"
+	TelemetryLaunchFailed      = "launch_failed"
+	TelemetryDownloadCompleted = "download_completed"
+	TelemetryDownloadFailed    = "download_failed"
+)
+
+var telemetryEventTypes = map[string]bool{
+	TelemetryLaunchSucceeded:   true,
+	TelemetryLaunchFailed:      true,
+	TelemetryDownloadCompleted: true,
+	TelemetryDownloadFailed:    true,
+}
+
+// Ограничения на строковые поля, чтобы в журнал не попадал мусор
+const (
+	maxTelemetryField = 256
+	maxTelemetryError = 1024
+)
+
+// Пачка событий от одного лаунчера. Сведения о системе передаются
+// один раз на пачку, а не в каждом событии.
+type TelemetryBatch struct {
+	ClientID        string             `json:"client_id"`
+	LauncherVersion string             `json:"launcher_version"`
+	GameVersion     string             `json:"game_version,omitempty"`
+	OS              string             `json:"os"`
+	Hardware        *TelemetryHardware `json:"hardware,omitempty"`
+	Events          []TelemetryEvent   `json:"events"`
+}
+
+type TelemetryHardware struct {
+	CPU   string `json:"cpu,omitempty"`
+	Cores int    `json:"cores,omitempty"`
+	RAMMB int    `json:"ram_mb,omitempty"`
+	GPU   string `json:"gpu,omitempty"`
+}
+
+type TelemetryEvent struct {
+	Type       string    `json:"type"`
+	Timestamp  time.Time `json:"timestamp"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Bytes      int64     `json:"bytes,omitempty"`
+	Artifact   string    `json:"artifact,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Запись в журнале телеметрии. Вместо ID клиента хранится его хэш.
+type TelemetryRecord struct {
+	ReceivedAt      time.Time          `json:"received_at"`
+	Client          string             `json:"client"`
+	LauncherVersion string             `json:"launcher_version"`
+	GameVersion     string             `json:"game_version,omitempty"`
+	OS              string             `json:"os"`
+	Hardware        *TelemetryHardware `json:"hardware,omitempty"`
+	TelemetryEvent
+}
+
+type TelemetryResponse struct {
+	Accepted int  `json:"accepted"`
+	Sampled  bool `json:"sampled"`
+}
+
+// Настройки телеметрии для лаунчера в /api/launcher-config
+type TelemetrySettings struct {
+	Enabled    bool    `json:"enabled"`
+	SampleRate float64 `json:"sample_rate"`
+}
+
+var telemetryMu sync.Mutex
+
+// Попадает ли клиент в выборку; решение стабильно для одного клиента
+func telemetrySampled(cfg *Config, clientID string) bool {
+	if clientID == "" {
+		return false
+	}
+	return float64(rolloutBucket("telemetry", clientID)) < cfg.TelemetrySampleRate*100
+}
+
+func telemetrySettings(cfg *Config, clientID string) TelemetrySettings {
+	return TelemetrySettings{
+		Enabled:    cfg.TelemetryEnabled && telemetrySampled(cfg, clientID),
+		SampleRate: cfg.TelemetrySampleRate,
+	}
+}
+
+// Проверка пачки по схеме
+func (b TelemetryBatch) validate(maxEvents int) error {
+	if b.ClientID == "" || len(b.ClientID) > maxTelemetryField {
+		return errors.New("client_id")
+	}
+	if b.LauncherVersion == "" || len(b.LauncherVersion) > maxTelemetryField {
+		return errors.New("launcher_version")
+	}
+	if b.OS == "" || len(b.OS) > maxTelemetryField || len(b.GameVersion) > maxTelemetryField {
+		return errors.New("os")
+	}
+	if b.Hardware != nil && (len(b.Hardware.CPU) > maxTelemetryField || len(b.Hardware.GPU) > maxTelemetryField ||
+		b.Hardware.Cores < 0 || b.Hardware.RAMMB < 0) {
+		return errors.New("hardware")
+	}
+	if len(b.Events) == 0 || len(b.Events) > maxEvents {
+		return fmt.Errorf("events: от 1 до %d", maxEvents)
+	}
+	for i, event := range b.Events {
+		if !telemetryEventTypes[event.Type] {
+			return fmt.Errorf("events[%d].type", i)
+		}
+		if event.Timestamp.IsZero() {
+			return fmt.Errorf("events[%d].timestamp", i)
+		}
+		if event.DurationMS < 0 || event.Bytes < 0 || len(event.Artifact) > maxTelemetryField || len(event.Error) > maxTelemetryError {
+			return fmt.Errorf("events[%d]", i)
+		}
+	}
+	return nil
+}
+
+// Хэш ID клиента, под которым его события лежат в журнале
+func telemetryClient(clientID string) string {
+	sum := sha256.Sum256([]byte(clientID))
+	return hex.EncodeToString(sum[:8])
+}
+
+func telemetryDir(cfg *Config) string {
+	return filepath.Join(cfg.DataDir, "telemetry")
+}
+
+// Дописывает события в DATA_DIR/telemetry/events_<дата>.jsonl
+func storeTelemetry(cfg *Config, batch TelemetryBatch, now time.Time) error {
+	client := telemetryClient(batch.ClientID)
+
+	dir := telemetryDir(cfg)
+	path := filepath.Join(dir, fmt.Sprintf("events_%s.jsonl", now.Format("2006-01-02")))
+
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, event := range batch.Events {
+		record := TelemetryRecord{
+			ReceivedAt:      now,
+			Client:          client,
+			LauncherVersion: batch.LauncherVersion,
+			GameVersion:     batch.GameVersion,
+			OS:              batch.OS,
+			Hardware:        batch.Hardware,
+			TelemetryEvent:  event,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Обход записей журнала телеметрии по файлам; fn получает строки файла
+// и отмечает, какие из них относятся к клиенту
+func scanTelemetry(cfg *Config, client string, fn func(path string, lines [][]byte, matched []bool) error) error {
+	paths, err := filepath.Glob(filepath.Join(telemetryDir(cfg), "events_*.jsonl"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := bytes.SplitAfter(data, []byte("\n"))
+		matched := make([]bool, len(lines))
+		for i, line := range lines {
+			var record struct {
+				Client string `json:"client"`
+			}
+			matched[i] = json.Unmarshal(line, &record) == nil && record.Client == client
+		}
+		if err := fn(path, lines, matched); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Запись событий клиента в w
+func exportTelemetry(cfg *Config, clientID string, w io.Writer) error {
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	return scanTelemetry(cfg, telemetryClient(clientID), func(_ string, lines [][]byte, matched []bool) error {
+		for i, line := range lines {
+			if matched[i] {
+				if _, err := w.Write(line); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Удаление событий клиента из журнала; число удаленных событий
+func purgeTelemetry(cfg *Config, clientID string) (int, error) {
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	removed := 0
+	err := scanTelemetry(cfg, telemetryClient(clientID), func(path string, lines [][]byte, matched []bool) error {
+		if !slices.Contains(matched, true) {
+			return nil
+		}
+		var kept bytes.Buffer
+		for i, line := range lines {
+			if matched[i] {
+				removed++
+			} else {
+				kept.Write(line)
+			}
+		}
+		return writeFileAtomic(path, kept.Bytes())
+	})
+	return removed, err
+}
+
+// Прием телеметрии от лаунчеров
+func (l *Logger) telemetryHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "📈", "/api/telemetry", func() {
+		cfg := currentConfig()
+		if !cfg.TelemetryEnabled {
+			writeError(w, r, http.StatusForbidden, ErrCodeTelemetryDisabled)
+			return
+		}
+
+		var batch TelemetryBatch
+		r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.TelemetryMaxBytes))
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		if err := batch.validate(cfg.TelemetryMaxEvents); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidTelemetry, err)
+			return
+		}
+
+		// Клиенты вне выборки получают успешный ответ, но события не хранятся
+		if !telemetrySampled(cfg, batch.ClientID) {
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(TelemetryResponse{Accepted: 0, Sampled: false})
+			return
+		}
+
+		if err := storeTelemetry(cfg, batch, time.Now().UTC()); err != nil {
+			l.logError("Ошибка записи телеметрии: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		telemetryStats.Record(batch)
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(TelemetryResponse{Accepted: len(batch.Events), Sampled: true})
+		l.logSuccess("Принято событий телеметрии: %d", len(batch.Events))
+	})
+}
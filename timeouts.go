@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Сервер с таймаутами на чтение заголовков и простаивающие соединения.
+// Общий WriteTimeout не ставим: скачивание игры может идти часами,
+// для него работает отдельный таймаут простоя (см. copyWithIdleTimeout
+// и idleTimeoutWriter).
+func newHTTPServer(addr string, handler http.Handler, readTimeout time.Duration) *http.Server {
+	cfg := currentConfig()
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    64 << 10,
+	}
+}
+
+// Ограничение времени JSON-обработчиков. При превышении клиент получает
+// 503 в стандартном формате ошибки.
+func withAPITimeout(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := json.Marshal(ErrorResponse{Error: APIError{
+			Code:      ErrCodeTimeout,
+			Message:   translate(r, "timeout"),
+			RequestID: requestID(r),
+		}})
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.TimeoutHandler(next, currentConfig().APITimeout, string(body)).ServeHTTP(w, r)
+	}
+}
+
+// Ошибка зависшего клиента: за отведенное время не принял ни одного блока
+var errClientStalled = errors.New("клиент не принимает данные")
+
+// Сколько отдаем через sendfile между сдвигами дедлайна записи. При
+// DOWNLOAD_IDLE_TIMEOUT 60s отключаются клиенты медленнее ~4 КБ/с.
+const sendfileChunk = 256 << 10
+
+// Ответ с таймаутом простоя для http.ServeContent: дедлайн записи
+// сдвигается перед каждым блоком, как в copyWithIdleTimeout. ReadFrom
+// отдает файл через ReadFrom соединения, поэтому на Linux работает
+// sendfile без копирования в пространство пользователя.
+type idleTimeoutWriter struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+	idle       time.Duration
+	status     int
+	written    int64
+	err        error
+}
+
+func newIdleTimeoutWriter(w http.ResponseWriter, idle time.Duration) *idleTimeoutWriter {
+	return &idleTimeoutWriter{ResponseWriter: w, controller: http.NewResponseController(w), idle: idle}
+}
+
+func (w *idleTimeoutWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *idleTimeoutWriter) extendDeadline() error {
+	if err := w.controller.SetWriteDeadline(time.Now().Add(w.idle)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// Ошибка записи запоминается: ServeContent ее не возвращает
+func (w *idleTimeoutWriter) fail(err error) error {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = errClientStalled
+	}
+	if w.err == nil {
+		w.err = err
+	}
+	return err
+}
+
+func (w *idleTimeoutWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if err := w.extendDeadline(); err != nil {
+		return 0, w.fail(err)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		return n, w.fail(err)
+	}
+	return n, nil
+}
+
+func (w *idleTimeoutWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	// ServeContent передает файл как io.LimitedReader; sendfile
+	// срабатывает, только если *os.File лежит в нем напрямую
+	rf, ok := w.ResponseWriter.(io.ReaderFrom)
+	limited, isLimited := src.(*io.LimitedReader)
+	if !ok || !isLimited {
+		return io.CopyBuffer(struct{ io.Writer }{w}, src, make([]byte, 64<<10))
+	}
+	file, isFile := limited.R.(*os.File)
+	if !isFile {
+		return io.CopyBuffer(struct{ io.Writer }{w}, src, make([]byte, 64<<10))
+	}
+
+	var total int64
+	for limited.N > 0 {
+		if err := w.extendDeadline(); err != nil {
+			return total, w.fail(err)
+		}
+		n, err := rf.ReadFrom(&io.LimitedReader{R: file, N: min(limited.N, sendfileChunk)})
+		limited.N -= n
+		total += n
+		w.written += n
+		if err != nil {
+			return total, w.fail(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return total, nil
+}
+
+func (w *idleTimeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Копирование файла клиенту с таймаутом простоя: перед каждым блоком
+// дедлайн записи сдвигается, поэтому медленный, но живой клиент скачает
+// файл целиком, а зависший будет отключен
+func copyWithIdleTimeout(w http.ResponseWriter, src io.Reader, idle time.Duration) (int64, error) {
+	controller := http.NewResponseController(w)
+	buf := make([]byte, 64<<10)
+	var written int64
+
+	defer controller.SetWriteDeadline(time.Time{})
+
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if err := controller.SetWriteDeadline(time.Now().Add(idle)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return written, err
+			}
+			m, err := w.Write(buf[:n])
+			written += int64(m)
+			if err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					return written, errClientStalled
+				}
+				return written, err
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Сертификат публичного слушателя. Перечитывается при перезагрузке
+// конфигурации, поэтому обновленный сертификат применяется без
+// перезапуска сервера.
+type certificateStore struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+var serverCertificate = &certificateStore{}
+
+func (s *certificateStore) Load(cfg *Config) error {
+	if cfg.TLSCertFile == "" {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cert = &cert
+	return nil
+}
+
+func (s *certificateStore) Get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, nil
+}
+
+// Настройки TLS общие для TCP и QUIC. HTTP/2 net/http включает сам,
+// когда у сервера есть TLSConfig.
+func serverTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: serverCertificate.Get,
+	}
+}
+
+// Экспериментальный HTTP/3 на том же порту по UDP. QUIC переживает
+// потерю пакетов и смену адреса клиента без обрыва скачивания, а
+// запросы к API не ждут друг друга в одном соединении.
+func newHTTP3Server(addr string, handler http.Handler) *http3.Server {
+	cfg := currentConfig()
+	return &http3.Server{
+		Addr:           addr,
+		Handler:        handler,
+		TLSConfig:      http3.ConfigureTLSConfig(serverTLSConfig()),
+		IdleTimeout:    cfg.IdleTimeout,
+		MaxHeaderBytes: 64 << 10,
+	}
+}
+
+// Объявление HTTP/3 в ответах по TCP через Alt-Svc: клиент с поддержкой
+// QUIC переходит на него со следующих запросов. Пока слушатель QUIC не
+// запущен, заголовок не добавляется.
+func withAltSvc(next http.Handler, server *http3.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			server.SetQUICHeaders(w.Header())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
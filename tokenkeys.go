@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ключ подписи токенов аккаунтов. Действующий ключ лежит в ACCOUNT_TOKEN_KEY,
+// выведенные из оборота — в DATA_DIR/account_token_keys/<kid>.pem; их токены
+// принимаются, пока не истечет срок ACCOUNT_TOKEN_TTL с момента замены.
+type AccountTokenKey struct {
+	ID        string     `json:"kid"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+
+	private ed25519.PrivateKey
+}
+
+// До какого момента принимаются токены ключа; для действующего — всегда
+func (k AccountTokenKey) usable(ttl time.Duration, now time.Time) bool {
+	return k.RetiredAt == nil || now.Before(k.RetiredAt.Add(ttl))
+}
+
+// Набор ключей: неизменяемый снимок, целиком заменяемый при ротации
+type accountTokenKeyring struct {
+	active  AccountTokenKey
+	retired []AccountTokenKey
+}
+
+func (k *accountTokenKeyring) all() []AccountTokenKey {
+	return append([]AccountTokenKey{k.active}, k.retired...)
+}
+
+var (
+	accountTokenKeys   atomic.Pointer[accountTokenKeyring]
+	accountTokenKeysMu sync.Mutex
+)
+
+// Открытый ключ в формате JWK (RFC 8037)
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func accountTokenKeysDir() string {
+	return filepath.Join(currentConfig().DataDir, "account_token_keys")
+}
+
+func accountTokenKeysFile() string {
+	return filepath.Join(currentConfig().DataDir, "account_token_keys.json")
+}
+
+// ID ключа — начало SHA-256 открытого ключа, поэтому не хранится отдельно
+func accountTokenKeyID(private ed25519.PrivateKey) string {
+	sum := sha256.Sum256(private.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Загрузка ключей; без ACCOUNT_TOKEN_KEY создается новый ключ. Ключи,
+// чьи токены уже истекли, удаляются.
+func (l *Logger) loadAccountTokenKey(cfg *Config) error {
+	accountTokenKeysMu.Lock()
+	defer accountTokenKeysMu.Unlock()
+
+	private, err := readEd25519PrivateKey(cfg.AccountTokenKey)
+	if errors.Is(err, os.ErrNotExist) {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		if err := writeEd25519PrivateKey(cfg.AccountTokenKey, private); err != nil {
+			return err
+		}
+		l.Printf("🔐 Создан ключ подписи токенов: %s", cfg.AccountTokenKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	var index []AccountTokenKey
+	if err := loadJSONFile(accountTokenKeysFile(), &index); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	ring := &accountTokenKeyring{active: AccountTokenKey{ID: accountTokenKeyID(private), CreatedAt: now, private: private}}
+	for _, key := range index {
+		if key.ID == ring.active.ID {
+			ring.active.CreatedAt = key.CreatedAt
+			continue
+		}
+		// Ключ без даты замены мог остаться после прерванной ротации
+		if key.RetiredAt == nil {
+			key.RetiredAt = &now
+		}
+		if key.private, err = readEd25519PrivateKey(filepath.Join(accountTokenKeysDir(), key.ID+".pem")); err != nil {
+			l.logError("Ключ подписи токенов %s пропущен: %v", key.ID, err)
+			continue
+		}
+		ring.retired = append(ring.retired, key)
+	}
+	return saveAccountTokenKeys(cfg, ring, now)
+}
+
+// Сохранение списка ключей с удалением тех, чьи токены уже истекли
+func saveAccountTokenKeys(cfg *Config, ring *accountTokenKeyring, now time.Time) error {
+	var expired []string
+	ring.retired = slices.DeleteFunc(ring.retired, func(key AccountTokenKey) bool {
+		if key.usable(cfg.AccountTokenTTL, now) {
+			return false
+		}
+		expired = append(expired, key.ID)
+		return true
+	})
+	if err := saveJSONFile(accountTokenKeysFile(), ring.all()); err != nil {
+		return err
+	}
+	accountTokenKeys.Store(ring)
+
+	for _, id := range expired {
+		os.Remove(filepath.Join(accountTokenKeysDir(), id+".pem"))
+	}
+	return nil
+}
+
+// Ротация: новый ключ становится действующим, прежний еще принимается.
+// Прежний ключ сохраняется до записи нового, чтобы сбой не терял токены.
+func rotateAccountTokenKey(cfg *Config, now time.Time) (AccountTokenKey, error) {
+	accountTokenKeysMu.Lock()
+	defer accountTokenKeysMu.Unlock()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return AccountTokenKey{}, err
+	}
+	current := accountTokenKeys.Load()
+	previous := current.active
+	previous.RetiredAt = &now
+	if err := writeEd25519PrivateKey(filepath.Join(accountTokenKeysDir(), previous.ID+".pem"), previous.private); err != nil {
+		return AccountTokenKey{}, err
+	}
+	if err := writeEd25519PrivateKey(cfg.AccountTokenKey, private); err != nil {
+		return AccountTokenKey{}, err
+	}
+
+	ring := &accountTokenKeyring{
+		active:  AccountTokenKey{ID: accountTokenKeyID(private), CreatedAt: now, private: private},
+		retired: append([]AccountTokenKey{previous}, current.retired...),
+	}
+	return ring.active, saveAccountTokenKeys(cfg, ring, now)
+}
+
+// Ключи, которыми можно проверять токены
+func usableAccountTokenKeys(cfg *Config, now time.Time) []AccountTokenKey {
+	return slices.DeleteFunc(accountTokenKeys.Load().all(), func(key AccountTokenKey) bool {
+		return !key.usable(cfg.AccountTokenTTL, now)
+	})
+}
+
+// Открытые ключи для проверки токенов аккаунтов, например игровыми серверами
+func (l *Logger) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔐", "/api/.well-known/jwks.json", func() {
+		set := JWKSet{Keys: []JWK{}}
+		for _, key := range usableAccountTokenKeys(currentConfig(), time.Now()) {
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(key.private.Public().(ed25519.PublicKey)),
+				Kid: key.ID,
+				Use: "sig",
+				Alg: "EdDSA",
+			})
+		}
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(set)
+	})
+}
+
+// Ключи подписи токенов для админки
+func (l *Logger) adminAccountTokenKeysHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeKeysManage, "🔐", "/admin/api/account-token-keys", func() {
+		json.NewEncoder(w).Encode(accountTokenKeys.Load().all())
+	})
+}
+
+// Ротация ключа подписи токенов без выхода игроков из аккаунтов
+func (l *Logger) adminRotateAccountTokenKeyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeKeysManage, "🔐", "/admin/api/account-token-keys/rotate", func() {
+		key, err := rotateAccountTokenKey(currentConfig(), time.Now().UTC())
+		if err != nil {
+			l.logError("Ошибка ротации ключа подписи токенов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(key)
+		actor, _ := adminActor(r, "")
+		l.logSuccess("Ключ подписи токенов заменен на %s (%s)", key.ID, actor)
+	})
+}
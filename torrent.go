@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Сведения о сгенерированном .torrent, хранятся рядом с ним в
+// DATA_DIR/torrents/game.json и позволяют не пересчитывать SHA-1
+// всех кусков при каждом запуске
+type TorrentMeta struct {
+	FileHash    string    `json:"file_hash"`
+	InfoHash    string    `json:"info_hash"`
+	Announce    string    `json:"announce,omitempty"`
+	WebSeed     string    `json:"web_seed"`
+	PieceLength int64     `json:"piece_length"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+var gameTorrent atomic.Pointer[TorrentMeta]
+
+func torrentPath() string {
+	return filepath.Join(currentConfig().DataDir, "torrents", "game.torrent")
+}
+
+func torrentMetaPath() string {
+	return filepath.Join(currentConfig().DataDir, "torrents", "game.json")
+}
+
+func loadTorrentMeta() error {
+	var meta *TorrentMeta
+	if err := loadJSONFile(torrentMetaPath(), &meta); err != nil {
+		return err
+	}
+	gameTorrent.Store(meta)
+	return nil
+}
+
+// Адрес встроенного трекера, пустой, если трекер выключен
+func torrentAnnounceURL(cfg *Config) string {
+	if !cfg.TorrentTracker {
+		return ""
+	}
+	return cfg.PublicURL + "/api/announce"
+}
+
+// Размер куска: не меньше 256 КБ и не больше ~2000 кусков на файл
+func torrentPieceLength(size int64) int64 {
+	length := int64(256 << 10)
+	for size/length > 2000 && length < 16<<20 {
+		length *= 2
+	}
+	return length
+}
+
+// Генерация .torrent для клиента игры. HTTP-скачивание прописано как
+// web seed (BEP 19), поэтому раздача работает, даже когда пиров нет.
+func buildGameTorrent(cfg *Config, path, fileHash string) (*TorrentMeta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	pieceLength := torrentPieceLength(info.Size())
+	var pieces bytes.Buffer
+	buf := make([]byte, pieceLength)
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			sum := sha1.Sum(buf[:n])
+			pieces.Write(sum[:])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	infoDict := map[string]interface{}{
+		"name":         filepath.Base(path),
+		"length":       info.Size(),
+		"piece length": pieceLength,
+		"pieces":       pieces.Bytes(),
+	}
+	encodedInfo, err := bencode(infoDict)
+	if err != nil {
+		return nil, err
+	}
+	infoHash := sha1.Sum(encodedInfo)
+
+	meta := &TorrentMeta{
+		FileHash:    fileHash,
+		InfoHash:    hex.EncodeToString(infoHash[:]),
+		Announce:    torrentAnnounceURL(cfg),
+		WebSeed:     cfg.PublicURL + "/api/download/game",
+		PieceLength: pieceLength,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	torrent := map[string]interface{}{
+		"info":          infoDict,
+		"url-list":      []interface{}{meta.WebSeed},
+		"created by":    "LOIL launcher server " + buildVersion,
+		"creation date": meta.CreatedAt.Unix(),
+		"comment":       "LOIL " + cfg.GameVersion,
+	}
+	if meta.Announce != "" {
+		torrent["announce"] = meta.Announce
+	}
+	data, err := bencode(torrent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFileAtomic(torrentPath(), data); err != nil {
+		return nil, err
+	}
+	if err := saveJSONFile(torrentMetaPath(), meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Перегенерирует .torrent, если изменились файл игры, PUBLIC_URL или трекер
+func (l *Logger) syncGameTorrent(cfg *Config, path, fileHash string) {
+	if existing := gameTorrent.Load(); existing != nil &&
+		existing.FileHash == fileHash &&
+		existing.Announce == torrentAnnounceURL(cfg) &&
+		existing.WebSeed == cfg.PublicURL+"/api/download/game" {
+		return
+	}
+
+	meta, err := buildGameTorrent(cfg, path, fileHash)
+	if err != nil {
+		l.logError("Ошибка генерации .torrent для %s: %v", path, err)
+		return
+	}
+	gameTorrent.Store(meta)
+	l.logSuccess("Сгенерирован .torrent для %s (info_hash: %s)", filepath.Base(path), meta.InfoHash)
+}
+
+// Обработчик скачивания .torrent клиента игры
+func (l *Logger) downloadGameTorrentHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧲", "/api/download/game.torrent", func() {
+		if l.rejectDuringMaintenance(w, r, "torrent") || l.rejectWithoutEntitlement(w, r, currentConfig().GameEntitlement, "torrent") {
+			return
+		}
+
+		meta := gameTorrent.Load()
+		data, err := os.ReadFile(torrentPath())
+		if meta == nil || os.IsNotExist(err) {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		if err != nil {
+			l.logError("Ошибка чтения .torrent: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileOpen)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-bittorrent")
+		w.Header().Set("Content-Disposition", contentDisposition(DispositionAttachment, currentConfig().GameClient+".torrent"))
+		w.Header().Set("X-Info-Hash", meta.InfoHash)
+		w.Write(data)
+		l.logSuccess("Отправлен .torrent (info_hash: %s)", meta.InfoHash)
+	})
+}
+
+// Кодирование в bencode. Поддерживаются только типы, которые нужны для
+// .torrent и ответов трекера.
+func bencode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := bencodeTo(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func bencodeTo(buf *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case string:
+		fmt.Fprintf(buf, "%d:%s", len(value), value)
+	case []byte:
+		fmt.Fprintf(buf, "%d:", len(value))
+		buf.Write(value)
+	case int:
+		fmt.Fprintf(buf, "i%de", value)
+	case int64:
+		fmt.Fprintf(buf, "i%de", value)
+	case []interface{}:
+		buf.WriteByte('l')
+		for _, item := range value {
+			if err := bencodeTo(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case map[string]interface{}:
+		// Ключи словаря обязаны идти в лексикографическом порядке
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('d')
+		for _, key := range keys {
+			bencodeTo(buf, key)
+			if err := bencodeTo(buf, value[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	default:
+		return fmt.Errorf("bencode: неподдерживаемый тип %T", v)
+	}
+	return nil
+}
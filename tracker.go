@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Встроенный HTTP-трекер (BEP 3, компактные ответы BEP 23). Раздает
+// только .torrent клиента игры, пиров хранит в памяти.
+type Tracker struct {
+	mu     sync.Mutex
+	swarms map[string]map[string]*trackerPeer
+}
+
+type trackerPeer struct {
+	id   string
+	ip   net.IP
+	port int
+	left int64
+	seen time.Time
+}
+
+var tracker = &Tracker{swarms: make(map[string]map[string]*trackerPeer)}
+
+const maxTrackerPeers = 50
+
+// Регистрирует пира и возвращает других участников раздачи
+func (t *Tracker) Announce(infoHash string, peer trackerPeer, event string, numWant int, ttl time.Duration) (peers []trackerPeer, complete, incomplete int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	swarm := t.swarms[infoHash]
+	if swarm == nil {
+		swarm = make(map[string]*trackerPeer)
+		t.swarms[infoHash] = swarm
+	}
+
+	// Пиры, которые давно не объявлялись, считаются ушедшими
+	for id, p := range swarm {
+		if peer.seen.Sub(p.seen) > ttl {
+			delete(swarm, id)
+		}
+	}
+
+	if event == "stopped" {
+		delete(swarm, peer.id)
+	} else {
+		swarm[peer.id] = &peer
+	}
+
+	for id, p := range swarm {
+		if p.left == 0 {
+			complete++
+		} else {
+			incomplete++
+		}
+		if id != peer.id && len(peers) < numWant {
+			peers = append(peers, *p)
+		}
+	}
+	return peers, complete, incomplete
+}
+
+// Ответ трекера с ошибкой; по протоколу это тоже 200 OK
+func writeTrackerFailure(w http.ResponseWriter, reason string) {
+	data, _ := bencode(map[string]interface{}{"failure reason": reason})
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(data)
+}
+
+// Обработчик announce-запросов торрент-клиентов
+func (l *Logger) trackerAnnounceHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🧲", "/api/announce", func() {
+		cfg := currentConfig()
+		if !cfg.TorrentTracker {
+			writeTrackerFailure(w, "tracker disabled")
+			return
+		}
+
+		query := r.URL.Query()
+		infoHash := hex.EncodeToString([]byte(query.Get("info_hash")))
+		if meta := gameTorrent.Load(); len(query.Get("info_hash")) != 20 || meta == nil || meta.InfoHash != infoHash {
+			writeTrackerFailure(w, "unknown info_hash")
+			return
+		}
+
+		peerID := query.Get("peer_id")
+		port, err := strconv.Atoi(query.Get("port"))
+		if len(peerID) != 20 || err != nil || port <= 0 || port > 65535 {
+			writeTrackerFailure(w, "invalid announce")
+			return
+		}
+		ip := net.ParseIP(getClientIP(r))
+		if ip == nil {
+			writeTrackerFailure(w, "invalid peer address")
+			return
+		}
+
+		left, _ := strconv.ParseInt(query.Get("left"), 10, 64)
+		numWant := maxTrackerPeers
+		if n, err := strconv.Atoi(query.Get("numwant")); err == nil && n >= 0 && n < numWant {
+			numWant = n
+		}
+
+		peers, complete, incomplete := tracker.Announce(infoHash, trackerPeer{
+			id:   peerID,
+			ip:   ip,
+			port: port,
+			left: left,
+			seen: time.Now(),
+		}, query.Get("event"), numWant, 2*cfg.TorrentAnnounceInterval)
+
+		response := map[string]interface{}{
+			"interval":     int64(cfg.TorrentAnnounceInterval / time.Second),
+			"min interval": int64(cfg.TorrentAnnounceInterval / 2 / time.Second),
+			"complete":     complete,
+			"incomplete":   incomplete,
+		}
+		if query.Get("compact") == "0" {
+			list := make([]interface{}, 0, len(peers))
+			for _, p := range peers {
+				list = append(list, map[string]interface{}{
+					"peer id": p.id,
+					"ip":      p.ip.String(),
+					"port":    p.port,
+				})
+			}
+			response["peers"] = list
+		} else {
+			var peers4, peers6 []byte
+			for _, p := range peers {
+				if ip4 := p.ip.To4(); ip4 != nil {
+					peers4 = binary.BigEndian.AppendUint16(append(peers4, ip4...), uint16(p.port))
+				} else {
+					peers6 = binary.BigEndian.AppendUint16(append(peers6, p.ip.To16()...), uint16(p.port))
+				}
+			}
+			response["peers"] = peers4
+			if len(peers6) > 0 {
+				response["peers6"] = peers6
+			}
+		}
+
+		data, err := bencode(response)
+		if err != nil {
+			l.logError("Ошибка кодирования ответа трекера: %v", err)
+			writeTrackerFailure(w, "internal error")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(data)
+		l.logSuccess("Announce от %s: сидов %d, личеров %d", ip, complete, incomplete)
+	})
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры TOTP (RFC 6238) по умолчанию, их понимают все приложения-аутентификаторы
+const (
+	totpIssuer = "LOIL"
+	totpPeriod = 30
+	totpDigits = 6
+	// Допустимое расхождение часов: по одному шагу в обе стороны
+	totpSkew = 1
+
+	recoveryCodesCount = 10
+
+	// Не больше 5 неверных кодов за 15 минут на аккаунт или ключ
+	maxCodeFailures   = 5
+	codeFailureWindow = 15 * time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// Код из приложения-аутентификатора или, для аккаунтов, код восстановления
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Коды восстановления показываются один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type AdminSessionResponse struct {
+	Session   string    `json:"session"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newTOTPSecret() string {
+	secret := make([]byte, 20)
+	rand.Read(secret)
+	return totpEncoding.EncodeToString(secret)
+}
+
+func totpURL(account, secret string) string {
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {totpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {strconv.Itoa(totpDigits)},
+		"period":    {strconv.Itoa(totpPeriod)},
+	}
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+account) + "?" + query.Encode()
+}
+
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// Проверка кода. Возвращает шаг времени совпавшего кода: код с шагом
+// не новее lastStep уже использован и повторно не принимается.
+func verifyTOTP(secret, code string, lastStep int64, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step > lastStep && subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// Коды восстановления вида 1a2b-3c4d; на диске только SHA-256
+func newRecoveryCodes() (codes, hashes []string) {
+	for range recoveryCodesCount {
+		id := randomID(4)
+		code := id[:4] + "-" + id[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(code)), "-", "")
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Проверка второго фактора аккаунта: TOTP или код восстановления,
+// который после использования сгорает. Меняет account, его нужно сохранить.
+func (a *Account) checkSecondFactor(code string, now time.Time) bool {
+	code = strings.TrimSpace(code)
+	if step, ok := verifyTOTP(a.TOTPSecret, code, a.TOTPLastStep, now); ok {
+		a.TOTPLastStep = step
+		return true
+	}
+	hash := hashRecoveryCode(code)
+	if i := slices.Index(a.RecoveryCodes, hash); i >= 0 {
+		a.RecoveryCodes = slices.Delete(slices.Clone(a.RecoveryCodes), i, i+1)
+		return true
+	}
+	return false
+}
+
+// Счетчик попыток: после limit неудач (или отправок) за окно действие
+// блокируется до конца окна
+type attemptLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	failures map[string]*attemptWindow
+}
+
+type attemptWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+var codeAttempts = &attemptLimiter{
+	limit:    maxCodeFailures,
+	window:   codeFailureWindow,
+	failures: make(map[string]*attemptWindow),
+}
+
+// Сколько ждать до следующей попытки; 0 — можно пробовать
+func (l *attemptLimiter) Blocked(subject string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.failures[subject]
+	if !ok {
+		return 0
+	}
+	if !now.Before(entry.resetAt) {
+		delete(l.failures, subject)
+		return 0
+	}
+	if entry.count < l.limit {
+		return 0
+	}
+	return entry.resetAt.Sub(now)
+}
+
+func (l *attemptLimiter) Fail(subject string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.failures[subject]
+	if !ok || !now.Before(entry.resetAt) {
+		entry = &attemptWindow{resetAt: now.Add(l.window)}
+		l.failures[subject] = entry
+	}
+	entry.count++
+}
+
+func (l *attemptLimiter) Reset(subject string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, subject)
+}
+
+// Отказ при исчерпанных попытках; при отказе ответ уже записан
+func (l *attemptLimiter) Reject(w http.ResponseWriter, r *http.Request, subject string) bool {
+	if apiErr := l.Check(subject); apiErr != nil {
+		writeModError(w, r, apiErr)
+		return true
+	}
+	return false
+}
+
+func (l *attemptLimiter) Check(subject string) *modError {
+	wait := l.Blocked(subject, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	return &modError{http.StatusTooManyRequests, ErrCodeTooManyAttempts, []interface{}{int(wait.Seconds()) + 1}}
+}
+
+// Проверка кода второго фактора аккаунта с учетом попыток. При отказе
+// ответ уже записан; при успехе использованный код сохранен.
+func (l *Logger) verifyAccountCode(w http.ResponseWriter, r *http.Request, account Account, code string) (Account, bool) {
+	account, apiErr := l.checkAccountCode(r, account, code)
+	if apiErr != nil {
+		writeModError(w, r, apiErr)
+		return account, false
+	}
+	return account, true
+}
+
+func (l *Logger) checkAccountCode(r *http.Request, account Account, code string) (Account, *modError) {
+	subject := "account:" + account.ID
+	if apiErr := codeAttempts.Check(subject); apiErr != nil {
+		return account, apiErr
+	}
+
+	now := time.Now()
+	updated, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+		if !a.TOTPEnabled {
+			return &modError{http.StatusConflict, ErrCodeTOTPNotEnrolled, nil}
+		}
+		if !a.checkSecondFactor(code, now) {
+			return &modError{http.StatusUnauthorized, ErrCodeInvalidTOTPCode, nil}
+		}
+		return nil
+	})
+	if err != nil {
+		l.logError("Ошибка сохранения аккаунтов: %v", err)
+		return account, &modError{http.StatusInternalServerError, ErrCodeInternal, nil}
+	}
+	if apiErr != nil {
+		if apiErr.code == ErrCodeInvalidTOTPCode {
+			codeAttempts.Fail(subject, now)
+			l.logError("Неверный код 2FA аккаунта %s с %s", account.Username, getClientIP(r))
+		}
+		return account, apiErr
+	}
+	codeAttempts.Reset(subject)
+	return updated, nil
+}
+
+// Начало подключения 2FA: секрет для приложения-аутентификатора.
+// Включается 2FA только после подтверждения кодом.
+func (l *Logger) accountTOTPEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔐", "/api/account/2fa/enroll", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		secret := newTOTPSecret()
+		_, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			if a.TOTPEnabled {
+				return &modError{http.StatusConflict, ErrCodeTOTPAlreadyEnabled, nil}
+			}
+			a.TOTPSecret = secret
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+		json.NewEncoder(w).Encode(TOTPEnrollResponse{Secret: secret, OTPAuthURL: totpURL(account.Username, secret)})
+	})
+}
+
+// Подтверждение подключения первым кодом; в ответе коды восстановления
+func (l *Logger) accountTOTPVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔐", "/api/account/2fa/verify", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req TOTPCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		subject := "account:" + account.ID
+		if codeAttempts.Reject(w, r, subject) {
+			return
+		}
+
+		now := time.Now()
+		codes, hashes := newRecoveryCodes()
+		_, apiErr, err := accounts.Update(account.ID, func(a *Account) *modError {
+			if a.TOTPEnabled {
+				return &modError{http.StatusConflict, ErrCodeTOTPAlreadyEnabled, nil}
+			}
+			if a.TOTPSecret == "" {
+				return &modError{http.StatusConflict, ErrCodeTOTPNotEnrolled, nil}
+			}
+			step, ok := verifyTOTP(a.TOTPSecret, strings.TrimSpace(req.Code), 0, now)
+			if !ok {
+				return &modError{http.StatusUnauthorized, ErrCodeInvalidTOTPCode, nil}
+			}
+			a.TOTPEnabled = true
+			a.TOTPLastStep = step
+			a.RecoveryCodes = hashes
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if apiErr != nil {
+			if apiErr.code == ErrCodeInvalidTOTPCode {
+				codeAttempts.Fail(subject, now)
+			}
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+		codeAttempts.Reset(subject)
+
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+		l.logSuccess("Аккаунт %s включил 2FA", account.Username)
+	})
+}
+
+// Новые коды восстановления взамен старых; нужен действующий код
+func (l *Logger) accountRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔐", "/api/account/2fa/recovery-codes", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req TOTPCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if _, ok := l.verifyAccountCode(w, r, account, req.Code); !ok {
+			return
+		}
+
+		codes, hashes := newRecoveryCodes()
+		if _, _, err := accounts.Update(account.ID, func(a *Account) *modError {
+			a.RecoveryCodes = hashes
+			return nil
+		}); err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+		l.logSuccess("Аккаунт %s обновил коды восстановления", account.Username)
+	})
+}
+
+// Отключение 2FA; нужен код из приложения или код восстановления
+func (l *Logger) accountTOTPDisableHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🔐", "/api/account/2fa/disable", func() {
+		account, ok := requireAccount(w, r)
+		if !ok {
+			return
+		}
+		var req TOTPCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if _, ok := l.verifyAccountCode(w, r, account, req.Code); !ok {
+			return
+		}
+
+		if _, _, err := accounts.Update(account.ID, func(a *Account) *modError {
+			a.TOTPSecret, a.TOTPEnabled, a.TOTPLastStep, a.RecoveryCodes = "", false, 0, nil
+			return nil
+		}); err != nil {
+			l.logError("Ошибка сохранения аккаунтов: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Аккаунт %s отключил 2FA", account.Username)
+	})
+}
+
+// Сессии 2FA админского API. Ключ с включенной 2FA работает только вместе
+// с заголовком X-Admin-Session, который выдается по коду из приложения.
+// Сессии живут в памяти: после перезапуска код нужно ввести заново.
+type adminSession struct {
+	keyID     string
+	expiresAt time.Time
+}
+
+var (
+	adminSessionsMu sync.Mutex
+	adminSessions   = make(map[string]adminSession)
+)
+
+func newAdminSession(keyID string, ttl time.Duration, now time.Time) (string, time.Time) {
+	token, hash := newAdminToken()
+	expiresAt := now.Add(ttl)
+
+	adminSessionsMu.Lock()
+	defer adminSessionsMu.Unlock()
+	for id, session := range adminSessions {
+		if !now.Before(session.expiresAt) {
+			delete(adminSessions, id)
+		}
+	}
+	adminSessions[hash] = adminSession{keyID: keyID, expiresAt: expiresAt}
+	return token, expiresAt
+}
+
+func validAdminSession(keyID, token string, now time.Time) bool {
+	sum := sha256.Sum256([]byte(token))
+	adminSessionsMu.Lock()
+	defer adminSessionsMu.Unlock()
+	session, ok := adminSessions[hex.EncodeToString(sum[:])]
+	return ok && session.keyID == keyID && now.Before(session.expiresAt)
+}
+
+// Сброс сессий ключа при отключении 2FA или отзыве ключа
+func dropAdminSessions(keyID string) {
+	adminSessionsMu.Lock()
+	defer adminSessionsMu.Unlock()
+	for id, session := range adminSessions {
+		if session.keyID == keyID {
+			delete(adminSessions, id)
+		}
+	}
+}
+
+// Второй фактор админского запроса. Ключу с 2FA нужна сессия. При
+// ADMIN_REQUIRE_2FA ключи без 2FA могут только подключить ее, а статический
+// ADMIN_TOKEN (подключить 2FA ему нельзя) — только управлять ключами.
+func checkAdminSecondFactor(w http.ResponseWriter, r *http.Request, key AdminKey, scope string) bool {
+	switch {
+	case key.TOTPEnabled:
+		if !validAdminSession(key.ID, r.Header.Get("X-Admin-Session"), time.Now()) {
+			writeError(w, r, http.StatusUnauthorized, ErrCodeTOTPRequired)
+			return false
+		}
+	case currentConfig().AdminRequire2FA && !(key.ID == staticAdminKey.ID && scope == ScopeKeysManage):
+		writeError(w, r, http.StatusForbidden, ErrCodeTOTPEnrollmentRequired)
+		return false
+	}
+	return true
+}
+
+// Подключение 2FA к ключу, которым выполнен запрос
+func (l *Logger) adminTOTPEnrollHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", false, "🔐", "/admin/api/2fa/enroll", func(key AdminKey) {
+		if key.ID == staticAdminKey.ID {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		secret := newTOTPSecret()
+		_, apiErr, err := adminKeys.Update(key.ID, func(k *AdminKey) *modError {
+			if k.TOTPEnabled {
+				return &modError{http.StatusConflict, ErrCodeTOTPAlreadyEnabled, nil}
+			}
+			k.TOTPSecret = secret
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения ключей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+		json.NewEncoder(w).Encode(TOTPEnrollResponse{Secret: secret, OTPAuthURL: totpURL("admin-"+key.Name, secret)})
+	})
+}
+
+// Проверка кода ключа: подтверждение подключения или выдача сессии
+func (l *Logger) verifyAdminCode(w http.ResponseWriter, r *http.Request, key AdminKey, enable bool) bool {
+	var req TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+		return false
+	}
+	subject := "admin:" + key.ID
+	if codeAttempts.Reject(w, r, subject) {
+		return false
+	}
+
+	now := time.Now()
+	_, apiErr, err := adminKeys.Update(key.ID, func(k *AdminKey) *modError {
+		switch {
+		case enable && k.TOTPEnabled:
+			return &modError{http.StatusConflict, ErrCodeTOTPAlreadyEnabled, nil}
+		case k.TOTPSecret == "" || (!enable && !k.TOTPEnabled):
+			return &modError{http.StatusConflict, ErrCodeTOTPNotEnrolled, nil}
+		}
+		step, ok := verifyTOTP(k.TOTPSecret, strings.TrimSpace(req.Code), k.TOTPLastStep, now)
+		if !ok {
+			return &modError{http.StatusUnauthorized, ErrCodeInvalidTOTPCode, nil}
+		}
+		k.TOTPEnabled = true
+		k.TOTPLastStep = step
+		return nil
+	})
+	if err != nil {
+		l.logError("Ошибка сохранения ключей: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+		return false
+	}
+	if apiErr != nil {
+		if apiErr.code == ErrCodeInvalidTOTPCode {
+			codeAttempts.Fail(subject, now)
+			l.logError("Неверный код 2FA ключа %s «%s» с %s", key.ID, key.Name, getClientIP(r))
+		}
+		writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+		return false
+	}
+	codeAttempts.Reset(subject)
+	return true
+}
+
+// Подтверждение подключения 2FA первым кодом
+func (l *Logger) adminTOTPVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", false, "🔐", "/admin/api/2fa/verify", func(key AdminKey) {
+		if !l.verifyAdminCode(w, r, key, true) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Ключ %s «%s» включил 2FA", key.ID, key.Name)
+	})
+}
+
+// Сессия для ключа с 2FA: POST /admin/api/2fa/session {"code": "123456"}
+func (l *Logger) adminSessionHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, "", false, "🔐", "/admin/api/2fa/session", func(key AdminKey) {
+		if !l.verifyAdminCode(w, r, key, false) {
+			return
+		}
+		token, expiresAt := newAdminSession(key.ID, currentConfig().Admin2FASessionTTL, time.Now().UTC())
+		json.NewEncoder(w).Encode(AdminSessionResponse{Session: token, ExpiresAt: expiresAt})
+		l.logSuccess("Открыта сессия 2FA ключа %s «%s»", key.ID, key.Name)
+	})
+}
+
+// Сброс 2FA ключа, например при потере телефона
+func (l *Logger) adminResetKeyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdminKey(w, r, ScopeKeysManage, true, "🔐", "/admin/api/keys/{id}/2fa", func(actor AdminKey) {
+		id := r.PathValue("id")
+		_, apiErr, err := adminKeys.Update(id, func(k *AdminKey) *modError {
+			if scope := actor.missingScope(*k); scope != "" {
+				return &modError{http.StatusForbidden, ErrCodeForbidden, []interface{}{scope}}
+			}
+			k.TOTPSecret, k.TOTPEnabled, k.TOTPLastStep = "", false, 0
+			return nil
+		})
+		if err != nil {
+			l.logError("Ошибка сохранения ключей: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeKeysSave)
+			return
+		}
+		if apiErr != nil {
+			writeError(w, r, apiErr.status, apiErr.code, apiErr.args...)
+			return
+		}
+		dropAdminSessions(id)
+
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("2FA ключа %s сброшена", id)
+	})
+}
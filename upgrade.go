@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Число публичных сокетов, переданных прежним процессом. Дескрипторы
+// идут с 3: публичные сокеты, админский, затем канал готовности.
+const upgradeFDsEnv = "LOIL_UPGRADE_FDS"
+
+// Сколько ждать готовности нового процесса; не дождались — обновление
+// отменяется, прежний процесс продолжает работу
+const upgradeStartTimeout = time.Minute
+
+// Закрывается, когда процесс передал сокеты новому и дорабатывает:
+// бесконечные потоки (/api/events) завершаются, клиенты переподключаются
+// уже к новому процессу
+var serverDraining = make(chan struct{})
+
+// Слушатели сервера: публичные и админский
+type serverListeners struct {
+	public []net.Listener
+	admin  net.Listener
+	// Канал, в который новый процесс сообщает о готовности
+	ready *os.File
+}
+
+// Сокеты от прежнего процесса при обновлении, иначе адреса из конфигурации
+func openServerListeners(cfg *Config) (*serverListeners, error) {
+	if os.Getenv(upgradeFDsEnv) != "" {
+		return inheritedListeners()
+	}
+
+	public, err := openListeners(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия адресов публичного API: %v", err)
+	}
+	admin, err := net.Listen("tcp", cfg.AdminAddr)
+	if err != nil {
+		for _, listener := range public {
+			listener.Close()
+		}
+		return nil, fmt.Errorf("ошибка открытия адреса админского API: %v", err)
+	}
+	return &serverListeners{public: public, admin: admin}, nil
+}
+
+func inheritedListeners() (*serverListeners, error) {
+	count, err := strconv.Atoi(os.Getenv(upgradeFDsEnv))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("некорректный %s=%q", upgradeFDsEnv, os.Getenv(upgradeFDsEnv))
+	}
+	os.Unsetenv(upgradeFDsEnv)
+
+	fileListener := func(fd int) (net.Listener, error) {
+		file := os.NewFile(uintptr(fd), "upgrade-"+strconv.Itoa(fd))
+		defer file.Close()
+		listener, err := net.FileListener(file)
+		if err != nil {
+			return nil, fmt.Errorf("дескриптор %d от прежнего процесса: %v", fd, err)
+		}
+		return listener, nil
+	}
+
+	listeners := &serverListeners{}
+	for fd := systemdListenFDsStart; fd <= systemdListenFDsStart+count; fd++ {
+		listener, err := fileListener(fd)
+		if err != nil {
+			listeners.Close()
+			return nil, err
+		}
+		if fd < systemdListenFDsStart+count {
+			listeners.public = append(listeners.public, listener)
+		} else {
+			listeners.admin = listener
+		}
+	}
+	listeners.ready = os.NewFile(uintptr(systemdListenFDsStart+count+1), "upgrade-ready")
+	return listeners, nil
+}
+
+func (s *serverListeners) Close() {
+	for _, listener := range s.public {
+		listener.Close()
+	}
+	if s.admin != nil {
+		s.admin.Close()
+	}
+}
+
+// Сообщение прежнему процессу, что новый принимает запросы
+func (s *serverListeners) notifyReady() {
+	if s.ready == nil {
+		return
+	}
+	s.ready.Write([]byte{1})
+	s.ready.Close()
+	s.ready = nil
+}
+
+// Запущенный сервер, которому можно заменить бинарник без остановки
+type upgradableServer struct {
+	listeners *serverListeners
+	public    *http.Server
+	admin     *http.Server
+	// Закрывается, когда прежний процесс дождался своих запросов
+	drained   chan struct{}
+	upgrading sync.Mutex
+}
+
+// Обновление бинарника по сигналу (SIGUSR2, как у nginx): новый процесс
+// запускается с теми же аргументами и сокетами, а прежний после его
+// готовности перестает принимать подключения и ждет завершения текущих
+// запросов — многогигабайтные скачивания не обрываются при деплое
+func (l *Logger) watchUpgradeSignal(server *upgradableServer) {
+	if len(upgradeSignals) == 0 {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, upgradeSignals...)
+
+	for range signals {
+		l.Println("🔁 Получен сигнал обновления, запускаем новый процесс...")
+		if err := l.upgrade(server); err != nil {
+			l.logError("Обновление без остановки не выполнено: %v", err)
+			continue
+		}
+		return
+	}
+}
+
+func (l *Logger) upgrade(server *upgradableServer) error {
+	if !server.upgrading.TryLock() {
+		return errors.New("обновление уже выполняется")
+	}
+	defer server.upgrading.Unlock()
+
+	cfg := currentConfig()
+	if cfg.TLSCertFile != "" && cfg.HTTP3 {
+		// Сокет QUIC не передается новому процессу
+		return errors.New("недоступно при включенном HTTP3")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, listener := range append(server.listeners.public, server.listeners.admin) {
+		filer, ok := listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("сокет %s нельзя передать", listener.Addr())
+		}
+		file, err := filer.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+	files = append(files, readyWriter)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(), upgradeFDsEnv+"="+strconv.Itoa(len(server.listeners.public)))
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("запуск %s: %v", executable, err)
+	}
+	// Копии дескрипторов у нового процесса; без нашей копии канал
+	// готовности закроется, если новый процесс завершится
+	for _, file := range files {
+		file.Close()
+	}
+	files = nil
+
+	started := make(chan error, 1)
+	go func() {
+		_, err := io.ReadFull(ready, make([]byte, 1))
+		started <- err
+	}()
+	select {
+	case err = <-started:
+	case <-time.After(upgradeStartTimeout):
+		err = fmt.Errorf("нет готовности за %v", upgradeStartTimeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("новый процесс не запустился: %v", err)
+	}
+	go cmd.Wait()
+
+	l.logSuccess("Новый процесс %d принимает запросы, дожидаемся текущих (до %v)", cmd.Process.Pid, cfg.UpgradeDrainTimeout)
+	go l.drain(server, cfg.UpgradeDrainTimeout)
+	return nil
+}
+
+// Завершение прежнего процесса после передачи сокетов
+func (l *Logger) drain(server *upgradableServer, timeout time.Duration) {
+	// Сокет в файловой системе теперь принадлежит новому процессу
+	for _, listener := range server.listeners.public {
+		if unix, ok := listener.(*net.UnixListener); ok {
+			unix.SetUnlinkOnClose(false)
+		}
+	}
+	close(serverDraining)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, srv := range []*http.Server{server.public, server.admin} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		l.logWarn("За %v запросы не завершились, оставшиеся подключения закрыты", timeout)
+	} else {
+		l.logSuccess("Все запросы завершены, прежний процесс останавливается")
+	}
+	close(server.drained)
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package main
+
+import "os"
+
+// Передать сокеты дочернему процессу на этих платформах нельзя
+var upgradeSignals []os.Signal
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Имя файла в каталоге клиентов для загружаемого артефакта
+func artifactFilename(cfg *Config, artifact string) (string, bool) {
+	switch artifact {
+	case "launcher":
+		return cfg.LauncherClient, true
+	case "game":
+		return cfg.GameClient, true
+	}
+	return "", false
+}
+
+// Данные события build_published для вебхуков
+type BuildPublishedEvent struct {
+	Artifact string `json:"artifact"`
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+	Hash     string `json:"hash"`
+	SHA256   string `json:"sha256"`
+}
+
+// Принятый файл: размер и хэши, посчитанные при приеме
+type uploadedFile struct {
+	Size   int64
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+// Прием тела запроса в файл. Файл пишется во временный и подменяет
+// старый только после проверки хэша из X-File-Hash, поэтому игроки
+// никогда не скачивают недописанный файл. С X-Upload-ID тело не читается:
+// берется файл завершенной загрузки по частям. Ошибки уже отправлены
+// клиенту, если ok == false.
+func (l *Logger) receiveUpload(w http.ResponseWriter, r *http.Request, target string) (uploadedFile, bool) {
+	dir := filepath.Dir(target)
+	name := filepath.Base(target)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		l.logError("Ошибка создания каталога %s: %v", dir, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+		return uploadedFile{}, false
+	}
+	if l.rejectLowDisk(w, r, dir) {
+		return uploadedFile{}, false
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		l.logError("Ошибка создания временного файла: %v", err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+		return uploadedFile{}, false
+	}
+	defer os.Remove(tmp.Name())
+
+	md5Hash, sha1Hash, sha256Hash := md5.New(), sha1.New(), sha256.New()
+	hashes := io.MultiWriter(md5Hash, sha1Hash, sha256Hash)
+	var chunked AdminUpload
+	var size int64
+	if id := r.Header.Get("X-Upload-ID"); id != "" {
+		tmp.Close()
+		unlock := lockAdminUpload(id)
+		defer unlock()
+		var ok bool
+		if chunked, ok = completedAdminUpload(w, r, id); !ok {
+			return uploadedFile{}, false
+		}
+		if err := takeAdminUpload(chunked, tmp.Name()); err != nil {
+			l.logError("Ошибка переноса загрузки по частям %s: %v", id, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return uploadedFile{}, false
+		}
+		defer func() {
+			if err := removeAdminUpload(id); err != nil {
+				l.logError("Ошибка сохранения загрузок по частям: %v", err)
+			}
+		}()
+		// Хэши для индекса и вебхуков считаются по перенесенному файлу
+		in, err := os.Open(tmp.Name())
+		if err == nil {
+			size, err = io.Copy(hashes, in)
+			in.Close()
+		}
+		if err != nil {
+			l.logError("Ошибка чтения файла %s: %v", name, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+			return uploadedFile{}, false
+		}
+	} else {
+		var err error
+		size, err = io.Copy(io.MultiWriter(tmp, hashes), r.Body)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			l.logError("Ошибка приема файла %s: %v", name, err)
+			writeBodyError(w, r, err, ErrCodeUploadFailed)
+			return uploadedFile{}, false
+		}
+	}
+
+	file := uploadedFile{
+		Size:   size,
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}
+	if expected := r.Header.Get("X-File-Hash"); expected != "" && !strings.EqualFold(expected, file.MD5) {
+		l.logError("Хэш загруженного файла %s не совпал: ожидали %s, получили %s", name, expected, file.MD5)
+		writeError(w, r, http.StatusBadRequest, ErrCodeHashMismatch)
+		return uploadedFile{}, false
+	}
+	if chunked.ID != "" && file.SHA256 != chunked.SHA256 {
+		l.logError("Файл загрузки по частям %s изменился после завершения", chunked.ID)
+		writeError(w, r, http.StatusBadRequest, ErrCodeHashMismatch)
+		return uploadedFile{}, false
+	}
+
+	os.Chmod(tmp.Name(), 0644)
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		l.logError("Ошибка замены файла %s: %v", target, err)
+		writeError(w, r, http.StatusInternalServerError, ErrCodeUploadFailed)
+		return uploadedFile{}, false
+	}
+	return file, true
+}
+
+// Загрузка новой сборки лаунчера или игры. ?version= (версия или
+// patch/minor/major) сразу публикует новую версию по тем же правилам, что
+// релизы: она должна быть новее текущей, если не указан force=true. Без
+// version файл заменяется, а версия остается прежней.
+func (l *Logger) adminUploadHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeBuildsWrite, "⬆️", "/admin/api/upload/{artifact}", func() {
+		cfg := currentConfig()
+		artifact := r.PathValue("artifact")
+		filename, ok := artifactFilename(cfg, artifact)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeUnknownArtifact, artifact)
+			return
+		}
+		version := r.URL.Query().Get("version")
+		if version != "" && !isVersionOrBump(version) {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+
+		// Версия проверяется и публикуется без гонки с релизами
+		publishMu.Lock()
+		defer publishMu.Unlock()
+		if version != "" {
+			var apiErr *modError
+			version, apiErr = resolveVersion(artifact, version, artifactVersion(currentConfig(), artifact), r.URL.Query().Get("force") == "true")
+			if apiErr != nil {
+				writeModError(w, r, apiErr)
+				return
+			}
+		}
+
+		target := filepath.Join(cfg.ClientsDir, filename)
+		file, ok := l.receiveUpload(w, r, target)
+		if !ok {
+			return
+		}
+		l.adoptBlob(BlobRef{Path: target, Channel: "stable", Artifact: artifact, Version: version}, file)
+		clientIndex.Remember(target, file)
+		if version != "" {
+			if err := publishArtifactVersion(artifact, version); err != nil {
+				l.logError("Ошибка сохранения опубликованных версий: %v", err)
+				writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+				return
+			}
+			l.logSuccess("Опубликована версия %s %s", artifact, version)
+		}
+
+		l.dispatchWebhook(WebhookBuildPublished, BuildPublishedEvent{Artifact: artifact, Filename: filename, Size: file.Size, Hash: file.MD5, SHA256: file.SHA256})
+
+		json.NewEncoder(w).Encode(FileInfoResponse{Filename: filename, Size: file.Size, Hash: file.MD5})
+		l.logSuccess("Загружена сборка %s: %s (%d bytes, хэш: %s)", artifact, filename, file.Size, file.MD5)
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Файл установки игры у игрока
+type VerifyFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type VerifyRequest struct {
+	Files []VerifyFile `json:"files"`
+}
+
+// Расхождения с каталогом игры. Для отсутствующих и поврежденных файлов
+// отдаются размер и хэш, которые должны получиться после починки.
+type VerifyResponse struct {
+	GameVersion string       `json:"game_version"`
+	OK          bool         `json:"ok"`
+	Missing     []VerifyFile `json:"missing"`
+	Corrupted   []VerifyFile `json:"corrupted"`
+	Extraneous  []string     `json:"extraneous"`
+	// Сколько скачать, чтобы починить установку
+	RepairBytes int64 `json:"repair_bytes"`
+}
+
+// Путь из списка лаунчера в виде a/b/c; false — путь выходит за
+// пределы каталога игры
+func normalizeVerifyPath(name string) (string, bool) {
+	name = path.Clean(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, ":") {
+		return "", false
+	}
+	return name, true
+}
+
+// Файлы игрока (сохранения, настройки) из VERIFY_IGNORE: лишними и
+// поврежденными не считаются, отсутствующие по-прежнему докачиваются
+func verifyIgnored(cfg *Config, name string) bool {
+	for _, pattern := range cfg.VerifyIgnore {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.HasPrefix(name, pattern) {
+				return true
+			}
+		} else if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Сравнение списка файлов лаунчера с каталогом игры. Вместе с ответом
+// возвращаются файлы каталога, которые нужно скачать.
+func verifyInstallation(cfg *Config, entries []archiveEntry, files []VerifyFile) (VerifyResponse, []archiveEntry, error) {
+	response := VerifyResponse{
+		GameVersion: cfg.GameVersion,
+		Missing:     []VerifyFile{},
+		Corrupted:   []VerifyFile{},
+		Extraneous:  []string{},
+	}
+	local := make(map[string]VerifyFile, len(files))
+	for _, file := range files {
+		local[file.Path] = file
+	}
+
+	var repair []archiveEntry
+	expected := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		expected[entry.Name] = true
+		have, ok := local[entry.Name]
+		if ok && verifyIgnored(cfg, entry.Name) {
+			continue
+		}
+
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			return VerifyResponse{}, nil, err
+		}
+		indexed, err := clientIndex.Lookup(entry.Path, info)
+		if err != nil {
+			return VerifyResponse{}, nil, err
+		}
+		want := VerifyFile{Path: entry.Name, Size: indexed.Size, SHA256: indexed.SHA256}
+
+		switch {
+		case !ok:
+			response.Missing = append(response.Missing, want)
+		case have.Size != want.Size || !strings.EqualFold(have.SHA256, want.SHA256):
+			response.Corrupted = append(response.Corrupted, want)
+		default:
+			continue
+		}
+		response.RepairBytes += want.Size
+		repair = append(repair, entry)
+	}
+
+	for _, file := range files {
+		if !expected[file.Path] && !verifyIgnored(cfg, file.Path) {
+			response.Extraneous = append(response.Extraneous, file.Path)
+		}
+	}
+	response.OK = len(response.Missing)+len(response.Corrupted)+len(response.Extraneous) == 0
+	return response, repair, nil
+}
+
+// Проверка установки для кнопки «Починить игру»: лаунчер присылает
+// пути, размеры и SHA-256 своих файлов и получает расхождения. С
+// ?archive=zip (или tar.gz) вместо списка приходит архив только с
+// недостающими и поврежденными файлами; лишние файлы лаунчер удаляет
+// по списку из обычного ответа.
+func (l *Logger) verifyHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleWithCORS(w, r, "🩺", "/api/verify", func() {
+		cfg := currentConfig()
+		var format archiveFormat
+		archive := r.URL.Query().Get("archive")
+		if archive != "" {
+			var ok bool
+			if format, ok = archiveFormats[archive]; !ok {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			if l.rejectDuringMaintenance(w, r, "файлов игры") || l.rejectWithoutEntitlement(w, r, cfg.GameEntitlement, "файлов игры") {
+				return
+			}
+		}
+		if cfg.GameDir == "" {
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+
+		var req VerifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBodyError(w, r, err, ErrCodeInvalidRequest)
+			return
+		}
+		for i, file := range req.Files {
+			name, ok := normalizeVerifyPath(file.Path)
+			if !ok {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+				return
+			}
+			req.Files[i].Path = name
+		}
+
+		entries, _, err := scanGameDir(cfg.GameDir)
+		if os.IsNotExist(err) {
+			l.logError("Каталог игры не найден: %s", cfg.GameDir)
+			writeError(w, r, http.StatusNotFound, ErrCodeFileNotFound)
+			return
+		}
+		var response VerifyResponse
+		var repair []archiveEntry
+		if err == nil {
+			response, repair, err = verifyInstallation(cfg, entries, req.Files)
+		}
+		if err != nil {
+			l.logError("Ошибка проверки каталога игры %s: %v", cfg.GameDir, err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeFileStat)
+			return
+		}
+
+		if archive != "" {
+			l.streamGameArchive(w, r, "repair", repair, format)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+		l.logSuccess("Проверка установки: нет %d, повреждено %d, лишних %d", len(response.Missing), len(response.Corrupted), len(response.Extraneous))
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Вместо версии при загрузке сборки можно указать patch, minor или
+// major — версия будет следующей после текущей в канале
+var versionBumps = []string{"patch", "minor", "major"}
+
+// Версия или patch/minor/major
+func isVersionOrBump(version string) bool {
+	return isSemver(version) || slices.Contains(versionBumps, version)
+}
+
+// Сравнение версий по правилам semver: предрелиз (1.2.0-beta.1) старше
+// предыдущей версии, но младше 1.2.0; метаданные сборки (+build.5) не
+// учитываются. Версии должны проходить isSemver.
+func compareSemver(a, b string) int {
+	pa, pb := semverPattern.FindStringSubmatch(a), semverPattern.FindStringSubmatch(b)
+	for i := 1; i <= 3; i++ {
+		x, _ := strconv.ParseUint(pa[i], 10, 64)
+		y, _ := strconv.ParseUint(pb[i], 10, 64)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	preA, preB := semverPrerelease(a), semverPrerelease(b)
+	switch {
+	case preA == "" && preB == "":
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	idsA, idsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		x, errX := strconv.ParseUint(idsA[i], 10, 64)
+		y, errY := strconv.ParseUint(idsB[i], 10, 64)
+		switch {
+		case errX == nil && errY == nil:
+			if x != y {
+				if x < y {
+					return -1
+				}
+				return 1
+			}
+		// Числовые идентификаторы младше буквенных
+		case errX == nil:
+			return -1
+		case errY == nil:
+			return 1
+		case idsA[i] != idsB[i]:
+			return strings.Compare(idsA[i], idsB[i])
+		}
+	}
+	switch {
+	case len(idsA) < len(idsB):
+		return -1
+	case len(idsA) > len(idsB):
+		return 1
+	}
+	return 0
+}
+
+func semverPrerelease(version string) string {
+	version, _, _ = strings.Cut(version, "+")
+	_, prerelease, _ := strings.Cut(version, "-")
+	return prerelease
+}
+
+// Следующая версия: предрелиз и метаданные отбрасываются, младшие
+// компоненты обнуляются. false — текущая версия не semver.
+func bumpVersion(current, bump string) (string, bool) {
+	parts := semverPattern.FindStringSubmatch(current)
+	if parts == nil {
+		return "", false
+	}
+	var numbers [3]uint64
+	for i := range numbers {
+		numbers[i], _ = strconv.ParseUint(parts[i+1], 10, 64)
+	}
+	switch bump {
+	case "major":
+		numbers = [3]uint64{numbers[0] + 1, 0, 0}
+	case "minor":
+		numbers = [3]uint64{numbers[0], numbers[1] + 1, 0}
+	case "patch":
+		numbers[2]++
+	default:
+		return "", false
+	}
+	return strconv.FormatUint(numbers[0], 10) + "." + strconv.FormatUint(numbers[1], 10) + "." + strconv.FormatUint(numbers[2], 10), true
+}
+
+// Версия сборки для канала с текущей версией current. Без force версия
+// должна быть строго новее текущей: опечатка в версии не должна откатить
+// игроков на старую сборку или оставить их без обновления.
+func resolveVersion(artifact, requested, current string, force bool) (string, *modError) {
+	version := requested
+	if slices.Contains(versionBumps, requested) {
+		var ok bool
+		if version, ok = bumpVersion(current, requested); !ok {
+			return "", &modError{http.StatusBadRequest, ErrCodeInvalidRequest, nil}
+		}
+	}
+	if !isSemver(version) {
+		return "", &modError{http.StatusBadRequest, ErrCodeInvalidRequest, nil}
+	}
+	if !force && isSemver(current) && compareSemver(version, current) <= 0 {
+		return "", &modError{http.StatusConflict, ErrCodeVersionNotNewer, []interface{}{artifact, version, current}}
+	}
+	return version, nil
+}
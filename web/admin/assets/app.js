@@ -0,0 +1,209 @@
+'use strict';
+
+// Клиент админского API; токен живет только в рамках вкладки
+const api = {
+  token: sessionStorage.getItem('loil-admin-token') || '',
+
+  async request(method, path, body, headers = {}) {
+    const response = await fetch('/admin/api' + path, {
+      method,
+      headers: Object.assign({ 'Authorization': 'Bearer ' + this.token }, headers),
+      body,
+    });
+    if (response.status === 204) {
+      return null;
+    }
+    const data = await response.json().catch(() => null);
+    if (!response.ok) {
+      const message = data && data.error ? data.error.message : response.statusText;
+      throw new Error(message);
+    }
+    return data;
+  },
+
+  json(method, path, payload) {
+    return this.request(method, path, JSON.stringify(payload), { 'Content-Type': 'application/json' });
+  },
+};
+
+const $ = (id) => document.getElementById(id);
+
+const statusLabels = {
+  draft: 'черновик',
+  scheduled: 'запланирована',
+  published: 'опубликована',
+  expired: 'снята',
+  '': 'опубликована',
+};
+
+function showMessage(text, ok) {
+  const el = $('message');
+  el.textContent = text;
+  el.className = ok ? 'ok' : 'error';
+}
+
+function cell(text) {
+  const td = document.createElement('td');
+  td.textContent = text;
+  return td;
+}
+
+function button(text, onClick) {
+  const b = document.createElement('button');
+  b.type = 'button';
+  b.textContent = text;
+  b.addEventListener('click', onClick);
+  return b;
+}
+
+async function loadMaintenance() {
+  const status = await api.request('GET', '/maintenance');
+  $('maintenance').checked = status.enabled;
+}
+
+async function loadStats() {
+  const stats = await api.request('GET', '/stats');
+  $('uptime').textContent = 'Сервер работает: ' + stats.uptime;
+  const tbody = $('stats');
+  tbody.replaceChildren();
+  for (const [name, s] of Object.entries(stats.downloads)) {
+    const tr = document.createElement('tr');
+    tr.append(
+      cell(name),
+      cell(s.downloads),
+      cell(s.failed),
+      cell(s.bytes_sent),
+      cell(s.last_download_at ? new Date(s.last_download_at).toLocaleString() : '—'),
+    );
+    tbody.append(tr);
+  }
+}
+
+let newsCache = [];
+
+async function loadNews() {
+  const data = await api.request('GET', '/news');
+  newsCache = data.news || [];
+  const tbody = $('news');
+  tbody.replaceChildren();
+  for (const item of newsCache) {
+    const tr = document.createElement('tr');
+    const actions = document.createElement('td');
+    actions.append(
+      button('Изменить', () => editNews(item)),
+      item.status === 'published' || !item.status
+        ? button('Снять', () => setStatus(item.id, 'draft'))
+        : button('Опубликовать', () => setStatus(item.id, 'published')),
+      button('Удалить', () => deleteNews(item)),
+    );
+    tr.append(cell(item.id), cell(item.title), cell(item.date || '—'), cell(statusLabels[item.status || '']), actions);
+    tbody.append(tr);
+  }
+}
+
+function editNews(item) {
+  $('news-id').value = item.id;
+  $('news-title').value = item.title;
+  $('news-content').value = item.content;
+  $('news-image').value = item.image || '';
+  $('news-date').value = item.date || '';
+}
+
+function resetNewsForm() {
+  $('news-form').reset();
+  $('news-id').value = '';
+}
+
+async function setStatus(id, status) {
+  await api.json('PUT', '/news/' + id + '/status', { status });
+  showMessage('Статус новости обновлен', true);
+  await loadNews();
+}
+
+async function deleteNews(item) {
+  if (!confirm('Удалить новость «' + item.title + '»?')) {
+    return;
+  }
+  await api.request('DELETE', '/news/' + item.id);
+  showMessage('Новость удалена', true);
+  await loadNews();
+}
+
+async function saveNews(event) {
+  event.preventDefault();
+  const id = $('news-id').value;
+  const existing = newsCache.find((n) => String(n.id) === id);
+  const payload = Object.assign({}, existing, {
+    title: $('news-title').value,
+    content: $('news-content').value,
+    image: $('news-image').value,
+    date: $('news-date').value,
+  });
+  if (id) {
+    await api.json('PUT', '/news/' + id, payload);
+  } else {
+    await api.json('POST', '/news', payload);
+  }
+  showMessage('Новость сохранена', true);
+  resetNewsForm();
+  await loadNews();
+}
+
+async function uploadBuild(event) {
+  event.preventDefault();
+  const file = $('build').files[0];
+  if (!file) {
+    return;
+  }
+  showMessage('Загрузка ' + file.name + '...', true);
+  const result = await api.request('PUT', '/upload/' + $('artifact').value, file, {
+    'Content-Type': 'application/octet-stream',
+  });
+  showMessage('Загружено: ' + result.filename + ' (' + result.size + ' байт, хэш ' + result.hash + ')', true);
+  await loadStats();
+}
+
+// Ошибки любого действия показываем в строке статуса
+function guarded(fn) {
+  return (...args) => fn(...args).catch((err) => showMessage(err.message, false));
+}
+
+async function enterPanel() {
+  // Каждый раздел загружается независимо: у ключа может не быть прав на все
+  const sections = [loadMaintenance, loadStats, loadNews];
+  const results = await Promise.allSettled(sections.map((load) => load()));
+  const failed = results.find((r) => r.status === 'rejected');
+  if (failed && results.every((r) => r.status === 'rejected')) {
+    throw failed.reason;
+  }
+  $('panel').hidden = false;
+  $('logout').hidden = false;
+  showMessage(failed ? failed.reason.message : '', !failed);
+}
+
+$('login').addEventListener('submit', guarded(async (event) => {
+  event.preventDefault();
+  api.token = $('token').value;
+  await enterPanel();
+  sessionStorage.setItem('loil-admin-token', api.token);
+  $('token').value = '';
+}));
+
+$('logout').addEventListener('click', () => {
+  sessionStorage.removeItem('loil-admin-token');
+  location.reload();
+});
+
+$('maintenance').addEventListener('change', guarded(async (event) => {
+  await api.json('PUT', '/maintenance', { enabled: event.target.checked });
+  showMessage(event.target.checked ? 'Режим техработ включен' : 'Режим техработ выключен', true);
+}));
+
+$('refresh-stats').addEventListener('click', guarded(loadStats));
+$('upload').addEventListener('submit', guarded(uploadBuild));
+$('news-form').addEventListener('submit', guarded(saveNews));
+$('news-reset').addEventListener('click', resetNewsForm);
+
+if (api.token) {
+  guarded(enterPanel)();
+}
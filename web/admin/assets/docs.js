@@ -0,0 +1,196 @@
+'use strict';
+
+// Документация публичного API по спецификации OpenAPI с админского
+// слушателя: операции по тегам, схемы и пробный запрос к PUBLIC_URL.
+// Токен администратора берется из панели (sessionStorage).
+const adminToken = sessionStorage.getItem('loil-admin-token') || '';
+
+const $ = (id) => document.getElementById(id);
+
+function showMessage(text, ok) {
+  const el = $('message');
+  el.textContent = text;
+  el.className = ok ? 'ok' : 'error';
+}
+
+function element(tag, className, text) {
+  const el = document.createElement(tag);
+  if (className) {
+    el.className = className;
+  }
+  if (text !== undefined) {
+    el.textContent = text;
+  }
+  return el;
+}
+
+// Схема с раскрытыми $ref; повторно встреченный тип не раскрывается,
+// чтобы рекурсивные структуры не зациклились
+function resolveSchema(spec, schema, seen = new Set()) {
+  if (Array.isArray(schema)) {
+    return schema.map((item) => resolveSchema(spec, item, seen));
+  }
+  if (!schema || typeof schema !== 'object') {
+    return schema;
+  }
+  if (schema.$ref) {
+    const name = schema.$ref.split('/').pop();
+    if (seen.has(name)) {
+      return { $ref: name };
+    }
+    return resolveSchema(spec, spec.components.schemas[name], new Set(seen).add(name));
+  }
+  const resolved = {};
+  for (const [key, value] of Object.entries(schema)) {
+    resolved[key] = resolveSchema(spec, value, seen);
+  }
+  return resolved;
+}
+
+function schemaBlock(spec, title, content) {
+  const block = element('div');
+  for (const [type, media] of Object.entries(content || {})) {
+    block.append(element('h4', '', title + ' (' + type + ')'));
+    block.append(element('pre', '', JSON.stringify(resolveSchema(spec, media.schema), null, 2)));
+  }
+  return block;
+}
+
+// Та же команда для curl: браузер пускает к публичному API только простые
+// запросы, остальные (POST с JSON, токен) удобнее повторить из консоли
+function curlCommand(init, target) {
+  const quote = (s) => "'" + s.replace(/'/g, "'\\''") + "'";
+  let command = 'curl -X ' + init.method;
+  for (const [name, value] of Object.entries(init.headers)) {
+    command += ' -H ' + quote(name + ': ' + value);
+  }
+  if (init.body) {
+    command += ' -d ' + quote(init.body);
+  }
+  return command + ' ' + quote(target);
+}
+
+// Пробный запрос: параметры пути и запроса из полей, тело как есть
+async function tryOperation(server, method, path, inputs, body, output) {
+  let url = path;
+  const query = new URLSearchParams();
+  for (const { param, input } of inputs) {
+    if (input.value === '') {
+      continue;
+    }
+    if (param.in === 'path') {
+      url = url.replace('{' + param.name + '}', encodeURIComponent(input.value));
+    } else {
+      query.set(param.name, input.value);
+    }
+  }
+  const headers = {};
+  const token = $('account-token').value;
+  if (token) {
+    headers['Authorization'] = 'Bearer ' + token;
+  }
+  const init = { method: method.toUpperCase(), headers };
+  if (body && body.value) {
+    headers['Content-Type'] = 'application/json';
+    init.body = body.value;
+  }
+  const target = server + url + (query.size ? '?' + query : '');
+  const command = curlCommand(init, target) + '\n\n';
+  output.textContent = command + '…';
+  try {
+    const response = await fetch(target, init);
+    const text = await response.text();
+    output.textContent = command + response.status + ' ' + response.statusText + '\n\n' + text;
+  } catch (err) {
+    output.textContent = command + err.message;
+  }
+}
+
+function renderOperation(spec, server, path, method, op) {
+  const details = element('details', 'operation');
+  const summary = element('summary');
+  summary.append(
+    element('span', 'method method-' + method, method.toUpperCase()),
+    element('code', '', path),
+    element('span', '', ' ' + (op.summary || '') + (op.security ? ' 🔒' : '')),
+  );
+  details.append(summary);
+
+  const inputs = [];
+  if (op.parameters) {
+    details.append(element('h4', '', 'Параметры'));
+    for (const param of op.parameters) {
+      const input = element('input');
+      input.placeholder = param.description || param.name;
+      const label = element('label', 'param', param.name + (param.required ? ' *' : '') + ' (' + param.in + ') ');
+      label.append(input);
+      details.append(label);
+      inputs.push({ param, input });
+    }
+  }
+
+  let body = null;
+  if (op.requestBody) {
+    details.append(schemaBlock(spec, 'Тело запроса', op.requestBody.content));
+    if (op.requestBody.content['application/json']) {
+      body = element('textarea');
+      body.rows = 5;
+      body.placeholder = '{}';
+      details.append(body);
+    }
+  }
+  for (const [status, response] of Object.entries(op.responses)) {
+    details.append(schemaBlock(spec, 'Ответ ' + status, response.content));
+  }
+
+  const output = element('pre', 'response');
+  const run = element('button', '', 'Выполнить');
+  run.type = 'button';
+  run.addEventListener('click', () => tryOperation(server, method, path, inputs, body, output));
+  details.append(run, output);
+  return details;
+}
+
+function renderSpec(spec) {
+  const server = spec.servers[0].url;
+  $('server').textContent = spec.info.title + ' ' + spec.info.version + ' — ' + server;
+
+  const byTag = new Map();
+  for (const [path, methods] of Object.entries(spec.paths).sort(([a], [b]) => a.localeCompare(b))) {
+    for (const [method, op] of Object.entries(methods)) {
+      const tag = (op.tags && op.tags[0]) || 'other';
+      if (!byTag.has(tag)) {
+        byTag.set(tag, []);
+      }
+      byTag.get(tag).push(renderOperation(spec, server, path, method, op));
+    }
+  }
+
+  const container = $('operations');
+  container.replaceChildren();
+  for (const tag of [...byTag.keys()].sort()) {
+    const section = element('section');
+    section.append(element('h2', '', tag), ...byTag.get(tag));
+    container.append(section);
+  }
+  $('docs').hidden = false;
+}
+
+async function loadSpec() {
+  if (!adminToken) {
+    showMessage('Сначала войдите в панель администратора', false);
+    return;
+  }
+  const response = await fetch('/admin/api/openapi.json', {
+    headers: { 'Authorization': 'Bearer ' + adminToken },
+  });
+  const text = await response.text();
+  if (!response.ok) {
+    const data = JSON.parse(text || 'null');
+    throw new Error(data && data.error ? data.error.message : response.statusText);
+  }
+  $('spec-link').href = URL.createObjectURL(new Blob([text], { type: 'application/json' }));
+  renderSpec(JSON.parse(text));
+}
+
+loadSpec().catch((err) => showMessage(err.message, false));
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// События исходящих вебхуков
+const (
+	WebhookBuildPublished      = "build_published"
+	WebhookNewsCreated         = "news_created"
+	WebhookPlayerBanned        = "player_banned"
+	WebhookMaintenanceToggled  = "maintenance_toggled"
+	WebhookFeedbackReceived    = "feedback_received"
+	WebhookAchievementUnlocked = "achievement_unlocked"
+	WebhookCheatReported       = "cheat_reported"
+)
+
+var webhookEvents = []string{WebhookBuildPublished, WebhookNewsCreated, WebhookPlayerBanned, WebhookMaintenanceToggled, WebhookFeedbackReceived, WebhookAchievementUnlocked, WebhookCheatReported}
+
+// Доставка: до webhookMaxAttempts попыток с паузой, растущей вдвое от
+// webhookRetryDelay; в журнале хранятся последние webhookDeliveryLog
+// доставок каждого вебхука
+const (
+	webhookMaxAttempts = 6
+	webhookRetryDelay  = 10 * time.Second
+	webhookDeliveryLog = 100
+)
+
+// Подписчик на события сервера. Secret выдается один раз при создании;
+// им подписывается тело каждой доставки.
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Пусто — все события
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+	Events   []string  `json:"events"`
+}
+
+// Тело запроса к подписчику
+type WebhookPayload struct {
+	ID    string    `json:"id"`
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// Запись журнала доставок
+type WebhookDelivery struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	// pending — ждет следующей попытки, delivered, failed — попытки кончились
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+// Список вебхуков в DATA_DIR/webhooks.json. Журнал доставок и еще не
+// доставленные события живут в памяти и не переживают перезапуск.
+type WebhookStore struct {
+	mu         sync.Mutex
+	hooks      []Webhook
+	deliveries map[string][]WebhookDelivery
+}
+
+var webhooks = &WebhookStore{deliveries: make(map[string][]WebhookDelivery)}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+func webhooksFile() string {
+	return filepath.Join(currentConfig().DataDir, "webhooks.json")
+}
+
+func (s *WebhookStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return loadJSONFile(webhooksFile(), &s.hooks)
+}
+
+// Вебхуки без секретов для админки
+func (s *WebhookStore) List() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Webhook, len(s.hooks))
+	for i, hook := range s.hooks {
+		list[i] = hook
+		list[i].Secret = ""
+	}
+	return list
+}
+
+func (s *WebhookStore) Create(req WebhookRequest) (Webhook, error) {
+	hook := Webhook{
+		ID:        randomID(6),
+		URL:       req.URL,
+		Events:    append([]string{}, req.Events...),
+		Secret:    randomID(24),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := append(slices.Clone(s.hooks), hook)
+	if err := saveJSONFile(webhooksFile(), next); err != nil {
+		return Webhook{}, err
+	}
+	s.hooks = next
+	return hook, nil
+}
+
+func (s *WebhookStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.hooks, func(h Webhook) bool { return h.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	next := slices.Delete(slices.Clone(s.hooks), i, i+1)
+	if err := saveJSONFile(webhooksFile(), next); err != nil {
+		return true, err
+	}
+	s.hooks = next
+	delete(s.deliveries, id)
+	return true, nil
+}
+
+// Вебхуки, подписанные на событие
+func (s *WebhookStore) subscribers(event string) []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Webhook
+	for _, hook := range s.hooks {
+		if len(hook.Events) == 0 || slices.Contains(hook.Events, event) {
+			list = append(list, hook)
+		}
+	}
+	return list
+}
+
+func (s *WebhookStore) Deliveries(id string) ([]WebhookDelivery, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.hooks, func(h Webhook) bool { return h.ID == id }) {
+		return nil, false
+	}
+	// Сначала новые
+	list := slices.Clone(s.deliveries[id])
+	slices.Reverse(list)
+	return list, true
+}
+
+// Запись или обновление доставки в журнале вебхука; false — вебхук
+// уже удален, и повторять доставку не нужно
+func (s *WebhookStore) record(hookID string, delivery WebhookDelivery) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.hooks, func(h Webhook) bool { return h.ID == hookID }) {
+		return false
+	}
+	entries := s.deliveries[hookID]
+	if i := slices.IndexFunc(entries, func(d WebhookDelivery) bool { return d.ID == delivery.ID }); i >= 0 {
+		entries[i] = delivery
+		return true
+	}
+	entries = append(entries, delivery)
+	if len(entries) > webhookDeliveryLog {
+		entries = slices.Delete(entries, 0, len(entries)-webhookDeliveryLog)
+	}
+	s.deliveries[hookID] = entries
+	return true
+}
+
+// Рассылка события подписчикам. Тело подписывается HMAC-SHA256 с
+// секретом вебхука в X-Loil-Signature, как у вебхуков списков игроков.
+// Зеркала узнают о публикациях отсюда же.
+func (l *Logger) dispatchWebhook(event string, data any) {
+	l.notifyMirrors(event)
+	hooks := webhooks.subscribers(event)
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload := WebhookPayload{ID: randomID(8), Event: event, Time: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		l.logError("Ошибка кодирования события %s для вебхуков: %v", event, err)
+		return
+	}
+	for _, hook := range hooks {
+		go l.deliverWebhook(hook, payload, body)
+	}
+}
+
+func (l *Logger) deliverWebhook(hook Webhook, payload WebhookPayload, body []byte) {
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	delivery := WebhookDelivery{ID: payload.ID, Event: payload.Event, Status: "pending", CreatedAt: payload.Time}
+	delay := webhookRetryDelay
+	for {
+		delivery.Attempts++
+		delivery.ResponseStatus, delivery.Error = 0, ""
+		status, err := postWebhook(hook.URL, body, map[string]string{
+			"X-Loil-Event":     payload.Event,
+			"X-Loil-Delivery":  payload.ID,
+			"X-Loil-Signature": signature,
+		})
+		delivery.ResponseStatus = status
+		if err == nil {
+			now := time.Now().UTC()
+			delivery.Status, delivery.DeliveredAt, delivery.NextAttemptAt = "delivered", &now, nil
+			webhooks.record(hook.ID, delivery)
+			return
+		}
+		delivery.Error = err.Error()
+
+		if delivery.Attempts >= webhookMaxAttempts {
+			delivery.Status, delivery.NextAttemptAt = "failed", nil
+			webhooks.record(hook.ID, delivery)
+			l.logError("Не удалось доставить событие %s в вебхук %s после %d попыток: %v", payload.Event, hook.ID, delivery.Attempts, err)
+			return
+		}
+		next := time.Now().UTC().Add(delay)
+		delivery.NextAttemptAt = &next
+		if !webhooks.record(hook.ID, delivery) {
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// POST тела доставки; код ответа нужен для журнала
+func postWebhook(target string, body []byte, headers map[string]string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("ответ %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Список вебхуков и известных событий
+func (l *Logger) adminListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪝", "/admin/api/webhooks", func() {
+		json.NewEncoder(w).Encode(WebhooksResponse{Webhooks: webhooks.List(), Events: webhookEvents})
+	})
+}
+
+// Новый вебхук; секрет для проверки подписи есть только в этом ответе
+func (l *Logger) adminCreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪝", "/admin/api/webhooks", func() {
+		var req WebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest)
+			return
+		}
+		for _, event := range req.Events {
+			if !slices.Contains(webhookEvents, event) {
+				writeError(w, r, http.StatusBadRequest, ErrCodeUnknownWebhookEvent, event)
+				return
+			}
+		}
+
+		hook, err := webhooks.Create(req)
+		if err != nil {
+			l.logError("Ошибка сохранения вебхуков: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+		l.logSuccess("Добавлен вебхук %s: %s %v", hook.ID, hook.URL, hook.Events)
+	})
+}
+
+func (l *Logger) adminDeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪝", "/admin/api/webhooks/{id}", func() {
+		id := r.PathValue("id")
+		found, err := webhooks.Delete(id)
+		if err != nil {
+			l.logError("Ошибка сохранения вебхуков: %v", err)
+			writeError(w, r, http.StatusInternalServerError, ErrCodeInternal)
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeWebhookNotFound, id)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		l.logSuccess("Вебхук %s удален", id)
+	})
+}
+
+// Журнал доставок вебхука, сначала новые
+func (l *Logger) adminWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	l.handleAdmin(w, r, ScopeServerAdmin, "🪝", "/admin/api/webhooks/{id}/deliveries", func() {
+		id := r.PathValue("id")
+		deliveries, ok := webhooks.Deliveries(id)
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeWebhookNotFound, id)
+			return
+		}
+		json.NewEncoder(w).Encode(WebhookDeliveriesResponse{Deliveries: append([]WebhookDelivery{}, deliveries...)})
+	})
+}